@@ -1,6 +1,12 @@
 /*
 package aead uses AEAD crypto with AES keys to encrypt and authenticate content composed of a plaintext metadata string and a plaintext data string.
-An encryption results in a string literal of the form <b64URLmetadata>.<b64URLciphertext>.<b64URLnonce>.
+An encryption results in a string literal of the form <b64URLmetadata>.<b64URLciphertext>.<b64URLnonce>,
+or v1.<b64URLmetadata>.<b64URLciphertext>.<b64URLnonce> once the v1 format is enabled with SetMode
+(v1p. if the data is padded to hide its length).
+
+The metadata may be a map of fields serialized as canonical JSON; see EncryptFields and DecryptFields.
+
+Every encryption and decryption can be audited by a hook set with SetAuditHook; see audit.go.
 */
 package aead
 
@@ -9,9 +15,12 @@ import (
 	"crypto/aes"
 	"crypto/cipher"
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"strings"
+	"sync/atomic"
 )
 
 /*
@@ -20,6 +29,8 @@ The key argument should be either 16, 24, or 32 bytes to select AES-128, AES-192
 
 If the key is nil, a new 32 byte AES key is generated.
 This option is used when the scope of key use is limited to within a single program execution.
+
+The cipher's KeyID is the Fingerprint of its key.
 */
 func NewAEADCipher(key []byte) (cipher.AEAD, error) {
 	var (
@@ -38,9 +49,9 @@ func NewAEADCipher(key []byte) (cipher.AEAD, error) {
 		}
 	} else {
 		switch len(key) {
-		case 16, 24, 36:
+		case 16, 24, 32:
 		default:
-			return nil, fmt.Errorf("An aead key must be of length 16. 24, or 32. This key is of length: ", len(key))
+			return nil, fmt.Errorf("An aead key must be of length 16. 24, or 32. This key is of length: %v", len(key))
 		}
 		keyval = key
 	}
@@ -58,16 +69,32 @@ func NewAEADCipher(key []byte) (cipher.AEAD, error) {
 	if err != nil {
 		return nil, err
 	}
-	return aeadCipher, nil
+	return keyedAEAD{AEAD: aeadCipher, keyID: Fingerprint(keyval)}, nil
 }
 
 /*
 Encrypt generates a literal of the form <b64URLmetadata>.<b64URLciphertext>.<b64URLnonce> given an AEAD cipher, a metadata string and a data
 string. Only the data is encrypted - the metadata must be appropriate to expose in the clear. Each call generates a random
 nonce of the length required by the cipher.
+
+If the Mode is Migrate or V1Only, the literal is in the v1 format. Options such as PadPow2 pad the data to hide its
+length; see pad.go.
 */
-func Encrypt(aeadCipher cipher.AEAD, metadata, data string) (string, error) {
+func Encrypt(aeadCipher cipher.AEAD, metadata, data string, opts ...Option) (string, error) {
+	var (
+		a       = Audit{Op: OpSeal, KeyID: KeyID(aeadCipher), MetadataHash: metadataHash(metadata)}
+		literal string
+		err     error
+	)
+
+	literal, err = encrypt(aeadCipher, metadata, data, opts, &a)
+	a.Outcome = outcome(a.Outcome, err)
+	audit(a)
+	return literal, err
+}
 
+//encrypt implements Encrypt and sets the version and ciphertext size of its Audit
+func encrypt(aeadCipher cipher.AEAD, metadata, data string, opts []Option, a *Audit) (string, error) {
 	var (
 		nonce         = make([]byte, aeadCipher.NonceSize())
 		ciphertext    []byte
@@ -75,9 +102,30 @@ func Encrypt(aeadCipher cipher.AEAD, metadata, data string) (string, error) {
 		b64ciphertext []byte
 		b64nonce      []byte
 		buf           bytes.Buffer
+		v1            = GetMode() != V0Only
+		version       = v1Prefix
+		plaintext     = []byte(data)
+		additional    = []byte(metadata)
+		o             = newEncryptOptions(opts)
 		err           error
 	)
 
+	//Padded data is marked by a v1p version
+	if o.bucket != nil {
+		if !v1 {
+			return "", fmt.Errorf("AEAD Padding requires the v1 literal format\n")
+		}
+		version = v1PaddedPrefix
+		plaintext = pad(plaintext, o.bucket)
+	}
+
+	//A v1 literal authenticates its version as well as the metadata
+	a.Version = v0Version
+	if v1 {
+		a.Version = version
+		additional = []byte(version + "." + metadata)
+	}
+
 	//A nonce of the length required by the AEAD is generated
 	_, err = rand.Read(nonce)
 	if err != nil {
@@ -85,7 +133,8 @@ func Encrypt(aeadCipher cipher.AEAD, metadata, data string) (string, error) {
 	}
 
 	//Seal encrypts the data using the aeadCipher's key and the nonce and appends an authentication code for the metadata
-	ciphertext = aeadCipher.Seal(ciphertext, nonce, []byte(data), []byte(metadata))
+	ciphertext = aeadCipher.Seal(ciphertext, nonce, plaintext, additional)
+	a.CiphertextSize = len(ciphertext)
 
 	//Base64 Encode metadata, ciphertext and nonce
 	b64metadata = make([]byte, base64.URLEncoding.EncodedLen(len([]byte(metadata))))
@@ -95,7 +144,10 @@ func Encrypt(aeadCipher cipher.AEAD, metadata, data string) (string, error) {
 	b64nonce = make([]byte, base64.URLEncoding.EncodedLen(len(nonce)))
 	base64.URLEncoding.Encode(b64nonce, nonce)
 
-	//Compose a <b64URLmetadata>.<b64URLciphertext>.<b64URLnonce> literal
+	//Compose a [v1[p].]<b64URLmetadata>.<b64URLciphertext>.<b64URLnonce> literal
+	if v1 {
+		buf.Write([]byte(version + "."))
+	}
 	buf.Write(b64metadata)
 	buf.Write([]byte("."))
 	buf.Write(b64ciphertext)
@@ -109,42 +161,111 @@ func Encrypt(aeadCipher cipher.AEAD, metadata, data string) (string, error) {
 /*
 Decrypt decrypts a literal of the form <b64URLmetadata>.<b64URLciphertext>.<b64URLnonce> given an AEAD cipher and
 produces a metadata and data string.
+
+In the Migrate Mode both v0 and v1 literals are accepted; V0Only and V1Only accept only their own format.
+The padding of a padded v1 literal is removed.
 */
 func Decrypt(aeadCipher cipher.AEAD, literal string) (string, string, error) {
+	var (
+		a              = Audit{Op: OpOpen, KeyID: KeyID(aeadCipher)}
+		metadata, data string
+		err            error
+	)
+
+	metadata, data, err = decrypt(aeadCipher, literal, &a)
+	a.Outcome = outcome(a.Outcome, err)
+	audit(a)
+	return metadata, data, err
+}
+
+//decrypt implements Decrypt and sets the version, metadata hash, ciphertext size and any failure Outcome of its Audit
+func decrypt(aeadCipher cipher.AEAD, literal string, a *Audit) (string, string, error) {
 	var (
 		literalSubStrings []string
 		metadata          []byte
 		ciphertext        []byte
 		nonce             []byte
 		data              []byte
+		additional        []byte
+		version           string
+		v1                bool
+		m                 = GetMode()
 		err               error
 	)
 
 	//Split the literal into its base64 encoded metadata, ciphertext and nonce components
 	literalSubStrings = strings.Split(literal, ".")
-	if len(literalSubStrings) != 3 {
+	switch {
+	case len(literalSubStrings) == 4 && (literalSubStrings[0] == v1Prefix || literalSubStrings[0] == v1PaddedPrefix):
+		v1 = true
+		version = literalSubStrings[0]
+		literalSubStrings = literalSubStrings[1:]
+	case len(literalSubStrings) != 3:
+		a.Outcome = OutcomeMalformed
 		return "", "", fmt.Errorf("Bad AEAD Literal: %v\n", literal)
+	default:
+		version = v0Version
+	}
+	a.Version = version
+	switch {
+	case v1 && m == V0Only:
+		a.Outcome = OutcomeRejected
+		return "", "", fmt.Errorf("AEAD Literal Version v1 Not Accepted: %v\n", literal)
+	case !v1 && m == V1Only:
+		a.Outcome = OutcomeRejected
+		return "", "", fmt.Errorf("AEAD Literal Version v0 Not Accepted: %v\n", literal)
 	}
 
 	//Decode the metadata, ciphertext and nonce
 	metadata, err = base64.URLEncoding.DecodeString(literalSubStrings[0])
 	if err != nil {
+		a.Outcome = OutcomeMalformed
 		return "", "", fmt.Errorf("Decode metadata failed: %v\n", literal)
 	}
+	a.MetadataHash = metadataHash(string(metadata))
 	ciphertext, err = base64.URLEncoding.DecodeString(literalSubStrings[1])
 	if err != nil {
+		a.Outcome = OutcomeMalformed
 		return "", "", fmt.Errorf("Decode ciphertext failed: %v\n", literal)
 	}
+	a.CiphertextSize = len(ciphertext)
 	nonce, err = base64.URLEncoding.DecodeString(literalSubStrings[2])
 	if err != nil {
+		a.Outcome = OutcomeMalformed
 		return "", "", fmt.Errorf("Decode nonce failed: %v\n", literal)
 	}
 
 	//Open validates the integrity of the metadata using the authentication code in the ciphertext
 	//and, if valid, decrypts the ciphertext
-	data, err = aeadCipher.Open(data, nonce, ciphertext, metadata)
+	additional = metadata
+	if v1 {
+		additional = []byte(version + "." + string(metadata))
+	}
+	data, err = aeadCipher.Open(data, nonce, ciphertext, additional)
 	if err != nil {
+		a.Outcome = OutcomeAuthFailed
 		return "", "", err
 	}
+	if version == v1PaddedPrefix {
+		data, err = unpad(data)
+		if err != nil {
+			a.Outcome = OutcomeBadPadding
+			return "", "", err
+		}
+	}
+	if v1 {
+		atomic.AddUint64(&decryptedV1, 1)
+	} else {
+		atomic.AddUint64(&decryptedV0, 1)
+	}
 	return string(metadata), string(data), nil
 }
+
+/*
+Fingerprint returns a short identifier of a key that is safe to log or display: the first 8 bytes of its SHA-256 hash
+in hex. It is used to confirm which key is in use without exposing it.
+*/
+func Fingerprint(key []byte) string {
+	var hash = sha256.Sum256(key)
+	return hex.EncodeToString(hash[:8])
+}
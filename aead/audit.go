@@ -0,0 +1,147 @@
+package aead
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync/atomic"
+
+	"github.com/develrns/resilient/oplog"
+)
+
+/*
+Every Encrypt and Decrypt (and so EncryptFields and DecryptFields) is audited: the audit hook set by SetAuditHook, if
+any, is called with an Audit of the operation, so that security monitoring can detect e.g. a spike of authentication
+failures, which indicates tampering with literals. An Audit only has details that are safe to log: it has neither the
+data nor the metadata, and its size is the size of the ciphertext, which is exposed by the literal anyway, rather than
+the size of the data, which padding may be hiding.
+
+OplogAudit is a hook that logs each Audit as an aead.audit event in the operational log.
+*/
+
+//The audited operations
+const (
+	OpSeal = "seal"
+	OpOpen = "open"
+)
+
+//The Outcomes of audited operations
+const (
+	//OutcomeOK is a successful operation
+	OutcomeOK = "ok"
+
+	//OutcomeMalformed is a Decrypt of a literal that could not be parsed
+	OutcomeMalformed = "malformed"
+
+	//OutcomeRejected is a Decrypt of a literal whose version is not accepted by the Mode
+	OutcomeRejected = "rejected"
+
+	//OutcomeAuthFailed is a Decrypt of a literal that failed authentication: it was tampered with or sealed with another key
+	OutcomeAuthFailed = "auth_failed"
+
+	//OutcomeBadPadding is a Decrypt of an authenticated padded literal whose padding is bad
+	OutcomeBadPadding = "bad_padding"
+
+	//OutcomeError is any other failure
+	OutcomeError = "error"
+)
+
+//EventAudit is the name of the operational log event logged by OplogAudit
+const EventAudit = "aead.audit"
+
+//v0Version is the Audit Version of a v0 literal
+const v0Version = "v0"
+
+//An Audit describes an Encrypt or Decrypt operation
+type Audit struct {
+	//Op is OpSeal or OpOpen
+	Op string
+
+	//KeyID identifies the key of the cipher (see KeyID)
+	KeyID string
+
+	//MetadataHash is the hex SHA-256 hash of the metadata, empty if it could not be decoded
+	MetadataHash string
+
+	//CiphertextSize is the size in bytes of the sealed data, 0 if it could not be decoded
+	CiphertextSize int
+
+	//Version is the literal format version: v0, v1 or v1p; empty if it is not known
+	Version string
+
+	//Outcome is OutcomeOK or the kind of failure
+	Outcome string
+}
+
+//auditHook holds the audit hook; it is an atomic.Value since it may be set while literals are processed
+var auditHook atomic.Value
+
+func init() {
+	auditHook.Store(func(Audit) {})
+	oplog.RegisterEvent(EventAudit, map[string]oplog.Class{
+		"op": oplog.Public, "keyID": oplog.Public, "metadataHash": oplog.Public, "ciphertextSize": oplog.Public,
+		"version": oplog.Public, "outcome": oplog.Public,
+	})
+}
+
+/*
+SetAuditHook sets the function that is called with the Audit of every Encrypt and Decrypt; nil disables auditing. The
+hook is called synchronously by the goroutine that encrypts or decrypts, so it must be fast and safe for concurrent use.
+*/
+func SetAuditHook(hook func(Audit)) {
+	if hook == nil {
+		hook = func(Audit) {}
+	}
+	auditHook.Store(hook)
+}
+
+//audit calls the audit hook
+func audit(a Audit) {
+	auditHook.Load().(func(Audit))(a)
+}
+
+//outcome returns the Outcome of an operation given the Outcome set by the failure, if any, and its error
+func outcome(failure string, err error) string {
+	switch {
+	case err == nil:
+		return OutcomeOK
+	case failure != "":
+		return failure
+	default:
+		return OutcomeError
+	}
+}
+
+//metadataHash returns the hex SHA-256 hash of metadata
+func metadataHash(metadata string) string {
+	var hash = sha256.Sum256([]byte(metadata))
+	return hex.EncodeToString(hash[:])
+}
+
+//keyedAEAD is a cipher created by NewAEADCipher, which knows the KeyID of its key
+type keyedAEAD struct {
+	cipher.AEAD
+	keyID string
+}
+
+/*
+KeyID returns the Fingerprint of the key of a cipher created by NewAEADCipher (or a Handshake), or "" for any other
+cipher.
+*/
+func KeyID(aeadCipher cipher.AEAD) string {
+	if keyed, ok := aeadCipher.(keyedAEAD); ok {
+		return keyed.keyID
+	}
+	return ""
+}
+
+/*
+OplogAudit is an audit hook that logs each Audit as an aead.audit event, all of whose fields are Public. Since every
+operation is logged, it is meant for services whose operational log volume allows it.
+*/
+func OplogAudit(a Audit) {
+	oplog.Logger().Event(EventAudit, map[string]interface{}{
+		"op": a.Op, "keyID": a.KeyID, "metadataHash": a.MetadataHash, "ciphertextSize": a.CiphertextSize,
+		"version": a.Version, "outcome": a.Outcome,
+	})
+}
@@ -0,0 +1,109 @@
+package aead
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"strings"
+	"sync"
+	"testing"
+)
+
+//recordAudits sets an audit hook that records the Audits until the test ends
+func recordAudits(test *testing.T) func() []Audit {
+	var (
+		m      sync.Mutex
+		audits []Audit
+	)
+
+	SetAuditHook(func(a Audit) {
+		m.Lock()
+		defer m.Unlock()
+		audits = append(audits, a)
+	})
+	test.Cleanup(func() { SetAuditHook(nil) })
+	return func() []Audit {
+		m.Lock()
+		defer m.Unlock()
+		recorded := audits
+		audits = nil
+		return recorded
+	}
+}
+
+func TestAudit(test *testing.T) {
+	var (
+		audits        = recordAudits(test)
+		aeadCipher, _ = NewAEADCipher(nil)
+		other, _      = NewAEADCipher(nil)
+		literal       string
+		padded        string
+		err           error
+	)
+
+	setMode(test, Migrate)
+	literal, err = Encrypt(aeadCipher, "m", "data")
+	if err == nil {
+		padded, err = Encrypt(aeadCipher, "m", "data", PadPow2(16))
+	}
+	if err != nil {
+		test.Fatal(err)
+	}
+	seals := audits()
+	if len(seals) != 2 || seals[0].Op != OpSeal || seals[0].Outcome != OutcomeOK || seals[0].Version != "v1" || seals[1].Version != "v1p" {
+		test.Fatalf("Seal Audits: %+v", seals)
+	}
+	if seals[0].KeyID != KeyID(aeadCipher) || seals[0].KeyID == "" || seals[0].MetadataHash != metadataHash("m") || seals[0].CiphertextSize != len("data")+aeadCipher.Overhead() {
+		test.Errorf("Seal Audit: %+v", seals[0])
+	}
+
+	//Each failure of Decrypt has its own Outcome; badPadding is authentic but its data has no padding
+	parts := strings.Split(literal, ".")
+	nonce := make([]byte, aeadCipher.NonceSize())
+	badPadding := "v1p." + parts[1] + "." + base64.URLEncoding.EncodeToString(aeadCipher.Seal(nil, nonce, []byte("data"), []byte("v1p.m"))) + "." + base64.URLEncoding.EncodeToString(nonce)
+	cases := []struct {
+		aeadCipher cipher.AEAD
+		literal    string
+		mode       Mode
+		outcome    string
+	}{
+		{aeadCipher, literal, Migrate, OutcomeOK},
+		{aeadCipher, padded, Migrate, OutcomeOK},
+		{aeadCipher, "a.b", Migrate, OutcomeMalformed},
+		{aeadCipher, "v1.!." + parts[2] + "." + parts[3], Migrate, OutcomeMalformed},
+		{aeadCipher, literal, V0Only, OutcomeRejected},
+		{other, literal, Migrate, OutcomeAuthFailed},
+		{aeadCipher, strings.Join(parts[1:], "."), Migrate, OutcomeAuthFailed},
+		{aeadCipher, badPadding, Migrate, OutcomeBadPadding},
+	}
+	for _, c := range cases {
+		SetMode(c.mode)
+		Decrypt(c.aeadCipher, c.literal)
+		opens := audits()
+		if len(opens) != 1 || opens[0].Op != OpOpen || opens[0].Outcome != c.outcome || opens[0].KeyID != KeyID(c.aeadCipher) {
+			test.Errorf("Open Audit of %v in Mode %v: %+v", c.literal, c.mode, opens)
+		}
+	}
+
+	//Padding that is not allowed by the Mode is an error
+	SetMode(V0Only)
+	Encrypt(aeadCipher, "m", "data", PadPow2(16))
+	if seals = audits(); len(seals) != 1 || seals[0].Outcome != OutcomeError {
+		test.Errorf("Audit of a failed seal: %+v", seals)
+	}
+
+	//A cipher not created by NewAEADCipher has no KeyID
+	block, _ := aes.NewCipher(make([]byte, 16))
+	gcm, _ := cipher.NewGCM(block)
+	Encrypt(gcm, "m", "data")
+	if seals = audits(); len(seals) != 1 || seals[0].KeyID != "" || seals[0].Outcome != OutcomeOK {
+		test.Errorf("Audit of a cipher without a KeyID: %+v", seals)
+	}
+
+	//A nil hook disables auditing
+	SetAuditHook(nil)
+	Encrypt(aeadCipher, "m", "data")
+	if seals = audits(); len(seals) != 0 {
+		test.Errorf("Audits without a hook: %+v", seals)
+	}
+}
@@ -0,0 +1,147 @@
+package aead
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+A Handshake derives a session AEAD cipher shared by two services. Each side creates a Handshake, sends its PublicKey
+to the other over any transport and then calls Complete with the peer's public key. Both sides derive the same
+AES-256 key from an X25519 ephemeral key agreement and HKDF-SHA256.
+
+Since the X25519 keys are ephemeral and the private key is discarded once Complete is called, a later compromise of
+either service does not expose the keys of past sessions (forward secrecy).
+
+Note that the handshake does not authenticate the peer; it must be run over a channel that has already been
+established with the intended peer (e.g. mutually authenticated TLS).
+
+The handshake uses the crypto/ecdh and crypto/hkdf packages, so the aead package requires Go 1.24 or later.
+*/
+type Handshake struct {
+	privateKey *ecdh.PrivateKey
+}
+
+/*
+NewHandshake generates the ephemeral X25519 key pair for a session.
+*/
+func NewHandshake() (*Handshake, error) {
+	var (
+		handshake Handshake
+		err       error
+	)
+
+	handshake.privateKey, err = ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &handshake, nil
+}
+
+/*
+PublicKey returns the b64URL encoded public key that is sent to the peer.
+*/
+func (h *Handshake) PublicKey() string {
+	return base64.URLEncoding.EncodeToString(h.privateKey.PublicKey().Bytes())
+}
+
+/*
+Complete derives the session AEAD cipher given the peer's b64URL encoded public key.
+The info string binds the key to its use (e.g. "oidc-session-v1") and must be the same on both sides.
+A Handshake can only be completed once.
+*/
+func (h *Handshake) Complete(peerPublicKey, info string) (cipher.AEAD, error) {
+	var (
+		peerKeyBytes []byte
+		peerKey      *ecdh.PublicKey
+		publicKey    []byte
+		salt         []byte
+		secret       []byte
+		key          []byte
+		err          error
+	)
+
+	if h.privateKey == nil {
+		return nil, fmt.Errorf("Handshake has already been completed")
+	}
+
+	peerKeyBytes, err = base64.URLEncoding.DecodeString(peerPublicKey)
+	if err != nil {
+		return nil, fmt.Errorf("Decode peer public key failed: %v", err)
+	}
+	peerKey, err = ecdh.X25519().NewPublicKey(peerKeyBytes)
+	if err != nil {
+		return nil, fmt.Errorf("Bad peer public key: %v", err)
+	}
+
+	secret, err = h.privateKey.ECDH(peerKey)
+	if err != nil {
+		return nil, err
+	}
+
+	//Both sides must use the same salt so the public keys are concatenated in byte order
+	publicKey = h.privateKey.PublicKey().Bytes()
+	if bytes.Compare(publicKey, peerKeyBytes) < 0 {
+		salt = append(append(salt, publicKey...), peerKeyBytes...)
+	} else {
+		salt = append(append(salt, peerKeyBytes...), publicKey...)
+	}
+
+	//The private key is discarded to provide forward secrecy
+	h.privateKey = nil
+
+	key, err = hkdf.Key(sha256.New, secret, salt, info, 32)
+	if err != nil {
+		return nil, err
+	}
+	return NewAEADCipher(key)
+}
+
+/*
+Exchange runs a complete handshake over a transport such as a net.Conn. Each side writes its public key as a
+newline terminated line and reads exactly the peer's line, leaving any following traffic unread.
+Both sides must call Exchange with the same info string.
+
+Exchange returns once its write has completed, even if its read fails, so that it does not leave a goroutine writing
+to the transport; a transport whose writes can block indefinitely should have a write deadline.
+*/
+func Exchange(rw io.ReadWriter, info string) (cipher.AEAD, error) {
+	var (
+		handshake *Handshake
+		writeErr  = make(chan error, 1)
+		line      = make([]byte, base64.URLEncoding.EncodedLen(32)+1)
+		err       error
+	)
+
+	handshake, err = NewHandshake()
+	if err != nil {
+		return nil, err
+	}
+
+	//The write is done concurrently with the read so that synchronous transports do not deadlock
+	go func() {
+		_, err := io.WriteString(rw, handshake.PublicKey()+"\n")
+		writeErr <- err
+	}()
+
+	//Exactly one line is read so no bytes that follow the handshake are consumed
+	_, err = io.ReadFull(rw, line)
+	if err != nil {
+		<-writeErr
+		return nil, fmt.Errorf("Read peer public key failed: %v", err)
+	}
+	err = <-writeErr
+	if err != nil {
+		return nil, fmt.Errorf("Write public key failed: %v", err)
+	}
+
+	return handshake.Complete(strings.TrimSpace(string(line)), info)
+}
@@ -0,0 +1,116 @@
+package aead
+
+import (
+	"crypto/cipher"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestExchange(test *testing.T) {
+	var (
+		a, b    = net.Pipe()
+		aeads   [2]cipher.AEAD
+		results = make(chan error, 2)
+	)
+	defer a.Close()
+	defer b.Close()
+
+	for i, conn := range []net.Conn{a, b} {
+		go func(i int, conn net.Conn) {
+			aead, err := Exchange(conn, "test-v1")
+			if err == nil {
+				aeads[i] = aead
+			}
+			results <- err
+		}(i, conn)
+	}
+	for i := 0; i < 2; i++ {
+		if err := <-results; err != nil {
+			test.Fatalf("Exchange: %v", err)
+		}
+	}
+
+	//Both sides derive the same key, so one side opens what the other seals
+	nonce := make([]byte, aeads[0].NonceSize())
+	sealed := aeads[0].Seal(nil, nonce, []byte("hello"), nil)
+	if opened, err := aeads[1].Open(nil, nonce, sealed, nil); err != nil || string(opened) != "hello" {
+		test.Errorf("Exchange derived different keys: %q %v", opened, err)
+	}
+
+	//The traffic that follows the handshake is left unread
+	go a.Write([]byte("after"))
+	after := make([]byte, 5)
+	if _, err := io.ReadFull(b, after); err != nil || string(after) != "after" {
+		test.Errorf("Read after Exchange: %q %v", after, err)
+	}
+}
+
+func TestExchangeInfo(test *testing.T) {
+	var (
+		a, b    = net.Pipe()
+		results = make(chan []byte, 2)
+	)
+	defer a.Close()
+	defer b.Close()
+
+	for conn, info := range map[net.Conn]string{a: "one", b: "two"} {
+		go func(conn net.Conn, info string) {
+			aead, err := Exchange(conn, info)
+			if err != nil {
+				results <- nil
+				return
+			}
+			results <- aead.Seal(nil, make([]byte, 12), []byte("hello"), nil)
+		}(conn, info)
+	}
+	if sealed := [][]byte{<-results, <-results}; sealed[0] == nil || string(sealed[0]) == string(sealed[1]) {
+		test.Errorf("Exchange with different info strings derived the same key")
+	}
+}
+
+// slowWriter is a transport whose reads fail and whose writes complete after a delay
+type slowWriter struct {
+	written int32
+}
+
+func (sw *slowWriter) Read([]byte) (int, error) {
+	return 0, errors.New("read failed")
+}
+
+func (sw *slowWriter) Write(p []byte) (int, error) {
+	time.Sleep(20 * time.Millisecond)
+	atomic.StoreInt32(&sw.written, 1)
+	return len(p), nil
+}
+
+func TestExchangeReadFailure(test *testing.T) {
+	var sw slowWriter
+
+	if _, err := Exchange(&sw, "test-v1"); err == nil {
+		test.Fatalf("Exchange with a failing read should fail")
+	}
+	if atomic.LoadInt32(&sw.written) != 1 {
+		test.Errorf("Exchange returned before its write completed")
+	}
+
+	//The peer of a closed pipe fails without leaving its write blocked
+	a, b := net.Pipe()
+	b.Close()
+	done := make(chan error, 1)
+	go func() {
+		_, err := Exchange(a, "test-v1")
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			test.Errorf("Exchange with a closed peer should fail")
+		}
+	case <-time.After(time.Second):
+		test.Errorf("Exchange with a closed peer did not return")
+	}
+}
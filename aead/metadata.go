@@ -0,0 +1,82 @@
+package aead
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"encoding/json"
+	"fmt"
+)
+
+/*
+Structured metadata replaces a free-form metadata string, which every caller formats and parses in its own way, with
+named fields. The fields are serialized as canonical JSON: a compact object with sorted keys and no HTML escaping, so
+that the same fields are always the same authenticated metadata.
+
+EncryptFields and DecryptFields are Encrypt and Decrypt with the metadata as a map of fields.
+*/
+
+/*
+EncodeMetadata serializes metadata fields as canonical JSON.
+*/
+func EncodeMetadata(fields map[string]string) string {
+	var buf bytes.Buffer
+
+	if fields == nil {
+		fields = map[string]string{}
+	}
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+
+	//A map[string]string always encodes; encoding/json sorts its keys
+	_ = enc.Encode(fields)
+	return string(bytes.TrimSuffix(buf.Bytes(), []byte("\n")))
+}
+
+/*
+ParseMetadata parses metadata serialized by EncodeMetadata. Metadata that is not a canonical JSON object of strings
+is rejected.
+*/
+func ParseMetadata(metadata string) (map[string]string, error) {
+	var (
+		fields map[string]string
+		err    error
+	)
+
+	err = json.Unmarshal([]byte(metadata), &fields)
+	if err != nil || fields == nil {
+		return nil, fmt.Errorf("Bad AEAD Metadata: %v\n", metadata)
+	}
+	if EncodeMetadata(fields) != metadata {
+		return nil, fmt.Errorf("AEAD Metadata Not Canonical: %v\n", metadata)
+	}
+	return fields, nil
+}
+
+/*
+EncryptFields is Encrypt with metadata fields.
+*/
+func EncryptFields(aeadCipher cipher.AEAD, fields map[string]string, data string, opts ...Option) (string, error) {
+	return Encrypt(aeadCipher, EncodeMetadata(fields), data, opts...)
+}
+
+/*
+DecryptFields is Decrypt of a literal whose metadata was encrypted by EncryptFields. It returns the parsed metadata fields.
+*/
+func DecryptFields(aeadCipher cipher.AEAD, literal string) (map[string]string, string, error) {
+	var (
+		metadata string
+		fields   map[string]string
+		data     string
+		err      error
+	)
+
+	metadata, data, err = Decrypt(aeadCipher, literal)
+	if err != nil {
+		return nil, "", err
+	}
+	fields, err = ParseMetadata(metadata)
+	if err != nil {
+		return nil, "", err
+	}
+	return fields, data, nil
+}
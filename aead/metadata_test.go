@@ -0,0 +1,58 @@
+package aead
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMetadata(test *testing.T) {
+	var cases = []struct {
+		fields   map[string]string
+		metadata string
+	}{
+		{nil, `{}`},
+		{map[string]string{}, `{}`},
+		{map[string]string{"type": "Session", "alg": "A<B>&C"}, `{"alg":"A<B>&C","type":"Session"}`},
+		{map[string]string{"b": "\"quoted\"\n", "a": "é"}, `{"a":"é","b":"\"quoted\"\n"}`},
+	}
+
+	for _, c := range cases {
+		metadata := EncodeMetadata(c.fields)
+		if metadata != c.metadata {
+			test.Errorf("EncodeMetadata %v: %v", c.fields, metadata)
+		}
+		fields, err := ParseMetadata(metadata)
+		if err != nil || len(fields) != len(c.fields) || (len(fields) > 0 && !reflect.DeepEqual(fields, c.fields)) {
+			test.Errorf("ParseMetadata %v: %v %v", metadata, fields, err)
+		}
+	}
+
+	//Metadata that is not canonical is rejected, since it would authenticate the same fields differently
+	for _, metadata := range []string{``, `null`, `[]`, `{"a":1}`, `{"b":"1","a":"2"}`, `{"a": "1"}`, `{"a":"\u003c"}`, `{"a":"1"} `} {
+		if fields, err := ParseMetadata(metadata); err == nil {
+			test.Errorf("ParseMetadata %v: %v", metadata, fields)
+		}
+	}
+}
+
+func TestEncryptFields(test *testing.T) {
+	var (
+		aeadCipher, _ = NewAEADCipher(nil)
+		fields        = map[string]string{"type": "Session", "kid": "k1"}
+	)
+
+	literal, err := EncryptFields(aeadCipher, fields, "d")
+	if err != nil {
+		test.Fatal(err)
+	}
+	decrypted, data, err := DecryptFields(aeadCipher, literal)
+	if err != nil || data != "d" || !reflect.DeepEqual(decrypted, fields) {
+		test.Errorf("DecryptFields: %v %q %v", decrypted, data, err)
+	}
+
+	//A literal whose metadata is not fields fails, although it is authentic
+	literal, _ = Encrypt(aeadCipher, "type=Session", "d")
+	if _, _, err = DecryptFields(aeadCipher, literal); err == nil {
+		test.Errorf("DecryptFields of free-form metadata")
+	}
+}
@@ -0,0 +1,94 @@
+package aead
+
+import (
+	"fmt"
+)
+
+/*
+Padding hides the length of the data of a literal, e.g. of a sealed session cookie whose size would otherwise reveal
+which policy or claim set a user has. The data is padded (ISO/IEC 7816-4: a 0x80 byte followed by zero bytes) to a
+bucketed length before it is sealed, and the padding is removed by Decrypt.
+
+A padded literal is a v1 literal whose version element is v1p:
+
+	v1p.<b64URLmetadata>.<b64URLciphertext>.<b64URLnonce>
+
+Like the v1 version, the v1p version is authenticated, so the padding cannot be made ambiguous by changing it.
+Since it is a v1 format, padding requires the Migrate or V1Only Mode.
+*/
+
+//v1PaddedPrefix is the version element of a padded v1 literal
+const v1PaddedPrefix = "v1p"
+
+//An Option configures Encrypt
+type Option func(*encryptOptions)
+
+//encryptOptions holds the configuration set by a list of Options
+type encryptOptions struct {
+	bucket func(n int) int
+}
+
+/*
+PadPow2 pads the data to the next power of two that is at least min bytes and longer than the data. Larger values of
+min hide more of the length variation of small data at the cost of longer literals.
+*/
+func PadPow2(min int) Option {
+	return func(o *encryptOptions) {
+		o.bucket = func(n int) int {
+			var size = 1
+
+			for size < min || size <= n {
+				size <<= 1
+			}
+			return size
+		}
+	}
+}
+
+/*
+PadMultiple pads the data to the next multiple of size bytes that is longer than the data. It grows literals less
+than PadPow2 but hides less of the length of large data.
+*/
+func PadMultiple(size int) Option {
+	return func(o *encryptOptions) {
+		o.bucket = func(n int) int {
+			if size < 1 {
+				return n + 1
+			}
+			return (n/size + 1) * size
+		}
+	}
+}
+
+//newEncryptOptions applies a list of Options to the default options
+func newEncryptOptions(opts []Option) *encryptOptions {
+	var o encryptOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &o
+}
+
+//pad pads data to its bucket length
+func pad(data []byte, bucket func(n int) int) []byte {
+	var padded = make([]byte, bucket(len(data)))
+
+	copy(padded, data)
+	padded[len(data)] = 0x80
+	return padded
+}
+
+//unpad removes the padding of padded data, which is zero bytes preceded by a 0x80 byte
+func unpad(padded []byte) ([]byte, error) {
+	for i := len(padded) - 1; i >= 0; i-- {
+		switch padded[i] {
+		case 0:
+		case 0x80:
+			return padded[:i], nil
+		default:
+			return nil, fmt.Errorf("Bad AEAD Padding\n")
+		}
+	}
+	return nil, fmt.Errorf("Bad AEAD Padding\n")
+}
@@ -0,0 +1,48 @@
+package aead
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPadUnpad(test *testing.T) {
+	var buckets = []struct {
+		opt   Option
+		sizes map[int]int
+	}{
+		{PadPow2(16), map[int]int{0: 16, 15: 16, 16: 32, 100: 128}},
+		{PadMultiple(10), map[int]int{0: 10, 9: 10, 10: 20, 25: 30}},
+		{PadMultiple(0), map[int]int{0: 1, 7: 8}},
+	}
+
+	for _, b := range buckets {
+		o := newEncryptOptions([]Option{b.opt})
+		for n, size := range b.sizes {
+			data := bytes.Repeat([]byte{0x80}, n)
+			padded := pad(data, o.bucket)
+			if len(padded) != size {
+				test.Errorf("pad of %v bytes: %v bytes, not %v", n, len(padded), size)
+			}
+			unpadded, err := unpad(padded)
+			if err != nil || !bytes.Equal(unpadded, data) {
+				test.Errorf("unpad of %v bytes: %v %v", n, unpadded, err)
+			}
+		}
+	}
+}
+
+func TestUnpadBad(test *testing.T) {
+	var cases = [][]byte{
+		nil,
+		{0, 0, 0},
+		{'a', 'b', 0x01, 0, 0},
+		{0x80, 'a', 0},
+		{'a', 0x80, 'b'},
+	}
+
+	for _, padded := range cases {
+		if unpadded, err := unpad(padded); err == nil {
+			test.Errorf("unpad of %v should fail: %v", padded, unpadded)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package aead
+
+import (
+	"sync/atomic"
+)
+
+/*
+Literal formats are versioned so that the format can evolve:
+
+	v0: <b64URLmetadata>.<b64URLciphertext>.<b64URLnonce>
+	v1: v1.<b64URLmetadata>.<b64URLciphertext>.<b64URLnonce>
+
+A v1 literal also authenticates its version prefix, so it cannot be downgraded to a v0 literal by stripping it.
+
+The Mode controls the rollout of v1. During Migrate, Encrypt emits v1 literals while Decrypt accepts both, and the
+DecryptCounts telemetry shows when v0 literals are no longer seen, i.e. when it is safe to switch to V1Only.
+*/
+type Mode int32
+
+//The literal format Modes
+const (
+	//V0Only emits and accepts only v0 literals (the default)
+	V0Only Mode = iota
+
+	//Migrate emits v1 literals and accepts both v0 and v1 literals
+	Migrate
+
+	//V1Only emits and accepts only v1 literals
+	V1Only
+)
+
+//v1Prefix is the version element of a v1 literal
+const v1Prefix = "v1"
+
+//mode is the current Mode; it is accessed atomically since it may be changed while literals are processed
+var mode int32
+
+//The counts of the literals decrypted by version
+var decryptedV0, decryptedV1 uint64
+
+/*
+SetMode sets the literal format Mode used by Encrypt and Decrypt.
+*/
+func SetMode(m Mode) {
+	atomic.StoreInt32(&mode, int32(m))
+}
+
+/*
+GetMode returns the literal format Mode.
+*/
+func GetMode() Mode {
+	return Mode(atomic.LoadInt32(&mode))
+}
+
+/*
+DecryptCounts returns the number of v0 and v1 literals that have been successfully decrypted.
+*/
+func DecryptCounts() (v0, v1 uint64) {
+	return atomic.LoadUint64(&decryptedV0), atomic.LoadUint64(&decryptedV1)
+}
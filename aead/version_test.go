@@ -0,0 +1,93 @@
+package aead
+
+import (
+	"strings"
+	"testing"
+)
+
+//setMode sets the Mode until the test ends
+func setMode(test *testing.T, m Mode) {
+	var replaced = GetMode()
+
+	SetMode(m)
+	test.Cleanup(func() { SetMode(replaced) })
+}
+
+func TestModes(test *testing.T) {
+	var (
+		aeadCipher, _ = NewAEADCipher(nil)
+		literals      = make(map[Mode]string)
+		accepted      = map[Mode][]bool{
+			//Whether the Mode accepts the literals of V0Only, Migrate and V1Only
+			V0Only:  {true, false, false},
+			Migrate: {true, true, true},
+			V1Only:  {false, true, true},
+		}
+		err error
+	)
+
+	for _, m := range []Mode{V0Only, Migrate, V1Only} {
+		setMode(test, m)
+		literals[m], err = Encrypt(aeadCipher, "m", "d")
+		if err != nil || strings.HasPrefix(literals[m], "v1.") != (m != V0Only) {
+			test.Fatalf("Encrypt in Mode %v: %v %v", m, literals[m], err)
+		}
+	}
+	for m, accepts := range accepted {
+		setMode(test, m)
+		for sealedIn, accept := range accepts {
+			metadata, data, err := Decrypt(aeadCipher, literals[Mode(sealedIn)])
+			if accept != (err == nil) || (accept && (metadata != "m" || data != "d")) {
+				test.Errorf("Decrypt in Mode %v of a literal of Mode %v: %q %q %v", m, sealedIn, metadata, data, err)
+			}
+		}
+	}
+}
+
+func TestDowngrade(test *testing.T) {
+	var (
+		aeadCipher, _ = NewAEADCipher(nil)
+		literal       string
+		err           error
+	)
+
+	setMode(test, Migrate)
+	literal, err = Encrypt(aeadCipher, "m", "d")
+	if err != nil {
+		test.Fatal(err)
+	}
+
+	//Stripping the version of a v1 literal makes a v0 literal that fails authentication, even where v0 is accepted
+	if _, _, err = Decrypt(aeadCipher, strings.TrimPrefix(literal, "v1.")); err == nil {
+		test.Errorf("Decrypt of a downgraded literal")
+	}
+	if _, _, err = Decrypt(aeadCipher, "v1p."+strings.TrimPrefix(literal, "v1.")); err == nil {
+		test.Errorf("Decrypt of a literal whose version was changed")
+	}
+}
+
+func TestDecryptCounts(test *testing.T) {
+	var (
+		aeadCipher, _ = NewAEADCipher(nil)
+		v0Literal     string
+		v1Literal     string
+	)
+
+	setMode(test, V0Only)
+	v0Literal, _ = Encrypt(aeadCipher, "m", "d")
+	SetMode(Migrate)
+	v1Literal, _ = Encrypt(aeadCipher, "m", "d")
+
+	//Only successful decryptions are counted
+	v0, v1 := DecryptCounts()
+	Decrypt(aeadCipher, v0Literal)
+	Decrypt(aeadCipher, v1Literal)
+	Decrypt(aeadCipher, v1Literal)
+	Decrypt(aeadCipher, v1Literal+"x")
+	Decrypt(aeadCipher, "bad")
+	SetMode(V1Only)
+	Decrypt(aeadCipher, v0Literal)
+	if v0After, v1After := DecryptCounts(); v0After-v0 != 1 || v1After-v1 != 2 {
+		test.Errorf("DecryptCounts: %v %v", v0After-v0, v1After-v1)
+	}
+}
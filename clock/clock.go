@@ -0,0 +1,199 @@
+/*
+Package clock is an injectable time source. Time dependent code gets the time, timeouts and tickers from a Clock
+rather than from the time package, so that tests can replace the Real clock with a Fake one whose time only moves when
+it is advanced, instead of waiting for real time to pass.
+
+The packages of this repository that have time dependent behavior (e.g. poll's State purging and push backoff, and
+log's timestamps and stack trace deduplication window) use the Real clock unless they are given another by their
+SetClock function.
+*/
+package clock
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+A Clock is a source of time.
+*/
+type Clock interface {
+	//Now returns the current time
+	Now() time.Time
+
+	//After returns a channel that receives the current time once d has elapsed
+	After(d time.Duration) <-chan time.Time
+
+	//NewTicker returns a Ticker that sends the current time every d; d must be positive
+	NewTicker(d time.Duration) Ticker
+}
+
+/*
+A Ticker delivers ticks at intervals. Like a time.Ticker, it drops ticks for a slow receiver.
+*/
+type Ticker interface {
+	//C returns the channel on which the ticks are delivered
+	C() <-chan time.Time
+
+	//Stop turns off the Ticker
+	Stop()
+}
+
+//Real is the Clock of the time package
+var Real Clock = realClock{}
+
+//realClock delegates to the time package
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return realTicker{time.NewTicker(d)}
+}
+
+//realTicker adapts a time.Ticker to a Ticker
+type realTicker struct {
+	t *time.Ticker
+}
+
+func (rt realTicker) C() <-chan time.Time {
+	return rt.t.C
+}
+
+func (rt realTicker) Stop() {
+	rt.t.Stop()
+}
+
+/*
+A Fake is a Clock whose time only changes when it is advanced. Its timeouts and tickers fire as Advance moves its
+time past them. It is safe for concurrent use.
+*/
+type Fake struct {
+	m       sync.Mutex
+	now     time.Time
+	waiters []*waiter
+}
+
+//A waiter is a pending After timeout or a Ticker of a Fake
+type waiter struct {
+	when    time.Time
+	period  time.Duration
+	c       chan time.Time
+	stopped bool
+}
+
+/*
+NewFake creates a Fake whose time is now.
+*/
+func NewFake(now time.Time) *Fake {
+	return &Fake{now: now}
+}
+
+/*
+Now returns the Fake's time.
+*/
+func (f *Fake) Now() time.Time {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.now
+}
+
+/*
+After returns a channel that receives the Fake's time once it has been advanced by d.
+*/
+func (f *Fake) After(d time.Duration) <-chan time.Time {
+	var w = &waiter{c: make(chan time.Time, 1)}
+
+	f.m.Lock()
+	defer f.m.Unlock()
+	w.when = f.now.Add(d)
+	if d <= 0 {
+		w.c <- f.now
+		return w.c
+	}
+	f.waiters = append(f.waiters, w)
+	return w.c
+}
+
+/*
+NewTicker returns a Ticker that ticks each time the Fake is advanced past another d.
+*/
+func (f *Fake) NewTicker(d time.Duration) Ticker {
+	var w = &waiter{period: d, c: make(chan time.Time, 1)}
+
+	if d <= 0 {
+		panic("non-positive interval for NewTicker")
+	}
+	f.m.Lock()
+	defer f.m.Unlock()
+	w.when = f.now.Add(d)
+	f.waiters = append(f.waiters, w)
+	return &fakeTicker{f: f, w: w}
+}
+
+/*
+Advance moves the Fake's time forward by d, firing the timeouts and ticks that fall due.
+*/
+func (f *Fake) Advance(d time.Duration) {
+	var pending []*waiter
+
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.now = f.now.Add(d)
+	for _, w := range f.waiters {
+		for !w.stopped && !w.when.After(f.now) {
+			select {
+			case w.c <- w.when:
+			default:
+			}
+			if w.period == 0 {
+				w.stopped = true
+				break
+			}
+			w.when = w.when.Add(w.period)
+		}
+		if !w.stopped {
+			pending = append(pending, w)
+		}
+	}
+	f.waiters = pending
+}
+
+/*
+Waiters returns the number of pending timeouts and running tickers. A test can poll it to know that the code under
+test is waiting on the Fake before advancing it.
+*/
+func (f *Fake) Waiters() int {
+	var n int
+
+	f.m.Lock()
+	defer f.m.Unlock()
+	for _, w := range f.waiters {
+		if !w.stopped {
+			n++
+		}
+	}
+	return n
+}
+
+//fakeTicker is a Ticker of a Fake
+type fakeTicker struct {
+	f *Fake
+	w *waiter
+}
+
+func (ft *fakeTicker) C() <-chan time.Time {
+	return ft.w.c
+}
+
+func (ft *fakeTicker) Stop() {
+	ft.f.m.Lock()
+	defer ft.f.m.Unlock()
+	ft.w.stopped = true
+}
@@ -0,0 +1,119 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+//fired returns the time received from a channel, and false if it has not fired
+func fired(c <-chan time.Time) (time.Time, bool) {
+	select {
+	case t := <-c:
+		return t, true
+	default:
+		return time.Time{}, false
+	}
+}
+
+func TestFakeAfter(test *testing.T) {
+	var (
+		start = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		fake  = NewFake(start)
+		soon  = fake.After(time.Second)
+		later = fake.After(time.Minute)
+	)
+
+	if fake.Waiters() != 2 {
+		test.Errorf("Waiters: %v", fake.Waiters())
+	}
+	fake.Advance(999 * time.Millisecond)
+	if _, ok := fired(soon); ok {
+		test.Errorf("After fired early")
+	}
+
+	//A timeout fires once, with its due time rather than the time it was advanced to
+	fake.Advance(2 * time.Second)
+	if t, ok := fired(soon); !ok || !t.Equal(start.Add(time.Second)) {
+		test.Errorf("After 1s: %v %v", t, ok)
+	}
+	if _, ok := fired(later); ok || fake.Waiters() != 1 {
+		test.Errorf("After 1m fired early: %v", fake.Waiters())
+	}
+	if !fake.Now().Equal(start.Add(2999 * time.Millisecond)) {
+		test.Errorf("Now: %v", fake.Now())
+	}
+	fake.Advance(time.Hour)
+	if t, ok := fired(later); !ok || !t.Equal(start.Add(time.Minute)) || fake.Waiters() != 0 {
+		test.Errorf("After 1m: %v %v %v", t, ok, fake.Waiters())
+	}
+	if _, ok := fired(soon); ok {
+		test.Errorf("After fired twice")
+	}
+
+	//A non-positive timeout fires immediately
+	if t, ok := fired(fake.After(0)); !ok || !t.Equal(fake.Now()) || fake.Waiters() != 0 {
+		test.Errorf("After 0: %v %v", t, ok)
+	}
+}
+
+func TestFakeTicker(test *testing.T) {
+	var (
+		start  = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		fake   = NewFake(start)
+		ticker = fake.NewTicker(time.Second)
+	)
+
+	fake.Advance(500 * time.Millisecond)
+	if _, ok := fired(ticker.C()); ok {
+		test.Errorf("Ticker ticked early")
+	}
+	fake.Advance(500 * time.Millisecond)
+	if t, ok := fired(ticker.C()); !ok || !t.Equal(start.Add(time.Second)) {
+		test.Errorf("First tick: %v %v", t, ok)
+	}
+
+	//Like a time.Ticker, a Ticker drops the ticks of a slow receiver
+	fake.Advance(3 * time.Second)
+	if t, ok := fired(ticker.C()); !ok || !t.Equal(start.Add(2*time.Second)) {
+		test.Errorf("Tick of a slow receiver: %v %v", t, ok)
+	}
+	if _, ok := fired(ticker.C()); ok {
+		test.Errorf("Dropped ticks were delivered")
+	}
+	fake.Advance(time.Second)
+	if t, ok := fired(ticker.C()); !ok || !t.Equal(start.Add(5*time.Second)) {
+		test.Errorf("Tick after the dropped ticks: %v %v", t, ok)
+	}
+
+	ticker.Stop()
+	fake.Advance(time.Minute)
+	if _, ok := fired(ticker.C()); ok || fake.Waiters() != 0 {
+		test.Errorf("Stopped Ticker ticked: %v", fake.Waiters())
+	}
+
+	defer func() {
+		if recover() == nil {
+			test.Errorf("NewTicker of a non-positive interval should panic")
+		}
+	}()
+	fake.NewTicker(0)
+}
+
+func TestReal(test *testing.T) {
+	var ticker = Real.NewTicker(time.Millisecond)
+	defer ticker.Stop()
+
+	if d := time.Since(Real.Now()); d < 0 || d > time.Second {
+		test.Errorf("Real Now: %v", d)
+	}
+	select {
+	case <-Real.After(time.Millisecond):
+	case <-time.After(5 * time.Second):
+		test.Errorf("Real After did not fire")
+	}
+	select {
+	case <-ticker.C():
+	case <-time.After(5 * time.Second):
+		test.Errorf("Real Ticker did not tick")
+	}
+}
@@ -0,0 +1,232 @@
+/*
+Package diagz provides optional runtime diagnostics HTTP endpoints for production debugging.
+They can be mounted into any of this repository's servers with Mount.
+
+All endpoints require authorization since they expose internal state:
+
+	<prefix>/goroutine	- goroutine profile (debug=1 text by default; ?debug=0 for pprof binary format)
+	<prefix>/heap		- heap profile (same debug parameter)
+	<prefix>/poll		- poll.States stats as JSON
+	<prefix>/log		- the most recent log entries captured by AttachLogRing
+	<prefix>/keys		- the fingerprints (never the keys) of the AEAD keys registered with RegisterKey
+	<prefix>/config		- a snapshot of the command line flags with secret values redacted
+
+Typically the prefix is "/debug".
+*/
+package diagz
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"flag"
+	"io"
+	"net/http"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/develrns/resilient/aead"
+	"github.com/develrns/resilient/log"
+	"github.com/develrns/resilient/oplog"
+	"github.com/develrns/resilient/poll"
+)
+
+//An Authorizer returns true if a request may access the diagnostics endpoints.
+type Authorizer func(r *http.Request) bool
+
+/*
+TokenAuth returns an Authorizer that requires an "Authorization: Bearer <token>" header with the given token.
+An empty token authorizes no requests, nor does a header without the Bearer scheme.
+*/
+func TokenAuth(token string) Authorizer {
+	return func(r *http.Request) bool {
+		var authorization = r.Header.Get("Authorization")
+		if token == "" || !strings.HasPrefix(authorization, "Bearer ") {
+			return false
+		}
+		return subtle.ConstantTimeCompare([]byte(authorization[len("Bearer "):]), []byte(token)) == 1
+	}
+}
+
+/*
+Mount registers the diagnostics endpoints under the prefix on a ServeMux. If mux is nil, http.DefaultServeMux is used.
+*/
+func Mount(mux *http.ServeMux, prefix string, authorize Authorizer) {
+	if mux == nil {
+		mux = http.DefaultServeMux
+	}
+	prefix = strings.TrimSuffix(prefix, "/")
+	mux.Handle(prefix+"/goroutine", guard(authorize, profileHandler("goroutine")))
+	mux.Handle(prefix+"/heap", guard(authorize, profileHandler("heap")))
+	mux.Handle(prefix+"/poll", guard(authorize, http.HandlerFunc(handlePoll)))
+	mux.Handle(prefix+"/log", guard(authorize, http.HandlerFunc(handleLog)))
+	mux.Handle(prefix+"/keys", guard(authorize, http.HandlerFunc(handleKeys)))
+	mux.Handle(prefix+"/config", guard(authorize, http.HandlerFunc(handleConfig)))
+}
+
+//guard wraps a handler with an authorization check
+func guard(authorize Authorizer, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authorize == nil || !authorize(r) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+//profileHandler writes a named runtime profile
+func profileHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			debug = 1
+			err   error
+		)
+
+		if r.URL.Query().Get("debug") != "" {
+			debug, err = strconv.Atoi(r.URL.Query().Get("debug"))
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+		}
+		if debug == 0 {
+			w.Header().Set("Content-Type", "application/octet-stream")
+		} else {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		}
+		pprof.Lookup(name).WriteTo(w, debug)
+	})
+}
+
+//writeJSON writes a value as an indented JSON response
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	var (
+		body []byte
+		err  error
+	)
+
+	body, err = json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(body)
+}
+
+//handlePoll writes the poll States stats
+func handlePoll(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, poll.States.Stats())
+}
+
+/*
+The log ring holds the most recent log entries so that they can be dumped without access to the log file.
+*/
+var ring struct {
+	m        sync.Mutex
+	entries  []string
+	next     int
+	full     bool
+	attached bool
+}
+
+//ringWriter is an io.Writer that adds each write (one log entry) to the log ring
+type ringWriter struct{}
+
+func (ringWriter) Write(p []byte) (int, error) {
+	ring.m.Lock()
+	defer ring.m.Unlock()
+	ring.entries[ring.next] = string(p)
+	ring.next = (ring.next + 1) % len(ring.entries)
+	if ring.next == 0 {
+		ring.full = true
+	}
+	return len(p), nil
+}
+
+/*
+AttachLogRing captures the most recent size entries of the shared log instance in a ring buffer dumped by <prefix>/log.
+It should be called after log.Config. Calling it again empties the ring and resizes it rather than capturing each
+entry twice.
+*/
+func AttachLogRing(size int) {
+	var golog = log.Logger().Logger()
+
+	if size <= 0 {
+		return
+	}
+	ring.m.Lock()
+	ring.entries = make([]string, size)
+	ring.next = 0
+	ring.full = false
+	attached := ring.attached
+	ring.attached = true
+	ring.m.Unlock()
+
+	//The output is set once ring is unlocked since a concurrent log write holds the logger's lock while it locks ring
+	if !attached {
+		golog.SetOutput(io.MultiWriter(golog.Writer(), ringWriter{}))
+	}
+}
+
+//handleLog writes the log ring entries, oldest first
+func handleLog(w http.ResponseWriter, r *http.Request) {
+	ring.m.Lock()
+	defer ring.m.Unlock()
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if ring.full {
+		for _, entry := range ring.entries[ring.next:] {
+			io.WriteString(w, entry)
+		}
+	}
+	for _, entry := range ring.entries[:ring.next] {
+		io.WriteString(w, entry)
+	}
+}
+
+//keys holds the fingerprints of registered AEAD keys keyed by name
+var keys = struct {
+	m sync.Mutex
+	f map[string]string
+}{f: make(map[string]string)}
+
+/*
+RegisterKey records the fingerprint of a named AEAD key for the <prefix>/keys endpoint. The key itself is not retained.
+*/
+func RegisterKey(name string, key []byte) {
+	keys.m.Lock()
+	defer keys.m.Unlock()
+	keys.f[name] = aead.Fingerprint(key)
+}
+
+//handleKeys writes the registered key fingerprints
+func handleKeys(w http.ResponseWriter, r *http.Request) {
+	keys.m.Lock()
+	defer keys.m.Unlock()
+	writeJSON(w, keys.f)
+}
+
+//Redacted is the value shown in place of a secret flag value
+const Redacted = "[REDACTED]"
+
+//handleConfig writes the command line flag values with secrets (see oplog.IsSecretFlag) redacted
+func handleConfig(w http.ResponseWriter, r *http.Request) {
+	var config = make(map[string]string)
+
+	flag.VisitAll(func(f *flag.Flag) {
+		switch {
+		case oplog.IsSecretFlag(f.Name) && f.Value.String() != "":
+			config[f.Name] = Redacted
+		default:
+			config[f.Name] = f.Value.String()
+		}
+	})
+	writeJSON(w, config)
+}
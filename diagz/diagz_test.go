@@ -0,0 +1,125 @@
+package diagz
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/develrns/resilient/log"
+)
+
+//request issues a request of a diagnostics endpoint with an Authorization header, if it is not empty
+func request(h http.Handler, method, path, authorization string) *httptest.ResponseRecorder {
+	var (
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest(method, path, nil)
+	)
+
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestTokenAuth(test *testing.T) {
+	var (
+		mux   = http.NewServeMux()
+		cases = []struct {
+			method, authorization string
+			code                  int
+		}{
+			{"GET", "", http.StatusUnauthorized},
+			{"GET", "t", http.StatusUnauthorized},
+			{"GET", "Basic t", http.StatusUnauthorized},
+			{"GET", "Bearer", http.StatusUnauthorized},
+			{"GET", "Bearer wrong", http.StatusUnauthorized},
+			{"GET", "Bearer t ", http.StatusUnauthorized},
+			{"GET", "Bearer t", http.StatusOK},
+			{"POST", "Bearer t", http.StatusMethodNotAllowed},
+		}
+	)
+
+	Mount(mux, "/debug/", TokenAuth("t"))
+	for _, c := range cases {
+		if w := request(mux, c.method, "/debug/keys", c.authorization); w.Code != c.code {
+			test.Errorf("%v with %q: %v", c.method, c.authorization, w.Code)
+		}
+	}
+
+	//An empty token or a nil Authorizer authorizes no requests
+	if TokenAuth("")(httptest.NewRequest("GET", "/", nil)) {
+		test.Errorf("TokenAuth of an empty token authorized a request without a token")
+	}
+	if w := request(guard(nil, http.HandlerFunc(handleKeys)), "GET", "/", "Bearer t"); w.Code != http.StatusUnauthorized {
+		test.Errorf("guard with a nil Authorizer: %v", w.Code)
+	}
+}
+
+func TestConfig(test *testing.T) {
+	var (
+		replaced = flag.CommandLine
+		config   map[string]string
+	)
+	defer func() { flag.CommandLine = replaced }()
+
+	flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+	flag.String("addr", ":443", "")
+	flag.String("clientsecret", "s", "")
+	flag.String("otp", "123456", "")
+	flag.String("apikey", "", "")
+	w := request(http.HandlerFunc(handleConfig), "GET", "/debug/config", "")
+	if err := json.Unmarshal(w.Body.Bytes(), &config); err != nil || w.Header().Get("Content-Type") != "application/json" {
+		test.Fatalf("Config: %v %v", w.Body.String(), err)
+	}
+
+	//Secret values are redacted, but an empty secret is shown so that a missing secret can be diagnosed
+	for name, value := range map[string]string{"addr": ":443", "clientsecret": Redacted, "otp": Redacted, "apikey": ""} {
+		if config[name] != value {
+			test.Errorf("Config %v: %q", name, config[name])
+		}
+	}
+}
+
+func TestAttachLogRing(test *testing.T) {
+	var (
+		golog    = log.Logger().Logger()
+		replaced = golog.Writer()
+	)
+	defer func() {
+		golog.SetOutput(replaced)
+		ring.m.Lock()
+		ring.entries, ring.next, ring.full, ring.attached = nil, 0, false, false
+		ring.m.Unlock()
+	}()
+
+	golog.SetOutput(ioutil.Discard)
+	AttachLogRing(0)
+	if ring.attached {
+		test.Errorf("AttachLogRing of size 0 attached the ring")
+	}
+	AttachLogRing(3)
+	golog.Print("1")
+	golog.Print("2")
+	if w := request(http.HandlerFunc(handleLog), "GET", "/debug/log", ""); w.Body.String() != "1\n2\n" {
+		test.Errorf("Log ring before it is full: %q", w.Body.String())
+	}
+
+	//Once the ring is full, the oldest entries are overwritten
+	for _, entry := range []string{"3", "4", "5"} {
+		golog.Print(entry)
+	}
+	if w := request(http.HandlerFunc(handleLog), "GET", "/debug/log", ""); w.Body.String() != "3\n4\n5\n" {
+		test.Errorf("Log ring after it wrapped around: %q", w.Body.String())
+	}
+
+	//Attaching again empties and resizes the ring rather than capturing each entry twice
+	AttachLogRing(2)
+	golog.Print("6")
+	if w := request(http.HandlerFunc(handleLog), "GET", "/debug/log", ""); w.Body.String() != "6\n" {
+		test.Errorf("Log ring after it was attached again: %q", w.Body.String())
+	}
+}
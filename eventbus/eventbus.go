@@ -0,0 +1,245 @@
+/*
+Package eventbus provides an in-process publish/subscribe event bus that decouples the components of an executable.
+A component publishes an Event on a topic without knowing which components (e.g. audit, metrics or session
+management) have subscribed to it; so neither needs to import the other.
+
+Each Subscription has a buffered channel and a slow consumer Policy that determines what Publish does when the
+buffer is full:
+
+	Block		- Publish waits until there is room (or the Subscription is closed)
+	DropNewest	- the published event is dropped
+	DropOldest	- the oldest buffered event is dropped to make room
+	Disconnect	- the Subscription is closed
+
+Dropped events are counted by a Subscription's Dropped method.
+
+Most executables use the shared Default bus.
+*/
+package eventbus
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+//The topics published by this repository's packages
+const (
+	//TopicLoginCompleted is published by the oidc RP when a login completes successfully
+	TopicLoginCompleted = "oidc.login.completed"
+
+	//TopicStateExpired is published by poll when an abandoned State is purged
+	TopicStateExpired = "poll.state.expired"
+
+	//TopicResultDelivered is published by poll with the wait-to-delivery latency when a State's result is delivered
+	TopicResultDelivered = "poll.result.delivered"
+
+	//AllTopics subscribes to the events of every topic
+	AllTopics = "*"
+)
+
+//A Policy determines what Publish does when a Subscription's buffer is full
+type Policy int
+
+//The slow consumer Policies
+const (
+	Block Policy = iota
+	DropNewest
+	DropOldest
+	Disconnect
+)
+
+//An Event is published on a topic. Data is topic specific.
+type Event struct {
+	Topic string
+	Time  time.Time
+	Data  interface{}
+}
+
+/*
+A Subscription receives the Events of a topic on C. C is closed when the Subscription is closed.
+*/
+type Subscription struct {
+	C        <-chan Event
+	c        chan Event
+	topic    string
+	policy   Policy
+	bus      *Bus
+	m        sync.Mutex
+	done     chan struct{}
+	closed   bool
+	inflight sync.WaitGroup
+	dropped  uint64
+}
+
+/*
+A Bus holds the Subscriptions of each topic. It may be used concurrently.
+*/
+type Bus struct {
+	m    sync.RWMutex
+	subs map[string][]*Subscription
+}
+
+//Default is the shared Bus of an executable.
+var Default = New()
+
+/*
+New creates a Bus.
+*/
+func New() *Bus {
+	var bus Bus
+	bus.subs = make(map[string][]*Subscription)
+	return &bus
+}
+
+/*
+Subscribe creates a Subscription to a topic (or AllTopics) with a buffer of the given size and a slow consumer Policy.
+*/
+func (b *Bus) Subscribe(topic string, buffer int, policy Policy) *Subscription {
+	var sub Subscription
+
+	sub.c = make(chan Event, buffer)
+	sub.C = sub.c
+	sub.topic = topic
+	sub.policy = policy
+	sub.bus = b
+	sub.done = make(chan struct{})
+
+	b.m.Lock()
+	defer b.m.Unlock()
+	b.subs[topic] = append(b.subs[topic], &sub)
+	return &sub
+}
+
+/*
+Publish delivers an event with the data to the Subscriptions of the topic according to their Policies.
+*/
+func (b *Bus) Publish(topic string, data interface{}) {
+	var (
+		event = Event{Topic: topic, Time: time.Now(), Data: data}
+		subs  []*Subscription
+	)
+
+	//The subscriptions are copied so that a blocked delivery does not hold the Bus lock
+	b.m.RLock()
+	subs = append(subs, b.subs[topic]...)
+	if topic != AllTopics {
+		subs = append(subs, b.subs[AllTopics]...)
+	}
+	b.m.RUnlock()
+
+	for _, sub := range subs {
+		sub.deliver(event)
+	}
+	return
+}
+
+//deliver sends an event to a Subscription according to its Policy
+func (s *Subscription) deliver(event Event) {
+	if s.policy == Block {
+		//The Subscription lock is not held while blocked so that Close can interrupt the delivery;
+		//instead, Close waits for the inflight deliveries before closing the channel.
+		s.m.Lock()
+		if s.closed {
+			s.m.Unlock()
+			return
+		}
+		s.inflight.Add(1)
+		s.m.Unlock()
+		defer s.inflight.Done()
+		select {
+		case s.c <- event:
+		case <-s.done:
+		}
+		return
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.c <- event:
+		return
+	default:
+	}
+
+	switch s.policy {
+	case DropNewest:
+		atomic.AddUint64(&s.dropped, 1)
+	case DropOldest:
+		select {
+		case <-s.c:
+			atomic.AddUint64(&s.dropped, 1)
+		default:
+		}
+		select {
+		case s.c <- event:
+		default:
+			atomic.AddUint64(&s.dropped, 1)
+		}
+	case Disconnect:
+		atomic.AddUint64(&s.dropped, 1)
+		go s.Close()
+	}
+	return
+}
+
+/*
+Dropped returns the number of events dropped because the Subscription's buffer was full.
+*/
+func (s *Subscription) Dropped() uint64 {
+	return atomic.LoadUint64(&s.dropped)
+}
+
+/*
+Close removes the Subscription from its Bus and closes its channel. Buffered events may still be received from C.
+*/
+func (s *Subscription) Close() {
+	var (
+		subs []*Subscription
+		bus  = s.bus
+	)
+
+	s.m.Lock()
+	if s.closed {
+		s.m.Unlock()
+		return
+	}
+	s.closed = true
+	close(s.done)
+	s.m.Unlock()
+
+	bus.m.Lock()
+	subs = bus.subs[s.topic]
+	for i, sub := range subs {
+		if sub == s {
+			bus.subs[s.topic] = append(subs[:i:i], subs[i+1:]...)
+			break
+		}
+	}
+	if len(bus.subs[s.topic]) == 0 {
+		delete(bus.subs, s.topic)
+	}
+	bus.m.Unlock()
+
+	s.inflight.Wait()
+	close(s.c)
+	return
+}
+
+/*
+Subscribe subscribes to a topic of the Default Bus.
+*/
+func Subscribe(topic string, buffer int, policy Policy) *Subscription {
+	return Default.Subscribe(topic, buffer, policy)
+}
+
+/*
+Publish publishes an event on a topic of the Default Bus.
+*/
+func Publish(topic string, data interface{}) {
+	Default.Publish(topic, data)
+	return
+}
@@ -0,0 +1,156 @@
+package eventbus
+
+import (
+	"testing"
+	"time"
+)
+
+//receive returns the data of the events buffered by a Subscription, without waiting for more
+func receive(sub *Subscription) []interface{} {
+	var data []interface{}
+
+	for {
+		select {
+		case event, ok := <-sub.C:
+			if !ok {
+				return data
+			}
+			data = append(data, event.Data)
+		default:
+			return data
+		}
+	}
+}
+
+//sameData is true if the data of the received events are the expected ints
+func sameData(data []interface{}, expected ...int) bool {
+	if len(data) != len(expected) {
+		return false
+	}
+	for i := range data {
+		if data[i] != expected[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestPublish(test *testing.T) {
+	var (
+		bus   = New()
+		sub   = bus.Subscribe("a", 10, Block)
+		other = bus.Subscribe("b", 10, Block)
+		all   = bus.Subscribe(AllTopics, 10, Block)
+	)
+
+	bus.Publish("a", 1)
+	bus.Publish("b", 2)
+	if data := receive(sub); !sameData(data, 1) {
+		test.Errorf("Subscription a: %v", data)
+	}
+	if data := receive(other); !sameData(data, 2) {
+		test.Errorf("Subscription b: %v", data)
+	}
+	if data := receive(all); !sameData(data, 1, 2) {
+		test.Errorf("Subscription of all topics: %v", data)
+	}
+
+	//A closed Subscription receives no more events and its buffered events may still be received
+	bus.Publish("a", 3)
+	sub.Close()
+	sub.Close()
+	bus.Publish("a", 4)
+	if data := receive(sub); !sameData(data, 3) {
+		test.Errorf("Closed Subscription: %v", data)
+	}
+	if _, ok := bus.subs["a"]; ok {
+		test.Errorf("Closed Subscription was not removed from the Bus")
+	}
+}
+
+func TestDropNewest(test *testing.T) {
+	var sub = New().Subscribe("a", 2, DropNewest)
+
+	for i := 1; i <= 4; i++ {
+		sub.bus.Publish("a", i)
+	}
+	if data := receive(sub); !sameData(data, 1, 2) || sub.Dropped() != 2 {
+		test.Errorf("DropNewest: %v %v", data, sub.Dropped())
+	}
+}
+
+func TestDropOldest(test *testing.T) {
+	var sub = New().Subscribe("a", 2, DropOldest)
+
+	for i := 1; i <= 4; i++ {
+		sub.bus.Publish("a", i)
+	}
+	if data := receive(sub); !sameData(data, 3, 4) || sub.Dropped() != 2 {
+		test.Errorf("DropOldest: %v %v", data, sub.Dropped())
+	}
+}
+
+func TestDisconnect(test *testing.T) {
+	var (
+		bus  = New()
+		sub  = bus.Subscribe("a", 1, Disconnect)
+		data []interface{}
+	)
+
+	bus.Publish("a", 1)
+	bus.Publish("a", 2)
+
+	//The Subscription is closed once the event that overflowed it is dropped
+	for event := range sub.C {
+		data = append(data, event.Data)
+	}
+	bus.Publish("a", 3)
+	if !sameData(data, 1) || sub.Dropped() != 1 {
+		test.Errorf("Disconnect: %v %v", data, sub.Dropped())
+	}
+}
+
+func TestBlock(test *testing.T) {
+	var (
+		bus       = New()
+		sub       = bus.Subscribe("a", 1, Block)
+		published = make(chan struct{})
+	)
+
+	//A Publish to a full Subscription waits until there is room
+	bus.Publish("a", 1)
+	go func() {
+		bus.Publish("a", 2)
+		close(published)
+	}()
+	select {
+	case <-published:
+		test.Fatalf("Publish to a full Subscription did not block")
+	case <-time.After(50 * time.Millisecond):
+	}
+	if event := <-sub.C; event.Data != 1 {
+		test.Errorf("First event: %v", event.Data)
+	}
+	<-published
+	if event := <-sub.C; event.Data != 2 || sub.Dropped() != 0 {
+		test.Errorf("Second event: %v %v", event.Data, sub.Dropped())
+	}
+
+	//Close interrupts a blocked Publish
+	bus.Publish("a", 3)
+	published = make(chan struct{})
+	go func() {
+		bus.Publish("a", 4)
+		close(published)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	sub.Close()
+	select {
+	case <-published:
+	case <-time.After(5 * time.Second):
+		test.Fatalf("Close did not interrupt a blocked Publish")
+	}
+	if data := receive(sub); !sameData(data, 3) {
+		test.Errorf("Events of the closed Subscription: %v", data)
+	}
+}
@@ -0,0 +1,87 @@
+package jld
+
+import (
+	"sync"
+)
+
+//nodeMaps holds the cleared maps released by NodeArenas for reuse
+var nodeMaps = sync.Pool{New: func() interface{} { return make(map[string]interface{}, 4) }}
+
+//maxPooledMap is the size of the largest map that is pooled; Go maps do not shrink, so larger ones are left to the GC
+const maxPooledMap = 64
+
+/*
+A NodeArena reduces the allocation of the maps of small nodes and value objects in services that construct thousands
+of them per request. Its NewN and NewV are NewN and NewV with maps drawn from a pool shared by all NodeArenas, and
+Release returns the maps to the pool. For example:
+
+	arena := jld.NewNodeArena()
+	defer arena.Release()
+	node := arena.NewN("", personT)
+	node[ageP.URI()] = arena.NewV(xsdInteger, 42)
+
+After Release, neither the maps nor any document that contains them may be used; a document that outlives the request
+(e.g. one that is cached) must be DeepCopied first. A NodeArena is meant to be used by one request, and is not safe for
+concurrent use. A nil *NodeArena allocates as NewN and NewV do, so an arena may be optional.
+*/
+type NodeArena struct {
+	maps []map[string]interface{}
+}
+
+/*
+NewNodeArena creates an empty NodeArena.
+*/
+func NewNodeArena() *NodeArena {
+	return &NodeArena{}
+}
+
+//get draws an empty map from the pool and records it for Release
+func (a *NodeArena) get() map[string]interface{} {
+	var m = nodeMaps.Get().(map[string]interface{})
+
+	a.maps = append(a.maps, m)
+	return m
+}
+
+/*
+NewN is NewN with a map drawn from the arena.
+*/
+func (a *NodeArena) NewN(id string, t ...TypeID) map[string]interface{} {
+	if a == nil {
+		return NewN(id, t...)
+	}
+	if len(t) == 0 {
+		return nil
+	}
+	return fillN(a.get(), id, t)
+}
+
+/*
+NewV is NewV with a map drawn from the arena.
+*/
+func (a *NodeArena) NewV(t TypeID, v interface{}) map[string]interface{} {
+	if a == nil {
+		return NewV(t, v)
+	}
+	return fillV(a.get(), t, v)
+}
+
+/*
+Release clears the maps drawn from the arena and returns them to the pool. The arena may then be reused.
+*/
+func (a *NodeArena) Release() {
+	if a == nil {
+		return
+	}
+	for i, m := range a.maps {
+		a.maps[i] = nil
+		if len(m) > maxPooledMap {
+			continue
+		}
+		for k := range m {
+			delete(m, k)
+		}
+		nodeMaps.Put(m)
+	}
+	a.maps = a.maps[:0]
+}
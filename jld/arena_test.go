@@ -0,0 +1,46 @@
+package jld
+
+import (
+	"testing"
+)
+
+func TestNodeArena(test *testing.T) {
+	var (
+		personT = NewTypeID("https://ex.org/vocab#Person", "")
+		intT    = NewTypeID(xsdBase+"integer", "")
+		arena   = NewNodeArena()
+		nilA    *NodeArena
+		node    map[string]interface{}
+		valobj  map[string]interface{}
+	)
+
+	node = arena.NewN("https://ex.org/a", personT)
+	valobj = arena.NewV(intT, 42)
+	node["https://ex.org/vocab#age"] = valobj
+	switch {
+	case node["@id"] != "https://ex.org/a" || !hasType(node, personT):
+		test.Errorf("NodeArena NewN: %v", node)
+	case !IsVtypeval(valobj, intT, 42):
+		test.Errorf("NodeArena NewV: %v", valobj)
+	case arena.NewN("x") != nil:
+		test.Errorf("NodeArena NewN without a type should be nil")
+	case len(arena.maps) != 2:
+		test.Errorf("NodeArena maps: %v", len(arena.maps))
+	}
+
+	arena.Release()
+	if len(node) != 0 || len(valobj) != 0 || len(arena.maps) != 0 {
+		test.Errorf("NodeArena Release: %v %v %v", node, valobj, len(arena.maps))
+	}
+	node = arena.NewN("", personT)
+	if len(node) != 2 || !hasType(node, personT) {
+		test.Errorf("NodeArena reuse: %v", node)
+	}
+	arena.Release()
+
+	node = nilA.NewN("https://ex.org/b", personT)
+	if node["@id"] != "https://ex.org/b" || !IsVtypeval(nilA.NewV(intT, 1), intT, 1) {
+		test.Errorf("nil NodeArena: %v", node)
+	}
+	nilA.Release()
+}
@@ -0,0 +1,176 @@
+package jld
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+/*
+HashBlankIDs renames the blank node identifiers of a document in place to deterministic, content-based ones:
+_:h<hash>, where the hash is of the blank node's content with all blank node identifiers masked. Unlike
+RelabelBlankNodes, it keeps the document's shape (e.g. the framed output of Canonicalize), so repeated
+canonicalizations of the same logical graph marshal to byte-identical JSON for caching and hashing.
+
+Blank nodes whose masked content is identical are numbered in document order (_:h<hash>-2, ...); this does not
+distinguish them by their position in the graph as URDNA2015 does, so use RelabelBlankNodes where that matters.
+*/
+func HashBlankIDs(input interface{}) error {
+	return hashBlankIDs(context.Background(), input)
+}
+
+//hashBlankIDs is HashBlankIDs with a Context, which is checked before each blank node is hashed
+func hashBlankIDs(ctx context.Context, input interface{}) error {
+	var (
+		contents = make(map[string]interface{})
+		order    []string
+		hashes   = make(map[string]string)
+		labels   = make(map[string]string)
+		used     = make(map[string]int)
+		masked   []byte
+		err      error
+	)
+
+	err = collectBlankNodes(input, contents, &order, 0)
+	if err != nil {
+		return err
+	}
+	for _, id := range order {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		masked, err = json.Marshal(maskBlankIDs(contents[id]))
+		if err != nil {
+			return nodeError(ErrBadValue, contents[id], "", "the blank node cannot be hashed: %v", err)
+		}
+		hash := sha256.Sum256(masked)
+		hashes[id] = hex.EncodeToString(hash[:8])
+	}
+
+	//Identical hashes are numbered in document order
+	sort.SliceStable(order, func(i, j int) bool { return hashes[order[i]] < hashes[order[j]] })
+	for _, id := range order {
+		label := "_:h" + hashes[id]
+		used[label]++
+		if used[label] > 1 {
+			label = fmt.Sprintf("%v-%v", label, used[label])
+		}
+		labels[id] = label
+	}
+	relabel(input, labels)
+	return nil
+}
+
+//collectBlankNodes maps the blank node identifiers of a document, in document order, to the first node with each
+func collectBlankNodes(input interface{}, contents map[string]interface{}, order *[]string, depth int) error {
+	var err error
+
+	if depth > maxGraphDepth {
+		return fmt.Errorf("Document nesting exceeds %v", maxGraphDepth)
+	}
+	switch input.(type) {
+	case []interface{}:
+		for _, item := range input.([]interface{}) {
+			err = collectBlankNodes(item, contents, order, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		if id, ok := obj["@id"].(string); ok && strings.HasPrefix(id, "_:") {
+			if _, ok := contents[id]; !ok {
+				*order = append(*order, id)
+				contents[id] = obj
+			} else if len(obj) > len(contents[id].(map[string]interface{})) {
+				//A node reference is superseded by the node's content
+				contents[id] = obj
+			}
+		}
+		for _, k := range sortedKeys(obj) {
+			if k == "@context" {
+				continue
+			}
+			err = collectBlankNodes(obj[k], contents, order, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//maskBlankIDs returns a copy of a value with its blank node identifiers replaced by "_:"
+func maskBlankIDs(input interface{}) interface{} {
+	switch input.(type) {
+	case []interface{}:
+		masked := make([]interface{}, len(input.([]interface{})))
+		for i, item := range input.([]interface{}) {
+			masked[i] = maskBlankIDs(item)
+		}
+		return masked
+	case map[string]interface{}:
+		masked := make(map[string]interface{}, len(input.(map[string]interface{})))
+		for k, v := range input.(map[string]interface{}) {
+			if id, ok := v.(string); ok && k == "@id" && strings.HasPrefix(id, "_:") {
+				masked[k] = "_:"
+				continue
+			}
+			masked[k] = maskBlankIDs(v)
+		}
+		return masked
+	default:
+		return input
+	}
+}
+
+//relabel replaces the blank node identifiers of a document in place
+func relabel(input interface{}, labels map[string]string) {
+	switch input.(type) {
+	case []interface{}:
+		for _, item := range input.([]interface{}) {
+			relabel(item, labels)
+		}
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		for k, v := range obj {
+			if id, ok := v.(string); ok && k == "@id" && labels[id] != "" {
+				obj[k] = labels[id]
+				continue
+			}
+			if k != "@context" {
+				relabel(v, labels)
+			}
+		}
+	}
+}
+
+//sortedKeys returns the keys of an object in order
+func sortedKeys(obj map[string]interface{}) []string {
+	var keys = make([]string, 0, len(obj))
+
+	for k := range obj {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+//stabilize relabels the blank nodes of framed nodes and orders them by @id if the options have StableBlankIDs
+func (o *options) stabilize(ctx context.Context, nodes []interface{}) error {
+	var err error
+
+	if !o.stableIDs {
+		return nil
+	}
+	err = hashBlankIDs(ctx, nodes)
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodeID(nodes[i]) < nodeID(nodes[j]) })
+	return nil
+}
@@ -0,0 +1,41 @@
+package jld
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHashBlankIDs(test *testing.T) {
+	var (
+		personT = NewTypeID("https://ex.org/types#Person", "")
+		knowsP  = NewPropID("https://ex.org/vocab#knows", "")
+		nameP   = NewPropID("https://ex.org/vocab#name", "")
+		docs    [2]string
+	)
+
+	for i := range docs {
+		a := NewN("", personT)
+		b := NewN("", personT)
+		b[nameP.URI()] = "B"
+		a[knowsP.URI()] = []interface{}{b, map[string]interface{}{"@id": b["@id"]}}
+		doc := []interface{}{a, b}
+		err := HashBlankIDs(doc)
+		if err != nil {
+			test.Fatalf("HashBlankIDs: %v", err)
+		}
+		bytes, _ := json.Marshal(doc)
+		docs[i] = string(bytes)
+		if a["@id"] == b["@id"] || a[knowsP.URI()].([]interface{})[1].(map[string]interface{})["@id"] != b["@id"] {
+			test.Errorf("HashBlankIDs: %v", docs[i])
+		}
+	}
+	if docs[0] != docs[1] {
+		test.Errorf("HashBlankIDs not deterministic:\n%v\n%v", docs[0], docs[1])
+	}
+
+	twins := []interface{}{NewN("", personT), NewN("", personT)}
+	HashBlankIDs(twins)
+	if nodeID(twins[0]) == nodeID(twins[1]) {
+		test.Errorf("HashBlankIDs twins: %v", twins)
+	}
+}
@@ -0,0 +1,86 @@
+package jld
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+/*
+The datatype registry holds the expected datatype of properties so that the coercion of their values is defined once
+rather than at each call site. Once a property's datatype is registered with RegisterDatatype:
+
+SetP stores a JSON primitive value (or a time.Time) of the property as a value object of the datatype, e.g. 42 is
+stored as {"@type": xsd:integer, "@value": 42}.
+
+GetVtype, GetInt, GetFloat and GetTime treat a plain JSON primitive value of the property as a value of the datatype,
+e.g. the string "42" is an xsd:integer, so documents from producers that omit the value object are read the same way.
+
+The registry is shared by concurrent requests and is mutexed. Datatypes are typically registered in an init function
+alongside a Vocabulary.
+*/
+var datatypes = struct {
+	m sync.RWMutex
+	t map[string]TypeID
+}{t: make(map[string]TypeID)}
+
+/*
+RegisterDatatype registers the expected datatype of a property, replacing any earlier registration.
+*/
+func RegisterDatatype(propID PropID, typeID TypeID) {
+	datatypes.m.Lock()
+	defer datatypes.m.Unlock()
+	datatypes.t[propID.URI()] = typeID
+}
+
+/*
+UnregisterDatatype removes the registered datatype of a property.
+*/
+func UnregisterDatatype(propID PropID) {
+	datatypes.m.Lock()
+	defer datatypes.m.Unlock()
+	delete(datatypes.t, propID.URI())
+}
+
+/*
+Datatype returns the registered datatype of a property.
+*/
+func Datatype(propID PropID) (TypeID, bool) {
+	var (
+		typeID TypeID
+		ok     bool
+	)
+
+	datatypes.m.RLock()
+	defer datatypes.m.RUnlock()
+	typeID, ok = datatypes.t[propID.URI()]
+	return typeID, ok
+}
+
+/*
+coerceV returns a primitive value as a value object of a datatype. A time.Time is stored in the lexical form of
+xsd:date or xsd:dateTime. It is false for any other value.
+*/
+func coerceV(typeID TypeID, v interface{}) (map[string]interface{}, bool) {
+	switch v.(type) {
+	case bool, int, int64, float32, float64, string, json.Number:
+		return NewV(typeID, v), true
+	case time.Time:
+		if xsdName(typeID.URI()) == "date" {
+			return NewV(typeID, v.(time.Time).Format("2006-01-02")), true
+		}
+		return NewV(typeID, v.(time.Time).Format(time.RFC3339Nano)), true
+	default:
+		return nil, false
+	}
+}
+
+//isPrimitive is true if the value is a JSON primitive
+func isPrimitive(v interface{}) bool {
+	switch v.(type) {
+	case bool, int, int64, float32, float64, string, json.Number:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,58 @@
+package jld
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDatatype(test *testing.T) {
+	var (
+		xsdInt = NewTypeID(xsdBase+"integer", "")
+		ageP   = NewPropID("https://ex.org/vocab#age", "")
+		bornP  = NewPropID("https://ex.org/vocab#born", "")
+		born   = time.Date(1990, 5, 1, 12, 0, 0, 0, time.UTC)
+		node   = map[string]interface{}{"@id": "https://ex.org/ann"}
+		i      int64
+		tm     time.Time
+		v      interface{}
+		ok     bool
+		err    error
+	)
+
+	RegisterDatatype(ageP, xsdInt)
+	RegisterDatatype(bornP, xsdDateTime)
+	defer UnregisterDatatype(ageP)
+	defer UnregisterDatatype(bornP)
+
+	err = SetP(node, ageP, 42)
+	if err != nil || !IsVtype(node[ageP.URI()], xsdInt) {
+		test.Errorf("SetP should coerce to xsd:integer: %v %v", node[ageP.URI()], err)
+	}
+	err = SetP(node, bornP, born)
+	if err != nil || !IsVtype(node[bornP.URI()], xsdDateTime) {
+		test.Errorf("SetP should coerce a time.Time to xsd:dateTime: %v %v", node[bornP.URI()], err)
+	}
+	if tm, ok = GetTime(node, bornP); !ok || !tm.Equal(born) {
+		test.Errorf("GetTime: %v %v", tm, ok)
+	}
+
+	//Raw primitives are read as values of the registered datatype
+	node[ageP.URI()] = "42"
+	if i, ok = GetInt(node, ageP); !ok || i != 42 {
+		test.Errorf("GetInt of a raw string: %v %v", i, ok)
+	}
+	if v, ok = GetVtype(node, ageP, xsdInt); !ok || v != "42" {
+		test.Errorf("GetVtype of a raw string: %v %v", v, ok)
+	}
+	if _, ok = GetVtype(node, ageP, xsdDateTime); ok {
+		test.Errorf("GetVtype should not match another type")
+	}
+
+	UnregisterDatatype(ageP)
+	if _, ok = GetInt(node, ageP); ok {
+		test.Errorf("GetInt of a raw string without a datatype should fail")
+	}
+	if err = SetP(node, ageP, 42); err != nil || node[ageP.URI()] != 42 {
+		test.Errorf("SetP without a datatype should not coerce: %v %v", node[ageP.URI()], err)
+	}
+}
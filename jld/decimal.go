@@ -0,0 +1,161 @@
+package jld
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+)
+
+/*
+Arbitrary precision numbers, such as large identifiers and monetary values, lose precision if they pass through a
+float64. NewV stores a json.Number, *big.Int, *big.Float or *big.Rat as the lexical form of the number in a string
+@value, which JSON LD processing leaves unchanged, so a value object of type xsd:integer or xsd:decimal carries the
+number exactly. GetBigInt and GetDecimal get such values, and JSON numbers decoded with a Decoder's UseNumber, without
+converting them to float64.
+*/
+
+//numberLexical returns the lexical form of an arbitrary precision number; it is false if the number has none,
+//i.e. a *big.Rat that is not a finite decimal, an infinite *big.Float or a nil pointer
+func numberLexical(v interface{}) (string, bool) {
+	switch v.(type) {
+	case json.Number:
+		return string(v.(json.Number)), true
+	case *big.Int:
+		if v.(*big.Int) == nil {
+			return "", false
+		}
+		return v.(*big.Int).String(), true
+	case *big.Float:
+		if v.(*big.Float) == nil || v.(*big.Float).IsInf() {
+			return "", false
+		}
+		return v.(*big.Float).Text('f', -1), true
+	case *big.Rat:
+		if v.(*big.Rat) == nil {
+			return "", false
+		}
+		return decimalString(v.(*big.Rat))
+	default:
+		return "", false
+	}
+}
+
+//decimalString returns the exact decimal form of a rational; it is false if its denominator has a prime factor
+//other than 2 and 5, since its decimal form is then infinite
+func decimalString(r *big.Rat) (string, bool) {
+	var (
+		denom  = new(big.Int).Set(r.Denom())
+		two    = big.NewInt(2)
+		five   = big.NewInt(5)
+		mod    = new(big.Int)
+		twos   int
+		fives  int
+		digits int
+	)
+
+	for mod.Mod(denom, two).Sign() == 0 {
+		denom.Quo(denom, two)
+		twos++
+	}
+	for mod.Mod(denom, five).Sign() == 0 {
+		denom.Quo(denom, five)
+		fives++
+	}
+	if denom.Cmp(big.NewInt(1)) != 0 {
+		return "", false
+	}
+	digits = twos
+	if fives > digits {
+		digits = fives
+	}
+	return r.FloatString(digits), true
+}
+
+/*
+GetBigInt gets the property of a node if it is an integer, without loss of precision: a JSON number (including a
+json.Number) with no fractional part, or a value object of an XML Schema integer datatype such as xsd:integer, whose
+value may be a string. A float64 is only an integer if it is exact, i.e. at most 2^53.
+*/
+func GetBigInt(input interface{}, propID PropID) (*big.Int, bool) {
+	var (
+		propI interface{}
+		t     string
+		i     *big.Int
+		small int64
+		ok    bool
+	)
+
+	propI, t, ok = typedValue(input, propID)
+	if !ok || (t != "" && !xsdIntegers[xsdName(t)]) {
+		return nil, false
+	}
+	switch propI.(type) {
+	case int, int64, float64:
+		small, ok = GetInt(input, propID)
+		if !ok {
+			return nil, false
+		}
+		return big.NewInt(small), true
+	case json.Number:
+		i, ok = new(big.Int).SetString(string(propI.(json.Number)), 10)
+	case string:
+		if t == "" {
+			return nil, false
+		}
+		i, ok = new(big.Int).SetString(propI.(string), 10)
+	default:
+		return nil, false
+	}
+	if !ok {
+		return nil, false
+	}
+	return i, true
+}
+
+/*
+GetDecimal gets the property of a node if it is a number as an exact rational: a JSON number (including a
+json.Number), or a value object of an XML Schema integer datatype or xsd:decimal, whose value may be a string.
+A float64 is converted exactly, so it is only as precise as the float64 it was decoded into.
+*/
+func GetDecimal(input interface{}, propID PropID) (*big.Rat, bool) {
+	var (
+		propI interface{}
+		t     string
+		s     string
+		r     *big.Rat
+		ok    bool
+	)
+
+	propI, t, ok = typedValue(input, propID)
+	if !ok || (t != "" && !xsdIntegers[xsdName(t)] && xsdName(t) != "decimal") {
+		return nil, false
+	}
+	switch propI.(type) {
+	case int:
+		return new(big.Rat).SetInt64(int64(propI.(int))), true
+	case int64:
+		return new(big.Rat).SetInt64(propI.(int64)), true
+	case float64:
+		r = new(big.Rat).SetFloat64(propI.(float64))
+		return r, r != nil
+	case json.Number:
+		s = string(propI.(json.Number))
+	case string:
+		if t == "" {
+			return nil, false
+		}
+		s = propI.(string)
+	default:
+		return nil, false
+	}
+
+	//big.Rat also parses fractions such as 1/3, which are not numbers
+	if _, err := strconv.ParseFloat(s, 64); err != nil && err.(*strconv.NumError).Err != strconv.ErrRange {
+		return nil, false
+	}
+	r, ok = new(big.Rat).SetString(s)
+	if !ok {
+		return nil, false
+	}
+	return r, true
+}
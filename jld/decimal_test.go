@@ -0,0 +1,85 @@
+package jld
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"testing"
+)
+
+func TestDecimal(test *testing.T) {
+	var (
+		idP      = NewPropID("https://ex.org/vocab#id", "")
+		priceP   = NewPropID("https://ex.org/vocab#price", "")
+		countP   = NewPropID("https://ex.org/vocab#count", "")
+		xsdInt   = NewTypeID(xsdBase+"integer", "")
+		xsdDec   = NewTypeID(xsdBase+"decimal", "")
+		large, _ = new(big.Int).SetString("123456789012345678901234567890", 10)
+		price, _ = new(big.Rat).SetString("19.99")
+		node     map[string]interface{}
+		decoder  *json.Decoder
+		b        []byte
+		i        *big.Int
+		r        *big.Rat
+		ok       bool
+		err      error
+	)
+
+	node = map[string]interface{}{
+		idP.URI():    NewV(xsdInt, large),
+		priceP.URI(): NewV(xsdDec, price),
+		countP.URI(): 3.0,
+	}
+	if node[idP.URI()].(map[string]interface{})["@value"] != "123456789012345678901234567890" {
+		test.Errorf("NewV of a *big.Int: %v", node[idP.URI()])
+	}
+	if node[priceP.URI()].(map[string]interface{})["@value"] != "19.99" {
+		test.Errorf("NewV of a *big.Rat: %v", node[priceP.URI()])
+	}
+	if NewV(xsdDec, big.NewRat(1, 3))["@value"] != nil {
+		test.Errorf("NewV of a *big.Rat that is not a finite decimal should be nil")
+	}
+
+	//Round trip through JSON
+	b, err = json.Marshal(node)
+	if err != nil {
+		test.Fatal(err)
+	}
+	node = nil
+	err = json.Unmarshal(b, &node)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if i, ok = GetBigInt(node, idP); !ok || i.Cmp(large) != 0 {
+		test.Errorf("GetBigInt: %v %v", i, ok)
+	}
+	if r, ok = GetDecimal(node, priceP); !ok || r.Cmp(price) != 0 {
+		test.Errorf("GetDecimal: %v %v", r, ok)
+	}
+	if _, ok = GetBigInt(node, priceP); ok {
+		test.Errorf("GetBigInt of an xsd:decimal should fail")
+	}
+	if i, ok = GetBigInt(node, countP); !ok || i.Int64() != 3 {
+		test.Errorf("GetBigInt of a float64: %v %v", i, ok)
+	}
+
+	//A JSON number decoded with UseNumber
+	decoder = json.NewDecoder(bytes.NewReader([]byte(`{"https://ex.org/vocab#id": 98765432109876543210}`)))
+	decoder.UseNumber()
+	node = nil
+	err = decoder.Decode(&node)
+	if err != nil {
+		test.Fatal(err)
+	}
+	if i, ok = GetBigInt(node, idP); !ok || i.String() != "98765432109876543210" {
+		test.Errorf("GetBigInt of a json.Number: %v %v", i, ok)
+	}
+	if r, ok = GetDecimal(node, idP); !ok || r.FloatString(0) != "98765432109876543210" {
+		test.Errorf("GetDecimal of a json.Number: %v %v", r, ok)
+	}
+
+	node[priceP.URI()] = NewV(xsdDec, "1/3")
+	if _, ok = GetDecimal(node, priceP); ok {
+		test.Errorf("GetDecimal of a fraction should fail")
+	}
+}
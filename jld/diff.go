@@ -0,0 +1,148 @@
+package jld
+
+import (
+	"sort"
+	"strings"
+)
+
+/*
+A Patch is the change set between two JSON LD documents as the N-Quads statements (one per string, without the line
+terminator) that were removed from and added to the first to produce the second. It is the form used to audit and
+replicate changes to policy documents.
+
+The statements are in URDNA2015 normal form, so blank nodes have canonical labels (_:c14n0, ...). Since these labels
+depend on the whole graph, a Patch that removes or adds statements about blank nodes should only be applied to the
+document it was computed from.
+*/
+type Patch struct {
+	Removed []string `json:"removed"`
+	Added   []string `json:"added"`
+}
+
+/*
+IsEmpty is true if the Patch has no changes, i.e. its documents are the same graph.
+*/
+func (p *Patch) IsEmpty() bool {
+	return len(p.Removed) == 0 && len(p.Added) == 0
+}
+
+/*
+Diff returns the Patch that changes the graph of document a into the graph of document b. The inputs may be
+unmarshalled JSON LD in any form.
+*/
+func Diff(a, b interface{}) (*Patch, error) {
+	var (
+		patch  Patch
+		quadsA map[string]bool
+		quadsB map[string]bool
+		err    error
+	)
+
+	quadsA, err = normalQuads(a)
+	if err != nil {
+		return nil, err
+	}
+	quadsB, err = normalQuads(b)
+	if err != nil {
+		return nil, err
+	}
+
+	for quad := range quadsA {
+		if !quadsB[quad] {
+			patch.Removed = append(patch.Removed, quad)
+		}
+	}
+	for quad := range quadsB {
+		if !quadsA[quad] {
+			patch.Added = append(patch.Added, quad)
+		}
+	}
+	sort.Strings(patch.Removed)
+	sort.Strings(patch.Added)
+	return &patch, nil
+}
+
+/*
+Equal is true if documents a and b are isomorphic graphs: they have the same statements once their blank nodes are
+mapped one to one. Unlike map equality, it ignores the form of the documents (e.g. compacted or expanded), the order
+of set values and the labels of blank nodes. The inputs may be unmarshalled JSON LD in any form.
+*/
+func Equal(a, b interface{}) (bool, error) {
+	var (
+		normalA string
+		normalB string
+		err     error
+	)
+
+	//URDNA2015 assigns the same canonical blank node labels to isomorphic graphs, so their normal forms are identical
+	normalA, err = Normalize(a)
+	if err != nil {
+		return false, err
+	}
+	normalB, err = Normalize(b)
+	if err != nil {
+		return false, err
+	}
+	return normalA == normalB, nil
+}
+
+/*
+Apply applies a Patch to a document and returns the patched document in the form produced by RelabelBlankNodes.
+Removed statements that are not in the document are ignored.
+*/
+func Apply(input interface{}, patch *Patch) ([]interface{}, error) {
+	var (
+		quads  map[string]bool
+		sorted []string
+		doc    []interface{}
+		err    error
+	)
+
+	quads, err = normalQuads(input)
+	if err != nil {
+		return nil, err
+	}
+	for _, quad := range patch.Removed {
+		delete(quads, quad)
+	}
+	for _, quad := range patch.Added {
+		quads[quad] = true
+	}
+
+	if len(quads) == 0 {
+		return []interface{}{}, nil
+	}
+
+	for quad := range quads {
+		sorted = append(sorted, quad+"\n")
+	}
+	sort.Strings(sorted)
+	doc, err = FromNQuads(strings.Join(sorted, ""))
+	if err != nil {
+		return nil, err
+	}
+
+	//The patched graph is relabelled since its canonical blank node labels may differ from the input's
+	return RelabelBlankNodes(doc)
+}
+
+//normalQuads returns the set of statements of a document's normalized N-Quads
+func normalQuads(input interface{}) (map[string]bool, error) {
+	var (
+		normalized string
+		quads      = make(map[string]bool)
+		err        error
+	)
+
+	normalized, err = Normalize(input)
+	if err != nil {
+		return nil, err
+	}
+	for _, quad := range strings.Split(normalized, "\n") {
+		quad = strings.TrimSpace(quad)
+		if quad != "" {
+			quads[quad] = true
+		}
+	}
+	return quads, nil
+}
@@ -0,0 +1,133 @@
+package jld
+
+import (
+	"strings"
+	"testing"
+)
+
+//diffDocs returns two versions of a document: bob is added, ann's age is changed and her note is removed
+func diffDocs() (map[string]interface{}, map[string]interface{}) {
+	var (
+		ctx = map[string]interface{}{"@vocab": "https://ex.org/vocab#"}
+		a   = map[string]interface{}{
+			"@context": ctx,
+			"@graph": []interface{}{
+				map[string]interface{}{"@id": "https://ex.org/ann", "age": 41.0, "note": "old", "address": map[string]interface{}{"city": "Oslo"}},
+			},
+		}
+		b = map[string]interface{}{
+			"@context": ctx,
+			"@graph": []interface{}{
+				map[string]interface{}{"@id": "https://ex.org/ann", "age": 42.0, "address": map[string]interface{}{"city": "Oslo"}},
+				map[string]interface{}{"@id": "https://ex.org/bob", "age": 30.0},
+			},
+		}
+	)
+
+	return a, b
+}
+
+func TestDiff(test *testing.T) {
+	var a, b = diffDocs()
+
+	patch, err := Diff(a, b)
+	if err != nil {
+		test.Fatalf("Diff: %v", err)
+	}
+	removed := strings.Join(patch.Removed, "\n")
+	added := strings.Join(patch.Added, "\n")
+	switch {
+	case len(patch.Removed) != 2 || len(patch.Added) != 2:
+		test.Errorf("Diff: %+v", patch)
+	case !strings.Contains(removed, `<https://ex.org/ann> <https://ex.org/vocab#note> "old" .`):
+		test.Errorf("Diff did not remove the note: %v", removed)
+	case !strings.Contains(removed, `<https://ex.org/ann> <https://ex.org/vocab#age> "41"^^`):
+		test.Errorf("Diff did not remove the old age: %v", removed)
+	case !strings.Contains(added, `<https://ex.org/ann> <https://ex.org/vocab#age> "42"^^`):
+		test.Errorf("Diff did not add the new age: %v", added)
+	case !strings.Contains(added, `<https://ex.org/bob> <https://ex.org/vocab#age> "30"^^`):
+		test.Errorf("Diff did not add bob: %v", added)
+	}
+
+	if patch, err = Diff(a, a); err != nil || !patch.IsEmpty() {
+		test.Errorf("Diff of a document with itself: %+v %v", patch, err)
+	}
+	if patch, err = Diff(b, a); err != nil || len(patch.Removed) != 2 || len(patch.Added) != 2 {
+		test.Errorf("Diff in reverse: %+v %v", patch, err)
+	}
+}
+
+func TestApply(test *testing.T) {
+	var a, b = diffDocs()
+
+	for _, docs := range [][2]interface{}{{a, b}, {b, a}, {a, a}, {a, map[string]interface{}{}}, {map[string]interface{}{}, b}} {
+		patch, err := Diff(docs[0], docs[1])
+		if err != nil {
+			test.Fatalf("Diff: %v", err)
+		}
+		applied, err := Apply(docs[0], patch)
+		if err != nil {
+			test.Fatalf("Apply: %v", err)
+		}
+		if equal, err := Equal(applied, docs[1]); err != nil || !equal {
+			test.Errorf("Apply(a, Diff(a, b)) is not b: %v %v", applied, err)
+		}
+	}
+
+	//A removed statement that is not in the document is ignored
+	patch := &Patch{Removed: []string{`<https://ex.org/cy> <https://ex.org/vocab#age> "9"^^<http://www.w3.org/2001/XMLSchema#integer> .`}}
+	if applied, err := Apply(a, patch); err != nil {
+		test.Errorf("Apply of a missing statement: %v", err)
+	} else if equal, _ := Equal(applied, a); !equal {
+		test.Errorf("Apply of a missing statement changed the document: %v", applied)
+	}
+}
+
+func TestEqual(test *testing.T) {
+	var (
+		ctx  = map[string]interface{}{"@vocab": "https://ex.org/vocab#"}
+		base = map[string]interface{}{
+			"@context": ctx,
+			"@id":      "https://ex.org/ann",
+			"name":     "Ann",
+			"tags":     []interface{}{"a", "b", "c"},
+			"address":  map[string]interface{}{"@id": "_:addr", "city": "Oslo"},
+		}
+		cases = []struct {
+			doc   interface{}
+			equal bool
+		}{
+			//Property order, in the expanded form and another order of the properties
+			{[]interface{}{map[string]interface{}{
+				"https://ex.org/vocab#address": map[string]interface{}{"https://ex.org/vocab#city": "Oslo"},
+				"https://ex.org/vocab#tags":    []interface{}{"a", "b", "c"},
+				"https://ex.org/vocab#name":    "Ann",
+				"@id":                          "https://ex.org/ann",
+			}}, true},
+			//Set order
+			{map[string]interface{}{"@context": ctx, "@id": "https://ex.org/ann", "name": "Ann", "tags": []interface{}{"c", "a", "b"}, "address": map[string]interface{}{"@id": "_:addr", "city": "Oslo"}}, true},
+			//Blank node label
+			{map[string]interface{}{"@context": ctx, "@id": "https://ex.org/ann", "name": "Ann", "tags": []interface{}{"a", "b", "c"}, "address": map[string]interface{}{"@id": "_:other", "city": "Oslo"}}, true},
+			//List order is not set order
+			{map[string]interface{}{"@context": ctx, "@id": "https://ex.org/ann", "name": "Ann", "tags": map[string]interface{}{"@list": []interface{}{"a", "b", "c"}}, "address": map[string]interface{}{"city": "Oslo"}}, false},
+			//Another value
+			{map[string]interface{}{"@context": ctx, "@id": "https://ex.org/ann", "name": "Ann", "tags": []interface{}{"a", "b"}, "address": map[string]interface{}{"city": "Oslo"}}, false},
+			//A named node is not a blank node
+			{map[string]interface{}{"@context": ctx, "@id": "https://ex.org/ann", "name": "Ann", "tags": []interface{}{"a", "b", "c"}, "address": map[string]interface{}{"@id": "https://ex.org/addr", "city": "Oslo"}}, false},
+		}
+	)
+
+	for i, c := range cases {
+		equal, err := Equal(base, c.doc)
+		if err != nil || equal != c.equal {
+			test.Errorf("Equal %v: %v %v", i, equal, err)
+		}
+	}
+
+	//Two blank nodes with the same statements are not merged
+	one := map[string]interface{}{"@context": ctx, "@id": "https://ex.org/ann", "knows": []interface{}{map[string]interface{}{"name": "Bob"}}}
+	two := map[string]interface{}{"@context": ctx, "@id": "https://ex.org/ann", "knows": []interface{}{map[string]interface{}{"name": "Bob"}, map[string]interface{}{"name": "Bob"}}}
+	if equal, err := Equal(one, two); err != nil || equal {
+		test.Errorf("Equal of one and two blank nodes: %v %v", equal, err)
+	}
+}
@@ -0,0 +1,283 @@
+package jld
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+ExportDOT returns a Graphviz DOT rendering of a document, such as the output of Canonicalize or Frame, for debugging;
+e.g. "dot -Tsvg" draws it. PrintDocument shows the JSON of a document, but the shape of its graph, such as which
+nodes a frame embedded and which it left as references, is easier to see when it is drawn:
+
+	nodes		- a box labelled with the node's @id, the short names of its types and its literal property values
+	edges		- an arrow labelled with the short name of the property, from a node to each node it embeds or
+			  references (or, for a @reverse property, from the node to it)
+	lists		- a record of the list's items; a literal item is shown in its field and a node item has an
+			  arrow from its field
+	named graphs	- a cluster of the graph's nodes labelled with its name
+
+A short name is the part of an IRI after its last '#' or '/'. A node reference to a node that is not in the document
+is drawn as a dashed box. A node with an @id is drawn once, however often it is embedded, so a cyclic document (e.g.
+after ResolveRefs) can be drawn.
+*/
+func ExportDOT(input interface{}) string {
+	var (
+		d   = dotWriter{ids: make(map[string]string), declared: make(map[string]bool)}
+		out strings.Builder
+	)
+
+	d.nodes.WriteString("digraph jld {\n\trankdir=LR;\n\tnode [shape=box, fontname=\"Helvetica\"];\n\tedge [fontname=\"Helvetica\", fontsize=10];\n")
+	d.value(input, 0)
+
+	out.WriteString(d.nodes.String())
+
+	//References to nodes that are not in the document
+	for _, id := range d.order {
+		if !d.declared[id] {
+			fmt.Fprintf(&out, "\t%v [label=%v, style=dashed];\n", d.ids[id], dotQuote(id))
+		}
+	}
+	out.WriteString(d.edges.String())
+	out.WriteString("}\n")
+	return out.String()
+}
+
+//dotWriter holds the state of an ExportDOT
+type dotWriter struct {
+	nodes    strings.Builder
+	edges    strings.Builder
+	ids      map[string]string
+	order    []string
+	declared map[string]bool
+	n        int
+	indent   string
+}
+
+//next returns a new DOT identifier with a prefix
+func (d *dotWriter) next(prefix string) string {
+	d.n++
+	return fmt.Sprintf("%v%d", prefix, d.n)
+}
+
+//dotID returns the DOT identifier of a node @id
+func (d *dotWriter) dotID(id string) string {
+	if dotID, ok := d.ids[id]; ok {
+		return dotID
+	}
+	d.ids[id] = d.next("n")
+	d.order = append(d.order, id)
+	return d.ids[id]
+}
+
+/*
+value draws the nodes of a value at a depth and returns the DOT identifiers of the nodes and lists it embeds or
+references, which the caller draws edges to.
+*/
+func (d *dotWriter) value(input interface{}, depth int) []string {
+	var targets []string
+
+	if depth > maxGraphDepth {
+		return nil
+	}
+	switch input.(type) {
+	case []interface{}:
+		for _, item := range input.([]interface{}) {
+			targets = append(targets, d.value(item, depth)...)
+		}
+		return targets
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		if _, ok := obj["@value"]; ok {
+			return nil
+		}
+		if v, ok := obj["@set"]; ok {
+			return d.value(v, depth)
+		}
+		if v, ok := obj["@list"]; ok {
+			return []string{d.list(asArray(v), depth)}
+		}
+		if isGraphObject(obj) {
+			return d.value(obj["@graph"], depth)
+		}
+		if isNamedGraph(obj) {
+			d.graph(obj, depth)
+			if len(obj) == 2 {
+				return nil
+			}
+		}
+		return []string{d.node(obj, depth)}
+	default:
+		return nil
+	}
+}
+
+//graph draws the nodes of a named graph as a cluster
+func (d *dotWriter) graph(obj map[string]interface{}, depth int) {
+	var indent = d.indent
+
+	fmt.Fprintf(&d.nodes, "%v\tsubgraph cluster_%v {\n%v\t\tlabel=%v;\n%v\t\tstyle=rounded;\n", indent, d.next(""), indent, dotQuote(obj["@id"].(string)), indent)
+	d.indent = indent + "\t"
+	d.value(obj["@graph"], depth+1)
+	d.indent = indent
+	fmt.Fprintf(&d.nodes, "%v\t}\n", indent)
+}
+
+//node draws a node, if it has not been drawn, and the nodes it embeds, and returns its DOT identifier
+func (d *dotWriter) node(node map[string]interface{}, depth int) string {
+	var (
+		id, _  = node["@id"].(string)
+		dotID  string
+		lines  []string
+		labels []string
+	)
+
+	if id == "" {
+		dotID = d.next("b")
+	} else {
+		dotID = d.dotID(id)
+		if IsNref(node) || d.declared[id] {
+			return dotID
+		}
+		d.declared[id] = true
+	}
+
+	if id != "" {
+		lines = append(lines, id)
+	}
+	if types, _ := nodeTypes(node); len(types) > 0 {
+		for _, t := range types {
+			labels = append(labels, shortIRI(t))
+		}
+		lines = append(lines, "«"+strings.Join(labels, ", ")+"»")
+	}
+	lines = append(lines, d.properties(node, dotID, false, depth)...)
+	fmt.Fprintf(&d.nodes, "%v\t%v [label=%v];\n", d.indent, dotID, dotQuote(strings.Join(lines, "\n")))
+	return dotID
+}
+
+//properties draws the edges of the properties of a node, or of its @reverse or @nest objects, and returns the label lines of its literal values
+func (d *dotWriter) properties(obj map[string]interface{}, dotID string, reverse bool, depth int) []string {
+	var lines []string
+
+	for _, prop := range Props(obj) {
+		switch prop.ID {
+		case IDP, TypeP, CtxP, "@graph", "@index":
+			continue
+		case "@reverse":
+			if nested, ok := prop.Value.(map[string]interface{}); ok {
+				d.properties(nested, dotID, true, depth)
+			}
+			continue
+		case "@nest":
+			for _, item := range asArray(prop.Value) {
+				if nested, ok := item.(map[string]interface{}); ok {
+					lines = append(lines, d.properties(nested, dotID, reverse, depth)...)
+				}
+			}
+			continue
+		case "@included":
+			d.value(prop.Value, depth)
+			continue
+		}
+
+		name := shortIRI(string(prop.ID))
+		for _, literal := range literals(prop.Value) {
+			lines = append(lines, name+" = "+literal)
+		}
+		for _, target := range d.value(prop.Value, depth+1) {
+			if reverse {
+				fmt.Fprintf(&d.edges, "\t%v -> %v [label=%v];\n", target, dotID, dotQuote(name))
+			} else {
+				fmt.Fprintf(&d.edges, "\t%v -> %v [label=%v];\n", dotID, target, dotQuote(name))
+			}
+		}
+	}
+	return lines
+}
+
+//list draws a list as a record of its items and returns its DOT identifier
+func (d *dotWriter) list(items []interface{}, depth int) string {
+	var (
+		dotID  = d.next("l")
+		fields = make([]string, 0, len(items))
+	)
+
+	for i, item := range items {
+		field := "•"
+		if lits := literals(item); len(lits) > 0 {
+			field = lits[0]
+		}
+		fields = append(fields, fmt.Sprintf("<f%d> %v", i, dotRecordEscape(field)))
+		for _, target := range d.value(item, depth+1) {
+			fmt.Fprintf(&d.edges, "\t%v:f%d -> %v;\n", dotID, i, target)
+		}
+	}
+	if len(fields) == 0 {
+		fields = append(fields, "(empty)")
+	}
+	fmt.Fprintf(&d.nodes, "%v\t%v [shape=record, label=\"%v\"];\n", d.indent, dotID, strings.Join(fields, "|"))
+	return dotID
+}
+
+//literals returns the text of the literal values of a property value: its primitives and value objects
+func literals(input interface{}) []string {
+	var lits []string
+
+	switch input.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		for _, item := range input.([]interface{}) {
+			lits = append(lits, literals(item)...)
+		}
+		return lits
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		v, ok := obj["@value"]
+		if !ok {
+			if set, ok := obj["@set"]; ok {
+				return literals(set)
+			}
+			return nil
+		}
+		lit := fmt.Sprintf("%q", fmt.Sprint(v))
+		if lang, ok := obj["@language"].(string); ok {
+			lit += "@" + lang
+		} else if t, ok := obj["@type"].(string); ok {
+			lit += "^^" + shortIRI(t)
+		}
+		return []string{lit}
+	case string:
+		return []string{fmt.Sprintf("%q", input)}
+	default:
+		return []string{fmt.Sprint(input)}
+	}
+}
+
+//shortIRI returns the part of an IRI after its last '#' or '/', or the IRI if there is none
+func shortIRI(iri string) string {
+	var i = strings.LastIndexAny(iri, "#/")
+
+	if i < 0 || i == len(iri)-1 {
+		return iri
+	}
+	return iri[i+1:]
+}
+
+//dotQuote returns a DOT quoted string whose lines are left justified
+func dotQuote(s string) string {
+	var r = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\l`)
+
+	if strings.Contains(s, "\n") {
+		return `"` + r.Replace(s) + `\l"`
+	}
+	return `"` + r.Replace(s) + `"`
+}
+
+//dotRecordEscape escapes the characters of a record field that DOT would otherwise interpret
+func dotRecordEscape(s string) string {
+	var r = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "{", `\{`, "}", `\}`, "|", `\|`, "<", `\<`, ">", `\>`, "\n", " ")
+
+	return r.Replace(s)
+}
@@ -0,0 +1,48 @@
+package jld
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExportDOT(test *testing.T) {
+	var (
+		doc = map[string]interface{}{
+			"@graph": []interface{}{
+				map[string]interface{}{
+					"@id":                          "https://ex.org/ann",
+					"@type":                        []interface{}{"https://ex.org/types#Person", "https://ex.org/types#Agent"},
+					"https://ex.org/vocab#name":    map[string]interface{}{"@value": "Ann \"A\"", "@language": "en"},
+					"https://ex.org/vocab#knows":   map[string]interface{}{"@id": "https://ex.org/bob"},
+					"https://ex.org/vocab#scores":  map[string]interface{}{"@list": []interface{}{float64(1), map[string]interface{}{"@id": "https://ex.org/ann"}}},
+					"https://ex.org/vocab#partner": map[string]interface{}{"@id": "https://ex.org/ann", "https://ex.org/vocab#name": "Ann"},
+				},
+				map[string]interface{}{
+					"@id":    "https://ex.org/g",
+					"@graph": []interface{}{map[string]interface{}{"@id": "https://ex.org/carl", "@type": "https://ex.org/types#Person"}},
+				},
+			},
+		}
+		dot = ExportDOT(doc)
+	)
+
+	for _, expected := range []string{
+		"digraph jld {",
+		`n1 [label="https://ex.org/ann\l«Person, Agent»\lname = \"Ann \\\"A\\\"\"@en\l"];`,
+		`n2 [label="https://ex.org/bob", style=dashed];`,
+		`n1 -> n2 [label="knows"];`,
+		`n1 -> n1 [label="partner"];`,
+		`[shape=record, label="<f0> 1|<f1> •"];`,
+		`:f1 -> n1;`,
+		"subgraph cluster_",
+		`label="https://ex.org/g";`,
+		`[label="https://ex.org/carl\l«Person»\l"];`,
+	} {
+		if !strings.Contains(dot, expected) {
+			test.Errorf("ExportDOT does not contain %v:\n%v", expected, dot)
+		}
+	}
+	if strings.Count(dot, "label=\"https://ex.org/ann") != 1 {
+		test.Errorf("ExportDOT should draw a node once:\n%v", dot)
+	}
+}
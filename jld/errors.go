@@ -0,0 +1,98 @@
+package jld
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+/*
+The kinds of the errors of the functions that check or change nodes. A NodeError is of one of these kinds, which
+errors.Is reports, so that callers can tell a bad request (e.g. a payload with a bad @type) from a failure of their
+own.
+*/
+var (
+	ErrNotANode     = errors.New("Bad Node")
+	ErrBadType      = errors.New("Bad Node @type")
+	ErrBadID        = errors.New("Bad Node ID")
+	ErrBadProperty  = errors.New("Bad Property")
+	ErrBadValue     = errors.New("Bad Value")
+	ErrNotAList     = errors.New("Not A List")
+	ErrBadKeyword   = errors.New("Unknown Keyword")
+	ErrBadIRI       = errors.New("Bad IRI")
+	ErrNodeNotFound = errors.New("Node Not Found")
+	ErrNoProof      = errors.New("Missing Proof")
+	ErrBadProof     = errors.New("Bad Proof")
+	ErrBadIndex     = errors.New("List Index Out Of Range")
+	ErrBadPointer   = errors.New("Bad Pointer")
+)
+
+/*
+A NodeError is an error in a node of a document. Kind is one of the Err kinds above. ID is the @id of the node, if it
+has one, and Path is the Pointer to the failure from the input of the function that returned the error (e.g. the
+property of SetP or the @type item of CheckStrict), so that an API handler can tell a client what to fix:
+
+	var nodeErr *jld.NodeError
+	if errors.As(err, &nodeErr) {
+		http.Error(w, nodeErr.Error(), http.StatusBadRequest)
+	}
+
+Detail says what is wrong, e.g. "42 of type float64 is not a type IRI". ID, Path and Detail may be empty.
+*/
+type NodeError struct {
+	Kind   error
+	ID     string
+	Path   string
+	Detail string
+}
+
+/*
+Error returns the kind and detail of the error followed by the node @id and path, e.g.
+"Bad Value: 3+4i of type complex128 (node https://ex.org/a at /https:~1~1ex.org~1vocab#age)".
+*/
+func (e *NodeError) Error() string {
+	var (
+		b     strings.Builder
+		where []string
+	)
+
+	b.WriteString(e.Kind.Error())
+	if e.Detail != "" {
+		b.WriteString(": ")
+		b.WriteString(e.Detail)
+	}
+	if e.ID != "" {
+		where = append(where, "node "+e.ID)
+	}
+	if e.Path != "" {
+		where = append(where, "at "+e.Path)
+	}
+	if len(where) > 0 {
+		b.WriteString(" (")
+		b.WriteString(strings.Join(where, " "))
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+/*
+Unwrap returns the kind of the error, for errors.Is.
+*/
+func (e *NodeError) Unwrap() error {
+	return e.Kind
+}
+
+//nodeError returns a NodeError of a kind in a node, whose @id it carries, at a path
+func nodeError(kind error, input interface{}, path string, format string, args ...interface{}) error {
+	var e = NodeError{Kind: kind, Path: path, Detail: fmt.Sprintf(format, args...)}
+
+	if node, ok := input.(map[string]interface{}); ok {
+		e.ID, _ = node["@id"].(string)
+	}
+	return &e
+}
+
+//notANode returns the error of an input that is not a node
+func notANode(input interface{}) error {
+	return nodeError(ErrNotANode, nil, "", "%T is not a node object", input)
+}
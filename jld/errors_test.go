@@ -0,0 +1,134 @@
+package jld
+
+import (
+	"crypto"
+	"errors"
+	"io"
+	"net/url"
+	"testing"
+)
+
+func TestNodeError(test *testing.T) {
+	var (
+		personT = NewTypeID("https://ex.org/types#Person", "")
+		ageP    = NewPropID("https://ex.org/vocab#age", "")
+		node    = NewN("https://ex.org/ann", personT)
+		nodeErr *NodeError
+		err     error
+	)
+
+	err = SetP("ann", ageP, 42)
+	if !errors.Is(err, ErrNotANode) {
+		test.Errorf("SetP of a non-node: %v", err)
+	}
+
+	err = SetP(node, ageP, complex(3, 4))
+	if !errors.Is(err, ErrBadValue) || !errors.As(err, &nodeErr) {
+		test.Fatalf("SetP of a bad value: %v", err)
+	}
+	if nodeErr.ID != "https://ex.org/ann" || nodeErr.Path != Pointer(ageP.URI()) {
+		test.Errorf("SetP of a bad value: %#v", nodeErr)
+	}
+	if err.Error() != "Bad Value: (3+4i) of type complex128 (node https://ex.org/ann at /https:~1~1ex.org~1vocab#age)" {
+		test.Errorf("SetP of a bad value: %v", err)
+	}
+
+	node["@type"] = []interface{}{personT.URI(), 42}
+	err = AddTypes(node, personT)
+	if !errors.Is(err, ErrBadType) || !errors.As(err, &nodeErr) || nodeErr.Path != "/@type/1" {
+		test.Errorf("AddTypes to a bad @type: %v", err)
+	}
+
+	_, err = Append(node, ageP, 42)
+	if !errors.Is(err, ErrBadProperty) {
+		test.Errorf("Append to a missing property: %v", err)
+	}
+
+	err = CheckStrict(map[string]interface{}{
+		"@graph": []interface{}{
+			map[string]interface{}{"@id": "https://ex.org/ann"},
+			map[string]interface{}{"@id": "https://ex.org/bob", "@type": []interface{}{"Person"}},
+		},
+	})
+	if !errors.Is(err, ErrBadType) || !errors.As(err, &nodeErr) {
+		test.Fatalf("CheckStrict of a relative @type: %v", err)
+	}
+	if nodeErr.ID != "https://ex.org/bob" || nodeErr.Path != "/@graph/1/@type/0" {
+		test.Errorf("CheckStrict of a relative @type: %#v", nodeErr)
+	}
+
+	err = CheckStrict(map[string]interface{}{"@id": "https://ex.org/ann", "@frobnicate": true})
+	if !errors.Is(err, ErrBadKeyword) {
+		test.Errorf("CheckStrict of an unknown keyword: %v", err)
+	}
+
+	err = NewIDPolicy(AbsoluteIDs()).Validate("people/ann")
+	if !errors.Is(err, ErrBadID) || !errors.As(err, &nodeErr) || nodeErr.ID != "people/ann" {
+		test.Errorf("Validate of a relative IRI: %v", err)
+	}
+
+	_, err = topNodes([]interface{}{node, "bob"})
+	if !errors.Is(err, ErrNotANode) || !errors.As(err, &nodeErr) || nodeErr.Path != "/1" {
+		test.Errorf("topNodes of a non-node: %v", err)
+	}
+}
+
+func TestNodeErrorKinds(test *testing.T) {
+	var (
+		stepsP  = NewPropID("https://ex.org/vocab#steps", "")
+		node    = NewN("https://ex.org/plan", NewTypeID("https://ex.org/types#Plan", ""))
+		base, _ = url.Parse("https://ex.org/")
+		nodeErr *NodeError
+		err     error
+	)
+
+	node[stepsP.URI()] = NewL([]interface{}{"a"})
+	_, err = InsertAt(node, stepsP, 2, "b")
+	if !errors.Is(err, ErrBadIndex) || !errors.As(err, &nodeErr) || nodeErr.Path != Pointer(stepsP.URI(), "@list", "2") {
+		test.Errorf("InsertAt out of range: %v", err)
+	}
+	_, err = RemoveAt(node, stepsP, -1)
+	if !errors.Is(err, ErrBadIndex) {
+		test.Errorf("RemoveAt out of range: %v", err)
+	}
+	_, err = NewListFrom(42)
+	if !errors.Is(err, ErrNotAList) {
+		test.Errorf("NewListFrom of a non-slice: %v", err)
+	}
+	_, err = NewListFrom([]interface{}{"a", complex(1, 1)})
+	if !errors.Is(err, ErrBadValue) || !errors.As(err, &nodeErr) || nodeErr.Path != "/1" {
+		test.Errorf("NewListFrom of a bad item: %v", err)
+	}
+
+	err = resolveIDs(map[string]interface{}{"@id": "%zz"}, base)
+	if !errors.Is(err, ErrBadID) || !errors.As(err, &nodeErr) || nodeErr.ID != "%zz" {
+		test.Errorf("resolveIDs of a bad @id: %v", err)
+	}
+
+	for _, pointer := range []string{"steps", "", "/none/a", Pointer(stepsP.URI(), "@list", "5"), Pointer(stepsP.URI(), "@list", "@id=https://ex.org/a")} {
+		err = SetAtPointer(node, pointer, "x")
+		if !errors.Is(err, ErrBadPointer) || !errors.As(err, &nodeErr) {
+			test.Errorf("SetAtPointer %v: %v", pointer, err)
+		}
+	}
+	err = SetAtPointer([]interface{}{}, "/-", "x")
+	if !errors.Is(err, ErrBadPointer) {
+		test.Errorf("SetAtPointer appending to the document: %v", err)
+	}
+
+	err = Sign(node, unsupportedSigner{}, "https://ex.org/keys#1")
+	if !errors.Is(err, ErrBadProof) {
+		test.Errorf("Sign with an unsupported key: %v", err)
+	}
+}
+
+//unsupportedSigner is a crypto.Signer of a key type that Sign does not support
+type unsupportedSigner struct{}
+
+func (unsupportedSigner) Public() crypto.PublicKey {
+	return "not a key"
+}
+
+func (unsupportedSigner) Sign(io.Reader, []byte, crypto.SignerOpts) ([]byte, error) {
+	return nil, errors.New("unsupported")
+}
@@ -0,0 +1,91 @@
+package jld
+
+import (
+	"strings"
+)
+
+/*
+Extract returns a standalone copy of the node with the @id in a document (e.g. a large stored graph) in which the
+nodes it references are embedded up to depth levels; deeper references become node references. A depth of 0 returns
+the node with all of its references as node references.
+
+Blank nodes are embedded whatever their depth since a reference to one is meaningless outside of the document, and a
+reference back to a node that is already being embedded is left as a node reference so that the result is not cyclic.
+The result does not share maps or slices with the document, but the document is indexed with NewGraph, which merges
+the properties of duplicate node definitions into the first.
+*/
+func Extract(doc interface{}, id string, depth int) (map[string]interface{}, error) {
+	var (
+		g    *Graph
+		root map[string]interface{}
+		ok   bool
+		err  error
+	)
+
+	g, err = NewGraph(doc)
+	if err != nil {
+		return nil, err
+	}
+	root, ok = g.GetByID(id)
+	if !ok {
+		return nil, &NodeError{Kind: ErrNodeNotFound, ID: id}
+	}
+	if depth < 0 {
+		depth = 0
+	}
+	return extractNode(g, root, depth, map[string]bool{id: true}, 0), nil
+}
+
+//extractNode copies a node, embedding the nodes it references while the remaining depth allows
+func extractNode(g *Graph, node map[string]interface{}, depth int, path map[string]bool, nesting int) map[string]interface{} {
+	var cp = make(map[string]interface{}, len(node))
+
+	for k, v := range node {
+		switch k {
+		case "@id", "@type", "@context":
+			cp[k] = DeepCopy(v)
+		default:
+			cp[k] = extractValue(g, v, depth, path, nesting+1)
+		}
+	}
+	return cp
+}
+
+//extractValue copies a property value of an extracted node
+func extractValue(g *Graph, input interface{}, depth int, path map[string]bool, nesting int) interface{} {
+	if nesting > maxGraphDepth {
+		return nil
+	}
+	switch input.(type) {
+	case []interface{}:
+		items := input.([]interface{})
+		cp := make([]interface{}, len(items))
+		for i, item := range items {
+			cp[i] = extractValue(g, item, depth, path, nesting+1)
+		}
+		return cp
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		if _, ok := obj["@value"]; ok {
+			return DeepCopy(obj)
+		}
+		id, hasID := obj["@id"].(string)
+		target, indexed := g.GetByID(id)
+		if !hasID || !indexed {
+			//A node that is only embedded here, or a list or set object, is copied inline
+			return extractNode(g, obj, depth, path, nesting)
+		}
+		blank := strings.HasPrefix(id, "_:")
+		if path[id] || (depth == 0 && !blank) {
+			return map[string]interface{}{"@id": id}
+		}
+		path[id] = true
+		defer delete(path, id)
+		if blank {
+			return extractNode(g, target, depth, path, nesting)
+		}
+		return extractNode(g, target, depth-1, path, nesting)
+	default:
+		return input
+	}
+}
@@ -0,0 +1,66 @@
+package jld
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestExtract(test *testing.T) {
+	var (
+		knows = "https://ex.org/vocab#knows"
+		addr  = "https://ex.org/vocab#address"
+		doc   = []interface{}{
+			map[string]interface{}{
+				"@id":  "https://ex.org/ann",
+				knows:  []interface{}{map[string]interface{}{"@id": "https://ex.org/bob"}},
+				addr:   []interface{}{map[string]interface{}{"@id": "_:a1"}},
+				"name": []interface{}{map[string]interface{}{"@value": "Ann"}},
+			},
+			map[string]interface{}{"@id": "https://ex.org/bob", knows: []interface{}{map[string]interface{}{"@id": "https://ex.org/carol"}}},
+			map[string]interface{}{"@id": "https://ex.org/carol", knows: []interface{}{map[string]interface{}{"@id": "https://ex.org/ann"}}},
+			map[string]interface{}{"@id": "_:a1", "https://ex.org/vocab#city": []interface{}{map[string]interface{}{"@value": "Oslo"}}},
+		}
+		before, _ = json.Marshal(doc)
+		after     []byte
+		ann       map[string]interface{}
+		err       error
+	)
+
+	ann, err = Extract(doc, "https://ex.org/ann", 0)
+	if err != nil {
+		test.Fatalf("Extract: %v", err)
+	}
+	if !IsNref(ann[knows].([]interface{})[0]) {
+		test.Errorf("Depth 0 should leave bob as a reference: %v", ann[knows])
+	}
+	if IsNref(ann[addr].([]interface{})[0]) {
+		test.Errorf("Blank nodes should always be embedded: %v", ann[addr])
+	}
+
+	ann, err = Extract(doc, "https://ex.org/ann", 5)
+	if err != nil {
+		test.Fatalf("Extract: %v", err)
+	}
+	bob := ann[knows].([]interface{})[0].(map[string]interface{})
+	carol := bob[knows].([]interface{})[0].(map[string]interface{})
+	if carol["@id"] != "https://ex.org/carol" || !IsNref(carol[knows].([]interface{})[0]) {
+		test.Errorf("The reference back to ann should be a node reference: %v", carol)
+	}
+
+	ann, err = Extract(doc, "https://ex.org/ann", 1)
+	if err != nil {
+		test.Fatalf("Extract: %v", err)
+	}
+	bob = ann[knows].([]interface{})[0].(map[string]interface{})
+	if IsNref(bob) || !IsNref(bob[knows].([]interface{})[0]) {
+		test.Errorf("Depth 1 should embed bob and leave carol as a reference: %v", bob)
+	}
+
+	after, _ = json.Marshal(doc)
+	if string(before) != string(after) {
+		test.Errorf("Extract should not change the document")
+	}
+	if _, err = Extract(doc, "https://ex.org/dan", 1); err == nil {
+		test.Errorf("Extract of a missing node should fail")
+	}
+}
@@ -0,0 +1,138 @@
+package jld
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/kazarena/json-gold/ld"
+	"github.com/pborman/uuid"
+)
+
+//listPlaceholderType returns a new @type for the value objects that stand in for lists while a document is framed
+func listPlaceholderType() string {
+	return "urn:uuid:" + uuid.NewRandom().String()
+}
+
+/*
+Frame frames a document with a JSON LD frame and returns the framed document, whose nodes are its @graph.
+
+The ld package's Frame drops the content of @list values, so its output diverges from the Node jsonld module's.
+This wrapper works around that: before framing, each list is replaced by a placeholder value object, whose @type is a
+random IRI new for each Frame so that no value of the input can be taken for one, and the nodes in lists are kept in the document as top level nodes; after framing, each placeholder is replaced by its list, in which
+node references are replaced by their nodes. Lists are re-embedded as they were expanded rather than framed, so the
+nodes in them are not filtered or further embedded by the frame.
+
+Options such as WithLoader and Strict configure the processing of the input; the frame is used as is.
+*/
+func Frame(input interface{}, frame interface{}, opts ...Option) (map[string]interface{}, error) {
+	var (
+		o         = newOptions(opts)
+		marker    = listPlaceholderType()
+		expanded  []interface{}
+		extracted []interface{}
+		lists     [][]interface{}
+		nodes     []interface{}
+		graph     *Graph
+		framed    map[string]interface{}
+		err       error
+	)
+
+	expanded, err = ExpandCtx(context.Background(), input, opts...)
+	if err != nil {
+		return nil, err
+	}
+	extracted = append(extractLists(expanded, marker, &lists, &nodes).([]interface{}), nodes...)
+	graph, err = NewGraph(expanded)
+	if err != nil {
+		return nil, err
+	}
+
+	framed, err = ld.NewJsonLdProcessor().Frame(extracted, frame, o.ldOptions())
+	if err != nil {
+		return nil, err
+	}
+	return reembedLists(framed, marker, lists, graph).(map[string]interface{}), nil
+}
+
+/*
+extractLists returns a copy of an expanded document in which each list is replaced by a placeholder of the marker type
+holding its index in lists. The nodes in lists, which framing would otherwise lose, are appended to nodes. The marker
+is new for each Frame, so a value object of the input cannot pass for a placeholder.
+*/
+func extractLists(input interface{}, marker string, lists *[][]interface{}, nodes *[]interface{}) interface{} {
+	switch input.(type) {
+	case []interface{}:
+		items := make([]interface{}, 0, len(input.([]interface{})))
+		for _, item := range input.([]interface{}) {
+			items = append(items, extractLists(item, marker, lists, nodes))
+		}
+		return items
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		if IsList(obj) {
+			items, _ := obj["@list"].([]interface{})
+			for _, item := range items {
+				if isNode(item) && !IsNref(item) {
+					*nodes = append(*nodes, extractLists(item, marker, lists, nodes))
+				}
+			}
+			*lists = append(*lists, items)
+			return map[string]interface{}{"@type": marker, "@value": strconv.Itoa(len(*lists) - 1)}
+		}
+		copied := make(map[string]interface{}, len(obj))
+		for k, v := range obj {
+			switch k {
+			case "@id", "@type", "@value", "@context":
+				copied[k] = v
+			default:
+				copied[k] = extractLists(v, marker, lists, nodes)
+			}
+		}
+		return copied
+	default:
+		return input
+	}
+}
+
+//reembedLists replaces the list placeholders of the marker type in a framed document with their lists
+func reembedLists(input interface{}, marker string, lists [][]interface{}, graph *Graph) interface{} {
+	switch input.(type) {
+	case []interface{}:
+		items := input.([]interface{})
+		for i, item := range items {
+			items[i] = reembedLists(item, marker, lists, graph)
+		}
+		return items
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		if obj["@type"] == marker {
+			if s, ok := obj["@value"].(string); ok {
+				if i, err := strconv.Atoi(s); err == nil && i >= 0 && i < len(lists) {
+					return map[string]interface{}{"@list": embedListItems(lists[i], graph)}
+				}
+			}
+		}
+		for k, v := range obj {
+			obj[k] = reembedLists(v, marker, lists, graph)
+		}
+		return obj
+	default:
+		return input
+	}
+}
+
+//embedListItems returns a copy of a list's items with node references replaced by their nodes
+func embedListItems(items []interface{}, graph *Graph) []interface{} {
+	var embedded = make([]interface{}, len(items))
+
+	for i, item := range items {
+		embedded[i] = item
+		if !IsNref(item) {
+			continue
+		}
+		if node, ok := graph.GetByID(nodeID(item)); ok {
+			embedded[i] = node
+		}
+	}
+	return embedded
+}
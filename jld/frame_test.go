@@ -0,0 +1,89 @@
+package jld
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractLists(test *testing.T) {
+	var (
+		stepsP = "https://ex.org/vocab#steps"
+		b      = map[string]interface{}{"@id": "https://ex.org/b", "https://ex.org/vocab#name": []interface{}{map[string]interface{}{"@value": "B"}}}
+		c      = map[string]interface{}{"@id": "https://ex.org/c"}
+		a      = map[string]interface{}{
+			"@id":  "https://ex.org/a",
+			stepsP: []interface{}{map[string]interface{}{"@list": []interface{}{b, map[string]interface{}{"@value": 1.0}, map[string]interface{}{"@id": "https://ex.org/b"}, c}}},
+		}
+		expanded = []interface{}{a}
+		lists    [][]interface{}
+		nodes    []interface{}
+		graph    *Graph
+		err      error
+	)
+
+	extracted := append(extractLists(expanded, "urn:x-test:list", &lists, &nodes).([]interface{}), nodes...)
+	if len(lists) != 1 || len(extracted) != 2 || IsList(extracted[0].(map[string]interface{})[stepsP].([]interface{})[0]) {
+		test.Fatalf("extractLists: %v %v", extracted, lists)
+	}
+	if !IsList(a[stepsP].([]interface{})[0]) {
+		test.Errorf("extractLists changed its input: %v", a)
+	}
+
+	graph, err = NewGraph(expanded)
+	if err != nil {
+		test.Fatal(err)
+	}
+	reembedded := reembedLists(extracted[0], "urn:x-test:list", lists, graph).(map[string]interface{})
+	list, ok := reembedded[stepsP].([]interface{})[0].(map[string]interface{})["@list"].([]interface{})
+	if !ok || len(list) != 4 || !reflect.DeepEqual(list[2], b) || !reflect.DeepEqual(list[3], c) {
+		test.Errorf("reembedLists: %v", reembedded)
+	}
+}
+
+func TestFrameForgedPlaceholder(test *testing.T) {
+	var (
+		stepsP = "https://ex.org/vocab#steps"
+		forged = map[string]interface{}{"@type": "urn:x-jld:list", "@value": "0"}
+		input  = []interface{}{
+			map[string]interface{}{
+				"@id":   "https://ex.org/a",
+				"@type": "https://ex.org/types#Plan",
+				stepsP:  []interface{}{map[string]interface{}{"@list": []interface{}{map[string]interface{}{"@value": "one"}}}},
+			},
+			map[string]interface{}{
+				"@id":   "https://ex.org/b",
+				"@type": "https://ex.org/types#Plan",
+				stepsP:  []interface{}{forged},
+			},
+		}
+		frame = map[string]interface{}{"@type": "https://ex.org/types#Plan"}
+	)
+
+	framed, err := Frame(input, frame)
+	if err != nil {
+		test.Fatalf("Frame: %v", err)
+	}
+	for _, node := range asArray(framed["@graph"]) {
+		node := node.(map[string]interface{})
+		steps := asArray(node[stepsP])
+		if len(steps) != 1 {
+			test.Fatalf("Frame %v: %v", node["@id"], steps)
+		}
+		_, isList := steps[0].(map[string]interface{})["@list"]
+		switch node["@id"] {
+		case "https://ex.org/a":
+			if !isList {
+				test.Errorf("Frame did not re-embed the list: %v", steps)
+			}
+		case "https://ex.org/b":
+			if isList || !reflect.DeepEqual(steps[0], forged) {
+				test.Errorf("Frame replaced a forged placeholder: %v", steps)
+			}
+		}
+	}
+
+	//Another Frame's marker is not taken for a placeholder either
+	if listPlaceholderType() == listPlaceholderType() {
+		test.Errorf("listPlaceholderType is not new for each call")
+	}
+}
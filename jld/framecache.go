@@ -0,0 +1,126 @@
+package jld
+
+import (
+	"context"
+	"strings"
+	"sync"
+)
+
+/*
+The frame of each type filter passed to Canonicalize is built once and cached, keyed by the filter's type URIs in
+order, since services canonicalize many documents with the same few filters. A cached frame is shared by concurrent
+calls; it is safe to share since the ld processor's Frame expands the frame rather than changing it, as
+TestCachedFrameUnchanged checks. TypeFrame returns copies, so its callers cannot change a cached frame either.
+
+The cache holds at most maxCachedFrames frames, so that a service that builds filters dynamically does not grow it
+without bound; the frames of further filters are built on each call.
+*/
+
+//maxCachedFrames is the maximum number of cached frames
+const maxCachedFrames = 256
+
+//frames is the frame cache
+var frames = struct {
+	m sync.RWMutex
+	f map[string]map[string]interface{}
+}{f: make(map[string]map[string]interface{})}
+
+//typeFrame returns the frame that matches the nodes of any of the types of a type filter
+func typeFrame(typeFilter []TypeID) map[string]interface{} {
+	var (
+		uris  = make([]string, len(typeFilter))
+		types = make([]interface{}, len(typeFilter))
+		key   string
+		frame map[string]interface{}
+		ok    bool
+	)
+
+	for i, typeID := range typeFilter {
+		uris[i] = typeID.URI()
+		types[i] = typeID.URI()
+	}
+	key = strings.Join(uris, " ")
+
+	frames.m.RLock()
+	frame, ok = frames.f[key]
+	frames.m.RUnlock()
+	if ok {
+		return frame
+	}
+
+	frame = map[string]interface{}{"@type": types}
+	frames.m.Lock()
+	defer frames.m.Unlock()
+	if len(frames.f) < maxCachedFrames {
+		frames.f[key] = frame
+	}
+	return frame
+}
+
+/*
+TypeFrame returns the frame with which Canonicalize frames the nodes of a type filter and the options: one that matches
+the nodes of any of the types, with the defaults of WithDefault and, with RequireAllTypes, @requireAll. It may be
+used with Frame, which uses a frame as is, so the nodes framed with it are not filtered by RequireAllTypes. The frame
+is a copy, which the caller may change, e.g. to add properties to match, without changing the cached frame.
+*/
+func TypeFrame(typeFilter []TypeID, opts ...Option) map[string]interface{} {
+	return DeepCopy(newOptions(opts).frame(typeFilter)).(map[string]interface{})
+}
+
+//frame returns the frame of a type filter and the options; a frame with neither defaults nor @requireAll is cached
+func (o *options) frame(typeFilter []TypeID) map[string]interface{} {
+	var (
+		cached = typeFrame(typeFilter)
+		frame  map[string]interface{}
+	)
+
+	if len(o.defaults) == 0 && !o.allTypes {
+		return cached
+	}
+	frame = make(map[string]interface{}, len(cached)+len(o.defaults)+1)
+	for k, v := range cached {
+		frame[k] = v
+	}
+	for uri, value := range o.defaults {
+		frame[uri] = map[string]interface{}{"@default": value}
+	}
+	if o.allTypes {
+		frame["@requireAll"] = true
+	}
+	return frame
+}
+
+/*
+matchAllTypes returns the framed nodes that have all the types of a frame if the options have RequireAllTypes. The
+Context is checked before each node.
+*/
+func (o *options) matchAllTypes(ctx context.Context, nodes []interface{}, frame map[string]interface{}) ([]interface{}, error) {
+	var (
+		types, _ = frame["@type"].([]interface{})
+		matched  = nodes[:0]
+	)
+
+	if !o.allTypes {
+		return nodes, nil
+	}
+	for _, item := range nodes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		node, ok := item.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		all := true
+		for _, t := range types {
+			if uri, _ := t.(string); !hasType(node, TypeID(uri)) {
+				all = false
+				break
+			}
+		}
+		if all {
+			matched = append(matched, node)
+		}
+	}
+	return matched, nil
+}
@@ -0,0 +1,136 @@
+package jld
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestTypeFrame(test *testing.T) {
+	var (
+		aT = NewTypeID("https://ex.org/vocab#A", "")
+		bT = NewTypeID("https://ex.org/vocab#B", "")
+	)
+
+	frame := typeFrame([]TypeID{aT, bT})
+	if !reflect.DeepEqual(frame, map[string]interface{}{"@type": []interface{}{aT.URI(), bT.URI()}}) {
+		test.Errorf("typeFrame: %v", frame)
+	}
+	if reflect.ValueOf(typeFrame([]TypeID{aT, bT})).Pointer() != reflect.ValueOf(frame).Pointer() {
+		test.Errorf("typeFrame was not cached")
+	}
+	if other := typeFrame([]TypeID{bT}); len(other["@type"].([]interface{})) != 1 {
+		test.Errorf("typeFrame of another filter: %v", other)
+	}
+}
+
+func TestTypeFrameOptions(test *testing.T) {
+	var (
+		aT    = NewTypeID("https://ex.org/vocab#A", "")
+		bT    = NewTypeID("https://ex.org/vocab#B", "")
+		nameP = NewPropID("https://ex.org/vocab#name", "")
+		noteP = NewPropID("https://ex.org/vocab#note", "")
+	)
+
+	if frame := TypeFrame([]TypeID{aT}); !reflect.DeepEqual(frame, typeFrame([]TypeID{aT})) {
+		test.Errorf("TypeFrame without options: %v", frame)
+	}
+	frame := TypeFrame([]TypeID{aT, bT}, WithDefault(nameP, "anon"), WithDefault(noteP, nil), RequireAllTypes())
+	expected := map[string]interface{}{
+		"@type":       []interface{}{aT.URI(), bT.URI()},
+		nameP.URI():   map[string]interface{}{"@default": "anon"},
+		noteP.URI():   map[string]interface{}{"@default": "@null"},
+		"@requireAll": true,
+	}
+	if !reflect.DeepEqual(frame, expected) {
+		test.Errorf("TypeFrame with options: %v", frame)
+	}
+	if _, ok := typeFrame([]TypeID{aT, bT})["@requireAll"]; ok {
+		test.Errorf("TypeFrame changed the cached frame")
+	}
+
+	//A TypeFrame is the caller's to change
+	changed := TypeFrame([]TypeID{aT})
+	changed["@type"].([]interface{})[0] = bT.URI()
+	changed[nameP.URI()] = map[string]interface{}{}
+	if cached := typeFrame([]TypeID{aT}); !reflect.DeepEqual(cached, map[string]interface{}{"@type": []interface{}{aT.URI()}}) {
+		test.Errorf("A change to a TypeFrame changed the cached frame: %v", cached)
+	}
+
+	nodes := []interface{}{
+		map[string]interface{}{"@id": "https://ex.org/a", "@type": aT.URI()},
+		map[string]interface{}{"@id": "https://ex.org/ab", "@type": []interface{}{bT.URI(), aT.URI()}},
+		map[string]interface{}{"@id": "https://ex.org/b", "@type": bT.URI()},
+	}
+	if matched, _ := newOptions(nil).matchAllTypes(context.Background(), append([]interface{}{}, nodes...), frame); len(matched) != 3 {
+		test.Errorf("matchAllTypes without RequireAllTypes: %v", matched)
+	}
+	matched, _ := newOptions([]Option{RequireAllTypes()}).matchAllTypes(context.Background(), nodes, frame)
+	if len(matched) != 1 || nodeID(matched[0].(map[string]interface{})) != "https://ex.org/ab" {
+		test.Errorf("matchAllTypes: %v", matched)
+	}
+}
+
+//TestCachedFrameUnchanged checks that framing does not change a cached frame, which the frame cache relies on
+func TestCachedFrameUnchanged(test *testing.T) {
+	var (
+		filter   = []TypeID{NewTypeID("https://ex.org/vocab#A", ""), NewTypeID("https://ex.org/vocab#C", "")}
+		nameP    = NewPropID("https://ex.org/vocab#name", "")
+		snapshot = DeepCopy(typeFrame(filter))
+		results  []interface{}
+	)
+
+	for _, opts := range [][]Option{nil, nil, {WithDefault(nameP, "anon"), RequireAllTypes()}} {
+		result, err := Canonicalize(benchmarkDoc, filter, opts...)
+		if err != nil {
+			test.Fatalf("Canonicalize: %v", err)
+		}
+		results = append(results, result)
+		if !reflect.DeepEqual(typeFrame(filter), snapshot) {
+			test.Fatalf("Canonicalize changed the cached frame: %v", typeFrame(filter))
+		}
+	}
+	if !reflect.DeepEqual(results[0], results[1]) {
+		test.Errorf("Canonicalize with a cached frame: %v, not %v", results[1], results[0])
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := Frame(benchmarkDoc, typeFrame(filter)); err != nil {
+			test.Fatalf("Frame: %v", err)
+		}
+		if !reflect.DeepEqual(typeFrame(filter), snapshot) {
+			test.Fatalf("Frame changed the cached frame: %v", typeFrame(filter))
+		}
+	}
+}
+
+//benchmarkDoc is a small document canonicalized by the benchmarks
+var benchmarkDoc = map[string]interface{}{
+	"@context": map[string]interface{}{"@vocab": "https://ex.org/vocab#"},
+	"@graph": []interface{}{
+		map[string]interface{}{"@id": "https://ex.org/a", "@type": "A", "name": "a", "b": map[string]interface{}{"@id": "https://ex.org/b"}},
+		map[string]interface{}{"@id": "https://ex.org/b", "@type": "B", "name": "b"},
+	},
+}
+
+//benchmarkFilter is the type filter of the benchmarks
+var benchmarkFilter = []TypeID{NewTypeID("https://ex.org/vocab#A", ""), NewTypeID("https://ex.org/vocab#B", "")}
+
+func BenchmarkCanonicalize(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := Canonicalize(benchmarkDoc, benchmarkFilter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+//BenchmarkCanonicalizeUncached clears the frame cache before each Canonicalize, for comparison with BenchmarkCanonicalize
+func BenchmarkCanonicalizeUncached(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		frames.m.Lock()
+		frames.f = make(map[string]map[string]interface{})
+		frames.m.Unlock()
+		if _, err := Canonicalize(benchmarkDoc, benchmarkFilter); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
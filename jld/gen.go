@@ -0,0 +1,177 @@
+package jld
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+/*
+GenerateIDs generates the Go source of a file of package pkg that declares a PropID or TypeID var for every term of
+a JSON LD context, so that a service's IDs are generated from its vocabulary's context rather than re-declared by
+hand. The context may be a context document ({"@context": {...}}) or the context object itself; source names it in
+the generated file's header. The jldgen command runs it from a go:generate directive.
+
+A term's IRI is its definition's IRI, which may be absolute, a compact IRI of a prefix defined in the context, or
+relative to @vocab; a term without an IRI is relative to @vocab. Prefix definitions (IRIs ending in "/" or "#" and
+terms with @prefix true), keyword aliases and null terms are not generated.
+
+A term is a TypeID if it is capitalized and its definition has none of @type, @container or @reverse, following the
+RDF convention that classes are capitalized; otherwise it is a PropID. The var of a term is its name made into an
+exported Go identifier with a P or T suffix (e.g. name is NameP and Person is PersonT).
+*/
+func GenerateIDs(context interface{}, pkg, source string) ([]byte, error) {
+	var (
+		ctx     map[string]interface{}
+		vocab   string
+		ids     = make(map[string]string)
+		isType  = make(map[string]bool)
+		names   []string
+		qualify = "jld."
+		buf     bytes.Buffer
+		src     []byte
+		ok      bool
+		err     error
+	)
+
+	ctx, ok = context.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("Bad Context")
+	}
+	if inner, isDoc := ctx["@context"]; isDoc {
+		ctx, ok = inner.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("Bad Context: @context must be an object")
+		}
+	}
+	vocab, _ = ctx["@vocab"].(string)
+
+	for term, def := range ctx {
+		var (
+			iri     string
+			defMap  map[string]interface{}
+			varName string
+		)
+
+		if strings.HasPrefix(term, "@") || def == nil {
+			continue
+		}
+		switch def.(type) {
+		case string:
+			iri = def.(string)
+		case map[string]interface{}:
+			defMap = def.(map[string]interface{})
+			if defMap["@prefix"] == true {
+				continue
+			}
+			iri, _ = defMap["@id"].(string)
+			if iri == "" {
+				iri, _ = defMap["@reverse"].(string)
+			}
+			if iri == "" && defMap["@id"] == nil {
+				iri = term
+			}
+		default:
+			return nil, fmt.Errorf("Bad Term Definition: %v", term)
+		}
+		if strings.HasPrefix(iri, "@") || strings.HasSuffix(iri, "/") || strings.HasSuffix(iri, "#") {
+			continue
+		}
+		iri = expandTerm(ctx, vocab, iri)
+		if iri == "" {
+			return nil, fmt.Errorf("Term %v has no IRI and the context has no @vocab", term)
+		}
+
+		typeTerm := unicode.IsUpper([]rune(term)[0]) && defMap["@type"] == nil && defMap["@container"] == nil && defMap["@reverse"] == nil
+		varName = goName(term)
+		if varName == "" {
+			return nil, fmt.Errorf("Term %v is not a Go identifier", term)
+		}
+		if typeTerm {
+			varName += "T"
+		} else {
+			varName += "P"
+		}
+		if _, dup := ids[varName]; dup {
+			return nil, fmt.Errorf("Terms map to the same var: %v", varName)
+		}
+		ids[varName] = iri
+		isType[varName] = typeTerm
+		names = append(names, varName)
+	}
+	sort.Strings(names)
+
+	if pkg == "jld" {
+		qualify = ""
+	}
+	fmt.Fprintf(&buf, "// Code generated by jldgen from %v. DO NOT EDIT.\n\npackage %v\n\n", source, pkg)
+	if qualify != "" {
+		fmt.Fprintf(&buf, "import \"github.com/develrns/resilient/jld\"\n\n")
+	}
+	fmt.Fprintf(&buf, "var (\n")
+	for _, name := range names {
+		if isType[name] {
+			fmt.Fprintf(&buf, "\t//%v is the type %v\n\t%v = %vNewTypeID(%q, \"\")\n\n", name, ids[name], name, qualify, ids[name])
+		} else {
+			fmt.Fprintf(&buf, "\t//%v is the property %v\n\t%v = %vNewPropID(%q, \"\")\n\n", name, ids[name], name, qualify, ids[name])
+		}
+	}
+	fmt.Fprintf(&buf, ")\n")
+
+	src, err = format.Source(buf.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	return src, nil
+}
+
+//expandTerm expands a compact IRI of a context prefix or a @vocab relative IRI; it is "" if it cannot be expanded
+func expandTerm(ctx map[string]interface{}, vocab, iri string) string {
+	var parts = strings.SplitN(iri, ":", 2)
+
+	if len(parts) == 2 && !strings.HasPrefix(parts[1], "//") {
+		switch prefix := ctx[parts[0]].(type) {
+		case string:
+			return prefix + parts[1]
+		case map[string]interface{}:
+			if id, ok := prefix["@id"].(string); ok {
+				return id + parts[1]
+			}
+		}
+	}
+	if strings.Contains(iri, ":") {
+		return iri
+	}
+	if vocab == "" {
+		return ""
+	}
+	return vocab + iri
+}
+
+//goName makes a term into an exported Go identifier by dropping the characters that are not letters or digits and
+//capitalizing the letters that follow them; it is "" if the term does not start with a letter
+func goName(term string) string {
+	var (
+		name  []rune
+		upper = true
+	)
+
+	for _, r := range term {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if len(name) == 0 && !unicode.IsLetter(r) {
+			return ""
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		name = append(name, r)
+	}
+	return string(name)
+}
@@ -0,0 +1,52 @@
+package jld
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateIDs(test *testing.T) {
+	var (
+		context = map[string]interface{}{
+			"@context": map[string]interface{}{
+				"@vocab":  "https://ex.org/vocab#",
+				"schema":  "http://schema.org/",
+				"id":      "@id",
+				"name":    "schema:name",
+				"Person":  "https://ex.org/types#Person",
+				"born":    map[string]interface{}{"@id": "schema:birthDate", "@type": "xsd:date"},
+				"knows":   map[string]interface{}{"@container": "@set"},
+				"Nothing": nil,
+			},
+		}
+		src []byte
+		err error
+	)
+
+	src, err = GenerateIDs(context, "vocab", "vocab.jsonld")
+	if err != nil {
+		test.Fatalf("GenerateIDs: %v", err)
+	}
+	for _, expected := range []string{
+		"// Code generated by jldgen from vocab.jsonld. DO NOT EDIT.",
+		`import "github.com/develrns/resilient/jld"`,
+		`NameP = jld.NewPropID("http://schema.org/name", "")`,
+		`BornP = jld.NewPropID("http://schema.org/birthDate", "")`,
+		`KnowsP = jld.NewPropID("https://ex.org/vocab#knows", "")`,
+		`PersonT = jld.NewTypeID("https://ex.org/types#Person", "")`,
+	} {
+		if !strings.Contains(string(src), expected) {
+			test.Errorf("Generated source lacks %v:\n%s", expected, src)
+		}
+	}
+	for _, unexpected := range []string{"SchemaP", "IdP", "NothingT"} {
+		if strings.Contains(string(src), unexpected) {
+			test.Errorf("Generated source should not have %v:\n%s", unexpected, src)
+		}
+	}
+
+	_, err = GenerateIDs(map[string]interface{}{"name": "name"}, "vocab", "x")
+	if err == nil {
+		test.Errorf("A relative term without @vocab should fail")
+	}
+}
@@ -0,0 +1,195 @@
+package jld
+
+import (
+	"time"
+)
+
+/*
+Get gets the property of a node as a T, unwrapping a value object's @value, so that callers need not assert and unwrap
+it themselves:
+
+	name, ok := Get[string](person, nameP)
+	age, ok := Get[int64](person, ageP)
+	born, ok := Get[time.Time](person, bornP)
+	employer, ok := Get[map[string]interface{}](person, employerP)
+
+int64, int, float64 and time.Time properties are converted as GetInt, GetFloat and GetTime convert them, so e.g. an
+xsd:integer value object with a string value is an int64. Any other T, such as string or bool, is the type of the
+unwrapped value. It returns false if the node does not have the property or it is not a T; a set or list is not a T
+unless T is []interface{} or map[string]interface{}, and GetSlice gets their items.
+*/
+func Get[T any](input interface{}, propID PropID) (T, bool) {
+	var (
+		zero  T
+		node  map[string]interface{}
+		propI interface{}
+		ok    bool
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return zero, false
+	}
+	_, propI, ok = propValue(node, propID)
+	if !ok {
+		return zero, false
+	}
+	return itemAs[T](propI, propID)
+}
+
+/*
+GetSlice gets the values of a node's property as a []T, converting each as Get does. The property may be a singleton,
+an array or a set or list object. It returns false if the node does not have the property or any of its values is not
+a T, rather than dropping them. Unlike GetSet and GetList, it does not change the node.
+*/
+func GetSlice[T any](input interface{}, propID PropID) ([]T, bool) {
+	var (
+		items []interface{}
+		slice []T
+		ok    bool
+	)
+
+	items, ok = propItems(input, propID)
+	if !ok || items == nil {
+		return nil, ok
+	}
+	slice = make([]T, 0, len(items))
+	for _, item := range items {
+		v, ok := itemAs[T](item, propID)
+		if !ok {
+			return nil, false
+		}
+		slice = append(slice, v)
+	}
+	return slice, true
+}
+
+/*
+GetStrings gets the values of a node's property as a []string, as GetSlice[string] does: each value may be a string
+or a value object whose @value is a string, such as a language-tagged string.
+*/
+func GetStrings(input interface{}, propID PropID) ([]string, bool) {
+	return GetSlice[string](input, propID)
+}
+
+/*
+GetNodes gets the values of a node's property as a []map[string]interface{} if they are all nodes or node references.
+Like GetSlice, the property may be a singleton, an array or a set or list object, and the node is not changed.
+*/
+func GetNodes(input interface{}, propID PropID) ([]map[string]interface{}, bool) {
+	var (
+		items []interface{}
+		nodes []map[string]interface{}
+		ok    bool
+	)
+
+	items, ok = propItems(input, propID)
+	if !ok || items == nil {
+		return nil, ok
+	}
+	nodes = make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		if !isNode(item) {
+			return nil, false
+		}
+		nodes = append(nodes, item.(map[string]interface{}))
+	}
+	return nodes, true
+}
+
+/*
+GetRefs gets the @ids of the values of a node's property if they are all node references or nodes with an @id, e.g.
+to follow the edges of a document whether or not their nodes are embedded.
+*/
+func GetRefs(input interface{}, propID PropID) ([]string, bool) {
+	var (
+		nodes []map[string]interface{}
+		ids   []string
+		ok    bool
+	)
+
+	nodes, ok = GetNodes(input, propID)
+	if !ok || nodes == nil {
+		return nil, ok
+	}
+	ids = make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		id, ok := node["@id"].(string)
+		if !ok || id == "" {
+			return nil, false
+		}
+		ids = append(ids, id)
+	}
+	return ids, true
+}
+
+//propItems returns the values of a node's property: the items of a set or list object or array, or a singleton's value
+func propItems(input interface{}, propID PropID) ([]interface{}, bool) {
+	var (
+		node  map[string]interface{}
+		propI interface{}
+		ok    bool
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	_, propI, ok = propValue(node, propID)
+	if !ok {
+		return nil, false
+	}
+	if obj, isObj := propI.(map[string]interface{}); isObj {
+		for _, k := range []string{"@list", "@set"} {
+			if v, found := obj[k]; found {
+				propI = v
+				break
+			}
+		}
+	}
+	if propI == nil {
+		return nil, true
+	}
+	return asArray(propI), true
+}
+
+//itemAs converts a value of a property to a T
+func itemAs[T any](propI interface{}, propID PropID) (T, bool) {
+	var (
+		zero   T
+		result interface{}
+		t      string
+		ok     bool
+	)
+
+	switch any(zero).(type) {
+	case int64, int, float64, time.Time:
+		propI, t, ok = typedItem(propI, propID)
+		if !ok {
+			return zero, false
+		}
+		switch any(zero).(type) {
+		case int64:
+			result, ok = intItem(propI, t)
+		case int:
+			i, isInt := intItem(propI, t)
+			result, ok = int(i), isInt && int64(int(i)) == i
+		case float64:
+			result, ok = floatItem(propI, t)
+		case time.Time:
+			result, ok = timeItem(propI, t)
+		}
+		if !ok {
+			return zero, false
+		}
+		return result.(T), true
+	}
+
+	if valobj, isObj := propI.(map[string]interface{}); isObj {
+		if v, found := valobj["@value"]; found {
+			propI = v
+		}
+	}
+	typed, ok := propI.(T)
+	return typed, ok
+}
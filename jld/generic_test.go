@@ -0,0 +1,155 @@
+package jld
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestGet(test *testing.T) {
+	var (
+		nameP    = NewPropID("https://ex.org/vocab#name", "")
+		ageP     = NewPropID("https://ex.org/vocab#age", "")
+		activeP  = NewPropID("https://ex.org/vocab#active", "")
+		bornP    = NewPropID("https://ex.org/vocab#born", "")
+		heightP  = NewPropID("https://ex.org/vocab#height", "")
+		knowsP   = NewPropID("https://ex.org/vocab#knows", "")
+		expected = time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+		friend   = map[string]interface{}{"@id": "https://ex.org/bob"}
+		node     map[string]interface{}
+	)
+
+	node = map[string]interface{}{
+		nameP.URI():   map[string]interface{}{"@value": "Alice", "@language": "en"},
+		ageP.URI():    map[string]interface{}{"@type": xsdBase + "integer", "@value": "42"},
+		activeP.URI(): true,
+		bornP.URI():   NewTimeV(expected),
+		heightP.URI(): json.Number("1.85"),
+		knowsP.URI():  friend,
+	}
+
+	if name, ok := Get[string](node, nameP); !ok || name != "Alice" {
+		test.Errorf("Get string: %v %v", name, ok)
+	}
+	if age, ok := Get[int64](node, ageP); !ok || age != 42 {
+		test.Errorf("Get int64: %v %v", age, ok)
+	}
+	if age, ok := Get[int](node, ageP); !ok || age != 42 {
+		test.Errorf("Get int: %v %v", age, ok)
+	}
+	if active, ok := Get[bool](node, activeP); !ok || !active {
+		test.Errorf("Get bool: %v %v", active, ok)
+	}
+	if born, ok := Get[time.Time](node, bornP); !ok || !born.Equal(expected) {
+		test.Errorf("Get time.Time: %v %v", born, ok)
+	}
+	if height, ok := Get[float64](node, heightP); !ok || height != 1.85 {
+		test.Errorf("Get float64: %v %v", height, ok)
+	}
+	if knows, ok := Get[map[string]interface{}](node, knowsP); !ok || knows["@id"] != "https://ex.org/bob" {
+		test.Errorf("Get node: %v %v", knows, ok)
+	}
+	if _, ok := Get[int64](node, heightP); ok {
+		test.Errorf("Get int64 of a fractional number should fail")
+	}
+	if _, ok := Get[string](node, ageP); !ok {
+		test.Errorf("Get string of a string value object should succeed")
+	}
+	if _, ok := Get[bool](node, nameP); ok {
+		test.Errorf("Get bool of a string should fail")
+	}
+	if _, ok := Get[string](node, NewPropID("https://ex.org/vocab#missing", "")); ok {
+		test.Errorf("Get of a missing property should fail")
+	}
+	if _, ok := Get[string]("not a node", nameP); ok {
+		test.Errorf("Get of a non-node should fail")
+	}
+}
+
+func TestGetSlice(test *testing.T) {
+	var (
+		tagsP   = NewPropID("https://ex.org/vocab#tags", "")
+		scoresP = NewPropID("https://ex.org/vocab#scores", "")
+		nameP   = NewPropID("https://ex.org/vocab#name", "")
+		mixedP  = NewPropID("https://ex.org/vocab#mixed", "")
+		node    map[string]interface{}
+	)
+
+	node = map[string]interface{}{
+		tagsP.URI():   []interface{}{"a", map[string]interface{}{"@value": "b"}},
+		scoresP.URI(): map[string]interface{}{"@list": []interface{}{float64(1), NewV(NewTypeID(xsdBase+"integer", ""), "2")}},
+		nameP.URI():   "Alice",
+		mixedP.URI():  []interface{}{"a", float64(1)},
+	}
+
+	tags, ok := GetSlice[string](node, tagsP)
+	if !ok || len(tags) != 2 || tags[0] != "a" || tags[1] != "b" {
+		test.Errorf("GetSlice array: %v %v", tags, ok)
+	}
+	scores, ok := GetSlice[int64](node, scoresP)
+	if !ok || len(scores) != 2 || scores[0] != 1 || scores[1] != 2 {
+		test.Errorf("GetSlice list: %v %v", scores, ok)
+	}
+	names, ok := GetSlice[string](node, nameP)
+	if !ok || len(names) != 1 || names[0] != "Alice" {
+		test.Errorf("GetSlice singleton: %v %v", names, ok)
+	}
+	if _, ok = node[nameP.URI()].(string); !ok {
+		test.Errorf("GetSlice changed the node: %v", node[nameP.URI()])
+	}
+	if _, ok = GetSlice[string](node, mixedP); ok {
+		test.Errorf("GetSlice of mixed values should fail")
+	}
+}
+
+func TestGetStringsNodesRefs(test *testing.T) {
+	var (
+		namesP   = NewPropID("https://ex.org/vocab#names", "")
+		knowsP   = NewPropID("https://ex.org/vocab#knows", "")
+		authorP  = NewPropID("https://ex.org/vocab#author", "")
+		blankP   = NewPropID("https://ex.org/vocab#blank", "")
+		personT  = NewTypeID("https://ex.org/types#Person", "")
+		carol    = map[string]interface{}{"@id": "https://ex.org/carol", "@type": personT.URI()}
+		node     map[string]interface{}
+		values   []string
+		nodes    []map[string]interface{}
+		ok       bool
+		expected = []string{"https://ex.org/bob", "https://ex.org/carol"}
+	)
+
+	node = map[string]interface{}{
+		namesP.URI():  []interface{}{"Alice", map[string]interface{}{"@value": "Alicia", "@language": "es"}},
+		knowsP.URI():  map[string]interface{}{"@set": []interface{}{map[string]interface{}{"@id": "https://ex.org/bob"}, carol}},
+		authorP.URI(): carol,
+		blankP.URI():  map[string]interface{}{"@type": personT.URI()},
+	}
+
+	values, ok = GetStrings(node, namesP)
+	if !ok || len(values) != 2 || values[0] != "Alice" || values[1] != "Alicia" {
+		test.Errorf("GetStrings: %v %v", values, ok)
+	}
+	if _, ok = GetStrings(node, knowsP); ok {
+		test.Errorf("GetStrings of nodes should fail")
+	}
+	nodes, ok = GetNodes(node, knowsP)
+	if !ok || len(nodes) != 2 || nodes[1]["@type"] != personT.URI() {
+		test.Errorf("GetNodes: %v %v", nodes, ok)
+	}
+	nodes, ok = GetNodes(node, authorP)
+	if !ok || len(nodes) != 1 || nodes[0]["@id"] != "https://ex.org/carol" {
+		test.Errorf("GetNodes singleton: %v %v", nodes, ok)
+	}
+	if _, ok = GetNodes(node, namesP); ok {
+		test.Errorf("GetNodes of values should fail")
+	}
+	values, ok = GetRefs(node, knowsP)
+	if !ok || len(values) != 2 || values[0] != expected[0] || values[1] != expected[1] {
+		test.Errorf("GetRefs: %v %v", values, ok)
+	}
+	if _, ok = GetRefs(node, blankP); ok {
+		test.Errorf("GetRefs of a node without an @id should fail")
+	}
+	if _, ok = node[authorP.URI()].(map[string]interface{}); !ok {
+		test.Errorf("GetNodes changed the node: %v", node[authorP.URI()])
+	}
+}
@@ -0,0 +1,355 @@
+package jld
+
+import (
+	"fmt"
+	"reflect"
+)
+
+/*
+A Graph indexes the nodes of a JSON LD document by @id. It ingests an expanded or flattened document (or the output of
+Canonicalize), including nodes embedded in property values, lists and @graph containers.
+
+Node references (objects with only an @id) are not indexed, and nodes without an @id cannot be indexed. If the same
+@id is defined more than once, the properties of the later definitions that the first lacks are added to it.
+With the ResolveRefs option, the references in the document are replaced by the nodes they refer to; otherwise use
+Resolve to dereference them.
+
+The nodes are also indexed by type, so GetAllOfType does not scan the Graph. The type index records the @type of a
+node when it is indexed: a node whose @type is changed afterwards (e.g. by AddTypes) must be added again for the
+index to follow.
+
+The indexed nodes are the document's own maps, not copies. A Graph is not safe for concurrent mutation; a Store is.
+*/
+type Graph struct {
+	nodes   map[string]map[string]interface{}
+	order   []string
+	types   map[string][]string
+	typesOf map[string][]string
+	resolve bool
+}
+
+/*
+NewGraph creates a Graph that indexes the nodes of the input document. The ResolveRefs Option is the only one that
+applies to a Graph.
+*/
+func NewGraph(input interface{}, opts ...Option) (*Graph, error) {
+	var (
+		g   Graph
+		err error
+	)
+
+	g.nodes = make(map[string]map[string]interface{})
+	g.types = make(map[string][]string)
+	g.typesOf = make(map[string][]string)
+	g.resolve = newOptions(opts).resolve
+	err = g.Add(input)
+	if err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+/*
+Add indexes the nodes of another document in the Graph.
+*/
+func (g *Graph) Add(input interface{}) error {
+	var err = g.ingest(input, 0)
+
+	if err != nil || !g.resolve {
+		return err
+	}
+	for _, id := range g.order {
+		g.resolveRefs(g.nodes[id], g.nodes[id], 0)
+	}
+	return nil
+}
+
+//maxGraphDepth limits the nesting depth of an ingested document
+const maxGraphDepth = 1000
+
+//ingest recursively indexes the nodes of a document
+func (g *Graph) ingest(input interface{}, depth int) error {
+	var (
+		obj      map[string]interface{}
+		existing map[string]interface{}
+		id       string
+		ok       bool
+		err      error
+	)
+
+	if depth > maxGraphDepth {
+		return fmt.Errorf("Graph document nesting exceeds %v", maxGraphDepth)
+	}
+
+	switch input.(type) {
+	case []interface{}:
+		for _, item := range input.([]interface{}) {
+			err = g.ingest(item, depth+1)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		obj = input.(map[string]interface{})
+	default:
+		return nil
+	}
+
+	if _, ok = obj["@value"]; ok {
+		return nil
+	}
+
+	id, ok = obj["@id"].(string)
+	if ok && !IsNref(obj) {
+		existing, ok = g.nodes[id]
+		if !ok {
+			g.nodes[id] = obj
+			g.order = append(g.order, id)
+		} else if !sameMap(existing, obj) {
+			for k, v := range obj {
+				if _, ok = existing[k]; !ok {
+					existing[k] = v
+				}
+			}
+		}
+		g.indexTypes(id, g.nodes[id])
+	}
+
+	for k, v := range obj {
+		switch k {
+		case "@id", "@type", "@value", "@context":
+			continue
+		}
+		err = g.ingest(v, depth+1)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//sameMap is true if the two maps are the same map
+func sameMap(m1, m2 map[string]interface{}) bool {
+	return reflect.ValueOf(m1).Pointer() == reflect.ValueOf(m2).Pointer()
+}
+
+/*
+GetByID returns the node with the @id.
+*/
+func (g *Graph) GetByID(id string) (map[string]interface{}, bool) {
+	var (
+		node map[string]interface{}
+		ok   bool
+	)
+
+	node, ok = g.nodes[id]
+	return node, ok
+}
+
+//put indexes a node by its @id, replacing (rather than merging with) any node with the @id
+func (g *Graph) put(id string, node map[string]interface{}) {
+	if _, ok := g.nodes[id]; !ok {
+		g.order = append(g.order, id)
+	}
+	g.nodes[id] = node
+	g.indexTypes(id, node)
+}
+
+//remove removes the node with the @id from the index and returns whether there was one
+func (g *Graph) remove(id string) bool {
+	if _, ok := g.nodes[id]; !ok {
+		return false
+	}
+	delete(g.nodes, id)
+	g.indexTypes(id, nil)
+	g.order = removeID(g.order, id)
+	return true
+}
+
+//indexTypes updates the type index of the node with the @id to its @type, or removes it from the index if it is nil
+func (g *Graph) indexTypes(id string, node map[string]interface{}) {
+	var (
+		old      = g.typesOf[id]
+		types, _ = nodeTypes(node)
+		kept     = make(map[string]bool, len(types))
+	)
+
+	for _, t := range types {
+		kept[t] = true
+	}
+	for _, t := range old {
+		if !kept[t] {
+			g.types[t] = removeID(g.types[t], id)
+			if len(g.types[t]) == 0 {
+				delete(g.types, t)
+			}
+		}
+		delete(kept, t)
+	}
+	for _, t := range types {
+		if kept[t] {
+			g.types[t] = append(g.types[t], id)
+			delete(kept, t)
+		}
+	}
+	if len(types) == 0 {
+		delete(g.typesOf, id)
+	} else {
+		g.typesOf[id] = types
+	}
+}
+
+//removeID removes an @id from a slice of them
+func removeID(ids []string, id string) []string {
+	for i, other := range ids {
+		if other == id {
+			return append(ids[:i], ids[i+1:]...)
+		}
+	}
+	return ids
+}
+
+/*
+Len returns the number of indexed nodes.
+*/
+func (g *Graph) Len() int {
+	return len(g.nodes)
+}
+
+/*
+AllNodes returns the indexed nodes in the order they were first found.
+*/
+func (g *Graph) AllNodes() []map[string]interface{} {
+	var nodes = make([]map[string]interface{}, 0, len(g.order))
+
+	for _, id := range g.order {
+		nodes = append(nodes, g.nodes[id])
+	}
+	return nodes
+}
+
+/*
+GetAllOfType returns the indexed nodes of type t, from the type index, in the order they were first indexed with the
+type.
+*/
+func (g *Graph) GetAllOfType(t TypeID) []map[string]interface{} {
+	var (
+		ids   = g.types[t.URI()]
+		nodes = make([]map[string]interface{}, 0, len(ids))
+	)
+
+	for _, id := range ids {
+		nodes = append(nodes, g.nodes[id])
+	}
+	return nodes
+}
+
+/*
+NodesOfType returns the indexed nodes of type t.
+
+Deprecated: NodesOfType is an alias of GetAllOfType, which should be used instead.
+*/
+func (g *Graph) NodesOfType(t TypeID) []map[string]interface{} {
+	return g.GetAllOfType(t)
+}
+
+/*
+EachOfType applies the function to each indexed node of type t. If the function returns an error, EachOfType
+terminates and returns this error.
+*/
+func (g *Graph) EachOfType(t TypeID, f func(map[string]interface{}) error) error {
+	var err error
+
+	for _, node := range g.GetAllOfType(t) {
+		err = f(node)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+/*
+Resolve returns the node that a node reference ({"@id": ...}) refers to if it is in the Graph; otherwise, or if the
+input is not a node reference, it returns the input.
+*/
+func Resolve(node interface{}, graph *Graph) interface{} {
+	if !IsNref(node) || graph == nil {
+		return node
+	}
+	if resolved, ok := graph.GetByID(nodeID(node)); ok {
+		return resolved
+	}
+	return node
+}
+
+//resolveRefs replaces the references in the values of an owner node that can be resolved without making a cycle
+func (g *Graph) resolveRefs(owner map[string]interface{}, input interface{}, depth int) {
+	if depth > maxGraphDepth {
+		return
+	}
+	switch input.(type) {
+	case []interface{}:
+		items := input.([]interface{})
+		for i, item := range items {
+			if IsNref(item) {
+				if node, ok := g.GetByID(nodeID(item)); ok && !reaches(node, owner, 0) {
+					items[i] = node
+				}
+				continue
+			}
+			g.resolveRefs(owner, item, depth+1)
+		}
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		if _, ok := obj["@value"]; ok {
+			return
+		}
+		//An embedded node owns its own values; it is resolved when its turn in the Graph's order comes
+		if _, ok := obj["@id"]; ok && depth > 0 {
+			if _, indexed := g.nodes[nodeID(obj)]; indexed {
+				return
+			}
+		}
+		for k, v := range obj {
+			switch k {
+			case "@id", "@type", "@context":
+				continue
+			}
+			if IsNref(v) {
+				if node, ok := g.GetByID(nodeID(v)); ok && !reaches(node, owner, 0) {
+					obj[k] = node
+				}
+				continue
+			}
+			g.resolveRefs(owner, v, depth+1)
+		}
+	}
+}
+
+//reaches is true if the target map is the input or is embedded in it
+func reaches(input interface{}, target map[string]interface{}, depth int) bool {
+	if depth > maxGraphDepth {
+		return true
+	}
+	switch input.(type) {
+	case []interface{}:
+		for _, item := range input.([]interface{}) {
+			if reaches(item, target, depth+1) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		if sameMap(obj, target) {
+			return true
+		}
+		for _, v := range obj {
+			if reaches(v, target, depth+1) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,151 @@
+package jld
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGraph(test *testing.T) {
+	var (
+		person = NewTypeID("https://ex.org/types#Person", "")
+		org    = NewTypeID("https://ex.org/types#Org", "")
+		doc    []interface{}
+		g      *Graph
+		node   map[string]interface{}
+		count  int
+		ok     bool
+		err    error
+	)
+
+	doc = []interface{}{
+		map[string]interface{}{
+			"@id":   "https://ex.org/ann",
+			"@type": []interface{}{"https://ex.org/types#Person"},
+			"https://ex.org/vocab#employer": []interface{}{
+				map[string]interface{}{
+					"@id":                       "https://ex.org/acme",
+					"@type":                     []interface{}{"https://ex.org/types#Org"},
+					"https://ex.org/vocab#name": []interface{}{map[string]interface{}{"@value": "Acme"}},
+				},
+			},
+			"https://ex.org/vocab#knows": []interface{}{map[string]interface{}{"@id": "https://ex.org/bob"}},
+		},
+		map[string]interface{}{
+			"@graph": []interface{}{
+				map[string]interface{}{
+					"@id":   "https://ex.org/bob",
+					"@type": "https://ex.org/types#Person",
+				},
+			},
+		},
+	}
+
+	g, err = NewGraph(doc)
+	if err != nil {
+		test.Fatalf("NewGraph: %v", err)
+	}
+	if g.Len() != 3 || len(g.AllNodes()) != 3 {
+		test.Errorf("Graph Len: %v", g.Len())
+	}
+	node, ok = g.GetByID("https://ex.org/acme")
+	if !ok || !hasType(node, org) {
+		test.Errorf("GetByID acme: %v %v", node, ok)
+	}
+	node, ok = g.GetByID("https://ex.org/bob")
+	if !ok || IsNref(node) {
+		test.Errorf("GetByID bob: %v %v", node, ok)
+	}
+	_, ok = g.GetByID("https://ex.org/carol")
+	if ok {
+		test.Errorf("GetByID carol should not be found")
+	}
+	if len(g.NodesOfType(person)) != 2 {
+		test.Errorf("NodesOfType Person: %v", g.NodesOfType(person))
+	}
+	err = g.EachOfType(org, func(n map[string]interface{}) error {
+		count++
+		return nil
+	})
+	if err != nil || count != 1 {
+		test.Errorf("EachOfType Org: %v %v", count, err)
+	}
+}
+
+func TestGetAllOfType(test *testing.T) {
+	var (
+		person = NewTypeID("https://ex.org/types#Person", "")
+		org    = NewTypeID("https://ex.org/types#Org", "")
+		ann    = NewN("https://ex.org/ann", person)
+		acme   = NewN("https://ex.org/acme", org, person)
+		g      *Graph
+		err    error
+	)
+
+	g, err = NewGraph([]interface{}{ann, acme, map[string]interface{}{"@id": "https://ex.org/bob"}})
+	if err != nil {
+		test.Fatalf("NewGraph: %v", err)
+	}
+	if people := g.GetAllOfType(person); len(people) != 2 || !sameMap(people[0], ann) || !sameMap(people[1], acme) {
+		test.Errorf("GetAllOfType Person: %v", people)
+	}
+
+	//A later definition of bob adds its @type
+	g.Add(map[string]interface{}{"@id": "https://ex.org/bob", "@type": person.URI()})
+	if people := g.GetAllOfType(person); len(people) != 3 || nodeID(people[2]) != "https://ex.org/bob" {
+		test.Errorf("GetAllOfType Person after Add: %v", people)
+	}
+
+	//A retyped node is reindexed when it is added again
+	RemoveType(acme, person)
+	g.Add(acme)
+	if people := g.GetAllOfType(person); len(people) != 2 || len(g.GetAllOfType(org)) != 1 {
+		test.Errorf("GetAllOfType Person after RemoveType: %v", people)
+	}
+
+	g.remove("https://ex.org/ann")
+	if people := g.GetAllOfType(person); len(people) != 1 || nodeID(people[0]) != "https://ex.org/bob" {
+		test.Errorf("GetAllOfType Person after remove: %v", people)
+	}
+	if others := g.GetAllOfType(NewTypeID("https://ex.org/types#Other", "")); len(others) != 0 {
+		test.Errorf("GetAllOfType Other: %v", others)
+	}
+}
+
+func TestResolve(test *testing.T) {
+	var (
+		knows = "https://ex.org/vocab#knows"
+		ann   = map[string]interface{}{"@id": "https://ex.org/ann", knows: []interface{}{map[string]interface{}{"@id": "https://ex.org/bob"}}}
+		bob   = map[string]interface{}{"@id": "https://ex.org/bob", knows: []interface{}{map[string]interface{}{"@id": "https://ex.org/ann"}, map[string]interface{}{"@id": "https://ex.org/carol"}}}
+		g     *Graph
+		err   error
+	)
+
+	g, err = NewGraph([]interface{}{ann, bob})
+	if err != nil {
+		test.Fatalf("NewGraph: %v", err)
+	}
+	if node, ok := Resolve(map[string]interface{}{"@id": "https://ex.org/bob"}, g).(map[string]interface{}); !ok || !sameMap(node, bob) {
+		test.Errorf("Resolve bob: %v", node)
+	}
+	if node := Resolve(map[string]interface{}{"@id": "https://ex.org/carol"}, g); !IsNref(node) {
+		test.Errorf("Resolve should leave a hanging reference: %v", node)
+	}
+	if !IsNref(ann[knows].([]interface{})[0]) {
+		test.Errorf("NewGraph without ResolveRefs should not change the document")
+	}
+
+	_, err = NewGraph([]interface{}{ann, bob}, ResolveRefs())
+	if err != nil {
+		test.Fatalf("NewGraph ResolveRefs: %v", err)
+	}
+	if node, ok := ann[knows].([]interface{})[0].(map[string]interface{}); !ok || !sameMap(node, bob) {
+		test.Errorf("ResolveRefs should embed bob in ann: %v", ann[knows])
+	}
+	if !IsNref(bob[knows].([]interface{})[0]) || !IsNref(bob[knows].([]interface{})[1]) {
+		test.Errorf("ResolveRefs should not make a cycle or resolve a hanging reference: %v", bob[knows])
+	}
+	_, err = json.Marshal([]interface{}{ann, bob})
+	if err != nil {
+		test.Errorf("Resolved document should marshal: %v", err)
+	}
+}
@@ -0,0 +1,154 @@
+package jld
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+//An IDOption configures an IDPolicy
+type IDOption func(*IDPolicy)
+
+/*
+An IDPolicy validates the @id values of the nodes a service creates. NewN accepts any id that url.Parse tolerates,
+which is almost anything; an IDPolicy rejects those that the service does not mean to create, with an error that
+says why. For example:
+
+	policy := jld.NewIDPolicy(jld.AbsoluteIDs(), jld.IDSchemes("https", "urn"))
+	node, err := policy.NewN(id, personT)
+
+Without IDOptions, an IDPolicy only rejects ids that are not IRIs (e.g. that contain spaces). A blank node
+identifier (one that starts with "_:", or the empty id, for which NewN creates one) satisfies AbsoluteIDs and
+IDSchemes, which constrain IRIs; BlankIDsOnly rejects every other id. An IDPolicy is not changed once it is
+created, so it may be shared.
+*/
+type IDPolicy struct {
+	absolute  bool
+	schemes   map[string]bool
+	blankOnly bool
+}
+
+/*
+AbsoluteIDs rejects relative IRIs, such as "people/ann", which are resolved against a base only by ResolveIDs or by
+the WithBase option of Canonicalize.
+*/
+func AbsoluteIDs() IDOption {
+	return func(p *IDPolicy) {
+		p.absolute = true
+	}
+}
+
+/*
+IDSchemes rejects IRIs whose scheme (compared case-insensitively) is not one of the schemes, e.g. "https" and "urn".
+It implies AbsoluteIDs, since a relative IRI has no scheme.
+*/
+func IDSchemes(schemes ...string) IDOption {
+	return func(p *IDPolicy) {
+		p.absolute = true
+		if p.schemes == nil {
+			p.schemes = make(map[string]bool, len(schemes))
+		}
+		for _, scheme := range schemes {
+			p.schemes[strings.ToLower(scheme)] = true
+		}
+	}
+}
+
+/*
+BlankIDsOnly rejects every id that is not a blank node identifier, e.g. for nodes that must not be addressable
+outside their document.
+*/
+func BlankIDsOnly() IDOption {
+	return func(p *IDPolicy) {
+		p.blankOnly = true
+	}
+}
+
+/*
+NewIDPolicy creates an IDPolicy with the IDOptions.
+*/
+func NewIDPolicy(opts ...IDOption) *IDPolicy {
+	var p IDPolicy
+
+	for _, opt := range opts {
+		opt(&p)
+	}
+	return &p
+}
+
+/*
+Validate returns an error that explains why the id is not valid by the IDPolicy. The empty id, for which NewN creates
+a blank node identifier, is always valid.
+*/
+func (p *IDPolicy) Validate(id string) error {
+	var (
+		u   *url.URL
+		err error
+	)
+
+	if id == "" || strings.HasPrefix(id, "_:") {
+		if strings.ContainsAny(id, " \t\r\n") {
+			return idError(id, "a blank node identifier cannot contain white space")
+		}
+		return nil
+	}
+	if p.blankOnly {
+		return idError(id, "only blank node identifiers are allowed")
+	}
+	if i := strings.IndexAny(id, " \t\r\n<>\"{}|\\^`"); i >= 0 {
+		return idError(id, "%q is not allowed in an IRI", id[i])
+	}
+	u, err = url.Parse(id)
+	if err != nil {
+		return idError(id, "%v", err)
+	}
+	if p.absolute && !u.IsAbs() {
+		return idError(id, "a relative IRI is not allowed")
+	}
+	if p.schemes != nil && !p.schemes[strings.ToLower(u.Scheme)] {
+		return idError(id, "scheme %v is not one of %v", u.Scheme, strings.Join(p.schemeList(), ", "))
+	}
+	return nil
+}
+
+//idError returns the NodeError of a bad id
+func idError(id string, format string, args ...interface{}) error {
+	return &NodeError{Kind: ErrBadID, ID: id, Detail: fmt.Sprintf(format, args...)}
+}
+
+//schemeList returns the sorted schemes of an IDPolicy
+func (p *IDPolicy) schemeList() []string {
+	var schemes = make([]string, 0, len(p.schemes))
+
+	for scheme := range p.schemes {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+/*
+NewN is NewN with the id validated by the IDPolicy. Rather than panicking on a bad id, it returns the error.
+*/
+func (p *IDPolicy) NewN(id string, t ...TypeID) (map[string]interface{}, error) {
+	var err = p.Validate(id)
+
+	if err != nil {
+		return nil, err
+	}
+	return NewN(id, t...), nil
+}
+
+/*
+AddN is AddN with the id validated by the IDPolicy.
+*/
+func (p *IDPolicy) AddN(input interface{}, id string, t ...TypeID) error {
+	var err = p.Validate(id)
+
+	if err != nil {
+		return err
+	}
+	AddN(input, id, t...)
+	return nil
+}
@@ -0,0 +1,58 @@
+package jld
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIDPolicy(test *testing.T) {
+	var (
+		personT = NewTypeID("https://ex.org/types#Person", "")
+		cases   = []struct {
+			policy *IDPolicy
+			id     string
+			err    string
+		}{
+			{NewIDPolicy(), "people/ann", ""},
+			{NewIDPolicy(), "https://ex.org/ann smith", "not allowed in an IRI"},
+			{NewIDPolicy(AbsoluteIDs()), "people/ann", "relative IRI"},
+			{NewIDPolicy(AbsoluteIDs()), "https://ex.org/ann", ""},
+			{NewIDPolicy(AbsoluteIDs()), "", ""},
+			{NewIDPolicy(AbsoluteIDs()), "_:b0", ""},
+			{NewIDPolicy(IDSchemes("https", "urn")), "HTTPS://ex.org/ann", ""},
+			{NewIDPolicy(IDSchemes("https", "urn")), "urn:uuid:8f1d6a3c-0000-4000-8000-000000000000", ""},
+			{NewIDPolicy(IDSchemes("https", "urn")), "http://ex.org/ann", "scheme http is not one of https, urn"},
+			{NewIDPolicy(IDSchemes("https")), "people/ann", "relative IRI"},
+			{NewIDPolicy(BlankIDsOnly()), "_:b0", ""},
+			{NewIDPolicy(BlankIDsOnly()), "", ""},
+			{NewIDPolicy(BlankIDsOnly()), "https://ex.org/ann", "only blank node identifiers"},
+			{NewIDPolicy(BlankIDsOnly()), "_:b 0", "white space"},
+		}
+	)
+
+	for _, c := range cases {
+		err := c.policy.Validate(c.id)
+		switch {
+		case c.err == "" && err != nil:
+			test.Errorf("Validate %q: %v", c.id, err)
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			test.Errorf("Validate %q: expected %q, got %v", c.id, c.err, err)
+		}
+	}
+
+	node, err := NewIDPolicy(AbsoluteIDs()).NewN("https://ex.org/ann", personT)
+	if err != nil || node["@id"] != "https://ex.org/ann" || !hasType(node, personT) {
+		test.Errorf("NewN: %v %v", node, err)
+	}
+	node, err = NewIDPolicy(AbsoluteIDs()).NewN("people/ann", personT)
+	if err == nil || node != nil {
+		test.Errorf("NewN of a relative IRI should fail: %v", node)
+	}
+	node = map[string]interface{}{}
+	if err = NewIDPolicy(BlankIDsOnly()).AddN(node, "https://ex.org/ann", personT); err == nil || len(node) != 0 {
+		test.Errorf("AddN of an IRI should fail: %v", node)
+	}
+	if err = NewIDPolicy(BlankIDsOnly()).AddN(node, "", personT); err != nil || !strings.HasPrefix(node["@id"].(string), "_:") {
+		test.Errorf("AddN of a blank node: %v %v", node, err)
+	}
+}
@@ -0,0 +1,135 @@
+package jld
+
+import (
+	"net/url"
+	"strings"
+)
+
+//noIndex is the index map key of the values that have no @index
+const noIndex = "@none"
+
+/*
+GetIndexMap gets a property that uses an @index container as a map keyed by index value, whatever the container's
+shape. In expanded form (e.g. the output of Canonicalize) the property is a set of values each with an @index; in
+compacted form it is an object whose keys are the index values (and whose keys are neither keywords nor absolute
+IRIs, which distinguishes it from a node). Values without an @index are keyed by "@none".
+
+A map value is the single value with its index, or a []interface{} if there are several. Values are returned as is;
+in expanded form they retain their @index.
+*/
+func GetIndexMap(input interface{}, propID PropID) (map[string]interface{}, bool) {
+	var (
+		propI   interface{}
+		items   []interface{}
+		indexed = make(map[string]interface{})
+		index   string
+		ok      bool
+	)
+
+	propI, ok = GetP(input, propID)
+	if !ok {
+		return nil, false
+	}
+	if obj, ok := propI.(map[string]interface{}); ok && isIndexMap(obj) {
+		for k, v := range obj {
+			indexed[k] = v
+		}
+		return indexed, true
+	}
+
+	switch propI.(type) {
+	case []interface{}:
+		items = propI.([]interface{})
+	default:
+		items = []interface{}{propI}
+	}
+	for _, item := range items {
+		index = noIndex
+		if obj, ok := item.(map[string]interface{}); ok {
+			if s, ok := obj["@index"].(string); ok {
+				index = s
+			}
+		}
+		switch existing := indexed[index].(type) {
+		case nil:
+			indexed[index] = item
+		case []interface{}:
+			indexed[index] = append(existing, item)
+		default:
+			indexed[index] = []interface{}{existing, item}
+		}
+	}
+	return indexed, true
+}
+
+//isIndexMap is true if an object is a compacted index map rather than a node or value object
+func isIndexMap(obj map[string]interface{}) bool {
+	if len(obj) == 0 {
+		return false
+	}
+	for k := range obj {
+		if strings.HasPrefix(k, "@") && k != noIndex {
+			return false
+		}
+		if u, err := url.Parse(k); err == nil && u.IsAbs() {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+SetIndexed sets the value of an index of a property that uses an @index container, replacing the index's existing
+values. If the property is a compacted index map, the index's entry is set; otherwise the value is added to the
+property's set in expanded form with an @index. A primitive value is wrapped in a value object.
+*/
+func SetIndexed(input interface{}, propID PropID, index string, value interface{}) error {
+	var (
+		node  map[string]interface{}
+		item  map[string]interface{}
+		items []interface{}
+		kept  []interface{}
+		ok    bool
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return notANode(input)
+	}
+	if obj, ok := node[propID.URI()].(map[string]interface{}); ok && isIndexMap(obj) {
+		obj[index] = value
+		return nil
+	}
+
+	switch value.(type) {
+	case map[string]interface{}:
+		if IsList(value) {
+			return nodeError(ErrBadValue, node, Pointer(propID.URI()), "a list object cannot be indexed")
+		}
+		item = make(map[string]interface{}, len(value.(map[string]interface{}))+1)
+		for k, v := range value.(map[string]interface{}) {
+			item[k] = v
+		}
+	case nil, []interface{}:
+		return nodeError(ErrBadValue, node, Pointer(propID.URI()), "%v of type %T cannot be indexed", value, value)
+	default:
+		item = map[string]interface{}{"@value": value}
+	}
+	item["@index"] = index
+
+	switch existing := node[propID.URI()].(type) {
+	case nil:
+	case []interface{}:
+		items = existing
+	default:
+		items = []interface{}{existing}
+	}
+	for _, existing := range items {
+		if obj, ok := existing.(map[string]interface{}); ok && obj["@index"] == index {
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	node[propID.URI()] = append(kept, item)
+	return nil
+}
@@ -0,0 +1,51 @@
+package jld
+
+import (
+	"testing"
+)
+
+func TestIndexMap(test *testing.T) {
+	var (
+		postP   = NewPropID("https://ex.org/vocab#post", "")
+		node    = NewN("https://ex.org/blog", NewTypeID("https://ex.org/types#Blog", ""))
+		indexed map[string]interface{}
+		ok      bool
+		err     error
+	)
+
+	err = SetIndexed(node, postP, "en", "Hello")
+	if err == nil {
+		err = SetIndexed(node, postP, "de", map[string]interface{}{"@id": "https://ex.org/posts/1"})
+	}
+	if err == nil {
+		err = SetIndexed(node, postP, "en", "Hi")
+	}
+	if err != nil {
+		test.Fatalf("SetIndexed: %v", err)
+	}
+	indexed, ok = GetIndexMap(node, postP)
+	switch {
+	case !ok || len(indexed) != 2:
+		test.Errorf("GetIndexMap: %v %v", indexed, ok)
+	case valueOf(indexed["en"]) != "Hi":
+		test.Errorf("GetIndexMap en: %v", indexed["en"])
+	case indexed["de"].(map[string]interface{})["@id"] != "https://ex.org/posts/1":
+		test.Errorf("GetIndexMap de: %v", indexed["de"])
+	}
+
+	node[postP.URI()] = map[string]interface{}{"en": "Hello", "fr": []interface{}{"Salut", "Bonjour"}}
+	err = SetIndexed(node, postP, "de", "Hallo")
+	indexed, ok = GetIndexMap(node, postP)
+	if err != nil || !ok || len(indexed) != 3 || indexed["de"] != "Hallo" {
+		test.Errorf("GetIndexMap compacted: %v %v %v", indexed, ok, err)
+	}
+
+	node[postP.URI()] = []interface{}{map[string]interface{}{"@value": "x"}, map[string]interface{}{"@value": "y"}}
+	indexed, ok = GetIndexMap(node, postP)
+	if !ok || len(indexed[noIndex].([]interface{})) != 2 {
+		test.Errorf("GetIndexMap unindexed: %v %v", indexed, ok)
+	}
+	if SetIndexed(node, postP, "en", NewL([]interface{}{"a"})) == nil {
+		test.Errorf("SetIndexed should reject a list")
+	}
+}
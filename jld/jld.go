@@ -4,8 +4,13 @@ Package jld provides a few JSON LD utility functions.
 package jld
 
 import (
+	"context"
 	"fmt"
 	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
 
 	"github.com/develrns/resilient/log"
 
@@ -124,45 +129,94 @@ func (pid PropID) URI() string {
 }
 
 /*
-BlankID creates a unique blank node identifier.
+BlankID creates a unique blank node identifier. It is a random UUID, so it is safe for concurrent use and needs no
+shared generator state.
 */
 func BlankID() string {
 	return "_:" + (uuid.NewRandom().String())
 }
 
+/*
+A BlankIDGenerator generates short, sequential blank node identifiers within its own namespace, e.g. for the nodes of
+a single document. It is safe for concurrent use.
+*/
+type BlankIDGenerator struct {
+	prefix string
+	n      uint64
+}
+
+/*
+NewBlankIDGenerator creates a BlankIDGenerator whose identifiers are _:<prefix>-<n>. If the prefix is empty a random
+one is used, so that the identifiers of separate generators do not collide when their documents are merged.
+*/
+func NewBlankIDGenerator(prefix string) *BlankIDGenerator {
+	if prefix == "" {
+		prefix = "b" + strings.Replace(uuid.NewRandom().String(), "-", "", -1)[:8]
+	}
+	return &BlankIDGenerator{prefix: prefix}
+}
+
+/*
+Next generates the next blank node identifier.
+*/
+func (g *BlankIDGenerator) Next() string {
+	return "_:" + g.prefix + "-" + strconv.FormatUint(atomic.AddUint64(&g.n, 1), 10)
+}
+
 /*
 NewV creates a typed value object. The value may be a bool,
-int, float32, float64 or string value. Any other type of value returns a value object with @value nil.
+int, int64, float32, float64 or string value; or an arbitrary precision json.Number, *big.Int, *big.Float or *big.Rat,
+which is stored as a string of its exact decimal form (see decimal.go). Any other type of value, or a *big.Rat that is
+not a finite decimal, returns a value object with @value nil.
 */
 func NewV(t TypeID, v interface{}) map[string]interface{} {
-	valobj := make(map[string]interface{}, 2)
-	valobj["@type"] = t
+	return fillV(make(map[string]interface{}, 2), t, v)
+}
+
+//fillV fills an empty map with the @type and @value of a typed value object
+func fillV(valobj map[string]interface{}, t TypeID, v interface{}) map[string]interface{} {
+	valobj["@type"] = t.URI()
 	switch v.(type) {
-	case bool, int, float32, float64, string:
+	case bool, int, int64, float32, float64, string:
 		valobj["@value"] = v
 	default:
-		valobj["@value"] = nil
+		if s, ok := numberLexical(v); ok {
+			valobj["@value"] = s
+		} else {
+			valobj["@value"] = nil
+		}
 	}
 	return valobj
 }
 
 /*
-NewN creates a node with @id and @type properties. If id is blank a blank node of the type is created.
+NewN creates a node with @id and @type properties. If id is blank a blank node of the types is created.
+A relative id is stored as is; it is resolved by ResolveIDs or by the WithBase option of Canonicalize.
+A single type is stored as a string and several as a []interface{} of strings (repeated types are dropped), the
+forms that unmarshalled JSON and the JSON LD processor use. It returns nil if there are no types.
 */
 func NewN(id string, t ...TypeID) map[string]interface{} {
+	if len(t) == 0 {
+		return nil
+	}
+	return fillN(make(map[string]interface{}, 2), id, t)
+}
+
+//fillN fills a map that has neither with the @id and @type of a node of the types
+func fillN(node map[string]interface{}, id string, t []TypeID) map[string]interface{} {
 	var (
-		node = make(map[string]interface{}, 2)
-		err  error
+		types []interface{}
+		seen  = make(map[TypeID]bool, len(t))
+		err   error
 	)
 
-	switch len(t) {
-	case 0:
-		return nil
-	case 1:
-		node["@type"] = t[0]
-	default:
-		node["@type"] = t
+	for _, typeID := range t {
+		if !seen[typeID] {
+			seen[typeID] = true
+			types = append(types, typeID.URI())
+		}
 	}
+	setTypes(node, types)
 
 	switch id {
 	case "":
@@ -178,13 +232,13 @@ func NewN(id string, t ...TypeID) map[string]interface{} {
 }
 
 /*
-AddN adds an id and type to an existing map. This simplifies creating a node from a composite literal.
+AddN adds an id and types to an existing map, storing them as NewN does. This simplifies creating a node from a
+composite literal. Without types, only the id is added.
 */
-func AddN(input interface{}, id string, t TypeID) {
+func AddN(input interface{}, id string, t ...TypeID) {
 	var (
 		node         map[string]interface{}
 		okID, okType bool
-		err          error
 	)
 
 	switch input.(type) {
@@ -195,19 +249,67 @@ func AddN(input interface{}, id string, t TypeID) {
 		if okID || okType {
 			panic("AddN to existing node")
 		}
-		node["@type"] = t
+		fillN(node, id, t)
+	}
+}
 
-		switch id {
-		case "":
-			node["@id"] = BlankID()
-		default:
-			_, err = url.Parse(id)
+/*
+ResolveIDs resolves the relative @id values of a document, such as one built with NewN and AddN, in place against
+the base IRI configured by WithBase. Blank node identifiers are not changed.
+*/
+func ResolveIDs(input interface{}, opts ...Option) error {
+	var (
+		o    = newOptions(opts)
+		base *url.URL
+		err  error
+	)
+
+	base, err = url.Parse(o.base)
+	if err != nil || !base.IsAbs() {
+		return fmt.Errorf("Bad Base IRI: %v", o.base)
+	}
+	return resolveIDs(input, base)
+}
+
+//resolveIDs recursively resolves the @id values of a document against a base IRI
+func resolveIDs(input interface{}, base *url.URL) error {
+	var (
+		ref *url.URL
+		err error
+	)
+
+	switch input.(type) {
+	case []interface{}:
+		for _, item := range input.([]interface{}) {
+			err = resolveIDs(item, base)
 			if err != nil {
-				panic("Bad ID")
+				return err
+			}
+		}
+	case map[string]interface{}:
+		for k, v := range input.(map[string]interface{}) {
+			switch k {
+			case "@context":
+				continue
+			case "@id":
+				id, ok := v.(string)
+				if !ok || strings.HasPrefix(id, "_:") {
+					continue
+				}
+				ref, err = url.Parse(id)
+				if err != nil {
+					return nodeError(ErrBadID, input, "", "%v", err)
+				}
+				input.(map[string]interface{})["@id"] = base.ResolveReference(ref).String()
+			default:
+				err = resolveIDs(v, base)
+				if err != nil {
+					return err
+				}
 			}
-			node["@id"] = id
 		}
 	}
+	return nil
 }
 
 /*
@@ -219,6 +321,63 @@ func NewL(s interface{}) map[string]interface{} {
 	return listobj
 }
 
+/*
+DeepCopy returns a copy of a node, document or other unmarshalled JSON value that shares no maps or slices with it,
+including those of nested nodes, value objects and list objects. Since GetSet, GetList and other helpers normalize
+their input in place, a shared document should be copied before it is transformed. A map or slice that is referenced
+more than once is copied for each reference; the input must not be cyclic.
+*/
+func DeepCopy(input interface{}) interface{} {
+	switch input.(type) {
+	case []interface{}:
+		items := input.([]interface{})
+		cp := make([]interface{}, len(items))
+		for i, item := range items {
+			cp[i] = DeepCopy(item)
+		}
+		return cp
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		cp := make(map[string]interface{}, len(obj))
+		for k, v := range obj {
+			cp[k] = DeepCopy(v)
+		}
+		return cp
+	default:
+		return input
+	}
+}
+
+//A Prop is a property of a node and its value
+type Prop struct {
+	ID    PropID
+	Value interface{}
+}
+
+/*
+Props returns the properties of a node, including its keywords (e.g. @id), sorted by their URIs, so that code that
+iterates over them, such as serialization, diffing and golden file tests, is deterministic. Since '@' sorts before
+letters, the keywords precede the properties with IRIs. It returns nil if the input is not a node.
+*/
+func Props(input interface{}) []Prop {
+	var (
+		node  map[string]interface{}
+		props []Prop
+		ok    bool
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	props = make([]Prop, 0, len(node))
+	for k, v := range node {
+		props = append(props, Prop{ID: PropID(k), Value: v})
+	}
+	sort.Slice(props, func(i, j int) bool { return props[i].ID < props[j].ID })
+	return props
+}
+
 /*
 GetP gets the property of a node
 */
@@ -233,7 +392,7 @@ func GetP(input interface{}, propID PropID) (interface{}, bool) {
 	if !ok {
 		return nil, false
 	}
-	propI, ok = node[propID.URI()]
+	_, propI, ok = propValue(node, propID)
 	if !ok {
 		return nil, false
 	}
@@ -254,7 +413,7 @@ func GetN(input interface{}, propID PropID) (map[string]interface{}, bool) {
 	if !ok {
 		return nil, false
 	}
-	propI, ok = node[propID.URI()]
+	_, propI, ok = propValue(node, propID)
 	if !ok {
 		return nil, false
 	}
@@ -278,7 +437,7 @@ func GetNtype(input interface{}, propID PropID, typeID TypeID) (map[string]inter
 	if !ok {
 		return nil, false
 	}
-	propI, ok = node[propID.URI()]
+	_, propI, ok = propValue(node, propID)
 	if !ok {
 		return nil, false
 	}
@@ -309,6 +468,7 @@ GetSet gets the property of a node if it is a set or singleton as a slice of int
 func GetSet(input interface{}, propID PropID) ([]interface{}, bool) {
 	var (
 		node  map[string]interface{}
+		owner map[string]interface{}
 		propI interface{}
 		array []interface{}
 		slice []interface{}
@@ -319,7 +479,7 @@ func GetSet(input interface{}, propID PropID) ([]interface{}, bool) {
 	if !ok {
 		return nil, false
 	}
-	propI, ok = node[propID.URI()]
+	owner, propI, ok = propValue(node, propID)
 	if !ok {
 		return nil, false
 	}
@@ -333,7 +493,7 @@ func GetSet(input interface{}, propID PropID) ([]interface{}, bool) {
 		array = make([]interface{}, 1)
 		slice = array[:]
 		slice[0] = propI
-		node[propID.URI()] = slice
+		owner[propID.URI()] = slice
 		return slice, true
 	}
 }
@@ -357,7 +517,7 @@ func GetList(input interface{}, propID PropID) ([]interface{}, bool) {
 	if !ok {
 		return nil, false
 	}
-	listI, ok = node[propID.URI()]
+	_, listI, ok = propValue(node, propID)
 	if !ok {
 		return nil, false
 	}
@@ -386,7 +546,8 @@ func GetList(input interface{}, propID PropID) ([]interface{}, bool) {
 }
 
 /*
-GetVtype gets the value of a node's typed value object if it is a value object of the requested type.
+GetVtype gets the value of a node's typed value object if it is a value object of the requested type, or if it is a
+JSON primitive and the requested type is the property's registered datatype (see RegisterDatatype).
 */
 func GetVtype(input interface{}, propID PropID, typeID TypeID) (interface{}, bool) {
 	var (
@@ -400,11 +561,14 @@ func GetVtype(input interface{}, propID PropID, typeID TypeID) (interface{}, boo
 	if !ok {
 		return nil, false
 	}
-	propI, ok = node[propID.URI()]
+	_, propI, ok = propValue(node, propID)
 	if !ok {
 		return nil, false
 	}
 	if !IsVtype(propI, typeID) {
+		if registered, ok := Datatype(propID); ok && registered.URI() == typeID.URI() && isPrimitive(propI) {
+			return propI, true
+		}
 		return nil, false
 	}
 	val = propI.(map[string]interface{})["@value"]
@@ -426,7 +590,7 @@ func GetString(input interface{}, propID PropID) (string, bool) {
 	if !ok {
 		return "", false
 	}
-	propI, ok = node[propID.URI()]
+	_, propI, ok = propValue(node, propID)
 	if !ok {
 		return "", false
 	}
@@ -465,7 +629,7 @@ func GetBool(input interface{}, propID PropID) (bool, bool) {
 	if !ok {
 		return false, false
 	}
-	propI, ok = node[propID.URI()]
+	_, propI, ok = propValue(node, propID)
 	if !ok {
 		return false, false
 	}
@@ -502,7 +666,6 @@ IsType is true if the input is a node or a typed value object of the type t.
 func IsType(input interface{}, t TypeID) bool {
 	var (
 		o  map[string]interface{}
-		tv interface{}
 		ok bool
 	)
 
@@ -510,56 +673,18 @@ func IsType(input interface{}, t TypeID) bool {
 	if !ok {
 		return false
 	}
-
-	tv, ok = o["@type"]
-	if !ok {
-		return false
-	}
-
-	switch tv.(type) {
-	case string:
-		return t.URI() == tv.(string)
-	case []string:
-		for _, typeval := range tv.([]string) {
-			if t.URI() == typeval {
-				return true
-			}
-		}
-	}
-	return false
+	return hasType(o, t)
 }
 
 /*
-IsNtype is true if the input is a node and it is of type t.
+IsNtype is true if the input is a node and it is of type t. The node may have several types, in any of the forms
+created by NewN, AddN, AddTypes and unmarshalling.
 */
 func IsNtype(input interface{}, t TypeID) bool {
-	var (
-		n  map[string]interface{}
-		tv interface{}
-		ok bool
-	)
-
 	if !ld.IsNode(input) {
 		return false
 	}
-	n = input.(map[string]interface{})
-
-	tv, ok = n["@type"]
-	if !ok {
-		return false
-	}
-
-	switch tv.(type) {
-	case string:
-		return t.URI() == tv.(string)
-	case []string:
-		for _, typeval := range tv.([]string) {
-			if t.URI() == typeval {
-				return true
-			}
-		}
-	}
-	return false
+	return hasType(input.(map[string]interface{}), t)
 }
 
 /*
@@ -785,25 +910,10 @@ func IsList(input interface{}) bool {
 }
 
 /*
-AddType Adds a type to a node.
+AddType adds a type to a node. See AddTypes.
 */
 func AddType(input interface{}, t TypeID) error {
-	var (
-		node map[string]interface{}
-		set  []interface{}
-		ok   bool
-	)
-
-	node, ok = input.(map[string]interface{})
-	if !ok {
-		return fmt.Errorf("Bad Node")
-	}
-	set, ok = GetSet(node, TypeP)
-	if !ok {
-		return fmt.Errorf("Bad Node @type")
-	}
-	set[len(set)] = t.URI()
-	return nil
+	return AddTypes(input, t)
 }
 
 /*
@@ -821,7 +931,7 @@ func Append(input interface{}, propID PropID, items ...interface{}) ([]interface
 
 	node, ok = input.(map[string]interface{})
 	if !ok {
-		return nil, fmt.Errorf("Bad Node")
+		return nil, notANode(input)
 	}
 	slice, okSet = GetSet(node, propID)
 	if okSet {
@@ -836,7 +946,7 @@ func Append(input interface{}, propID PropID, items ...interface{}) ([]interface
 		listObj["@list"] = newSlice
 		return newSlice, nil
 	}
-	return nil, fmt.Errorf("Bad Node")
+	return nil, nodeError(ErrBadProperty, node, Pointer(propID.URI()), "the node has no set or list of %v", propID.URI())
 }
 
 /*
@@ -903,34 +1013,68 @@ fully 'unrolled'. If the unrolled edges include a node with multiple incoming ed
 
 The input must be unmarshalled JSON.
 If only one node matches the typeFilter, it is returned; if no nodes are matched, the result is nil; otherwise an array of the matched nodes are returned.
+With the WrapGraph option the result is always a @graph object, and named graphs are framed separately.
+The ld package's Frame used here drops the content of @list values; use Frame to frame documents with lists.
+JSON LD 1.1 constructs (@nest, @json, @included and scoped contexts) are rewritten into JSON LD 1.0; see jld11.go.
+
+Options such as WithLoader and Strict configure the processing.
+*/
+func Canonicalize(input interface{}, typeFilter []TypeID, opts ...Option) (interface{}, error) {
+	return CanonicalizeCtx(context.Background(), input, typeFilter, opts...)
+}
+
+/*
+CanonicalizeCtx is Canonicalize with a Context; if the Context is done, it returns the Context's error. Cancellation
+is checked between the expand and frame phases and between the framing of named graphs, and for each node as the
+expanded document is checked by Strict and as the framed nodes are filtered by RequireAllTypes and relabelled by
+StableBlankIDs. The ld processor's expansion and framing run to completion once started.
 */
-func Canonicalize(input interface{}, typeFilter []TypeID) (interface{}, error) {
+func CanonicalizeCtx(ctx context.Context, input interface{}, typeFilter []TypeID, opts ...Option) (interface{}, error) {
 	var (
 		jsonLdProcessor = ld.NewJsonLdProcessor()
+		o               = newOptions(opts)
+		ldOptions       = o.ldOptions()
 		err             error
-		frame           = make(map[string]interface{}, 1)
-		types           = make([]interface{}, len(typeFilter))
+		frame           = o.frame(typeFilter)
 		expanded        []interface{}
 		framed          map[string]interface{}
 		graph           []interface{}
 	)
 
-	//Convert the array of TypeIDs to an array of their URI values
-	for i, typeID := range typeFilter {
-		types[i] = typeID.URI()
+	err = o.checkInput(input)
+	if err != nil {
+		return nil, err
 	}
-	frame["@type"] = types
-
-	expanded, err = jsonLdProcessor.Expand(input, nil)
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	expanded, err = o.expand(ctx, jsonLdProcessor, input)
+	if err != nil {
+		return nil, err
+	}
+	err = o.checkExpanded(ctx, expanded)
 	if err != nil {
 		return nil, err
 	}
 
-	framed, err = jsonLdProcessor.Frame(expanded, frame, nil)
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	if o.wrapGraph {
+		return canonicalizeGraphs(ctx, jsonLdProcessor, expanded, frame, o)
+	}
+	framed, err = jsonLdProcessor.Frame(expanded, frame, ldOptions)
+	if err != nil {
+		return nil, err
+	}
+	graph, err = o.matchAllTypes(ctx, framed["@graph"].([]interface{}), frame)
+	if err != nil {
+		return nil, err
+	}
+	err = o.stabilize(ctx, graph)
 	if err != nil {
 		return nil, err
 	}
-	graph = framed["@graph"].([]interface{})
 	switch len(graph) {
 	case 0:
 		return nil, nil
@@ -941,6 +1085,124 @@ func Canonicalize(input interface{}, typeFilter []TypeID) (interface{}, error) {
 	}
 }
 
+/*
+Expand removes all context from an unmarshalled JSON LD document, producing a document with full URI properties and
+types. Remote @context URLs are resolved with the DocumentLoader configured by WithLoader.
+*/
+func Expand(input interface{}, opts ...Option) ([]interface{}, error) {
+	return ExpandCtx(context.Background(), input, opts...)
+}
+
+/*
+ExpandCtx is Expand with a Context; if the Context is done, it returns the Context's error. Cancellation is checked
+before expansion and once the document is rewritten from JSON LD 1.1, and for each node as the expanded document is
+checked by Strict. The ld processor's expansion runs to completion once started.
+*/
+func ExpandCtx(ctx context.Context, input interface{}, opts ...Option) ([]interface{}, error) {
+	var (
+		o        = newOptions(opts)
+		expanded []interface{}
+		err      error
+	)
+
+	err = o.checkInput(input)
+	if err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	expanded, err = o.expand(ctx, ld.NewJsonLdProcessor(), input)
+	if err != nil {
+		return nil, err
+	}
+	err = o.checkExpanded(ctx, expanded)
+	if err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+/*
+Compact compacts a document, such as the output of Canonicalize, against a caller supplied context so that the
+output uses the context's short property names and types rather than full URIs. The ctx may be a context map, a
+document with an @context property, or a context URL resolved by the DocumentLoader configured by WithLoader.
+The result includes the @context.
+
+The ctx may also be a CompiledContext created by Precompile, which is not parsed again.
+*/
+func Compact(input interface{}, ctx interface{}, opts ...Option) (map[string]interface{}, error) {
+	var (
+		o        = newOptions(opts)
+		expanded []interface{}
+		err      error
+	)
+
+	if cc, ok := ctx.(*CompiledContext); ok {
+		expanded, err = Expand(input, opts...)
+		if err != nil {
+			return nil, err
+		}
+		return cc.compact(expanded)
+	}
+
+	//In strict mode, the input is expanded first so that its IRIs can be checked
+	if o.strict {
+		_, err = Expand(input, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	//A JSON LD 1.1 input is compacted from its 1.0 expansion
+	if uses11(input) {
+		input, err = Expand(input, opts...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ld.NewJsonLdProcessor().Compact(input, ctx, o.ldOptions())
+}
+
+/*
+CompactWithPrefixes compacts a document against a context built from CURIE prefixes (e.g. "tn" for
+"https://ex.org/tn#"), so that its properties and types are compacted to prefixed names such as tn:policy without
+the caller constructing a JSON LD context. Each prefix must be a name without a colon and each IRI must be absolute.
+*/
+func CompactWithPrefixes(input interface{}, prefixes map[string]string, opts ...Option) (map[string]interface{}, error) {
+	var (
+		ctx map[string]interface{}
+		err error
+	)
+
+	ctx, err = prefixContext(prefixes)
+	if err != nil {
+		return nil, err
+	}
+	return Compact(input, ctx, opts...)
+}
+
+//prefixContext builds a context that defines CURIE prefixes
+func prefixContext(prefixes map[string]string) (map[string]interface{}, error) {
+	var (
+		defs = make(map[string]interface{}, len(prefixes))
+		u    *url.URL
+		err  error
+	)
+
+	for prefix, iri := range prefixes {
+		if prefix == "" || strings.ContainsAny(prefix, ":/#") || strings.HasPrefix(prefix, "@") || strings.HasPrefix(prefix, "_") {
+			return nil, fmt.Errorf("Bad CURIE Prefix: %v", prefix)
+		}
+		u, err = url.Parse(iri)
+		if err != nil || !u.IsAbs() {
+			return nil, fmt.Errorf("Bad CURIE Prefix IRI: %v", iri)
+		}
+		defs[prefix] = iri
+	}
+	return map[string]interface{}{"@context": defs}, nil
+}
+
 /*
 PrintDocument is the same as ld.PrintDocument - it prints the internal JSON LD Document as formatted JSON LD.
 It's here to eliminate the need to import the ld package.
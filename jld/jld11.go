@@ -0,0 +1,509 @@
+package jld
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/kazarena/json-gold/ld"
+)
+
+/*
+The ld package implements JSON LD 1.0, which rejects or mangles the JSON LD 1.1 constructs that partners have started
+to send. Before a document is expanded (by Expand, Canonicalize, Frame and Compact), it is rewritten into the
+equivalent 1.0 document:
+
+@nest: the properties of nested objects (under @nest or a term aliased to it) are lifted into the containing node,
+and @nest is removed from term definitions.
+
+@json: JSON literals, whether in {"@value": ..., "@type": "@json"} value objects or the values of terms whose @type
+is @json, become value objects of type rdf:JSON whose value is the literal's JSON serialized with sorted keys - the
+RDF form of a JSON literal. GetJSON parses them.
+
+@included: included nodes are moved to the graph of the node that includes them after expansion. GetIncluded gets
+the included nodes of an unexpanded node.
+
+Scoped contexts: a property scoped context is embedded in each node value of the property, and a type scoped context
+is embedded in each node of the type. Unlike JSON LD 1.1, an embedded context propagates to nested nodes, so a type
+scoped context also applies to the nodes embedded in a node of its type.
+
+@version, @protected, @propagate and @import are removed from contexts. Remote contexts are used as they are loaded.
+
+The 1.1 term definitions are collected from all the inline contexts of a document by term name, rather than tracked
+per active context, so a document is refused if a term has 1.1 definitions that differ between its contexts (e.g. a
+@nest alias in one context and a property in another) or if it resets its context with a null @context.
+
+The Get functions also find the properties of a node that are nested under @nest.
+*/
+
+//rdfJSON is the datatype of a JSON literal
+const rdfJSON = "http://www.w3.org/1999/02/22-rdf-syntax-ns#JSON"
+
+//includedP is the property that holds a node's @included nodes while the node is expanded by the ld package
+const includedP = "urn:x-jld:included"
+
+//terms11 holds the JSON LD 1.1 term definitions of the inline contexts of a document
+type terms11 struct {
+	nest     map[string]bool
+	json     map[string]bool
+	scoped   map[string]interface{}
+	roles    map[string]termRole
+	conflict string
+	reset    bool
+	found    bool
+}
+
+//A termRole is what a term definition means to the rewriting of a document: the 1.1 parts of the definition
+type termRole struct {
+	nest   bool
+	json   bool
+	scoped interface{}
+}
+
+//newTerms11 creates an empty terms11
+func newTerms11() *terms11 {
+	return &terms11{
+		nest:   make(map[string]bool),
+		json:   make(map[string]bool),
+		scoped: make(map[string]interface{}),
+		roles:  make(map[string]termRole),
+	}
+}
+
+//contextKeywords11 are the JSON LD 1.1 context keywords that a 1.0 processor rejects
+var contextKeywords11 = []string{"@version", "@protected", "@propagate", "@import"}
+
+/*
+collect collects the 1.1 term definitions of all the inline contexts of a document, regardless of their scope.
+It sets found if the document uses a 1.1 construct, and conflict or reset if the definitions cannot be used regardless
+of their scope.
+*/
+func (t *terms11) collect(input interface{}, depth int) {
+	if depth > maxGraphDepth {
+		return
+	}
+	switch input.(type) {
+	case []interface{}:
+		for _, item := range input.([]interface{}) {
+			t.collect(item, depth+1)
+		}
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		for k, v := range obj {
+			switch k {
+			case "@context":
+				t.collectContext(v, depth+1)
+			case "@nest", "@included":
+				t.found = true
+			case "@type":
+				if v == "@json" {
+					t.found = true
+				}
+			}
+			t.collect(v, depth+1)
+		}
+	}
+}
+
+//collectContext collects the 1.1 term definitions of a @context value
+func (t *terms11) collectContext(input interface{}, depth int) {
+	switch input.(type) {
+	case nil:
+		t.reset = true
+	case []interface{}:
+		for _, item := range input.([]interface{}) {
+			t.collectContext(item, depth+1)
+		}
+	case map[string]interface{}:
+		for term, def := range input.(map[string]interface{}) {
+			for _, kw := range contextKeywords11 {
+				if term == kw {
+					t.found = true
+				}
+			}
+			t.define(term, def)
+			switch def.(type) {
+			case string:
+				if def == "@nest" {
+					t.nest[term] = true
+					t.found = true
+				}
+			case map[string]interface{}:
+				defMap := def.(map[string]interface{})
+				if defMap["@type"] == "@json" {
+					t.json[term] = true
+					t.found = true
+				}
+				if scoped, ok := defMap["@context"]; ok {
+					t.scoped[term] = scoped
+					t.found = true
+					t.collect(scoped, depth+1)
+				}
+				if _, ok := defMap["@nest"]; ok {
+					t.found = true
+				}
+			}
+		}
+	}
+}
+
+//define records the role of a term definition, and the term as a conflict if it was defined with another role
+func (t *terms11) define(term string, def interface{}) {
+	var role termRole
+
+	if len(term) > 0 && term[0] == '@' {
+		return
+	}
+	switch def.(type) {
+	case string:
+		role.nest = def == "@nest"
+	case map[string]interface{}:
+		defMap := def.(map[string]interface{})
+		role.json = defMap["@type"] == "@json"
+		role.scoped = defMap["@context"]
+	}
+	if defined, ok := t.roles[term]; ok && !reflect.DeepEqual(defined, role) && t.conflict == "" {
+		t.conflict = term
+	}
+	t.roles[term] = role
+}
+
+//uses11 is true if a document uses JSON LD 1.1 constructs
+func uses11(input interface{}) bool {
+	var t = newTerms11()
+
+	t.collect(input, 0)
+	return t.found
+}
+
+/*
+downlevel returns the JSON LD 1.0 equivalent of a document that uses JSON LD 1.1 constructs, or the document itself if
+it does not use them. The document is not changed. A document whose 1.1 term definitions depend on their scope is an
+error.
+*/
+func downlevel(input interface{}) (interface{}, error) {
+	var t = newTerms11()
+
+	t.collect(input, 0)
+	if !t.found {
+		return input, nil
+	}
+	if t.conflict != "" {
+		return nil, fmt.Errorf("Bad Context: the JSON LD 1.1 term %v has conflicting definitions in the contexts of the document", t.conflict)
+	}
+	if t.reset && len(t.nest)+len(t.json)+len(t.scoped) > 0 {
+		return nil, fmt.Errorf("Bad Context: a document with JSON LD 1.1 term definitions cannot reset its context with a null @context")
+	}
+	return t.rewrite(DeepCopy(input), 0), nil
+}
+
+//rewrite rewrites a copy of a document in place
+func (t *terms11) rewrite(input interface{}, depth int) interface{} {
+	var obj map[string]interface{}
+
+	if depth > maxGraphDepth {
+		return input
+	}
+	switch input.(type) {
+	case []interface{}:
+		items := input.([]interface{})
+		for i, item := range items {
+			items[i] = t.rewrite(item, depth+1)
+		}
+		return items
+	case map[string]interface{}:
+		obj = input.(map[string]interface{})
+	default:
+		return input
+	}
+
+	if _, ok := obj["@value"]; ok {
+		if obj["@type"] == "@json" {
+			return jsonLiteral(obj["@value"])
+		}
+		return obj
+	}
+
+	if ctx, ok := obj["@context"]; ok {
+		obj["@context"] = t.rewriteContext(ctx)
+	}
+	for _, typeName := range typeNames(obj["@type"]) {
+		if scoped, ok := t.scoped[typeName]; ok {
+			obj["@context"] = withContext(obj["@context"], t.rewriteContext(DeepCopy(scoped)), false)
+		}
+	}
+	t.liftNested(obj, depth)
+	if included, ok := obj["@included"]; ok {
+		delete(obj, "@included")
+		obj[includedP] = included
+	}
+
+	for k, v := range obj {
+		switch {
+		case k == "@context":
+			continue
+		case t.json[k]:
+			obj[k] = jsonLiteral(v)
+			continue
+		}
+		if scoped, ok := t.scoped[k]; ok {
+			for _, item := range asArray(v) {
+				nodeObj, isObj := item.(map[string]interface{})
+				if _, isValue := nodeObj["@value"]; isObj && !isValue {
+					nodeObj["@context"] = withContext(nodeObj["@context"], t.rewriteContext(DeepCopy(scoped)), true)
+				}
+			}
+		}
+		obj[k] = t.rewrite(v, depth+1)
+	}
+	return obj
+}
+
+//liftNested moves the properties of the nested objects of a node into the node
+func (t *terms11) liftNested(obj map[string]interface{}, depth int) {
+	//Nested objects may themselves have nested objects, which are lifted by the following pass
+	for lifted := true; lifted && depth <= maxGraphDepth; depth++ {
+		lifted = false
+		for k, v := range obj {
+			if k != "@nest" && !t.nest[k] {
+				continue
+			}
+			delete(obj, k)
+			lifted = true
+			for _, item := range asArray(v) {
+				nested, ok := item.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				for nk, nv := range nested {
+					if existing, ok := obj[nk]; ok {
+						obj[nk] = append(asArray(existing), asArray(nv)...)
+					} else {
+						obj[nk] = nv
+					}
+				}
+			}
+		}
+	}
+}
+
+//rewriteContext removes the 1.1 keywords and term definitions of a copy of a @context value
+func (t *terms11) rewriteContext(input interface{}) interface{} {
+	switch input.(type) {
+	case []interface{}:
+		items := input.([]interface{})
+		for i, item := range items {
+			items[i] = t.rewriteContext(item)
+		}
+		return items
+	case map[string]interface{}:
+		ctx := input.(map[string]interface{})
+		for _, kw := range contextKeywords11 {
+			delete(ctx, kw)
+		}
+		for term, def := range ctx {
+			switch def.(type) {
+			case string:
+				if def == "@nest" || def == "@included" || def == "@json" {
+					delete(ctx, term)
+				}
+			case map[string]interface{}:
+				defMap := def.(map[string]interface{})
+				delete(defMap, "@nest")
+				delete(defMap, "@context")
+				delete(defMap, "@protected")
+				delete(defMap, "@propagate")
+				if defMap["@type"] == "@json" {
+					defMap["@type"] = rdfJSON
+				}
+			}
+		}
+		return ctx
+	default:
+		return input
+	}
+}
+
+//withContext combines a node's embedded context with a scoped context, which is applied first or last
+func withContext(embedded, scoped interface{}, scopedFirst bool) interface{} {
+	switch {
+	case embedded == nil:
+		return scoped
+	case scopedFirst:
+		return append(asArray(scoped), asArray(embedded)...)
+	default:
+		return append(asArray(embedded), asArray(scoped)...)
+	}
+}
+
+//typeNames returns the string values of a node's @type
+func typeNames(input interface{}) []string {
+	var names []string
+
+	for _, item := range asArray(input) {
+		if name, ok := item.(string); ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+//jsonLiteral returns a JSON value as an rdf:JSON value object
+func jsonLiteral(v interface{}) map[string]interface{} {
+	var serialized, _ = json.Marshal(v)
+
+	return map[string]interface{}{"@value": string(serialized), "@type": rdfJSON}
+}
+
+/*
+liftIncluded moves the included nodes of the nodes of an expanded graph to the graph; the nodes of a nested @graph
+are moved to that graph.
+*/
+func liftIncluded(graph []interface{}, depth int) []interface{} {
+	var included []interface{}
+
+	if depth > maxGraphDepth {
+		return graph
+	}
+	for _, item := range graph {
+		included = append(included, takeIncluded(item, depth+1)...)
+	}
+	if len(included) == 0 {
+		return graph
+	}
+	return liftIncluded(append(graph, included...), depth+1)
+}
+
+//takeIncluded removes and returns the included nodes of an expanded value outside of its nested graphs
+func takeIncluded(input interface{}, depth int) []interface{} {
+	var included []interface{}
+
+	if depth > maxGraphDepth {
+		return nil
+	}
+	switch input.(type) {
+	case []interface{}:
+		for _, item := range input.([]interface{}) {
+			included = append(included, takeIncluded(item, depth+1)...)
+		}
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		if nodes, ok := obj[includedP]; ok {
+			delete(obj, includedP)
+			included = append(included, asArray(nodes)...)
+		}
+		for k, v := range obj {
+			if k == "@graph" {
+				if graph, ok := v.([]interface{}); ok {
+					obj[k] = liftIncluded(graph, depth+1)
+				}
+				continue
+			}
+			included = append(included, takeIncluded(v, depth+1)...)
+		}
+	}
+	return included
+}
+
+//expand11 expands a document that may use JSON LD 1.1 constructs; the Context is checked once it is rewritten
+func expand11(ctx context.Context, proc *ld.JsonLdProcessor, input interface{}, ldOptions *ld.JsonLdOptions) ([]interface{}, error) {
+	var (
+		down     interface{}
+		expanded []interface{}
+		err      error
+	)
+
+	down, err = downlevel(input)
+	if err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	expanded, err = proc.Expand(down, ldOptions)
+	if err != nil {
+		return nil, err
+	}
+	return liftIncluded(expanded, 0), nil
+}
+
+/*
+GetJSON gets the property of a node if it is a JSON literal: a {"@value": ..., "@type": "@json"} value object or an
+rdf:JSON value object, such as one in the output of Canonicalize, whose value is parsed.
+*/
+func GetJSON(input interface{}, propID PropID) (interface{}, bool) {
+	var (
+		propI  interface{}
+		valobj map[string]interface{}
+		v      interface{}
+		ok     bool
+	)
+
+	propI, ok = GetP(input, propID)
+	if !ok {
+		return nil, false
+	}
+	if items, isArray := propI.([]interface{}); isArray && len(items) == 1 {
+		propI = items[0]
+	}
+	valobj, ok = propI.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	switch valobj["@type"] {
+	case "@json":
+		return valobj["@value"], true
+	case rdfJSON:
+		s, isString := valobj["@value"].(string)
+		if !isString || json.Unmarshal([]byte(s), &v) != nil {
+			return nil, false
+		}
+		return v, true
+	default:
+		return nil, false
+	}
+}
+
+/*
+GetIncluded gets the @included nodes of a node.
+*/
+func GetIncluded(input interface{}) ([]interface{}, bool) {
+	var (
+		node map[string]interface{}
+		ok   bool
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	if _, ok = node["@included"]; !ok {
+		return nil, false
+	}
+	return asArray(node["@included"]), true
+}
+
+//propValue gets the value of a node's property and the object that holds it: the node or, if the property is nested
+//under @nest, the nested object
+func propValue(node map[string]interface{}, propID PropID) (map[string]interface{}, interface{}, bool) {
+	return nestedValue(node, propID.URI(), 0)
+}
+
+//nestedValue finds a property in an object or its @nest objects
+func nestedValue(obj map[string]interface{}, uri string, depth int) (map[string]interface{}, interface{}, bool) {
+	if v, ok := obj[uri]; ok {
+		return obj, v, true
+	}
+	if depth > maxGraphDepth {
+		return nil, nil, false
+	}
+	for _, item := range asArray(obj["@nest"]) {
+		if nested, ok := item.(map[string]interface{}); ok {
+			if owner, v, found := nestedValue(nested, uri, depth+1); found {
+				return owner, v, true
+			}
+		}
+	}
+	return nil, nil, false
+}
@@ -0,0 +1,183 @@
+package jld
+
+import (
+	"testing"
+)
+
+func TestDownlevel(test *testing.T) {
+	var (
+		doc = map[string]interface{}{
+			"@context": map[string]interface{}{
+				"@version": 1.1,
+				"@vocab":   "https://ex.org/vocab#",
+				"labels":   "@nest",
+				"settings": map[string]interface{}{"@type": "@json"},
+				"address":  map[string]interface{}{"@context": map[string]interface{}{"@vocab": "https://ex.org/addr#"}},
+			},
+			"@id":      "https://ex.org/ann",
+			"labels":   map[string]interface{}{"name": "Ann"},
+			"settings": map[string]interface{}{"theme": "dark"},
+			"address":  map[string]interface{}{"city": "Oslo"},
+			"@included": []interface{}{
+				map[string]interface{}{"@id": "https://ex.org/bob"},
+			},
+		}
+		down map[string]interface{}
+		ctx  map[string]interface{}
+		ok   bool
+	)
+
+	downI, err := downlevel(doc)
+	down, ok = downI.(map[string]interface{})
+	if err != nil || !ok {
+		test.Fatalf("downlevel should return a node")
+	}
+	if _, ok = doc["labels"]; !ok {
+		test.Errorf("downlevel should not change the document")
+	}
+	ctx = down["@context"].(map[string]interface{})
+	if _, ok = ctx["@version"]; ok {
+		test.Errorf("@version should be removed: %v", ctx)
+	}
+	if _, ok = ctx["labels"]; ok {
+		test.Errorf("The @nest alias should be removed: %v", ctx)
+	}
+	if ctx["settings"].(map[string]interface{})["@type"] != rdfJSON {
+		test.Errorf("The @json term should be typed rdf:JSON: %v", ctx["settings"])
+	}
+	if _, ok = ctx["address"].(map[string]interface{})["@context"]; ok {
+		test.Errorf("The scoped context should be removed from the term: %v", ctx["address"])
+	}
+	if down["name"] != "Ann" {
+		test.Errorf("Nested properties should be lifted: %v", down)
+	}
+	if settings := down["settings"].(map[string]interface{}); settings["@type"] != rdfJSON || settings["@value"] != `{"theme":"dark"}` {
+		test.Errorf("The JSON literal should be an rdf:JSON value: %v", settings)
+	}
+	if _, ok = down["address"].(map[string]interface{})["@context"]; !ok {
+		test.Errorf("The scoped context should be embedded in the value: %v", down["address"])
+	}
+	if _, ok = down[includedP]; !ok {
+		test.Errorf("@included should be held by the placeholder property: %v", down)
+	}
+
+	plain := map[string]interface{}{"@id": "https://ex.org/ann"}
+	if downI, err = downlevel(plain); err != nil || !sameMap(downI.(map[string]interface{}), plain) || uses11(plain) {
+		test.Errorf("A JSON LD 1.0 document should not be rewritten")
+	}
+}
+
+func TestLiftIncluded(test *testing.T) {
+	var (
+		expanded = []interface{}{
+			map[string]interface{}{
+				"@id":     "https://ex.org/ann",
+				includedP: []interface{}{map[string]interface{}{"@id": "https://ex.org/bob", includedP: []interface{}{map[string]interface{}{"@id": "https://ex.org/carol"}}}},
+			},
+		}
+		graph []interface{}
+	)
+
+	graph = liftIncluded(expanded, 0)
+	if len(graph) != 3 || nodeID(graph[1]) != "https://ex.org/bob" || nodeID(graph[2]) != "https://ex.org/carol" {
+		test.Errorf("liftIncluded: %v", graph)
+	}
+	if _, ok := graph[0].(map[string]interface{})[includedP]; ok {
+		test.Errorf("The placeholder property should be removed: %v", graph[0])
+	}
+}
+
+func TestGet11(test *testing.T) {
+	var (
+		nameP     = NewPropID("https://ex.org/vocab#name", "")
+		settingsP = NewPropID("https://ex.org/vocab#settings", "")
+		node      = map[string]interface{}{
+			"@nest":            map[string]interface{}{nameP.URI(): "Ann"},
+			settingsP.URI():    map[string]interface{}{"@value": `{"theme":"dark"}`, "@type": rdfJSON},
+			"@included":        map[string]interface{}{"@id": "https://ex.org/bob"},
+			"https://ex.org/x": map[string]interface{}{"@value": map[string]interface{}{"a": 1.0}, "@type": "@json"},
+		}
+		v  interface{}
+		s  string
+		ok bool
+	)
+
+	if s, ok = GetString(node, nameP); !ok || s != "Ann" {
+		test.Errorf("GetString of a nested property: %v %v", s, ok)
+	}
+	if v, ok = GetJSON(node, settingsP); !ok || v.(map[string]interface{})["theme"] != "dark" {
+		test.Errorf("GetJSON of an rdf:JSON value: %v %v", v, ok)
+	}
+	if v, ok = GetJSON(node, NewPropID("https://ex.org/x", "")); !ok || v.(map[string]interface{})["a"] != 1.0 {
+		test.Errorf("GetJSON of an @json value: %v %v", v, ok)
+	}
+	if included, ok := GetIncluded(node); !ok || len(included) != 1 {
+		test.Errorf("GetIncluded: %v %v", included, ok)
+	}
+}
+
+func TestDownlevelConflicts(test *testing.T) {
+	var (
+		vocab = "https://ex.org/vocab#"
+		cases = []map[string]interface{}{
+			//labels is a @nest alias for ann and a property of her address
+			{
+				"@context": map[string]interface{}{"@vocab": vocab, "labels": "@nest"},
+				"@id":      "https://ex.org/ann",
+				"labels":   map[string]interface{}{"name": "Ann"},
+				"address": map[string]interface{}{
+					"@context": map[string]interface{}{"labels": "https://ex.org/addr#labels"},
+					"labels":   "home",
+				},
+			},
+			//data is a JSON literal for ann and a node for bob
+			{
+				"@context": map[string]interface{}{"@vocab": vocab},
+				"@graph": []interface{}{
+					map[string]interface{}{"@context": map[string]interface{}{"data": map[string]interface{}{"@type": "@json"}}, "@id": "https://ex.org/ann", "data": map[string]interface{}{"a": 1.0}},
+					map[string]interface{}{"@context": map[string]interface{}{"data": map[string]interface{}{"@id": vocab + "data"}}, "@id": "https://ex.org/bob", "data": map[string]interface{}{"@id": "https://ex.org/d"}},
+				},
+			},
+			//address has two scoped contexts
+			{
+				"@context": map[string]interface{}{"@vocab": vocab, "address": map[string]interface{}{"@context": map[string]interface{}{"@vocab": "https://ex.org/addr#"}}},
+				"@id":      "https://ex.org/ann",
+				"knows": map[string]interface{}{
+					"@context": map[string]interface{}{"address": map[string]interface{}{"@context": map[string]interface{}{"@vocab": "https://ex.org/other#"}}},
+					"address":  map[string]interface{}{"city": "Oslo"},
+				},
+			},
+			//the context of bob, in which labels is not a @nest alias, is reset
+			{
+				"@context": map[string]interface{}{"@vocab": vocab, "labels": "@nest"},
+				"@id":      "https://ex.org/ann",
+				"knows":    map[string]interface{}{"@context": []interface{}{nil, map[string]interface{}{"@vocab": vocab}}, "@id": "https://ex.org/bob", "labels": "b"},
+			},
+		}
+	)
+
+	for i, doc := range cases {
+		if expanded, err := Expand(doc); err == nil {
+			test.Errorf("Expand of conflicting definitions %v should fail: %v", i, expanded)
+		}
+	}
+
+	//The same 1.1 definition in two contexts is not a conflict
+	same := map[string]interface{}{
+		"@context": map[string]interface{}{"@vocab": vocab, "labels": "@nest"},
+		"@id":      "https://ex.org/ann",
+		"knows": map[string]interface{}{
+			"@context": map[string]interface{}{"labels": "@nest"},
+			"@id":      "https://ex.org/bob",
+			"labels":   map[string]interface{}{"name": "Bob"},
+		},
+	}
+	expanded, err := Expand(same)
+	if err != nil {
+		test.Fatalf("Expand of repeated definitions: %v", err)
+	}
+	bob, ok := expanded[0].(map[string]interface{})[vocab+"knows"].([]interface{})[0].(map[string]interface{})
+	if !ok || bob[vocab+"name"] == nil {
+		test.Errorf("Expand of repeated definitions: %v", expanded)
+	}
+}
@@ -1,6 +1,10 @@
 package jld
 
 import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -93,4 +97,302 @@ func TestNewV(test *testing.T) {
 }
 
 func TestNewN(test *testing.T) {
+	var (
+		personT = NewTypeID("https://ex.org/types#Person", "")
+		agentT  = NewTypeID("https://ex.org/types#Agent", "")
+		otherT  = NewTypeID("https://ex.org/types#Other", "")
+		node    map[string]interface{}
+	)
+
+	node = NewN("https://ex.org/ann", personT)
+	if node["@type"] != personT.URI() || !IsNtype(node, personT) || IsNtype(node, agentT) {
+		test.Errorf("NewN of one type: %v", node)
+	}
+	node = NewN("https://ex.org/ann", personT, agentT, personT)
+	types, ok := node["@type"].([]interface{})
+	if !ok || len(types) != 2 || types[0] != personT.URI() || types[1] != agentT.URI() {
+		test.Errorf("NewN of several types: %v", node["@type"])
+	}
+	if !IsNtype(node, personT) || !IsNtype(node, agentT) || IsNtype(node, otherT) || !IsType(node, agentT) {
+		test.Errorf("IsNtype of several types: %v", node["@type"])
+	}
+	if NewN("https://ex.org/ann") != nil {
+		test.Errorf("NewN without a type should be nil")
+	}
+
+	node = map[string]interface{}{"https://ex.org/vocab#name": "Ann"}
+	AddN(node, "", personT, agentT)
+	if id, _ := node["@id"].(string); id[:2] != "_:" || !IsNtype(node, personT) || !IsNtype(node, agentT) {
+		test.Errorf("AddN of several types: %v", node)
+	}
+	node = map[string]interface{}{}
+	AddN(node, "https://ex.org/ann")
+	if _, ok = node["@type"]; ok || node["@id"] != "https://ex.org/ann" {
+		test.Errorf("AddN without a type: %v", node)
+	}
+}
+
+func TestResolveIDs(test *testing.T) {
+	var (
+		t    = NewTypeID("https://ex.org/types#Person", "")
+		node = NewN("people/ann", t)
+		err  error
+	)
+
+	node["https://ex.org/vocab#knows"] = []interface{}{NewN("../orgs/acme", t), NewN("", t)}
+	err = ResolveIDs(node, WithBase("https://ex.org/base/doc"))
+	switch {
+	case err != nil:
+		test.Errorf("ResolveIDs: %v", err)
+	case node["@id"] != "https://ex.org/base/people/ann":
+		test.Errorf("ResolveIDs @id: %v", node["@id"])
+	}
+	knows := node["https://ex.org/vocab#knows"].([]interface{})
+	if id := knows[0].(map[string]interface{})["@id"]; id != "https://ex.org/orgs/acme" {
+		test.Errorf("ResolveIDs nested @id: %v", id)
+	}
+	if id := knows[1].(map[string]interface{})["@id"].(string); id[:2] != "_:" {
+		test.Errorf("ResolveIDs blank @id: %v", id)
+	}
+
+	err = ResolveIDs(node)
+	if err == nil {
+		test.Errorf("ResolveIDs should require a base")
+	}
+}
+
+func TestCanonicalizeCtx(test *testing.T) {
+	var (
+		ctx, cancel = context.WithCancel(context.Background())
+		node        = NewN("https://ex.org/a", NewTypeID("https://ex.org/types#A", ""))
+		err         error
+	)
+
+	cancel()
+	_, err = CanonicalizeCtx(ctx, node, []TypeID{NewTypeID("https://ex.org/types#A", "")})
+	if err != context.Canceled {
+		test.Errorf("CanonicalizeCtx: %v", err)
+	}
+	_, err = ExpandCtx(ctx, node)
+	if err != context.Canceled {
+		test.Errorf("ExpandCtx: %v", err)
+	}
+}
+
+//countdownContext is a Context that is canceled after its Err has been called n times
+type countdownContext struct {
+	context.Context
+	n int
+}
+
+func (cc *countdownContext) Err() error {
+	if cc.n <= 0 {
+		return context.Canceled
+	}
+	cc.n--
+	return nil
+}
+
+func TestCanonicalizeCtxMidRun(test *testing.T) {
+	var (
+		aT    = NewTypeID("https://ex.org/types#A", "")
+		bT    = NewTypeID("https://ex.org/types#B", "")
+		nodes []interface{}
+		err   error
+	)
+
+	for i := 0; i < 500; i++ {
+		nodes = append(nodes, map[string]interface{}{
+			"@id":                      fmt.Sprintf("https://ex.org/n%v", i),
+			"@type":                    []interface{}{aT.URI(), bT.URI()},
+			"https://ex.org/vocab#tag": map[string]interface{}{"@id": fmt.Sprintf("_:t%v", i), "https://ex.org/vocab#n": fmt.Sprint(i)},
+			"https://ex.org/vocab#see": map[string]interface{}{"@id": fmt.Sprintf("_:t%v", i)},
+		})
+	}
+	doc := map[string]interface{}{"@graph": nodes}
+
+	//The phases alone check the Context a few times; the checks of each node cancel the run once it is under way
+	for _, opts := range [][]Option{{Strict()}, {RequireAllTypes()}, {StableBlankIDs()}, {StableBlankIDs(), WrapGraph()}} {
+		_, err = CanonicalizeCtx(&countdownContext{Context: context.Background(), n: 10}, doc, []TypeID{aT}, opts...)
+		if err != context.Canceled {
+			test.Errorf("CanonicalizeCtx canceled mid-run: %v", err)
+		}
+		if _, err = CanonicalizeCtx(&countdownContext{Context: context.Background(), n: 5000}, doc, []TypeID{aT}, opts...); err != nil {
+			test.Errorf("CanonicalizeCtx: %v", err)
+		}
+	}
+	if _, err = ExpandCtx(&countdownContext{Context: context.Background(), n: 10}, doc, Strict()); err != context.Canceled {
+		test.Errorf("ExpandCtx canceled mid-run: %v", err)
+	}
+}
+
+func TestBlankIDGenerator(test *testing.T) {
+	var (
+		g    = NewBlankIDGenerator("")
+		ids  = make(chan string, 1000)
+		seen = make(map[string]bool)
+		wg   sync.WaitGroup
+	)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				ids <- g.Next()
+			}
+		}()
+	}
+	wg.Wait()
+	close(ids)
+	for id := range ids {
+		if seen[id] {
+			test.Errorf("BlankIDGenerator duplicate: %v", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != 1000 || NewBlankIDGenerator("doc").Next() != "_:doc-1" {
+		test.Errorf("BlankIDGenerator: %v ids", len(seen))
+	}
+}
+
+func TestPrefixContext(test *testing.T) {
+	var (
+		ctx map[string]interface{}
+		err error
+	)
+
+	ctx, err = prefixContext(map[string]string{"tn": "https://ex.org/tn#", "schema": "http://schema.org/"})
+	if err != nil || ctx["@context"].(map[string]interface{})["tn"] != "https://ex.org/tn#" {
+		test.Errorf("prefixContext: %v %v", ctx, err)
+	}
+	for _, bad := range []map[string]string{{"t:n": "https://ex.org/tn#"}, {"@vocab": "https://ex.org/"}, {"tn": "tn#"}} {
+		if _, err = prefixContext(bad); err == nil {
+			test.Errorf("prefixContext %v: no error", bad)
+		}
+	}
+}
+
+func TestDeepCopy(test *testing.T) {
+	var (
+		tagsP = NewPropID("https://ex.org/vocab#tags", "")
+		stepP = NewPropID("https://ex.org/vocab#steps", "")
+		doc   = map[string]interface{}{
+			"@graph": []interface{}{
+				map[string]interface{}{
+					"@id":       "https://ex.org/a",
+					tagsP.URI(): "x",
+					stepP.URI(): NewL("one"),
+					"@reverse":  map[string]interface{}{"https://ex.org/vocab#of": map[string]interface{}{"@id": "https://ex.org/b"}},
+				},
+			},
+		}
+		cp   map[string]interface{}
+		node map[string]interface{}
+	)
+
+	cp = DeepCopy(doc).(map[string]interface{})
+	node = cp["@graph"].([]interface{})[0].(map[string]interface{})
+	GetSet(node, tagsP)
+	GetList(node, stepP)
+	node["@reverse"].(map[string]interface{})["https://ex.org/vocab#of"] = nil
+
+	orig := doc["@graph"].([]interface{})[0].(map[string]interface{})
+	if orig[tagsP.URI()] != "x" {
+		test.Errorf("GetSet of the copy should not normalize the original: %v", orig[tagsP.URI()])
+	}
+	if orig[stepP.URI()].(map[string]interface{})["@list"] != "one" {
+		test.Errorf("GetList of the copy should not normalize the original: %v", orig[stepP.URI()])
+	}
+	if orig["@reverse"].(map[string]interface{})["https://ex.org/vocab#of"] == nil {
+		test.Errorf("A nested map of the copy should not be shared")
+	}
+	if DeepCopy("s") != "s" || DeepCopy(nil) != nil {
+		test.Errorf("DeepCopy of a primitive should be the primitive")
+	}
+}
+
+func TestProps(test *testing.T) {
+	var (
+		node = map[string]interface{}{
+			"https://ex.org/vocab#b": 2,
+			"@type":                  "https://ex.org/vocab#T",
+			"https://ex.org/vocab#a": 1,
+			"@id":                    "https://ex.org/x",
+		}
+		ids []string
+	)
+
+	for i := 0; i < 10; i++ {
+		ids = nil
+		for _, prop := range Props(node) {
+			ids = append(ids, prop.ID.URI())
+		}
+		if strings.Join(ids, " ") != "@id @type https://ex.org/vocab#a https://ex.org/vocab#b" {
+			test.Fatalf("Props: %v", ids)
+		}
+	}
+	if props := Props(node); props[2].Value != 1 || props[3].Value != 2 {
+		test.Errorf("Props values: %v", props)
+	}
+	if Props("x") != nil || len(Props(map[string]interface{}{})) != 0 {
+		test.Errorf("Props of a non-node or empty node")
+	}
+}
+
+func TestCompact(test *testing.T) {
+	var (
+		ctx = map[string]interface{}{
+			"ex":     "https://ex.org/vocab#",
+			"name":   "ex:name",
+			"knows":  map[string]interface{}{"@id": "ex:knows", "@type": "@id"},
+			"Person": "https://ex.org/types#Person",
+		}
+		expanded = []interface{}{
+			map[string]interface{}{
+				"@id":                        "https://ex.org/ann",
+				"@type":                      []interface{}{"https://ex.org/types#Person"},
+				"https://ex.org/vocab#name":  []interface{}{map[string]interface{}{"@value": "Ann"}},
+				"https://ex.org/vocab#knows": []interface{}{map[string]interface{}{"@id": "https://ex.org/bob"}},
+				"https://ex.org/vocab#age":   []interface{}{map[string]interface{}{"@value": 42.0}},
+			},
+		}
+		loader = compactLoader{"https://ex.org/context.jsonld": map[string]interface{}{"@context": ctx}}
+	)
+
+	for _, c := range []interface{}{ctx, map[string]interface{}{"@context": ctx}, "https://ex.org/context.jsonld"} {
+		compacted, err := Compact(expanded, c, WithLoader(loader))
+		if err != nil {
+			test.Fatalf("Compact against %v: %v", c, err)
+		}
+		switch {
+		case compacted["@context"] == nil:
+			test.Errorf("Compact has no @context: %v", compacted)
+		case compacted["@type"] != "Person" || compacted["name"] != "Ann" || compacted["knows"] != "https://ex.org/bob":
+			test.Errorf("Compact against %v: %v", c, compacted)
+		case compacted["ex:age"] != 42.0:
+			test.Errorf("Compact of a property without a term: %v", compacted)
+		}
+		reexpanded, err := Expand(compacted, WithLoader(loader))
+		if err != nil {
+			test.Fatalf("Expand of Compact: %v", err)
+		}
+		if equal, err := Equal(reexpanded, expanded); err != nil || !equal {
+			test.Errorf("Compact changed the graph: %v %v", compacted, err)
+		}
+	}
+
+	if compacted, err := Compact(expanded, "https://ex.org/missing.jsonld", WithLoader(loader)); err == nil {
+		test.Errorf("Compact against a context that cannot be loaded should fail: %v", compacted)
+	}
+}
+
+//compactLoader is a DocumentLoader of the documents of a map
+type compactLoader map[string]interface{}
+
+func (cl compactLoader) LoadDocument(u string) (*RemoteDocument, error) {
+	if doc, ok := cl[u]; ok {
+		return &RemoteDocument{DocumentURL: u, Document: doc}, nil
+	}
+	return nil, fmt.Errorf("Not Found: %v", u)
 }
@@ -0,0 +1,68 @@
+/*
+Command jldgen generates the PropID and TypeID vars of the terms of a JSON LD context file (see jld.GenerateIDs), so that
+a vocabulary and the code that uses it are kept in sync by go generate, e.g.:
+
+	//go:generate go run github.com/develrns/resilient/jld/jldgen -context vocab.jsonld -package vocab -o ids.go
+
+The command accepts the following flags:
+
+	-context	- the JSON LD context file
+	-package	- the package of the generated file
+	-o		- the generated file (default stdout)
+*/
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/develrns/resilient/jld"
+	"github.com/develrns/resilient/log"
+)
+
+func main() {
+	var (
+		logger      = log.Logger()
+		contextFile string
+		pkg         string
+		out         string
+		contextJSON []byte
+		context     interface{}
+		src         []byte
+		err         error
+	)
+
+	flag.StringVar(&contextFile, "context", "", "the JSON LD context file")
+	flag.StringVar(&pkg, "package", "", "the package of the generated file")
+	flag.StringVar(&out, "o", "", "the generated file (default stdout)")
+	flag.Parse()
+	if contextFile == "" || pkg == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	contextJSON, err = ioutil.ReadFile(contextFile)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	err = json.Unmarshal(contextJSON, &context)
+	if err != nil {
+		logger.Fatalf("Bad Context File %v: %v\n", contextFile, err)
+	}
+	src, err = jld.GenerateIDs(context, pkg, filepath.Base(contextFile))
+	if err != nil {
+		logger.Fatal(err)
+	}
+
+	if out == "" {
+		os.Stdout.Write(src)
+		return
+	}
+	err = ioutil.WriteFile(out, src, 0644)
+	if err != nil {
+		logger.Fatal(err)
+	}
+}
@@ -0,0 +1,130 @@
+package jld
+
+import (
+	"sort"
+	"strings"
+)
+
+/*
+NewLV creates a language-tagged string value object. The language is a BCP 47 tag such as "en" or "fr-CA".
+*/
+func NewLV(lang, value string) map[string]interface{} {
+	valobj := make(map[string]interface{}, 2)
+	valobj["@language"] = lang
+	valobj["@value"] = value
+	return valobj
+}
+
+/*
+GetLang returns the language of a language-tagged value object.
+*/
+func GetLang(input interface{}) (string, bool) {
+	var (
+		valobj map[string]interface{}
+		lang   string
+		ok     bool
+	)
+
+	valobj, ok = input.(map[string]interface{})
+	if !ok {
+		return "", false
+	}
+	lang, ok = valobj["@language"].(string)
+	return lang, ok
+}
+
+//A langString is a string value and its language ("" if it has none)
+type langString struct {
+	lang  string
+	value string
+}
+
+//langStrings returns the string values of a property: a string, a value object, a set of these or a language map
+func langStrings(propI interface{}) []langString {
+	var (
+		strs []langString
+		obj  map[string]interface{}
+		ok   bool
+	)
+
+	switch propI.(type) {
+	case string:
+		return []langString{{value: propI.(string)}}
+	case []interface{}:
+		for _, item := range propI.([]interface{}) {
+			strs = append(strs, langStrings(item)...)
+		}
+		return strs
+	case map[string]interface{}:
+		obj = propI.(map[string]interface{})
+	default:
+		return nil
+	}
+
+	if _, ok = obj["@value"]; ok {
+		s, ok := obj["@value"].(string)
+		if !ok {
+			return nil
+		}
+		lang, _ := obj["@language"].(string)
+		return []langString{{lang: lang, value: s}}
+	}
+
+	//A language map (a property with an @language container in compacted form) maps language tags to strings.
+	//It is sorted by language so that the fallback to any string is deterministic.
+	for lang, item := range obj {
+		for _, ls := range langStrings(item) {
+			strs = append(strs, langString{lang: lang, value: ls.value})
+		}
+	}
+	sort.SliceStable(strs, func(i, j int) bool { return strs[i].lang < strs[j].lang })
+	return strs
+}
+
+/*
+GetStringByLang gets the string of a node's multilingual property that best matches the languages, in preference
+order. The property may be a language-tagged value object, a set of them or a language map. For each language in
+turn, an exact match is preferred over a match of its primary language (e.g. "fr-CA" matches "fr" and "fr" matches
+"fr-FR"). If no language matches, a string with no language and then any string is returned.
+*/
+func GetStringByLang(input interface{}, propID PropID, langs ...string) (string, bool) {
+	var (
+		prop interface{}
+		strs []langString
+		best = -1
+		rank int
+	)
+
+	prop, _ = GetP(input, propID)
+	strs = langStrings(prop)
+	if len(strs) == 0 {
+		return "", false
+	}
+
+	//A lower rank is a better match; ranks past the requested languages are the fallbacks
+	for i, ls := range strs {
+		rank = langRank(ls.lang, langs)
+		if best < 0 || rank < langRank(strs[best].lang, langs) {
+			best = i
+		}
+	}
+	return strs[best].value, true
+}
+
+//langRank ranks how well a value's language matches the preferred languages
+func langRank(lang string, langs []string) int {
+	var primary = strings.ToLower(strings.SplitN(lang, "-", 2)[0])
+
+	for i, want := range langs {
+		switch {
+		case strings.EqualFold(lang, want):
+			return 2 * i
+		case lang != "" && primary == strings.ToLower(strings.SplitN(want, "-", 2)[0]):
+			return 2*i + 1
+		}
+	}
+	if lang == "" {
+		return 2 * len(langs)
+	}
+	return 2*len(langs) + 1
+}
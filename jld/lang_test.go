@@ -0,0 +1,49 @@
+package jld
+
+import (
+	"testing"
+)
+
+func TestLangValues(test *testing.T) {
+	var (
+		labelP = NewPropID("https://ex.org/vocab#label", "")
+		node   = map[string]interface{}{
+			labelP.URI(): []interface{}{NewLV("en", "Login"), NewLV("fr-CA", "Connexion"), "login"},
+		}
+		compacted = map[string]interface{}{
+			labelP.URI(): map[string]interface{}{"de": "Anmeldung", "es": "Iniciar sesión"},
+		}
+		s    string
+		lang string
+		ok   bool
+	)
+
+	lang, ok = GetLang(NewLV("de", "Anmeldung"))
+	if !ok || lang != "de" {
+		test.Errorf("GetLang: %v %v", lang, ok)
+	}
+	if _, ok = GetLang(NewV(NewTypeID(xsdBase+"string", ""), "x")); ok {
+		test.Errorf("GetLang of a typed value should fail")
+	}
+
+	for _, tc := range []struct {
+		input    interface{}
+		langs    []string
+		expected string
+	}{
+		{node, []string{"fr-CA", "en"}, "Connexion"},
+		{node, []string{"fr", "en"}, "Connexion"},
+		{node, []string{"EN"}, "Login"},
+		{node, []string{"ja"}, "login"},
+		{compacted, []string{"es-MX"}, "Iniciar sesión"},
+		{compacted, nil, "Anmeldung"},
+	} {
+		s, ok = GetStringByLang(tc.input, labelP, tc.langs...)
+		if !ok || s != tc.expected {
+			test.Errorf("GetStringByLang %v: %v %v", tc.langs, s, ok)
+		}
+	}
+	if _, ok = GetStringByLang(node, NewPropID("https://ex.org/vocab#none", "")); ok {
+		test.Errorf("GetStringByLang of a missing property should fail")
+	}
+}
@@ -0,0 +1,110 @@
+package jld
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+)
+
+/*
+NewListFrom creates a list object from a typed slice (e.g. a []string, []int or []map[string]interface{}) so that an
+ordered collection need not first be copied to a []interface{}. Each element must be a JSON LD value: a string, bool, int,
+int64, float32, float64, json.Number, map[string]interface{} or []interface{}. A nil slice creates an empty list.
+*/
+func NewListFrom(slice interface{}) (map[string]interface{}, error) {
+	var (
+		sv    = reflect.ValueOf(slice)
+		items []interface{}
+	)
+
+	if slice == nil {
+		return NewL([]interface{}{}), nil
+	}
+	if sv.Kind() != reflect.Slice && sv.Kind() != reflect.Array {
+		return nil, nodeError(ErrNotAList, nil, "", "%T is not a slice", slice)
+	}
+	items = make([]interface{}, sv.Len())
+	for i := range items {
+		item := sv.Index(i).Interface()
+		switch item.(type) {
+		case string, bool, int, int64, float32, float64, json.Number, map[string]interface{}, []interface{}:
+			items[i] = item
+		default:
+			return nil, nodeError(ErrBadValue, nil, Pointer(strconv.Itoa(i)), "%v of type %T is not a JSON LD value", item, item)
+		}
+	}
+	return NewL(items), nil
+}
+
+/*
+InsertAt inserts items into a node's list property before the item at index; an index equal to the length of the list
+appends them. It returns the resulting slice.
+*/
+func InsertAt(input interface{}, propID PropID, index int, items ...interface{}) ([]interface{}, error) {
+	var (
+		listObj  map[string]interface{}
+		slice    []interface{}
+		newSlice []interface{}
+		err      error
+	)
+
+	listObj, slice, err = listOf(input, propID)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index > len(slice) {
+		return nil, nodeError(ErrBadIndex, input, Pointer(propID.URI(), "@list", strconv.Itoa(index)), "%v of a list of %v items", index, len(slice))
+	}
+	newSlice = make([]interface{}, 0, len(slice)+len(items))
+	newSlice = append(newSlice, slice[:index]...)
+	newSlice = append(newSlice, items...)
+	newSlice = append(newSlice, slice[index:]...)
+	listObj["@list"] = newSlice
+	return newSlice, nil
+}
+
+/*
+RemoveAt removes the item at index from a node's list property. It returns the resulting slice.
+*/
+func RemoveAt(input interface{}, propID PropID, index int) ([]interface{}, error) {
+	var (
+		listObj  map[string]interface{}
+		slice    []interface{}
+		newSlice []interface{}
+		err      error
+	)
+
+	listObj, slice, err = listOf(input, propID)
+	if err != nil {
+		return nil, err
+	}
+	if index < 0 || index >= len(slice) {
+		return nil, nodeError(ErrBadIndex, input, Pointer(propID.URI(), "@list", strconv.Itoa(index)), "%v of a list of %v items", index, len(slice))
+	}
+	newSlice = make([]interface{}, 0, len(slice)-1)
+	newSlice = append(newSlice, slice[:index]...)
+	newSlice = append(newSlice, slice[index+1:]...)
+	listObj["@list"] = newSlice
+	return newSlice, nil
+}
+
+//listOf returns the list object of a node's list property and its items
+func listOf(input interface{}, propID PropID) (map[string]interface{}, []interface{}, error) {
+	var (
+		node  map[string]interface{}
+		listI interface{}
+		slice []interface{}
+		ok    bool
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return nil, nil, notANode(input)
+	}
+	slice, ok = GetList(node, propID)
+	if !ok {
+		return nil, nil, nodeError(ErrNotAList, node, Pointer(propID.URI()), "%v", propID.URI())
+	}
+	_, listI, _ = propValue(node, propID)
+	return listI.(map[string]interface{}), slice, nil
+}
@@ -0,0 +1,87 @@
+package jld
+
+import (
+	"testing"
+)
+
+func TestNewListFrom(test *testing.T) {
+	var (
+		list map[string]interface{}
+		err  error
+	)
+
+	list, err = NewListFrom([]string{"a", "b"})
+	if err != nil || !sameList(list["@list"].([]interface{}), []interface{}{"a", "b"}) {
+		test.Errorf("NewListFrom []string: %v %v", list, err)
+	}
+	list, err = NewListFrom([]int{1, 2})
+	if err != nil || !sameList(list["@list"].([]interface{}), []interface{}{1, 2}) {
+		test.Errorf("NewListFrom []int: %v %v", list, err)
+	}
+	list, err = NewListFrom([]map[string]interface{}{NewN("https://ex.org/a")})
+	if err != nil || len(list["@list"].([]interface{})) != 1 {
+		test.Errorf("NewListFrom []map[string]interface{}: %v %v", list, err)
+	}
+	list, err = NewListFrom(nil)
+	if err != nil || len(list["@list"].([]interface{})) != 0 {
+		test.Errorf("NewListFrom nil: %v %v", list, err)
+	}
+	if _, err = NewListFrom("a"); err == nil {
+		test.Errorf("NewListFrom of a string should fail")
+	}
+	if _, err = NewListFrom([]struct{}{{}}); err == nil {
+		test.Errorf("NewListFrom of a []struct{} should fail")
+	}
+}
+
+func TestInsertRemoveAt(test *testing.T) {
+	var (
+		stepsP = NewPropID("https://ex.org/vocab#steps", "")
+		nameP  = NewPropID("https://ex.org/vocab#name", "")
+		node   = map[string]interface{}{stepsP.URI(): NewL([]interface{}{"b", "d"}), nameP.URI(): "x"}
+		slice  []interface{}
+		err    error
+	)
+
+	slice, err = InsertAt(node, stepsP, 0, "a")
+	if err != nil || !sameList(slice, []interface{}{"a", "b", "d"}) {
+		test.Fatalf("InsertAt 0: %v %v", slice, err)
+	}
+	slice, err = InsertAt(node, stepsP, 2, "c")
+	if err != nil || !sameList(slice, []interface{}{"a", "b", "c", "d"}) {
+		test.Fatalf("InsertAt 2: %v %v", slice, err)
+	}
+	slice, err = InsertAt(node, stepsP, 4, "e", "f")
+	if err != nil || !sameList(slice, []interface{}{"a", "b", "c", "d", "e", "f"}) {
+		test.Fatalf("InsertAt the end: %v %v", slice, err)
+	}
+	slice, err = RemoveAt(node, stepsP, 1)
+	if err != nil || !sameList(slice, []interface{}{"a", "c", "d", "e", "f"}) {
+		test.Fatalf("RemoveAt 1: %v %v", slice, err)
+	}
+	if got, _ := GetList(node, stepsP); !sameList(got, slice) {
+		test.Errorf("The node's list should be updated: %v", got)
+	}
+	if _, err = RemoveAt(node, stepsP, 5); err == nil {
+		test.Errorf("RemoveAt out of range should fail")
+	}
+	if _, err = InsertAt(node, stepsP, -1, "z"); err == nil {
+		test.Errorf("InsertAt out of range should fail")
+	}
+	if _, err = InsertAt(node, nameP, 0, "z"); err == nil {
+		test.Errorf("InsertAt of a property that is not a list should fail")
+	}
+}
+
+//sameList is true if two slices have equal items
+func sameList(a, b []interface{}) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,366 @@
+package jld
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kazarena/json-gold/ld"
+)
+
+type (
+	//RemoteDocument is a document retrieved by a DocumentLoader.
+	RemoteDocument = ld.RemoteDocument
+
+	//A DocumentLoader retrieves the remote documents (typically @context documents) referenced by a JSON LD document.
+	DocumentLoader interface {
+		LoadDocument(u string) (*RemoteDocument, error)
+	}
+)
+
+/*
+An HTTPLoader is a DocumentLoader that fetches documents with an HTTP GET. Since the URLs it fetches come from the
+@context of untrusted documents, each fetch is limited (see the LoaderOptions) so that a malicious @context URL cannot
+stall or bloat document processing:
+
+	hosts	- the hosts that may be fetched from, including by redirects (AllowHosts); by default none
+	timeout	- the time allowed for a fetch, including reading the document (FetchTimeout); by default 10 seconds
+	size	- the maximum size of a document (MaxDocumentSize); by default 1 MiB
+*/
+type HTTPLoader struct {
+	client  *http.Client
+	hosts   []string
+	anyHost bool
+	timeout time.Duration
+	maxSize int64
+}
+
+/*
+defaultLoader is the DocumentLoader used without WithLoader. Since it allows no hosts, the @context URL of an untrusted
+document cannot make a service fetch it unless the service opted in with WithLoader.
+*/
+var defaultLoader = NewHTTPLoader(nil)
+
+//A LoaderOption configures an HTTPLoader
+type LoaderOption func(*HTTPLoader)
+
+//The default limits of an HTTPLoader
+const (
+	defaultFetchTimeout    = 10 * time.Second
+	defaultMaxDocumentSize = 1 << 20
+)
+
+/*
+AllowHosts limits an HTTPLoader to fetching from the hosts. A host is a hostname, without a port, or a wildcard such
+as *.ex.org that allows the subdomains of ex.org (but not ex.org itself). Hostnames are not case sensitive.
+*/
+func AllowHosts(hosts ...string) LoaderOption {
+	return func(l *HTTPLoader) {
+		for _, host := range hosts {
+			l.hosts = append(l.hosts, strings.ToLower(host))
+		}
+	}
+}
+
+/*
+AllowAnyHost lets an HTTPLoader fetch from any host, e.g. that of a loopback test server or, behind an egress proxy
+that limits them, the hosts of partners' contexts. Without it, an HTTPLoader fetches only from the hosts of AllowHosts,
+so that a @context URL of an untrusted document cannot make a service request its internal network.
+*/
+func AllowAnyHost() LoaderOption {
+	return func(l *HTTPLoader) {
+		l.anyHost = true
+	}
+}
+
+/*
+FetchTimeout limits the time of each fetch of an HTTPLoader, from sending the request to reading the whole document.
+*/
+func FetchTimeout(timeout time.Duration) LoaderOption {
+	return func(l *HTTPLoader) {
+		l.timeout = timeout
+	}
+}
+
+/*
+MaxDocumentSize limits the size in bytes of the documents fetched by an HTTPLoader.
+*/
+func MaxDocumentSize(size int64) LoaderOption {
+	return func(l *HTTPLoader) {
+		l.maxSize = size
+	}
+}
+
+/*
+NewHTTPLoader creates an HTTPLoader that uses the client. If the client is nil, http.DefaultClient is used. The
+HTTPLoader fetches only from the hosts allowed by AllowHosts, or from any host with AllowAnyHost; with neither, every
+fetch fails. The client is not changed; unless any host is allowed, the HTTPLoader uses a copy of it that also checks
+redirects against the allowed hosts.
+*/
+func NewHTTPLoader(client *http.Client, opts ...LoaderOption) *HTTPLoader {
+	var l = HTTPLoader{timeout: defaultFetchTimeout, maxSize: defaultMaxDocumentSize}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+	for _, opt := range opts {
+		opt(&l)
+	}
+	l.client = client
+	if !l.anyHost {
+		l.client = l.checkingRedirects(client)
+	}
+	return &l
+}
+
+//checkingRedirects returns a copy of a client whose redirects are also checked against the allowed hosts
+func (l *HTTPLoader) checkingRedirects(client *http.Client) *http.Client {
+	var (
+		checking = *client
+		next     = client.CheckRedirect
+	)
+
+	checking.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !l.allowed(req.URL) {
+			return fmt.Errorf("Redirect to host %v not allowed", req.URL.Hostname())
+		}
+		if next != nil {
+			return next(req, via)
+		}
+
+		//The http package's default policy
+		if len(via) >= 10 {
+			return fmt.Errorf("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &checking
+}
+
+//allowed is true if a URL's host is allowed
+func (l *HTTPLoader) allowed(u *url.URL) bool {
+	var host = strings.ToLower(u.Hostname())
+
+	if l.anyHost {
+		return true
+	}
+	for _, allowed := range l.hosts {
+		if allowed == host || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+LoadDocument fetches and parses a JSON LD document.
+*/
+func (l *HTTPLoader) LoadDocument(u string) (*RemoteDocument, error) {
+	var (
+		req    *http.Request
+		rsp    *http.Response
+		ctx    = context.Background()
+		cancel context.CancelFunc
+		body   []byte
+		doc    interface{}
+		err    error
+	)
+
+	req, err = http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !l.allowed(req.URL) {
+		return nil, fmt.Errorf("Loading document %v failed: host %v not allowed", u, req.URL.Hostname())
+	}
+	if l.timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, l.timeout)
+		defer cancel()
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "application/ld+json, application/json")
+	rsp, err = l.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("Loading document %v failed: %v", u, err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("Loading document %v failed: %v", u, rsp.Status)
+	}
+
+	//One byte more than the maximum is read to detect a document that is too large
+	if l.maxSize > 0 && rsp.ContentLength > l.maxSize {
+		return nil, fmt.Errorf("Loading document %v failed: its size %v exceeds %v", u, rsp.ContentLength, l.maxSize)
+	}
+	if l.maxSize > 0 {
+		body, err = ioutil.ReadAll(io.LimitReader(rsp.Body, l.maxSize+1))
+	} else {
+		body, err = ioutil.ReadAll(rsp.Body)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Loading document %v failed: %v", u, err)
+	}
+	if l.maxSize > 0 && int64(len(body)) > l.maxSize {
+		return nil, fmt.Errorf("Loading document %v failed: its size exceeds %v", u, l.maxSize)
+	}
+	doc, err = ld.DocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("Parsing document %v failed: %v", u, err)
+	}
+	return &RemoteDocument{DocumentURL: rsp.Request.URL.String(), Document: doc}, nil
+}
+
+/*
+A CachingLoader is a DocumentLoader that caches the documents loaded by another DocumentLoader for a TTL.
+It may be shared by concurrent requests.
+*/
+type CachingLoader struct {
+	next  DocumentLoader
+	ttl   time.Duration
+	m     sync.Mutex
+	cache map[string]cachedDocument
+}
+
+//cachedDocument is a cached document and its expiry time
+type cachedDocument struct {
+	doc     *RemoteDocument
+	expires time.Time
+}
+
+/*
+NewCachingLoader creates a CachingLoader that caches the documents loaded by next for the ttl.
+*/
+func NewCachingLoader(next DocumentLoader, ttl time.Duration) *CachingLoader {
+	var cl CachingLoader
+	cl.next = next
+	cl.ttl = ttl
+	cl.cache = make(map[string]cachedDocument)
+	return &cl
+}
+
+/*
+LoadDocument returns a cached document if it has not expired; otherwise, it loads and caches it.
+Failed loads are not cached. Each load returns a copy of the cached document, so that a caller that changes it does not
+change the documents of other callers.
+*/
+func (cl *CachingLoader) LoadDocument(u string) (*RemoteDocument, error) {
+	var (
+		cached cachedDocument
+		doc    *RemoteDocument
+		ok     bool
+		err    error
+	)
+
+	cl.m.Lock()
+	cached, ok = cl.cache[u]
+	cl.m.Unlock()
+	if ok && time.Now().Before(cached.expires) {
+		return copyDocument(cached.doc), nil
+	}
+
+	doc, err = cl.next.LoadDocument(u)
+	if err != nil {
+		return nil, err
+	}
+
+	cl.m.Lock()
+	defer cl.m.Unlock()
+	cl.cache[u] = cachedDocument{doc: copyDocument(doc), expires: time.Now().Add(cl.ttl)}
+	return doc, nil
+}
+
+//copyDocument returns a copy of a remote document
+func copyDocument(doc *RemoteDocument) *RemoteDocument {
+	var copied = *doc
+
+	copied.Document = DeepCopy(doc.Document)
+	return &copied
+}
+
+/*
+Purge deletes all expired documents from the cache.
+*/
+func (cl *CachingLoader) Purge() {
+	var now = time.Now()
+
+	cl.m.Lock()
+	defer cl.m.Unlock()
+	for u, cached := range cl.cache {
+		if now.After(cached.expires) {
+			delete(cl.cache, u)
+		}
+	}
+	return
+}
+
+/*
+The static context registry holds context documents, such as schema.org's or a service's own vocabulary's, that are
+compiled into the binary (e.g. with go:embed) and registered with RegisterContext. The ld processor resolves the
+@context URLs of the registered documents from the registry rather than the network, whatever the DocumentLoader
+configured by WithLoader, so that their expansion has no network dependency in production.
+
+The registry is shared by concurrent requests and is mutexed. Contexts are typically registered in an init function.
+*/
+var contexts = struct {
+	m sync.RWMutex
+	d map[string]interface{}
+}{d: make(map[string]interface{})}
+
+/*
+RegisterContext registers the context document of a URL, replacing any earlier registration. The document may be
+JSON, as a []byte or string, or unmarshalled JSON. A document without an @context property is taken to be the
+context itself.
+*/
+func RegisterContext(u string, document interface{}) error {
+	var err error
+
+	switch document.(type) {
+	case []byte:
+		document, err = ld.DocumentFromReader(bytes.NewReader(document.([]byte)))
+	case string:
+		document, err = ld.DocumentFromReader(strings.NewReader(document.(string)))
+	}
+	if err != nil {
+		return fmt.Errorf("Parsing context %v failed: %v", u, err)
+	}
+	if m, ok := document.(map[string]interface{}); !ok || m["@context"] == nil {
+		document = map[string]interface{}{"@context": document}
+	}
+
+	contexts.m.Lock()
+	defer contexts.m.Unlock()
+	contexts.d[u] = DeepCopy(document)
+	return nil
+}
+
+/*
+UnregisterContext removes the registered context document of a URL.
+*/
+func UnregisterContext(u string) {
+	contexts.m.Lock()
+	defer contexts.m.Unlock()
+	delete(contexts.d, u)
+}
+
+//staticLoader is a DocumentLoader that loads registered contexts from the registry and other documents with next
+type staticLoader struct {
+	next DocumentLoader
+}
+
+//LoadDocument implements DocumentLoader; each load of a registered context is a copy so that it cannot be changed
+func (sl staticLoader) LoadDocument(u string) (*RemoteDocument, error) {
+	contexts.m.RLock()
+	document, ok := contexts.d[u]
+	contexts.m.RUnlock()
+	if ok {
+		return &RemoteDocument{DocumentURL: u, Document: DeepCopy(document)}, nil
+	}
+	return sl.next.LoadDocument(u)
+}
@@ -0,0 +1,175 @@
+package jld
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+type countingLoader struct {
+	loads int
+}
+
+func (cl *countingLoader) LoadDocument(u string) (*RemoteDocument, error) {
+	cl.loads++
+	return &RemoteDocument{DocumentURL: u, Document: map[string]interface{}{"@context": map[string]interface{}{}}}, nil
+}
+
+func TestCachingLoader(test *testing.T) {
+	var (
+		next = &countingLoader{}
+		cl   = NewCachingLoader(next, time.Hour)
+		doc  *RemoteDocument
+		err  error
+	)
+
+	for i := 0; i < 3; i++ {
+		doc, err = cl.LoadDocument("https://ex.org/context.jsonld")
+		if err != nil || doc.DocumentURL != "https://ex.org/context.jsonld" {
+			test.Fatalf("LoadDocument: %v %v", doc, err)
+		}
+	}
+	if next.loads != 1 {
+		test.Errorf("LoadDocument loads: %v", next.loads)
+	}
+
+	//A change to a loaded document does not change the cached document
+	doc.Document.(map[string]interface{})["@context"] = "changed"
+	doc, _ = cl.LoadDocument("https://ex.org/context.jsonld")
+	if _, ok := doc.Document.(map[string]interface{})["@context"].(map[string]interface{}); !ok {
+		test.Errorf("LoadDocument returned a changed document: %v", doc.Document)
+	}
+
+	cl = NewCachingLoader(next, -time.Second)
+	cl.LoadDocument("https://ex.org/context.jsonld")
+	cl.LoadDocument("https://ex.org/context.jsonld")
+	if next.loads != 3 {
+		test.Errorf("LoadDocument expired loads: %v", next.loads)
+	}
+}
+
+func TestHTTPLoaderLimits(test *testing.T) {
+	var (
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/large":
+				w.Write([]byte(`{"@context": {"name": "` + strings.Repeat("x", 2048) + `"}}`))
+			case "/slow":
+				time.Sleep(200 * time.Millisecond)
+				w.Write([]byte(`{"@context": {}}`))
+			case "/redirect":
+				http.Redirect(w, r, "http://localhost.invalid/context", http.StatusFound)
+			default:
+				w.Write([]byte(`{"@context": {}}`))
+			}
+		}))
+		l   *HTTPLoader
+		doc *RemoteDocument
+		err error
+	)
+	defer server.Close()
+
+	l = NewHTTPLoader(nil, AllowHosts("127.0.0.1"), MaxDocumentSize(1024), FetchTimeout(50*time.Millisecond))
+	doc, err = l.LoadDocument(server.URL + "/context")
+	if err != nil || doc.Document == nil {
+		test.Errorf("LoadDocument: %v %v", doc, err)
+	}
+	for _, path := range []string{"/large", "/slow", "/redirect"} {
+		if _, err = l.LoadDocument(server.URL + path); err == nil {
+			test.Errorf("LoadDocument %v should fail", path)
+		}
+	}
+	if _, err = l.LoadDocument("http://ex.org/context"); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		test.Errorf("LoadDocument of a host that is not allowed: %v", err)
+	}
+
+	//No host is allowed by default
+	l = NewHTTPLoader(nil)
+	if _, err = l.LoadDocument(server.URL + "/context"); err == nil || !strings.Contains(err.Error(), "not allowed") {
+		test.Errorf("LoadDocument without allowed hosts: %v", err)
+	}
+	l = NewHTTPLoader(nil, AllowAnyHost())
+	if doc, err = l.LoadDocument(server.URL + "/context"); err != nil {
+		test.Errorf("LoadDocument with any host allowed: %v", err)
+	}
+
+	l = NewHTTPLoader(nil, AllowHosts("*.ex.org"))
+	switch {
+	case !l.allowed(&url.URL{Host: "a.EX.org:8443"}):
+		test.Errorf("A subdomain should be allowed by a wildcard")
+	case l.allowed(&url.URL{Host: "ex.org"}) || l.allowed(&url.URL{Host: "badex.org"}):
+		test.Errorf("A wildcard should only allow subdomains")
+	}
+}
+
+func TestRegisterContext(test *testing.T) {
+	var (
+		next = &countingLoader{}
+		sl   = staticLoader{next: next}
+		doc  *RemoteDocument
+		err  error
+	)
+
+	err = RegisterContext("https://ex.org/embedded.jsonld", []byte(`{"@context": {"name": "https://ex.org/vocab#name"}}`))
+	if err != nil {
+		test.Fatalf("RegisterContext: %v", err)
+	}
+	defer UnregisterContext("https://ex.org/embedded.jsonld")
+	RegisterContext("https://ex.org/bare.jsonld", map[string]interface{}{"name": "https://ex.org/vocab#name"})
+	defer UnregisterContext("https://ex.org/bare.jsonld")
+
+	doc, err = sl.LoadDocument("https://ex.org/embedded.jsonld")
+	ctx, _ := doc.Document.(map[string]interface{})["@context"].(map[string]interface{})
+	if err != nil || ctx["name"] != "https://ex.org/vocab#name" || next.loads != 0 {
+		test.Errorf("LoadDocument of a registered context: %v %v %v", doc, err, next.loads)
+	}
+	ctx["name"] = nil
+	doc, _ = sl.LoadDocument("https://ex.org/embedded.jsonld")
+	if doc.Document.(map[string]interface{})["@context"].(map[string]interface{})["name"] == nil {
+		test.Errorf("A loaded registered context should be a copy")
+	}
+	doc, _ = sl.LoadDocument("https://ex.org/bare.jsonld")
+	if _, ok := doc.Document.(map[string]interface{})["@context"].(map[string]interface{}); !ok {
+		test.Errorf("A bare context should be registered as a context document: %v", doc.Document)
+	}
+	sl.LoadDocument("https://ex.org/other.jsonld")
+	if next.loads != 1 {
+		test.Errorf("LoadDocument of an unregistered context: %v", next.loads)
+	}
+	if RegisterContext("https://ex.org/bad.jsonld", "{") == nil {
+		test.Errorf("RegisterContext of bad JSON should fail")
+	}
+}
+
+func TestDefaultLoader(test *testing.T) {
+	var (
+		fetches int
+		server  = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fetches++
+			w.Write([]byte(`{"@context": {"name": "https://ex.org/vocab#name"}}`))
+		}))
+		doc = map[string]interface{}{"@context": server.URL + "/context", "name": "n"}
+		err error
+	)
+	defer server.Close()
+
+	//Without WithLoader, remote contexts are not fetched, but registered ones are resolved
+	if _, err = Expand(doc); err == nil || fetches != 0 {
+		test.Errorf("Expand with a remote context without a loader: %v %v", err, fetches)
+	}
+	if _, err = ToNQuads(doc); err == nil || fetches != 0 {
+		test.Errorf("ToNQuads with a remote context without a loader: %v %v", err, fetches)
+	}
+	RegisterContext(server.URL+"/context", map[string]interface{}{"name": "https://ex.org/vocab#name"})
+	defer UnregisterContext(server.URL + "/context")
+	if expanded, err := Expand(doc); err != nil || len(expanded) != 1 || fetches != 0 {
+		test.Errorf("Expand with a registered context: %v %v %v", expanded, err, fetches)
+	}
+	UnregisterContext(server.URL + "/context")
+	if expanded, err := Expand(doc, WithLoader(NewHTTPLoader(nil, AllowAnyHost()))); err != nil || len(expanded) != 1 || fetches != 1 {
+		test.Errorf("Expand with a loader: %v %v %v", expanded, err, fetches)
+	}
+}
@@ -0,0 +1,355 @@
+package jld
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"time"
+)
+
+/*
+Marshal converts an annotated struct (or pointer to struct) into a JSON LD node map with full URI property keys.
+
+Struct fields are mapped with jld tags:
+
+	type Person struct {
+		_       struct{}  `jldtype:"https://ex.org/types#Person"`
+		ID      string    `jld:"@id"`
+		Name    string    `jld:"https://ex.org/vocab#name"`
+		Email   []string  `jld:"https://ex.org/vocab#email,omitempty"`
+		Steps   []Step    `jld:"https://ex.org/vocab#steps,list"`
+		Age     int       `jld:"https://ex.org/vocab#age,type=http://www.w3.org/2001/XMLSchema#integer"`
+		Knows   *Person   `jld:"https://ex.org/vocab#knows"`
+		Manager *Person   `jld:"https://ex.org/vocab#manager,ref"`
+		Secret  string    `jld:"-"`
+	}
+
+The jldtype tag provides the node's @type; a field tagged "@type" (a TypeID, string or slice of them) may be used instead
+when the type is only known at run time. A field tagged "@id" provides the node's @id; if there is none or it is empty,
+a blank node identifier is generated.
+
+Tag options:
+
+	omitempty - the property is omitted if the field has its zero value
+	list      - a slice is emitted as a list object rather than a set
+	type=<t>  - a primitive is emitted as a value object of type t
+	ref       - a nested struct is emitted as a node reference rather than an embedded node
+
+Primitives are emitted as is; a time.Time is emitted as an xsd:dateTime value object; a nested struct is emitted as
+an embedded node; a slice is emitted as a set; a map[string]interface{} is assumed to already be JSON LD and is emitted as is.
+Untagged fields are ignored. A pointer that has already been marshaled is emitted as a node reference so cyclic
+structures terminate.
+*/
+func Marshal(v interface{}) (map[string]interface{}, error) {
+	var (
+		rv   = reflect.ValueOf(v)
+		node interface{}
+		err  error
+	)
+
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, fmt.Errorf("Marshal of nil value")
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Marshal of non-struct value of type: %v", rv.Type())
+	}
+
+	node, err = newMarshaler().node(reflect.ValueOf(v))
+	if err != nil {
+		return nil, err
+	}
+	return node.(map[string]interface{}), nil
+}
+
+var (
+	xsdDateTime = NewTypeID("http://www.w3.org/2001/XMLSchema#dateTime", "")
+	timeType    = reflect.TypeOf(time.Time{})
+	nodeType    = reflect.TypeOf(map[string]interface{}{})
+)
+
+//jldTag is a parsed jld struct field tag
+type jldTag struct {
+	name      string
+	omitEmpty bool
+	list      bool
+	ref       bool
+	typeID    TypeID
+}
+
+//parseTag parses a jld struct field tag of the form <name>[,<option>]*
+func parseTag(tag string) jldTag {
+	var (
+		parts = strings.Split(tag, ",")
+		t     jldTag
+	)
+
+	t.name = parts[0]
+	for _, opt := range parts[1:] {
+		switch {
+		case opt == "omitempty":
+			t.omitEmpty = true
+		case opt == "list":
+			t.list = true
+		case opt == "ref":
+			t.ref = true
+		case strings.HasPrefix(opt, "type="):
+			t.typeID = NewTypeID(strings.TrimPrefix(opt, "type="), "")
+		}
+	}
+	return t
+}
+
+//marshaler tracks the pointers already marshaled so that cycles are emitted as node references
+type marshaler struct {
+	seen map[uintptr]string
+}
+
+func newMarshaler() *marshaler {
+	return &marshaler{seen: make(map[uintptr]string)}
+}
+
+//node marshals a struct or pointer to struct into a node
+func (m *marshaler) node(rv reflect.Value) (interface{}, error) {
+	var (
+		ptr   uintptr
+		isPtr bool
+		rt    reflect.Type
+		node  = make(map[string]interface{})
+		id    string
+		val   interface{}
+		tag   jldTag
+		ok    bool
+		err   error
+	)
+
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.Kind() == reflect.Ptr {
+			ptr = rv.Pointer()
+			isPtr = true
+			id, ok = m.seen[ptr]
+			if ok {
+				return map[string]interface{}{"@id": id}, nil
+			}
+		}
+		rv = rv.Elem()
+	}
+	rt = rv.Type()
+
+	//The @id and @type are set first so that a cyclic reference to this node can be emitted as a node reference
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		typeTag := field.Tag.Get("jldtype")
+		if typeTag != "" {
+			node["@type"] = NewTypeID(typeTag, "").URI()
+			continue
+		}
+		tag = parseTag(field.Tag.Get("jld"))
+		switch tag.name {
+		case "@id":
+			if rv.Field(i).Kind() != reflect.String {
+				return nil, fmt.Errorf("Marshal @id field %v is not a string", field.Name)
+			}
+			id = rv.Field(i).String()
+		case "@type":
+			val, err = marshalTypes(rv.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			if val != nil {
+				node["@type"] = val
+			}
+		}
+	}
+	if id == "" {
+		id = BlankID()
+	}
+	node["@id"] = id
+	if isPtr {
+		m.seen[ptr] = id
+	}
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		tag = parseTag(field.Tag.Get("jld"))
+		switch tag.name {
+		case "", "-", "@id", "@type":
+			continue
+		}
+		if field.PkgPath != "" {
+			return nil, fmt.Errorf("Marshal tagged field %v is not exported", field.Name)
+		}
+		fv := rv.Field(i)
+		if tag.omitEmpty && isEmptyValue(fv) {
+			continue
+		}
+		val, err = m.value(fv, tag)
+		if err != nil {
+			return nil, fmt.Errorf("Marshal field %v: %v", field.Name, err)
+		}
+		if val == nil {
+			continue
+		}
+		node[NewPropID(tag.name, "").URI()] = val
+	}
+	return node, nil
+}
+
+//marshalTypes converts an @type field to a string or slice of strings
+func marshalTypes(fv reflect.Value) (interface{}, error) {
+	var types []interface{}
+
+	switch fv.Kind() {
+	case reflect.String:
+		if fv.String() == "" {
+			return nil, nil
+		}
+		return fv.String(), nil
+	case reflect.Slice, reflect.Array:
+		for j := 0; j < fv.Len(); j++ {
+			if fv.Index(j).Kind() != reflect.String {
+				return nil, fmt.Errorf("Marshal @type field element is not a string")
+			}
+			types = append(types, fv.Index(j).String())
+		}
+		switch len(types) {
+		case 0:
+			return nil, nil
+		case 1:
+			return types[0], nil
+		default:
+			return types, nil
+		}
+	default:
+		return nil, fmt.Errorf("Marshal @type field is not a string or slice")
+	}
+}
+
+//value marshals a field value
+func (m *marshaler) value(fv reflect.Value, tag jldTag) (interface{}, error) {
+	var (
+		set []interface{}
+		val interface{}
+		err error
+	)
+
+	switch fv.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if fv.IsNil() {
+			return nil, nil
+		}
+		if fv.Kind() == reflect.Interface {
+			return m.value(fv.Elem(), tag)
+		}
+		if fv.Elem().Kind() != reflect.Struct || fv.Elem().Type() == timeType {
+			return m.value(fv.Elem(), tag)
+		}
+		return m.nodeOrRef(fv, tag)
+	case reflect.Struct:
+		if fv.Type() == timeType {
+			return NewTimeV(fv.Interface().(time.Time)), nil
+		}
+		return m.nodeOrRef(fv, tag)
+	case reflect.Map:
+		if fv.Type() != nodeType {
+			return nil, fmt.Errorf("unsupported map type: %v", fv.Type())
+		}
+		if fv.IsNil() {
+			return nil, nil
+		}
+		return fv.Interface(), nil
+	case reflect.Slice, reflect.Array:
+		if fv.Kind() == reflect.Slice && fv.IsNil() {
+			return nil, nil
+		}
+		set = make([]interface{}, 0, fv.Len())
+		for j := 0; j < fv.Len(); j++ {
+			val, err = m.value(fv.Index(j), jldTag{ref: tag.ref, typeID: tag.typeID})
+			if err != nil {
+				return nil, err
+			}
+			if val != nil {
+				set = append(set, val)
+			}
+		}
+		if tag.list {
+			return NewL(set), nil
+		}
+		return set, nil
+	}
+
+	val, err = marshalPrimitive(fv)
+	if err != nil {
+		return nil, err
+	}
+	if tag.typeID != "" {
+		return NewV(tag.typeID, val), nil
+	}
+	return val, nil
+}
+
+//nodeOrRef marshals a struct as either an embedded node or a node reference
+func (m *marshaler) nodeOrRef(fv reflect.Value, tag jldTag) (interface{}, error) {
+	var (
+		node interface{}
+		err  error
+	)
+
+	node, err = m.node(fv)
+	if err != nil {
+		return nil, err
+	}
+	if tag.ref {
+		return map[string]interface{}{"@id": node.(map[string]interface{})["@id"]}, nil
+	}
+	return node, nil
+}
+
+/*
+marshalPrimitive converts a primitive to one of the value types accepted by NewV. An integer that does not fit an int,
+such as a uint64 above math.MaxInt64, is an error rather than a wrapped value.
+*/
+func marshalPrimitive(fv reflect.Value) (interface{}, error) {
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		return fv.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i := fv.Int(); i < math.MinInt || i > math.MaxInt {
+			return nil, fmt.Errorf("value %v of type %v overflows int", i, fv.Type())
+		}
+		return int(fv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if u := fv.Uint(); u > math.MaxInt {
+			return nil, fmt.Errorf("value %v of type %v overflows int", u, fv.Type())
+		}
+		return int(fv.Uint()), nil
+	case reflect.Float32:
+		return float32(fv.Float()), nil
+	case reflect.Float64:
+		return fv.Float(), nil
+	default:
+		return nil, fmt.Errorf("unsupported type: %v", fv.Type())
+	}
+}
+
+//isEmptyValue is true if the value is its type's zero value
+func isEmptyValue(fv reflect.Value) bool {
+	switch fv.Kind() {
+	case reflect.Slice, reflect.Map, reflect.String, reflect.Array:
+		return fv.Len() == 0
+	case reflect.Ptr, reflect.Interface:
+		return fv.IsNil()
+	case reflect.Struct:
+		if fv.Type() == timeType {
+			return fv.Interface().(time.Time).IsZero()
+		}
+		return false
+	default:
+		return reflect.DeepEqual(fv.Interface(), reflect.Zero(fv.Type()).Interface())
+	}
+}
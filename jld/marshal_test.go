@@ -0,0 +1,90 @@
+package jld
+
+import (
+	"math"
+	"testing"
+)
+
+type testPerson struct {
+	_       struct{}    `jldtype:"https://ex.org/types#Person"`
+	ID      string      `jld:"@id"`
+	Name    string      `jld:"https://ex.org/vocab#name"`
+	Email   []string    `jld:"https://ex.org/vocab#email,omitempty"`
+	Age     int         `jld:"https://ex.org/vocab#age,type=http://www.w3.org/2001/XMLSchema#integer"`
+	Steps   []string    `jld:"https://ex.org/vocab#steps,list,omitempty"`
+	Knows   *testPerson `jld:"https://ex.org/vocab#knows"`
+	Manager *testPerson `jld:"https://ex.org/vocab#manager,ref"`
+	Secret  string      `jld:"-"`
+}
+
+func TestMarshal(test *testing.T) {
+	var (
+		ann   = &testPerson{ID: "https://ex.org/ann", Name: "Ann", Age: 42, Steps: []string{"a", "b"}, Secret: "x"}
+		bob   = &testPerson{Name: "Bob", Email: []string{"bob@ex.org"}}
+		node  map[string]interface{}
+		knows map[string]interface{}
+		steps []interface{}
+		ok    bool
+		err   error
+	)
+
+	ann.Knows = bob
+	bob.Knows = ann
+	ann.Manager = bob
+
+	node, err = Marshal(ann)
+	if err != nil {
+		test.Fatalf("Marshal: %v", err)
+	}
+	switch {
+	case node["@id"] != "https://ex.org/ann":
+		test.Errorf("Marshal @id: %v", node["@id"])
+	case node["@type"] != "https://ex.org/types#Person":
+		test.Errorf("Marshal @type: %v", node["@type"])
+	case node["https://ex.org/vocab#name"] != "Ann":
+		test.Errorf("Marshal name: %v", node["https://ex.org/vocab#name"])
+	case !IsVtypeval(node["https://ex.org/vocab#age"], "http://www.w3.org/2001/XMLSchema#integer", 42):
+		test.Errorf("Marshal age: %v", node["https://ex.org/vocab#age"])
+	}
+	if _, ok = node["https://ex.org/vocab#email"]; ok {
+		test.Errorf("Marshal omitempty email: %v", node["https://ex.org/vocab#email"])
+	}
+	steps, ok = GetList(node, "https://ex.org/vocab#steps")
+	if !ok || len(steps) != 2 {
+		test.Errorf("Marshal steps: %v", node["https://ex.org/vocab#steps"])
+	}
+
+	knows, ok = GetN(node, "https://ex.org/vocab#knows")
+	if !ok || knows["https://ex.org/vocab#name"] != "Bob" {
+		test.Fatalf("Marshal knows: %v", node["https://ex.org/vocab#knows"])
+	}
+	//bob.Knows is a cycle back to ann so it must be a node reference
+	if id, ok := GetNRef(knows["https://ex.org/vocab#knows"]); !ok || id != "https://ex.org/ann" {
+		test.Errorf("Marshal cycle: %v", knows["https://ex.org/vocab#knows"])
+	}
+	if id, ok := GetNRef(node["https://ex.org/vocab#manager"]); !ok || id != knows["@id"] {
+		test.Errorf("Marshal ref: %v", node["https://ex.org/vocab#manager"])
+	}
+
+	_, err = Marshal("not a struct")
+	if err == nil {
+		test.Errorf("Marshal of a string should fail")
+	}
+}
+
+func TestMarshalUintOverflow(test *testing.T) {
+	type counter struct {
+		ID    string `jld:"@id"`
+		Count uint64 `jld:"https://ex.org/vocab#count"`
+	}
+	var c = counter{ID: "https://ex.org/c", Count: math.MaxInt64}
+
+	node, err := Marshal(c)
+	if err != nil || valueOf(node["https://ex.org/vocab#count"]) != math.MaxInt64 {
+		test.Errorf("Marshal of MaxInt64: %v %v", node, err)
+	}
+	c.Count = math.MaxUint64
+	if node, err = Marshal(c); err == nil {
+		test.Errorf("Marshal of a uint64 above MaxInt64 should fail: %v", node)
+	}
+}
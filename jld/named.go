@@ -0,0 +1,176 @@
+package jld
+
+import (
+	"context"
+
+	"github.com/kazarena/json-gold/ld"
+)
+
+/*
+splitGraphs splits the top level of an expanded document into its default graph and its named graphs in document
+order. A named graph's node is kept in the default graph, without its @graph, if it has other properties.
+*/
+func splitGraphs(expanded []interface{}) ([]interface{}, []string, map[string][]interface{}) {
+	var (
+		defaultGraph []interface{}
+		names        []string
+		named        = make(map[string][]interface{})
+	)
+
+	for _, item := range expanded {
+		obj, ok := item.(map[string]interface{})
+		if !ok {
+			defaultGraph = append(defaultGraph, item)
+			continue
+		}
+		id, okID := obj["@id"].(string)
+		graph, okGraph := obj["@graph"].([]interface{})
+		if !okID || !okGraph {
+			defaultGraph = append(defaultGraph, item)
+			continue
+		}
+
+		if _, ok := named[id]; !ok {
+			names = append(names, id)
+		}
+		named[id] = append(named[id], graph...)
+		if len(obj) > 2 {
+			node := make(map[string]interface{}, len(obj)-1)
+			for k, v := range obj {
+				if k != "@graph" {
+					node[k] = v
+				}
+			}
+			defaultGraph = append(defaultGraph, node)
+		}
+	}
+	return defaultGraph, names, named
+}
+
+//frameGraph frames the nodes of a graph
+func frameGraph(proc *ld.JsonLdProcessor, nodes []interface{}, frame map[string]interface{}, ldOptions *ld.JsonLdOptions) ([]interface{}, error) {
+	var (
+		framed map[string]interface{}
+		err    error
+	)
+
+	framed, err = proc.Frame(nodes, frame, ldOptions)
+	if err != nil {
+		return nil, err
+	}
+	graph, _ := framed["@graph"].([]interface{})
+	if graph == nil {
+		graph = []interface{}{}
+	}
+	return graph, nil
+}
+
+/*
+canonicalizeGraphs frames the default graph and each named graph of an expanded document separately and returns them
+as a @graph object. The Context is checked before each graph is framed and as its nodes are filtered and relabelled.
+*/
+func canonicalizeGraphs(ctx context.Context, proc *ld.JsonLdProcessor, expanded []interface{}, frame map[string]interface{}, o *options) (interface{}, error) {
+	var (
+		defaultGraph, names, named = splitGraphs(expanded)
+		ldOptions                  = o.ldOptions()
+		graph                      []interface{}
+		nodes                      []interface{}
+		err                        error
+	)
+
+	graph, err = frameGraph(proc, defaultGraph, frame, ldOptions)
+	if err == nil {
+		graph, err = o.matchAllTypes(ctx, graph, frame)
+	}
+	if err == nil {
+		err = o.stabilize(ctx, graph)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, name := range names {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		nodes, err = frameGraph(proc, named[name], frame, ldOptions)
+		if err == nil {
+			nodes, err = o.matchAllTypes(ctx, nodes, frame)
+		}
+		if err == nil {
+			err = o.stabilize(ctx, nodes)
+		}
+		if err != nil {
+			return nil, err
+		}
+		graph = append(graph, map[string]interface{}{"@id": name, "@graph": nodes})
+	}
+	return map[string]interface{}{"@graph": graph}, nil
+}
+
+/*
+GetGraph gets the nodes of a graph of a document, such as the output of Canonicalize with the WrapGraph option. An
+empty graphID gets the default graph: the items of the document's top level @graph (or the document itself if it is
+an array) that are not named graphs. Otherwise it gets the @graph of the top level named graph with the graphID, or
+of the document itself if it is that named graph.
+*/
+func GetGraph(input interface{}, graphID string) ([]interface{}, bool) {
+	var (
+		items []interface{}
+		graph []interface{}
+	)
+
+	switch input.(type) {
+	case []interface{}:
+		items = input.([]interface{})
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		graphI, ok := obj["@graph"]
+		if !ok {
+			return nil, false
+		}
+		if graphID != "" && obj["@id"] == graphID {
+			return asArray(graphI), true
+		}
+		if _, ok := obj["@id"]; ok && graphID == "" {
+			return nil, false
+		}
+		items = asArray(graphI)
+	default:
+		return nil, false
+	}
+
+	if graphID == "" {
+		graph = []interface{}{}
+		for _, item := range items {
+			if !isNamedGraph(item) {
+				graph = append(graph, item)
+			}
+		}
+		return graph, true
+	}
+	for _, item := range items {
+		if isNamedGraph(item) && item.(map[string]interface{})["@id"] == graphID {
+			return asArray(item.(map[string]interface{})["@graph"]), true
+		}
+	}
+	return nil, false
+}
+
+//isNamedGraph is true if an item is a named graph object
+func isNamedGraph(item interface{}) bool {
+	obj, ok := item.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	_, okID := obj["@id"].(string)
+	_, okGraph := obj["@graph"]
+	return okID && okGraph
+}
+
+//asArray returns a value as an array, wrapping it if it is not one
+func asArray(v interface{}) []interface{} {
+	if a, ok := v.([]interface{}); ok {
+		return a
+	}
+	return []interface{}{v}
+}
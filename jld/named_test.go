@@ -0,0 +1,53 @@
+package jld
+
+import (
+	"testing"
+)
+
+func TestGetGraph(test *testing.T) {
+	var (
+		a     = map[string]interface{}{"@id": "https://ex.org/a"}
+		b     = map[string]interface{}{"@id": "https://ex.org/b"}
+		g1    = map[string]interface{}{"@id": "https://ex.org/g1", "@graph": []interface{}{b}}
+		doc   = map[string]interface{}{"@graph": []interface{}{a, g1}}
+		graph []interface{}
+		ok    bool
+	)
+
+	graph, ok = GetGraph(doc, "")
+	if !ok || len(graph) != 1 || graph[0].(map[string]interface{})["@id"] != "https://ex.org/a" {
+		test.Errorf("GetGraph default: %v %v", graph, ok)
+	}
+	graph, ok = GetGraph(doc, "https://ex.org/g1")
+	if !ok || len(graph) != 1 || graph[0].(map[string]interface{})["@id"] != "https://ex.org/b" {
+		test.Errorf("GetGraph named: %v %v", graph, ok)
+	}
+	graph, ok = GetGraph(g1, "https://ex.org/g1")
+	if !ok || len(graph) != 1 {
+		test.Errorf("GetGraph self: %v %v", graph, ok)
+	}
+	if graph, ok = GetGraph(doc, "https://ex.org/g2"); ok {
+		test.Errorf("GetGraph missing: %v", graph)
+	}
+	if graph, ok = GetGraph(a, ""); ok {
+		test.Errorf("GetGraph node: %v", graph)
+	}
+}
+
+func TestSplitGraphs(test *testing.T) {
+	var (
+		expanded = []interface{}{
+			map[string]interface{}{"@id": "https://ex.org/a"},
+			map[string]interface{}{"@id": "https://ex.org/g1", "@graph": []interface{}{map[string]interface{}{"@id": "https://ex.org/b"}}},
+			map[string]interface{}{"@id": "https://ex.org/g2", "@graph": []interface{}{}, "https://ex.org/vocab#p": []interface{}{map[string]interface{}{"@value": "x"}}},
+		}
+		defaultGraph, names, named = splitGraphs(expanded)
+	)
+
+	if len(defaultGraph) != 2 || len(names) != 2 || names[0] != "https://ex.org/g1" || len(named["https://ex.org/g1"]) != 1 {
+		test.Errorf("splitGraphs: %v %v %v", defaultGraph, names, named)
+	}
+	if _, ok := defaultGraph[1].(map[string]interface{})["@graph"]; ok {
+		test.Errorf("splitGraphs kept @graph: %v", defaultGraph[1])
+	}
+}
@@ -0,0 +1,387 @@
+package jld
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+//The RDF terms of the statements written by an NQuadsWriter
+const (
+	rdfBase     = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+	rdfTypeIRI  = rdfBase + "type"
+	rdfFirstIRI = rdfBase + "first"
+	rdfRestIRI  = rdfBase + "rest"
+	rdfNilIRI   = rdfBase + "nil"
+	rdfLangIRI  = rdfBase + "langString"
+)
+
+//nquadsLanguage matches the language tags that N-Quads allows
+var nquadsLanguage = regexp.MustCompile(`^[a-zA-Z]+(-[a-zA-Z0-9]+)*$`)
+
+/*
+An NQuadsWriter serializes nodes to N-Quads incrementally, one statement per Write to its io.Writer, so that an export
+of a large document or of a stream of nodes (e.g. from a Store or a Paginator) does not hold its serialization in
+memory, as ToNQuads does. The io.Writer is typically buffered:
+
+	bw := bufio.NewWriter(f)
+	nw := jld.NewNQuadsWriter(bw)
+	for _, node := range nodes {
+		if err := nw.Write(node); err != nil {
+			...
+		}
+	}
+	err = bw.Flush()
+
+The nodes must have absolute IRIs for their @ids, types and properties, as in an expanded document or the output of
+Canonicalize; a document with a @context must be expanded first. As when JSON LD is converted to RDF, a property that
+is not an absolute IRI is not written, and a node without an @id is a blank node. Blank node identifiers are
+relabelled _:b0, _:b1 and so on, consistently across the Writes of an NQuadsWriter, so the nodes of several Writes may
+reference each other. The statements are not normalized: use Normalize for a canonical serialization.
+
+IRIs and language tags are not escaped: a Write of an IRI with a character that N-Quads does not allow in one, such
+as a space or '>', fails with an ErrBadIRI NodeError, and one of a language tag that is not of the N-Quads form fails
+with an ErrBadValue NodeError, rather than write a statement that would change the meaning of the output.
+*/
+type NQuadsWriter struct {
+	w      io.Writer
+	blanks map[string]string
+	n      int
+	err    error
+}
+
+/*
+NewNQuadsWriter creates an NQuadsWriter that writes to w.
+*/
+func NewNQuadsWriter(w io.Writer) *NQuadsWriter {
+	return &NQuadsWriter{w: w, blanks: make(map[string]string)}
+}
+
+/*
+Write writes the statements of a document: a node, an array of nodes or a @graph object. A node with an @id and a
+@graph is a named graph, whose nodes' statements have its @id as their graph label. A document nested deeper than the
+Graph limit is not written. Once a Write fails, or a Write to the io.Writer fails, Write returns its error; the
+statements of a failed Write before its failure may have been written.
+*/
+func (nw *NQuadsWriter) Write(input interface{}) error {
+	if nw.err != nil {
+		return nw.err
+	}
+	if obj, ok := input.(map[string]interface{}); ok {
+		if _, ok := obj["@context"]; ok {
+			return fmt.Errorf("Bad Document: a document with a @context must be expanded before it is written as N-Quads")
+		}
+	}
+	nw.nodes(input, "", 0)
+	return nw.err
+}
+
+//nodes writes the statements of the top level nodes of a document or graph in a graph
+func (nw *NQuadsWriter) nodes(input interface{}, graph string, depth int) {
+	switch input.(type) {
+	case []interface{}:
+		for _, item := range input.([]interface{}) {
+			nw.nodes(item, graph, depth)
+		}
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		if isGraphObject(obj) {
+			nw.nodes(obj["@graph"], graph, depth)
+			return
+		}
+		if _, ok := obj["@value"]; !ok {
+			nw.node(obj, graph, depth)
+		}
+	}
+}
+
+//node writes the statements of a node and the nodes it embeds in a graph and returns its term
+func (nw *NQuadsWriter) node(node map[string]interface{}, graph string, depth int) string {
+	var (
+		id, _   = node["@id"].(string)
+		subject string
+	)
+
+	if id == "" {
+		subject = nw.blank("")
+	} else {
+		subject = nw.term(id)
+	}
+	if depth > maxGraphDepth {
+		nw.fail(fmt.Errorf("Document nesting exceeds %v", maxGraphDepth))
+		return subject
+	}
+	if IsNref(node) {
+		return subject
+	}
+
+	types, _ := nodeTypes(node)
+	for _, t := range types {
+		nw.statement(subject, "<"+rdfTypeIRI+">", nw.term(t), graph)
+	}
+	for _, prop := range Props(node) {
+		switch {
+		case prop.ID == "@graph":
+			if id != "" {
+				nw.nodes(prop.Value, subject, depth+1)
+			}
+		case prop.ID == "@reverse":
+			reverse, _ := prop.Value.(map[string]interface{})
+			for _, revProp := range Props(reverse) {
+				if !isAbsoluteIRI(string(revProp.ID)) {
+					continue
+				}
+				for _, object := range nw.objects(revProp.Value, graph, depth+1) {
+					nw.statement(object, nw.term(string(revProp.ID)), subject, graph)
+				}
+			}
+		case prop.ID == "@included":
+			nw.nodes(prop.Value, graph, depth+1)
+		case isAbsoluteIRI(string(prop.ID)):
+			for _, object := range nw.objects(prop.Value, graph, depth+1) {
+				nw.statement(subject, nw.term(string(prop.ID)), object, graph)
+			}
+		}
+	}
+	return subject
+}
+
+//objects writes the statements of the nodes and lists of a property value in a graph and returns the terms of its items
+func (nw *NQuadsWriter) objects(value interface{}, graph string, depth int) []string {
+	var terms []string
+
+	switch value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		for _, item := range value.([]interface{}) {
+			terms = append(terms, nw.objects(item, graph, depth)...)
+		}
+		return terms
+	case map[string]interface{}:
+		obj := value.(map[string]interface{})
+		if _, ok := obj["@value"]; ok {
+			if literal, ok := nw.literal(obj); ok {
+				return []string{literal}
+			}
+			return nil
+		}
+		if items, ok := obj["@list"]; ok {
+			return []string{nw.list(asArray(items), graph, depth)}
+		}
+		if items, ok := obj["@set"]; ok {
+			return nw.objects(items, graph, depth)
+		}
+		return []string{nw.node(obj, graph, depth)}
+	default:
+		if literal, ok := nw.literal(map[string]interface{}{"@value": value}); ok {
+			return []string{literal}
+		}
+		return nil
+	}
+}
+
+//list writes the rdf:first and rdf:rest statements of a list in a graph and returns the term of its head
+func (nw *NQuadsWriter) list(items []interface{}, graph string, depth int) string {
+	var (
+		head = "<" + rdfNilIRI + ">"
+		node string
+		next string
+	)
+
+	if len(items) == 0 {
+		return head
+	}
+	head = nw.blank("")
+	node = head
+	for i, item := range items {
+		for _, object := range nw.objects(item, graph, depth+1) {
+			nw.statement(node, "<"+rdfFirstIRI+">", object, graph)
+		}
+		next = "<" + rdfNilIRI + ">"
+		if i < len(items)-1 {
+			next = nw.blank("")
+		}
+		nw.statement(node, "<"+rdfRestIRI+">", next, graph)
+		node = next
+	}
+	return head
+}
+
+//statement writes a statement, unless a Write has failed
+func (nw *NQuadsWriter) statement(subject, predicate, object, graph string) {
+	var line string
+
+	if nw.err != nil {
+		return
+	}
+	if graph == "" {
+		line = subject + " " + predicate + " " + object + " .\n"
+	} else {
+		line = subject + " " + predicate + " " + object + " " + graph + " .\n"
+	}
+	_, nw.err = io.WriteString(nw.w, line)
+}
+
+//fail records the first failure of a Write
+func (nw *NQuadsWriter) fail(err error) {
+	if nw.err == nil {
+		nw.err = err
+	}
+}
+
+//term returns the term of an IRI or blank node identifier, or fails the Write if the IRI cannot be written
+func (nw *NQuadsWriter) term(id string) string {
+	if strings.HasPrefix(id, "_:") {
+		return nw.blank(id)
+	}
+	if !isNQuadsIRI(id) {
+		nw.fail(nodeError(ErrBadIRI, nil, "", "%q cannot be written as an N-Quads IRI", id))
+	}
+	return "<" + id + ">"
+}
+
+//blank returns the relabelled blank node identifier of a blank node identifier, or a new one if it is ""
+func (nw *NQuadsWriter) blank(id string) string {
+	var (
+		label string
+		ok    bool
+	)
+
+	if label, ok = nw.blanks[id]; ok {
+		return label
+	}
+	label = "_:b" + strconv.Itoa(nw.n)
+	nw.n++
+	if id != "" {
+		nw.blanks[id] = label
+	}
+	return label
+}
+
+//isNQuadsIRI is true if an IRI has none of the characters that N-Quads does not allow in an IRI
+func isNQuadsIRI(iri string) bool {
+	for _, c := range iri {
+		if c <= ' ' || strings.ContainsRune("<>\"{}|^`\\", c) {
+			return false
+		}
+	}
+	return iri != ""
+}
+
+//isAbsoluteIRI is true if a property key is an absolute IRI rather than a keyword, term or blank node identifier
+func isAbsoluteIRI(key string) bool {
+	var i = strings.Index(key, ":")
+
+	return i > 0 && !strings.HasPrefix(key, "@") && !strings.HasPrefix(key, "_:")
+}
+
+/*
+literal returns the N-Quads literal of a value object, by the JSON LD conversion of its @value to RDF, or fails the
+Write if its language tag or datatype cannot be written.
+*/
+func (nw *NQuadsWriter) literal(valobj map[string]interface{}) (string, bool) {
+	var (
+		datatype, _ = valobj["@type"].(string)
+		lang, _     = valobj["@language"].(string)
+		lexical     string
+	)
+
+	switch v := valobj["@value"].(type) {
+	case string:
+		lexical = v
+		if datatype == "" && lang == "" {
+			datatype = xsdBase + "string"
+		}
+	case bool:
+		lexical = strconv.FormatBool(v)
+		if datatype == "" {
+			datatype = xsdBase + "boolean"
+		}
+	case json.Number:
+		if f, err := v.Float64(); err == nil {
+			lexical, datatype = numberLiteral(f, datatype)
+		} else {
+			return "", false
+		}
+	case int:
+		lexical, datatype = numberLiteral(float64(v), datatype)
+	case int64:
+		lexical, datatype = numberLiteral(float64(v), datatype)
+	case float32:
+		lexical, datatype = numberLiteral(float64(v), datatype)
+	case float64:
+		lexical, datatype = numberLiteral(v, datatype)
+	default:
+		return "", false
+	}
+
+	lexical = `"` + nquadsEscape(lexical) + `"`
+	switch {
+	case lang != "":
+		if !nquadsLanguage.MatchString(lang) {
+			nw.fail(nodeError(ErrBadValue, nil, "", "%q cannot be written as an N-Quads language tag", lang))
+		}
+		return lexical + "@" + lang, true
+	case datatype == xsdBase+"string" || datatype == rdfLangIRI:
+		return lexical, true
+	default:
+		return lexical + "^^" + nw.term(datatype), true
+	}
+}
+
+/*
+numberLiteral returns the lexical form and datatype of a number: an integer is in integer form and by default an
+xsd:integer, and any other number, or one of type xsd:double, is in the canonical xsd:double form and by default an
+xsd:double. NaN and the infinities, which JSON cannot hold but a Go value can, are NaN, INF and -INF.
+*/
+func numberLiteral(f float64, datatype string) (string, string) {
+	var (
+		mantissa string
+		exponent string
+		lexical  string
+	)
+
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		if datatype == "" {
+			datatype = xsdBase + "double"
+		}
+		switch {
+		case math.IsNaN(f):
+			return "NaN", datatype
+		case f > 0:
+			return "INF", datatype
+		default:
+			return "-INF", datatype
+		}
+	}
+	if f == math.Trunc(f) && math.Abs(f) < 1e21 && datatype != xsdBase+"double" {
+		if datatype == "" {
+			datatype = xsdBase + "integer"
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), datatype
+	}
+	if datatype == "" {
+		datatype = xsdBase + "double"
+	}
+
+	//The canonical xsd:double form, e.g. 1.5E1
+	lexical = strconv.FormatFloat(f, 'E', -1, 64)
+	mantissa, exponent = lexical[:strings.Index(lexical, "E")], lexical[strings.Index(lexical, "E")+1:]
+	if !strings.Contains(mantissa, ".") {
+		mantissa += ".0"
+	}
+	e, _ := strconv.Atoi(exponent)
+	return mantissa + "E" + strconv.Itoa(e), datatype
+}
+
+//nquadsEscape escapes the characters of a literal that N-Quads does not allow in a string
+func nquadsEscape(s string) string {
+	var r = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+	return r.Replace(s)
+}
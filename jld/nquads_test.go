@@ -0,0 +1,151 @@
+package jld
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestNQuadsWriter(test *testing.T) {
+	var (
+		b  strings.Builder
+		nw = NewNQuadsWriter(&b)
+	)
+
+	doc := []interface{}{
+		map[string]interface{}{
+			"@id":                      "https://ex.org/ann",
+			"@type":                    "https://ex.org/types#Person",
+			"https://ex.org/vocab#age": 42,
+			"https://ex.org/vocab#name": []interface{}{
+				map[string]interface{}{"@value": "Ann \"A\"", "@language": "en"},
+			},
+			"https://ex.org/vocab#height": 1.5,
+			"https://ex.org/vocab#knows":  map[string]interface{}{"@id": "_:x"},
+			"https://ex.org/vocab#tags":   map[string]interface{}{"@list": []interface{}{"a", true}},
+			"name":                        "dropped",
+		},
+		map[string]interface{}{
+			"@id":    "https://ex.org/g",
+			"@graph": []interface{}{map[string]interface{}{"@id": "_:x", "https://ex.org/vocab#note": "in g"}},
+		},
+	}
+	if err := nw.Write(doc); err != nil {
+		test.Fatalf("Write: %v", err)
+	}
+	if err := nw.Write(map[string]interface{}{"@id": "_:x", "https://ex.org/vocab#seen": map[string]interface{}{"@value": "2020", "@type": "https://ex.org/types#year"}}); err != nil {
+		test.Fatalf("Write: %v", err)
+	}
+
+	expected := `<https://ex.org/ann> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <https://ex.org/types#Person> .
+<https://ex.org/ann> <https://ex.org/vocab#age> "42"^^<http://www.w3.org/2001/XMLSchema#integer> .
+<https://ex.org/ann> <https://ex.org/vocab#height> "1.5E0"^^<http://www.w3.org/2001/XMLSchema#double> .
+<https://ex.org/ann> <https://ex.org/vocab#knows> _:b0 .
+<https://ex.org/ann> <https://ex.org/vocab#name> "Ann \"A\""@en .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> "a" .
+_:b1 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> _:b2 .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#first> "true"^^<http://www.w3.org/2001/XMLSchema#boolean> .
+_:b2 <http://www.w3.org/1999/02/22-rdf-syntax-ns#rest> <http://www.w3.org/1999/02/22-rdf-syntax-ns#nil> .
+<https://ex.org/ann> <https://ex.org/vocab#tags> _:b1 .
+_:b0 <https://ex.org/vocab#note> "in g" <https://ex.org/g> .
+_:b0 <https://ex.org/vocab#seen> "2020"^^<https://ex.org/types#year> .
+`
+	if b.String() != expected {
+		test.Errorf("Write:\n%v", b.String())
+	}
+
+	err := nw.Write(map[string]interface{}{"@context": map[string]interface{}{}, "@id": "https://ex.org/ann"})
+	if err == nil {
+		test.Errorf("Write of a document with a @context should fail")
+	}
+
+	nw = NewNQuadsWriter(failingWriter{})
+	if err = nw.Write(NewN("https://ex.org/ann", NewTypeID("https://ex.org/types#Person", ""))); !errors.Is(err, errWriteFailed) {
+		test.Errorf("Write to a failing writer: %v", err)
+	}
+	if err = nw.Write(NewN("https://ex.org/bob")); !errors.Is(err, errWriteFailed) {
+		test.Errorf("Write after a failed Write: %v", err)
+	}
+}
+
+func TestNQuadsWriterRejects(test *testing.T) {
+	var (
+		nameP    = "https://ex.org/vocab#name"
+		injected = "https://ex.org/a> <https://ex.org/vocab#admin> \"true\" .\n<https://ex.org/b"
+		deep     = map[string]interface{}{"@id": "https://ex.org/deep"}
+		b        strings.Builder
+		err      error
+	)
+
+	for i := 0; i <= maxGraphDepth+1; i++ {
+		deep = map[string]interface{}{"https://ex.org/vocab#child": deep}
+	}
+	cases := []struct {
+		input interface{}
+		kind  error
+	}{
+		{map[string]interface{}{"@id": injected, nameP: "Ann"}, ErrBadIRI},
+		{map[string]interface{}{"@id": "https://ex.org/a", "@type": "https://ex.org/types#A B"}, ErrBadIRI},
+		{map[string]interface{}{"@id": "https://ex.org/a", nameP: map[string]interface{}{"@id": "https://ex.org/{b}"}}, ErrBadIRI},
+		{map[string]interface{}{"@id": "https://ex.org/a", nameP: map[string]interface{}{"@value": "x", "@type": "https://ex.org/t>"}}, ErrBadIRI},
+		{map[string]interface{}{"@id": "https://ex.org/a", nameP: map[string]interface{}{"@value": "x", "@language": "en .\n<https://ex.org/b> <https://ex.org/c> \"d\""}}, ErrBadValue},
+		{map[string]interface{}{"@id": "https://ex.org/a", nameP: map[string]interface{}{"@value": "x", "@language": "en-"}}, ErrBadValue},
+		{deep, nil},
+	}
+
+	for i, c := range cases {
+		b.Reset()
+		err = NewNQuadsWriter(&b).Write(c.input)
+		switch {
+		case err == nil:
+			test.Errorf("Write %v should fail: %v", i, b.String())
+		case c.kind != nil && !errors.Is(err, c.kind):
+			test.Errorf("Write %v: %v", i, err)
+		}
+	}
+
+	err = NewNQuadsWriter(&b).Write(map[string]interface{}{"@id": "https://ex.org/a", nameP: map[string]interface{}{"@value": "x", "@language": "en-GB-x-a1"}})
+	if err != nil {
+		test.Errorf("Write of a language tag with subtags: %v", err)
+	}
+	b.Reset()
+	err = NewNQuadsWriter(&b).Write(map[string]interface{}{"@id": "https://ex.org/a", nameP: math.Inf(1)})
+	if err != nil || b.String() != "<https://ex.org/a> <https://ex.org/vocab#name> \"INF\"^^<http://www.w3.org/2001/XMLSchema#double> .\n" {
+		test.Errorf("Write of an infinity: %v %v", b.String(), err)
+	}
+}
+
+func TestNumberLiteral(test *testing.T) {
+	var cases = []struct {
+		f        float64
+		datatype string
+		lexical  string
+		typ      string
+	}{
+		{42, "", "42", xsdBase + "integer"},
+		{-7, xsdBase + "long", "-7", xsdBase + "long"},
+		{42, xsdBase + "double", "4.2E1", xsdBase + "double"},
+		{0.001, "", "1.0E-3", xsdBase + "double"},
+		{1e21, "", "1.0E21", xsdBase + "double"},
+		{math.NaN(), "", "NaN", xsdBase + "double"},
+		{math.Inf(1), "", "INF", xsdBase + "double"},
+		{math.Inf(-1), xsdBase + "float", "-INF", xsdBase + "float"},
+	}
+
+	for _, c := range cases {
+		if lexical, typ := numberLiteral(c.f, c.datatype); lexical != c.lexical || typ != c.typ {
+			test.Errorf("numberLiteral %v %v: %v %v", c.f, c.datatype, lexical, typ)
+		}
+	}
+}
+
+//errWriteFailed is the error of a failingWriter
+var errWriteFailed = errors.New("write failed")
+
+//failingWriter is an io.Writer whose Writes fail
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errWriteFailed
+}
@@ -0,0 +1,193 @@
+package jld
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/kazarena/json-gold/ld"
+)
+
+type (
+	//An Option configures the JSON LD processing done by Expand, Compact and Canonicalize, or a Graph created by NewGraph.
+	Option func(*options)
+
+	//options holds the configuration set by a list of Options
+	options struct {
+		loader    DocumentLoader
+		strict    bool
+		base      string
+		wrapGraph bool
+		stableIDs bool
+		resolve   bool
+		compiled  *CompiledContext
+		defaults  map[string]interface{}
+		allTypes  bool
+	}
+)
+
+/*
+WithLoader configures the DocumentLoader used to resolve remote @context URLs. Without it, only the contexts registered
+with RegisterContext are resolved and loading any other @context URL fails.
+*/
+func WithLoader(loader DocumentLoader) Option {
+	return func(o *options) {
+		o.loader = loader
+	}
+}
+
+/*
+WithBase configures the base IRI of the input document, against which relative @id values are resolved. Without a
+base, relative IRIs are left relative by expansion.
+*/
+func WithBase(base string) Option {
+	return func(o *options) {
+		o.base = base
+	}
+}
+
+/*
+Strict rejects input documents with relative IRIs that are not resolved by WithBase, unknown @ keywords or non-IRI @type values (see CheckStrict)
+rather than silently dropping or passing them through.
+*/
+func Strict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+/*
+WrapGraph makes Canonicalize return its nodes wrapped in a {"@graph": [...]} object, the form of node-jsonld's frame
+output, even if there are none or one. Named graphs in the input are framed separately and returned as
+{"@id": name, "@graph": [...]} objects following the default graph's nodes; without WrapGraph their nodes are merged
+into the default graph. Use GetGraph to get a graph's nodes.
+*/
+func WrapGraph() Option {
+	return func(o *options) {
+		o.wrapGraph = true
+	}
+}
+
+/*
+StableBlankIDs makes Canonicalize rename the blank node identifiers of its output with HashBlankIDs and order its
+nodes by @id, so that repeated canonicalizations of the same logical graph produce byte-identical output.
+*/
+func StableBlankIDs() Option {
+	return func(o *options) {
+		o.stableIDs = true
+	}
+}
+
+/*
+ResolveRefs makes NewGraph (and the Graph's Add) replace the node references in the property values of the document's
+nodes with the nodes they refer to, if they are in the Graph. A reference that would make the document cyclic, such as
+one back to a node that embeds the referring node, is left as a reference so that the document can still be marshalled.
+*/
+func ResolveRefs() Option {
+	return func(o *options) {
+		o.resolve = true
+	}
+}
+
+/*
+WithCompiledContext makes Expand, Canonicalize and Frame expand documents against a CompiledContext, as if it were
+the context of the document, rather than an empty context. A document's own @context is applied on top of it.
+*/
+func WithCompiledContext(cc *CompiledContext) Option {
+	return func(o *options) {
+		o.compiled = cc
+	}
+}
+
+/*
+WithDefault makes Canonicalize (and TypeFrame) frame with a default value of a property: a framed node that does not
+have the property has the value, rather than the property being missing. The value may be a JSON primitive, a value
+object or a node reference; nil is the JSON LD null, for which the framed property is null.
+*/
+func WithDefault(propID PropID, value interface{}) Option {
+	return func(o *options) {
+		if o.defaults == nil {
+			o.defaults = make(map[string]interface{})
+		}
+		if value == nil {
+			value = "@null"
+		}
+		o.defaults[propID.URI()] = value
+	}
+}
+
+/*
+RequireAllTypes makes Canonicalize only match the nodes that have all the types of its type filter, rather than any
+of them. JSON LD framing matches a node with any of a frame's types even with @requireAll, which TypeFrame also sets,
+so the nodes are filtered after framing.
+*/
+func RequireAllTypes() Option {
+	return func(o *options) {
+		o.allTypes = true
+	}
+}
+
+//newOptions applies a list of Options to the default options
+func newOptions(opts []Option) *options {
+	var o options
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &o
+}
+
+//checkInput checks the keywords of an input document if the options are strict
+func (o *options) checkInput(input interface{}) error {
+	if !o.strict {
+		return nil
+	}
+	return checkStrict(input, "", "", true, false)
+}
+
+//checkExpanded checks the IRIs of an expanded document if the options are strict, and the Context before each node
+func (o *options) checkExpanded(ctx context.Context, expanded []interface{}) error {
+	var err error
+
+	if !o.strict {
+		return nil
+	}
+	for i, item := range expanded {
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		err = checkStrict(item, Pointer(strconv.Itoa(i)), "", false, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//expand expands a document that may use JSON LD 1.1 constructs against the compiled context, if any
+func (o *options) expand(ctx context.Context, proc *ld.JsonLdProcessor, input interface{}) ([]interface{}, error) {
+	if o.compiled != nil {
+		return o.compiled.expand(ctx, input)
+	}
+	return expand11(ctx, proc, input, o.ldOptions())
+}
+
+//ldOptions converts the options to ld processor options
+func (o *options) ldOptions() *ld.JsonLdOptions {
+	var ldOptions = newLdOptions(o.base)
+
+	if o.loader != nil {
+		ldOptions.DocumentLoader = staticLoader{next: o.loader}
+	}
+	return ldOptions
+}
+
+/*
+newLdOptions returns ld processor options that load registered contexts from the static context registry and other
+documents with defaultLoader. The ld package's own default loader fetches any URL without limits, so it is never used.
+*/
+func newLdOptions(base string) *ld.JsonLdOptions {
+	var ldOptions = ld.NewJsonLdOptions(base)
+
+	ldOptions.DocumentLoader = staticLoader{next: defaultLoader}
+	return ldOptions
+}
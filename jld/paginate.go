@@ -0,0 +1,154 @@
+package jld
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+)
+
+//hydra is the base of the Hydra Core vocabulary
+const hydra = "http://www.w3.org/ns/hydra/core#"
+
+//The Hydra types and properties of the pages of a Paginator
+const (
+	hydraCollection  = hydra + "Collection"
+	hydraView        = hydra + "PartialCollectionView"
+	hydraMember      = hydra + "member"
+	hydraTotalItems  = hydra + "totalItems"
+	hydraViewP       = hydra + "view"
+	hydraFirst       = hydra + "first"
+	hydraPrevious    = hydra + "previous"
+	hydraNext        = hydra + "next"
+	hydraLast        = hydra + "last"
+	pageQueryParam   = "page"
+	defaultPageLimit = 100
+)
+
+/*
+A Paginator splits the top level nodes of a large document, such as the thousands of nodes that Canonicalize may
+match, into pages that an API returns one at a time. The nodes are ordered by @id, so a page has the same nodes
+however the document was produced; a document whose blank node identifiers must be stable should be canonicalized
+with StableBlankIDs. Nodes without an @id follow the others in document order.
+
+A page is a Hydra Collection (https://www.w3.org/ns/hydra/core) whose members are the nodes of the page, and whose
+view links to the first, previous, next and last pages:
+
+	{
+	  "@id": "https://ex.org/people",
+	  "@type": "http://www.w3.org/ns/hydra/core#Collection",
+	  "http://www.w3.org/ns/hydra/core#totalItems": 250,
+	  "http://www.w3.org/ns/hydra/core#member": [...],
+	  "http://www.w3.org/ns/hydra/core#view": {
+	    "@id": "https://ex.org/people?page=2",
+	    "@type": "http://www.w3.org/ns/hydra/core#PartialCollectionView",
+	    "http://www.w3.org/ns/hydra/core#first": {"@id": "https://ex.org/people?page=1"},
+	    "http://www.w3.org/ns/hydra/core#previous": {"@id": "https://ex.org/people?page=1"},
+	    "http://www.w3.org/ns/hydra/core#next": {"@id": "https://ex.org/people?page=3"},
+	    "http://www.w3.org/ns/hydra/core#last": {"@id": "https://ex.org/people?page=3"}
+	  }
+	}
+
+Pages are numbered from 1 and their @ids are the collection's @id with a page query parameter. A Paginator is not
+changed once it is created, so it may be shared, but the pages share their member nodes with the document.
+*/
+type Paginator struct {
+	collection *url.URL
+	nodes      []map[string]interface{}
+	limit      int
+}
+
+/*
+NewPaginator creates a Paginator of the top level nodes of a document (nil, a node, an array of nodes or a @graph
+object) into pages of at most limit nodes; a limit of 0 or less is 100. The collection is the absolute IRI of the
+collection, e.g. the URL of the API that returns its pages.
+*/
+func NewPaginator(input interface{}, collection string, limit int) (*Paginator, error) {
+	var (
+		p   = Paginator{limit: limit}
+		err error
+	)
+
+	p.collection, err = url.Parse(collection)
+	if err != nil || !p.collection.IsAbs() {
+		return nil, fmt.Errorf("Bad Collection IRI: %v", collection)
+	}
+	if p.limit <= 0 {
+		p.limit = defaultPageLimit
+	}
+	p.nodes, err = topNodes(input)
+	if err != nil {
+		return nil, err
+	}
+	p.nodes = append([]map[string]interface{}{}, p.nodes...)
+	sort.SliceStable(p.nodes, func(i, j int) bool {
+		idI, idJ := nodeID(p.nodes[i]), nodeID(p.nodes[j])
+		if idI == "" || idJ == "" {
+			return idJ == "" && idI != ""
+		}
+		return idI < idJ
+	})
+	return &p, nil
+}
+
+/*
+Pages returns the number of pages. A Paginator of no nodes has one empty page.
+*/
+func (p *Paginator) Pages() int {
+	if len(p.nodes) == 0 {
+		return 1
+	}
+	return (len(p.nodes) + p.limit - 1) / p.limit
+}
+
+/*
+PageID returns the @id of page n.
+*/
+func (p *Paginator) PageID(n int) string {
+	var (
+		u     = *p.collection
+		query = u.Query()
+	)
+
+	query.Set(pageQueryParam, strconv.Itoa(n))
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+/*
+Page returns page n as a Hydra Collection. It is an error if there is no page n.
+*/
+func (p *Paginator) Page(n int) (map[string]interface{}, error) {
+	var (
+		pages   = p.Pages()
+		members = make([]interface{}, 0, p.limit)
+		view    map[string]interface{}
+	)
+
+	if n < 1 || n > pages {
+		return nil, fmt.Errorf("Bad Page: %v is not in 1 to %v", n, pages)
+	}
+	for _, node := range p.nodes[min((n-1)*p.limit, len(p.nodes)):min(n*p.limit, len(p.nodes))] {
+		members = append(members, node)
+	}
+
+	view = map[string]interface{}{
+		"@id":      p.PageID(n),
+		"@type":    hydraView,
+		hydraFirst: map[string]interface{}{"@id": p.PageID(1)},
+		hydraLast:  map[string]interface{}{"@id": p.PageID(pages)},
+	}
+	if n > 1 {
+		view[hydraPrevious] = map[string]interface{}{"@id": p.PageID(n - 1)}
+	}
+	if n < pages {
+		view[hydraNext] = map[string]interface{}{"@id": p.PageID(n + 1)}
+	}
+	return map[string]interface{}{
+		"@id":           p.collection.String(),
+		"@type":         hydraCollection,
+		hydraTotalItems: len(p.nodes),
+		hydraMember:     members,
+		hydraViewP:      view,
+	}, nil
+}
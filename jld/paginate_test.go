@@ -0,0 +1,65 @@
+package jld
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestPaginator(test *testing.T) {
+	var (
+		nodes []interface{}
+		p     *Paginator
+		page  map[string]interface{}
+		err   error
+	)
+
+	for _, i := range []int{4, 2, 0, 3, 1} {
+		nodes = append(nodes, map[string]interface{}{"@id": fmt.Sprintf("https://ex.org/n%d", i)})
+	}
+	nodes = append([]interface{}{map[string]interface{}{"https://ex.org/vocab#name": "blank"}}, nodes...)
+
+	p, err = NewPaginator(map[string]interface{}{"@graph": nodes}, "https://ex.org/people?sort=id", 2)
+	if err != nil || p.Pages() != 3 {
+		test.Fatalf("NewPaginator: %v %v", p, err)
+	}
+
+	page, err = p.Page(1)
+	members := page[hydraMember].([]interface{})
+	view := page[hydraViewP].(map[string]interface{})
+	switch {
+	case err != nil:
+		test.Errorf("Page 1: %v", err)
+	case page["@id"] != "https://ex.org/people?sort=id" || page[hydraTotalItems] != 6:
+		test.Errorf("Page 1 collection: %v", page)
+	case len(members) != 2 || nodeID(members[0]) != "https://ex.org/n0" || nodeID(members[1]) != "https://ex.org/n1":
+		test.Errorf("Page 1 members: %v", members)
+	case view["@id"] != "https://ex.org/people?page=1&sort=id" || view[hydraPrevious] != nil:
+		test.Errorf("Page 1 view: %v", view)
+	case nodeID(view[hydraNext]) != "https://ex.org/people?page=2&sort=id" || nodeID(view[hydraLast]) != "https://ex.org/people?page=3&sort=id":
+		test.Errorf("Page 1 links: %v", view)
+	}
+
+	page, err = p.Page(3)
+	members = page[hydraMember].([]interface{})
+	view = page[hydraViewP].(map[string]interface{})
+	switch {
+	case err != nil:
+		test.Errorf("Page 3: %v", err)
+	case len(members) != 2 || nodeID(members[0]) != "https://ex.org/n4" || nodeID(members[1]) != "":
+		test.Errorf("Page 3 should end with the node without an @id: %v", members)
+	case view[hydraNext] != nil || nodeID(view[hydraPrevious]) != "https://ex.org/people?page=2&sort=id":
+		test.Errorf("Page 3 links: %v", view)
+	}
+
+	if _, err = p.Page(4); err == nil {
+		test.Errorf("Page 4 should fail")
+	}
+	if p, err = NewPaginator(nil, "https://ex.org/people", 0); err != nil || p.Pages() != 1 {
+		test.Errorf("NewPaginator of no nodes: %v %v", p, err)
+	} else if page, err = p.Page(1); err != nil || len(page[hydraMember].([]interface{})) != 0 {
+		test.Errorf("Empty page: %v %v", page, err)
+	}
+	if _, err = NewPaginator(nil, "people", 10); err == nil {
+		test.Errorf("NewPaginator of a relative collection should fail")
+	}
+}
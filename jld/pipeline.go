@@ -0,0 +1,165 @@
+package jld
+
+import (
+	"fmt"
+	"strconv"
+)
+
+/*
+A Stage is a step of a Pipeline. It is passed the nodes output by the previous Stage and returns the nodes passed to
+the next one.
+*/
+type Stage func(nodes []map[string]interface{}) ([]map[string]interface{}, error)
+
+/*
+A Pipeline composes Stages, such as Select, Filter and MapProps, into a transformation of the nodes of a canonicalized
+document, so that the same selection and reshaping loops are not rewritten by every consumer. For example:
+
+	p := NewPipeline(Select(personT), Filter(func(n map[string]interface{}) bool { return !IsNtype(n, adminT) }))
+	p = p.Then(MapProps(map[PropID]PropID{legacyNameP: nameP}))
+	people, err := p.Run(canonical)
+*/
+type Pipeline []Stage
+
+/*
+NewPipeline creates a Pipeline that runs the stages in order.
+*/
+func NewPipeline(stages ...Stage) Pipeline {
+	return Pipeline(append([]Stage{}, stages...))
+}
+
+/*
+Then returns a Pipeline that runs the stages after those of p. p is not changed.
+*/
+func (p Pipeline) Then(stages ...Stage) Pipeline {
+	return Pipeline(append(append([]Stage{}, p...), stages...))
+}
+
+/*
+Run runs the Pipeline over the top level nodes of a document, which may be the output of Canonicalize: nil, a node,
+an array of nodes or a @graph object. Since Stages may change the nodes, they are passed a DeepCopy of the document's
+nodes, so the document is not changed. It returns the nodes output by the last Stage.
+*/
+func (p Pipeline) Run(input interface{}) ([]map[string]interface{}, error) {
+	var (
+		nodes []map[string]interface{}
+		err   error
+	)
+
+	nodes, err = topNodes(DeepCopy(input))
+	if err != nil {
+		return nil, err
+	}
+	for _, stage := range p {
+		nodes, err = stage(nodes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return nodes, nil
+}
+
+//topNodes returns the top level nodes of a document
+func topNodes(input interface{}) ([]map[string]interface{}, error) {
+	var nodes []map[string]interface{}
+
+	switch input.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		if !isGraphObject(obj) {
+			return []map[string]interface{}{obj}, nil
+		}
+		return topNodes(obj["@graph"])
+	case []interface{}:
+		for i, item := range input.([]interface{}) {
+			node, ok := item.(map[string]interface{})
+			if !ok {
+				return nil, nodeError(ErrNotANode, nil, Pointer(strconv.Itoa(i)), "%v of type %T is not a node object", item, item)
+			}
+			nodes = append(nodes, node)
+		}
+		return nodes, nil
+	default:
+		return nil, nodeError(ErrNotANode, nil, "", "%T is not a node, array of nodes or @graph object", input)
+	}
+}
+
+/*
+Select is a Stage that passes the nodes of any of the types.
+*/
+func Select(types ...TypeID) Stage {
+	return func(nodes []map[string]interface{}) ([]map[string]interface{}, error) {
+		var selected []map[string]interface{}
+
+		for _, node := range nodes {
+			for _, t := range types {
+				if hasType(node, t) {
+					selected = append(selected, node)
+					break
+				}
+			}
+		}
+		return selected, nil
+	}
+}
+
+/*
+Filter is a Stage that passes the nodes for which keep is true.
+*/
+func Filter(keep func(map[string]interface{}) bool) Stage {
+	return func(nodes []map[string]interface{}) ([]map[string]interface{}, error) {
+		var kept []map[string]interface{}
+
+		for _, node := range nodes {
+			if keep(node) {
+				kept = append(kept, node)
+			}
+		}
+		return kept, nil
+	}
+}
+
+/*
+MapProps is a Stage that renames the properties of each node from the keys of renames to their values. A node that
+has both a renamed property and the property it is renamed to is an error, since one of their values would be lost.
+*/
+func MapProps(renames map[PropID]PropID) Stage {
+	return func(nodes []map[string]interface{}) ([]map[string]interface{}, error) {
+		for _, node := range nodes {
+			for from, to := range renames {
+				v, ok := node[from.URI()]
+				if !ok || from.URI() == to.URI() {
+					continue
+				}
+				if _, exists := node[to.URI()]; exists {
+					return nil, fmt.Errorf("Property Rename Conflict: %v already has %v", nodeID(node), to.URI())
+				}
+				delete(node, from.URI())
+				node[to.URI()] = v
+			}
+		}
+		return nodes, nil
+	}
+}
+
+/*
+Transform is a Stage that replaces each node with the result of f. If f returns nil, the node is dropped.
+*/
+func Transform(f func(map[string]interface{}) (map[string]interface{}, error)) Stage {
+	return func(nodes []map[string]interface{}) ([]map[string]interface{}, error) {
+		var transformed []map[string]interface{}
+
+		for _, node := range nodes {
+			out, err := f(node)
+			if err != nil {
+				return nil, err
+			}
+			if out != nil {
+				transformed = append(transformed, out)
+			}
+		}
+		return transformed, nil
+	}
+}
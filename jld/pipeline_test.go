@@ -0,0 +1,77 @@
+package jld
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestPipeline(test *testing.T) {
+	var (
+		personT = NewTypeID("https://ex.org/vocab#Person", "")
+		adminT  = NewTypeID("https://ex.org/vocab#Admin", "")
+		oldP    = NewPropID("https://ex.org/vocab#fullName", "")
+		nameP   = NewPropID("https://ex.org/vocab#name", "")
+		doc     = map[string]interface{}{
+			"@graph": []interface{}{
+				map[string]interface{}{"@id": "https://ex.org/a", "@type": personT.URI(), oldP.URI(): "A"},
+				map[string]interface{}{"@id": "https://ex.org/b", "@type": []interface{}{personT.URI(), adminT.URI()}, oldP.URI(): "B"},
+				map[string]interface{}{"@id": "https://ex.org/c", "@type": "https://ex.org/vocab#Thing", oldP.URI(): "C"},
+			},
+		}
+		ids = func(nodes []map[string]interface{}) string {
+			var s []string
+			for _, node := range nodes {
+				s = append(s, fmt.Sprint(node["@id"]))
+			}
+			return strings.Join(s, " ")
+		}
+		nodes []map[string]interface{}
+		err   error
+	)
+
+	p := NewPipeline(Select(personT, adminT))
+	nodes, err = p.Run(doc)
+	if err != nil || ids(nodes) != "https://ex.org/a https://ex.org/b" {
+		test.Errorf("Pipeline Select: %v %v", ids(nodes), err)
+	}
+
+	q := p.Then(Filter(func(node map[string]interface{}) bool { return !hasType(node, adminT) }), MapProps(map[PropID]PropID{oldP: nameP}))
+	nodes, err = q.Run(doc)
+	switch {
+	case err != nil || ids(nodes) != "https://ex.org/a":
+		test.Errorf("Pipeline Filter: %v %v", ids(nodes), err)
+	case nodes[0][nameP.URI()] != "A" || nodes[0][oldP.URI()] != nil:
+		test.Errorf("Pipeline MapProps: %v", nodes[0])
+	}
+	if len(p) != 1 {
+		test.Errorf("Pipeline Then changed its Pipeline: %v", len(p))
+	}
+	if _, ok := doc["@graph"].([]interface{})[0].(map[string]interface{})[oldP.URI()]; !ok {
+		test.Errorf("Pipeline changed its document: %v", doc)
+	}
+
+	nodes, err = NewPipeline(Transform(func(node map[string]interface{}) (map[string]interface{}, error) {
+		if node["@id"] == "https://ex.org/c" {
+			return nil, nil
+		}
+		return map[string]interface{}{"@id": node["@id"]}, nil
+	})).Run(doc["@graph"])
+	if err != nil || ids(nodes) != "https://ex.org/a https://ex.org/b" || len(nodes[0]) != 1 {
+		test.Errorf("Pipeline Transform: %v %v", nodes, err)
+	}
+
+	_, err = NewPipeline(MapProps(map[PropID]PropID{oldP: nameP})).Run(map[string]interface{}{"@id": "_:x", oldP.URI(): "X", nameP.URI(): "Y"})
+	if err == nil {
+		test.Errorf("Pipeline MapProps did not fail on a conflict")
+	}
+
+	nodes, err = NewPipeline().Run(nil)
+	if err != nil || nodes != nil {
+		test.Errorf("Pipeline nil: %v %v", nodes, err)
+	}
+	_, err = NewPipeline().Run("x")
+	if err == nil {
+		test.Errorf("Pipeline did not fail on a bad document")
+	}
+}
@@ -0,0 +1,213 @@
+package jld
+
+import (
+	"strings"
+	"time"
+)
+
+/*
+ToPlain and FromPlain convert between nodes and plain Go maps without JSON LD keywords, for templates and UI layers
+that should not see them. In a plain map:
+
+	id	- is the node's @id
+	type	- is the short name of the node's @type, or an array of them if it has several
+	others	- are the short names of properties in a Vocabulary, and their values are plain
+
+A plain value is the @value of a value object, the @id of a node reference, the plain map of a node or an array of
+plain values for a set or list. Other keywords, such as @index and @reverse, are dropped by ToPlain. Since the value
+types, languages, references and lists are dropped, FromPlain is not an exact inverse of ToPlain.
+*/
+
+/*
+ToPlain converts a node to a plain map. A property or type name is its registered short name in the Vocabulary (the
+least, if it has several), or its name relative to the Vocabulary's base, or else its full IRI.
+*/
+func ToPlain(node map[string]interface{}, vocab *Vocabulary) map[string]interface{} {
+	var c = plainConverter{vocab: vocab, props: make(map[string]string), types: make(map[string]string)}
+
+	for name, propID := range vocab.Props() {
+		addName(c.props, name, propID.URI())
+	}
+	for name, typeID := range vocab.Types() {
+		addName(c.types, name, typeID.URI())
+	}
+	return c.node(node, 0)
+}
+
+//plainConverter holds the reverse name lookups of a ToPlain conversion
+type plainConverter struct {
+	vocab *Vocabulary
+	props map[string]string
+	types map[string]string
+}
+
+//addName adds a short name of a URI to a reverse lookup, keeping the least name of each URI
+func addName(reverse map[string]string, name, uri string) {
+	if prev, ok := reverse[uri]; !ok || name < prev {
+		reverse[uri] = name
+	}
+}
+
+//shortName returns the short name of a URI, its name relative to a base, or the URI itself
+func shortName(uri string, reverse map[string]string, base string) string {
+	if name, ok := reverse[uri]; ok {
+		return name
+	}
+	if base != "" && strings.HasPrefix(uri, base) && len(uri) > len(base) {
+		return uri[len(base):]
+	}
+	return uri
+}
+
+//node converts a node at a depth to a plain map
+func (c *plainConverter) node(node map[string]interface{}, depth int) map[string]interface{} {
+	var plain = make(map[string]interface{}, len(node))
+
+	for k, v := range node {
+		switch {
+		case k == "@id":
+			plain["id"] = v
+		case k == "@type":
+			plain["type"] = c.typeNames(v)
+		case strings.HasPrefix(k, "@"):
+			continue
+		default:
+			plain[shortName(k, c.props, c.vocab.pb.Str())] = c.value(v, depth+1)
+		}
+	}
+	return plain
+}
+
+//typeNames returns the short names of an @type value
+func (c *plainConverter) typeNames(tv interface{}) interface{} {
+	var names []interface{}
+
+	switch tv.(type) {
+	case string:
+		return shortName(tv.(string), c.types, c.vocab.tb.Str())
+	case []string:
+		for _, t := range tv.([]string) {
+			names = append(names, shortName(t, c.types, c.vocab.tb.Str()))
+		}
+	case []interface{}:
+		for _, t := range tv.([]interface{}) {
+			names = append(names, c.typeNames(t))
+		}
+	default:
+		return tv
+	}
+	return names
+}
+
+//value converts a property value at a depth to a plain value
+func (c *plainConverter) value(input interface{}, depth int) interface{} {
+	var plain []interface{}
+
+	switch input.(type) {
+	case []interface{}:
+		plain = make([]interface{}, 0, len(input.([]interface{})))
+		for _, item := range input.([]interface{}) {
+			plain = append(plain, c.value(item, depth))
+		}
+		return plain
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		if v, ok := obj["@value"]; ok {
+			return v
+		}
+		for _, k := range []string{"@list", "@set"} {
+			if v, ok := obj[k]; ok {
+				if items, ok := v.([]interface{}); ok {
+					return c.value(items, depth)
+				}
+				return c.value([]interface{}{v}, depth)
+			}
+		}
+
+		//A node nested too deeply, e.g. in a cycle created by ResolveRefs, is cut off at its @id
+		if IsNref(obj) || depth > maxGraphDepth {
+			return obj["@id"]
+		}
+		return c.node(obj, depth)
+	default:
+		return input
+	}
+}
+
+/*
+FromPlain converts a plain map to a node of the type t, or, if t is empty, of the type named by the map's type. The
+map's names are resolved with the Vocabulary's P and T, and nested maps are converted to nodes of the types they name.
+A primitive value or time.Time of a property with a registered datatype (see RegisterDatatype) becomes a value object
+of the datatype, and any other time.Time an xsd:dateTime value object; other primitive values are used as is.
+*/
+func FromPlain(m map[string]interface{}, t TypeID, vocab *Vocabulary) map[string]interface{} {
+	var node = make(map[string]interface{}, len(m))
+
+	for k, v := range m {
+		switch k {
+		case "id":
+			node["@id"] = v
+		case "type":
+			if t == "" {
+				node["@type"] = fromPlainType(v, vocab)
+			}
+		default:
+			propID := vocab.P(k)
+			node[propID.URI()] = fromPlainValue(propID, v, vocab)
+		}
+	}
+	if t != "" {
+		node["@type"] = t.URI()
+	}
+	return node
+}
+
+//fromPlainType returns the @type value of a plain type name or array of names
+func fromPlainType(v interface{}, vocab *Vocabulary) interface{} {
+	var types []interface{}
+
+	switch v.(type) {
+	case string:
+		return vocab.T(v.(string)).URI()
+	case []string:
+		for _, name := range v.([]string) {
+			types = append(types, vocab.T(name).URI())
+		}
+	case []interface{}:
+		for _, name := range v.([]interface{}) {
+			types = append(types, fromPlainType(name, vocab))
+		}
+	default:
+		return v
+	}
+	return types
+}
+
+//fromPlainValue converts the plain value of a property to a JSON LD value
+func fromPlainValue(propID PropID, v interface{}, vocab *Vocabulary) interface{} {
+	var values []interface{}
+
+	switch v.(type) {
+	case map[string]interface{}:
+		return FromPlain(v.(map[string]interface{}), "", vocab)
+	case []interface{}:
+		for _, item := range v.([]interface{}) {
+			values = append(values, fromPlainValue(propID, item, vocab))
+		}
+		return values
+	case []string:
+		for _, item := range v.([]string) {
+			values = append(values, fromPlainValue(propID, item, vocab))
+		}
+		return values
+	}
+	if datatype, ok := Datatype(propID); ok {
+		if valobj, ok := coerceV(datatype, v); ok {
+			return valobj
+		}
+	}
+	if tv, ok := v.(time.Time); ok {
+		return NewTimeV(tv)
+	}
+	return v
+}
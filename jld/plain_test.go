@@ -0,0 +1,67 @@
+package jld
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPlain(test *testing.T) {
+	var (
+		v        = NewVocabulary("https://ex.org/types#", "https://ex.org/vocab#")
+		personT  = v.DefineT("Person")
+		nameP    = v.DefineP("name")
+		ageP     = v.DefineP("age", NewTypeID(xsdBase+"integer", ""))
+		friendsP = v.DefineP("friends")
+		mboxP    = NewPropID("http://xmlns.com/foaf/0.1/mbox", "")
+		born     = time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+		node     = map[string]interface{}{
+			"@id":                       "https://ex.org/a",
+			"@type":                     personT.URI(),
+			"@index":                    "x",
+			nameP.URI():                 map[string]interface{}{"@value": "A", "@language": "en"},
+			ageP.URI():                  NewV(NewTypeID(xsdBase+"integer", ""), 42),
+			mboxP.URI():                 "a@ex.org",
+			"https://ex.org/vocab#born": NewTimeV(born),
+			friendsP.URI(): map[string]interface{}{"@list": []interface{}{
+				map[string]interface{}{"@id": "https://ex.org/b"},
+				map[string]interface{}{"@id": "https://ex.org/c", "@type": []interface{}{personT.URI(), "https://other.org/T"}, nameP.URI(): "C"},
+			}},
+		}
+		plain map[string]interface{}
+	)
+	defer UnregisterDatatype(ageP)
+
+	plain = ToPlain(node, v)
+	friends, _ := plain["friends"].([]interface{})
+	switch {
+	case len(plain) != 7 || plain["id"] != "https://ex.org/a" || plain["type"] != "Person":
+		test.Errorf("ToPlain: %v", plain)
+	case plain["name"] != "A" || plain["age"] != 42 || plain[mboxP.URI()] != "a@ex.org" || plain["born"] == nil:
+		test.Errorf("ToPlain values: %v", plain)
+	case len(friends) != 2 || friends[0] != "https://ex.org/b":
+		test.Errorf("ToPlain list: %v", plain["friends"])
+	}
+	if c, ok := friends[1].(map[string]interface{}); !ok || c["name"] != "C" || len(c["type"].([]interface{})) != 2 || c["type"].([]interface{})[1] != "https://other.org/T" {
+		test.Errorf("ToPlain nested node: %v", friends[1])
+	}
+
+	node = FromPlain(map[string]interface{}{
+		"id":      "https://ex.org/d",
+		"type":    "Ignored",
+		"name":    "D",
+		"age":     7,
+		"born":    born,
+		"friends": []interface{}{map[string]interface{}{"type": "Person", "name": "E"}},
+	}, personT, v)
+	friends, _ = node[friendsP.URI()].([]interface{})
+	switch {
+	case node["@id"] != "https://ex.org/d" || node["@type"] != personT.URI() || node[nameP.URI()] != "D":
+		test.Errorf("FromPlain: %v", node)
+	case !IsVtypeval(node[ageP.URI()], NewTypeID(xsdBase+"integer", ""), 7):
+		test.Errorf("FromPlain datatype: %v", node[ageP.URI()])
+	case !IsVtype(node["https://ex.org/vocab#born"], xsdDateTime):
+		test.Errorf("FromPlain time: %v", node["https://ex.org/vocab#born"])
+	case len(friends) != 1 || !IsNtype(friends[0], personT) || friends[0].(map[string]interface{})[nameP.URI()] != "E":
+		test.Errorf("FromPlain nested: %v", friends)
+	}
+}
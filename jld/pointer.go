@@ -0,0 +1,217 @@
+package jld
+
+import (
+	"strconv"
+	"strings"
+)
+
+/*
+A pointer addresses a location in a document, such as the output of Expand or Canonicalize, in the style of an
+RFC 6901 JSON pointer: a sequence of segments, each prefixed by "/", in which "~" is escaped as "~0" and "/" as "~1".
+Since property IRIs contain "/", Pointer builds a pointer from unescaped segments. A segment is:
+
+	<key>		- the member of an object, e.g. a property IRI or @graph
+	<index>		- the element of an array at a 0 based index
+	@id=<iri>	- the node of an array whose @id is the IRI, or the current node if its @id is the IRI
+	-		- (SetAtPointer only) the position after the last element of an array, to append to it
+
+An index or @id segment also selects in the array of a @list or @set object, so a list need not be addressed via its
+@list member. For example, the name of the employer of the node https://ex.org/a of a @graph object:
+
+	GetAtPointer(doc, Pointer("@graph", "@id=https://ex.org/a", employerP.URI(), nameP.URI()))
+*/
+
+//idSegment is the prefix of an @id segment
+const idSegment = "@id="
+
+/*
+Pointer returns the pointer of unescaped segments.
+*/
+func Pointer(segments ...string) string {
+	var b strings.Builder
+
+	for _, segment := range segments {
+		b.WriteString("/")
+		b.WriteString(strings.Replace(strings.Replace(segment, "~", "~0", -1), "/", "~1", -1))
+	}
+	return b.String()
+}
+
+//parsePointer returns the unescaped segments of a pointer
+func parsePointer(pointer string) ([]string, error) {
+	var segments []string
+
+	if pointer == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, nodeError(ErrBadPointer, nil, pointer, "a pointer must be empty or start with /")
+	}
+	for _, segment := range strings.Split(pointer[1:], "/") {
+		segments = append(segments, strings.Replace(strings.Replace(segment, "~1", "/", -1), "~0", "~", -1))
+	}
+	return segments, nil
+}
+
+//pointerArray returns the array that index and @id segments select in: an array or the array of a @list or @set object
+func pointerArray(input interface{}) ([]interface{}, bool) {
+	switch input.(type) {
+	case []interface{}:
+		return input.([]interface{}), true
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		for _, k := range []string{"@list", "@set"} {
+			if v, ok := obj[k]; ok {
+				array, ok := v.([]interface{})
+				return array, ok
+			}
+		}
+	}
+	return nil, false
+}
+
+//step returns the value that a segment selects in a value
+func step(input interface{}, segment string) (interface{}, bool) {
+	if strings.HasPrefix(segment, idSegment) {
+		id := segment[len(idSegment):]
+		if node, ok := input.(map[string]interface{}); ok && node["@id"] == id {
+			return node, true
+		}
+		array, _ := pointerArray(input)
+		for _, item := range array {
+			if node, ok := item.(map[string]interface{}); ok && node["@id"] == id {
+				return node, true
+			}
+		}
+		return nil, false
+	}
+	if array, ok := pointerArray(input); ok {
+		if i, err := strconv.Atoi(segment); err == nil && i >= 0 && i < len(array) && segment == strconv.Itoa(i) {
+			return array[i], true
+		}
+		if _, isArray := input.([]interface{}); isArray {
+			return nil, false
+		}
+	}
+	if obj, ok := input.(map[string]interface{}); ok {
+		v, ok := obj[segment]
+		return v, ok
+	}
+	return nil, false
+}
+
+/*
+GetAtPointer gets the value at a pointer in a document. The empty pointer is the document itself. It returns false if
+the pointer is malformed or there is no value at it.
+*/
+func GetAtPointer(input interface{}, pointer string) (interface{}, bool) {
+	var (
+		segments []string
+		err      error
+		ok       bool
+	)
+
+	segments, err = parsePointer(pointer)
+	if err != nil {
+		return nil, false
+	}
+	for _, segment := range segments {
+		input, ok = step(input, segment)
+		if !ok {
+			return nil, false
+		}
+	}
+	return input, true
+}
+
+/*
+SetAtPointer sets the value at a pointer in a document, in place. The location's parent must exist: the last segment
+may add a member to an object, replace an element of an array or, with "-", append to an array, but not create
+intermediate objects. The document itself, at the empty pointer, cannot be set, and an element cannot be appended to
+an array that is the document itself.
+*/
+func SetAtPointer(input interface{}, pointer string, value interface{}) error {
+	var (
+		segments    []string
+		parent      = input
+		grandparent interface{}
+		err         error
+		ok          bool
+	)
+
+	segments, err = parsePointer(pointer)
+	if err != nil {
+		return err
+	}
+	if len(segments) == 0 {
+		return nodeError(ErrBadPointer, input, pointer, "the document cannot be set")
+	}
+
+	//The grandparent is needed to append to the parent, which may be an array
+	for _, segment := range segments[:len(segments)-1] {
+		grandparent = parent
+		parent, ok = step(parent, segment)
+		if !ok {
+			return nodeError(ErrBadPointer, input, Pointer(segments[:len(segments)-1]...), "there is no value at the pointer")
+		}
+	}
+	last := segments[len(segments)-1]
+
+	if array, ok := pointerArray(parent); ok && !strings.HasPrefix(last, idSegment) {
+		switch i, err := strconv.Atoi(last); {
+		case last == "-":
+			return appendAtPointer(grandparent, segments, parent, append(array, value))
+		case err == nil && i >= 0 && i < len(array) && last == strconv.Itoa(i):
+			array[i] = value
+			return nil
+		case isArray(parent):
+			return nodeError(ErrBadPointer, input, pointer, "%v is not an index of the array", last)
+		}
+	}
+	if strings.HasPrefix(last, idSegment) {
+		return nodeError(ErrBadPointer, input, pointer, "an @id segment cannot be set")
+	}
+	obj, ok := parent.(map[string]interface{})
+	if !ok {
+		return nodeError(ErrBadPointer, input, Pointer(segments[:len(segments)-1]...), "there is no object at the pointer")
+	}
+	obj[last] = value
+	return nil
+}
+
+//isArray is true if the input is an array
+func isArray(input interface{}) bool {
+	_, ok := input.([]interface{})
+	return ok
+}
+
+//appendAtPointer replaces the array that is the parent of a pointer's last segment, or the array of its @list or @set object, with an appended array
+func appendAtPointer(grandparent interface{}, segments []string, parent interface{}, appended []interface{}) error {
+	if obj, ok := parent.(map[string]interface{}); ok {
+		for _, k := range []string{"@list", "@set"} {
+			if _, ok := obj[k]; ok {
+				obj[k] = appended
+				return nil
+			}
+		}
+	}
+	if len(segments) < 2 {
+		return nodeError(ErrBadPointer, nil, Pointer(segments...), "an element cannot be appended to the document")
+	}
+
+	//The array is replaced in its own parent, in which it is an array element or an object member
+	key := segments[len(segments)-2]
+	if array, ok := pointerArray(grandparent); ok {
+		if i, err := strconv.Atoi(key); err == nil && i >= 0 && i < len(array) {
+			array[i] = appended
+			return nil
+		}
+	}
+	if obj, ok := grandparent.(map[string]interface{}); ok {
+		if _, ok := obj[key]; ok {
+			obj[key] = appended
+			return nil
+		}
+	}
+	return nodeError(ErrBadPointer, nil, Pointer(segments...), "an element cannot be appended at the pointer")
+}
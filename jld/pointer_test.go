@@ -0,0 +1,79 @@
+package jld
+
+import (
+	"testing"
+)
+
+func TestPointer(test *testing.T) {
+	var (
+		nameP  = NewPropID("https://ex.org/vocab#name", "")
+		stepsP = NewPropID("https://ex.org/vocab#steps", "")
+		tagsP  = NewPropID("https://ex.org/vocab#tags", "")
+		doc    = map[string]interface{}{
+			"@graph": []interface{}{
+				map[string]interface{}{
+					"@id":        "https://ex.org/a",
+					nameP.URI():  "A",
+					stepsP.URI(): map[string]interface{}{"@list": []interface{}{"one", "two"}},
+					tagsP.URI():  []interface{}{"x"},
+				},
+				map[string]interface{}{"@id": "https://ex.org/b", nameP.URI(): "B"},
+			},
+		}
+		v   interface{}
+		ok  bool
+		err error
+	)
+
+	if p := Pointer("@graph", "@id=https://ex.org/a", "a~b"); p != "/@graph/@id=https:~1~1ex.org~1a/a~0b" {
+		test.Errorf("Pointer: %v", p)
+	}
+	for pointer, want := range map[string]interface{}{
+		Pointer("@graph", "1", nameP.URI()):                    "B",
+		Pointer("@graph", "@id=https://ex.org/a", nameP.URI()): "A",
+		Pointer("@graph", "0", stepsP.URI(), "1"):              "two",
+		Pointer("@graph", "0", stepsP.URI(), "@list", "0"):     "one",
+	} {
+		v, ok = GetAtPointer(doc, pointer)
+		if !ok || v != want {
+			test.Errorf("GetAtPointer %v: %v %v", pointer, v, ok)
+		}
+	}
+	for _, pointer := range []string{"@graph", "/@graph/2", "/@graph/01", "/@graph/@id=https:~1~1ex.org~1c", "/x/y"} {
+		if v, ok = GetAtPointer(doc, pointer); ok {
+			test.Errorf("GetAtPointer %v should fail: %v", pointer, v)
+		}
+	}
+	if v, ok = GetAtPointer(doc, ""); !ok || v == nil {
+		test.Errorf("GetAtPointer of the document: %v", v)
+	}
+
+	a := Pointer("@graph", "@id=https://ex.org/a")
+	for pointer, value := range map[string]interface{}{
+		a + Pointer(nameP.URI()):       "A2",
+		a + Pointer(stepsP.URI(), "-"): "three",
+		a + Pointer(tagsP.URI(), "-"):  "y",
+		a + Pointer(tagsP.URI(), "0"):  "w",
+		Pointer("@graph", "-"):         map[string]interface{}{"@id": "https://ex.org/c"},
+	} {
+		if err = SetAtPointer(doc, pointer, value); err != nil {
+			test.Errorf("SetAtPointer %v: %v", pointer, err)
+		}
+	}
+	for pointer, want := range map[string]interface{}{
+		a + Pointer(nameP.URI()):       "A2",
+		a + Pointer(stepsP.URI(), "2"): "three",
+		a + Pointer(tagsP.URI(), "0"):  "w",
+		a + Pointer(tagsP.URI(), "1"):  "y",
+		Pointer("@graph", "2", "@id"):  "https://ex.org/c",
+	} {
+		if v, ok = GetAtPointer(doc, pointer); !ok || v != want {
+			test.Errorf("GetAtPointer after SetAtPointer %v: %v %v", pointer, v, ok)
+		}
+	}
+	for _, pointer := range []string{"", "/x/y", a + Pointer(tagsP.URI(), "5"), Pointer("@graph", "@id=https://ex.org/z")} {
+		if err = SetAtPointer(doc, pointer, "v"); err == nil {
+			test.Errorf("SetAtPointer %v should fail", pointer)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package jld
+
+import (
+	"context"
+
+	"github.com/kazarena/json-gold/ld"
+)
+
+/*
+A CompiledContext is a JSON LD context that has been parsed, and whose remote contexts have been loaded, once by
+Precompile, so that the documents compacted or expanded with it do not repeat the work. It is immutable once created
+and may be shared by concurrent goroutines, e.g. in a package var of a compaction service.
+
+Compact uses a CompiledContext passed as its ctx in place of a context, and Expand, Canonicalize and Frame expand
+documents against one configured with WithCompiledContext.
+*/
+type CompiledContext struct {
+	//context is the @context value put in compacted documents
+	context interface{}
+
+	//active is the parsed context; it is only read once Precompile returns
+	active *ld.Context
+}
+
+/*
+Precompile parses a context, which may be a context map, a document with an @context property, a context URL or an
+array of them, into a CompiledContext. Its remote contexts are loaded by the DocumentLoader configured by WithLoader,
+and the WithBase option is the base of its compaction and expansion. JSON LD 1.1 term definitions are rewritten into
+JSON LD 1.0 as they are for documents (see jld11.go).
+*/
+func Precompile(ctxDoc interface{}, opts ...Option) (*CompiledContext, error) {
+	var (
+		o      = newOptions(opts)
+		ctx    = ctxDoc
+		down   interface{}
+		active *ld.Context
+		err    error
+	)
+
+	if m, ok := ctxDoc.(map[string]interface{}); ok {
+		if embedded, ok := m["@context"]; ok {
+			ctx = embedded
+		}
+	}
+	ctx = DeepCopy(ctx)
+
+	down, err = downlevel(map[string]interface{}{"@context": ctx})
+	if err != nil {
+		return nil, err
+	}
+	active, err = ld.NewContext(nil, o.ldOptions()).Parse(down.(map[string]interface{})["@context"])
+	if err != nil {
+		return nil, err
+	}
+
+	//The inverse context used by compaction is built lazily; building it now keeps the CompiledContext read only
+	active.GetInverse()
+	return &CompiledContext{context: ctx, active: active}, nil
+}
+
+//expand expands a document that may use JSON LD 1.1 constructs against the CompiledContext; the Context is checked once it is rewritten
+func (cc *CompiledContext) expand(ctx context.Context, input interface{}) ([]interface{}, error) {
+	var (
+		down      interface{}
+		expandedI interface{}
+		expanded  []interface{}
+		err       error
+	)
+
+	down, err = downlevel(input)
+	if err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	expandedI, err = ld.NewJsonLdApi().Expand(cc.active, "", down)
+	if err != nil {
+		return nil, err
+	}
+
+	//As the ld processor does, a top level @graph object is replaced by its nodes and the result is always an array
+	if obj, ok := expandedI.(map[string]interface{}); ok && len(obj) == 1 {
+		if graph, ok := obj["@graph"]; ok {
+			expandedI = graph
+		}
+	}
+	switch expandedI.(type) {
+	case nil:
+		expanded = []interface{}{}
+	case []interface{}:
+		expanded = expandedI.([]interface{})
+	default:
+		expanded = []interface{}{expandedI}
+	}
+	return liftIncluded(expanded, 0), nil
+}
+
+//compact compacts an expanded document with the CompiledContext and adds a copy of its @context
+func (cc *CompiledContext) compact(expanded []interface{}) (map[string]interface{}, error) {
+	var (
+		compactedI interface{}
+		compacted  map[string]interface{}
+		err        error
+	)
+
+	compactedI, err = ld.NewJsonLdApi().Compact(cc.active, "", expanded, true)
+	if err != nil {
+		return nil, err
+	}
+	switch compactedI.(type) {
+	case map[string]interface{}:
+		compacted = compactedI.(map[string]interface{})
+	case []interface{}:
+		compacted = make(map[string]interface{}, 2)
+		if graph := compactedI.([]interface{}); len(graph) > 0 {
+			compacted[cc.active.CompactIri("@graph", nil, true, false)] = graph
+		}
+	default:
+		compacted = make(map[string]interface{}, 1)
+	}
+	if cc.context != nil {
+		compacted["@context"] = DeepCopy(cc.context)
+	}
+	return compacted, nil
+}
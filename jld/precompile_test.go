@@ -0,0 +1,42 @@
+package jld
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPrecompile(test *testing.T) {
+	var (
+		ctx = map[string]interface{}{"name": "https://ex.org/vocab#name"}
+		cc  *CompiledContext
+		wg  sync.WaitGroup
+		err error
+	)
+
+	cc, err = Precompile(map[string]interface{}{"@context": ctx})
+	if err != nil {
+		test.Fatalf("Precompile: %v", err)
+	}
+	ctx["name"] = "https://ex.org/other#name"
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			compacted, err := Compact(map[string]interface{}{}, cc)
+			if err != nil {
+				test.Errorf("Compact with a CompiledContext: %v", err)
+				return
+			}
+			c, ok := compacted["@context"].(map[string]interface{})
+			if !ok || c["name"] != "https://ex.org/vocab#name" {
+				test.Errorf("Compact with a CompiledContext @context: %v", compacted)
+				return
+			}
+
+			//The @context of a compacted document is the caller's to change
+			c["name"] = nil
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,218 @@
+package jld
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+/*
+A Column is a column of the rows of Project. Its Path is the property of a row's node whose values are the column's
+cells or, if it has several properties, the path to them through the nodes that the first properties embed or
+reference: e.g. employer and then name for the names of the employers of each row's node.
+*/
+type Column struct {
+	Name string
+	Path []PropID
+}
+
+/*
+NewColumn creates the Column of a path of properties, named by the short names of its properties joined by dots.
+*/
+func NewColumn(path ...PropID) Column {
+	var names = make([]string, 0, len(path))
+
+	for _, propID := range path {
+		names = append(names, shortIRI(propID.URI()))
+	}
+	return Column{Name: strings.Join(names, "."), Path: path}
+}
+
+/*
+Columns creates the Columns of dotted column specs of short property names, e.g. "name" and "employer.name". A
+Column is named by its spec.
+*/
+func (v *Vocabulary) Columns(specs ...string) []Column {
+	var columns = make([]Column, 0, len(specs))
+
+	for _, spec := range specs {
+		column := Column{Name: spec}
+		for _, name := range strings.Split(spec, ".") {
+			column.Path = append(column.Path, v.P(name))
+		}
+		columns = append(columns, column)
+	}
+	return columns
+}
+
+/*
+Project flattens the top level nodes of a document, such as the output of Canonicalize, into rows for export to
+analytics tools: a row is a map from the name of each Column to its cell. A cell is nil if the node has no values on
+the Column's path, the value if it has one and a []interface{} of them if it has several. A value is the @value of a
+value object, the @id of a node or node reference or a primitive, and the items of sets and lists are values.
+
+A node reference on a path is followed to the node with its @id in the document, if there is one, so that columns can
+span nodes that a frame did not embed.
+*/
+func Project(input interface{}, columns []Column) ([]map[string]interface{}, error) {
+	var (
+		p     = projector{index: make(map[string]map[string]interface{})}
+		nodes []map[string]interface{}
+		rows  []map[string]interface{}
+		err   error
+	)
+
+	for _, column := range columns {
+		if len(column.Path) == 0 {
+			return nil, fmt.Errorf("Bad Column: %v has no properties", column.Name)
+		}
+	}
+	nodes, err = topNodes(input)
+	if err != nil {
+		return nil, err
+	}
+	err = Walk(func(node map[string]interface{}) error {
+		if id, ok := node["@id"].(string); ok && id != "" {
+			p.index[id] = node
+		}
+		return nil
+	}, input)
+	if err != nil {
+		return nil, err
+	}
+
+	rows = make([]map[string]interface{}, 0, len(nodes))
+	for _, node := range nodes {
+		row := make(map[string]interface{}, len(columns))
+		for _, column := range columns {
+			row[column.Name] = cell(p.values(node, column.Path))
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+//projector holds the node index of a Project
+type projector struct {
+	index map[string]map[string]interface{}
+}
+
+//values returns the values at the end of a path from a node
+func (p projector) values(node map[string]interface{}, path []PropID) []interface{} {
+	var (
+		items  []interface{}
+		values []interface{}
+	)
+
+	items, _ = propItems(node, path[0])
+	for _, item := range items {
+		if len(path) == 1 {
+			if v, ok := projectedValue(item); ok {
+				values = append(values, v)
+			}
+			continue
+		}
+		if next := p.node(item); next != nil {
+			values = append(values, p.values(next, path[1:])...)
+		}
+	}
+	return values
+}
+
+//node returns the node of a value: the value if it is a node, the node of a node reference or nil
+func (p projector) node(item interface{}) map[string]interface{} {
+	var (
+		node map[string]interface{}
+		id   string
+	)
+
+	if !isNode(item) {
+		return nil
+	}
+	node = item.(map[string]interface{})
+	id, _ = node["@id"].(string)
+	if indexed, ok := p.index[id]; ok && IsNref(node) {
+		return indexed
+	}
+	return node
+}
+
+//projectedValue returns the cell value of an item of a property
+func projectedValue(item interface{}) (interface{}, bool) {
+	switch item.(type) {
+	case nil:
+		return nil, false
+	case map[string]interface{}:
+		obj := item.(map[string]interface{})
+		if v, ok := obj["@value"]; ok {
+			return v, true
+		}
+		if id, ok := obj["@id"].(string); ok {
+			return id, true
+		}
+		return nil, false
+	default:
+		return item, true
+	}
+}
+
+//cell returns the cell of a Column's values
+func cell(values []interface{}) interface{} {
+	switch len(values) {
+	case 0:
+		return nil
+	case 1:
+		return values[0]
+	default:
+		return values
+	}
+}
+
+/*
+WriteCSV writes rows created by Project as CSV with a header of the Column names. A nil cell is empty and the values
+of a cell that has several are joined with "; ".
+*/
+func WriteCSV(w io.Writer, rows []map[string]interface{}, columns []Column) error {
+	var (
+		cw     = csv.NewWriter(w)
+		record = make([]string, len(columns))
+		err    error
+	)
+
+	for i, column := range columns {
+		record[i] = column.Name
+	}
+	err = cw.Write(record)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		for i, column := range columns {
+			record[i] = cellString(row[column.Name])
+		}
+		err = cw.Write(record)
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+//cellString returns the CSV text of a cell
+func cellString(c interface{}) string {
+	var texts []string
+
+	switch c.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		for _, v := range c.([]interface{}) {
+			texts = append(texts, fmt.Sprint(v))
+		}
+		return strings.Join(texts, "; ")
+	default:
+		return fmt.Sprint(c)
+	}
+}
@@ -0,0 +1,58 @@
+package jld
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestProject(test *testing.T) {
+	var (
+		vocab   = NewVocabulary(NewTypeBase("https://ex.org/types#"), NewPropBase("https://ex.org/vocab#"))
+		nameP   = vocab.DefineP("name")
+		columns = vocab.Columns("name", "age", "employer.name", "tags")
+		doc     = []interface{}{
+			map[string]interface{}{
+				"@id":                           "https://ex.org/ann",
+				nameP.URI():                     map[string]interface{}{"@value": "Ann", "@language": "en"},
+				"https://ex.org/vocab#age":      float64(42),
+				"https://ex.org/vocab#employer": map[string]interface{}{"@id": "https://ex.org/acme", nameP.URI(): "Acme"},
+				"https://ex.org/vocab#tags":     []interface{}{"a", "b"},
+			},
+			map[string]interface{}{
+				"@id":                           "https://ex.org/bob",
+				nameP.URI():                     "Bob",
+				"https://ex.org/vocab#employer": map[string]interface{}{"@id": "https://ex.org/acme"},
+				"https://ex.org/vocab#tags":     map[string]interface{}{"@list": []interface{}{"c"}},
+			},
+		}
+		rows []map[string]interface{}
+		buf  bytes.Buffer
+		err  error
+	)
+
+	rows, err = Project(doc, columns)
+	if err != nil || len(rows) != 2 {
+		test.Fatalf("Project: %v %v", rows, err)
+	}
+	if rows[0]["name"] != "Ann" || rows[0]["age"] != float64(42) || rows[0]["employer.name"] != "Acme" {
+		test.Errorf("Project row 0: %v", rows[0])
+	}
+	if tags, ok := rows[0]["tags"].([]interface{}); !ok || len(tags) != 2 {
+		test.Errorf("Project multi-valued cell: %v", rows[0]["tags"])
+	}
+	if rows[1]["age"] != nil || rows[1]["employer.name"] != "Acme" || rows[1]["tags"] != "c" {
+		test.Errorf("Project should follow references: %v", rows[1])
+	}
+
+	err = WriteCSV(&buf, rows, columns)
+	if expected := "name,age,employer.name,tags\nAnn,42,Acme,a; b\nBob,,Acme,c\n"; err != nil || buf.String() != expected {
+		test.Errorf("WriteCSV: %q %v", buf.String(), err)
+	}
+
+	if column := NewColumn(vocab.P("employer"), nameP); column.Name != "employer.name" || len(column.Path) != 2 {
+		test.Errorf("NewColumn: %v", column)
+	}
+	if _, err = Project(doc, []Column{{Name: "empty"}}); err == nil {
+		test.Errorf("Project of a Column without properties should fail")
+	}
+}
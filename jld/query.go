@@ -0,0 +1,236 @@
+package jld
+
+/*
+A Query traverses property chains of a JSON LD document without chains of GetN and GetSet type assertions, e.g.
+
+	names := Q(person).P(employerP).N().P(nameP).Strings()
+
+A Query holds a set of items, initially the input. P replaces them with the values of a property, flattening sets and
+lists; N keeps only the nodes; and the result methods unwrap value objects. A step that finds nothing yields an
+empty Query rather than an error, so a chain can always be completed.
+
+Node references are resolved by a Graph provided with In; without one, they remain references (so only their @id
+is available).
+*/
+type Query struct {
+	items []interface{}
+	graph *Graph
+}
+
+/*
+Q creates a Query of a node or a slice of nodes.
+*/
+func Q(input interface{}) *Query {
+	var q Query
+
+	switch input.(type) {
+	case nil:
+	case []interface{}:
+		q.items = input.([]interface{})
+	default:
+		q.items = []interface{}{input}
+	}
+	return &q
+}
+
+/*
+In resolves the node references of the Query's later steps in a Graph.
+*/
+func (q *Query) In(g *Graph) *Query {
+	return &Query{items: q.items, graph: g}
+}
+
+/*
+P replaces the items with the values of their property. Set and list values are flattened.
+*/
+func (q *Query) P(propID PropID) *Query {
+	var (
+		items []interface{}
+		node  map[string]interface{}
+		ok    bool
+	)
+
+	for _, item := range q.items {
+		node, ok = q.resolve(item).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		items = appendValues(items, node[propID.URI()])
+	}
+	return &Query{items: items, graph: q.graph}
+}
+
+//appendValues appends a property value to items, flattening sets and lists
+func appendValues(items []interface{}, v interface{}) []interface{} {
+	switch v.(type) {
+	case nil:
+	case []interface{}:
+		for _, item := range v.([]interface{}) {
+			items = appendValues(items, item)
+		}
+	case map[string]interface{}:
+		if list, ok := v.(map[string]interface{})["@list"]; ok {
+			return appendValues(items, list)
+		}
+		items = append(items, v)
+	default:
+		items = append(items, v)
+	}
+	return items
+}
+
+/*
+N keeps only the items that are nodes or node references, resolving the references in the Query's Graph.
+*/
+func (q *Query) N() *Query {
+	var items []interface{}
+
+	for _, item := range q.items {
+		item = q.resolve(item)
+		if isNode(item) {
+			items = append(items, item)
+		}
+	}
+	return &Query{items: items, graph: q.graph}
+}
+
+/*
+Type keeps only the items that are nodes of type t.
+*/
+func (q *Query) Type(t TypeID) *Query {
+	var items []interface{}
+
+	for _, item := range q.items {
+		item = q.resolve(item)
+		if isNode(item) && hasType(item.(map[string]interface{}), t) {
+			items = append(items, item)
+		}
+	}
+	return &Query{items: items, graph: q.graph}
+}
+
+//resolve returns the Graph's node of a node reference, if there is one, and otherwise the item
+func (q *Query) resolve(item interface{}) interface{} {
+	var (
+		id   string
+		node map[string]interface{}
+		ok   bool
+	)
+
+	if q.graph == nil {
+		return item
+	}
+	id, ok = GetNRef(item)
+	if !ok {
+		return item
+	}
+	node, ok = q.graph.GetByID(id)
+	if !ok {
+		return item
+	}
+	return node
+}
+
+//isNode is true if the item is a node object or node reference rather than a value, list or set object
+func isNode(item interface{}) bool {
+	var (
+		obj map[string]interface{}
+		ok  bool
+	)
+
+	obj, ok = item.(map[string]interface{})
+	if !ok {
+		return false
+	}
+	for _, k := range []string{"@value", "@list", "@set"} {
+		if _, ok = obj[k]; ok {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+All returns the items with value objects unwrapped to their @value.
+*/
+func (q *Query) All() []interface{} {
+	var values = make([]interface{}, 0, len(q.items))
+
+	for _, item := range q.items {
+		values = append(values, valueOf(item))
+	}
+	return values
+}
+
+/*
+First returns the first item with a value object unwrapped to its @value.
+*/
+func (q *Query) First() (interface{}, bool) {
+	if len(q.items) == 0 {
+		return nil, false
+	}
+	return valueOf(q.items[0]), true
+}
+
+/*
+Len returns the number of items.
+*/
+func (q *Query) Len() int {
+	return len(q.items)
+}
+
+/*
+Nodes returns the items that are nodes or node references.
+*/
+func (q *Query) Nodes() []map[string]interface{} {
+	var nodes []map[string]interface{}
+
+	for _, item := range q.items {
+		if isNode(item) {
+			nodes = append(nodes, item.(map[string]interface{}))
+		}
+	}
+	return nodes
+}
+
+/*
+IDs returns the @id of the items that are nodes or node references.
+*/
+func (q *Query) IDs() []string {
+	var ids []string
+
+	for _, node := range q.Nodes() {
+		if id, ok := node["@id"].(string); ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+/*
+Strings returns the string values of the items; other items are skipped.
+*/
+func (q *Query) Strings() []string {
+	var strs []string
+
+	for _, item := range q.items {
+		if s, ok := valueOf(item).(string); ok {
+			strs = append(strs, s)
+		}
+	}
+	return strs
+}
+
+/*
+Bools returns the boolean values of the items; other items are skipped.
+*/
+func (q *Query) Bools() []bool {
+	var bools []bool
+
+	for _, item := range q.items {
+		if b, ok := valueOf(item).(bool); ok {
+			bools = append(bools, b)
+		}
+	}
+	return bools
+}
@@ -0,0 +1,53 @@
+package jld
+
+import (
+	"testing"
+)
+
+func TestQuery(test *testing.T) {
+	var (
+		person    = NewTypeID("https://ex.org/types#Person", "")
+		employerP = NewPropID("https://ex.org/vocab#employer", "")
+		knowsP    = NewPropID("https://ex.org/vocab#knows", "")
+		nameP     = NewPropID("https://ex.org/vocab#name", "")
+		ann, bob  map[string]interface{}
+		g         *Graph
+		strs      []string
+		err       error
+	)
+
+	bob = map[string]interface{}{
+		"@id":           "https://ex.org/bob",
+		"@type":         []interface{}{person.URI()},
+		nameP.URI():     []interface{}{map[string]interface{}{"@value": "Bob"}},
+		employerP.URI(): map[string]interface{}{"@id": "_:acme", nameP.URI(): "Acme"},
+	}
+	ann = map[string]interface{}{
+		"@id":        "https://ex.org/ann",
+		"@type":      person.URI(),
+		knowsP.URI(): map[string]interface{}{"@list": []interface{}{map[string]interface{}{"@id": "https://ex.org/bob"}}},
+	}
+
+	strs = Q(bob).P(employerP).N().P(nameP).Strings()
+	if len(strs) != 1 || strs[0] != "Acme" {
+		test.Errorf("Query employer name: %v", strs)
+	}
+	if ids := Q(ann).P(knowsP).N().IDs(); len(ids) != 1 || ids[0] != "https://ex.org/bob" {
+		test.Errorf("Query knows IDs: %v", ids)
+	}
+	if Q(ann).P(knowsP).P(nameP).Len() != 0 {
+		test.Errorf("Query should not see through an unresolved reference")
+	}
+
+	g, err = NewGraph([]interface{}{ann, bob})
+	if err != nil {
+		test.Fatalf("NewGraph: %v", err)
+	}
+	strs = Q(ann).In(g).P(knowsP).Type(person).P(nameP).Strings()
+	if len(strs) != 1 || strs[0] != "Bob" {
+		test.Errorf("Query knows name: %v", strs)
+	}
+	if _, ok := Q(nil).P(nameP).N().First(); ok {
+		test.Errorf("Query of nil should be empty")
+	}
+}
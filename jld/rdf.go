@@ -0,0 +1,146 @@
+package jld
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/kazarena/json-gold/ld"
+)
+
+//nquadsFormat is the ld format name of N-Quads
+const nquadsFormat = "application/nquads"
+
+/*
+ToNQuads serializes a JSON LD document to N-Quads so that it can be stored in a triple store.
+The input may be unmarshalled JSON LD in any form (e.g. the output of Canonicalize or a document built with NewN).
+The serialization is returned as one string; use an NQuadsWriter to export a document too large to hold it in memory.
+*/
+func ToNQuads(input interface{}) (string, error) {
+	var (
+		jsonLdProcessor = ld.NewJsonLdProcessor()
+		options         = newLdOptions("")
+		nquadsI         interface{}
+		nquads          string
+		ok              bool
+		err             error
+	)
+
+	options.Format = nquadsFormat
+	nquadsI, err = jsonLdProcessor.ToRDF(input, options)
+	if err != nil {
+		return "", err
+	}
+	nquads, ok = nquadsI.(string)
+	if !ok {
+		return "", fmt.Errorf("ToRDF did not produce N-Quads")
+	}
+	return nquads, nil
+}
+
+/*
+FromNQuads parses N-Quads into an expanded JSON LD document. Literals keep their datatype as typed value objects
+(e.g. an xsd:integer is {"@type": xsd:integer, "@value": "42"}) so no precision is lost; and rdf:type statements are
+converted to @type. The result may be passed to Canonicalize. Malformed N-Quads are an error.
+*/
+func FromNQuads(nquads string) ([]interface{}, error) {
+	var (
+		jsonLdProcessor = ld.NewJsonLdProcessor()
+		options         = newLdOptions("")
+		docI            interface{}
+		doc             []interface{}
+		ok              bool
+		err             error
+	)
+
+	//FromRDF ignores the errors of its N-Quads parser, so the input is parsed first to report them
+	_, err = ld.ParseNQuads(nquads)
+	if err != nil {
+		return nil, err
+	}
+
+	options.Format = nquadsFormat
+	options.UseNativeTypes = false
+	options.UseRdfType = false
+	docI, err = jsonLdProcessor.FromRDF(nquads, options)
+	if err != nil {
+		return nil, err
+	}
+	switch docI.(type) {
+	case nil:
+		return nil, nil
+	}
+	doc, ok = docI.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("FromRDF did not produce an expanded document")
+	}
+	return doc, nil
+}
+
+/*
+Normalize canonicalizes a JSON LD document with the URDNA2015 algorithm and returns it as sorted N-Quads with
+canonical blank node labels. Documents that are the same graph always normalize to the same string, so it is the
+form to hash or sign. Unlike Canonicalize, it does not frame or compact the document.
+*/
+func Normalize(input interface{}) (string, error) {
+	var (
+		jsonLdProcessor = ld.NewJsonLdProcessor()
+		options         = newLdOptions("")
+		normalizedI     interface{}
+		normalized      string
+		ok              bool
+		err             error
+	)
+
+	options.Format = nquadsFormat
+	options.Algorithm = "URDNA2015"
+	normalizedI, err = jsonLdProcessor.Normalize(input, options)
+	if err != nil {
+		return "", err
+	}
+	normalized, ok = normalizedI.(string)
+	if !ok {
+		return "", fmt.Errorf("Normalize did not produce N-Quads")
+	}
+	return normalized, nil
+}
+
+/*
+RelabelBlankNodes returns the input as an expanded, flattened JSON LD document whose blank node IDs are the canonical
+labels (_:c14n0, _:c14n1, ...) assigned by the URDNA2015 algorithm and whose nodes are sorted by @id. Two semantically
+identical inputs therefore marshal to byte-identical JSON, which makes the output usable as a cache key or test fixture.
+HashBlankIDs relabels blank nodes in place without changing a document's shape.
+*/
+func RelabelBlankNodes(input interface{}) ([]interface{}, error) {
+	var (
+		normalized string
+		doc        []interface{}
+		err        error
+	)
+
+	normalized, err = Normalize(input)
+	if err != nil {
+		return nil, err
+	}
+	doc, err = FromNQuads(normalized)
+	if err != nil {
+		return nil, err
+	}
+
+	//FromRDF orders the nodes of the default graph by subject, but the order is not part of its contract
+	sort.SliceStable(doc, func(i, j int) bool {
+		return nodeID(doc[i]) < nodeID(doc[j])
+	})
+	return doc, nil
+}
+
+//nodeID returns the @id of a node or "" if it has none
+func nodeID(input interface{}) string {
+	var (
+		node map[string]interface{}
+		id   string
+	)
+
+	node, _ = input.(map[string]interface{})
+	id, _ = node["@id"].(string)
+	return id
+}
@@ -0,0 +1,193 @@
+package jld
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+//rdfDoc is a compacted document with a typed value, a language string, a list and a blank node
+var rdfDoc = map[string]interface{}{
+	"@context": map[string]interface{}{
+		"@vocab": "https://ex.org/vocab#",
+		"age":    map[string]interface{}{"@type": "http://www.w3.org/2001/XMLSchema#integer"},
+		"steps":  map[string]interface{}{"@container": "@list"},
+	},
+	"@id":   "https://ex.org/ann",
+	"@type": "Person",
+	"age":   "42",
+	"name":  map[string]interface{}{"@value": "Ann", "@language": "en"},
+	"steps": []interface{}{"a", "b"},
+	"knows": map[string]interface{}{"name": "Bob"},
+}
+
+func TestNQuadsRoundTrip(test *testing.T) {
+	var (
+		nquads string
+		doc    []interface{}
+		err    error
+	)
+
+	nquads, err = ToNQuads(rdfDoc)
+	if err != nil {
+		test.Fatalf("ToNQuads: %v", err)
+	}
+	for _, statement := range []string{
+		`<https://ex.org/ann> <http://www.w3.org/1999/02/22-rdf-syntax-ns#type> <https://ex.org/vocab#Person> .`,
+		`<https://ex.org/ann> <https://ex.org/vocab#age> "42"^^<http://www.w3.org/2001/XMLSchema#integer> .`,
+		`<https://ex.org/ann> <https://ex.org/vocab#name> "Ann"@en .`,
+	} {
+		if !strings.Contains(nquads, statement+"\n") {
+			test.Errorf("ToNQuads has no %v:\n%v", statement, nquads)
+		}
+	}
+
+	doc, err = FromNQuads(nquads)
+	if err != nil {
+		test.Fatalf("FromNQuads: %v", err)
+	}
+	if equal, err := Equal(doc, rdfDoc); err != nil || !equal {
+		test.Errorf("FromNQuads of ToNQuads is not the document: %v %v", doc, err)
+	}
+	ann, ok := topNodeByID(doc, "https://ex.org/ann")
+	if !ok || !hasType(ann, NewTypeID("https://ex.org/vocab#Person", "")) {
+		test.Fatalf("FromNQuads: %v", doc)
+	}
+	if age := asArray(ann["https://ex.org/vocab#age"]); len(age) != 1 || !IsVtypeval(age[0], NewTypeID(xsdBase+"integer", ""), "42") {
+		test.Errorf("FromNQuads age: %v", ann)
+	}
+
+	doc, err = FromNQuads("")
+	if err != nil || len(doc) != 0 {
+		test.Errorf("FromNQuads of no statements: %v %v", doc, err)
+	}
+}
+
+func TestNQuadsMalformed(test *testing.T) {
+	for _, nquads := range []string{
+		"<https://ex.org/a> <https://ex.org/p> .\n",
+		"<https://ex.org/a> <https://ex.org/p> \"unterminated .\n",
+		"<https://ex.org/a> <https://ex.org/p> <https://ex.org/b>\n",
+		"not n-quads\n",
+	} {
+		if doc, err := FromNQuads(nquads); err == nil {
+			test.Errorf("FromNQuads of %q should fail: %v", nquads, doc)
+		}
+	}
+
+	if nquads, err := ToNQuads(map[string]interface{}{"@context": 42, "@id": "https://ex.org/a"}); err == nil {
+		test.Errorf("ToNQuads of a document with a bad @context should fail: %v", nquads)
+	}
+}
+
+func TestNormalize(test *testing.T) {
+	var (
+		//The same graph of a node knowing two blank nodes, in other orders and with other blank node labels
+		a = []interface{}{
+			map[string]interface{}{"@id": "https://ex.org/ann", "https://ex.org/vocab#knows": []interface{}{
+				map[string]interface{}{"@id": "_:x"}, map[string]interface{}{"@id": "_:y"},
+			}},
+			map[string]interface{}{"@id": "_:x", "https://ex.org/vocab#name": "Bob"},
+			map[string]interface{}{"@id": "_:y", "https://ex.org/vocab#name": "Cy", "https://ex.org/vocab#knows": map[string]interface{}{"@id": "_:x"}},
+		}
+		b = []interface{}{
+			map[string]interface{}{"@id": "_:q", "https://ex.org/vocab#knows": map[string]interface{}{"@id": "_:p"}, "https://ex.org/vocab#name": "Cy"},
+			map[string]interface{}{"https://ex.org/vocab#name": "Bob", "@id": "_:p"},
+			map[string]interface{}{"https://ex.org/vocab#knows": []interface{}{
+				map[string]interface{}{"@id": "_:q"}, map[string]interface{}{"@id": "_:p"},
+			}, "@id": "https://ex.org/ann"},
+		}
+	)
+
+	normalA, err := Normalize(a)
+	if err != nil {
+		test.Fatalf("Normalize: %v", err)
+	}
+	normalB, err := Normalize(b)
+	if err != nil {
+		test.Fatalf("Normalize: %v", err)
+	}
+	if normalA != normalB {
+		test.Errorf("Normalize of the same graph:\n%v\n%v", normalA, normalB)
+	}
+	if strings.Contains(normalA, "_:x") || !strings.Contains(normalA, "_:c14n0") {
+		test.Errorf("Normalize did not relabel the blank nodes:\n%v", normalA)
+	}
+	lines := strings.Split(strings.TrimSuffix(normalA, "\n"), "\n")
+	if len(lines) != 5 || !sort.StringsAreSorted(lines) {
+		test.Errorf("Normalize is not 5 sorted statements:\n%v", normalA)
+	}
+
+	//Another graph, in which Bob knows Cy, is not normalized the same
+	b[0].(map[string]interface{})["https://ex.org/vocab#knows"] = []interface{}{}
+	b[1].(map[string]interface{})["https://ex.org/vocab#knows"] = map[string]interface{}{"@id": "_:q"}
+	if normalB, err = Normalize(b); err != nil || normalB == normalA {
+		test.Errorf("Normalize of another graph:\n%v %v", normalB, err)
+	}
+}
+
+func TestRelabelBlankNodes(test *testing.T) {
+	var (
+		knowsP = "https://ex.org/vocab#knows"
+		nameP  = "https://ex.org/vocab#name"
+		doc    = func(x, y string) []interface{} {
+			return []interface{}{
+				map[string]interface{}{"@id": "https://ex.org/ann", knowsP: map[string]interface{}{"@id": x}},
+				map[string]interface{}{"@id": x, nameP: "Bob", knowsP: map[string]interface{}{"@id": y}},
+				map[string]interface{}{"@id": y, nameP: "Cy"},
+			}
+		}
+	)
+
+	relabelled, err := RelabelBlankNodes(doc("_:x", "_:y"))
+	if err != nil {
+		test.Fatalf("RelabelBlankNodes: %v", err)
+	}
+	other, err := RelabelBlankNodes(doc("_:b9", "_:a1"))
+	if err != nil {
+		test.Fatalf("RelabelBlankNodes: %v", err)
+	}
+	if !reflect.DeepEqual(relabelled, other) {
+		test.Errorf("RelabelBlankNodes is not stable:\n%v\n%v", relabelled, other)
+	}
+	again, err := RelabelBlankNodes(relabelled)
+	if err != nil || !reflect.DeepEqual(again, relabelled) {
+		test.Errorf("RelabelBlankNodes of its output: %v %v", again, err)
+	}
+
+	//The nodes are sorted by @id and the references follow the blank nodes' new labels
+	byName := make(map[string]map[string]interface{})
+	for i, item := range relabelled {
+		node := item.(map[string]interface{})
+		if i > 0 && nodeID(relabelled[i-1]) >= nodeID(node) {
+			test.Errorf("RelabelBlankNodes is not sorted by @id: %v", relabelled)
+		}
+		if id := nodeID(node); strings.HasPrefix(id, "_:") && !strings.HasPrefix(id, "_:c14n") {
+			test.Errorf("RelabelBlankNodes kept the label %v", id)
+		}
+		if names, ok := node[nameP].([]interface{}); ok && len(names) == 1 {
+			byName[valueOf(names[0]).(string)] = node
+		}
+	}
+	ann, _ := topNodeByID(relabelled, "https://ex.org/ann")
+	bob, cy := byName["Bob"], byName["Cy"]
+	switch {
+	case ann == nil || bob == nil || cy == nil:
+		test.Fatalf("RelabelBlankNodes lost a node: %v", relabelled)
+	case nodeID(asArray(ann[knowsP])[0]) != nodeID(bob):
+		test.Errorf("RelabelBlankNodes: ann does not know bob: %v", relabelled)
+	case nodeID(asArray(bob[knowsP])[0]) != nodeID(cy):
+		test.Errorf("RelabelBlankNodes: bob does not know cy: %v", relabelled)
+	}
+}
+
+//topNodeByID returns the top level node of an expanded document with an @id
+func topNodeByID(doc []interface{}, id string) (map[string]interface{}, bool) {
+	for _, item := range doc {
+		if nodeID(item) == id {
+			return item.(map[string]interface{}), true
+		}
+	}
+	return nil, false
+}
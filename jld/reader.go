@@ -0,0 +1,127 @@
+package jld
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+/*
+ExpandReader is Expand of a raw JSON document read from an io.Reader. The document is decoded incrementally, token by
+token, so the raw JSON is never held in memory as a whole alongside its unmarshalled form. Wrap the reader with an
+io.LimitReader to bound the size of documents received from partners.
+*/
+func ExpandReader(r io.Reader, opts ...Option) ([]interface{}, error) {
+	var (
+		input interface{}
+		err   error
+	)
+
+	input, err = DecodeReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return Expand(input, opts...)
+}
+
+/*
+CanonicalizeReader is Canonicalize of a raw JSON document read from an io.Reader; it is decoded as by ExpandReader.
+*/
+func CanonicalizeReader(r io.Reader, typeFilter []TypeID, opts ...Option) (interface{}, error) {
+	var (
+		input interface{}
+		err   error
+	)
+
+	input, err = DecodeReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return Canonicalize(input, typeFilter, opts...)
+}
+
+/*
+DecodeReader incrementally decodes a single raw JSON document from an io.Reader into the same form as json.Unmarshal
+into an interface{}. Data following the document is an error.
+*/
+func DecodeReader(r io.Reader) (interface{}, error) {
+	var (
+		dec   = json.NewDecoder(r)
+		token json.Token
+		doc   interface{}
+		err   error
+	)
+
+	token, err = dec.Token()
+	if err != nil {
+		return nil, fmt.Errorf("Bad JSON Document: %v", err)
+	}
+	doc, err = decodeValue(dec, token, 0)
+	if err != nil {
+		return nil, err
+	}
+	_, err = dec.Token()
+	if err != io.EOF {
+		return nil, fmt.Errorf("Bad JSON Document: data follows the document")
+	}
+	return doc, nil
+}
+
+//decodeValue decodes the value that starts with a token
+func decodeValue(dec *json.Decoder, token json.Token, depth int) (interface{}, error) {
+	var (
+		item interface{}
+		err  error
+	)
+
+	if depth > maxGraphDepth {
+		return nil, fmt.Errorf("Document nesting exceeds %v", maxGraphDepth)
+	}
+
+	switch token {
+	case json.Delim('['):
+		array := []interface{}{}
+		for dec.More() {
+			token, err = dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("Bad JSON Document: %v", err)
+			}
+			item, err = decodeValue(dec, token, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, item)
+		}
+		_, err = dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("Bad JSON Document: %v", err)
+		}
+		return array, nil
+	case json.Delim('{'):
+		obj := make(map[string]interface{})
+		for dec.More() {
+			token, err = dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("Bad JSON Document: %v", err)
+			}
+			key, _ := token.(string)
+			token, err = dec.Token()
+			if err != nil {
+				return nil, fmt.Errorf("Bad JSON Document: %v", err)
+			}
+			obj[key], err = decodeValue(dec, token, depth+1)
+			if err != nil {
+				return nil, err
+			}
+		}
+		_, err = dec.Token()
+		if err != nil {
+			return nil, fmt.Errorf("Bad JSON Document: %v", err)
+		}
+		return obj, nil
+	case json.Delim(']'), json.Delim('}'):
+		return nil, fmt.Errorf("Bad JSON Document: unexpected %v", token)
+	default:
+		return token, nil
+	}
+}
@@ -0,0 +1,32 @@
+package jld
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeReader(test *testing.T) {
+	var (
+		raw = `{"@context": {"name": "https://ex.org/vocab#name"}, "@graph": [{"@id": "https://ex.org/a", "name": "A", "n": 1.5, "ok": true, "x": null, "l": {"@list": []}}]}`
+		doc interface{}
+		exp interface{}
+		err error
+	)
+
+	doc, err = DecodeReader(strings.NewReader(raw))
+	if err != nil {
+		test.Fatalf("DecodeReader: %v", err)
+	}
+	json.Unmarshal([]byte(raw), &exp)
+	if !reflect.DeepEqual(doc, exp) {
+		test.Errorf("DecodeReader: %v != %v", doc, exp)
+	}
+
+	for _, bad := range []string{`{"a": 1} {}`, `[1, 2`, `{"a": }`, ``} {
+		if _, err = DecodeReader(strings.NewReader(bad)); err == nil {
+			test.Errorf("DecodeReader of %q: no error", bad)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package jld
+
+import (
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+//setNode validates the input and property of a Set function and returns the input as a node
+func setNode(input interface{}, propID PropID) (map[string]interface{}, error) {
+	var (
+		u   *url.URL
+		err error
+	)
+
+	if !isNode(input) {
+		return nil, notANode(input)
+	}
+	u, err = url.Parse(propID.URI())
+	if err != nil || !u.IsAbs() {
+		return nil, nodeError(ErrBadProperty, input, "", "%v is not an absolute IRI", propID)
+	}
+	return input.(map[string]interface{}), nil
+}
+
+/*
+SetP sets the property of a node to a value, replacing any existing value. The value must be unmarshalled JSON
+(a string, bool, number, json.Number, map or slice of these); if it is nil, the property is removed.
+The property must be an absolute IRI - use NewN or AddN to set @id and @type.
+If the property has a registered datatype (see RegisterDatatype), a primitive value or time.Time is stored as a
+value object of the datatype.
+*/
+func SetP(input interface{}, propID PropID, value interface{}) error {
+	var (
+		node   map[string]interface{}
+		typeID TypeID
+		valobj map[string]interface{}
+		ok     bool
+		err    error
+	)
+
+	node, err = setNode(input, propID)
+	if err != nil {
+		return err
+	}
+	if typeID, ok = Datatype(propID); ok {
+		if valobj, ok = coerceV(typeID, value); ok {
+			node[propID.URI()] = valobj
+			return nil
+		}
+	}
+	switch value.(type) {
+	case nil:
+		delete(node, propID.URI())
+		return nil
+	case string, bool, int, int64, float32, float64, json.Number, map[string]interface{}, []interface{}:
+		node[propID.URI()] = value
+		return nil
+	default:
+		return nodeError(ErrBadValue, node, Pointer(propID.URI()), "%v of type %T", value, value)
+	}
+}
+
+/*
+SetV sets the property of a node to a typed value object. The value may be a bool, int, float32, float64 or string.
+*/
+func SetV(input interface{}, propID PropID, t TypeID, v interface{}) error {
+	var (
+		node   map[string]interface{}
+		valobj map[string]interface{}
+		err    error
+	)
+
+	node, err = setNode(input, propID)
+	if err != nil {
+		return err
+	}
+	valobj = NewV(t, v)
+	if valobj["@value"] == nil {
+		return nodeError(ErrBadValue, node, Pointer(propID.URI()), "%v of type %T", v, v)
+	}
+	node[propID.URI()] = valobj
+	return nil
+}
+
+/*
+SetN sets the property of a node to a node or node reference.
+*/
+func SetN(input interface{}, propID PropID, n map[string]interface{}) error {
+	var (
+		node map[string]interface{}
+		err  error
+	)
+
+	node, err = setNode(input, propID)
+	if err != nil {
+		return err
+	}
+	if !isNode(n) {
+		return nodeError(ErrNotANode, node, Pointer(propID.URI()), "%T is not a node object", n)
+	}
+	node[propID.URI()] = n
+	return nil
+}
+
+/*
+SetList sets the property of a node to a list object of the items. Since JSON LD does not allow lists of lists,
+an item may not be a list object.
+*/
+func SetList(input interface{}, propID PropID, items ...interface{}) error {
+	var (
+		node map[string]interface{}
+		list = make([]interface{}, 0, len(items))
+		err  error
+	)
+
+	node, err = setNode(input, propID)
+	if err != nil {
+		return err
+	}
+	for i, item := range items {
+		if IsList(item) {
+			return nodeError(ErrBadValue, node, Pointer(propID.URI(), strconv.Itoa(i)), "a list cannot contain a list")
+		}
+		list = append(list, item)
+	}
+	node[propID.URI()] = NewL(list)
+	return nil
+}
+
+/*
+RemoveP removes a property from a node, e.g. to redact it before returning a document to a client. Removing a
+property the node does not have is not an error. Use RemoveType to remove a type.
+*/
+func RemoveP(input interface{}, propID PropID) error {
+	var (
+		node map[string]interface{}
+		ok   bool
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return notANode(input)
+	}
+	delete(node, propID.URI())
+	return nil
+}
+
+/*
+AddTypes adds types to a node's @type set, creating it if the node has none. Types the node already has are not
+repeated, and the set is normalized: a single type is stored as a string and several as a []interface{} of strings.
+*/
+func AddTypes(input interface{}, t ...TypeID) error {
+	var (
+		node  map[string]interface{}
+		types []string
+		added []interface{}
+		seen  = make(map[string]bool)
+		ok    bool
+		err   error
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return notANode(input)
+	}
+	types, err = nodeTypes(node)
+	if err != nil {
+		return err
+	}
+	for _, typeID := range t {
+		types = append(types, typeID.URI())
+	}
+
+	for _, typeURI := range types {
+		if !seen[typeURI] {
+			seen[typeURI] = true
+			added = append(added, typeURI)
+		}
+	}
+	setTypes(node, added)
+	return nil
+}
+
+/*
+RemoveType removes a type from a node's @type. If one type remains, the @type set is collapsed to it; if none
+remain, @type is removed. Removing a type the node does not have is not an error.
+*/
+func RemoveType(input interface{}, t TypeID) error {
+	var (
+		node  map[string]interface{}
+		types []string
+		kept  []interface{}
+		ok    bool
+		err   error
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return notANode(input)
+	}
+	types, err = nodeTypes(node)
+	if err != nil {
+		return err
+	}
+
+	for _, typeURI := range types {
+		if typeURI != t.URI() {
+			kept = append(kept, typeURI)
+		}
+	}
+	setTypes(node, kept)
+	return nil
+}
+
+//nodeTypes returns the type URIs of a node's @type in any of the representations used by NewN, AddN and unmarshalling
+func nodeTypes(node map[string]interface{}) ([]string, error) {
+	var types []string
+
+	switch tv := node["@type"].(type) {
+	case nil:
+	case string:
+		types = []string{tv}
+	case TypeID:
+		types = []string{tv.URI()}
+	case []string:
+		types = append(types, tv...)
+	case []TypeID:
+		for _, typeID := range tv {
+			types = append(types, typeID.URI())
+		}
+	case []interface{}:
+		for i, typeI := range tv {
+			switch typeI.(type) {
+			case string:
+				types = append(types, typeI.(string))
+			case TypeID:
+				types = append(types, typeI.(TypeID).URI())
+			default:
+				return nil, nodeError(ErrBadType, node, Pointer("@type", strconv.Itoa(i)), "%v of type %T is not a type IRI", typeI, typeI)
+			}
+		}
+	default:
+		return nil, nodeError(ErrBadType, node, Pointer("@type"), "%v of type %T is not a type IRI", tv, tv)
+	}
+	return types, nil
+}
+
+//setTypes sets a node's @type to a set of type URIs: none removes it, one is a string and several an array
+func setTypes(node map[string]interface{}, types []interface{}) {
+	switch len(types) {
+	case 0:
+		delete(node, "@type")
+	case 1:
+		node["@type"] = types[0]
+	default:
+		node["@type"] = types
+	}
+}
@@ -0,0 +1,137 @@
+package jld
+
+import (
+	"testing"
+)
+
+func TestSetters(test *testing.T) {
+	var (
+		person = NewTypeID("https://ex.org/types#Person", "")
+		nameP  = NewPropID("https://ex.org/vocab#name", "")
+		ageP   = NewPropID("https://ex.org/vocab#age", "")
+		knowsP = NewPropID("https://ex.org/vocab#knows", "")
+		tagsP  = NewPropID("https://ex.org/vocab#tags", "")
+		xsdInt = NewTypeID(xsdBase+"integer", "")
+		node   = NewN("https://ex.org/ann", person)
+		bob    = NewN("https://ex.org/bob", person)
+		s      string
+		i      int64
+		n      map[string]interface{}
+		items  []interface{}
+		ok     bool
+		err    error
+	)
+
+	err = SetP(node, nameP, "Ann")
+	if err != nil {
+		test.Errorf("SetP: %v", err)
+	}
+	s, ok = GetString(node, nameP)
+	if !ok || s != "Ann" {
+		test.Errorf("SetP GetString: %v %v", s, ok)
+	}
+	err = SetV(node, ageP, xsdInt, 42)
+	if err != nil {
+		test.Errorf("SetV: %v", err)
+	}
+	i, ok = GetInt(node, ageP)
+	if !ok || i != 42 {
+		test.Errorf("SetV GetInt: %v %v", i, ok)
+	}
+	err = SetN(node, knowsP, bob)
+	if err != nil {
+		test.Errorf("SetN: %v", err)
+	}
+	n, ok = GetN(node, knowsP)
+	if !ok || n["@id"] != "https://ex.org/bob" {
+		test.Errorf("SetN GetN: %v %v", n, ok)
+	}
+	err = SetList(node, tagsP, "a", "b")
+	if err != nil {
+		test.Errorf("SetList: %v", err)
+	}
+	items, ok = GetList(node, tagsP)
+	if !ok || len(items) != 2 {
+		test.Errorf("SetList GetList: %v %v", items, ok)
+	}
+
+	err = SetP(node, nameP, nil)
+	if _, ok = GetP(node, nameP); err != nil || ok {
+		test.Errorf("SetP nil should remove the property: %v", err)
+	}
+	switch {
+	case SetP(node, TypeP, "x") == nil:
+		test.Errorf("SetP should reject a keyword property")
+	case SetP(node, nameP, struct{}{}) == nil:
+		test.Errorf("SetP should reject a non-JSON value")
+	case SetP(NewV(xsdInt, 1), nameP, "x") == nil:
+		test.Errorf("SetP should reject a value object")
+	case SetV(node, ageP, xsdInt, []int{1}) == nil:
+		test.Errorf("SetV should reject a non-primitive value")
+	case SetN(node, knowsP, NewV(xsdInt, 1)) == nil:
+		test.Errorf("SetN should reject a value object")
+	case SetList(node, tagsP, NewL([]interface{}{"a"})) == nil:
+		test.Errorf("SetList should reject a list of lists")
+	}
+}
+
+func TestRemove(test *testing.T) {
+	var (
+		person = NewTypeID("https://ex.org/types#Person", "")
+		agent  = NewTypeID("https://ex.org/types#Agent", "")
+		nameP  = NewPropID("https://ex.org/vocab#name", "")
+		node   = NewN("https://ex.org/ann", person, agent)
+		err    error
+	)
+
+	node[nameP.URI()] = "Ann"
+	err = RemoveP(node, nameP)
+	if _, ok := node[nameP.URI()]; err != nil || ok {
+		test.Errorf("RemoveP: %v %v", node, err)
+	}
+	err = RemoveType(node, agent)
+	if err != nil || node["@type"] != person.URI() {
+		test.Errorf("RemoveType should collapse to a string: %v %v", node["@type"], err)
+	}
+	err = RemoveType(node, agent)
+	if err != nil || node["@type"] != person.URI() {
+		test.Errorf("RemoveType of a missing type: %v %v", node["@type"], err)
+	}
+	err = RemoveType(node, person)
+	if _, ok := node["@type"]; err != nil || ok {
+		test.Errorf("RemoveType should remove @type: %v %v", node["@type"], err)
+	}
+	if RemoveP("x", nameP) == nil {
+		test.Errorf("RemoveP should reject a non-node")
+	}
+}
+
+func TestAddTypes(test *testing.T) {
+	var (
+		person = NewTypeID("https://ex.org/types#Person", "")
+		agent  = NewTypeID("https://ex.org/types#Agent", "")
+		node   = map[string]interface{}{"@id": "https://ex.org/ann"}
+		err    error
+	)
+
+	err = AddTypes(node, person)
+	if err != nil || node["@type"] != person.URI() {
+		test.Errorf("AddTypes to an untyped node: %v %v", node["@type"], err)
+	}
+	err = AddType(node, agent)
+	if types, ok := node["@type"].([]interface{}); err != nil || !ok || len(types) != 2 || types[1] != agent.URI() {
+		test.Errorf("AddType should grow the set: %v %v", node["@type"], err)
+	}
+	err = AddTypes(node, agent, person)
+	if types, ok := node["@type"].([]interface{}); err != nil || !ok || len(types) != 2 {
+		test.Errorf("AddTypes should dedupe: %v %v", node["@type"], err)
+	}
+	node = NewN("https://ex.org/bob", person)
+	err = AddTypes(node, agent)
+	if types, ok := node["@type"].([]interface{}); err != nil || !ok || types[0] != person.URI() {
+		test.Errorf("AddTypes should normalize a TypeID: %v %v", node["@type"], err)
+	}
+	if AddTypes(map[string]interface{}{"@type": 1}, person) == nil {
+		test.Errorf("AddTypes should reject a bad @type")
+	}
+}
@@ -0,0 +1,297 @@
+package jld
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+)
+
+/*
+A Shape describes the nodes that a service accepts, e.g. a canonicalized request payload, in place of ad hoc Get
+checks: the types a node may have and, for each of its properties, how many values it must have, the datatype of
+its literal values and the types of its node values. For example:
+
+	personShape := jld.NewShape(personT).
+		Property(nameP, jld.Required(), jld.MaxCount(1), jld.ValueType(xsdStringT)).
+		Property(employerP, jld.OfTypes(orgT))
+
+	if violations := personShape.Validate(node); len(violations) > 0 {
+		...
+	}
+
+Validate reports every violation rather than the first, so a client can be told all that is wrong with its payload.
+A property's values are counted as JSON LD counts them: a set is its items and a list is one value (whose items are
+checked by ValueType and OfTypes). A Shape is not changed once it is built, so it may be shared.
+
+Node references are resolved by a Graph provided with In; without one, a node reference satisfies OfTypes, since
+its types are not known.
+*/
+type Shape struct {
+	types []TypeID
+	props []propertyShape
+	graph *Graph
+}
+
+//A propertyShape is the rules of a property of a Shape
+type propertyShape struct {
+	propID    PropID
+	minCount  int
+	maxCount  int
+	valueType TypeID
+	nodeTypes []TypeID
+}
+
+//A PropertyRule constrains the values of a property of a Shape
+type PropertyRule func(*propertyShape)
+
+/*
+A Violation is a node's violation of a Shape: the @id of the node (which is "" for a blank node), the property whose
+rule it violates (which is "" for a violation of the Shape's types) and a description.
+*/
+type Violation struct {
+	ID      string
+	PropID  PropID
+	Message string
+}
+
+/*
+String returns the Violation as a message, e.g. "https://ex.org/ann https://ex.org/vocab#name: 0 values, at least 1
+required".
+*/
+func (v Violation) String() string {
+	var parts []string
+
+	if v.ID != "" {
+		parts = append(parts, v.ID)
+	}
+	if v.PropID != "" {
+		parts = append(parts, v.PropID.URI()+":")
+	} else if len(parts) > 0 {
+		parts[0] += ":"
+	}
+	return strings.Join(append(parts, v.Message), " ")
+}
+
+/*
+NewShape creates a Shape of the nodes of any of the types; with none, a node may have any type.
+*/
+func NewShape(t ...TypeID) *Shape {
+	return &Shape{types: t}
+}
+
+/*
+Property returns a Shape that also constrains a property with the rules. A property without rules may have any
+values.
+*/
+func (s *Shape) Property(propID PropID, rules ...PropertyRule) *Shape {
+	var (
+		ps    = propertyShape{propID: propID}
+		shape = *s
+	)
+
+	for _, rule := range rules {
+		rule(&ps)
+	}
+	shape.props = append(append([]propertyShape{}, s.props...), ps)
+	return &shape
+}
+
+/*
+In returns a Shape that resolves the node references of the nodes it validates in a Graph.
+*/
+func (s *Shape) In(g *Graph) *Shape {
+	var shape = *s
+
+	shape.graph = g
+	return &shape
+}
+
+/*
+Required requires a property to have a value.
+*/
+func Required() PropertyRule {
+	return MinCount(1)
+}
+
+/*
+MinCount requires a property to have at least n values.
+*/
+func MinCount(n int) PropertyRule {
+	return func(ps *propertyShape) {
+		ps.minCount = n
+	}
+}
+
+/*
+MaxCount allows a property at most n values; e.g. MaxCount(1) makes it single valued. 0 is no limit.
+*/
+func MaxCount(n int) PropertyRule {
+	return func(ps *propertyShape) {
+		ps.maxCount = n
+	}
+}
+
+/*
+ValueType requires the values of a property to be literals of a datatype: value objects of the type or, for the XML
+Schema string, boolean and numeric datatypes, JSON primitives of a matching kind (e.g. a JSON string for xsd:string
+and a number with no fractional part for xsd:integer).
+*/
+func ValueType(t TypeID) PropertyRule {
+	return func(ps *propertyShape) {
+		ps.valueType = t
+	}
+}
+
+/*
+OfTypes requires the values of a property to be nodes, or node references, of any of the types.
+*/
+func OfTypes(t ...TypeID) PropertyRule {
+	return func(ps *propertyShape) {
+		ps.nodeTypes = t
+	}
+}
+
+/*
+Validate returns the violations of the Shape by a node, or nil if it has none.
+*/
+func (s *Shape) Validate(input interface{}) []Violation {
+	var (
+		node       map[string]interface{}
+		id         string
+		violations []Violation
+		ok         bool
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok || !isNode(node) {
+		return []Violation{{Message: fmt.Sprintf("%T is not a node", input)}}
+	}
+	id, _ = node["@id"].(string)
+
+	if len(s.types) > 0 && !hasAnyType(node, s.types) {
+		violations = append(violations, Violation{ID: id, Message: fmt.Sprintf("type is not one of %v", typeList(s.types))})
+	}
+	for _, ps := range s.props {
+		values := shapeValues(nil, node[ps.propID.URI()])
+		if len(values) < ps.minCount {
+			violations = append(violations, Violation{ID: id, PropID: ps.propID, Message: fmt.Sprintf("%v values, at least %v required", len(values), ps.minCount)})
+		}
+		if ps.maxCount > 0 && len(values) > ps.maxCount {
+			violations = append(violations, Violation{ID: id, PropID: ps.propID, Message: fmt.Sprintf("%v values, at most %v allowed", len(values), ps.maxCount)})
+		}
+		for _, item := range values {
+			if IsList(item) {
+				for _, listItem := range shapeValues(nil, item.(map[string]interface{})["@list"]) {
+					violations = s.checkValue(violations, id, ps, listItem)
+				}
+				continue
+			}
+			violations = s.checkValue(violations, id, ps, item)
+		}
+	}
+	return violations
+}
+
+//checkValue appends the violations of the ValueType and OfTypes rules of a property by one of its values
+func (s *Shape) checkValue(violations []Violation, id string, ps propertyShape, item interface{}) []Violation {
+	if ps.valueType != "" && !isOfDatatype(item, ps.valueType) {
+		violations = append(violations, Violation{ID: id, PropID: ps.propID, Message: fmt.Sprintf("%v is not a %v", valueOf(item), ps.valueType.URI())})
+	}
+	if len(ps.nodeTypes) > 0 {
+		nodes := Q(item).In(s.graph).N().Nodes()
+		switch {
+		case len(nodes) == 0:
+			violations = append(violations, Violation{ID: id, PropID: ps.propID, Message: fmt.Sprintf("%v is not a node", valueOf(item))})
+		case IsNref(nodes[0]):
+		case !hasAnyType(nodes[0], ps.nodeTypes):
+			ref, _ := nodes[0]["@id"].(string)
+			violations = append(violations, Violation{ID: id, PropID: ps.propID, Message: fmt.Sprintf("node %v type is not one of %v", ref, typeList(ps.nodeTypes))})
+		}
+	}
+	return violations
+}
+
+//shapeValues appends the values of a property value to values, flattening arrays and sets but not lists
+func shapeValues(values []interface{}, v interface{}) []interface{} {
+	switch v.(type) {
+	case nil:
+	case []interface{}:
+		for _, item := range v.([]interface{}) {
+			values = shapeValues(values, item)
+		}
+	case map[string]interface{}:
+		if set, ok := v.(map[string]interface{})["@set"]; ok {
+			return shapeValues(values, set)
+		}
+		values = append(values, v)
+	default:
+		values = append(values, v)
+	}
+	return values
+}
+
+//isOfDatatype is true if a value is a literal of the datatype
+func isOfDatatype(item interface{}, t TypeID) bool {
+	var (
+		v  = item
+		vt string
+	)
+
+	if obj, ok := item.(map[string]interface{}); ok {
+		v, ok = obj["@value"]
+		if !ok {
+			return false
+		}
+		switch obj["@type"].(type) {
+		case string:
+			vt = obj["@type"].(string)
+		case TypeID:
+			vt = obj["@type"].(TypeID).URI()
+		}
+		if vt != "" {
+			return vt == t.URI()
+		}
+		if _, ok = obj["@language"]; ok {
+			return false
+		}
+	}
+
+	//A primitive, or a value object without a type, matches the XML Schema datatypes of its kind
+	switch v.(type) {
+	case string:
+		return xsdName(t.URI()) == "string"
+	case bool:
+		return xsdName(t.URI()) == "boolean"
+	case float64:
+		f := v.(float64)
+		return xsdFloats[xsdName(t.URI())] || xsdIntegers[xsdName(t.URI())] && f == math.Trunc(f)
+	case int, int64:
+		return xsdFloats[xsdName(t.URI())] || xsdIntegers[xsdName(t.URI())]
+	case json.Number:
+		_, err := v.(json.Number).Int64()
+		return xsdFloats[xsdName(t.URI())] || xsdIntegers[xsdName(t.URI())] && err == nil
+	default:
+		return false
+	}
+}
+
+//hasAnyType is true if a node has any of the types
+func hasAnyType(node map[string]interface{}, types []TypeID) bool {
+	for _, t := range types {
+		if hasType(node, t) {
+			return true
+		}
+	}
+	return false
+}
+
+//typeList returns the URIs of types separated by commas
+func typeList(types []TypeID) string {
+	var uris = make([]string, 0, len(types))
+
+	for _, t := range types {
+		uris = append(uris, t.URI())
+	}
+	return strings.Join(uris, ", ")
+}
@@ -0,0 +1,70 @@
+package jld
+
+import (
+	"testing"
+)
+
+func TestShape(test *testing.T) {
+	var (
+		personT  = NewTypeID("https://ex.org/types#Person", "")
+		orgT     = NewTypeID("https://ex.org/types#Org", "")
+		stringT  = NewTypeID(xsdBase+"string", "")
+		integerT = NewTypeID(xsdBase+"integer", "")
+		nameP    = NewPropID("https://ex.org/vocab#name", "")
+		ageP     = NewPropID("https://ex.org/vocab#age", "")
+		employer = NewPropID("https://ex.org/vocab#employer", "")
+		tagsP    = NewPropID("https://ex.org/vocab#tags", "")
+		shape    = NewShape(personT).
+				Property(nameP, Required(), MaxCount(1), ValueType(stringT)).
+				Property(ageP, ValueType(integerT)).
+				Property(employer, OfTypes(orgT)).
+				Property(tagsP, MaxCount(1), ValueType(stringT))
+		good = NewN("https://ex.org/ann", personT)
+		bad  = NewN("https://ex.org/bob", orgT)
+	)
+
+	good[nameP.URI()] = "Ann"
+	good[ageP.URI()] = []interface{}{map[string]interface{}{"@value": "42", "@type": integerT.URI()}}
+	good[employer.URI()] = NewN("https://ex.org/acme", orgT)
+	good[tagsP.URI()] = NewL([]interface{}{"a", "b"})
+	if violations := shape.Validate(good); len(violations) != 0 {
+		test.Errorf("Validate good: %v", violations)
+	}
+
+	bad[ageP.URI()] = 41.5
+	bad[employer.URI()] = []interface{}{NewN("https://ex.org/carol", personT), "acme"}
+	bad[tagsP.URI()] = NewL([]interface{}{"a", 1})
+	violations := shape.Validate(bad)
+	expected := []string{
+		"https://ex.org/bob: type is not one of https://ex.org/types#Person",
+		"https://ex.org/bob https://ex.org/vocab#name: 0 values, at least 1 required",
+		"https://ex.org/bob https://ex.org/vocab#age: 41.5 is not a " + integerT.URI(),
+		"https://ex.org/bob https://ex.org/vocab#employer: node https://ex.org/carol type is not one of https://ex.org/types#Org",
+		"https://ex.org/bob https://ex.org/vocab#employer: acme is not a node",
+		"https://ex.org/bob https://ex.org/vocab#tags: 1 is not a " + stringT.URI(),
+	}
+	if len(violations) != len(expected) {
+		test.Fatalf("Validate bad: %v", violations)
+	}
+	for i, v := range violations {
+		if v.String() != expected[i] {
+			test.Errorf("Violation %v: %v", i, v)
+		}
+	}
+
+	//A node reference is resolved in the Graph of In
+	ref := NewN("https://ex.org/dan", personT)
+	ref[nameP.URI()] = "Dan"
+	ref[employer.URI()] = map[string]interface{}{"@id": "https://ex.org/carol"}
+	if violations = shape.Validate(ref); len(violations) != 0 {
+		test.Errorf("Validate without a Graph: %v", violations)
+	}
+	g, _ := NewGraph([]interface{}{NewN("https://ex.org/carol", personT)})
+	if violations = shape.In(g).Validate(ref); len(violations) != 1 {
+		test.Errorf("Validate in a Graph: %v", violations)
+	}
+
+	if violations = shape.Validate("ann"); len(violations) != 1 {
+		test.Errorf("Validate of a non-node: %v", violations)
+	}
+}
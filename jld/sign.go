@@ -0,0 +1,257 @@
+package jld
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"github.com/develrns/resilient/clock"
+)
+
+//proofBase is the base of the proof types of Sign, which are specific to this package
+const proofBase = "https://github.com/develrns/resilient/jld/proof#"
+
+var (
+	//SecBase is the Linked Data Security vocabulary base
+	SecBase = NewPropBase("https://w3id.org/security#")
+
+	//SecTypeBase is the Linked Data Security vocabulary type base
+	SecTypeBase = NewTypeBase("https://w3id.org/security#")
+
+	//ProofP is the property of a signed node that holds its proof node
+	ProofP = NewPropID("proof", SecBase)
+
+	//ProofValueP is the property of a proof node that holds the b64URL encoded signature
+	ProofValueP = NewPropID("proofValue", SecBase)
+
+	//VerificationMethodP is the property of a proof node that references the signer's key
+	VerificationMethodP = NewPropID("verificationMethod", SecBase)
+
+	//CreatedP is the property of a proof node that holds its creation time
+	CreatedP = NewPropID("http://purl.org/dc/terms/created", "")
+
+	//Ed25519ProofT is the type of a proof created with an Ed25519 key
+	Ed25519ProofT = NewTypeID(proofBase+"Ed25519Signature", "")
+
+	//RsaProofT is the type of a proof created with an RSA key
+	RsaProofT = NewTypeID(proofBase+"RsaSignature", "")
+)
+
+//signClock is the Clock of the proof creation times, which tests may replace with SetSignClock
+var signClock = struct {
+	m sync.Mutex
+	c clock.Clock
+}{c: clock.Real}
+
+/*
+SetSignClock replaces the Clock of the creation times of Sign's proofs, e.g. with a clock.Fake in a test. It returns the
+replaced Clock.
+*/
+func SetSignClock(c clock.Clock) clock.Clock {
+	signClock.m.Lock()
+	defer signClock.m.Unlock()
+	c, signClock.c = signClock.c, c
+	return c
+}
+
+//signNow returns the time of the Clock of Sign
+func signNow() time.Time {
+	signClock.m.Lock()
+	defer signClock.m.Unlock()
+	return signClock.c.Now()
+}
+
+/*
+Sign attaches a proof node to a node as the value of its ProofP property, so that the node can be verified by Verify.
+The key may be an ed25519.PrivateKey or an *rsa.PrivateKey. The verificationMethod is the IRI of the signer's public
+key; it is included in the proof so the verifier can select the key.
+
+The proof is in the style of a Linked Data Signature, but its format is specific to this package, so its types are
+Ed25519ProofT and RsaProofT rather than those of a standard suite (e.g. Ed25519Signature2018, whose signature is a
+detached JWS), and a standard verifier does not accept it. The proof node has:
+
+	@type			- Ed25519ProofT or RsaProofT
+	CreatedP		- the xsd:dateTime of the signature, in seconds
+	VerificationMethodP	- a reference to the verificationMethod
+	ProofValueP		- the base64url (with padding) encoded signature
+
+The signed message is the concatenation of the SHA-256 hashes of the URDNA2015 normalizations of the proof options
+(the proof without its proofValue) and of the node without its proof. It is signed with Ed25519 or with RSA PKCS #1
+v1.5 of its SHA-256 hash. An existing proof is replaced.
+*/
+func Sign(input interface{}, key crypto.Signer, verificationMethod string) error {
+	var (
+		node      map[string]interface{}
+		proof     map[string]interface{}
+		proofType TypeID
+		message   []byte
+		digest    [32]byte
+		signature []byte
+		ok        bool
+		err       error
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return notANode(input)
+	}
+
+	switch key.Public().(type) {
+	case ed25519.PublicKey:
+		proofType = Ed25519ProofT
+	case *rsa.PublicKey:
+		proofType = RsaProofT
+	default:
+		return nodeError(ErrBadProof, node, "", "%T is not a supported signing key", key.Public())
+	}
+
+	proof = map[string]interface{}{
+		"@type":                   proofType.URI(),
+		CreatedP.URI():            NewV(xsdDateTime, signNow().UTC().Format(time.RFC3339)),
+		VerificationMethodP.URI(): map[string]interface{}{"@id": verificationMethod},
+	}
+
+	message, err = signingInput(node, proof)
+	if err != nil {
+		return err
+	}
+
+	switch proofType {
+	case Ed25519ProofT:
+		signature, err = key.Sign(rand.Reader, message, crypto.Hash(0))
+	default:
+		digest = sha256.Sum256(message)
+		signature, err = key.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		return err
+	}
+
+	proof[ProofValueP.URI()] = base64.URLEncoding.EncodeToString(signature)
+	node[ProofP.URI()] = proof
+	return nil
+}
+
+/*
+Verify verifies the proof node attached to a node by Sign. The key must be the ed25519.PublicKey or *rsa.PublicKey of
+the proof's verification method; use GetProofMethod to select it. A node without a proof is an ErrNoProof NodeError,
+and a proof that is not of Sign's format, is not signed by the key or does not sign the node is an ErrBadProof one.
+*/
+func Verify(input interface{}, key crypto.PublicKey) error {
+	var (
+		node       map[string]interface{}
+		proof      map[string]interface{}
+		options    map[string]interface{}
+		proofValue string
+		message    []byte
+		digest     [32]byte
+		signature  []byte
+		ok         bool
+		err        error
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return notANode(input)
+	}
+	proof, ok = GetN(node, ProofP)
+	if !ok {
+		return nodeError(ErrNoProof, node, Pointer(ProofP.URI()), "the node has no proof node")
+	}
+	proofValue, ok = GetString(proof, ProofValueP)
+	if !ok {
+		return nodeError(ErrBadProof, node, Pointer(ProofP.URI(), ProofValueP.URI()), "the proof has no proof value")
+	}
+	signature, err = base64.URLEncoding.DecodeString(proofValue)
+	if err != nil {
+		return nodeError(ErrBadProof, node, Pointer(ProofP.URI(), ProofValueP.URI()), "%v", err)
+	}
+
+	//The proof options are the proof without its proofValue
+	options = make(map[string]interface{}, len(proof))
+	for k, v := range proof {
+		if k != ProofValueP.URI() {
+			options[k] = v
+		}
+	}
+	message, err = signingInput(node, options)
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case IsType(proof, Ed25519ProofT):
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return nodeError(ErrBadProof, node, Pointer(ProofP.URI()), "an Ed25519 proof requires an Ed25519 key, not %T", key)
+		}
+		if !ed25519.Verify(pub, message, signature) {
+			return nodeError(ErrBadProof, node, Pointer(ProofP.URI()), "the signature does not verify")
+		}
+		return nil
+	case IsType(proof, RsaProofT):
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return nodeError(ErrBadProof, node, Pointer(ProofP.URI()), "an RSA proof requires an RSA key, not %T", key)
+		}
+		digest = sha256.Sum256(message)
+		err = rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], signature)
+		if err != nil {
+			return nodeError(ErrBadProof, node, Pointer(ProofP.URI()), "the signature does not verify: %v", err)
+		}
+		return nil
+	default:
+		return nodeError(ErrBadProof, node, Pointer(ProofP.URI(), "@type"), "%v is not a proof type of Sign", proof["@type"])
+	}
+}
+
+/*
+GetProofMethod returns the verification method IRI of a node's proof so the verifier can look up the public key.
+*/
+func GetProofMethod(input interface{}) (string, bool) {
+	var (
+		proof map[string]interface{}
+		ok    bool
+	)
+
+	proof, ok = GetN(input, ProofP)
+	if !ok {
+		return "", false
+	}
+	return GetNRef(proof[VerificationMethodP.URI()])
+}
+
+//signingInput returns the concatenation of the SHA-256 hashes of the normalized proof options and the normalized node without its proof.
+func signingInput(node, options map[string]interface{}) ([]byte, error) {
+	var (
+		unsigned          = make(map[string]interface{}, len(node))
+		normalized        string
+		optionsHash, hash [32]byte
+		err               error
+	)
+
+	for k, v := range node {
+		if k != ProofP.URI() {
+			unsigned[k] = v
+		}
+	}
+
+	normalized, err = Normalize(options)
+	if err != nil {
+		return nil, err
+	}
+	optionsHash = sha256.Sum256([]byte(normalized))
+
+	normalized, err = Normalize(unsigned)
+	if err != nil {
+		return nil, err
+	}
+	hash = sha256.Sum256([]byte(normalized))
+
+	return append(optionsHash[:], hash[:]...), nil
+}
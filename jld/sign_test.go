@@ -0,0 +1,89 @@
+package jld
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/develrns/resilient/clock"
+)
+
+func TestSignVerify(test *testing.T) {
+	var (
+		personT  = NewTypeID("https://ex.org/types#Person", "")
+		nameP    = NewPropID("https://ex.org/vocab#name", "")
+		created  = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		edKey    = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+		rsaKey   *rsa.PrivateKey
+		keys     []crypto.Signer
+		replaced = SetSignClock(clock.NewFake(created))
+		err      error
+	)
+	defer SetSignClock(replaced)
+
+	rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		test.Fatalf("GenerateKey: %v", err)
+	}
+	keys = []crypto.Signer{edKey, rsaKey}
+
+	for i, key := range keys {
+		node := NewN("https://ex.org/ann", personT)
+		node[nameP.URI()] = "Ann"
+
+		err = Sign(node, key, "https://ex.org/keys#1")
+		if err != nil {
+			test.Fatalf("Sign %T: %v", key, err)
+		}
+		proof, _ := GetN(node, ProofP)
+		if v, _ := GetVtype(proof, CreatedP, xsdDateTime); v != "2020-01-02T03:04:05Z" {
+			test.Errorf("Sign %T created: %v", key, proof[CreatedP.URI()])
+		}
+		if method, ok := GetProofMethod(node); !ok || method != "https://ex.org/keys#1" {
+			test.Errorf("GetProofMethod %T: %v", key, method)
+		}
+		err = Verify(node, key.Public())
+		if err != nil {
+			test.Errorf("Verify %T: %v", key, err)
+		}
+
+		//A changed node, a changed proof and another key do not verify
+		node[nameP.URI()] = "Bob"
+		if err = Verify(node, key.Public()); !errors.Is(err, ErrBadProof) {
+			test.Errorf("Verify %T of a changed node: %v", key, err)
+		}
+		node[nameP.URI()] = "Ann"
+		proof[CreatedP.URI()] = NewV(xsdDateTime, "2021-01-02T03:04:05Z")
+		if err = Verify(node, key.Public()); !errors.Is(err, ErrBadProof) {
+			test.Errorf("Verify %T of a changed proof: %v", key, err)
+		}
+		proof[CreatedP.URI()] = NewV(xsdDateTime, "2020-01-02T03:04:05Z")
+		if err = Verify(node, key.Public()); err != nil {
+			test.Errorf("Verify %T of the restored node: %v", key, err)
+		}
+		for j, other := range keys {
+			if j != i {
+				if err = Verify(node, other.Public()); !errors.Is(err, ErrBadProof) {
+					test.Errorf("Verify %T with a %T key: %v", key, other, err)
+				}
+			}
+		}
+	}
+
+	//A proof of a standard suite is not accepted as one of Sign's
+	node := NewN("https://ex.org/ann", personT)
+	Sign(node, edKey, "https://ex.org/keys#1")
+	proof, _ := GetN(node, ProofP)
+	proof["@type"] = "https://w3id.org/security#Ed25519Signature2018"
+	if err = Verify(node, edKey.Public()); !errors.Is(err, ErrBadProof) {
+		test.Errorf("Verify of an Ed25519Signature2018 proof: %v", err)
+	}
+
+	if err = Verify(NewN("https://ex.org/ann", personT), edKey.Public()); !errors.Is(err, ErrNoProof) {
+		test.Errorf("Verify of a node without a proof: %v", err)
+	}
+}
@@ -0,0 +1,131 @@
+package jld
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+A Size is the approximate in memory footprint of an unmarshalled JSON LD document and the number of its nodes and
+edges (node valued property values, including node references).
+*/
+type Size struct {
+	Bytes int64 `json:"bytes"`
+	Nodes int   `json:"nodes"`
+	Edges int   `json:"edges"`
+}
+
+//The approximate sizes in bytes of the parts of an unmarshalled document on a 64 bit platform
+const (
+	interfaceBytes = 16
+	stringBytes    = 16
+	sliceBytes     = 24
+	mapBytes       = 48
+	mapEntryBytes  = stringBytes + interfaceBytes + 8
+	numberBytes    = 8
+)
+
+/*
+SizeOf estimates the in memory footprint of an unmarshalled JSON LD document and counts its nodes and edges, so that
+a service can enforce admission limits before canonicalizing a large submitted graph; its cost is linear in the size
+of the document. The estimate is of the document as unmarshalled by encoding/json; canonicalization typically needs
+several times as much. A document nested deeper than the Graph nesting limit is rejected with an error.
+*/
+func SizeOf(doc interface{}) (Size, error) {
+	var (
+		size Size
+		err  error
+	)
+
+	err = sizeOf(doc, &size, 0, false, false)
+	return size, err
+}
+
+//sizeOf recursively adds the size of a document to size; edge is true if it is a property value
+func sizeOf(input interface{}, size *Size, depth int, inContext, edge bool) error {
+	var err error
+
+	if depth > maxGraphDepth {
+		return fmt.Errorf("Document nesting exceeds %v", maxGraphDepth)
+	}
+	size.Bytes += interfaceBytes
+
+	switch input.(type) {
+	case string:
+		size.Bytes += stringBytes + int64(len(input.(string)))
+	case float64, int, int64, float32:
+		size.Bytes += numberBytes
+	case []interface{}:
+		size.Bytes += sliceBytes
+		for _, item := range input.([]interface{}) {
+			err = sizeOf(item, size, depth+1, inContext, edge)
+			if err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		size.Bytes += mapBytes
+		if !inContext && isNode(obj) && !isGraphObject(obj) {
+			size.Nodes++
+			if edge {
+				size.Edges++
+			}
+		}
+		for k, v := range obj {
+			size.Bytes += mapEntryBytes + int64(len(k))
+			switch {
+			case inContext || k == "@context":
+				err = sizeOf(v, size, depth+1, true, false)
+			case k == "@list" || k == "@set":
+				err = sizeOf(v, size, depth+1, false, edge)
+			case k == "@reverse":
+				err = sizeOfReverse(v, size, depth+1)
+			case strings.HasPrefix(k, "@"):
+				err = sizeOf(v, size, depth+1, false, false)
+			default:
+				err = sizeOf(v, size, depth+1, false, true)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+//sizeOfReverse adds the size of a @reverse map, whose property values are edges, to size
+func sizeOfReverse(input interface{}, size *Size, depth int) error {
+	var (
+		reverse map[string]interface{}
+		ok      bool
+		err     error
+	)
+
+	reverse, ok = input.(map[string]interface{})
+	if !ok {
+		return sizeOf(input, size, depth, false, false)
+	}
+	size.Bytes += interfaceBytes + mapBytes
+	for k, v := range reverse {
+		size.Bytes += mapEntryBytes + int64(len(k))
+		err = sizeOf(v, size, depth+1, false, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//isGraphObject is true if an object only wraps a default graph, i.e. has no keys but @context and @graph
+func isGraphObject(obj map[string]interface{}) bool {
+	if _, ok := obj["@graph"]; !ok {
+		return false
+	}
+	for k := range obj {
+		if k != "@context" && k != "@graph" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package jld
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestSizeOf(test *testing.T) {
+	var (
+		doc  interface{}
+		size Size
+		err  error
+	)
+
+	err = json.Unmarshal([]byte(`{
+		"@context": {"knows": {"@id": "https://ex.org/vocab#knows", "@type": "@id"}},
+		"@graph": [
+			{"@id": "https://ex.org/a", "knows": [{"@id": "https://ex.org/b"}, {"@id": "https://ex.org/c", "name": "C"}]},
+			{"@id": "https://ex.org/d", "tags": {"@list": [{"@id": "https://ex.org/e"}, "x"]}, "@reverse": {"knows": {"@id": "https://ex.org/f"}}}
+		]
+	}`), &doc)
+	if err != nil {
+		test.Fatal(err)
+	}
+	size, err = SizeOf(doc)
+	if err != nil || size.Nodes != 6 || size.Edges != 4 || size.Bytes < 500 {
+		test.Errorf("SizeOf: %+v %v", size, err)
+	}
+
+	var deep interface{} = "x"
+	for i := 0; i <= maxGraphDepth; i++ {
+		deep = []interface{}{deep}
+	}
+	if _, err = SizeOf(deep); err == nil {
+		test.Errorf("SizeOf: no nesting error")
+	}
+}
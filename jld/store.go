@@ -0,0 +1,100 @@
+package jld
+
+import (
+	"sync"
+)
+
+/*
+A Store is an in-memory store of nodes, such as the output of Canonicalize, keyed by @id, that is safe for
+concurrent use: e.g. a service's cache of the nodes it serves. It is backed by a Graph index, guarded by a
+sync.RWMutex so that concurrent readers do not block each other.
+
+A Store keeps its own copies of the nodes that are Put and returns copies, so the nodes its callers change (GetSet
+and other helpers normalize their input in place) are not shared with other goroutines. Nodes embedded in a stored
+node are part of its value; they are not stored, or returned by Get, by their own @id.
+*/
+type Store struct {
+	m sync.RWMutex
+	g *Graph
+}
+
+/*
+NewStore creates an empty Store.
+*/
+func NewStore() *Store {
+	var g, _ = NewGraph(nil)
+
+	return &Store{g: g}
+}
+
+/*
+Put stores a copy of a node, replacing any node with its @id. The node must have an @id and must not be a node
+reference.
+*/
+func (s *Store) Put(node map[string]interface{}) error {
+	var id, _ = node["@id"].(string)
+
+	if id == "" {
+		return nodeError(ErrBadID, node, "", "a stored node must have an @id")
+	}
+	if IsNref(node) {
+		return nodeError(ErrNotANode, node, "", "a node reference cannot be stored")
+	}
+	node = DeepCopy(node).(map[string]interface{})
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.g.put(id, node)
+	return nil
+}
+
+/*
+Get returns a copy of the node with the @id.
+*/
+func (s *Store) Get(id string) (map[string]interface{}, bool) {
+	var (
+		node map[string]interface{}
+		ok   bool
+	)
+
+	s.m.RLock()
+	defer s.m.RUnlock()
+	node, ok = s.g.GetByID(id)
+	if !ok {
+		return nil, false
+	}
+	return DeepCopy(node).(map[string]interface{}), true
+}
+
+/*
+Delete removes the node with the @id and returns whether there was one.
+*/
+func (s *Store) Delete(id string) bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.g.remove(id)
+}
+
+/*
+Query returns copies of the nodes of type t, which it finds with the type index of the Store's Graph, in the order
+they were first Put with the type.
+*/
+func (s *Store) Query(t TypeID) []map[string]interface{} {
+	var nodes []map[string]interface{}
+
+	s.m.RLock()
+	defer s.m.RUnlock()
+	for _, node := range s.g.GetAllOfType(t) {
+		nodes = append(nodes, DeepCopy(node).(map[string]interface{}))
+	}
+	return nodes
+}
+
+/*
+Len returns the number of stored nodes.
+*/
+func (s *Store) Len() int {
+	s.m.RLock()
+	defer s.m.RUnlock()
+	return s.g.Len()
+}
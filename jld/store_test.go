@@ -0,0 +1,91 @@
+package jld
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestStore(test *testing.T) {
+	var (
+		personT = NewTypeID("https://ex.org/types#Person", "")
+		orgT    = NewTypeID("https://ex.org/types#Org", "")
+		nameP   = NewPropID("https://ex.org/vocab#name", "")
+		s       = NewStore()
+		ann     = NewN("https://ex.org/ann", personT)
+		node    map[string]interface{}
+		ok      bool
+		err     error
+	)
+
+	ann[nameP.URI()] = "Ann"
+	err = s.Put(ann)
+	if err != nil {
+		test.Fatalf("Put: %v", err)
+	}
+	s.Put(NewN("https://ex.org/acme", orgT))
+	s.Put(NewN("https://ex.org/bob", personT))
+
+	ann[nameP.URI()] = "Changed"
+	node, ok = s.Get("https://ex.org/ann")
+	if !ok || node[nameP.URI()] != "Ann" {
+		test.Errorf("Get did not return the stored copy: %v", node)
+	}
+	node[nameP.URI()] = "Changed"
+	if node, _ = s.Get("https://ex.org/ann"); node[nameP.URI()] != "Ann" {
+		test.Errorf("Get returned the stored node: %v", node)
+	}
+
+	s.Put(NewN("https://ex.org/ann", personT))
+	if node, _ = s.Get("https://ex.org/ann"); node[nameP.URI()] != nil || s.Len() != 3 {
+		test.Errorf("Put did not replace the node: %v", node)
+	}
+
+	people := s.Query(personT)
+	if len(people) != 2 || nodeID(people[0]) != "https://ex.org/ann" || nodeID(people[1]) != "https://ex.org/bob" {
+		test.Errorf("Query: %v", people)
+	}
+
+	if !s.Delete("https://ex.org/ann") || s.Delete("https://ex.org/ann") {
+		test.Errorf("Delete")
+	}
+	if _, ok = s.Get("https://ex.org/ann"); ok || s.Len() != 2 || len(s.Query(personT)) != 1 {
+		test.Errorf("Delete did not remove the node")
+	}
+
+	if err = s.Put(map[string]interface{}{"@type": personT.URI()}); !errors.Is(err, ErrBadID) {
+		test.Errorf("Put of a node without an @id: %v", err)
+	}
+	if err = s.Put(map[string]interface{}{"@id": "https://ex.org/ann"}); !errors.Is(err, ErrNotANode) {
+		test.Errorf("Put of a node reference: %v", err)
+	}
+}
+
+func TestStoreConcurrent(test *testing.T) {
+	var (
+		personT = NewTypeID("https://ex.org/types#Person", "")
+		s       = NewStore()
+		wg      sync.WaitGroup
+	)
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				id := fmt.Sprintf("https://ex.org/p%v-%v", i, j)
+				s.Put(NewN(id, personT))
+				s.Get(id)
+				s.Query(personT)
+				if j%2 == 0 {
+					s.Delete(id)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if s.Len() != 400 || len(s.Query(personT)) != 400 {
+		test.Errorf("Len: %v", s.Len())
+	}
+}
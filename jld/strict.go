@@ -0,0 +1,138 @@
+package jld
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+//keywords are the JSON LD 1.1 keywords
+var keywords = map[string]bool{
+	"@base": true, "@container": true, "@context": true, "@direction": true, "@graph": true, "@id": true,
+	"@import": true, "@included": true, "@index": true, "@json": true, "@language": true, "@list": true, "@nest": true,
+	"@none": true, "@prefix": true, "@propagate": true, "@protected": true, "@reverse": true, "@set": true,
+	"@type": true, "@value": true, "@version": true, "@vocab": true,
+}
+
+/*
+CheckStrict returns a NodeError describing the first violation of the strict rules in an unmarshalled JSON LD document:
+
+  - a key that starts with @ but is not a JSON LD keyword
+  - an @id that is not an absolute IRI or a blank node identifier
+  - a node @type that is not an absolute IRI, compact IRI or blank node identifier
+
+The errors are of the kinds ErrBadKeyword, ErrBadIRI and ErrBadType; the path of an error is the Pointer to the
+violation from the document and its ID the @id of the node it is in.
+
+Term definitions inside @context are not checked, and the document is checked as is, so terms and relative IRIs that
+a @context would expand are rejected. Services that must not accept sloppy partner payloads should call it on
+documents built with NewN and AddN, and pass the Strict option to Canonicalize, Expand and Compact, which check the
+keywords of the input and the IRIs of its expansion.
+*/
+func CheckStrict(input interface{}) error {
+	return checkStrict(input, "", "", true, true)
+}
+
+/*
+checkStrict recursively checks the keywords and/or the IRIs of a document; path is the Pointer to the input and id
+the @id of the node it is in, which locate it in errors.
+*/
+func checkStrict(input interface{}, path string, id string, kw, iris bool) error {
+	var (
+		obj map[string]interface{}
+		err error
+	)
+
+	switch input.(type) {
+	case []interface{}:
+		for i, item := range input.([]interface{}) {
+			err = checkStrict(item, path+Pointer(strconv.Itoa(i)), id, kw, iris)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		obj = input.(map[string]interface{})
+	default:
+		return nil
+	}
+	if objID, ok := obj["@id"].(string); ok {
+		id = objID
+	}
+
+	for k, v := range obj {
+		switch {
+		case k == "@context":
+			continue
+		case kw && strings.HasPrefix(k, "@") && !keywords[k]:
+			return &NodeError{Kind: ErrBadKeyword, ID: id, Path: path, Detail: k}
+		case k == "@id":
+			if iris {
+				err = checkIRI(v, ErrBadIRI, path+Pointer(k), id)
+			}
+		case k == "@type":
+			if _, ok := obj["@value"]; iris && !ok {
+				err = checkTypes(v, path+Pointer(k), id)
+			}
+		default:
+			err = checkStrict(v, path+Pointer(k), id, kw, iris)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+//checkTypes checks that a node @type is an IRI or an array of IRIs
+func checkTypes(v interface{}, path string, id string) error {
+	var err error
+
+	switch v.(type) {
+	case []interface{}:
+		for i, t := range v.([]interface{}) {
+			err = checkIRI(t, ErrBadType, path+Pointer(strconv.Itoa(i)), id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case []string:
+		for i, t := range v.([]string) {
+			err = checkIRI(t, ErrBadType, path+Pointer(strconv.Itoa(i)), id)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	case TypeID:
+		return checkIRI(string(v.(TypeID)), ErrBadType, path, id)
+	default:
+		return checkIRI(v, ErrBadType, path, id)
+	}
+}
+
+//checkIRI checks that a value is an absolute (or compact) IRI or a blank node identifier, returning an error of a kind
+func checkIRI(v interface{}, kind error, path string, id string) error {
+	var (
+		iri string
+		u   *url.URL
+		ok  bool
+		err error
+	)
+
+	iri, ok = v.(string)
+	if !ok {
+		return &NodeError{Kind: kind, ID: id, Path: path, Detail: fmt.Sprintf("%v of type %T is not an IRI", v, v)}
+	}
+	if strings.HasPrefix(iri, "_:") {
+		return nil
+	}
+	u, err = url.Parse(iri)
+	if err != nil || !u.IsAbs() {
+		return &NodeError{Kind: kind, ID: id, Path: path, Detail: fmt.Sprintf("%v is a relative IRI", iri)}
+	}
+	return nil
+}
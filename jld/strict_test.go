@@ -0,0 +1,40 @@
+package jld
+
+import (
+	"testing"
+)
+
+func TestCheckStrict(test *testing.T) {
+	var (
+		good = NewN("https://ex.org/ann", NewTypeID("https://ex.org/types#Person", ""))
+		err  error
+	)
+
+	good["https://ex.org/vocab#knows"] = []interface{}{map[string]interface{}{"@id": "_:b0"}}
+	good["https://ex.org/vocab#age"] = []interface{}{map[string]interface{}{"@type": "xsd:integer", "@value": "42"}}
+	err = CheckStrict(good)
+	if err != nil {
+		test.Errorf("CheckStrict good: %v", err)
+	}
+
+	err = CheckStrict(map[string]interface{}{"@id": "ann"})
+	if err == nil {
+		test.Errorf("CheckStrict should reject a relative @id")
+	}
+	err = CheckStrict(map[string]interface{}{"@id": "https://ex.org/ann", "@type": []interface{}{"Person"}})
+	if err == nil {
+		test.Errorf("CheckStrict should reject a relative @type")
+	}
+	err = CheckStrict(map[string]interface{}{"@id": "https://ex.org/ann", "@type": []interface{}{42}})
+	if err == nil {
+		test.Errorf("CheckStrict should reject a non-IRI @type")
+	}
+	err = CheckStrict([]interface{}{map[string]interface{}{"@id": "https://ex.org/ann", "@frobnicate": true}})
+	if err == nil {
+		test.Errorf("CheckStrict should reject an unknown keyword")
+	}
+	err = CheckStrict(map[string]interface{}{"@context": map[string]interface{}{"@anything": "x"}, "@id": "_:b1"})
+	if err != nil {
+		test.Errorf("CheckStrict should not check @context: %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package jld
+
+import (
+	"encoding/json"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//xsdBase is the XML Schema datatype base
+const xsdBase = "http://www.w3.org/2001/XMLSchema#"
+
+var (
+	//xsdIntegers are the XML Schema integer datatypes
+	xsdIntegers = map[string]bool{
+		"integer": true, "int": true, "long": true, "short": true, "byte": true,
+		"nonNegativeInteger": true, "positiveInteger": true, "nonPositiveInteger": true, "negativeInteger": true,
+		"unsignedLong": true, "unsignedInt": true, "unsignedShort": true, "unsignedByte": true,
+	}
+
+	//xsdFloats are the XML Schema non-integer numeric datatypes
+	xsdFloats = map[string]bool{"decimal": true, "double": true, "float": true}
+)
+
+/*
+typedValue gets the @value and @type of a node's property. A property that is not a value object is returned with
+its registered datatype, if any, and otherwise with an empty type.
+*/
+func typedValue(input interface{}, propID PropID) (interface{}, string, bool) {
+	var (
+		node  map[string]interface{}
+		propI interface{}
+		ok    bool
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return nil, "", false
+	}
+	_, propI, ok = propValue(node, propID)
+	if !ok {
+		return nil, "", false
+	}
+	return typedItem(propI, propID)
+}
+
+//typedItem gets the @value and @type of a value of a property, as typedValue does
+func typedItem(propI interface{}, propID PropID) (interface{}, string, bool) {
+	var (
+		valobj map[string]interface{}
+		t      string
+		ok     bool
+	)
+
+	valobj, ok = propI.(map[string]interface{})
+	if !ok {
+		if typeID, registered := Datatype(propID); registered && isPrimitive(propI) {
+			return propI, typeID.URI(), true
+		}
+		return propI, "", true
+	}
+	propI, ok = valobj["@value"]
+	if !ok {
+		return nil, "", false
+	}
+	switch valobj["@type"].(type) {
+	case string:
+		t = valobj["@type"].(string)
+	case TypeID:
+		t = valobj["@type"].(TypeID).URI()
+	}
+	return propI, t, true
+}
+
+//xsdName returns the local name of an XML Schema datatype (in full or xsd: compact form) or "" if it is not one
+func xsdName(t string) string {
+	switch {
+	case strings.HasPrefix(t, xsdBase):
+		return strings.TrimPrefix(t, xsdBase)
+	case strings.HasPrefix(t, "xsd:"):
+		return strings.TrimPrefix(t, "xsd:")
+	default:
+		return ""
+	}
+}
+
+/*
+GetInt gets the property of a node if it is an integer: a JSON number (including a json.Number from a Decoder with
+UseNumber) with no fractional part, or a value object of an XML Schema integer datatype such as xsd:integer, whose
+value may be a string.
+*/
+func GetInt(input interface{}, propID PropID) (int64, bool) {
+	var (
+		propI interface{}
+		t     string
+		ok    bool
+	)
+
+	propI, t, ok = typedValue(input, propID)
+	if !ok {
+		return 0, false
+	}
+	return intItem(propI, t)
+}
+
+//intItem converts the @value and @type of a value, as returned by typedItem, as GetInt does
+func intItem(propI interface{}, t string) (int64, bool) {
+	var (
+		i   int64
+		err error
+	)
+
+	if t != "" && !xsdIntegers[xsdName(t)] {
+		return 0, false
+	}
+	switch propI.(type) {
+	case int:
+		return int64(propI.(int)), true
+	case int64:
+		return propI.(int64), true
+	case float64:
+		i = int64(propI.(float64))
+		if float64(i) != propI.(float64) || math.Abs(propI.(float64)) > 1<<53 {
+			return 0, false
+		}
+		return i, true
+	case json.Number:
+		i, err = propI.(json.Number).Int64()
+	case string:
+		if t == "" {
+			return 0, false
+		}
+		i, err = strconv.ParseInt(propI.(string), 10, 64)
+	default:
+		return 0, false
+	}
+	if err != nil {
+		return 0, false
+	}
+	return i, true
+}
+
+/*
+GetFloat gets the property of a node if it is a number: a JSON number (including a json.Number), or a value object
+of an XML Schema numeric datatype such as xsd:double or xsd:integer, whose value may be a string.
+*/
+func GetFloat(input interface{}, propID PropID) (float64, bool) {
+	var (
+		propI interface{}
+		t     string
+		ok    bool
+	)
+
+	propI, t, ok = typedValue(input, propID)
+	if !ok {
+		return 0, false
+	}
+	return floatItem(propI, t)
+}
+
+//floatItem converts the @value and @type of a value, as returned by typedItem, as GetFloat does
+func floatItem(propI interface{}, t string) (float64, bool) {
+	var (
+		f   float64
+		err error
+	)
+
+	if t != "" && !xsdIntegers[xsdName(t)] && !xsdFloats[xsdName(t)] {
+		return 0, false
+	}
+	switch propI.(type) {
+	case float64:
+		return propI.(float64), true
+	case float32:
+		return float64(propI.(float32)), true
+	case int:
+		return float64(propI.(int)), true
+	case int64:
+		return float64(propI.(int64)), true
+	case json.Number:
+		f, err = propI.(json.Number).Float64()
+	case string:
+		if t == "" {
+			return 0, false
+		}
+		f, err = strconv.ParseFloat(propI.(string), 64)
+	default:
+		return 0, false
+	}
+	if err != nil {
+		return 0, false
+	}
+	return f, true
+}
+
+/*
+GetTime gets the property of a node if it is a time: a value object of type xsd:dateTime (RFC 3339) or xsd:date
+(a date at midnight UTC), or an RFC 3339 string.
+*/
+func GetTime(input interface{}, propID PropID) (time.Time, bool) {
+	var (
+		propI interface{}
+		t     string
+		ok    bool
+	)
+
+	propI, t, ok = typedValue(input, propID)
+	if !ok {
+		return time.Time{}, false
+	}
+	return timeItem(propI, t)
+}
+
+//timeItem converts the @value and @type of a value, as returned by typedItem, as GetTime does
+func timeItem(propI interface{}, t string) (time.Time, bool) {
+	var (
+		s   string
+		tm  time.Time
+		ok  bool
+		err error
+	)
+
+	if tm, ok = propI.(time.Time); ok {
+		return tm, true
+	}
+	s, ok = propI.(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	switch {
+	case t == "", xsdName(t) == "dateTime":
+		tm, err = time.Parse(time.RFC3339Nano, s)
+	case xsdName(t) == "date":
+		tm, err = time.Parse("2006-01-02", s)
+	default:
+		return time.Time{}, false
+	}
+	if err != nil {
+		return time.Time{}, false
+	}
+	return tm, true
+}
+
+/*
+NewTimeV creates an xsd:dateTime value object of a time formatted as RFC 3339 (with fractional seconds if it has any),
+which GetTimeV and GetTime parse back to the same instant and location offset. Since the value is a typed string it
+round trips through Canonicalize unchanged.
+*/
+func NewTimeV(tm time.Time) map[string]interface{} {
+	return NewV(xsdDateTime, tm.Format(time.RFC3339Nano))
+}
+
+/*
+GetTimeV gets the time of an xsd:dateTime value object, such as one created by NewTimeV. The type may be in full or
+xsd: compact form.
+*/
+func GetTimeV(input interface{}) (time.Time, bool) {
+	var (
+		valobj map[string]interface{}
+		t      string
+		s      string
+		tm     time.Time
+		ok     bool
+		err    error
+	)
+
+	valobj, ok = input.(map[string]interface{})
+	if !ok {
+		return time.Time{}, false
+	}
+	switch valobj["@type"].(type) {
+	case string:
+		t = valobj["@type"].(string)
+	case TypeID:
+		t = valobj["@type"].(TypeID).URI()
+	}
+	if xsdName(t) != "dateTime" {
+		return time.Time{}, false
+	}
+	s, ok = valobj["@value"].(string)
+	if !ok {
+		return time.Time{}, false
+	}
+	tm, err = time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return tm, true
+}
@@ -0,0 +1,107 @@
+package jld
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTypedGetters(test *testing.T) {
+	var (
+		ageP     = NewPropID("https://ex.org/vocab#age", "")
+		heightP  = NewPropID("https://ex.org/vocab#height", "")
+		bornP    = NewPropID("https://ex.org/vocab#born", "")
+		nameP    = NewPropID("https://ex.org/vocab#name", "")
+		node     map[string]interface{}
+		i        int64
+		f        float64
+		tm       time.Time
+		ok       bool
+		expected = time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	)
+
+	node = map[string]interface{}{
+		ageP.URI():    map[string]interface{}{"@type": xsdBase + "integer", "@value": "42"},
+		heightP.URI(): json.Number("1.85"),
+		bornP.URI():   NewV(xsdDateTime, expected.Format(time.RFC3339)),
+		nameP.URI():   map[string]interface{}{"@type": xsdBase + "string", "@value": "42"},
+	}
+
+	i, ok = GetInt(node, ageP)
+	if !ok || i != 42 {
+		test.Errorf("GetInt age: %v %v", i, ok)
+	}
+	f, ok = GetFloat(node, ageP)
+	if !ok || f != 42 {
+		test.Errorf("GetFloat age: %v %v", f, ok)
+	}
+	f, ok = GetFloat(node, heightP)
+	if !ok || f != 1.85 {
+		test.Errorf("GetFloat height: %v %v", f, ok)
+	}
+	_, ok = GetInt(node, heightP)
+	if ok {
+		test.Errorf("GetInt height should not be an integer")
+	}
+	_, ok = GetInt(node, nameP)
+	if ok {
+		test.Errorf("GetInt of an xsd:string should fail")
+	}
+	tm, ok = GetTime(node, bornP)
+	if !ok || !tm.Equal(expected) {
+		test.Errorf("GetTime born: %v %v", tm, ok)
+	}
+	_, ok = GetTime(node, nameP)
+	if ok {
+		test.Errorf("GetTime of an xsd:string should fail")
+	}
+
+	node[ageP.URI()] = float64(7)
+	i, ok = GetInt(node, ageP)
+	if !ok || i != 7 {
+		test.Errorf("GetInt float64: %v %v", i, ok)
+	}
+}
+
+func TestTimeV(test *testing.T) {
+	var (
+		zone     = time.FixedZone("", -5*60*60)
+		expected = time.Date(2001, 2, 3, 4, 5, 6, 789000000, zone)
+		valobj   map[string]interface{}
+		b        []byte
+		tm       time.Time
+		ok       bool
+		err      error
+	)
+
+	valobj = NewTimeV(expected)
+	if valobj["@value"] != "2001-02-03T04:05:06.789-05:00" || !IsVtype(valobj, xsdDateTime) {
+		test.Fatalf("NewTimeV: %v", valobj)
+	}
+
+	//Round trip through JSON, as a canonicalized document would
+	b, err = json.Marshal(valobj)
+	if err != nil {
+		test.Fatal(err)
+	}
+	valobj = nil
+	err = json.Unmarshal(b, &valobj)
+	if err != nil {
+		test.Fatal(err)
+	}
+	tm, ok = GetTimeV(valobj)
+	if !ok || !tm.Equal(expected) || tm.Format(time.RFC3339) != expected.Format(time.RFC3339) {
+		test.Errorf("GetTimeV: %v %v", tm, ok)
+	}
+
+	valobj["@type"] = "xsd:dateTime"
+	if tm, ok = GetTimeV(valobj); !ok || !tm.Equal(expected) {
+		test.Errorf("GetTimeV of a compact type: %v %v", tm, ok)
+	}
+	if _, ok = GetTimeV(NewV(NewTypeID(xsdBase+"string", ""), "2001-02-03T04:05:06Z")); ok {
+		test.Errorf("GetTimeV of an xsd:string should fail")
+	}
+	if _, ok = GetTimeV(NewV(xsdDateTime, "yesterday")); ok {
+		test.Errorf("GetTimeV of a bad time should fail")
+	}
+}
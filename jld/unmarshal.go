@@ -0,0 +1,299 @@
+package jld
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+/*
+Unmarshal populates an annotated struct from a node such as one returned by Canonicalize. The v argument must be a
+pointer to a struct using the jld tags described by Marshal.
+
+Value objects are unwrapped to their @value; a node reference populates only the @id field of a nested struct;
+a set, list or singleton is converted to a slice field; and a numeric value may be a float64, int, json.Number or
+numeric string. If the struct has a jldtype tag, the node must be of that type. Properties without a matching field
+are ignored; fields without a matching property are left unchanged.
+*/
+func Unmarshal(input interface{}, v interface{}) error {
+	var rv = reflect.ValueOf(v)
+
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("Unmarshal requires a non-nil pointer to a struct")
+	}
+	return unmarshalNode(input, rv.Elem())
+}
+
+//unmarshalNode populates a struct value from a node
+func unmarshalNode(input interface{}, rv reflect.Value) error {
+	var (
+		node  map[string]interface{}
+		rt    = rv.Type()
+		propI interface{}
+		tag   jldTag
+		ok    bool
+		err   error
+	)
+
+	node, ok = input.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("Unmarshal of %v requires a node", rt)
+	}
+
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		typeTag := field.Tag.Get("jldtype")
+		if typeTag != "" {
+			if !hasType(node, NewTypeID(typeTag, "")) {
+				return fmt.Errorf("Unmarshal of %v requires a node of type: %v", rt, typeTag)
+			}
+			continue
+		}
+		tag = parseTag(field.Tag.Get("jld"))
+		switch tag.name {
+		case "", "-":
+			continue
+		}
+		if field.PkgPath != "" {
+			return fmt.Errorf("Unmarshal tagged field %v is not exported", field.Name)
+		}
+		propI, ok = node[NewPropID(tag.name, "").URI()]
+		if !ok {
+			continue
+		}
+		err = unmarshalValue(propI, rv.Field(i))
+		if err != nil {
+			return fmt.Errorf("Unmarshal field %v: %w", field.Name, err)
+		}
+	}
+	return nil
+}
+
+//hasType is true if the node's @type is, or contains, the type t
+func hasType(node map[string]interface{}, t TypeID) bool {
+	var types, _ = nodeTypes(node)
+
+	for _, typeURI := range types {
+		if typeURI == t.URI() {
+			return true
+		}
+	}
+	return false
+}
+
+//unmarshalValue sets a field from a property value
+func unmarshalValue(propI interface{}, fv reflect.Value) error {
+	var (
+		slice []interface{}
+		ok    bool
+		err   error
+	)
+
+	if propI == nil {
+		return nil
+	}
+
+	switch fv.Kind() {
+	case reflect.Interface:
+		if !reflect.TypeOf(propI).AssignableTo(fv.Type()) {
+			return nodeError(ErrBadValue, nil, "", "%T cannot be set in a field of type %v", propI, fv.Type())
+		}
+		fv.Set(reflect.ValueOf(propI))
+		return nil
+	case reflect.Ptr:
+		if fv.IsNil() {
+			fv.Set(reflect.New(fv.Type().Elem()))
+		}
+		return unmarshalValue(propI, fv.Elem())
+	case reflect.Map:
+		if fv.Type() != nodeType {
+			return fmt.Errorf("unsupported map type: %v", fv.Type())
+		}
+		if !reflect.TypeOf(propI).AssignableTo(fv.Type()) {
+			return nodeError(ErrBadValue, nil, "", "%T cannot be set in a field of type %v", propI, fv.Type())
+		}
+		fv.Set(reflect.ValueOf(propI))
+		return nil
+	case reflect.Slice:
+		if IsList(propI) {
+			propI = propI.(map[string]interface{})["@list"]
+		}
+		slice, ok = propI.([]interface{})
+		if !ok {
+			slice = []interface{}{propI}
+		}
+		fv.Set(reflect.MakeSlice(fv.Type(), len(slice), len(slice)))
+		for j, item := range slice {
+			err = unmarshalValue(item, fv.Index(j))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	//A singleton set may be unmarshaled into a non-slice field
+	slice, ok = propI.([]interface{})
+	if ok {
+		if len(slice) != 1 {
+			return fmt.Errorf("set of %v values for a single valued field", len(slice))
+		}
+		propI = slice[0]
+	}
+	if IsList(propI) {
+		return fmt.Errorf("list for a non-slice field")
+	}
+
+	if fv.Kind() == reflect.Struct {
+		if fv.Type() == timeType {
+			return unmarshalTime(propI, fv)
+		}
+		if IsNref(propI) {
+			return unmarshalRef(propI, fv)
+		}
+		return unmarshalNode(propI, fv)
+	}
+	return unmarshalPrimitive(valueOf(propI), fv)
+}
+
+//valueOf returns the @value of a value object or the input itself if it is not a value object
+func valueOf(input interface{}) interface{} {
+	var (
+		valobj map[string]interface{}
+		ok     bool
+	)
+
+	valobj, ok = input.(map[string]interface{})
+	if !ok {
+		return input
+	}
+	if _, ok = valobj["@value"]; !ok {
+		return input
+	}
+	return valobj["@value"]
+}
+
+//unmarshalRef sets the @id field of a struct from a node reference
+func unmarshalRef(input interface{}, fv reflect.Value) error {
+	var (
+		id = input.(map[string]interface{})["@id"]
+		rt = fv.Type()
+	)
+
+	for i := 0; i < rt.NumField(); i++ {
+		if parseTag(rt.Field(i).Tag.Get("jld")).name == "@id" {
+			return unmarshalPrimitive(id, fv.Field(i))
+		}
+	}
+	return nil
+}
+
+//unmarshalTime sets a time.Time field from an RFC3339 string or value object
+func unmarshalTime(input interface{}, fv reflect.Value) error {
+	var (
+		s   string
+		t   time.Time
+		ok  bool
+		err error
+	)
+
+	s, ok = valueOf(input).(string)
+	if !ok {
+		return fmt.Errorf("time value is not a string: %v", input)
+	}
+	t, err = time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	fv.Set(reflect.ValueOf(t))
+	return nil
+}
+
+//unmarshalPrimitive sets a primitive field from a JSON primitive
+func unmarshalPrimitive(val interface{}, fv reflect.Value) error {
+	var (
+		f   float64
+		i   int64
+		err error
+	)
+
+	switch fv.Kind() {
+	case reflect.String:
+		switch val.(type) {
+		case string:
+			fv.SetString(val.(string))
+			return nil
+		}
+	case reflect.Bool:
+		switch val.(type) {
+		case bool:
+			fv.SetBool(val.(bool))
+			return nil
+		case string:
+			b, err := strconv.ParseBool(val.(string))
+			if err != nil {
+				return err
+			}
+			fv.SetBool(b)
+			return nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch val.(type) {
+		case int:
+			i = int64(val.(int))
+		case int64:
+			i = val.(int64)
+		case float64:
+			i = int64(val.(float64))
+			if float64(i) != val.(float64) {
+				return fmt.Errorf("non-integer value %v for an integer field", val)
+			}
+		case json.Number:
+			i, err = val.(json.Number).Int64()
+		case string:
+			i, err = strconv.ParseInt(val.(string), 10, 64)
+		default:
+			return fmt.Errorf("value %v of type %T for an integer field", val, val)
+		}
+		if err != nil {
+			return err
+		}
+		switch fv.Kind() {
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if i < 0 || fv.OverflowUint(uint64(i)) {
+				return fmt.Errorf("value %v overflows %v", val, fv.Type())
+			}
+			fv.SetUint(uint64(i))
+		default:
+			if fv.OverflowInt(i) {
+				return fmt.Errorf("value %v overflows %v", val, fv.Type())
+			}
+			fv.SetInt(i)
+		}
+		return nil
+	case reflect.Float32, reflect.Float64:
+		switch val.(type) {
+		case float64:
+			f = val.(float64)
+		case float32:
+			f = float64(val.(float32))
+		case int:
+			f = float64(val.(int))
+		case json.Number:
+			f, err = val.(json.Number).Float64()
+		case string:
+			f, err = strconv.ParseFloat(val.(string), 64)
+		default:
+			return fmt.Errorf("value %v of type %T for a float field", val, val)
+		}
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+		return nil
+	}
+	return fmt.Errorf("value %v of type %T for a field of type %v", val, val, fv.Type())
+}
@@ -0,0 +1,84 @@
+package jld
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestUnmarshal(test *testing.T) {
+	var (
+		node = map[string]interface{}{
+			"@id":                        "https://ex.org/ann",
+			"@type":                      []interface{}{"https://ex.org/types#Agent", "https://ex.org/types#Person"},
+			"https://ex.org/vocab#name":  map[string]interface{}{"@value": "Ann"},
+			"https://ex.org/vocab#email": "ann@ex.org",
+			"https://ex.org/vocab#age":   map[string]interface{}{"@type": "http://www.w3.org/2001/XMLSchema#integer", "@value": "42"},
+			"https://ex.org/vocab#steps": map[string]interface{}{"@list": []interface{}{"a", "b"}},
+			"https://ex.org/vocab#knows": map[string]interface{}{
+				"@id":                       "https://ex.org/bob",
+				"@type":                     "https://ex.org/types#Person",
+				"https://ex.org/vocab#name": "Bob",
+			},
+			"https://ex.org/vocab#manager": map[string]interface{}{"@id": "https://ex.org/bob"},
+		}
+		p   testPerson
+		err error
+	)
+
+	err = Unmarshal(node, &p)
+	if err != nil {
+		test.Fatalf("Unmarshal: %v", err)
+	}
+	switch {
+	case p.ID != "https://ex.org/ann":
+		test.Errorf("Unmarshal ID: %v", p.ID)
+	case p.Name != "Ann":
+		test.Errorf("Unmarshal Name: %v", p.Name)
+	case len(p.Email) != 1 || p.Email[0] != "ann@ex.org":
+		test.Errorf("Unmarshal Email: %v", p.Email)
+	case p.Age != 42:
+		test.Errorf("Unmarshal Age: %v", p.Age)
+	case len(p.Steps) != 2 || p.Steps[1] != "b":
+		test.Errorf("Unmarshal Steps: %v", p.Steps)
+	case p.Knows == nil || p.Knows.Name != "Bob":
+		test.Errorf("Unmarshal Knows: %v", p.Knows)
+	case p.Manager == nil || p.Manager.ID != "https://ex.org/bob":
+		test.Errorf("Unmarshal Manager: %v", p.Manager)
+	}
+
+	node["@type"] = "https://ex.org/types#Agent"
+	err = Unmarshal(node, &p)
+	if err == nil {
+		test.Errorf("Unmarshal of a node of the wrong type should fail")
+	}
+}
+
+func TestUnmarshalMismatch(test *testing.T) {
+	type mismatched struct {
+		Meta  map[string]interface{} `jld:"https://ex.org/vocab#meta"`
+		Label fmt.Stringer           `jld:"https://ex.org/vocab#label"`
+	}
+	var (
+		cases = []map[string]interface{}{
+			{"https://ex.org/vocab#meta": "not a node"},
+			{"https://ex.org/vocab#meta": []interface{}{map[string]interface{}{"@id": "https://ex.org/a"}, "b"}},
+			{"https://ex.org/vocab#label": "not a Stringer"},
+		}
+		nodeErr *NodeError
+	)
+
+	for _, node := range cases {
+		var m mismatched
+		err := Unmarshal(node, &m)
+		if !errors.Is(err, ErrBadValue) || !errors.As(err, &nodeErr) {
+			test.Errorf("Unmarshal of %v: %v", node, err)
+		}
+	}
+
+	var m mismatched
+	err := Unmarshal(map[string]interface{}{"https://ex.org/vocab#meta": map[string]interface{}{"@id": "https://ex.org/a"}}, &m)
+	if err != nil || m.Meta["@id"] != "https://ex.org/a" {
+		test.Errorf("Unmarshal of a node into a map field: %v %v", m.Meta, err)
+	}
+}
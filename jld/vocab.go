@@ -0,0 +1,280 @@
+package jld
+
+import (
+	"strings"
+	"sync"
+)
+
+/*
+A Vocabulary resolves short property and type names such as "name" or "email" to full PropIDs and TypeIDs.
+A name is resolved by first looking up a registered alias; if there is none, a JSON LD keyword (e.g. "@id") or an
+absolute IRI is used as is; otherwise, the name is appended to the Vocabulary's base.
+
+A service declares the properties and types of its base with DefineP and DefineT rather than as separate PropID and
+TypeID vars, and Context emits the @context of everything registered.
+
+A Vocabulary is typically created and registered in a package var and then shared by concurrent HTTP requests;
+therefore, its alias maps are mutexed.
+*/
+type Vocabulary struct {
+	m     sync.RWMutex
+	tb    TypeBase
+	pb    PropBase
+	props map[string]PropID
+	types map[string]TypeID
+}
+
+/*
+NewVocabulary creates a Vocabulary whose unregistered short names are resolved relative to the TypeBase and PropBase.
+*/
+func NewVocabulary(tb TypeBase, pb PropBase) *Vocabulary {
+	var v Vocabulary
+	v.tb = tb
+	v.pb = pb
+	v.props = make(map[string]PropID)
+	v.types = make(map[string]TypeID)
+	return &v
+}
+
+/*
+RegisterP registers name as an alias for the PropID. It is used for properties that are not in the Vocabulary's PropBase.
+*/
+func (v *Vocabulary) RegisterP(name string, propID PropID) {
+	v.m.Lock()
+	defer v.m.Unlock()
+	v.props[name] = propID
+	return
+}
+
+/*
+RegisterT registers name as an alias for the TypeID. It is used for types that are not in the Vocabulary's TypeBase.
+*/
+func (v *Vocabulary) RegisterT(name string, typeID TypeID) {
+	v.m.Lock()
+	defer v.m.Unlock()
+	v.types[name] = typeID
+	return
+}
+
+/*
+DefineP registers name as the short name of the property name in the Vocabulary's PropBase and returns its PropID.
+A service declares its properties with it, e.g. var NameP = vocab.DefineP("name"), so that they appear in Context.
+A datatype, if any, is registered as the property's datatype (see RegisterDatatype).
+*/
+func (v *Vocabulary) DefineP(name string, datatype ...TypeID) PropID {
+	var propID = NewPropID(name, v.pb)
+
+	v.RegisterP(name, propID)
+	if len(datatype) > 0 {
+		RegisterDatatype(propID, datatype[0])
+	}
+	return propID
+}
+
+/*
+DefineT registers name as the short name of the type name in the Vocabulary's TypeBase and returns its TypeID.
+*/
+func (v *Vocabulary) DefineT(name string) TypeID {
+	var typeID = NewTypeID(name, v.tb)
+
+	v.RegisterT(name, typeID)
+	return typeID
+}
+
+/*
+Props returns the registered short property names and their PropIDs.
+*/
+func (v *Vocabulary) Props() map[string]PropID {
+	var props map[string]PropID
+
+	v.m.RLock()
+	defer v.m.RUnlock()
+	props = make(map[string]PropID, len(v.props))
+	for name, propID := range v.props {
+		props[name] = propID
+	}
+	return props
+}
+
+/*
+Types returns the registered short type names and their TypeIDs.
+*/
+func (v *Vocabulary) Types() map[string]TypeID {
+	var types map[string]TypeID
+
+	v.m.RLock()
+	defer v.m.RUnlock()
+	types = make(map[string]TypeID, len(v.types))
+	for name, typeID := range v.types {
+		types[name] = typeID
+	}
+	return types
+}
+
+/*
+Context returns a JSON LD @context that defines a term for each registered short name, so that Compact with it
+produces documents that use the short names. A property with a registered datatype is defined with the datatype as
+its @type. If a type and a property are registered with the same short name, the term is the property.
+*/
+func (v *Vocabulary) Context() map[string]interface{} {
+	var context = make(map[string]interface{})
+
+	v.m.RLock()
+	defer v.m.RUnlock()
+	for name, typeID := range v.types {
+		context[name] = typeID.URI()
+	}
+	for name, propID := range v.props {
+		if datatype, ok := Datatype(propID); ok {
+			context[name] = map[string]interface{}{"@id": propID.URI(), "@type": datatype.URI()}
+			continue
+		}
+		context[name] = propID.URI()
+	}
+	return context
+}
+
+/*
+P resolves a short property name to its PropID.
+*/
+func (v *Vocabulary) P(name string) PropID {
+	var (
+		propID PropID
+		ok     bool
+	)
+
+	v.m.RLock()
+	propID, ok = v.props[name]
+	v.m.RUnlock()
+	if ok {
+		return propID
+	}
+	if isAbsName(name) {
+		return NewPropID(name, "")
+	}
+	return NewPropID(name, v.pb)
+}
+
+/*
+T resolves a short type name to its TypeID.
+*/
+func (v *Vocabulary) T(name string) TypeID {
+	var (
+		typeID TypeID
+		ok     bool
+	)
+
+	v.m.RLock()
+	typeID, ok = v.types[name]
+	v.m.RUnlock()
+	if ok {
+		return typeID
+	}
+	if isAbsName(name) {
+		return NewTypeID(name, "")
+	}
+	return NewTypeID(name, v.tb)
+}
+
+//isAbsName is true if a name is a JSON LD keyword, blank node identifier or absolute IRI and must not be resolved against a base.
+func isAbsName(name string) bool {
+	return strings.HasPrefix(name, "@") || strings.Contains(name, ":")
+}
+
+/*
+GetP is GetP with the property identified by a short name.
+*/
+func (v *Vocabulary) GetP(input interface{}, name string) (interface{}, bool) {
+	return GetP(input, v.P(name))
+}
+
+/*
+GetN is GetN with the property identified by a short name.
+*/
+func (v *Vocabulary) GetN(input interface{}, name string) (map[string]interface{}, bool) {
+	return GetN(input, v.P(name))
+}
+
+/*
+GetNtype is GetNtype with the property and type identified by short names.
+*/
+func (v *Vocabulary) GetNtype(input interface{}, name, typeName string) (map[string]interface{}, bool) {
+	return GetNtype(input, v.P(name), v.T(typeName))
+}
+
+/*
+GetSet is GetSet with the property identified by a short name.
+*/
+func (v *Vocabulary) GetSet(input interface{}, name string) ([]interface{}, bool) {
+	return GetSet(input, v.P(name))
+}
+
+/*
+GetList is GetList with the property identified by a short name.
+*/
+func (v *Vocabulary) GetList(input interface{}, name string) ([]interface{}, bool) {
+	return GetList(input, v.P(name))
+}
+
+/*
+GetVtype is GetVtype with the property and type identified by short names.
+*/
+func (v *Vocabulary) GetVtype(input interface{}, name, typeName string) (interface{}, bool) {
+	return GetVtype(input, v.P(name), v.T(typeName))
+}
+
+/*
+GetString is GetString with the property identified by a short name.
+*/
+func (v *Vocabulary) GetString(input interface{}, name string) (string, bool) {
+	return GetString(input, v.P(name))
+}
+
+/*
+GetBool is GetBool with the property identified by a short name.
+*/
+func (v *Vocabulary) GetBool(input interface{}, name string) (bool, bool) {
+	return GetBool(input, v.P(name))
+}
+
+/*
+Append is Append with the property identified by a short name.
+*/
+func (v *Vocabulary) Append(input interface{}, name string, items ...interface{}) ([]interface{}, error) {
+	return Append(input, v.P(name), items...)
+}
+
+/*
+SetP is SetP with the property identified by a short name.
+*/
+func (v *Vocabulary) SetP(input interface{}, name string, value interface{}) error {
+	return SetP(input, v.P(name), value)
+}
+
+/*
+SetV is SetV with the property and type identified by short names.
+*/
+func (v *Vocabulary) SetV(input interface{}, name, typeName string, value interface{}) error {
+	return SetV(input, v.P(name), v.T(typeName), value)
+}
+
+/*
+SetN is SetN with the property identified by a short name.
+*/
+func (v *Vocabulary) SetN(input interface{}, name string, n map[string]interface{}) error {
+	return SetN(input, v.P(name), n)
+}
+
+/*
+SetList is SetList with the property identified by a short name.
+*/
+func (v *Vocabulary) SetList(input interface{}, name string, items ...interface{}) error {
+	return SetList(input, v.P(name), items...)
+}
+
+/*
+RemoveP is RemoveP with the property identified by a short name.
+*/
+func (v *Vocabulary) RemoveP(input interface{}, name string) error {
+	return RemoveP(input, v.P(name))
+}
@@ -0,0 +1,127 @@
+package jld
+
+import (
+	"testing"
+)
+
+func TestVocabulary(test *testing.T) {
+	var (
+		v    = NewVocabulary("https://ex.org/types#", "https://ex.org/vocab#")
+		node map[string]interface{}
+		s    string
+		ok   bool
+	)
+
+	v.RegisterP("mbox", NewPropID("http://xmlns.com/foaf/0.1/mbox", ""))
+
+	switch {
+	case v.P("name") != "https://ex.org/vocab#name":
+		test.Errorf("P name: %v", v.P("name"))
+	case v.P("mbox") != "http://xmlns.com/foaf/0.1/mbox":
+		test.Errorf("P mbox: %v", v.P("mbox"))
+	case v.P("@id") != IDP:
+		test.Errorf("P @id: %v", v.P("@id"))
+	case v.P("https://other.org/p") != "https://other.org/p":
+		test.Errorf("P absolute: %v", v.P("https://other.org/p"))
+	case v.T("Person") != "https://ex.org/types#Person":
+		test.Errorf("T Person: %v", v.T("Person"))
+	}
+
+	node = map[string]interface{}{
+		"https://ex.org/vocab#name":      "Ann",
+		"http://xmlns.com/foaf/0.1/mbox": "ann@ex.org",
+	}
+	s, ok = v.GetString(node, "name")
+	if !ok || s != "Ann" {
+		test.Errorf("GetString name: %v %v", s, ok)
+	}
+	s, ok = v.GetString(node, "mbox")
+	if !ok || s != "ann@ex.org" {
+		test.Errorf("GetString mbox: %v %v", s, ok)
+	}
+	_, ok = v.GetString(node, "email")
+	if ok {
+		test.Errorf("GetString email should not be found")
+	}
+}
+
+func TestVocabularyContext(test *testing.T) {
+	var (
+		v       = NewVocabulary("https://ex.org/types#", "https://ex.org/vocab#")
+		xsdInt  = NewTypeID(xsdBase+"integer", "")
+		nameP   = v.DefineP("name")
+		ageP    = v.DefineP("age", xsdInt)
+		person  = v.DefineT("Person")
+		context map[string]interface{}
+	)
+
+	defer UnregisterDatatype(ageP)
+	if nameP != "https://ex.org/vocab#name" || person != "https://ex.org/types#Person" || v.P("age") != ageP {
+		test.Errorf("Define: %v %v %v", nameP, person, v.P("age"))
+	}
+	if len(v.Props()) != 2 || len(v.Types()) != 1 {
+		test.Errorf("Props %v Types %v", v.Props(), v.Types())
+	}
+
+	context = v.Context()
+	if context["name"] != nameP.URI() || context["Person"] != person.URI() {
+		test.Errorf("Context terms: %v", context)
+	}
+	if age, ok := context["age"].(map[string]interface{}); !ok || age["@id"] != ageP.URI() || age["@type"] != xsdInt.URI() {
+		test.Errorf("Context typed term: %v", context["age"])
+	}
+}
+
+func TestVocabularySetters(test *testing.T) {
+	var (
+		v      = NewVocabulary("https://ex.org/types#", "https://ex.org/vocab#")
+		node   = NewN("https://ex.org/ann", v.DefineT("Person"))
+		friend = NewN("https://ex.org/bob", v.T("Person"))
+		s      string
+		ok     bool
+	)
+
+	v.RegisterP("mbox", NewPropID("http://xmlns.com/foaf/0.1/mbox", ""))
+	v.RegisterT("year", NewTypeID(xsdBase+"gYear", ""))
+
+	if err := v.SetP(node, "name", "Ann"); err != nil {
+		test.Fatalf("SetP: %v", err)
+	}
+	if err := v.SetP(node, "mbox", "ann@ex.org"); err != nil {
+		test.Fatalf("SetP: %v", err)
+	}
+	if s, ok = v.GetString(node, "name"); !ok || s != "Ann" || node["https://ex.org/vocab#name"] != "Ann" {
+		test.Errorf("SetP name: %v", node)
+	}
+	if s, ok = v.GetString(node, "mbox"); !ok || s != "ann@ex.org" {
+		test.Errorf("SetP mbox: %v", node)
+	}
+	if err := v.SetV(node, "born", "year", "1990"); err != nil {
+		test.Fatalf("SetV: %v", err)
+	}
+	if value, _ := v.GetVtype(node, "born", "year"); value != "1990" {
+		test.Errorf("SetV born: %v", node["https://ex.org/vocab#born"])
+	}
+	if err := v.SetN(node, "knows", friend); err != nil {
+		test.Fatalf("SetN: %v", err)
+	}
+	if n, ok := v.GetNtype(node, "knows", "Person"); !ok || n["@id"] != "https://ex.org/bob" {
+		test.Errorf("SetN knows: %v", node["https://ex.org/vocab#knows"])
+	}
+	if err := v.SetList(node, "tags", "a", "b"); err != nil {
+		test.Fatalf("SetList: %v", err)
+	}
+	if list, ok := v.GetList(node, "tags"); !ok || len(list) != 2 {
+		test.Errorf("SetList tags: %v", node["https://ex.org/vocab#tags"])
+	}
+
+	if err := v.RemoveP(node, "mbox"); err != nil {
+		test.Fatalf("RemoveP: %v", err)
+	}
+	if _, ok = v.GetP(node, "mbox"); ok {
+		test.Errorf("RemoveP mbox: %v", node)
+	}
+	if err := v.SetP("not a node", "name", "Ann"); err == nil {
+		test.Errorf("SetP of a non node should fail")
+	}
+}
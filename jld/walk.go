@@ -0,0 +1,158 @@
+package jld
+
+import (
+	"errors"
+)
+
+/*
+SkipNode is returned by a pre-order Walk function to skip the nodes nested in the node it was called with. It is not
+an error; Walk continues with the node's siblings.
+*/
+var SkipNode = errors.New("skip this node")
+
+type (
+	//A WalkOption configures Walk
+	WalkOption func(*walkOptions)
+
+	//walkOptions holds the configuration set by a list of WalkOptions
+	walkOptions struct {
+		maxDepth  int
+		postOrder bool
+	}
+)
+
+/*
+MaxDepth limits Walk to the nodes nested at most depth levels below the top level nodes, which are at depth 0.
+*/
+func MaxDepth(depth int) WalkOption {
+	return func(o *walkOptions) {
+		if depth >= 0 && depth < o.maxDepth {
+			o.maxDepth = depth
+		}
+	}
+}
+
+/*
+PostOrder makes Walk call its function with a node after the nodes nested in it rather than before.
+*/
+func PostOrder() WalkOption {
+	return func(o *walkOptions) {
+		o.postOrder = true
+	}
+}
+
+//walker holds the state of a Walk
+type walker struct {
+	f    func(map[string]interface{}) error
+	o    walkOptions
+	seen map[string]bool
+}
+
+/*
+Walk calls f with every node of a document, unlike ApplyN, which only calls it with the top level nodes: the nodes of
+@graph arrays, sets and lists, the nodes embedded in property values (including @reverse, @included and @nest values)
+and the nodes of named graphs. Value objects, node references and the @context are not visited. The properties of a
+node are walked in sorted order.
+
+By default a node is visited before the nodes nested in it, so f may transform a node before its nested nodes are
+walked, or return SkipNode to skip them; with PostOrder it is visited after them. A node with an @id is only visited
+once, so a document whose nodes are shared or cyclic (e.g. after ResolveRefs) is walked without repetition. The depth
+of the walk is limited by MaxDepth and, in any case, to 1000 levels.
+
+If f returns an error other than SkipNode, the walk stops and Walk returns it.
+*/
+func Walk(f func(map[string]interface{}) error, input interface{}, opts ...WalkOption) error {
+	var w = walker{f: f, o: walkOptions{maxDepth: maxGraphDepth}, seen: make(map[string]bool)}
+
+	for _, opt := range opts {
+		opt(&w.o)
+	}
+	return w.value(input, 0)
+}
+
+//value walks the nodes of a value at a depth
+func (w *walker) value(input interface{}, depth int) error {
+	switch input.(type) {
+	case []interface{}:
+		for _, item := range input.([]interface{}) {
+			if err := w.value(item, depth); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		obj := input.(map[string]interface{})
+		if _, ok := obj["@value"]; ok {
+			return nil
+		}
+		for _, k := range []string{"@list", "@set"} {
+			if v, ok := obj[k]; ok {
+				return w.value(v, depth)
+			}
+		}
+		if isGraphObject(obj) {
+			return w.value(obj["@graph"], depth)
+		}
+		return w.node(obj, depth)
+	default:
+		return nil
+	}
+}
+
+//node visits a node and walks the nodes nested in it
+func (w *walker) node(node map[string]interface{}, depth int) error {
+	var err error
+
+	if depth > w.o.maxDepth || IsNref(node) {
+		return nil
+	}
+	if id, ok := node["@id"].(string); ok && id != "" {
+		if w.seen[id] {
+			return nil
+		}
+		w.seen[id] = true
+	}
+
+	if !w.o.postOrder {
+		err = w.f(node)
+		if err == SkipNode {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	err = w.properties(node, depth)
+	if err != nil {
+		return err
+	}
+	if w.o.postOrder {
+		err = w.f(node)
+		if err != nil && err != SkipNode {
+			return err
+		}
+	}
+	return nil
+}
+
+//properties walks the nodes nested in the property values of a node, or of one of its @reverse or @nest objects
+func (w *walker) properties(obj map[string]interface{}, depth int) error {
+	var err error
+
+	for _, prop := range Props(obj) {
+		switch prop.ID {
+		case IDP, TypeP, CtxP:
+			continue
+		case "@reverse", "@nest":
+			if nested, ok := prop.Value.(map[string]interface{}); ok {
+				err = w.properties(nested, depth)
+			}
+		default:
+			err = w.value(prop.Value, depth+1)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package jld
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestWalk(test *testing.T) {
+	var (
+		doc = map[string]interface{}{
+			"@context": map[string]interface{}{"@vocab": "https://ex.org/vocab#"},
+			"@graph": []interface{}{
+				map[string]interface{}{
+					"@id":                      "https://ex.org/a",
+					"https://ex.org/vocab#kid": map[string]interface{}{"@id": "_:b", "https://ex.org/vocab#toy": map[string]interface{}{"@id": "_:c", "@type": "https://ex.org/vocab#Toy"}},
+					"https://ex.org/vocab#steps": map[string]interface{}{"@list": []interface{}{
+						map[string]interface{}{"@id": "_:d", "https://ex.org/vocab#n": map[string]interface{}{"@value": 1}},
+					}},
+					"https://ex.org/vocab#ref": map[string]interface{}{"@id": "https://ex.org/e"},
+				},
+				map[string]interface{}{"@id": "https://ex.org/e", "@type": "https://ex.org/vocab#Thing"},
+			},
+		}
+		visited []string
+		visit   = func(node map[string]interface{}) error {
+			visited = append(visited, fmt.Sprint(node["@id"]))
+			return nil
+		}
+		err error
+	)
+
+	err = Walk(visit, doc)
+	if err != nil || strings.Join(visited, " ") != "https://ex.org/a _:b _:c _:d https://ex.org/e" {
+		test.Errorf("Walk: %v %v", visited, err)
+	}
+
+	visited = nil
+	err = Walk(visit, doc, PostOrder())
+	if err != nil || strings.Join(visited, " ") != "_:c _:b _:d https://ex.org/a https://ex.org/e" {
+		test.Errorf("Walk PostOrder: %v %v", visited, err)
+	}
+
+	visited = nil
+	err = Walk(visit, doc, MaxDepth(1))
+	if err != nil || strings.Join(visited, " ") != "https://ex.org/a _:b _:d https://ex.org/e" {
+		test.Errorf("Walk MaxDepth: %v %v", visited, err)
+	}
+
+	visited = nil
+	err = Walk(func(node map[string]interface{}) error {
+		visit(node)
+		if node["@id"] == "_:b" {
+			return SkipNode
+		}
+		return nil
+	}, doc)
+	if err != nil || strings.Join(visited, " ") != "https://ex.org/a _:b _:d https://ex.org/e" {
+		test.Errorf("Walk SkipNode: %v %v", visited, err)
+	}
+
+	if err = Walk(func(map[string]interface{}) error { return fmt.Errorf("stop") }, doc); err == nil || err.Error() != "stop" {
+		test.Errorf("Walk should return the function's error: %v", err)
+	}
+}
+
+func TestWalkCycle(test *testing.T) {
+	var (
+		a     = map[string]interface{}{"@id": "https://ex.org/a"}
+		b     = map[string]interface{}{"@id": "https://ex.org/b", "https://ex.org/vocab#knows": a}
+		count int
+		err   error
+	)
+
+	a["https://ex.org/vocab#knows"] = b
+	err = Walk(func(map[string]interface{}) error {
+		count++
+		return nil
+	}, []interface{}{a, b})
+	if err != nil || count != 2 {
+		test.Errorf("Walk of a cyclic document: %v %v", count, err)
+	}
+}
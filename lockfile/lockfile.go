@@ -0,0 +1,347 @@
+/*
+Package lockfile coordinates processes that share a host, e.g. several RP or worker instances, so that tasks such as
+key rotation and log rotation are not run by more than one of them at a time.
+
+It provides three primitives:
+
+A Lock is an advisory (flock) lock of a file. It is released by Unlock or when its process exits, so it cannot be left
+stale by a crash. It only excludes processes that also lock the file.
+
+A FileLock is a lock file created exclusively that holds the PID of its owner. It is visible to tools that test for
+the file's existence (e.g. a rotation script). A lock file whose owner is no longer running is stale and is replaced.
+
+A Lease elects a leader: the owner of an unexpired lease file is the leader until its TTL passes without being renewed.
+Unlike a Lock, leadership outlives the process that held it for the rest of the TTL, which keeps a periodic task from
+being run again by another instance that starts just after it.
+
+The locks use flock and signal 0 and are only supported on Unix. The lease times come from a clock.Clock that tests
+may replace with SetClock.
+*/
+package lockfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/develrns/resilient/clock"
+)
+
+//leaseClock is the Clock of the lease times, which tests may replace with SetClock
+var leaseClock = struct {
+	m sync.Mutex
+	c clock.Clock
+}{c: clock.Real}
+
+/*
+SetClock replaces the Clock of the lease times, e.g. with a clock.Fake in a test. It returns the replaced Clock.
+*/
+func SetClock(c clock.Clock) clock.Clock {
+	leaseClock.m.Lock()
+	defer leaseClock.m.Unlock()
+	c, leaseClock.c = leaseClock.c, c
+	return c
+}
+
+//now returns the time of the lease Clock
+func now() time.Time {
+	leaseClock.m.Lock()
+	defer leaseClock.m.Unlock()
+	return leaseClock.c.Now()
+}
+
+/*
+A Lock is a held advisory lock of a file.
+*/
+type Lock struct {
+	file *os.File
+}
+
+/*
+Acquire waits for and acquires the advisory lock of a file, creating the file if it does not exist.
+*/
+func Acquire(path string) (*Lock, error) {
+	return lock(path, syscall.LOCK_EX)
+}
+
+/*
+TryAcquire acquires the advisory lock of a file if it is not held. If it is held, it returns a nil Lock and no error.
+*/
+func TryAcquire(path string) (*Lock, error) {
+	var l, err = lock(path, syscall.LOCK_EX|syscall.LOCK_NB)
+
+	if err == syscall.EWOULDBLOCK {
+		return nil, nil
+	}
+	return l, err
+}
+
+//lock opens a file and flocks it
+func lock(path string, how int) (*Lock, error) {
+	var (
+		file *os.File
+		err  error
+	)
+
+	file, err = os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		err = syscall.Flock(int(file.Fd()), how)
+		if err != syscall.EINTR {
+			break
+		}
+	}
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &Lock{file: file}, nil
+}
+
+/*
+Unlock releases the Lock. The lock file is not removed, since another process may already be waiting to lock it.
+*/
+func (l *Lock) Unlock() error {
+	var err = syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+
+	l.file.Close()
+	return err
+}
+
+/*
+A FileLock is a held lock file.
+*/
+type FileLock struct {
+	path string
+}
+
+/*
+TryFileLock creates a lock file holding this process's PID. If the lock file exists and its owner is running, it
+returns a nil FileLock and no error; if its owner is no longer running, the stale lock file is replaced.
+*/
+func TryFileLock(path string) (*FileLock, error) {
+	var (
+		file *os.File
+		err  error
+	)
+
+	//The stale check and replacement are serialized by an advisory lock so that two processes cannot both replace it
+	guard, err := Acquire(path + ".guard")
+	if err != nil {
+		return nil, err
+	}
+	defer guard.Unlock()
+
+	file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		if running(path) {
+			return nil, nil
+		}
+		err = os.Remove(path)
+		if err != nil {
+			return nil, err
+		}
+		file, err = os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	}
+	if err != nil {
+		return nil, err
+	}
+	_, err = file.WriteString(strconv.Itoa(os.Getpid()) + "\n")
+	file.Close()
+	if err != nil {
+		os.Remove(path)
+		return nil, err
+	}
+	return &FileLock{path: path}, nil
+}
+
+//running is true if the owner of a lock file is running. A lock file without a valid PID is treated as held.
+func running(path string) bool {
+	var (
+		content []byte
+		pid     int
+		err     error
+	)
+
+	content, err = ioutil.ReadFile(path)
+	if err != nil {
+		return !os.IsNotExist(err)
+	}
+	pid, err = strconv.Atoi(strings.TrimSpace(string(content)))
+	if err != nil || pid <= 0 {
+		return true
+	}
+	err = syscall.Kill(pid, 0)
+	return err == nil || err == syscall.EPERM
+}
+
+/*
+Unlock removes the lock file.
+*/
+func (fl *FileLock) Unlock() error {
+	return os.Remove(fl.path)
+}
+
+//leaseRecord is the content of a lease file
+type leaseRecord struct {
+	Owner   string    `json:"owner"`
+	Expires time.Time `json:"expires"`
+}
+
+/*
+A Lease is a candidate's view of a leader election lease file.
+*/
+type Lease struct {
+	path  string
+	owner string
+	ttl   time.Duration
+}
+
+/*
+NewLease creates a Lease on a lease file for a candidate owner (e.g. a hostname and PID) that is held for ttl after
+each Acquire.
+*/
+func NewLease(path, owner string, ttl time.Duration) (*Lease, error) {
+	if owner == "" {
+		return nil, fmt.Errorf("Missing Lease Owner")
+	}
+	if ttl <= 0 {
+		return nil, fmt.Errorf("Bad Lease TTL: %v", ttl)
+	}
+	return &Lease{path: path, owner: owner, ttl: ttl}, nil
+}
+
+/*
+DefaultOwner returns a lease owner that identifies this process on its host.
+*/
+func DefaultOwner() string {
+	var hostname, _ = os.Hostname()
+
+	return hostname + "/" + strconv.Itoa(os.Getpid())
+}
+
+/*
+Acquire makes the candidate the leader if the lease is free, expired or already its own, and then renews the lease for
+its TTL. It returns whether the candidate is the leader. A leader should call Acquire again well before the TTL passes.
+*/
+func (l *Lease) Acquire() (bool, error) {
+	var (
+		record leaseRecord
+		ok     bool
+		err    error
+	)
+
+	guard, err := Acquire(l.path + ".guard")
+	if err != nil {
+		return false, err
+	}
+	defer guard.Unlock()
+
+	record, ok, err = l.read()
+	if err != nil {
+		return false, err
+	}
+	if ok && record.Owner != l.owner && now().Before(record.Expires) {
+		return false, nil
+	}
+	return true, l.write(leaseRecord{Owner: l.owner, Expires: now().Add(l.ttl)})
+}
+
+/*
+Release gives up the lease if the candidate holds it, so that another candidate can lead without waiting for the TTL.
+*/
+func (l *Lease) Release() error {
+	var (
+		record leaseRecord
+		ok     bool
+		err    error
+	)
+
+	guard, err := Acquire(l.path + ".guard")
+	if err != nil {
+		return err
+	}
+	defer guard.Unlock()
+
+	record, ok, err = l.read()
+	if err != nil || !ok || record.Owner != l.owner {
+		return err
+	}
+	return os.Remove(l.path)
+}
+
+/*
+Leader returns the owner of the lease and whether it is unexpired.
+*/
+func (l *Lease) Leader() (string, bool, error) {
+	var record, ok, err = l.read()
+
+	if err != nil || !ok {
+		return "", false, err
+	}
+	return record.Owner, now().Before(record.Expires), nil
+}
+
+/*
+RunIfLeader runs a task if the candidate is or becomes the leader. The lease is kept after the task so that other
+candidates do not also run it within the TTL. It returns whether the task was run and its error.
+*/
+func (l *Lease) RunIfLeader(task func() error) (bool, error) {
+	var (
+		leader bool
+		err    error
+	)
+
+	leader, err = l.Acquire()
+	if err != nil || !leader {
+		return false, err
+	}
+	return true, task()
+}
+
+//read reads the lease file; it is false if there is none
+func (l *Lease) read() (leaseRecord, bool, error) {
+	var (
+		record  leaseRecord
+		content []byte
+		err     error
+	)
+
+	content, err = ioutil.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return record, false, nil
+	}
+	if err != nil {
+		return record, false, err
+	}
+	err = json.Unmarshal(content, &record)
+	if err != nil {
+		//A torn or corrupt lease is treated as free rather than blocking every candidate forever
+		return record, false, nil
+	}
+	return record, true, nil
+}
+
+//write replaces the lease file atomically
+func (l *Lease) write(record leaseRecord) error {
+	var (
+		content []byte
+		tmp     = l.path + ".tmp"
+		err     error
+	)
+
+	content, _ = json.Marshal(&record)
+	err = ioutil.WriteFile(tmp, content, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, l.path)
+}
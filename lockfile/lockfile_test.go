@@ -0,0 +1,177 @@
+package lockfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/develrns/resilient/clock"
+)
+
+func TestTryAcquire(test *testing.T) {
+	var (
+		path = filepath.Join(test.TempDir(), "lock")
+		held *Lock
+		l    *Lock
+		err  error
+	)
+
+	held, err = Acquire(path)
+	if err != nil {
+		test.Fatalf("Acquire: %v", err)
+	}
+
+	//A flock excludes another open file of the same process
+	if l, err = TryAcquire(path); l != nil || err != nil {
+		test.Errorf("TryAcquire of a held Lock: %v %v", l, err)
+	}
+	if err = held.Unlock(); err != nil {
+		test.Errorf("Unlock: %v", err)
+	}
+	if l, err = TryAcquire(path); l == nil || err != nil {
+		test.Fatalf("TryAcquire of a released Lock: %v %v", l, err)
+	}
+	l.Unlock()
+}
+
+func TestTryFileLock(test *testing.T) {
+	var (
+		path    = filepath.Join(test.TempDir(), "lock")
+		fl      *FileLock
+		err     error
+		content []byte
+	)
+
+	fl, err = TryFileLock(path)
+	if err != nil || fl == nil {
+		test.Fatalf("TryFileLock: %v %v", fl, err)
+	}
+	content, _ = ioutil.ReadFile(path)
+	if strings.TrimSpace(string(content)) != strconv.Itoa(os.Getpid()) {
+		test.Errorf("Lock file content: %q", content)
+	}
+	if other, err := TryFileLock(path); other != nil || err != nil {
+		test.Errorf("TryFileLock of a held lock file: %v %v", other, err)
+	}
+	if err = fl.Unlock(); err != nil {
+		test.Errorf("Unlock: %v", err)
+	}
+	if _, err = os.Stat(path); !os.IsNotExist(err) {
+		test.Errorf("Unlock did not remove the lock file: %v", err)
+	}
+
+	//A lock file without a valid PID is held; one whose owner is not running is stale and replaced
+	ioutil.WriteFile(path, []byte("garbage\n"), 0644)
+	if fl, err = TryFileLock(path); fl != nil || err != nil {
+		test.Errorf("TryFileLock of an invalid lock file: %v %v", fl, err)
+	}
+	ioutil.WriteFile(path, []byte("2147483646\n"), 0644)
+	if fl, err = TryFileLock(path); fl == nil || err != nil {
+		test.Fatalf("TryFileLock of a stale lock file: %v %v", fl, err)
+	}
+	fl.Unlock()
+}
+
+func TestNewLease(test *testing.T) {
+	if _, err := NewLease("lease", "", time.Minute); err == nil {
+		test.Errorf("NewLease without an owner should fail")
+	}
+	if _, err := NewLease("lease", "a", 0); err == nil {
+		test.Errorf("NewLease without a TTL should fail")
+	}
+}
+
+func TestLease(test *testing.T) {
+	var (
+		fake     = clock.NewFake(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+		replaced = SetClock(fake)
+		path     = filepath.Join(test.TempDir(), "lease")
+		a, _     = NewLease(path, "a", time.Minute)
+		b, _     = NewLease(path, "b", time.Minute)
+	)
+	defer SetClock(replaced)
+
+	if owner, ok, err := a.Leader(); owner != "" || ok || err != nil {
+		test.Errorf("Leader of no lease: %v %v %v", owner, ok, err)
+	}
+	if leader, err := a.Acquire(); !leader || err != nil {
+		test.Fatalf("Acquire of a free lease: %v %v", leader, err)
+	}
+	if leader, err := b.Acquire(); leader || err != nil {
+		test.Errorf("Acquire of a held lease: %v %v", leader, err)
+	}
+
+	//A renewed lease is held for its TTL from the renewal
+	fake.Advance(30 * time.Second)
+	a.Acquire()
+	fake.Advance(45 * time.Second)
+	if leader, _ := b.Acquire(); leader {
+		test.Errorf("Acquire of a renewed lease")
+	}
+	if owner, ok, _ := b.Leader(); owner != "a" || !ok {
+		test.Errorf("Leader of a renewed lease: %v %v", owner, ok)
+	}
+
+	//Once the lease expires it is taken over, and its previous owner is no longer the leader
+	fake.Advance(16 * time.Second)
+	if owner, ok, _ := b.Leader(); owner != "a" || ok {
+		test.Errorf("Leader of an expired lease: %v %v", owner, ok)
+	}
+	if leader, err := b.Acquire(); !leader || err != nil {
+		test.Fatalf("Acquire of an expired lease: %v %v", leader, err)
+	}
+	if leader, _ := a.Acquire(); leader {
+		test.Errorf("Acquire of a taken over lease")
+	}
+
+	//Only the owner's Release frees the lease
+	if err := a.Release(); err != nil {
+		test.Errorf("Release by another candidate: %v", err)
+	}
+	if owner, ok, _ := a.Leader(); owner != "b" || !ok {
+		test.Errorf("Leader after a Release by another candidate: %v %v", owner, ok)
+	}
+	if err := b.Release(); err != nil {
+		test.Errorf("Release: %v", err)
+	}
+	if leader, _ := a.Acquire(); !leader {
+		test.Errorf("Acquire of a released lease")
+	}
+
+	//A corrupt lease is free
+	ioutil.WriteFile(path, []byte(`{"owner":`), 0644)
+	if leader, _ := b.Acquire(); !leader {
+		test.Errorf("Acquire of a corrupt lease")
+	}
+}
+
+func TestRunIfLeader(test *testing.T) {
+	var (
+		path = filepath.Join(test.TempDir(), "lease")
+		a, _ = NewLease(path, "a", time.Minute)
+		b, _ = NewLease(path, "b", time.Minute)
+		runs []string
+	)
+
+	task := func(name string) func() error {
+		return func() error {
+			runs = append(runs, name)
+			return os.ErrClosed
+		}
+	}
+	if ran, err := a.RunIfLeader(task("a")); !ran || err != os.ErrClosed {
+		test.Errorf("RunIfLeader of the leader: %v %v", ran, err)
+	}
+
+	//The lease is kept after the task so that another candidate does not run it within the TTL
+	if ran, err := b.RunIfLeader(task("b")); ran || err != nil {
+		test.Errorf("RunIfLeader of another candidate: %v %v", ran, err)
+	}
+	if len(runs) != 1 || runs[0] != "a" {
+		test.Errorf("Runs: %v", runs)
+	}
+}
@@ -9,6 +9,25 @@ See the golang log package for a definition of the oplogflg bits that are ore'ed
 
 If Config is not called, the default is to log to stderr with no prefix and no flag.
 
+Very large logged values (e.g. token responses and JSON LD documents) break downstream log shippers. SetMaxFieldLength
+and SetMaxLineLength limit the size of each logged value and of each entry. A value or entry that exceeds its limit
+is deterministically truncated to a prefix and suffix separated by a marker noting the number of truncated bytes.
+
+SetStderrMirror mirrors the entries of a Level and above (e.g. LevelWarn) to stderr when the log is written to a file,
+so that container orchestration captures critical failures even if the file is misconfigured.
+
+SetStackTraces attaches stack traces to entries of a Level and above, with a depth limit and deduplication of
+identical traces within a window, so that errors can be triaged without reproducing them under a debugger.
+
+Request returns a RequestLog that prefixes a request's entries with its correlation ID and supports tail sampling of
+debug entries (see SetTailSampling).
+
+InfoCtx and the other Ctx methods prefix an entry with the trace and span IDs of the active trace of a context; see
+trace.go.
+
+The debug entry timestamps and the stack trace deduplication window use a clock.Clock, which tests may replace with
+SetClock. The timestamps of the log flag header are generated by the golang logger and do not.
+
 Due to initialization order issues, this logger cannot be used in init() functions.
 
 See standard go log package for more info.
@@ -16,13 +35,32 @@ See standard go log package for more info.
 package log
 
 import (
+	"fmt"
 	golog "log"
 	"os"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/develrns/resilient/clock"
 )
 
 type (
 	LoggerT struct {
 		logger *golog.Logger
+
+		//m guards the length limits, maxDebugEntries, stacks, clock and the stderr mirror policy and logger, which may
+		//be changed while requests are logged
+		m               sync.Mutex
+		maxLineLength   int
+		maxFieldLength  int
+		maxDebugEntries int
+		stacks          stackPolicy
+		clock           clock.Clock
+		mirrorOn        bool
+		mirrorMin       Level
+		mirror          *golog.Logger
+		toStderr        bool
 	}
 )
 
@@ -35,7 +73,8 @@ func (l *LoggerT) Fatal(v ...interface{}) {
 	if l.logger == nil {
 		Config("", "", 0)
 	}
-	l.logger.Fatal(v...)
+	l.output(3, LevelFatal, l.limitLine(l.withStack(LevelFatal, fmt.Sprint(l.limitFields(v)...), 0)))
+	os.Exit(1)
 }
 
 /*
@@ -45,7 +84,8 @@ func (l *LoggerT) Fatalf(format string, v ...interface{}) {
 	if l.logger == nil {
 		Config("", "", 0)
 	}
-	l.logger.Fatalf(format, v...)
+	l.output(3, LevelFatal, l.limitLine(l.withStack(LevelFatal, fmt.Sprintf(format, l.limitFields(v)...), 0)))
+	os.Exit(1)
 }
 
 /*
@@ -55,7 +95,8 @@ func (l *LoggerT) Fatalln(v ...interface{}) {
 	if l.logger == nil {
 		Config("", "", 0)
 	}
-	l.logger.Fatalln(v...)
+	l.output(3, LevelFatal, l.limitLine(l.withStack(LevelFatal, fmt.Sprintln(l.limitFields(v)...), 0)))
+	os.Exit(1)
 }
 
 /*
@@ -65,7 +106,9 @@ func (l *LoggerT) Panic(v ...interface{}) {
 	if l.logger == nil {
 		Config("", "", 0)
 	}
-	l.logger.Panic(v...)
+	s := l.limitLine(fmt.Sprint(l.limitFields(v)...))
+	l.output(3, LevelFatal, l.limitLine(l.withStack(LevelFatal, s, 0)))
+	panic(s)
 }
 
 /*
@@ -75,7 +118,9 @@ func (l *LoggerT) Panicf(format string, v ...interface{}) {
 	if l.logger == nil {
 		Config("", "", 0)
 	}
-	l.logger.Panicf(format, v...)
+	s := l.limitLine(fmt.Sprintf(format, l.limitFields(v)...))
+	l.output(3, LevelFatal, l.limitLine(l.withStack(LevelFatal, s, 0)))
+	panic(s)
 }
 
 /*
@@ -85,7 +130,9 @@ func (l *LoggerT) Panicln(v ...interface{}) {
 	if l.logger == nil {
 		Config("", "", 0)
 	}
-	l.logger.Panicln(v...)
+	s := l.limitLine(fmt.Sprintln(l.limitFields(v)...))
+	l.output(3, LevelFatal, l.limitLine(l.withStack(LevelFatal, s, 0)))
+	panic(s)
 }
 
 /*
@@ -95,7 +142,7 @@ func (l *LoggerT) Print(v ...interface{}) {
 	if l.logger == nil {
 		Config("", "", 0)
 	}
-	l.logger.Print(v...)
+	l.output(3, LevelInfo, l.limitLine(l.withStack(LevelInfo, fmt.Sprint(l.limitFields(v)...), 0)))
 }
 
 /*
@@ -105,7 +152,7 @@ func (l *LoggerT) Printf(format string, v ...interface{}) {
 	if l.logger == nil {
 		Config("", "", 0)
 	}
-	l.logger.Printf(format, v...)
+	l.output(3, LevelInfo, l.limitLine(l.withStack(LevelInfo, fmt.Sprintf(format, l.limitFields(v)...), 0)))
 }
 
 /*
@@ -125,7 +172,7 @@ func (l *LoggerT) Println(v ...interface{}) {
 	if l.logger == nil {
 		Config("", "", 0)
 	}
-	l.logger.Println(v...)
+	l.output(3, LevelInfo, l.limitLine(l.withStack(LevelInfo, fmt.Sprintln(l.limitFields(v)...), 0)))
 }
 
 /*
@@ -147,6 +194,10 @@ func Config(logname, logprefix string, logflg int) {
 	}
 
 	logger.logger = golog.New(logFile, logprefix, logflg)
+	logger.m.Lock()
+	logger.toStderr = logFile == os.Stderr
+	logger.mirror = golog.New(os.Stderr, logprefix, logflg)
+	logger.m.Unlock()
 
 	if openErr != nil {
 		logger.Printf("Logging to stderr because opening log file with Name: %v failed with Error: %v\n", logname, openErr)
@@ -159,3 +210,125 @@ Logger returns the shared logger
 func Logger() *LoggerT {
 	return logger
 }
+
+/*
+SetMaxLineLength sets the maximum length in bytes of a logged entry, excluding the prefix and flag generated header.
+A longer entry is truncated. A max of 0, the default, is no limit.
+*/
+func SetMaxLineLength(max int) {
+	logger.m.Lock()
+	defer logger.m.Unlock()
+	logger.maxLineLength = max
+}
+
+/*
+SetMaxFieldLength sets the maximum length in bytes of each value logged by Print, Printf, etc. A longer value is
+truncated. A max of 0, the default, is no limit.
+*/
+func SetMaxFieldLength(max int) {
+	logger.m.Lock()
+	defer logger.m.Unlock()
+	logger.maxFieldLength = max
+}
+
+/*
+SetClock replaces the Clock of the shared logger, e.g. with a clock.Fake in a test. It returns the replaced Clock.
+*/
+func SetClock(c clock.Clock) clock.Clock {
+	var replaced clock.Clock
+
+	logger.m.Lock()
+	defer logger.m.Unlock()
+	replaced = logger.clock
+	if replaced == nil {
+		replaced = clock.Real
+	}
+	logger.clock = c
+	return replaced
+}
+
+//now returns the time of the logger's Clock
+func (l *LoggerT) now() time.Time {
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.clock == nil {
+		return clock.Real.Now()
+	}
+	return l.clock.Now()
+}
+
+//limitFields truncates each value whose formatted length exceeds the max field length.
+func (l *LoggerT) limitFields(v []interface{}) []interface{} {
+	var (
+		limited []interface{}
+		s       string
+		max     int
+	)
+
+	l.m.Lock()
+	max = l.maxFieldLength
+	l.m.Unlock()
+	if max <= 0 {
+		return v
+	}
+	limited = make([]interface{}, len(v))
+	for i, field := range v {
+		switch field.(type) {
+		case string, []byte, fmt.Stringer, error:
+			s = fmt.Sprint(field)
+		default:
+			s = fmt.Sprintf("%+v", field)
+		}
+		if len(s) > max {
+			limited[i] = truncate(s, max)
+		} else {
+			limited[i] = field
+		}
+	}
+	return limited
+}
+
+//limitLine truncates an entry that exceeds the max line length.
+func (l *LoggerT) limitLine(s string) string {
+	var max int
+
+	l.m.Lock()
+	max = l.maxLineLength
+	l.m.Unlock()
+	if max <= 0 || len(s) <= max {
+		return s
+	}
+	return truncate(s, max)
+}
+
+/*
+truncate shortens s to at most max bytes by keeping a prefix and suffix of s separated by a marker of the form
+"...[truncated N bytes]...". Cuts are made on UTF-8 boundaries. If max is too small to hold the marker, the marker alone is returned.
+*/
+func truncate(s string, max int) string {
+	var (
+		marker  string
+		keep    int
+		head    int
+		tail    int
+		removed int
+	)
+
+	//The marker length depends on the number of removed bytes, which depends on the marker length;
+	//so it is computed from the largest possible count.
+	marker = fmt.Sprintf("...[truncated %v bytes]...", len(s))
+	keep = max - len(marker)
+	if keep <= 0 {
+		return marker
+	}
+	head = keep - keep/2
+	tail = len(s) - keep/2
+	for head > 0 && !utf8.RuneStart(s[head]) {
+		head--
+	}
+	for tail < len(s) && !utf8.RuneStart(s[tail]) {
+		tail++
+	}
+	removed = tail - head
+	return s[:head] + fmt.Sprintf("...[truncated %v bytes]...", removed) + s[tail:]
+}
@@ -0,0 +1,100 @@
+package log
+
+import (
+	"bytes"
+	"errors"
+	golog "log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/develrns/resilient/clock"
+)
+
+//capture redirects the shared logger to a buffer and replaces its Clock with a Fake until the test ends, when the
+//settings of the shared logger are restored to their defaults
+func capture(test *testing.T) (*bytes.Buffer, *clock.Fake) {
+	var (
+		buf      bytes.Buffer
+		fake     = clock.NewFake(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+		replaced = logger.logger
+		mirror   = logger.mirror
+		toStderr = logger.toStderr
+		c        = SetClock(fake)
+	)
+
+	logger.logger = golog.New(&buf, "", 0)
+	test.Cleanup(func() {
+		logger.m.Lock()
+		logger.logger, logger.mirror, logger.toStderr = replaced, mirror, toStderr
+		logger.m.Unlock()
+		SetClock(c)
+		SetStderrMirror(false, LevelDebug)
+		SetMaxLineLength(0)
+		SetMaxFieldLength(0)
+		SetTailSampling(0)
+		SetStackTraces(LevelDebug, 0, 0)
+	})
+	return &buf, fake
+}
+
+func TestTruncate(test *testing.T) {
+	var (
+		s     = strings.Repeat("0123456789", 10)
+		cases = []struct {
+			s, truncated string
+			max          int
+		}{
+			{s, "012345678901...[truncated 77 bytes]...90123456789", 50},
+			{s, "012345678901234567890123456789012345...[truncated 28 bytes]...456789012345678901234567890123456789", 99},
+
+			//If the max cannot hold more than the marker, the marker alone is returned
+			{s, "...[truncated 100 bytes]...", 27},
+			{s, "...[truncated 100 bytes]...", 1},
+
+			//Cuts are not made within a rune, so fewer bytes may be kept
+			{strings.Repeat("é", 50), "ééé...[truncated 88 bytes]...ééé", 40},
+		}
+	)
+
+	for _, c := range cases {
+		if truncated := truncate(c.s, c.max); truncated != c.truncated || (len(truncated) > c.max && c.max >= len("...[truncated 100 bytes]...")) {
+			test.Errorf("truncate %v: %q", c.max, truncated)
+		}
+	}
+}
+
+func TestMaxLengths(test *testing.T) {
+	var (
+		output, _ = capture(test)
+		long      = strings.Repeat("x", 100)
+	)
+
+	Logger().Print(long)
+	if output.String() != long+"\n" {
+		test.Errorf("Entry without limits: %q", output)
+	}
+
+	//Each value is limited, whatever its type
+	SetMaxFieldLength(40)
+	output.Reset()
+	Logger().Printf("%v|%v|%v|%v", long, errors.New(long), []string{long}, "short")
+	entries := strings.Split(strings.TrimSuffix(output.String(), "\n"), "|")
+	if len(entries) != 4 || entries[3] != "short" {
+		test.Fatalf("Entry with a max field length: %q", output)
+	}
+	for _, field := range entries[:3] {
+		if len(field) > 40 || !strings.Contains(field, "...[truncated ") {
+			test.Errorf("Truncated field: %q", field)
+		}
+	}
+
+	//The entry is limited after its values
+	SetMaxFieldLength(0)
+	SetMaxLineLength(60)
+	output.Reset()
+	Logger().Println(long, long)
+	if output.String() != truncate(long+" "+long+"\n", 60) || len(output.String()) > 60 {
+		test.Errorf("Entry with a max line length: %q", output)
+	}
+}
@@ -0,0 +1,40 @@
+package log
+
+import (
+	golog "log"
+)
+
+/*
+SetStderrMirror mirrors the entries of the min Level and above (e.g. LevelWarn for warnings, errors, fatals and panics)
+to stderr, with the same prefix and flag as the shared log, when the log is written to a file. Container orchestrators
+capture stderr (e.g. kubectl logs), so critical failures are still seen if the log file is misconfigured or lost.
+Mirroring is a no-op while the log is written to stderr. Setting on to false, the default, disables it.
+
+Entries written through the golang logger returned by Logger (e.g. by an http.Server) are not mirrored.
+*/
+func SetStderrMirror(on bool, min Level) {
+	logger.m.Lock()
+	defer logger.m.Unlock()
+	logger.mirrorOn = on
+	logger.mirrorMin = min
+}
+
+//output writes an entry of a level to the log and, if the mirror policy requires it, to stderr via the mirror logger
+//created by Config. Its call depth is that of the golang logger's Output plus one for output itself.
+func (l *LoggerT) output(calldepth int, level Level, entry string) {
+	var mirror *golog.Logger
+
+	if l.logger == nil {
+		Config("", "", 0)
+	}
+	l.logger.Output(calldepth, entry)
+
+	l.m.Lock()
+	if l.mirrorOn && level >= l.mirrorMin && !l.toStderr {
+		mirror = l.mirror
+	}
+	l.m.Unlock()
+	if mirror != nil {
+		mirror.Output(calldepth, entry)
+	}
+}
@@ -0,0 +1,72 @@
+package log
+
+import (
+	"bytes"
+	golog "log"
+	"path/filepath"
+	"testing"
+)
+
+func TestStderrMirror(test *testing.T) {
+	var (
+		output, _ = capture(test)
+		mirrored  bytes.Buffer
+		r         = Logger().Request("r1")
+	)
+
+	logger.m.Lock()
+	logger.mirror, logger.toStderr = golog.New(&mirrored, "", 0), false
+	logger.m.Unlock()
+
+	//Only the entries of the min Level and above are mirrored
+	SetStderrMirror(true, LevelWarn)
+	Logger().Print("info")
+	r.Debugf("debug")
+	r.Warnf("warn")
+	r.Errorf("error")
+	if output.String() != "info\n[r1] DEBUG debug\n[r1] WARN warn\n[r1] ERROR error\n" || mirrored.String() != "[r1] WARN warn\n[r1] ERROR error\n" {
+		test.Errorf("Entries: %q mirrored: %q", output, mirrored.String())
+	}
+
+	//Mirroring is a no-op while the log is written to stderr, or when it is disabled
+	mirrored.Reset()
+	logger.m.Lock()
+	logger.toStderr = true
+	logger.m.Unlock()
+	r.Errorf("error")
+	logger.m.Lock()
+	logger.toStderr = false
+	logger.m.Unlock()
+	SetStderrMirror(false, LevelWarn)
+	r.Errorf("error")
+	if mirrored.Len() != 0 {
+		test.Errorf("Mirrored: %q", mirrored.String())
+	}
+}
+
+func TestConfigMirror(test *testing.T) {
+	var (
+		mirror   *golog.Logger
+		mirrored bytes.Buffer
+	)
+
+	capture(test)
+
+	//Config creates the mirror logger, with the prefix and flag of the shared log
+	Config(filepath.Join(test.TempDir(), "log"), "svc: ", golog.Lmsgprefix)
+	logger.m.Lock()
+	mirror = logger.mirror
+	logger.m.Unlock()
+	if logger.toStderr || mirror == nil || mirror.Prefix() != "svc: " || mirror.Flags() != golog.Lmsgprefix {
+		test.Fatalf("Mirror logger: %v %v", logger.toStderr, mirror)
+	}
+
+	//It is used for each mirrored entry
+	mirror.SetOutput(&mirrored)
+	SetStderrMirror(true, LevelInfo)
+	Logger().Print("1")
+	Logger().Print("2")
+	if mirrored.String() != "svc: 1\nsvc: 2\n" {
+		test.Errorf("Mirrored: %q", mirrored.String())
+	}
+}
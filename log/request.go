@@ -0,0 +1,145 @@
+package log
+
+import (
+	"fmt"
+	"sync"
+)
+
+/*
+A RequestLog logs the entries of a single request, each prefixed with the request's correlation ID.
+
+Debug entries are written as they are logged unless tail sampling is enabled by SetTailSampling. With tail sampling,
+a request's debug entries are buffered and only written when the request ends (see End) if it failed, i.e. if
+Errorf or Fail was called. This gives full detail for failed requests without the volume of debug entries for
+successful ones. Since buffered entries are written late, each one includes the time it was logged.
+
+A RequestLog may be used concurrently by the goroutines of its request.
+*/
+type RequestLog struct {
+	l       *LoggerT
+	id      string
+	m       sync.Mutex
+	debug   []string
+	dropped int
+	failed  bool
+	ended   bool
+}
+
+/*
+SetTailSampling enables tail sampling of debug entries with a buffer of at most maxEntries debug entries per request;
+once a request's buffer is full, its oldest entries are dropped. A max of 0, the default, disables tail sampling.
+*/
+func SetTailSampling(maxEntries int) {
+	logger.m.Lock()
+	defer logger.m.Unlock()
+	logger.maxDebugEntries = maxEntries
+}
+
+/*
+Request creates a RequestLog for a request identified by a correlation ID.
+*/
+func (l *LoggerT) Request(correlationID string) *RequestLog {
+	return &RequestLog{l: l, id: correlationID}
+}
+
+/*
+Debugf logs a debug entry, or buffers it if tail sampling is enabled.
+*/
+func (r *RequestLog) Debugf(format string, v ...interface{}) {
+	var (
+		max   int
+		entry string
+	)
+
+	r.l.m.Lock()
+	max = r.l.maxDebugEntries
+	r.l.m.Unlock()
+
+	if max <= 0 {
+		r.output(LevelDebug, "DEBUG "+format, v)
+		return
+	}
+
+	entry = fmt.Sprintf("[%v] DEBUG %v ", r.id, r.l.now().Format("15:04:05.000000")) + fmt.Sprintf(format, r.l.limitFields(v)...)
+	entry = r.l.withStack(LevelDebug, entry, 0)
+	r.m.Lock()
+	defer r.m.Unlock()
+	if r.ended {
+		return
+	}
+	if len(r.debug) >= max {
+		r.debug = r.debug[1:]
+		r.dropped++
+	}
+	r.debug = append(r.debug, entry)
+}
+
+/*
+Printf logs an entry.
+*/
+func (r *RequestLog) Printf(format string, v ...interface{}) {
+	r.output(LevelInfo, format, v)
+}
+
+/*
+Warnf logs a warning entry. Unlike Errorf, it does not mark the request failed.
+*/
+func (r *RequestLog) Warnf(format string, v ...interface{}) {
+	r.output(LevelWarn, "WARN "+format, v)
+}
+
+/*
+Errorf logs an error entry and marks the request failed.
+*/
+func (r *RequestLog) Errorf(format string, v ...interface{}) {
+	r.Fail()
+	r.output(LevelError, "ERROR "+format, v)
+}
+
+//output writes an entry of a level prefixed with the correlation ID; its call depth reports the caller of the RequestLog method
+func (r *RequestLog) output(level Level, format string, v []interface{}) {
+	var entry = fmt.Sprintf("[%v] ", r.id) + fmt.Sprintf(format, r.l.limitFields(v)...)
+
+	r.l.output(4, level, r.l.limitLine(r.l.withStack(level, entry, 1)))
+}
+
+/*
+Fail marks the request failed so that End writes its buffered debug entries.
+*/
+func (r *RequestLog) Fail() {
+	r.m.Lock()
+	defer r.m.Unlock()
+	r.failed = true
+}
+
+/*
+End ends the request. If it failed, its buffered debug entries are written; otherwise they are discarded.
+Later debug entries are ignored.
+*/
+func (r *RequestLog) End() {
+	var (
+		debug   []string
+		dropped int
+		failed  bool
+	)
+
+	r.m.Lock()
+	if r.ended {
+		r.m.Unlock()
+		return
+	}
+	r.ended = true
+	debug, dropped, failed = r.debug, r.dropped, r.failed
+	r.debug = nil
+	r.m.Unlock()
+
+	if !failed {
+		return
+	}
+	if dropped > 0 {
+		r.l.output(3, LevelDebug, fmt.Sprintf("[%v] DEBUG %v earlier debug entries were dropped", r.id, dropped))
+	}
+	for _, entry := range debug {
+		r.l.output(3, LevelDebug, r.l.limitLine(entry))
+	}
+}
@@ -0,0 +1,73 @@
+package log
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestLog(test *testing.T) {
+	var (
+		output, _ = capture(test)
+		r         = Logger().Request("r1")
+	)
+
+	//Without tail sampling, debug entries are written as they are logged
+	r.Debugf("d%v", 1)
+	r.Printf("i%v", 1)
+	r.Warnf("w%v", 1)
+	r.End()
+	r.Debugf("d%v", 2)
+	if output.String() != "[r1] DEBUG d1\n[r1] i1\n[r1] WARN w1\n[r1] DEBUG d2\n" {
+		test.Errorf("Request entries: %q", output)
+	}
+}
+
+func TestTailSampling(test *testing.T) {
+	var (
+		output, fake = capture(test)
+		ok           = Logger().Request("ok")
+		failed       = Logger().Request("failed")
+	)
+
+	SetTailSampling(2)
+
+	//The debug entries of a request that did not fail are discarded
+	ok.Debugf("d1")
+	ok.Warnf("w1")
+	ok.End()
+	if output.String() != "[ok] WARN w1\n" {
+		test.Errorf("Entries of a successful request: %q", output)
+	}
+
+	//Those of a failed request are written when it ends, with the time they were logged, and the oldest are dropped
+	output.Reset()
+	for _, entry := range []string{"d1", "d2", "d3"} {
+		failed.Debugf("%v", entry)
+		fake.Advance(time.Second)
+	}
+	failed.Errorf("e1")
+	if output.String() != "[failed] ERROR e1\n" {
+		test.Errorf("Entries of a failed request before it ends: %q", output)
+	}
+	failed.End()
+	if output.String() != "[failed] ERROR e1\n[failed] DEBUG 1 earlier debug entries were dropped\n[failed] DEBUG 03:04:06.000000 d2\n[failed] DEBUG 03:04:07.000000 d3\n" {
+		test.Errorf("Entries of a failed request: %q", output)
+	}
+
+	//Once a request has ended, its debug entries are ignored and End does nothing
+	output.Reset()
+	failed.Debugf("d4")
+	failed.End()
+	if output.String() != "" {
+		test.Errorf("Entries after End: %q", output)
+	}
+
+	//Fail marks a request failed without an error entry
+	failed = Logger().Request("fail")
+	failed.Debugf("d1")
+	failed.Fail()
+	failed.End()
+	if output.String() != "[fail] DEBUG 03:04:08.000000 d1\n" {
+		test.Errorf("Entries of a request marked failed: %q", output)
+	}
+}
@@ -0,0 +1,108 @@
+package log
+
+import (
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"strings"
+	"time"
+)
+
+//A Level is the severity of a log entry
+type Level int
+
+//The entry Levels in increasing severity
+const (
+	//LevelDebug is a RequestLog Debugf or DebugCtx entry
+	LevelDebug Level = iota
+
+	//LevelInfo is a Print, Printf, Println, InfoCtx or RequestLog Printf entry
+	LevelInfo
+
+	//LevelWarn is a RequestLog Warnf or WarnCtx entry
+	LevelWarn
+
+	//LevelError is a RequestLog Errorf or ErrorCtx entry
+	LevelError
+
+	//LevelFatal is a Fatal or Panic entry
+	LevelFatal
+)
+
+//maxStackTraces limits the number of traces remembered for deduplication
+const maxStackTraces = 1000
+
+//stackPolicy is the stack trace attachment policy set by SetStackTraces
+type stackPolicy struct {
+	min    Level
+	depth  int
+	window time.Duration
+	seen   map[uint64]time.Time
+}
+
+/*
+SetStackTraces attaches the stack trace of the logging call, of at most depth frames, to entries of the min Level and
+above (e.g. LevelError for errors, fatals and panics). A trace identical to one attached within the window is not
+repeated; the entry refers to the earlier trace's ID instead. A depth of 0, the default, disables stack traces.
+*/
+func SetStackTraces(min Level, depth int, window time.Duration) {
+	logger.m.Lock()
+	defer logger.m.Unlock()
+	logger.stacks = stackPolicy{min: min, depth: depth, window: window, seen: make(map[uint64]time.Time)}
+}
+
+/*
+withStack appends the stack trace of the logging call to an entry of a level if the policy requires it. The skip
+is the number of frames between the logging method that called withStack and the logging call.
+*/
+func (l *LoggerT) withStack(level Level, entry string, skip int) string {
+	var (
+		pcs    []uintptr
+		n      int
+		id     uint64
+		hash   = fnv.New64a()
+		frames *runtime.Frames
+		trace  strings.Builder
+		now    = l.now()
+	)
+
+	l.m.Lock()
+	defer l.m.Unlock()
+	if l.stacks.depth <= 0 || level < l.stacks.min {
+		return entry
+	}
+
+	//Frames 0, 1 and 2 are runtime.Callers, withStack and the logging method
+	pcs = make([]uintptr, l.stacks.depth)
+	n = runtime.Callers(3+skip, pcs)
+	pcs = pcs[:n]
+	for _, pc := range pcs {
+		fmt.Fprintf(hash, "%x.", pc)
+	}
+	id = hash.Sum64()
+
+	if last, ok := l.stacks.seen[id]; ok && now.Sub(last) < l.stacks.window {
+		return fmt.Sprintf("%v\n[stack trace %016x repeated]", entry, id)
+	}
+	if len(l.stacks.seen) >= maxStackTraces {
+		for seenID, last := range l.stacks.seen {
+			if now.Sub(last) >= l.stacks.window {
+				delete(l.stacks.seen, seenID)
+			}
+		}
+	}
+	if len(l.stacks.seen) < maxStackTraces {
+		l.stacks.seen[id] = now
+	}
+
+	fmt.Fprintf(&trace, "%v\n[stack trace %016x]", entry, id)
+	frames = runtime.CallersFrames(pcs)
+	for {
+		frame, more := frames.Next()
+		fmt.Fprintf(&trace, "\n\t%v\n\t\t%v:%v", frame.Function, frame.File, frame.Line)
+		if !more {
+			break
+		}
+	}
+	return trace.String()
+}
@@ -0,0 +1,61 @@
+package log
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+//stackTrace matches the stack trace header of an entry
+var stackTrace = regexp.MustCompile(`\[stack trace ([0-9a-f]{16})( repeated)?\]`)
+
+func TestStackTraces(test *testing.T) {
+	var (
+		output, fake = capture(test)
+		r            = Logger().Request("r1")
+		traces       [][]string
+	)
+
+	//The calls of logError in the loop have identical stacks
+	SetStackTraces(LevelError, 2, time.Minute)
+	logError := func() {
+		r.Errorf("failed")
+	}
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			fake.Advance(time.Minute)
+		}
+		logError()
+	}
+	r.Warnf("slow")
+	r.Errorf("failed elsewhere")
+
+	traces = stackTrace.FindAllStringSubmatch(output.String(), -1)
+	if len(traces) != 4 || strings.Contains(output.String(), "WARN slow\n[stack trace") {
+		test.Fatalf("Entries: %v", output)
+	}
+
+	//The trace reports the logging call, with at most depth frames
+	entry := strings.SplitN(output.String(), "[r1] ERROR failed\n", 3)[1]
+	if !strings.Contains(entry, "log.TestStackTraces.func1\n\t\t") || !strings.Contains(entry, "stack_test.go:") || strings.Count(entry, "\n\t\t") != 2 {
+		test.Errorf("Stack trace: %v", entry)
+	}
+
+	//A repeated trace refers to the earlier one within the window and is attached in full again after it
+	if traces[0][2] != "" || traces[1][2] != " repeated" || traces[1][1] != traces[0][1] || traces[2][2] != "" || traces[2][1] != traces[0][1] {
+		test.Errorf("Repeated traces: %v", traces)
+	}
+	if traces[3][2] != "" || traces[3][1] == traces[0][1] {
+		test.Errorf("Trace of another call: %v", traces[3])
+	}
+
+	//A depth of 0 disables stack traces
+	SetStackTraces(LevelDebug, 0, time.Minute)
+	output.Reset()
+	Logger().Print("info")
+	r.Errorf("failed")
+	if strings.Contains(output.String(), "[stack trace") {
+		test.Errorf("Entries without stack traces: %v", output)
+	}
+}
@@ -0,0 +1,159 @@
+package log
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+/*
+The Ctx logging methods (DebugCtx, InfoCtx, WarnCtx and ErrorCtx) attach the trace and span IDs of the active trace of
+a context to their entries as trace_id and span_id fields, so that log entries can be correlated with traces:
+
+	[trace_id=4bf92f3577b34da6a3ce929d0e0e4736 span_id=00f067aa0ba902b7] WARN upstream slow: 2.5s
+
+The trace of a context is found by the trace extractor set with SetTraceExtractor, e.g. one that reads the span context
+of the OpenTelemetry SDK:
+
+	log.SetTraceExtractor(func(ctx context.Context) (log.Trace, bool) {
+		sc := trace.SpanContextFromContext(ctx)
+		return log.Trace{TraceID: sc.TraceID().String(), SpanID: sc.SpanID().String()}, sc.IsValid()
+	})
+
+If there is no extractor, or it finds no trace, the trace put in the context by WithTraceparent (e.g. from the W3C
+traceparent header of an incoming request) or WithTrace is used. An entry without a trace has no fields.
+*/
+
+//A Trace identifies the trace and span of a log entry
+type Trace struct {
+	TraceID string
+	SpanID  string
+}
+
+//traceKey is the context key of a Trace
+type traceKey struct{}
+
+//traceExtractor is the trace extractor set by SetTraceExtractor; it is mutexed since it may be set while requests are logged
+var traceExtractor = struct {
+	m sync.Mutex
+	f func(context.Context) (Trace, bool)
+}{}
+
+/*
+SetTraceExtractor sets the function that finds the active trace of a context; nil removes it.
+*/
+func SetTraceExtractor(f func(context.Context) (Trace, bool)) {
+	traceExtractor.m.Lock()
+	defer traceExtractor.m.Unlock()
+	traceExtractor.f = f
+}
+
+/*
+WithTrace returns a copy of the context that carries a Trace.
+*/
+func WithTrace(ctx context.Context, trace Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+/*
+WithTraceparent returns a copy of the context that carries the Trace of a W3C traceparent header value
+(version-traceid-parentid-flags). It returns the context unchanged if the value is not a valid traceparent.
+*/
+func WithTraceparent(ctx context.Context, traceparent string) context.Context {
+	var trace, ok = parseTraceparent(traceparent)
+
+	if !ok {
+		return ctx
+	}
+	return WithTrace(ctx, trace)
+}
+
+//parseTraceparent parses a W3C traceparent header value; all zero trace and parent IDs are invalid
+func parseTraceparent(traceparent string) (Trace, bool) {
+	var fields = strings.Split(strings.TrimSpace(traceparent), "-")
+
+	if len(fields) < 4 || !isLowerHex(fields[0], 2) || fields[0] == "ff" || (fields[0] == "00" && len(fields) != 4) {
+		return Trace{}, false
+	}
+	if !isLowerHex(fields[1], 32) || !isLowerHex(fields[2], 16) || !isLowerHex(fields[3], 2) {
+		return Trace{}, false
+	}
+	if strings.Trim(fields[1], "0") == "" || strings.Trim(fields[2], "0") == "" {
+		return Trace{}, false
+	}
+	return Trace{TraceID: fields[1], SpanID: fields[2]}, true
+}
+
+//isLowerHex is true if s is n lowercase hex digits
+func isLowerHex(s string, n int) bool {
+	if len(s) != n {
+		return false
+	}
+	for _, c := range s {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+/*
+TraceFrom returns the active trace of a context: the one found by the trace extractor, or else the one put in the
+context by WithTrace or WithTraceparent.
+*/
+func TraceFrom(ctx context.Context) (Trace, bool) {
+	var f func(context.Context) (Trace, bool)
+
+	if ctx == nil {
+		return Trace{}, false
+	}
+	traceExtractor.m.Lock()
+	f = traceExtractor.f
+	traceExtractor.m.Unlock()
+	if f != nil {
+		if trace, ok := f(ctx); ok {
+			return trace, true
+		}
+	}
+	trace, ok := ctx.Value(traceKey{}).(Trace)
+	return trace, ok
+}
+
+/*
+DebugCtx logs a debug entry with the trace of the context.
+*/
+func (l *LoggerT) DebugCtx(ctx context.Context, format string, v ...interface{}) {
+	l.ctxOutput(ctx, LevelDebug, "DEBUG "+format, v)
+}
+
+/*
+InfoCtx logs an entry with the trace of the context.
+*/
+func (l *LoggerT) InfoCtx(ctx context.Context, format string, v ...interface{}) {
+	l.ctxOutput(ctx, LevelInfo, format, v)
+}
+
+/*
+WarnCtx logs a warning entry with the trace of the context.
+*/
+func (l *LoggerT) WarnCtx(ctx context.Context, format string, v ...interface{}) {
+	l.ctxOutput(ctx, LevelWarn, "WARN "+format, v)
+}
+
+/*
+ErrorCtx logs an error entry with the trace of the context.
+*/
+func (l *LoggerT) ErrorCtx(ctx context.Context, format string, v ...interface{}) {
+	l.ctxOutput(ctx, LevelError, "ERROR "+format, v)
+}
+
+//ctxOutput writes an entry of a level prefixed with the trace fields of a context; its call depth reports the caller of the Ctx method
+func (l *LoggerT) ctxOutput(ctx context.Context, level Level, format string, v []interface{}) {
+	var entry = fmt.Sprintf(format, l.limitFields(v)...)
+
+	if trace, ok := TraceFrom(ctx); ok {
+		entry = fmt.Sprintf("[trace_id=%v span_id=%v] ", trace.TraceID, trace.SpanID) + entry
+	}
+	l.output(4, level, l.limitLine(l.withStack(level, entry, 1)))
+}
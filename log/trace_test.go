@@ -0,0 +1,82 @@
+package log
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseTraceparent(test *testing.T) {
+	var (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+		cases   = []struct {
+			traceparent string
+			ok          bool
+		}{
+			{"00-" + traceID + "-" + spanID + "-01", true},
+			{" 00-" + traceID + "-" + spanID + "-00 ", true},
+
+			//A later version may have more fields
+			{"01-" + traceID + "-" + spanID + "-01-extra", true},
+			{"00-" + traceID + "-" + spanID + "-01-extra", false},
+			{"ff-" + traceID + "-" + spanID + "-01", false},
+			{"0-" + traceID + "-" + spanID + "-01", false},
+			{"00-" + traceID + "-" + spanID, false},
+			{"00-4BF92F3577B34DA6A3CE929D0E0E4736-" + spanID + "-01", false},
+			{"00-" + traceID[1:] + "-" + spanID + "-01", false},
+			{"00-" + traceID + "-" + spanID + "0-01", false},
+			{"00-" + traceID + "-" + spanID + "-1", false},
+			{"00-" + traceID + "-" + spanID + "-0g", false},
+			{"00-00000000000000000000000000000000-" + spanID + "-01", false},
+			{"00-" + traceID + "-0000000000000000-01", false},
+			{"", false},
+		}
+	)
+
+	for _, c := range cases {
+		trace, ok := parseTraceparent(c.traceparent)
+		if ok != c.ok || (ok && (trace.TraceID != traceID || trace.SpanID != spanID)) {
+			test.Errorf("parseTraceparent %q: %+v %v", c.traceparent, trace, ok)
+		}
+	}
+}
+
+//extractedKey is the context key of the test's trace extractor
+type extractedKey struct{}
+
+func TestTraceFrom(test *testing.T) {
+	var (
+		output, _ = capture(test)
+		ctx       = WithTraceparent(context.Background(), "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		extracted = Trace{TraceID: "t", SpanID: "s"}
+	)
+	defer SetTraceExtractor(nil)
+
+	if trace, ok := TraceFrom(ctx); !ok || trace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || trace.SpanID != "00f067aa0ba902b7" {
+		test.Errorf("TraceFrom a traceparent: %+v %v", trace, ok)
+	}
+	if _, ok := TraceFrom(WithTraceparent(context.Background(), "bad")); ok {
+		test.Errorf("TraceFrom a bad traceparent")
+	}
+	if _, ok := TraceFrom(nil); ok {
+		test.Errorf("TraceFrom a nil context")
+	}
+
+	//The extractor's trace takes precedence, but the context's is used if it finds none
+	SetTraceExtractor(func(ctx context.Context) (Trace, bool) {
+		return extracted, ctx.Value(extractedKey{}) != nil
+	})
+	if trace, ok := TraceFrom(context.WithValue(ctx, extractedKey{}, true)); !ok || trace != extracted {
+		test.Errorf("TraceFrom with an extractor: %+v %v", trace, ok)
+	}
+	if trace, ok := TraceFrom(ctx); !ok || trace.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		test.Errorf("TraceFrom with an extractor that finds no trace: %+v %v", trace, ok)
+	}
+
+	Logger().InfoCtx(WithTrace(context.Background(), Trace{TraceID: "a", SpanID: "b"}), "info %v", 1)
+	Logger().WarnCtx(context.Background(), "warn")
+	Logger().ErrorCtx(WithTrace(context.Background(), Trace{TraceID: "a", SpanID: "b"}), "error")
+	if output.String() != "[trace_id=a span_id=b] info 1\nWARN warn\n[trace_id=a span_id=b] ERROR error\n" {
+		test.Errorf("Ctx entries: %q", output)
+	}
+}
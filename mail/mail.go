@@ -0,0 +1,251 @@
+/*
+Package mail delivers out-of-band notifications from test tooling, such as captured OTPs, conformance report completion
+and probe alerts, so that each tool does not invent its own delivery mechanism.
+
+A Message is delivered by a Sender. The backends are:
+
+	SMTP	- sends the Message as a plain text e-mail via an SMTP server (see NewSMTP)
+	Webhook	- POSTs the Message as JSON to a URL, e.g. a chat or alerting integration (see NewWebhook)
+
+Multi fans a Message out to several Senders. The Kind of a Message (e.g. KindOTP) lets a receiver route it.
+
+Most executables configure the shared Default Sender with SetDefault and deliver with Send. Until it is configured,
+the Default Sender logs and discards Messages.
+*/
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/develrns/resilient/log"
+)
+
+var logger = log.Logger()
+
+//The Message Kinds sent by this repository's test tooling
+const (
+	//KindOTP is an OTP captured from an OP second factor flow
+	KindOTP = "otp"
+
+	//KindReport is the completion of a conformance or load test report
+	KindReport = "report"
+
+	//KindAlert is a probe alert
+	KindAlert = "alert"
+)
+
+//A Message is a notification
+type Message struct {
+	Kind    string   `json:"kind"`
+	To      []string `json:"to,omitempty"`
+	Subject string   `json:"subject"`
+	Body    string   `json:"body"`
+}
+
+/*
+A Sender delivers Messages. Send returns an error if the Message was not accepted by the backend.
+*/
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+/*
+SMTP is a Sender that sends Messages as plain text e-mail via an SMTP server. A Message without recipients is sent to
+the SMTP Sender's default recipients.
+*/
+type SMTP struct {
+	addr string
+	from string
+	to   []string
+	auth smtp.Auth
+}
+
+/*
+NewSMTP creates an SMTP Sender that sends from an address via the SMTP server at addr (host:port) with auth, which may
+be nil (e.g. smtp.PlainAuth). The server must support STARTTLS if auth is set.
+*/
+func NewSMTP(addr, from string, to []string, auth smtp.Auth) *SMTP {
+	return &SMTP{addr: addr, from: from, to: to, auth: auth}
+}
+
+/*
+Send implements Sender. The net/smtp client cannot be cancelled, so the Context is only checked before sending.
+*/
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	var (
+		to  = msg.To
+		buf bytes.Buffer
+		err error
+	)
+
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	if len(to) == 0 {
+		to = s.to
+	}
+	if len(to) == 0 {
+		return fmt.Errorf("Message Has No Recipients: %v", msg.Subject)
+	}
+	for _, header := range append([]string{s.from, msg.Subject}, to...) {
+		if strings.ContainsAny(header, "\r\n") {
+			return fmt.Errorf("Bad Message Header: %q", header)
+		}
+	}
+
+	fmt.Fprintf(&buf, "From: %v\r\n", s.from)
+	fmt.Fprintf(&buf, "To: %v\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&buf, "Subject: %v\r\n", msg.Subject)
+	fmt.Fprintf(&buf, "Date: %v\r\n", time.Now().Format(time.RFC1123Z))
+	if msg.Kind != "" {
+		fmt.Fprintf(&buf, "X-Notification-Kind: %v\r\n", msg.Kind)
+	}
+	buf.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	buf.WriteString(strings.Replace(msg.Body, "\n", "\r\n", -1))
+
+	err = smtp.SendMail(s.addr, s.auth, s.from, to, buf.Bytes())
+	if err != nil {
+		return fmt.Errorf("SMTP Send Failed: %v", err)
+	}
+	return nil
+}
+
+/*
+Webhook is a Sender that POSTs each Message as JSON to a URL:
+
+	{"kind": "otp", "to": ["..."], "subject": "...", "body": "..."}
+
+A 2xx response is success.
+*/
+type Webhook struct {
+	url    string
+	client *http.Client
+	header http.Header
+}
+
+/*
+NewWebhook creates a Webhook Sender that POSTs to url with client; a nil client is one with a 30 second timeout. The
+header (e.g. an Authorization header), which may be nil, is added to each request.
+*/
+func NewWebhook(url string, client *http.Client, header http.Header) *Webhook {
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &Webhook{url: url, client: client, header: header}
+}
+
+/*
+Send implements Sender.
+*/
+func (wh *Webhook) Send(ctx context.Context, msg Message) error {
+	var (
+		body []byte
+		req  *http.Request
+		rsp  *http.Response
+		err  error
+	)
+
+	body, err = json.Marshal(&msg)
+	if err != nil {
+		return err
+	}
+	req, err = http.NewRequest("POST", wh.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	for name, values := range wh.header {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	rsp, err = wh.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("Webhook Send Failed: %v", err)
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return fmt.Errorf("Webhook Send Failed: %v responded %v", wh.url, rsp.Status)
+	}
+	return nil
+}
+
+//multi is the Sender returned by Multi
+type multi []Sender
+
+/*
+Multi returns a Sender that sends each Message to all of the senders. Its Send tries every Sender and returns the
+errors of those that failed, if any, as one error.
+*/
+func Multi(senders ...Sender) Sender {
+	return multi(senders)
+}
+
+/*
+Send implements Sender.
+*/
+func (m multi) Send(ctx context.Context, msg Message) error {
+	var failures []string
+
+	for _, sender := range m {
+		if err := sender.Send(ctx, msg); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("%v of %v Senders Failed: %v", len(failures), len(m), strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+//discard is the Default Sender until SetDefault is called; it logs and discards Messages
+type discard struct{}
+
+//Send implements Sender
+func (discard) Send(ctx context.Context, msg Message) error {
+	logger.Printf("mail: no Sender is configured; discarded %v message: %v\n", msg.Kind, msg.Subject)
+	return nil
+}
+
+//defaultSender is the shared Sender of an executable. It is mutexed since it may be set while tooling sends.
+var defaultSender = struct {
+	m sync.Mutex
+	s Sender
+}{s: discard{}}
+
+/*
+SetDefault sets the shared Default Sender; a nil Sender restores the one that logs and discards Messages.
+*/
+func SetDefault(s Sender) {
+	if s == nil {
+		s = discard{}
+	}
+	defaultSender.m.Lock()
+	defer defaultSender.m.Unlock()
+	defaultSender.s = s
+}
+
+/*
+Default returns the shared Default Sender.
+*/
+func Default() Sender {
+	defaultSender.m.Lock()
+	defer defaultSender.m.Unlock()
+	return defaultSender.s
+}
+
+/*
+Send sends a Message with the Default Sender.
+*/
+func Send(ctx context.Context, msg Message) error {
+	return Default().Send(ctx, msg)
+}
@@ -0,0 +1,176 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+//smtpServer accepts one SMTP session on a local listener and sends the DATA it receives
+func smtpServer(test *testing.T) (string, <-chan string) {
+	var (
+		data     = make(chan string, 1)
+		listener net.Listener
+		err      error
+	)
+
+	listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		test.Fatalf("Listen: %v", err)
+	}
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		tp := textproto.NewConn(conn)
+		tp.PrintfLine("220 localhost ready")
+		for {
+			line, err := tp.ReadLine()
+			if err != nil {
+				return
+			}
+			switch verb := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); verb {
+			case "DATA":
+				tp.PrintfLine("354 go ahead")
+				lines, _ := tp.ReadDotLines()
+				data <- strings.Join(lines, "\n")
+				tp.PrintfLine("250 queued")
+			case "QUIT":
+				tp.PrintfLine("221 bye")
+				return
+			default:
+				tp.PrintfLine("250 ok")
+			}
+		}
+	}()
+	return listener.Addr().String(), data
+}
+
+func TestSMTP(test *testing.T) {
+	var (
+		addr, data = smtpServer(test)
+		s          = NewSMTP(addr, "tool@ex.org", []string{"ops@ex.org"}, nil)
+		err        error
+	)
+
+	err = s.Send(context.Background(), Message{Kind: KindOTP, Subject: "OTP", Body: "123456\nexpires soon"})
+	if err != nil {
+		test.Fatalf("Send: %v", err)
+	}
+	message := <-data
+	for _, expected := range []string{"From: tool@ex.org\n", "To: ops@ex.org\n", "Subject: OTP\n", "X-Notification-Kind: otp\n", "\n\n123456\nexpires soon"} {
+		if !strings.Contains(message, expected) {
+			test.Errorf("Message does not contain %q:\n%v", expected, message)
+		}
+	}
+}
+
+func TestSMTPRejects(test *testing.T) {
+	var (
+		s         = NewSMTP("127.0.0.1:1", "tool@ex.org", nil, nil)
+		cancelled context.Context
+		cancel    context.CancelFunc
+	)
+
+	cases := []Message{
+		{Subject: "no recipients"},
+		{To: []string{"ops@ex.org"}, Subject: "OTP\r\nBcc: attacker@ex.org"},
+		{To: []string{"ops@ex.org\r\nBcc: attacker@ex.org"}, Subject: "OTP"},
+	}
+	for _, msg := range cases {
+		if err := s.Send(context.Background(), msg); err == nil {
+			test.Errorf("Send %q should fail", msg)
+		}
+	}
+	cancelled, cancel = context.WithCancel(context.Background())
+	cancel()
+	if err := s.Send(cancelled, Message{To: []string{"ops@ex.org"}}); !errors.Is(err, context.Canceled) {
+		test.Errorf("Send with a cancelled Context: %v", err)
+	}
+}
+
+func TestWebhook(test *testing.T) {
+	var (
+		received Message
+		auth     string
+		status   = http.StatusNoContent
+		srv      = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth = r.Header.Get("Authorization")
+			json.NewDecoder(r.Body).Decode(&received)
+			w.WriteHeader(status)
+		}))
+		wh  = NewWebhook(srv.URL, nil, http.Header{"Authorization": {"Bearer t"}})
+		msg = Message{Kind: KindAlert, To: []string{"ops"}, Subject: "probe failed", Body: "details"}
+	)
+	defer srv.Close()
+
+	if err := wh.Send(context.Background(), msg); err != nil {
+		test.Fatalf("Send: %v", err)
+	}
+	if auth != "Bearer t" || received.Kind != msg.Kind || received.Subject != msg.Subject || received.Body != msg.Body || len(received.To) != 1 {
+		test.Errorf("Received: %v %+v", auth, received)
+	}
+
+	status = http.StatusBadGateway
+	if err := wh.Send(context.Background(), msg); err == nil || !strings.Contains(err.Error(), "502") {
+		test.Errorf("Send to a failing webhook: %v", err)
+	}
+}
+
+//recorder is a Sender that records its Messages and fails with its err
+type recorder struct {
+	msgs []Message
+	err  error
+}
+
+func (r *recorder) Send(ctx context.Context, msg Message) error {
+	r.msgs = append(r.msgs, msg)
+	return r.err
+}
+
+func TestMulti(test *testing.T) {
+	var (
+		ok     = &recorder{}
+		failed = &recorder{err: errors.New("backend down")}
+		m      = Multi(failed, ok, failed)
+		err    = m.Send(context.Background(), Message{Subject: "s"})
+	)
+
+	//Every Sender is tried even if an earlier one fails
+	if err == nil || !strings.HasPrefix(err.Error(), "2 of 3 Senders Failed") || len(ok.msgs) != 1 || len(failed.msgs) != 2 {
+		test.Errorf("Multi Send: %v %v %v", err, ok.msgs, failed.msgs)
+	}
+	if err = Multi(ok).Send(context.Background(), Message{}); err != nil {
+		test.Errorf("Multi Send: %v", err)
+	}
+}
+
+func TestDefault(test *testing.T) {
+	var r = &recorder{}
+	defer SetDefault(nil)
+
+	if err := Send(context.Background(), Message{Subject: "discarded"}); err != nil {
+		test.Errorf("Send with the discarding Default: %v", err)
+	}
+	SetDefault(r)
+	if Default() != Sender(r) {
+		test.Errorf("Default: %v", Default())
+	}
+	Send(context.Background(), Message{Subject: "s"})
+	if len(r.msgs) != 1 {
+		test.Errorf("Send: %v", r.msgs)
+	}
+	SetDefault(nil)
+	if _, ok := Default().(discard); !ok {
+		test.Errorf("SetDefault nil: %T", Default())
+	}
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+/*
+OpenID Connect Back-Channel Logout 1.0 lets the OP end the RP sessions of an OP session directly. The OP POSTs a
+logout_token form parameter to /backchannel-logout. The logout token is a JWT signed by the OP, which is verified
+with the OP's JWKS (or, for HS256, the secret this RP shares with its OP) like a JARM response. Its claims must have:
+
+	iss		- the OP
+	aud		- this RP's client ID
+	iat		- the time it was issued, which is no more than logoutTokenAge ago
+	exp		- if present, a time that has not passed
+	jti		- a unique identifier, which has not been seen in another logout token
+	events		- an object with a http://schemas.openid.net/event/backchannel-logout member
+	sid and/or sub	- the OP session or subject whose RP sessions are destroyed
+	nonce		- must not be present, so that an ID Token cannot be used as a logout token
+
+If the token is valid, the RP sessions of the sid (or, without a sid, all the RP sessions of the sub) are destroyed and
+the response is 200 OK, even if there were none. Otherwise the response is 400 Bad Request with a JSON error body.
+
+The jti of each valid logout token is remembered until its iat is too old for it to be accepted again, so that a
+captured logout token cannot be replayed.
+*/
+
+//backchannelLogoutEvent is the events member that identifies a logout token
+const backchannelLogoutEvent = "http://schemas.openid.net/event/backchannel-logout"
+
+//logoutTokenAge is the age of a logout token, by its iat, after which it is rejected
+const logoutTokenAge = 10 * time.Minute
+
+//logoutJTIs holds the jti of each accepted logout token and the time after which it is forgotten. Since it is
+//accessed by concurrent requests, it must be mutexed.
+var logoutJTIs = struct {
+	m sync.Mutex
+	s map[string]time.Time
+}{s: make(map[string]time.Time)}
+
+//rememberLogoutJTI adds a logout token's jti to logoutJTIs, purging the forgotten ones. It returns false if the jti
+//has already been seen.
+func rememberLogoutJTI(jti string, iat time.Time) bool {
+	var now = clk.Now()
+
+	logoutJTIs.m.Lock()
+	defer logoutJTIs.m.Unlock()
+	for seen, forget := range logoutJTIs.s {
+		if now.After(forget) {
+			delete(logoutJTIs.s, seen)
+		}
+	}
+	if _, ok := logoutJTIs.s[jti]; ok {
+		return false
+	}
+	logoutJTIs.s[jti] = iat.Add(logoutTokenAge)
+	return true
+}
+
+/*
+handleBackchannelLogout implements the RP back-channel logout endpoint.
+*/
+func handleBackchannelLogout(w http.ResponseWriter, r *http.Request) {
+	var (
+		token    *jwt.Token
+		sid, sub string
+		deleted  int
+		err      error
+	)
+
+	w.Header().Set("Cache-Control", "no-store")
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = ioutil.NopCloser(io.LimitReader(r.Body, 64*1024))
+	logoutToken := r.PostFormValue("logout_token")
+	if logoutToken == "" {
+		writeLogoutError(w, fmt.Errorf("Missing logout_token"))
+		return
+	}
+	token, err = jwt.Parse(logoutToken, opKeyfunc)
+	if err != nil {
+		writeLogoutError(w, fmt.Errorf("Logout Token Verification Failed: %v", err))
+		return
+	}
+	sid, sub, err = validateLogoutClaims(token.Claims)
+	if err != nil {
+		writeLogoutError(w, fmt.Errorf("Logout Token Validation Failed: %v", err))
+		return
+	}
+	deleted = deleteSessions(sid, sub)
+	logger.Printf("Back-channel logout of sid %v sub %v destroyed %v sessions\n", sid, sub, deleted)
+	w.WriteHeader(http.StatusOK)
+}
+
+//writeLogoutError responds with 400 Bad Request and an invalid_request JSON error body
+func writeLogoutError(w http.ResponseWriter, err error) {
+	var body, _ = json.Marshal(map[string]string{"error": "invalid_request", "error_description": err.Error()})
+
+	logger.Printf("Back-channel logout rejected: %v\n", err)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	w.Write(body)
+}
+
+//validateLogoutClaims checks the claims of a logout token and returns its sid and sub
+func validateLogoutClaims(claims map[string]interface{}) (string, string, error) {
+	var (
+		sid, sub string
+		jti      string
+		events   map[string]interface{}
+		iat, exp float64
+		ok       bool
+	)
+
+	if claims["iss"] != opIssuer {
+		return "", "", fmt.Errorf("Bad iss: %v", claims["iss"])
+	}
+	if !isAudience(claims["aud"]) {
+		return "", "", fmt.Errorf("Bad aud: %v", claims["aud"])
+	}
+	iat, ok = claims["iat"].(float64)
+	if !ok {
+		return "", "", fmt.Errorf("Missing iat")
+	}
+	if clk.Now().Add(time.Minute).Before(time.Unix(int64(iat), 0)) {
+		return "", "", fmt.Errorf("Issued in the future at: %v", time.Unix(int64(iat), 0).UTC())
+	}
+	if clk.Now().After(time.Unix(int64(iat), 0).Add(logoutTokenAge)) {
+		return "", "", fmt.Errorf("Issued too long ago at: %v", time.Unix(int64(iat), 0).UTC())
+	}
+	if exp, ok = claims["exp"].(float64); ok && clk.Now().After(time.Unix(int64(exp), 0)) {
+		return "", "", fmt.Errorf("Expired at: %v", time.Unix(int64(exp), 0).UTC())
+	}
+	if jti, _ = claims["jti"].(string); jti == "" {
+		return "", "", fmt.Errorf("Missing jti")
+	}
+	events, ok = claims["events"].(map[string]interface{})
+	if !ok {
+		return "", "", fmt.Errorf("Missing events")
+	}
+	if _, ok = events[backchannelLogoutEvent].(map[string]interface{}); !ok {
+		return "", "", fmt.Errorf("Missing %v event", backchannelLogoutEvent)
+	}
+	if _, ok = claims["nonce"]; ok {
+		return "", "", fmt.Errorf("A logout token must not have a nonce")
+	}
+	sid, _ = claims["sid"].(string)
+	sub, _ = claims["sub"].(string)
+	if sid == "" && sub == "" {
+		return "", "", fmt.Errorf("Missing sid and sub")
+	}
+
+	//The jti is remembered last so that an invalid token cannot use up the jti of a valid one
+	if !rememberLogoutJTI(jti, time.Unix(int64(iat), 0)) {
+		return "", "", fmt.Errorf("Replayed jti: %v", jti)
+	}
+	return sid, sub, nil
+}
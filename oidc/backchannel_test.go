@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+//logout POSTs a logout token to the back-channel logout endpoint and returns the response
+func logout(logoutToken string) *httptest.ResponseRecorder {
+	var (
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest("POST", "/backchannel-logout", strings.NewReader(url.Values{"logout_token": {logoutToken}}.Encode()))
+	)
+
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	handleBackchannelLogout(w, r)
+	return w
+}
+
+func TestBackchannelLogout(test *testing.T) {
+	var (
+		o      = newOP(test)
+		events = map[string]interface{}{backchannelLogoutEvent: map[string]interface{}{}}
+		claims = func(jti string, extra map[string]interface{}) map[string]interface{} {
+			for name, value := range map[string]interface{}{"jti": jti, "events": events, "exp": nil, "sid": "op-session"} {
+				if _, ok := extra[name]; !ok {
+					extra[name] = value
+				}
+			}
+			return o.claims(extra)
+		}
+		cases = []struct {
+			name  string
+			token string
+			err   string
+		}{
+			{"wrong iss", o.sign(test, "HS256", claims("1", map[string]interface{}{"iss": "https://evil.org"})), "Bad iss"},
+			{"wrong aud", o.sign(test, "HS256", claims("2", map[string]interface{}{"aud": "other"})), "Bad aud"},
+			{"missing iat", o.sign(test, "HS256", claims("3", map[string]interface{}{"iat": nil})), "Missing iat"},
+			{"old iat", o.sign(test, "HS256", claims("4", map[string]interface{}{"iat": o.fake.Now().Add(-logoutTokenAge - time.Second).Unix()})), "Issued too long ago"},
+			{"future iat", o.sign(test, "HS256", claims("5", map[string]interface{}{"iat": o.fake.Now().Add(2 * time.Minute).Unix()})), "used before issued"},
+			{"expired", o.sign(test, "HS256", claims("6", map[string]interface{}{"exp": o.fake.Now().Add(-time.Second).Unix()})), "expired"},
+			{"missing jti", o.sign(test, "HS256", claims("", map[string]interface{}{})), "Missing jti"},
+			{"missing events", o.sign(test, "HS256", claims("7", map[string]interface{}{"events": nil})), "Missing events"},
+			{"other event", o.sign(test, "HS256", claims("8", map[string]interface{}{"events": map[string]interface{}{"other": map[string]interface{}{}}})), "Missing " + backchannelLogoutEvent},
+			{"nonce", o.sign(test, "HS256", claims("9", map[string]interface{}{"nonce": "n"})), "must not have a nonce"},
+			{"missing sid and sub", o.sign(test, "HS256", claims("10", map[string]interface{}{"sid": nil})), "Missing sid and sub"},
+			{"alg none", o.sign(test, "none", claims("11", map[string]interface{}{})), "Unsupported OP Signing Algorithm"},
+			{"wrong key", forge(map[string]interface{}{"alg": "HS256"}, claims("12", map[string]interface{}{}), []byte("other secret")), "signature is invalid"},
+			{"missing", "", "Missing logout_token"},
+		}
+	)
+	defer deleteSessions("op-session", "")
+
+	for _, c := range cases {
+		w := logout(c.token)
+		if w.Code != http.StatusBadRequest || w.Header().Get("Content-Type") != "application/json" || !strings.Contains(w.Body.String(), c.err) {
+			test.Errorf("Logout with %v: %v %v", c.name, w.Code, w.Body.String())
+		}
+	}
+
+	//An iat that is ahead of the RP's clock by more than the allowed skew is rejected
+	future := claims("13", map[string]interface{}{"iat": float64(o.fake.Now().Add(2 * time.Minute).Unix())})
+	if _, _, err := validateLogoutClaims(future); err == nil || !strings.HasPrefix(err.Error(), "Issued in the future") {
+		test.Errorf("validateLogoutClaims with a future iat: %v", err)
+	}
+
+	//A valid logout token destroys the RP sessions of its sid
+	addSession(Session{ID: "a", Sub: "ann", SID: "op-session", Expires: o.fake.Now().Add(time.Hour)})
+	addSession(Session{ID: "b", Sub: "ann", SID: "other-session", Expires: o.fake.Now().Add(time.Hour)})
+	valid := o.sign(test, "RS256", claims("valid", map[string]interface{}{"sub": "ann"}))
+	if w := logout(valid); w.Code != http.StatusOK || isActiveSession("a") || !isActiveSession("b") {
+		test.Errorf("Logout: %v %v", w.Code, w.Body.String())
+	}
+
+	//Without a sid, all the sessions of the sub are destroyed
+	if w := logout(o.sign(test, "ES256", claims("sub", map[string]interface{}{"sid": nil, "sub": "ann"}))); w.Code != http.StatusOK || isActiveSession("b") {
+		test.Errorf("Logout of a sub: %v %v", w.Code, w.Body.String())
+	}
+
+	//A replayed token is rejected until its iat is too old for it to be accepted anyway
+	if w := logout(valid); w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "Replayed jti: valid") {
+		test.Errorf("Logout with a replayed token: %v %v", w.Code, w.Body.String())
+	}
+	o.fake.Advance(logoutTokenAge + time.Second)
+	rememberLogoutJTI("new", o.fake.Now())
+	logoutJTIs.m.Lock()
+	_, remembered := logoutJTIs.s["valid"]
+	logoutJTIs.m.Unlock()
+	if remembered {
+		test.Errorf("The jti of an expired token was not forgotten")
+	}
+
+	r := httptest.NewRequest("GET", "/backchannel-logout", nil)
+	w := httptest.NewRecorder()
+	if handleBackchannelLogout(w, r); w.Code != http.StatusMethodNotAllowed {
+		test.Errorf("GET: %v", w.Code)
+	}
+}
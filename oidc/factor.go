@@ -0,0 +1,183 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+/*
+An OP policy may require a second factor during the Authn Request: the OP responds with a factor selection form, then
+an email or SMS OTP entry form, before it redirects back to /authn-token. So that multi-factor policies can be tested
+end to end, the load mode mock browser completes these forms rather than failing the login:
+
+	-factor	- the factor to select in a factor selection form (e.g. email or sms)
+	-otp	- the OTP to enter in an OTP form; the test policy must accept a fixed OTP
+
+The forms' hidden fields, which hold the OP's aead sealed state, are submitted unchanged and checkboxes are left
+unchecked. A form that asks for anything else fails the login.
+*/
+
+//maxFactorForms limits the number of OP forms in the authorize stage
+const maxFactorForms = 5
+
+//otpField matches the names of OTP input fields
+var otpField = regexp.MustCompile(`(?i)otp|passcode|code`)
+
+var (
+	formTag   = regexp.MustCompile(`(?is)<form\b([^>]*)>(.*?)</form>`)
+	inputTag  = regexp.MustCompile(`(?is)<input\b([^>]*)>`)
+	selectTag = regexp.MustCompile(`(?is)<select\b([^>]*)>(.*?)</select>`)
+	optionTag = regexp.MustCompile(`(?is)<option\b([^>]*)>`)
+	attribute = regexp.MustCompile(`(?is)([a-z-]+)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>]+))`)
+)
+
+//An opForm is an HTML form of an OP page
+type opForm struct {
+	action  string
+	method  string
+	fields  url.Values
+	choices map[string][]string
+	inputs  []string
+}
+
+//attributes parses the attributes of an HTML tag
+func attributes(tag string) map[string]string {
+	var attrs = make(map[string]string)
+
+	for _, match := range attribute.FindAllStringSubmatch(tag, -1) {
+		attrs[strings.ToLower(match[1])] = html.UnescapeString(match[2] + match[3] + match[4])
+	}
+	return attrs
+}
+
+/*
+parseOPForm parses the first form of an OP page. Its action is resolved against the page URL.
+*/
+func parseOPForm(page string, pageURL *url.URL) (*opForm, error) {
+	var (
+		match  = formTag.FindStringSubmatch(page)
+		form   = opForm{fields: url.Values{}, choices: make(map[string][]string)}
+		attrs  map[string]string
+		action *url.URL
+		err    error
+	)
+
+	if match == nil {
+		return nil, fmt.Errorf("OP page has no form")
+	}
+	attrs = attributes(match[1])
+	action, err = pageURL.Parse(attrs["action"])
+	if err != nil {
+		return nil, fmt.Errorf("Bad OP form action: %v", attrs["action"])
+	}
+	form.action = action.String()
+	form.method = strings.ToUpper(attrs["method"])
+	if form.method == "" {
+		form.method = "GET"
+	}
+
+	for _, input := range inputTag.FindAllStringSubmatch(match[2], -1) {
+		attrs = attributes(input[1])
+		name := attrs["name"]
+		if name == "" {
+			continue
+		}
+		switch strings.ToLower(attrs["type"]) {
+		case "hidden":
+			form.fields.Add(name, attrs["value"])
+		case "radio":
+			form.choices[name] = append(form.choices[name], attrs["value"])
+		case "checkbox", "submit", "button", "image", "reset":
+		default:
+			form.inputs = append(form.inputs, name)
+		}
+	}
+	for _, sel := range selectTag.FindAllStringSubmatch(match[2], -1) {
+		name := attributes(sel[1])["name"]
+		for _, option := range optionTag.FindAllStringSubmatch(sel[2], -1) {
+			form.choices[name] = append(form.choices[name], attributes(option[1])["value"])
+		}
+	}
+	return &form, nil
+}
+
+/*
+fill selects the factor in the form's choices and enters the OTP in its OTP input.
+*/
+func (f *opForm) fill(factor, otp string) error {
+	for name, values := range f.choices {
+		selected := false
+		for _, value := range values {
+			if factor != "" && strings.EqualFold(value, factor) {
+				f.fields.Set(name, value)
+				selected = true
+			}
+		}
+		if !selected {
+			return fmt.Errorf("OP form %v has no factor %q in %v", name, factor, values)
+		}
+	}
+	for _, name := range f.inputs {
+		if !otpField.MatchString(name) {
+			return fmt.Errorf("OP form asks for %v", name)
+		}
+		if otp == "" {
+			return fmt.Errorf("OP form asks for an OTP but -otp is not set")
+		}
+		f.fields.Set(name, otp)
+	}
+	return nil
+}
+
+/*
+submit submits the form with the mock browser.
+*/
+func (f *opForm) submit(browser *http.Client) (*http.Response, error) {
+	if f.method == "POST" {
+		return browser.PostForm(f.action, f.fields)
+	}
+	return browser.Get(f.action + "?" + f.fields.Encode())
+}
+
+/*
+authorize issues an Authn Request step with the mock browser and returns the absolute redirect location it responds
+with. If the OP responds with a second factor form instead, the form is completed and submitted until the OP redirects.
+*/
+func authorize(browser *http.Client, target string) (string, error) {
+	var (
+		rsp  *http.Response
+		page []byte
+		form *opForm
+		err  error
+	)
+
+	rsp, err = browser.Get(target)
+	for i := 0; err == nil && rsp.StatusCode == http.StatusOK; i++ {
+		page, err = ioutil.ReadAll(io.LimitReader(rsp.Body, 1024*1024))
+		rsp.Body.Close()
+		if err != nil {
+			return "", err
+		}
+		if i == maxFactorForms {
+			return "", fmt.Errorf("more than %v OP forms", maxFactorForms)
+		}
+		form, err = parseOPForm(string(page), rsp.Request.URL)
+		if err == nil {
+			err = form.fill(factor, otp)
+		}
+		if err != nil {
+			return "", err
+		}
+		rsp, err = form.submit(browser)
+	}
+	if err != nil {
+		return "", err
+	}
+	return redirectLocation(rsp)
+}
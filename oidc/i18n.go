@@ -0,0 +1,144 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+/*
+The RP's user facing text is localized with message catalogs. The language is selected from the ui_locales
+parameter, if any, and otherwise from the Accept-Language header. English is the default.
+*/
+
+//defaultLang is the language used if no requested language has a catalog
+const defaultLang = "en"
+
+//catalogs holds the message catalog of each supported language keyed by message ID
+var catalogs = map[string]map[string]string{
+	"en": {
+		"error.title":      "Login failed",
+		"error.detail":     "The OpenID Connect login could not be completed. Details:",
+		"result.success":   "Login succeeded",
+		"logout.confirmed": "You have been logged out.",
+	},
+	"es": {
+		"error.title":      "Error de inicio de sesión",
+		"error.detail":     "No se pudo completar el inicio de sesión de OpenID Connect. Detalles:",
+		"result.success":   "Inicio de sesión correcto",
+		"logout.confirmed": "Se ha cerrado su sesión.",
+	},
+	"fr": {
+		"error.title":      "Échec de la connexion",
+		"error.detail":     "La connexion OpenID Connect n'a pas pu aboutir. Détails :",
+		"result.success":   "Connexion réussie",
+		"logout.confirmed": "Vous avez été déconnecté.",
+	},
+	"de": {
+		"error.title":      "Anmeldung fehlgeschlagen",
+		"error.detail":     "Die OpenID Connect Anmeldung konnte nicht abgeschlossen werden. Details:",
+		"result.success":   "Anmeldung erfolgreich",
+		"logout.confirmed": "Sie wurden abgemeldet.",
+	},
+}
+
+//A localizer provides the messages of a language
+type localizer string
+
+/*
+newLocalizer selects the language of a request's response. An explicit uiLocales value (the space delimited
+OpenID Connect ui_locales list, in preference order) takes precedence; if it is empty, the ui_locales query parameter
+and then the Accept-Language header are used.
+*/
+func newLocalizer(r *http.Request, uiLocales string) localizer {
+	var lang string
+
+	if uiLocales == "" {
+		uiLocales = r.URL.Query().Get("ui_locales")
+	}
+	for _, tag := range strings.Fields(uiLocales) {
+		lang = matchLang(tag)
+		if lang != "" {
+			return localizer(lang)
+		}
+	}
+	for _, tag := range parseAcceptLanguage(r.Header.Get("Accept-Language")) {
+		lang = matchLang(tag)
+		if lang != "" {
+			return localizer(lang)
+		}
+	}
+	return localizer(defaultLang)
+}
+
+//matchLang returns the catalog language of a BCP 47 language tag (e.g. "fr-CA" matches "fr") or "" if there is none
+func matchLang(tag string) string {
+	var lang = strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+
+	_, ok := catalogs[lang]
+	if !ok {
+		return ""
+	}
+	return lang
+}
+
+//parseAcceptLanguage returns the language tags of an Accept-Language header in descending q-value order
+func parseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+	var (
+		langs []weighted
+		tags  []string
+	)
+
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		if fields[0] == "" || fields[0] == "*" {
+			continue
+		}
+		w := weighted{tag: fields[0], q: 1}
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				q, err := strconv.ParseFloat(param[2:], 64)
+				if err == nil {
+					w.q = q
+				}
+			}
+		}
+		if w.q > 0 {
+			langs = append(langs, w)
+		}
+	}
+	sort.SliceStable(langs, func(i, j int) bool { return langs[i].q > langs[j].q })
+	for _, w := range langs {
+		tags = append(tags, w.tag)
+	}
+	return tags
+}
+
+//lang returns the localizer's language tag
+func (l localizer) lang() string {
+	return string(l)
+}
+
+//msg returns the localized message with the ID, falling back to English and then to the ID itself
+func (l localizer) msg(id string) string {
+	var (
+		m  string
+		ok bool
+	)
+
+	m, ok = catalogs[string(l)][id]
+	if ok {
+		return m
+	}
+	m, ok = catalogs[defaultLang][id]
+	if ok {
+		return m
+	}
+	return id
+}
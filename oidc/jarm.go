@@ -0,0 +1,375 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+/*
+JARM (JWT Secured Authorization Response Mode) is enabled with the -responsemode flag. With response_mode jwt or
+query.jwt the Authn Response is a single "response" query parameter; with form_post.jwt it is a "response" form
+parameter POSTed to /authn-token. Its value is a JWT signed by the OP, optionally encrypted as a JWE, whose claims
+hold the Authn Response parameters (code, state or error).
+
+The JWT signature is verified with the OP's JWKS (or, for HS256, the secret this RP shares with its OP); its iss must
+be the OP, its aud this RP's client ID, and it must not have expired. An encrypted response must use direct
+encryption (alg "dir") with AES GCM and a key derived from the shared secret as specified by OpenID Connect Core 10.2.
+
+A response that fails validation is reported as a jarmError so that it is distinct from other Authn Response errors.
+*/
+
+//The JARM response modes
+const (
+	responseModeJWT         = "jwt"
+	responseModeQueryJWT    = "query.jwt"
+	responseModeFormPostJWT = "form_post.jwt"
+)
+
+//jwksTTL is how long the OP's JWKS is cached
+const jwksTTL = time.Hour
+
+//jwksMinRefetch is the minimum interval between fetches of the OP's JWKS, so that tokens with unknown kids cannot
+//make every request fetch it
+const jwksMinRefetch = time.Minute
+
+//A jarmError is a JARM response validation failure
+type jarmError struct {
+	err error
+}
+
+func (e jarmError) Error() string {
+	return "JARM Authn Response Validation Failed: " + e.err.Error()
+}
+
+//isJARM is true if a response mode is a JARM mode
+func isJARM(responseMode string) bool {
+	switch responseMode {
+	case responseModeJWT, responseModeQueryJWT, responseModeFormPostJWT:
+		return true
+	default:
+		return false
+	}
+}
+
+//jwks caches the OP's JSON Web Key Set keyed by kid, the time it was fetched and the time a fetch was last started.
+//Since it is accessed by concurrent requests, it must be mutexed.
+var jwks = struct {
+	m         sync.Mutex
+	keys      map[string]interface{}
+	fetched   time.Time
+	attempted time.Time
+}{}
+
+//jsonWebKey is the JSON form of an RSA or EC public JWK
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+/*
+jwksKey returns the OP's public key with the kid. The JWKS is fetched if it is not cached, has expired or does not
+have the kid (i.e. the OP may have rotated its keys), but no more often than every jwksMinRefetch: meanwhile an
+unknown kid is rejected and an expired key is still used. The JWKS is fetched without jwks locked, so that a slow OP
+does not block the requests whose keys are cached.
+*/
+func jwksKey(kid string) (interface{}, error) {
+	var (
+		key  interface{}
+		keys map[string]interface{}
+		ok   bool
+		now  = clk.Now()
+		err  error
+	)
+
+	jwks.m.Lock()
+	key, ok = jwks.keys[kid]
+	if ok && now.Sub(jwks.fetched) < jwksTTL {
+		jwks.m.Unlock()
+		return key, nil
+	}
+	if now.Sub(jwks.attempted) < jwksMinRefetch {
+		jwks.m.Unlock()
+		if !ok {
+			return nil, fmt.Errorf("OP JWKS has no key with kid: %v", kid)
+		}
+		return key, nil
+	}
+	jwks.attempted = now
+	jwks.m.Unlock()
+
+	keys, err = fetchJWKS(opJWKSEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	jwks.m.Lock()
+	jwks.keys = keys
+	jwks.fetched = clk.Now()
+	jwks.m.Unlock()
+	key, ok = keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("OP JWKS has no key with kid: %v", kid)
+	}
+	return key, nil
+}
+
+//fetchJWKS retrieves and parses a JWKS; keys that are not RSA or P-256 EC signing keys are ignored
+func fetchJWKS(endpoint string) (map[string]interface{}, error) {
+	var (
+		rsp  *http.Response
+		body struct {
+			Keys []jsonWebKey `json:"keys"`
+		}
+		keys = make(map[string]interface{})
+		err  error
+	)
+
+	rsp, err = opClient.Get(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("OP JWKS Request Failed: %v", err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OP JWKS Request Failed: %v", rsp.Status)
+	}
+	err = json.NewDecoder(io.LimitReader(rsp.Body, 1024*1024)).Decode(&body)
+	if err != nil {
+		return nil, fmt.Errorf("Decoding OP JWKS Failed: %v", err)
+	}
+	for _, jwk := range body.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		switch jwk.Kty {
+		case "RSA":
+			n, errN := base64.RawURLEncoding.DecodeString(jwk.N)
+			e, errE := base64.RawURLEncoding.DecodeString(jwk.E)
+			if errN != nil || errE != nil || len(e) > 4 {
+				continue
+			}
+			keys[jwk.Kid] = &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		case "EC":
+			x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+			y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+			if jwk.Crv != "P-256" || errX != nil || errY != nil {
+				continue
+			}
+			keys[jwk.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		}
+	}
+	return keys, nil
+}
+
+//opKeyfunc is a jwt.Keyfunc that supplies the key to verify the signature of a JARM response or logout token. The
+//JWKS key of the kid must be of the alg's type, so that a token cannot select a key for another algorithm.
+func opKeyfunc(t *jwt.Token) (interface{}, error) {
+	var (
+		alg, _ = t.Header["alg"].(string)
+		kid, _ = t.Header["kid"].(string)
+		key    interface{}
+		ok     bool
+		err    error
+	)
+
+	switch alg {
+	case "HS256":
+		return []byte(opSharedSecret), nil
+	case "RS256", "PS256":
+		key, err = jwksKey(kid)
+		_, ok = key.(*rsa.PublicKey)
+	case "ES256":
+		key, err = jwksKey(kid)
+		_, ok = key.(*ecdsa.PublicKey)
+	default:
+		return nil, fmt.Errorf("Unsupported OP Signing Algorithm: %v", alg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("OP JWKS key with kid: %v is not a %v key", kid, alg)
+	}
+	return key, nil
+}
+
+/*
+jarmParams returns the Authn Response parameters carried by the JARM response of a request.
+*/
+func jarmParams(r *http.Request) (url.Values, error) {
+	var (
+		response string
+		token    *jwt.Token
+		params   = url.Values{}
+		err      error
+	)
+
+	switch responseMode {
+	case responseModeFormPostJWT:
+		if r.Method != "POST" {
+			return nil, jarmError{fmt.Errorf("Bad HTTP Method: %v", r.Method)}
+		}
+		r.Body = ioutil.NopCloser(io.LimitReader(r.Body, 64*1024))
+		response = r.PostFormValue("response")
+	default:
+		response = r.URL.Query().Get("response")
+	}
+	if response == "" {
+		return nil, jarmError{fmt.Errorf("Missing response parameter")}
+	}
+
+	//A JWE has five parts and a JWS three
+	if strings.Count(response, ".") == 4 {
+		response, err = decryptJARM(response)
+		if err != nil {
+			return nil, jarmError{err}
+		}
+	}
+
+	token, err = jwt.Parse(response, opKeyfunc)
+	if err != nil {
+		return nil, jarmError{err}
+	}
+	err = validateJARMClaims(token.Claims)
+	if err != nil {
+		return nil, jarmError{err}
+	}
+
+	for _, name := range []string{"code", "state", "error", "error_description", "error_uri"} {
+		if value, ok := token.Claims[name].(string); ok {
+			params.Set(name, value)
+		}
+	}
+	return params, nil
+}
+
+//validateJARMClaims checks the iss, aud and exp claims of a JARM response
+func validateJARMClaims(claims map[string]interface{}) error {
+	var (
+		exp float64
+		ok  bool
+	)
+
+	if claims["iss"] != opIssuer {
+		return fmt.Errorf("Bad iss: %v", claims["iss"])
+	}
+	if !isAudience(claims["aud"]) {
+		return fmt.Errorf("Bad aud: %v", claims["aud"])
+	}
+	exp, ok = claims["exp"].(float64)
+	if !ok {
+		return fmt.Errorf("Missing exp")
+	}
+	if clk.Now().After(time.Unix(int64(exp), 0)) {
+		return fmt.Errorf("Expired at: %v", time.Unix(int64(exp), 0).UTC())
+	}
+	return nil
+}
+
+/*
+decryptJARM decrypts a compact JWE that uses direct encryption with AES GCM and returns its payload. Per OpenID
+Connect Core 10.2, the key is the leftmost bytes of the SHA-256 hash of the client secret.
+*/
+func decryptJARM(jwe string) (string, error) {
+	var (
+		parts      = strings.Split(jwe, ".")
+		headerJSON []byte
+		header     struct {
+			Alg string `json:"alg"`
+			Enc string `json:"enc"`
+		}
+		keyLen     int
+		secretHash = sha256.Sum256([]byte(opSharedSecret))
+		block      cipher.Block
+		gcm        cipher.AEAD
+		iv, ct     []byte
+		tag        []byte
+		payload    []byte
+		err        error
+	)
+
+	headerJSON, err = base64.RawURLEncoding.DecodeString(parts[0])
+	if err == nil {
+		err = json.Unmarshal(headerJSON, &header)
+	}
+	if err != nil {
+		return "", fmt.Errorf("Bad JWE Header: %v", err)
+	}
+	switch header.Enc {
+	case "A128GCM":
+		keyLen = 16
+	case "A192GCM":
+		keyLen = 24
+	case "A256GCM":
+		keyLen = 32
+	default:
+		return "", fmt.Errorf("Unsupported JWE enc: %v", header.Enc)
+	}
+	if header.Alg != "dir" || parts[1] != "" {
+		return "", fmt.Errorf("Unsupported JWE alg: %v", header.Alg)
+	}
+
+	iv, err = base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return "", fmt.Errorf("Bad JWE IV: %v", err)
+	}
+	ct, err = base64.RawURLEncoding.DecodeString(parts[3])
+	if err != nil {
+		return "", fmt.Errorf("Bad JWE Ciphertext: %v", err)
+	}
+	tag, err = base64.RawURLEncoding.DecodeString(parts[4])
+	if err != nil {
+		return "", fmt.Errorf("Bad JWE Tag: %v", err)
+	}
+	block, err = aes.NewCipher(secretHash[:keyLen])
+	if err != nil {
+		return "", err
+	}
+	gcm, err = cipher.NewGCMWithNonceSize(block, len(iv))
+	if err != nil {
+		return "", err
+	}
+
+	//The additional authenticated data is the ASCII of the encoded protected header
+	payload, err = gcm.Open(nil, iv, append(ct, tag...), []byte(parts[0]))
+	if err != nil {
+		return "", fmt.Errorf("JWE Decryption Failed: %v", err)
+	}
+	return string(payload), nil
+}
+
+//isAudience is true if an aud claim, which may be a string or an array, includes this RP's client ID
+func isAudience(aud interface{}) bool {
+	switch aud.(type) {
+	case string:
+		return aud.(string) == clientID
+	case []interface{}:
+		for _, a := range aud.([]interface{}) {
+			if a == clientID {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,219 @@
+package main
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+//encrypt returns a compact JWE of a payload with direct encryption by the key derived from the shared secret
+func encrypt(test *testing.T, payload, alg, enc string) string {
+	var (
+		header     = b64([]byte(`{"alg":"` + alg + `","enc":"` + enc + `"}`))
+		secretHash = sha256.Sum256([]byte(opSharedSecret))
+		iv         = make([]byte, 12)
+	)
+
+	block, err := aes.NewCipher(secretHash[:16])
+	if err != nil {
+		test.Fatalf("NewCipher: %v", err)
+	}
+	gcm, _ := cipher.NewGCM(block)
+	sealed := gcm.Seal(nil, iv, []byte(payload), []byte(header))
+	ct, tag := sealed[:len(sealed)-gcm.Overhead()], sealed[len(sealed)-gcm.Overhead():]
+	return header + ".." + b64(iv) + "." + b64(ct) + "." + b64(tag)
+}
+
+//forge returns a JWT with the header and claims whose signature is the HMAC-SHA256 with the key
+func forge(header, claims map[string]interface{}, key []byte) string {
+	var (
+		encodedHeader, _ = json.Marshal(header)
+		encodedClaims, _ = json.Marshal(claims)
+		signing          = b64(encodedHeader) + "." + b64(encodedClaims)
+		mac              = hmac.New(sha256.New, key)
+	)
+
+	mac.Write([]byte(signing))
+	return signing + "." + b64(mac.Sum(nil))
+}
+
+func TestJARMParams(test *testing.T) {
+	var (
+		o         = newOP(test)
+		valid     = o.claims(map[string]interface{}{"code": "c", "state": "s"})
+		pub, _    = x509.MarshalPKIXPublicKey(&o.rsaKey.PublicKey)
+		signed    = o.sign(test, "HS256", valid)
+		encrypted = encrypt(test, signed, "dir", "A128GCM")
+		cases     = []struct {
+			name     string
+			response string
+			err      string
+		}{
+			{"HS256", signed, ""},
+			{"RS256", o.sign(test, "RS256", valid), ""},
+			{"PS256", o.sign(test, "PS256", valid), ""},
+			{"ES256", o.sign(test, "ES256", valid), ""},
+			{"aud array", o.sign(test, "HS256", o.claims(map[string]interface{}{"code": "c", "state": "s", "aud": []string{"other", "rp"}})), ""},
+			{"encrypted", encrypted, ""},
+			{"missing", "", "Missing response parameter"},
+			{"wrong iss", o.sign(test, "HS256", o.claims(map[string]interface{}{"iss": "https://evil.org"})), "Bad iss"},
+			{"wrong aud", o.sign(test, "HS256", o.claims(map[string]interface{}{"aud": []string{"other"}})), "Bad aud"},
+			{"missing exp", o.sign(test, "HS256", o.claims(map[string]interface{}{"exp": nil})), "Missing exp"},
+			{"expired", o.sign(test, "HS256", o.claims(map[string]interface{}{"exp": o.fake.Now().Add(-time.Second).Unix()})), "expired"},
+			{"alg none", o.sign(test, "none", valid), "Unsupported OP Signing Algorithm: none"},
+			{"HS512", forge(map[string]interface{}{"alg": "HS512"}, valid, []byte(opSharedSecret)), "Unsupported OP Signing Algorithm: HS512"},
+			{"HS256 signed with the RSA public key", forge(map[string]interface{}{"alg": "HS256", "kid": "rsa"}, valid, pub), "signature is invalid"},
+			{"RS256 signed with the shared secret", forge(map[string]interface{}{"alg": "RS256", "kid": "rsa"}, valid, []byte(opSharedSecret)), "verification error"},
+			{"unknown kid", forge(map[string]interface{}{"alg": "RS256", "kid": "unknown"}, valid, nil), "OP JWKS has no key with kid: unknown"},
+			{"RS256 with the EC key", forge(map[string]interface{}{"alg": "RS256", "kid": "ec"}, valid, nil), "is not a RS256 key"},
+			{"encryption key", forge(map[string]interface{}{"alg": "RS256", "kid": "enc"}, valid, nil), "OP JWKS has no key with kid: enc"},
+			{"bad JWE tag", encrypted[:len(encrypted)-2] + "AA", "JWE Decryption Failed"},
+			{"JWE alg", encrypt(test, signed, "A128KW", "A128GCM"), "Unsupported JWE alg: A128KW"},
+			{"JWE enc", encrypt(test, signed, "dir", "A128CBC-HS256"), "Unsupported JWE enc: A128CBC-HS256"},
+		}
+	)
+
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "/authn-token?response="+url.QueryEscape(c.response), nil)
+		params, err := jarmParams(r)
+		switch {
+		case c.err == "" && (err != nil || params.Get("code") != "c" || params.Get("state") != "s"):
+			test.Errorf("jarmParams %v: %v %v", c.name, params, err)
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			test.Errorf("jarmParams %v: %v", c.name, err)
+		case err != nil:
+			if _, ok := err.(jarmError); !ok {
+				test.Errorf("jarmParams %v: %T is not a jarmError", c.name, err)
+			}
+		}
+	}
+
+	//The exp claim is checked against the RP's clock
+	valid = o.claims(map[string]interface{}{"exp": float64(o.fake.Now().Unix())})
+	o.fake.Advance(time.Second)
+	if err := validateJARMClaims(valid); err == nil || !strings.HasPrefix(err.Error(), "Expired at") {
+		test.Errorf("validateJARMClaims of an expired response: %v", err)
+	}
+}
+
+func TestJARMFormPost(test *testing.T) {
+	var (
+		o        = newOP(test)
+		response = o.sign(test, "HS256", o.claims(map[string]interface{}{"error": "access_denied", "state": "s"}))
+		r        = httptest.NewRequest("POST", "/authn-token", strings.NewReader(url.Values{"response": {response}}.Encode()))
+	)
+
+	responseMode = responseModeFormPostJWT
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if params, err := jarmParams(r); err != nil || params.Get("error") != "access_denied" || params.Get("code") != "" {
+		test.Errorf("jarmParams of a form post: %v %v", params, err)
+	}
+
+	//The response of a form post mode is not accepted in the query
+	if _, err := jarmParams(httptest.NewRequest("GET", "/authn-token?response="+response, nil)); err == nil || !strings.Contains(err.Error(), "Bad HTTP Method") {
+		test.Errorf("jarmParams of a GET in form post mode: %v", err)
+	}
+}
+
+func TestOPKeyfunc(test *testing.T) {
+	var (
+		o     = newOP(test)
+		cases = []struct {
+			alg, kid string
+			key      interface{}
+		}{
+			{"HS256", "", []byte(opSharedSecret)},
+			{"RS256", "rsa", &o.rsaKey.PublicKey},
+			{"PS256", "rsa", &o.rsaKey.PublicKey},
+			{"ES256", "ec", &o.ecKey.PublicKey},
+			{"none", "", nil},
+			{"HS384", "", nil},
+			{"RS256", "ec", nil},
+			{"ES256", "rsa", nil},
+			{"", "", nil},
+		}
+	)
+
+	for _, c := range cases {
+		key, err := opKeyfunc(&jwt.Token{Header: map[string]interface{}{"alg": c.alg, "kid": c.kid}})
+		if c.key == nil && err == nil {
+			test.Errorf("opKeyfunc %v %v should fail", c.alg, c.kid)
+		}
+		if c.key == nil || err != nil {
+			continue
+		}
+		switch expected := c.key.(type) {
+		case []byte:
+			if secret, ok := key.([]byte); !ok || string(secret) != string(expected) {
+				test.Errorf("opKeyfunc %v: %v", c.alg, key)
+			}
+		case *rsa.PublicKey:
+			if public, ok := key.(*rsa.PublicKey); !ok || public.N.Cmp(expected.N) != 0 || public.E != expected.E {
+				test.Errorf("opKeyfunc %v: %v", c.alg, key)
+			}
+		case *ecdsa.PublicKey:
+			if public, ok := key.(*ecdsa.PublicKey); !ok || public.X.Cmp(expected.X) != 0 || public.Y.Cmp(expected.Y) != 0 {
+				test.Errorf("opKeyfunc %v: %v", c.alg, key)
+			}
+		}
+	}
+}
+
+func TestJWKSKey(test *testing.T) {
+	var (
+		o       = newOP(test)
+		fetches = func() int32 { return atomic.LoadInt32(&o.fetches) }
+	)
+
+	if _, err := jwksKey("rsa"); err != nil || fetches() != 1 {
+		test.Fatalf("jwksKey: %v %v", err, fetches())
+	}
+	if _, err := jwksKey("ec"); err != nil || fetches() != 1 {
+		test.Errorf("jwksKey of a cached key: %v %v", err, fetches())
+	}
+
+	//An unknown kid does not fetch the JWKS more often than every jwksMinRefetch
+	for i := 0; i < 3; i++ {
+		if _, err := jwksKey("unknown"); err == nil || fetches() != 1 {
+			test.Errorf("jwksKey of an unknown kid: %v %v", err, fetches())
+		}
+	}
+	o.fake.Advance(jwksMinRefetch)
+	jwksKey("unknown")
+	jwksKey("unknown")
+	if fetches() != 2 {
+		test.Errorf("jwksKey of an unknown kid after jwksMinRefetch: %v", fetches())
+	}
+
+	//An expired JWKS is fetched again; if that fails the request fails, but until jwksMinRefetch has passed the expired
+	//key is used rather than fetching again
+	o.fake.Advance(jwksTTL)
+	atomic.StoreInt32(&o.down, 1)
+	if _, err := jwksKey("rsa"); err == nil || fetches() != 3 {
+		test.Errorf("jwksKey when the OP is down: %v %v", err, fetches())
+	}
+	if key, err := jwksKey("rsa"); key == nil || err != nil || fetches() != 3 {
+		test.Errorf("jwksKey of an expired key: %v %v", err, fetches())
+	}
+	atomic.StoreInt32(&o.down, 0)
+	o.fake.Advance(jwksMinRefetch)
+	if _, err := jwksKey("rsa"); err != nil || fetches() != 4 {
+		test.Errorf("jwksKey when the OP is up: %v %v", err, fetches())
+	}
+	if _, err := jwksKey("rsa"); err != nil || fetches() != 4 {
+		test.Errorf("jwksKey of a refreshed key: %v %v", err, fetches())
+	}
+}
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+/*
+In load mode (-loadusers > 0) the RP is also a load generator for the OP's policy endpoints. Each of -loadusers
+virtual users runs -loadlogins sequential code flow logins against this RP with a mock browser: a client with its
+own cookie jar that drives the redirects programmatically rather than following them. The users are started evenly
+over the -rampup period. The OP must complete its Authn Request without user interaction (i.e. redirect back to
+/authn-token) other than the second factor forms completed as described in factor.go; any other OP page that needs
+user input fails the login.
+
+The latency of each stage of a login is recorded in a histogram:
+
+	login		- the RP /login request that redirects to the OP
+	authorize	- the OP Authn Request up to its redirect to /authn-token, including any intermediate OP redirects
+	callback	- the RP /authn-token request, which includes the OP Token and User Info requests
+
+When all users have finished, the histograms are logged and the RP exits.
+*/
+
+//loadStages are the stages of a login in order
+var loadStages = []string{"login", "authorize", "callback"}
+
+//maxLoadRedirects limits the number of OP redirects in the authorize stage
+const maxLoadRedirects = 10
+
+//histogramBounds are the upper bounds of the latency histogram buckets; the last bucket is unbounded
+var histogramBounds = []time.Duration{
+	10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond,
+	250 * time.Millisecond, 500 * time.Millisecond, time.Second, 2500 * time.Millisecond,
+	5 * time.Second, 10 * time.Second,
+}
+
+//A histogram records the latencies and errors of a login stage. It is mutexed since virtual users run concurrently.
+type histogram struct {
+	m       sync.Mutex
+	counts  []int
+	samples []time.Duration
+	errors  int
+	pinning int
+}
+
+//newHistogram allocates a histogram
+func newHistogram() *histogram {
+	return &histogram{counts: make([]int, len(histogramBounds)+1)}
+}
+
+//record adds a latency to the histogram
+func (h *histogram) record(d time.Duration) {
+	var i = sort.Search(len(histogramBounds), func(i int) bool { return d <= histogramBounds[i] })
+
+	h.m.Lock()
+	defer h.m.Unlock()
+	h.counts[i]++
+	h.samples = append(h.samples, d)
+}
+
+//fail counts a failed stage and, separately, whether it failed because of OP certificate pinning
+func (h *histogram) fail(err error) {
+	h.m.Lock()
+	defer h.m.Unlock()
+	h.errors++
+	if isPinningError(err) || (err != nil && strings.Contains(err.Error(), pinningFailure)) {
+		h.pinning++
+	}
+}
+
+//percentile returns the latency at a percentile (0-100) of the recorded samples
+func (h *histogram) percentile(p float64) time.Duration {
+	var i int
+
+	if len(h.samples) == 0 {
+		return 0
+	}
+	i = int(p / 100 * float64(len(h.samples)-1))
+	return h.samples[i]
+}
+
+//report formats the histogram's summary and buckets
+func (h *histogram) report(stage string) string {
+	var (
+		b     strings.Builder
+		bound string
+	)
+
+	h.m.Lock()
+	defer h.m.Unlock()
+	sort.Slice(h.samples, func(i, j int) bool { return h.samples[i] < h.samples[j] })
+	fmt.Fprintf(&b, "%v: ok %d errors %d (pinning %d) p50 %v p90 %v p99 %v max %v\n", stage, len(h.samples), h.errors, h.pinning,
+		h.percentile(50), h.percentile(90), h.percentile(99), h.percentile(100))
+	for i, count := range h.counts {
+		if i < len(histogramBounds) {
+			bound = "<= " + histogramBounds[i].String()
+		} else {
+			bound = "> " + histogramBounds[len(histogramBounds)-1].String()
+		}
+		fmt.Fprintf(&b, "\t%-10v %d\n", bound, count)
+	}
+	return b.String()
+}
+
+/*
+runLoad runs the virtual users against the RP at rpBase and logs the stage histograms.
+*/
+func runLoad(rpBase string, users, logins int, rampUp time.Duration) {
+	var (
+		histograms = make(map[string]*histogram, len(loadStages))
+		wg         sync.WaitGroup
+		start      = time.Now()
+		report     strings.Builder
+	)
+
+	for _, stage := range loadStages {
+		histograms[stage] = newHistogram()
+	}
+	for i := 0; i < users; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			time.Sleep(rampUp * time.Duration(i) / time.Duration(users))
+			for n := 0; n < logins; n++ {
+				err := virtualLogin(rpBase, histograms)
+				if err != nil {
+					logger.Printf("Virtual user %v login %v failed with Error: %v\n", i, n, err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	fmt.Fprintf(&report, "Load test of %v users x %v logins completed in %v\n", users, logins, time.Since(start))
+	for _, stage := range loadStages {
+		report.WriteString(histograms[stage].report(stage))
+	}
+	logger.Print(report.String())
+}
+
+/*
+virtualLogin runs one code flow login with a new mock browser and records the latency of each stage.
+*/
+func virtualLogin(rpBase string, histograms map[string]*histogram) error {
+	var (
+		jar      *cookiejar.Jar
+		browser  *http.Client
+		location string
+		callback = rpBase + "/authn-token"
+		rsp      *http.Response
+		err      error
+	)
+
+	jar, err = cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	browser = &http.Client{
+		Transport: opClient.Transport,
+		Jar:       jar,
+		Timeout:   time.Minute,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	//login
+	location, err = timedRedirect(browser, rpBase+"/login", histograms["login"])
+	if err != nil {
+		return fmt.Errorf("login: %v", err)
+	}
+
+	//authorize
+	start := time.Now()
+	for i := 0; !strings.HasPrefix(location, callback); i++ {
+		if i == maxLoadRedirects {
+			err = fmt.Errorf("authorize: more than %v redirects", maxLoadRedirects)
+			histograms["authorize"].fail(err)
+			return err
+		}
+		location, err = authorize(browser, location)
+		if err != nil {
+			histograms["authorize"].fail(err)
+			return fmt.Errorf("authorize: %v", err)
+		}
+	}
+	histograms["authorize"].record(time.Since(start))
+
+	//callback
+	start = time.Now()
+	rsp, err = browser.Get(location)
+	if err != nil {
+		histograms["callback"].fail(err)
+		return fmt.Errorf("callback: %v", err)
+	}
+	defer rsp.Body.Close()
+	if rsp.StatusCode != http.StatusOK {
+		//The RP's error page includes the error of its OP requests, e.g. a pinning failure
+		body, _ := ioutil.ReadAll(io.LimitReader(rsp.Body, 64*1024))
+		err = fmt.Errorf("callback: %v %v", rsp.Status, string(body))
+		histograms["callback"].fail(err)
+		return err
+	}
+	_, err = io.Copy(ioutil.Discard, rsp.Body)
+	if err != nil {
+		histograms["callback"].fail(err)
+		return fmt.Errorf("callback: %v %v", rsp.Status, err)
+	}
+	histograms["callback"].record(time.Since(start))
+	return nil
+}
+
+//timedRedirect issues a redirect request and records its latency in a histogram
+func timedRedirect(browser *http.Client, target string, h *histogram) (string, error) {
+	var (
+		start    = time.Now()
+		location string
+		err      error
+	)
+
+	location, err = redirect(browser, target)
+	if err != nil {
+		h.fail(err)
+		return "", err
+	}
+	h.record(time.Since(start))
+	return location, nil
+}
+
+//redirect issues a GET that must respond with a redirect and returns the absolute redirect location
+func redirect(browser *http.Client, target string) (string, error) {
+	var (
+		rsp *http.Response
+		err error
+	)
+
+	rsp, err = browser.Get(target)
+	if err != nil {
+		return "", err
+	}
+	return redirectLocation(rsp)
+}
+
+//redirectLocation returns the absolute redirect location of a response, which must be a redirect, and closes its body
+func redirectLocation(rsp *http.Response) (string, error) {
+	var (
+		location *url.URL
+		err      error
+	)
+
+	defer rsp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(rsp.Body, 64*1024))
+	switch rsp.StatusCode {
+	case http.StatusFound, http.StatusSeeOther, http.StatusTemporaryRedirect, http.StatusMovedPermanently:
+	default:
+		return "", fmt.Errorf("%v responded %v rather than a redirect", rsp.Request.URL, rsp.Status)
+	}
+	location, err = rsp.Request.URL.Parse(rsp.Header.Get("Location"))
+	if err != nil {
+		return "", err
+	}
+	return location.String(), nil
+}
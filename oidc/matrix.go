@@ -0,0 +1,115 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+	"github.com/pborman/uuid"
+)
+
+/*
+To run the OP's token endpoint auth method and signing algorithm compatibility matrix without restarting the RP,
+a /login request may select, for its flow only:
+
+	token_auth	- the token endpoint client auth method: client_secret_jwt (the default), client_secret_post or
+			  client_secret_basic
+	alg		- the client_secret_jwt assertion signing algorithm: HS256 (the default), HS384 or HS512
+
+The selection is carried to /authn-token in the authn cookie.
+*/
+
+//The token endpoint client auth methods
+const (
+	authClientSecretJWT   = "client_secret_jwt"
+	authClientSecretPost  = "client_secret_post"
+	authClientSecretBasic = "client_secret_basic"
+)
+
+//assertionAlgs are the supported client_secret_jwt signing algorithms
+var assertionAlgs = map[string]jwt.SigningMethod{
+	"HS256": jwt.SigningMethodHS256,
+	"HS384": jwt.SigningMethodHS384,
+	"HS512": jwt.SigningMethodHS512,
+}
+
+/*
+flowOptions returns the token endpoint auth method and signing algorithm selected by a /login request's query,
+or their defaults.
+*/
+func flowOptions(query url.Values) (string, string, error) {
+	var (
+		tokenAuth = query.Get("token_auth")
+		alg       = query.Get("alg")
+	)
+
+	switch tokenAuth {
+	case "":
+		tokenAuth = authClientSecretJWT
+	case authClientSecretJWT, authClientSecretPost, authClientSecretBasic:
+	default:
+		return "", "", fmt.Errorf("Unsupported token_auth: %v", tokenAuth)
+	}
+	switch {
+	case alg == "":
+		alg = "HS256"
+	case tokenAuth != authClientSecretJWT:
+		return "", "", fmt.Errorf("alg %v requires token_auth %v", alg, authClientSecretJWT)
+	case assertionAlgs[alg] == nil:
+		return "", "", fmt.Errorf("Unsupported alg: %v", alg)
+	}
+	return tokenAuth, alg, nil
+}
+
+/*
+newTokenRequest creates an OP Token Endpoint request for an authorization code that authenticates this RP with the
+auth method and, for client_secret_jwt, the signing algorithm of the flow. The redirectURI must be the flow's
+Authn Request redirect_uri.
+*/
+func newTokenRequest(code, redirectURI, tokenAuth, alg string) (*http.Request, error) {
+	var (
+		form                  = url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {redirectURI}}
+		clientAssertion       *jwt.Token
+		clientAssertionString string
+		requestTime           = clk.Now().UTC()
+		req                   *http.Request
+		err                   error
+	)
+
+	//An authn cookie set before flows could select their options has neither, nor a redirect URI
+	if tokenAuth == "" {
+		tokenAuth, alg = authClientSecretJWT, "HS256"
+	}
+	if redirectURI == "" {
+		form.Set("redirect_uri", "https://"+exthost+"/authn-token")
+	}
+
+	switch tokenAuth {
+	case authClientSecretJWT:
+		clientAssertion = jwt.New(assertionAlgs[alg])
+		clientAssertion.Claims = map[string]interface{}{"iss": clientID, "sub": clientID, "aud": opTokenEndpoint, "jti": uuid.NewRandom().String(), "exp": requestTime.Add(time.Minute * 10).String(), "iat": requestTime.String()}
+		clientAssertionString, err = clientAssertion.SignedString([]byte(opSharedSecret))
+		if err != nil {
+			return nil, fmt.Errorf("Client Assertion Signing Error: %v", err)
+		}
+		form.Set("client_id", clientID)
+		form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+		form.Set("client_assertion", clientAssertionString)
+	case authClientSecretPost:
+		form.Set("client_id", clientID)
+		form.Set("client_secret", opSharedSecret)
+	}
+
+	req, err = http.NewRequest("POST", opTokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if tokenAuth == authClientSecretBasic {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(opSharedSecret))
+	}
+	return req, nil
+}
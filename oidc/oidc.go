@@ -34,12 +34,51 @@ used to identify this RP to the TNaaS OP.
 
 (7) The ID Token JWT is decoded and the JSON encoded ID Token content and UserInfo content is returned in the /login response.
 
+After a login the ID Token's role claim is mapped to application roles that are stored in a session cookie; a /me
+GET request returns the session. See session.go. A /protected GET request responds with the access decision of
+configurable claim requirements for the session; see protected.go. A /transcript GET request returns the
+transcript of the session's login as a JSON LD document; see transcript.go.
+
+A /logout GET request clears the authn and session cookies. The OP may also end sessions by POSTing a logout token to
+/backchannel-logout; see backchannel.go.
+
+The login result is streamed (and gzip compressed if accepted). If the User Info exceeds -maxuserinfo bytes, the
+result contains its first page of claims and links to the following pages which are served by /userinfo-page/<key>/<n>.
+
+A /login request may select the token endpoint client auth method and signing algorithm of its flow with its
+token_auth and alg query parameters; see matrix.go.
+
+The login result, error pages and logout confirmation are localized. The language is selected from the /login
+ui_locales query parameter (which is also passed to the OP) or from the Accept-Language header.
+
+OP requests require TLS 1.2 or later with forward secret AEAD cipher suites, and the OP's public key may be
+pinned; see transport.go.
+
+In load mode the RP also runs concurrent virtual user logins against itself to load test the OP; see load.go.
+
 The service accepts the following command flags in either '-' or '--' form:
+
 	-exthost   	- the public hostname of this RP
 	-ophost		- the host name of this RP's OpenID Connect Authentication Server
 	-clientid	- the OpenID Connect client ID of this RP
 	-secret		- the secret this RP shares with its OP
 	-scope		- the list of optional, space delimited Authn Request scope values; the full list is "profile email address phone"
+	-maxuserinfo	- the maximum number of bytes of User Info claims returned in a login result or User Info page
+	-trustedproxies	- the comma separated CIDRs of the reverse proxies or load balancers whose forwarding headers are trusted;
+			  if it is set, redirect URIs are built from the forwarded scheme and host rather than exthost
+	-diagtoken	- the bearer token required by the /debug diagnostics endpoints; if it is not set they are disabled
+	-responsemode	- the JARM response mode (jwt, query.jwt or form_post.jwt); if it is not set the Authn Response is a plain query
+	-loadusers	- the number of virtual users of load mode; if it is 0 (the default), load mode is disabled
+	-loadlogins	- the number of logins run by each virtual user
+	-rampup		- the period over which the virtual users are started (e.g. 30s)
+	-factor		- the second factor virtual users select in an OP factor selection form (e.g. email or sms)
+	-otp		- the OTP virtual users enter in an OP second factor form
+	-roleclaim	- the ID Token claim whose values are mapped to application roles (default groups)
+	-rolemap	- the comma separated claim value=role pairs of the role mapping, e.g. "admins=admin,staff=user"
+	-require	- the comma separated claim requirements of the /protected demo endpoint, e.g.
+			  "email_verified=true,groups=admins" (see protected.go)
+	-oppins		- the comma separated sha256/<base64 hash> public key pins of the OP; if it is set, a connection to
+			  ophost must present a pinned key (see transport.go)
 	-log       	- The log file name
 	-logprefix 	- The logging prefix
 	-logflag   	- The logging flag
@@ -50,20 +89,28 @@ package main
 
 import (
 	"crypto/cipher"
-	"crypto/tls"
+	"crypto/rand"
 	"crypto/x509"
 	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"net/url"
+	"strings"
 	"time"
 
 	"bitbucket.org/mark_hapner/tn-go/certbndl"
 
 	"github.com/develrns/resilient/aead"
+	"github.com/develrns/resilient/clock"
+	"github.com/develrns/resilient/diagz"
+	"github.com/develrns/resilient/eventbus"
 	"github.com/develrns/resilient/log"
+	"github.com/develrns/resilient/oplog"
+	"github.com/develrns/resilient/proxyaware"
 
 	jwt "github.com/dgrijalva/jwt-go"
 	"github.com/pborman/uuid"
@@ -81,8 +128,12 @@ type (
 
 	//AuthnReqState is the content of an Authn Request cookie set by this RP
 	AuthnReqState struct {
-		State string
-		Nonce string
+		State       string
+		Nonce       string
+		UILocales   string
+		TokenAuth   string
+		Alg         string
+		RedirectURI string
 	}
 )
 
@@ -90,6 +141,9 @@ var (
 	//The RP logger
 	logger = log.Logger()
 
+	//The time source of token, session and page expiry
+	clk = clock.Real
+
 	//Command flags
 	exthost        string
 	ophost         string
@@ -97,13 +151,50 @@ var (
 	opSharedSecret string
 	scope          string
 
-	//The HTTPS client used to issue OP requests
-	opClient *http.Client
+	//The maximum number of bytes of User Info claims returned in a page
+	maxUserInfoPage int
+
+	//The bearer token required by the diagnostics endpoints; if it is empty they are not mounted
+	diagToken string
+
+	//The trusted proxies and the Resolver of the forwarded scheme and host of requests
+	trustedProxies string
+	proxies        *proxyaware.Resolver
+
+	//The JARM response mode; if it is empty the Authn Response is a plain query
+	responseMode string
+
+	//The load mode virtual users, logins per user and ramp up period
+	loadUsers  int
+	loadLogins int
+	rampUp     time.Duration
+
+	//The second factor and OTP of the load mode virtual users
+	factor string
+	otp    string
+
+	//The ID Token role claim and the roles of each of its values
+	roleClaim    string
+	roleMapValue string
+	roleMap      map[string][]string
+
+	//The claim requirements of /protected
+	requirementsValue string
+	requirements      []claimRequirement
+
+	//The HTTPS client used to issue OP requests and the OP public key pins it enforces; see transport.go
+	opClient    *http.Client
+	opPinsValue string
+	opPins      map[string]bool
 
 	//The OP Endpoints
 	opAuthnEndpoint    string
 	opTokenEndpoint    string
 	opUserInfoEndpoint string
+	opJWKSEndpoint     string
+
+	//The OP's issuer identifier
+	opIssuer string
 
 	//The AEAD cipher used to encrypt/decrypt all subscriber identifiers in the hidden fields of TBD 2nd Factor Selection Forms
 	aeadCipher cipher.AEAD
@@ -117,6 +208,7 @@ func init() {
 		logFileName string
 		logPrefix   string
 		logFlag     int
+		err         error
 	)
 
 	flag.StringVar(&exthost, "exthost", "", "the public hostname of this RP")
@@ -124,6 +216,19 @@ func init() {
 	flag.StringVar(&clientID, "clientid", "", "the OpenID Connect client ID of this RP")
 	flag.StringVar(&opSharedSecret, "secret", "", "the secret this RP shares with its OP")
 	flag.StringVar(&scope, "scope", "", `the list of optional, space delimited Authn Request scope values; the full list is "profile email address phone"`)
+	flag.IntVar(&maxUserInfoPage, "maxuserinfo", 1024*1024, "the maximum number of bytes of User Info claims returned in a login result or User Info page")
+	flag.StringVar(&trustedProxies, "trustedproxies", "", "the comma separated CIDRs of the trusted reverse proxies (default none)")
+	flag.StringVar(&diagToken, "diagtoken", "", "the bearer token required by the /debug diagnostics endpoints (default disabled)")
+	flag.StringVar(&responseMode, "responsemode", "", "the JARM response mode: jwt, query.jwt or form_post.jwt (default none)")
+	flag.IntVar(&loadUsers, "loadusers", 0, "the number of virtual users of load mode (default disabled)")
+	flag.IntVar(&loadLogins, "loadlogins", 1, "the number of logins run by each virtual user")
+	flag.DurationVar(&rampUp, "rampup", 0, "the period over which the virtual users are started")
+	flag.StringVar(&factor, "factor", "", "the second factor virtual users select in an OP factor selection form")
+	flag.StringVar(&otp, "otp", "", "the OTP virtual users enter in an OP second factor form")
+	flag.StringVar(&roleClaim, "roleclaim", "groups", "the ID Token claim whose values are mapped to application roles")
+	flag.StringVar(&roleMapValue, "rolemap", "", "the comma separated claim value=role pairs of the role mapping")
+	flag.StringVar(&requirementsValue, "require", "", "the comma separated claim requirements of /protected (default none)")
+	flag.StringVar(&opPinsValue, "oppins", "", "the comma separated sha256/<base64> public key pins of the OP (default none)")
 	flag.StringVar(&logFileName, "log", "", "log file name (default stdout)")
 	flag.StringVar(&logPrefix, "logprefix", "", "logging prefix")
 	flag.IntVar(&logFlag, "logflag", 0, "logging flag")
@@ -134,14 +239,58 @@ func init() {
 	opAuthnEndpoint = "https://" + ophost + "/openId/authenticate"
 	opTokenEndpoint = "https://" + ophost + "/openId/token"
 	opUserInfoEndpoint = "https://" + ophost + "/openId/userinfo"
+	opJWKSEndpoint = "https://" + ophost + "/openId/jwks"
+	opIssuer = "https://" + ophost
+
+	if responseMode != "" && !isJARM(responseMode) {
+		logger.Fatalf("Bad -responsemode: %v\n", responseMode)
+	}
+	proxies, err = proxyaware.New(strings.Split(trustedProxies, ",")...)
+	if err != nil {
+		logger.Fatalf("Bad -trustedproxies: %v\n", err)
+	}
+	roleMap, err = parseRoleMap(roleMapValue)
+	if err != nil {
+		logger.Fatalf("Bad -rolemap: %v\n", err)
+	}
+	requirements, err = parseRequirements(requirementsValue)
+	if err != nil {
+		logger.Fatalf("Bad -require: %v\n", err)
+	}
+	opPins, err = parsePins(opPinsValue)
+	if err != nil {
+		logger.Fatalf("Bad -oppins: %v\n", err)
+	}
 }
 
 /*
-writeError responds with 400 Bad Request and an error msg body
+writeError responds with 400 Bad Request and a localized error page body containing the error msg
 */
-func writeError(w http.ResponseWriter, err error) {
+func writeError(w http.ResponseWriter, l localizer, err error) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Language", l.lang())
 	w.WriteHeader(http.StatusBadRequest)
-	w.Write([]byte(err.Error()))
+	w.Write([]byte(l.msg("error.title") + "\n\n" + l.msg("error.detail") + "\n" + err.Error()))
+}
+
+/*
+baseURL returns the absolute URL of this RP for a request. Behind trusted proxies it is built from the scheme and host
+of the request forwarded by them; otherwise it is built from exthost.
+*/
+func baseURL(r *http.Request) string {
+	if trustedProxies != "" {
+		if origin, ok := proxyaware.FromRequest(r); ok && origin.Host != "" {
+			return origin.BaseURL()
+		}
+	}
+	return "https://" + exthost
+}
+
+/*
+authnRedirectURI returns the absolute URI of this RP's /authn-token endpoint (see baseURL).
+*/
+func authnRedirectURI(r *http.Request) string {
+	return baseURL(r) + "/authn-token"
 }
 
 /*
@@ -151,6 +300,21 @@ func keyfunc(t *jwt.Token) (interface{}, error) {
 	return []byte(opSharedSecret), nil
 }
 
+/*
+authnRequestURL returns the URL of an Authn Request with a state, nonce, redirect URI and, if it is set, ui_locales.
+*/
+func authnRequestURL(state, nonce, redirectURI, uiLocales string) string {
+	var authnReqURL = opAuthnEndpoint + "?response_type=code&scope=openid%20" + scope + "&client_id=" + clientID + "&state=" + state + "&nonce=" + nonce + "&redirect_uri=" + url.QueryEscape(redirectURI)
+
+	if uiLocales != "" {
+		authnReqURL = authnReqURL + "&ui_locales=" + url.QueryEscape(uiLocales)
+	}
+	if responseMode != "" {
+		authnReqURL = authnReqURL + "&response_mode=" + url.QueryEscape(responseMode)
+	}
+	return authnReqURL
+}
+
 /*
 handleLogin implements an RP login request. This is expected to be a GET issued by a browser user agent.
 
@@ -166,29 +330,43 @@ func handleLogin(w http.ResponseWriter, r *http.Request) {
 		authnReqStateBytes []byte
 		authnCookie        http.Cookie
 		authnCookieValue   string
+		uiLocales          = r.URL.Query().Get("ui_locales")
+		l                  = newLocalizer(r, "")
+		tokenAuth, alg     string
+		redirectURI        = authnRedirectURI(r)
 		err                error
 	)
 
 	if r.Method != "GET" {
-		writeError(w, fmt.Errorf("Bad HTTP Method: %v", r.Method))
+		writeError(w, l, fmt.Errorf("Bad HTTP Method: %v", r.Method))
+		return
+	}
+	tokenAuth, alg, err = flowOptions(r.URL.Query())
+	if err != nil {
+		writeError(w, l, err)
 		return
 	}
 
 	//The Authn Request
-	authnReqURL = opAuthnEndpoint + "?response_type=code&scope=openid%20" + scope + "&client_id=" + clientID + "&state=" + oidState + "&nonce=" + oidNonce + "&redirect_uri=https://" + exthost + "/authn-token"
+	authnReqURL = authnRequestURL(oidState, oidNonce, redirectURI, uiLocales)
 	fmt.Println(authnReqURL)
 
 	//The authnReqState is aead encrypted to produce a value stored as an authn cookie. This value transmits the oidState to the Authn Response while maintaining its privacy and integrity
 	//from any prying eyes that may exist in the browser.
-	authnReqState = AuthnReqState{State: oidState, Nonce: oidNonce}
+	authnReqState = AuthnReqState{State: oidState, Nonce: oidNonce, UILocales: uiLocales, TokenAuth: tokenAuth, Alg: alg, RedirectURI: redirectURI}
 	authnReqStateBytes, _ = json.Marshal(&authnReqState)
-	authnCookieValue, err = aead.Encrypt(aeadCipher, "AuthnReqState", string(authnReqStateBytes))
+	authnCookieValue, err = aead.EncryptFields(aeadCipher, map[string]string{"type": "AuthnReqState"}, string(authnReqStateBytes))
 	if err != nil {
-		writeError(w, err)
+		writeError(w, l, err)
 		return
 	}
 	authnCookie = http.Cookie{Name: "authnCookie", Value: authnCookieValue, Path: "/authn-token", Domain: exthost, HttpOnly: true, Secure: true, MaxAge: 300}
 
+	//A form_post.jwt Authn Response is a cross site POST, which only includes the cookie if it is SameSite=None
+	if responseMode == responseModeFormPostJWT {
+		authnCookie.SameSite = http.SameSiteNoneMode
+	}
+
 	//Issue the Authn Request via a redirect to the OP Authn Reqest endpoint.
 	w.Header().Set("Location", authnReqURL)
 	http.SetCookie(w, &authnCookie)
@@ -213,132 +391,158 @@ func handleAuthnToken(w http.ResponseWriter, r *http.Request) {
 		authnReqStateString string
 		authnRespParams     = r.URL.Query()
 		authnCookie         *http.Cookie
-		clientAssertion     = jwt.New(jwt.SigningMethodHS256)
 		tokenRspBody        TokenRspBody
 		idToken             *jwt.Token
 		userInfoReq         *http.Request
 		userInfoRsp         *http.Response
+		userInfoPageFiles   []string
+		idTokenJSON         []byte
+		sessionCookie       *http.Cookie
+		sessionID           string
+		login               *transcript
+		messageJSON         []byte
+		resultWriter        io.Writer
+		closeResultWriter   func() error
+		l                   = newLocalizer(r, "")
 		ok                  bool
 		err                 error
 	)
 
-	fmt.Println("https://" + exthost + "/authn-token/?" + r.URL.RawQuery)
+	fmt.Println(authnRedirectURI(r) + "?" + r.URL.RawQuery)
 
-	if r.Method != "GET" {
-		writeError(w, fmt.Errorf("Bad HTTP Method: %v\n", r.Method))
+	if r.Method != "GET" && !(r.Method == "POST" && responseMode == responseModeFormPostJWT) {
+		writeError(w, l, fmt.Errorf("Bad HTTP Method: %v\n", r.Method))
 		return
 	}
 
+	//A JARM Authn Response carries its parameters in a signed JWT
+	if isJARM(responseMode) {
+		authnRespParams, err = jarmParams(r)
+		if err != nil {
+			logger.Println(err)
+			writeError(w, l, err)
+			return
+		}
+	}
+
 	//The authnCookie contains the aead encrypted oidState
 	authnCookie, err = r.Cookie("authnCookie")
 	if err != nil {
-		writeError(w, fmt.Errorf("Missing authnCookie\n"))
+		writeError(w, l, fmt.Errorf("Missing authnCookie\n"))
 		return
 	}
-	_, authnReqStateString, err = aead.Decrypt(aeadCipher, authnCookie.Value)
+	authnCookieMetadata, authnReqStateString, err := aead.DecryptFields(aeadCipher, authnCookie.Value)
 	if err != nil {
-		writeError(w, err)
+		writeError(w, l, err)
+		return
+	}
+	if authnCookieMetadata["type"] != "AuthnReqState" {
+		writeError(w, l, fmt.Errorf("Bad authnCookie\n"))
 		return
 	}
 	json.Unmarshal([]byte(authnReqStateString), &authnReqState)
+	l = newLocalizer(r, authnReqState.UILocales)
+	login = newTranscript(authnReqState)
+	login.exchange("authn-response", r, redactValues(authnRespParams), nil, "")
 
 	//Validate that the oidState values match
 	authnRespStateList, ok := authnRespParams["state"]
 	if !ok {
-		writeError(w, fmt.Errorf("Missing Authn Response State\n"))
+		writeError(w, l, fmt.Errorf("Missing Authn Response State\n"))
 		return
 	}
 	switch len(authnRespStateList) {
 	case 1:
 		if authnReqState.State != authnRespStateList[0] {
-			writeError(w, fmt.Errorf("State match failed\nexpected state: %v\nprovided state: %v\n", authnReqState.State, authnRespStateList[0]))
+			writeError(w, l, fmt.Errorf("State match failed\nexpected state: %v\nprovided state: %v\n", authnReqState.State, authnRespStateList[0]))
 			return
 		}
 	default:
-		writeError(w, fmt.Errorf("Authn Response State has %v values", len(authnRespStateList)))
+		writeError(w, l, fmt.Errorf("Authn Response State has %v values", len(authnRespStateList)))
 		return
 	}
 	if authnReqState.State != authnRespParams["state"][0] {
-		writeError(w, fmt.Errorf("State match failed\nexpected state: %v\nprovided state: %v\n", authnReqState.State, authnRespParams["state"]))
+		writeError(w, l, fmt.Errorf("State match failed\nexpected state: %v\nprovided state: %v\n", authnReqState.State, authnRespParams["state"]))
 		return
 	}
+	login.check("state", true, "The Authn Response state matches the Authn Request state")
 
 	//If the OP returned an Authn Request error, report it.
 	_, ok = authnRespParams["error"]
 	if ok {
-		writeError(w, fmt.Errorf("OP Authn Request Error: %v\n %v\n %v\n", authnRespParams["error"], authnRespParams["error_description"], authnRespParams["error_uri"]))
+		writeError(w, l, fmt.Errorf("OP Authn Request Error: %v\n %v\n %v\n", authnRespParams["error"], authnRespParams["error_description"], authnRespParams["error_uri"]))
 		return
 	}
 
 	//One Authorization Code must be provided
 	authnRespCodeList, ok := authnRespParams["code"]
 	if !ok {
-		writeError(w, fmt.Errorf("Missing Authn Response Authorization Code"))
+		writeError(w, l, fmt.Errorf("Missing Authn Response Authorization Code"))
 		return
 	}
 	if len(authnRespCodeList) != 1 {
-		writeError(w, fmt.Errorf("Authn Response Authorization Code has %v values\n", len(authnRespStateList)))
+		writeError(w, l, fmt.Errorf("Authn Response Authorization Code has %v values\n", len(authnRespStateList)))
 		return
 	}
+	login.check("code", true, "The Authn Response has one Authorization Code")
 
-	//Issue the Token Request to the OP Token Endpoint. TNaaS OPs always use client_secret_jwt client authentication.
-	requestTime := time.Now().UTC()
-	clientAssertion.Claims = map[string]interface{}{"iss": clientID, "sub": clientID, "aud": opTokenEndpoint, "jti": uuid.NewRandom().String(), "exp": requestTime.Add(time.Minute * 10).String(), "iat": requestTime.String()}
-	fmt.Println("Client Assertion Claims: ", clientAssertion.Claims)
-	clientAssertionString, err := clientAssertion.SignedString([]byte(opSharedSecret))
+	//Issue the Token Request to the OP Token Endpoint with the flow's client auth method
+	tokenReq, err := newTokenRequest(authnRespParams["code"][0], authnReqState.RedirectURI, authnReqState.TokenAuth, authnReqState.Alg)
 	if err != nil {
-		writeError(w, fmt.Errorf("Client Assertion Signing Error: %v", err))
+		writeError(w, l, err)
 		return
 	}
-	tokenRequestForm := url.Values{"grant_type": {"authorization_code"}, "code": {authnRespParams["code"][0]}, "client_id": {clientID}, "client_assertion_type": {"urn:ietf:params:oauth:client-assertion-type:jwt-bearer"}, "client_assertion": {clientAssertionString}, "redirect_uri": {"https://" + exthost + "/authn-token"}}
-	tokenRsp, err := opClient.PostForm(opTokenEndpoint, tokenRequestForm)
+	logger.DebugCtx(r.Context(), "Token Request to %v with token_auth: %v alg: %v", opTokenEndpoint, authnReqState.TokenAuth, authnReqState.Alg)
+	tokenRsp, err := opClient.Do(tokenReq)
 	if err != nil {
-		writeError(w, fmt.Errorf("Token Endpoint Form Post Error: %v", err))
+		writeError(w, l, fmt.Errorf("Token Endpoint Form Post Error: %v", err))
 		return
 	}
 
-	fmt.Println(opTokenEndpoint, " form: ", tokenRequestForm)
-
 	//Read the Token Response Body
 	tokenRspBodyBytes, err := ioutil.ReadAll(tokenRsp.Body)
 	fmt.Println("Token Endpoint Response Body: ", string(tokenRspBodyBytes))
 
+	login.exchange("token", tokenReq, requestForm(tokenReq), tokenRsp, redactJSON(tokenRspBodyBytes))
+
 	//Validate the response is good and unmarshal it's JSON body
 	if tokenRsp.StatusCode != http.StatusOK {
-		writeError(w, fmt.Errorf("OP Token Request Status Error: %v\n%v", tokenRsp.Status, string(tokenRspBodyBytes)))
+		writeError(w, l, fmt.Errorf("OP Token Request Status Error: %v\n%v", tokenRsp.Status, string(tokenRspBodyBytes)))
 		return
 	}
 	if tokenRsp.Header.Get("Content-Type") != "application/json" {
-		writeError(w, fmt.Errorf("OP Token Request Bad Content-Type: %v", tokenRsp.Header.Get("Content-Type")))
+		writeError(w, l, fmt.Errorf("OP Token Request Bad Content-Type: %v", tokenRsp.Header.Get("Content-Type")))
 		return
 	}
 	err = json.Unmarshal(tokenRspBodyBytes, &tokenRspBody)
 	if err != nil {
-		writeError(w, fmt.Errorf("Error Decoding Token Response Body: %v", err))
+		writeError(w, l, fmt.Errorf("Error Decoding Token Response Body: %v", err))
 		return
 	}
 	fmt.Println("Parsed Token Endpoint Response Body: ", tokenRspBody)
 
 	//The ID Token provided by the OP is parsed
 	if tokenRspBody.IDToken == "" {
-		writeError(w, fmt.Errorf("Missing Token Response ID Token"))
+		writeError(w, l, fmt.Errorf("Missing Token Response ID Token"))
 		return
 	}
 	idToken, err = jwt.Parse(tokenRspBody.IDToken, keyfunc)
 	if err != nil {
-		writeError(w, fmt.Errorf("ID Token Parsing Failed with Error: %v", err))
+		writeError(w, l, fmt.Errorf("ID Token Parsing Failed with Error: %v", err))
 		return
 	}
+	login.check("id_token", true, fmt.Sprintf("The ID Token %v signature is valid", idToken.Method.Alg()))
 
 	//The Authn Request nonce  must match the ID Token nonce
 	if authnReqState.Nonce != idToken.Claims["nonce"].(string) {
-		writeError(w, fmt.Errorf("Authn Request Nonce does not match ID Token Nonce: %v  %v", authnReqState.Nonce, idToken.Claims["nonce"].(string)))
+		writeError(w, l, fmt.Errorf("Authn Request Nonce does not match ID Token Nonce: %v  %v", authnReqState.Nonce, idToken.Claims["nonce"].(string)))
 		return
 	}
+	login.check("nonce", true, "The ID Token nonce matches the Authn Request nonce")
 
 	//Use the Access Token to retrieve the subject's userinfo from the OP userinfo endpoint.
 	if tokenRspBody.AccessToken == "" {
-		writeError(w, fmt.Errorf("Missing Token Response Access Token"))
+		writeError(w, l, fmt.Errorf("Missing Token Response Access Token"))
 		return
 	}
 	userInfoReq, err = http.NewRequest("GET", opUserInfoEndpoint, nil)
@@ -346,37 +550,98 @@ func handleAuthnToken(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("User Info Request: ", userInfoReq)
 	userInfoRsp, err = opClient.Do(userInfoReq)
 	if err != nil {
-		writeError(w, fmt.Errorf("User Info Request Failed: %v", err))
+		writeError(w, l, fmt.Errorf("User Info Request Failed: %v", err))
 		return
 	}
-	userInfoRspBodyBytes, err := ioutil.ReadAll(userInfoRsp.Body)
-	if err != nil {
-		writeError(w, fmt.Errorf("Reading User Info Request Body Failed: %v", err))
+	defer userInfoRsp.Body.Close()
+	if userInfoRsp.StatusCode != http.StatusOK {
+		userInfoRspBodyBytes, _ := ioutil.ReadAll(io.LimitReader(userInfoRsp.Body, 64*1024))
+		writeError(w, l, fmt.Errorf("User Info Request Failed: %v\n%v", userInfoRsp.Status, string(userInfoRspBodyBytes)))
 		return
 	}
-	if userInfoRsp.StatusCode != http.StatusOK {
-		writeError(w, fmt.Errorf("User Info Request Failed: %v\n%v", userInfoRsp.Status, string(userInfoRspBodyBytes)))
+
+	//The User Info is streamed into pages so that very large User Info documents are not held in memory
+	userInfoPageFiles, err = paginateUserInfo(userInfoRsp.Body, maxUserInfoPage)
+	if err != nil {
+		writeError(w, l, fmt.Errorf("Reading User Info Request Body Failed: %v", err))
 		return
 	}
+	userInfoPage, _ := ioutil.ReadFile(userInfoPageFiles[0])
+	login.exchange("userinfo", userInfoReq, "", userInfoRsp, string(userInfoPage))
 
 	//The content of the ID Token Header and Claims is transformed to JSON
-	headerJSON := "{"
+	headerValues := make(map[string]string, len(idToken.Header))
 	for key, val := range idToken.Header {
-		headerJSON = headerJSON + `"` + key + `": "` + val.(string) + `",`
+		headerValues[key] = fmt.Sprint(val)
 	}
-	headerJSON = headerJSON[:len(headerJSON)-2] + "}"
-
-	claimsJSON := "{"
+	claimValues := make(map[string]string, len(idToken.Claims))
 	for key, val := range idToken.Claims {
-		claimsJSON = claimsJSON + `"` + key + `": "` + fmt.Sprint(val) + `",`
+		claimValues[key] = fmt.Sprint(val)
 	}
-	claimsJSON = claimsJSON[:len(claimsJSON)-2] + "}"
+	idTokenJSON, _ = json.Marshal(map[string]interface{}{"header": headerValues, "claims": claimValues})
+	messageJSON, _ = json.Marshal(l.msg("result.success"))
+	login.IDToken = string(idTokenJSON)
+	login.Subject = claimValues["sub"]
 
-	idTokenJSON := `{"header": ` + headerJSON + `, "claims": ` + claimsJSON + "}"
-	resultJSON := `{"idtoken": ` + idTokenJSON + `, "userinfo": ` + string(userInfoRspBodyBytes) + "}"
+	//The subject's application session is set before the result is streamed
+	required, err := requiredClaims(idToken.Claims, userInfoPageFiles)
+	if err != nil {
+		removePages(userInfoPageFiles)
+		writeError(w, l, fmt.Errorf("Reading User Info Pages Failed: %v", err))
+		return
+	}
+	login.Completed = clk.Now().UTC()
+	sessionCookie, sessionID, err = newSessionCookie(idToken.Claims, required, login)
+	if err != nil {
+		removePages(userInfoPageFiles)
+		writeError(w, l, err)
+		return
+	}
+	http.SetCookie(w, sessionCookie)
+
+	//The result is streamed with the first User Info page inline and links to any following pages
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Language", l.lang())
+	resultWriter, closeResultWriter = newResultWriter(w, r)
+	fmt.Fprintf(resultWriter, `{"message": %s, "idtoken": %s, "userinfo": `, messageJSON, idTokenJSON)
+	err = copyPage(resultWriter, userInfoPageFiles[0])
+	if err != nil {
+		logger.Printf("Streaming User Info failed with Error: %v\n", err)
+	}
+	if len(userInfoPageFiles) > 1 {
+		pagesKey := addPages(userInfoPageFiles, sessionID)
+		linksJSON, _ := json.Marshal(pageLinks(r, pagesKey, 0, len(userInfoPageFiles)))
+		fmt.Fprintf(resultWriter, `, "userinfo_pages": %d, "userinfo_links": %s`, len(userInfoPageFiles), linksJSON)
+	} else {
+		removePages(userInfoPageFiles)
+	}
+	io.WriteString(resultWriter, "}")
+	closeResultWriter()
 
-	w.Header().Set("Content-Type", "application/JSON")
-	w.Write([]byte(resultJSON))
+	eventbus.Publish(eventbus.TopicLoginCompleted, map[string]string{"sub": claimValues["sub"], "clientID": clientID})
+}
+
+/*
+handleLogout clears the authn and session cookies and responds with a localized logout confirmation.
+*/
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	var (
+		l           = newLocalizer(r, "")
+		authnCookie = http.Cookie{Name: "authnCookie", Value: "", Path: "/authn-token", Domain: exthost, HttpOnly: true, Secure: true, MaxAge: -1}
+	)
+
+	if r.Method != "GET" {
+		writeError(w, l, fmt.Errorf("Bad HTTP Method: %v", r.Method))
+		return
+	}
+	if session, err := getSession(r); err == nil {
+		deleteSession(session.ID)
+	}
+	http.SetCookie(w, &authnCookie)
+	http.SetCookie(w, clearSessionCookie())
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Language", l.lang())
+	w.Write([]byte(l.msg("logout.confirmed")))
 }
 
 /*
@@ -386,32 +651,74 @@ func main() {
 	var (
 		certPool *x509.CertPool
 		server   http.Server
+		listener net.Listener
+		aeadKey  = make([]byte, 32)
 		err      error
 	)
 
 	//This aeadCipher is used to encrypt/decrypt the Authn Request Cookie that is used to pass the Authn Request State value
-	//from the Authn Request to the Authn Response.
-	aeadCipher, err = aead.NewAEADCipher()
+	//from the Authn Request to the Authn Response. Its key is generated here so that its fingerprint can be registered
+	//with the diagnostics endpoints.
+	_, err = rand.Read(aeadKey)
 	if err != nil {
-		return
+		logger.Fatal(err)
+	}
+	aeadCipher, err = aead.NewAEADCipher(aeadKey)
+	if err != nil {
+		logger.Fatal(err)
 	}
+	diagz.RegisterKey("authnCookie", aeadKey)
 
 	//Initialize an HTTPS capable client
 	certPool = x509.NewCertPool()
 	certPool.AppendCertsFromPEM([]byte(certbndl.PemCerts))
 	opClient = &http.Client{
 		Transport: &http.Transport{
-			TLSClientConfig: &tls.Config{RootCAs: certPool},
+			TLSClientConfig: newOPTLSConfig(certPool, ophost, opPins),
 		},
 	}
 
 	//Start the service
-	server = http.Server{Addr: ":443", ReadTimeout: 10 * time.Minute, WriteTimeout: 10 * time.Minute, ErrorLog: logger.Logger()}
+	server = http.Server{Addr: ":443", Handler: proxies.Middleware(http.DefaultServeMux), ReadTimeout: 10 * time.Minute, WriteTimeout: 10 * time.Minute, ErrorLog: logger.Logger()}
 	http.HandleFunc("/login", handleLogin)
 	http.HandleFunc("/authn-token", handleAuthnToken)
+	http.HandleFunc("/logout", handleLogout)
+	http.HandleFunc("/me", handleMe)
+	http.HandleFunc("/protected", handleProtected)
+	http.HandleFunc("/transcript", handleTranscript)
+	http.HandleFunc("/backchannel-logout", handleBackchannelLogout)
+	http.HandleFunc("/userinfo-page/", handleUserInfoPage)
+	if diagToken != "" {
+		diagz.AttachLogRing(1000)
+		diagz.Mount(nil, "/debug", diagz.TokenAuth(diagToken))
+	}
+	go purgePages()
 	logger.Println("Starting oidc on " + exthost + ":443")
-	err = server.ListenAndServeTLS("resilient-networks.crt", "resilient-networks.key")
+	oplog.ServiceStarting(oplog.NewServiceInfo("oidc"))
+	listener, err = net.Listen("tcp", server.Addr)
+	if err != nil {
+		oplog.ServiceStopping(err.Error())
+		logger.Fatal(err)
+	}
+	oplog.ServiceReady()
+
+	//In load mode, the server runs until the virtual users have finished
+	if loadUsers > 0 {
+		go func() {
+			err := server.ServeTLS(listener, "resilient-networks.crt", "resilient-networks.key")
+			if err != nil && err != http.ErrServerClosed {
+				logger.Fatal(err)
+			}
+		}()
+		runLoad("https://"+exthost, loadUsers, loadLogins, rampUp)
+		oplog.ServiceStopping("load test completed")
+		server.Close()
+		return
+	}
+
+	err = server.ServeTLS(listener, "resilient-networks.crt", "resilient-networks.key")
 	if err != nil {
+		oplog.ServiceStopping(err.Error())
 		logger.Fatal(err)
 	}
 
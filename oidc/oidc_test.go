@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/develrns/resilient/clock"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+//Since init parses the command line, the testing flags must be registered before it runs; package variables are
+//initialized before any init function.
+var _ = func() bool {
+	testing.Init()
+	return true
+}()
+
+//An op is a fake OP whose JWKS endpoint serves the public keys of its RSA and EC signing keys
+type op struct {
+	rsaKey  *rsa.PrivateKey
+	ecKey   *ecdsa.PrivateKey
+	fake    *clock.Fake
+	fetches int32
+	down    int32
+}
+
+//newOP starts a fake OP and configures this RP to use it until the test ends
+func newOP(test *testing.T) *op {
+	var (
+		o   = &op{fake: clock.NewFake(time.Now().Truncate(time.Second))}
+		srv *httptest.Server
+		err error
+	)
+
+	o.rsaKey, err = rsa.GenerateKey(rand.Reader, 2048)
+	if err == nil {
+		o.ecKey, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	}
+	if err != nil {
+		test.Fatalf("GenerateKey: %v", err)
+	}
+	srv = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&o.fetches, 1)
+		if atomic.LoadInt32(&o.down) != 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string][]jsonWebKey{"keys": {
+			{Kty: "RSA", Kid: "rsa", Use: "sig", N: b64(o.rsaKey.N.Bytes()), E: b64(big.NewInt(int64(o.rsaKey.E)).Bytes())},
+			{Kty: "EC", Kid: "ec", Crv: "P-256", X: b64(o.ecKey.X.Bytes()), Y: b64(o.ecKey.Y.Bytes())},
+			{Kty: "RSA", Kid: "enc", Use: "enc", N: b64(o.rsaKey.N.Bytes()), E: "AQAB"},
+		}})
+	}))
+
+	replaced := struct {
+		clk                                                       clock.Clock
+		client                                                    *http.Client
+		endpoint, issuer, clientID, secret, responseMode, exthost string
+	}{clk, opClient, opJWKSEndpoint, opIssuer, clientID, opSharedSecret, responseMode, exthost}
+	test.Cleanup(func() {
+		srv.Close()
+		clk, opClient, opJWKSEndpoint, opIssuer = replaced.clk, replaced.client, replaced.endpoint, replaced.issuer
+		clientID, opSharedSecret, responseMode, exthost = replaced.clientID, replaced.secret, replaced.responseMode, replaced.exthost
+		resetJWKS()
+	})
+	resetJWKS()
+	clk, opClient, opJWKSEndpoint, opIssuer = o.fake, srv.Client(), srv.URL+"/openId/jwks", "https://op.ex.org"
+	clientID, opSharedSecret, responseMode, exthost = "rp", "shared secret", responseModeJWT, "rp.ex.org"
+	return o
+}
+
+//resetJWKS empties the JWKS cache
+func resetJWKS() {
+	jwks.m.Lock()
+	defer jwks.m.Unlock()
+	jwks.keys, jwks.fetched, jwks.attempted = nil, time.Time{}, time.Time{}
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+//claims returns valid claims issued by the OP to this RP, with the extra claims; a nil extra claim is removed
+func (o *op) claims(extra map[string]interface{}) map[string]interface{} {
+	var claims = map[string]interface{}{
+		"iss": opIssuer, "aud": clientID, "iat": o.fake.Now().Unix(), "exp": o.fake.Now().Add(5 * time.Minute).Unix(),
+	}
+
+	for name, value := range extra {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+	return claims
+}
+
+//sign returns a JWT of the claims signed by the OP with the alg
+func (o *op) sign(test *testing.T, alg string, claims map[string]interface{}) string {
+	var (
+		token = jwt.New(jwt.GetSigningMethod(alg))
+		key   interface{}
+	)
+
+	token.Claims = claims
+	switch alg {
+	case "HS256":
+		key = []byte(opSharedSecret)
+	case "RS256", "PS256":
+		token.Header["kid"], key = "rsa", o.rsaKey
+	case "ES256":
+		token.Header["kid"], key = "ec", o.ecKey
+	case "none":
+		key = jwt.UnsafeAllowNoneSignatureType
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		test.Fatalf("SignedString %v: %v", alg, err)
+	}
+	return signed
+}
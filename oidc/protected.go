@@ -0,0 +1,250 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+/*
+The /protected endpoint demonstrates end-to-end enforcement of a policy driven by the claims that TNaaS asserts. It is
+guarded by the claim requirements of the -require flag, e.g.:
+
+	-require	- the comma separated claim requirements, e.g. "email_verified=true,groups=admins"
+
+A requirement "claim=value" is satisfied if the claim is value or, if it is an array of values or a string of space
+separated values (like the role claim), one of them is value. A bare "claim" is satisfied if the claim is present and
+is not false, null or empty. All the requirements must be satisfied.
+
+The requirements are evaluated against the claims of the session's ID Token, or, if the ID Token does not have a
+claim, its User Info. Only the required claims are kept with the session, in the session table rather than the
+session cookie, so the decision is not made with claims that were not asserted at login.
+
+A GET request responds with the JSON decision and an explanation of each requirement:
+
+	{"decision": "allow", "sub": "...", "requirements": [{"requirement": "groups=admins", "satisfied": true,
+	 "explanation": "ID Token claim groups has admins"}]}
+
+The response is 200 OK if access is allowed, 403 Forbidden if it is denied and 401 Unauthorized if there is no session.
+*/
+
+//A claimRequirement is a requirement of the -require flag
+type claimRequirement struct {
+	Claim string
+	Value string
+}
+
+//A requirementResult is the evaluation of a claimRequirement in a /protected response
+type requirementResult struct {
+	Requirement string `json:"requirement"`
+	Satisfied   bool   `json:"satisfied"`
+	Explanation string `json:"explanation"`
+}
+
+//An accessDecision is the body of a /protected response
+type accessDecision struct {
+	Decision     string              `json:"decision"`
+	Sub          string              `json:"sub"`
+	Requirements []requirementResult `json:"requirements"`
+}
+
+//The source of a required claim
+const (
+	claimSourceIDToken  = "ID Token"
+	claimSourceUserInfo = "User Info"
+)
+
+//String returns the -require form of a claimRequirement
+func (req claimRequirement) String() string {
+	if req.Value == "" {
+		return req.Claim
+	}
+	return req.Claim + "=" + req.Value
+}
+
+/*
+parseRequirements parses a -require flag value into its claim requirements.
+*/
+func parseRequirements(value string) ([]claimRequirement, error) {
+	var requirements []claimRequirement
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, "=", 2)
+		claim := strings.TrimSpace(kv[0])
+		if claim == "" {
+			return nil, fmt.Errorf("Bad Claim Requirement: %v", item)
+		}
+		req := claimRequirement{Claim: claim}
+		if len(kv) == 2 {
+			req.Value = strings.TrimSpace(kv[1])
+			if req.Value == "" {
+				return nil, fmt.Errorf("Bad Claim Requirement: %v", item)
+			}
+		}
+		requirements = append(requirements, req)
+	}
+	return requirements, nil
+}
+
+/*
+requiredClaims returns the required claims of the ID Token and User Info of a login, keyed by their source and then
+claim name. userInfoPageFiles are the User Info pages written by paginateUserInfo; they are read a page at a time.
+*/
+func requiredClaims(idTokenClaims map[string]interface{}, userInfoPageFiles []string) (map[string]map[string]interface{}, error) {
+	var (
+		claims    = map[string]map[string]interface{}{claimSourceIDToken: {}, claimSourceUserInfo: {}}
+		names     = make(map[string]bool)
+		pageBytes []byte
+		page      map[string]interface{}
+		err       error
+	)
+
+	for _, req := range requirements {
+		names[req.Claim] = true
+	}
+	if len(names) == 0 {
+		return claims, nil
+	}
+	for name := range names {
+		if v, ok := idTokenClaims[name]; ok {
+			claims[claimSourceIDToken][name] = v
+		}
+	}
+	for _, file := range userInfoPageFiles {
+		pageBytes, err = ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		page = nil
+		err = json.Unmarshal(pageBytes, &page)
+		if err != nil {
+			return nil, fmt.Errorf("Bad User Info Page: %v", err)
+		}
+		for name := range names {
+			if v, ok := page[name]; ok {
+				claims[claimSourceUserInfo][name] = v
+			}
+		}
+	}
+	return claims, nil
+}
+
+//claimValues returns the string values of a claim: its elements if it is an array, or its space separated values
+func claimValues(v interface{}) []string {
+	var values []string
+
+	switch cv := v.(type) {
+	case string:
+		return strings.Fields(cv)
+	case []interface{}:
+		for _, item := range cv {
+			values = append(values, fmt.Sprint(item))
+		}
+		return values
+	case nil:
+		return nil
+	default:
+		return []string{fmt.Sprint(cv)}
+	}
+}
+
+/*
+evaluate evaluates a claimRequirement against the required claims of a session.
+*/
+func (req claimRequirement) evaluate(claims map[string]map[string]interface{}) requirementResult {
+	var (
+		result = requirementResult{Requirement: req.String()}
+		source string
+		v      interface{}
+		ok     bool
+	)
+
+	for _, source = range []string{claimSourceIDToken, claimSourceUserInfo} {
+		if v, ok = claims[source][req.Claim]; ok {
+			break
+		}
+	}
+	if !ok {
+		result.Explanation = fmt.Sprintf("Neither the ID Token nor the User Info has claim %v", req.Claim)
+		return result
+	}
+
+	values := claimValues(v)
+	if req.Value == "" {
+		result.Satisfied = len(values) > 0 && v != false
+		if result.Satisfied {
+			result.Explanation = fmt.Sprintf("%v claim %v is present", source, req.Claim)
+		} else {
+			result.Explanation = fmt.Sprintf("%v claim %v is %v", source, req.Claim, jsonString(v))
+		}
+		return result
+	}
+	for _, value := range values {
+		if value == req.Value {
+			result.Satisfied = true
+			break
+		}
+	}
+	switch {
+	case result.Satisfied && len(values) == 1:
+		result.Explanation = fmt.Sprintf("%v claim %v is %v", source, req.Claim, req.Value)
+	case result.Satisfied:
+		result.Explanation = fmt.Sprintf("%v claim %v has %v", source, req.Claim, req.Value)
+	default:
+		result.Explanation = fmt.Sprintf("%v claim %v is %v, not %v", source, req.Claim, jsonString(v), req.Value)
+	}
+	return result
+}
+
+//jsonString returns the JSON text of a claim value for an explanation
+func jsonString(v interface{}) string {
+	var b, _ = json.Marshal(v)
+	return string(b)
+}
+
+/*
+handleProtected responds with the access decision of the -require claim requirements for the logged in subject.
+*/
+func handleProtected(w http.ResponseWriter, r *http.Request) {
+	var (
+		session  Session
+		claims   map[string]map[string]interface{}
+		decision = accessDecision{Decision: "allow", Requirements: []requirementResult{}}
+		status   = http.StatusOK
+		body     []byte
+		err      error
+	)
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	session, err = getSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	claims = sessionClaims(session.ID)
+	decision.Sub = session.Sub
+	for _, req := range requirements {
+		result := req.evaluate(claims)
+		if !result.Satisfied {
+			decision.Decision = "deny"
+			status = http.StatusForbidden
+		}
+		decision.Requirements = append(decision.Requirements, result)
+	}
+	logger.Printf("/protected %v for %v\n", decision.Decision, session.Sub)
+
+	body, _ = json.Marshal(&decision)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	w.Write(body)
+}
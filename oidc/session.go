@@ -0,0 +1,270 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/develrns/resilient/aead"
+
+	"github.com/pborman/uuid"
+)
+
+/*
+After a login the RP maps the ID Token's role claim (e.g. the groups that the TNaaS policy asserts) to application
+roles and stores them with the subject in an aead encrypted session cookie. The /me endpoint returns the session as
+JSON, which is how a downstream application would consume the policy's output:
+
+	-roleclaim	- the ID Token claim whose values are mapped to roles (default groups)
+	-rolemap	- the comma separated claim value=role pairs, e.g. "admins=admin,staff=user,staff=auditor"
+
+A claim value may map to several roles, and claim values that are not mapped grant no role. The role claim may be a
+string of space separated values or an array of strings.
+
+Each session has an ID and is recorded, with its subject and the OP's session ID (the ID Token's sid claim, if any),
+in an in-memory session table so that it can be destroyed before it expires: by /logout or by an OP back-channel
+logout (see backchannel.go). A session cookie whose session is not in the table is rejected, so sessions do not survive
+a restart of the RP.
+*/
+
+//sessionMaxAge is the lifetime of a session
+const sessionMaxAge = 8 * time.Hour
+
+//sessionCookieName is the name of the session cookie
+const sessionCookieName = "sessionCookie"
+
+//A Session is the application session of a logged in subject
+type Session struct {
+	ID      string    `json:"id"`
+	Sub     string    `json:"sub"`
+	SID     string    `json:"sid,omitempty"`
+	Roles   []string  `json:"roles"`
+	Expires time.Time `json:"expires"`
+
+	//claims are the claims required by /protected (see protected.go), which are kept in the sessions table only
+	claims map[string]map[string]interface{}
+
+	//login is the transcript of the Session's login (see transcript.go), which is also kept in the sessions table only
+	login *transcript
+}
+
+//sessions is the table of active Sessions keyed by ID. Since it is accessed by concurrent requests, it must be mutexed.
+var sessions = struct {
+	m sync.Mutex
+	s map[string]Session
+}{s: make(map[string]Session)}
+
+//addSession adds a Session to the sessions table, purging the expired ones
+func addSession(session Session) {
+	var now = clk.Now()
+
+	sessions.m.Lock()
+	defer sessions.m.Unlock()
+	for id, s := range sessions.s {
+		if now.After(s.Expires) {
+			delete(sessions.s, id)
+		}
+	}
+	sessions.s[session.ID] = session
+}
+
+//isActiveSession is true if a Session is in the sessions table
+func isActiveSession(id string) bool {
+	sessions.m.Lock()
+	defer sessions.m.Unlock()
+	_, ok := sessions.s[id]
+	return ok
+}
+
+//sessionClaims returns the required claims of a Session in the sessions table, or nil if it is not in the table
+func sessionClaims(id string) map[string]map[string]interface{} {
+	sessions.m.Lock()
+	defer sessions.m.Unlock()
+	return sessions.s[id].claims
+}
+
+//sessionTranscript returns the login transcript of a Session in the sessions table, or nil if it is not in the table
+func sessionTranscript(id string) *transcript {
+	sessions.m.Lock()
+	defer sessions.m.Unlock()
+	return sessions.s[id].login
+}
+
+//deleteSession removes a Session from the sessions table
+func deleteSession(id string) {
+	sessions.m.Lock()
+	defer sessions.m.Unlock()
+	delete(sessions.s, id)
+}
+
+/*
+deleteSessions removes the Sessions of an OP session and subject from the sessions table and returns the number
+removed. If sid is set, the Sessions with that sid (and the sub, if it is set) are removed; otherwise all the Sessions
+of the sub are removed.
+*/
+func deleteSessions(sid, sub string) int {
+	var deleted int
+
+	sessions.m.Lock()
+	defer sessions.m.Unlock()
+	for id, s := range sessions.s {
+		if (sid != "" && s.SID == sid && (sub == "" || s.Sub == sub)) || (sid == "" && sub != "" && s.Sub == sub) {
+			delete(sessions.s, id)
+			deleted++
+		}
+	}
+	return deleted
+}
+
+/*
+parseRoleMap parses a -rolemap flag value into the roles of each claim value.
+*/
+func parseRoleMap(value string) (map[string][]string, error) {
+	var roleMap = make(map[string][]string)
+
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return nil, fmt.Errorf("Bad Role Mapping: %v", pair)
+		}
+		claimValue := strings.TrimSpace(kv[0])
+		roleMap[claimValue] = append(roleMap[claimValue], strings.TrimSpace(kv[1]))
+	}
+	return roleMap, nil
+}
+
+/*
+mapRoles returns the sorted, deduplicated roles mapped from the values of a claim.
+*/
+func mapRoles(claims map[string]interface{}, claim string, roleMap map[string][]string) []string {
+	var (
+		values []string
+		seen   = make(map[string]bool)
+		roles  = []string{}
+	)
+
+	switch cv := claims[claim].(type) {
+	case string:
+		values = strings.Fields(cv)
+	case []interface{}:
+		for _, v := range cv {
+			if s, ok := v.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	for _, value := range values {
+		for _, role := range roleMap[value] {
+			if !seen[role] {
+				seen[role] = true
+				roles = append(roles, role)
+			}
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+/*
+newSessionCookie creates the session cookie of a subject whose ID Token has a set of claims and returns it with the
+Session's ID. required are the claims required by /protected and login is the transcript of the login, which are kept
+with the Session in the sessions table.
+*/
+func newSessionCookie(claims map[string]interface{}, required map[string]map[string]interface{}, login *transcript) (*http.Cookie, string, error) {
+	var (
+		session      Session
+		sessionBytes []byte
+		value        string
+		err          error
+	)
+
+	session.ID = uuid.NewRandom().String()
+	session.Sub, _ = claims["sub"].(string)
+	session.SID, _ = claims["sid"].(string)
+	session.Roles = mapRoles(claims, roleClaim, roleMap)
+	session.Expires = clk.Now().Add(sessionMaxAge).UTC()
+	sessionBytes, _ = json.Marshal(&session)
+	value, err = aead.EncryptFields(aeadCipher, map[string]string{"type": "Session"}, string(sessionBytes))
+	if err != nil {
+		return nil, "", err
+	}
+	session.claims = required
+	session.login = login
+	addSession(session)
+	return &http.Cookie{Name: sessionCookieName, Value: value, Path: "/", Domain: exthost, HttpOnly: true, Secure: true, MaxAge: int(sessionMaxAge / time.Second)}, session.ID, nil
+}
+
+/*
+getSession gets the Session of a request's session cookie. It fails if there is no cookie, it was not sealed by this RP
+as a session, the session has expired or it has been destroyed.
+*/
+func getSession(r *http.Request) (Session, error) {
+	var (
+		session       Session
+		cookie        *http.Cookie
+		metadata      map[string]string
+		sessionString string
+		err           error
+	)
+
+	cookie, err = r.Cookie(sessionCookieName)
+	if err != nil {
+		return session, fmt.Errorf("Missing Session")
+	}
+	metadata, sessionString, err = aead.DecryptFields(aeadCipher, cookie.Value)
+	if err != nil || metadata["type"] != "Session" {
+		return session, fmt.Errorf("Bad Session")
+	}
+	err = json.Unmarshal([]byte(sessionString), &session)
+	if err != nil {
+		return session, fmt.Errorf("Bad Session")
+	}
+	if clk.Now().After(session.Expires) {
+		return session, fmt.Errorf("Expired Session")
+	}
+	if !isActiveSession(session.ID) {
+		return session, fmt.Errorf("Revoked Session")
+	}
+	return session, nil
+}
+
+/*
+clearSessionCookie returns a cookie that clears the session cookie.
+*/
+func clearSessionCookie() *http.Cookie {
+	return &http.Cookie{Name: sessionCookieName, Value: "", Path: "/", Domain: exthost, HttpOnly: true, Secure: true, MaxAge: -1}
+}
+
+/*
+handleMe responds with the JSON Session of the logged in subject, or 401 Unauthorized if there is none.
+*/
+func handleMe(w http.ResponseWriter, r *http.Request) {
+	var (
+		session Session
+		body    []byte
+		err     error
+	)
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	session, err = getSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	body, _ = json.Marshal(&session)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(body)
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pborman/uuid"
+)
+
+/*
+Some test policies return userinfo documents in the tens of megabytes. To avoid holding them in memory, a userinfo
+response body is streamed into pages of at most maxUserInfoPage bytes of claims, each held in a temp file. The first
+page is streamed into the login result; if there are more pages, the result links to them and they are served by
+/userinfo-page/<key>/<n> to the session that logged in. Responses are gzip compressed if the user agent accepts it.
+*/
+
+//pageTTL is how long the pages of a paginated userinfo are kept
+const pageTTL = 10 * time.Minute
+
+//userInfoPages holds the temp file names of the pages of a userinfo document and the ID of the Session they belong to
+type userInfoPages struct {
+	files     []string
+	sessionID string
+	created   time.Time
+}
+
+//pageStore holds the userInfoPages of paginated userinfo documents keyed by a type 4 UUID.
+//Since it is accessed by concurrent requests, it must be mutexed.
+var pageStore = struct {
+	m sync.Mutex
+	p map[string]*userInfoPages
+}{p: make(map[string]*userInfoPages)}
+
+/*
+paginateUserInfo splits a userinfo JSON object into pages of claims and writes each page to a temp file as a JSON
+object. A page is closed once it holds at least maxBytes of claims, so a single claim larger than maxBytes is its own page.
+Only one claim at a time is held in memory.
+*/
+func paginateUserInfo(body io.Reader, maxBytes int) ([]string, error) {
+	var (
+		decoder = json.NewDecoder(body)
+		token   json.Token
+		claim   json.RawMessage
+		keyJSON []byte
+		files   []string
+		page    *os.File
+		writer  *bufio.Writer
+		size    int
+		err     error
+	)
+
+	//closePage terminates the current page's JSON object
+	closePage := func() error {
+		if page == nil {
+			return nil
+		}
+		writer.WriteString("}")
+		err := writer.Flush()
+		page.Close()
+		page = nil
+		return err
+	}
+
+	token, err = decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, fmt.Errorf("User Info is not a JSON object")
+	}
+
+	for decoder.More() {
+		token, err = decoder.Token()
+		if err != nil {
+			break
+		}
+		err = decoder.Decode(&claim)
+		if err != nil {
+			break
+		}
+		if page == nil {
+			page, err = ioutil.TempFile("", "oidc-userinfo-")
+			if err != nil {
+				break
+			}
+			files = append(files, page.Name())
+			writer = bufio.NewWriter(page)
+			writer.WriteString("{")
+			size = 0
+		} else {
+			writer.WriteString(",")
+		}
+		keyJSON, _ = json.Marshal(token)
+		writer.Write(keyJSON)
+		writer.WriteString(":")
+		writer.Write(claim)
+		size += len(keyJSON) + len(claim)
+		if size >= maxBytes {
+			err = closePage()
+			if err != nil {
+				break
+			}
+		}
+	}
+	if err == nil {
+		err = closePage()
+	} else {
+		closePage()
+	}
+	if err != nil {
+		removePages(files)
+		return nil, err
+	}
+
+	//An empty userinfo object is a single empty page
+	if len(files) == 0 {
+		page, err = ioutil.TempFile("", "oidc-userinfo-")
+		if err != nil {
+			return nil, err
+		}
+		page.WriteString("{}")
+		page.Close()
+		files = append(files, page.Name())
+	}
+	return files, nil
+}
+
+//removePages removes the temp files of a userinfo document
+func removePages(files []string) {
+	for _, file := range files {
+		os.Remove(file)
+	}
+}
+
+//addPages stores the pages of a userinfo document of a Session and returns their key
+func addPages(files []string, sessionID string) string {
+	var key = uuid.NewRandom().String()
+
+	pageStore.m.Lock()
+	defer pageStore.m.Unlock()
+	pageStore.p[key] = &userInfoPages{files: files, sessionID: sessionID, created: clk.Now()}
+	return key
+}
+
+/*
+openPage opens the file of a Session's userinfo document page and returns it with the number of pages. The file is
+opened while pageStore is locked so that purgePages cannot remove it first; once open, it can be read to the end even
+if it is removed.
+*/
+func openPage(key string, n int, sessionID string) (*os.File, int, error) {
+	var (
+		pages *userInfoPages
+		page  *os.File
+		ok    bool
+		err   error
+	)
+
+	pageStore.m.Lock()
+	defer pageStore.m.Unlock()
+	pages, ok = pageStore.p[key]
+	if !ok || pages.sessionID != sessionID || n < 0 || n >= len(pages.files) {
+		return nil, 0, fmt.Errorf("User Info Page Not Found: %v/%v", key, n)
+	}
+	page, err = os.Open(pages.files[n])
+	if err != nil {
+		return nil, 0, err
+	}
+	return page, len(pages.files), nil
+}
+
+//purgePages deletes the pages that are older than pageTTL once per minute
+func purgePages() {
+	var ticker = clk.NewTicker(time.Minute)
+
+	for {
+		_ = <-ticker.C()
+		pageStore.m.Lock()
+		for key, pages := range pageStore.p {
+			if clk.Now().After(pages.created.Add(pageTTL)) {
+				removePages(pages.files)
+				delete(pageStore.p, key)
+			}
+		}
+		pageStore.m.Unlock()
+	}
+}
+
+//pageURL is the URL of a userinfo document's page (see baseURL)
+func pageURL(r *http.Request, key string, n int) string {
+	return baseURL(r) + "/userinfo-page/" + key + "/" + strconv.Itoa(n)
+}
+
+/*
+newResultWriter returns a writer for a response body that is gzip compressed if the request accepts gzip.
+The returned close function must be called once the body has been written. Since no Content-Length is set, the body
+is sent chunked as it is written.
+*/
+func newResultWriter(w http.ResponseWriter, r *http.Request) (io.Writer, func() error) {
+	var gzipWriter *gzip.Writer
+
+	if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") {
+		return w, func() error { return nil }
+	}
+	w.Header().Set("Content-Encoding", "gzip")
+	w.Header().Add("Vary", "Accept-Encoding")
+	gzipWriter = gzip.NewWriter(w)
+	return gzipWriter, gzipWriter.Close
+}
+
+//copyPage streams a page file to a writer
+func copyPage(w io.Writer, file string) error {
+	var (
+		page *os.File
+		err  error
+	)
+
+	page, err = os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer page.Close()
+	_, err = io.Copy(w, page)
+	return err
+}
+
+/*
+handleUserInfoPage serves page n of a paginated userinfo document as a JSON object containing the page's claims and
+its prev and next page links. The path is /userinfo-page/<key>/<n>. Only the Session that logged in can get its pages.
+*/
+func handleUserInfoPage(w http.ResponseWriter, r *http.Request) {
+	var (
+		l        = newLocalizer(r, "")
+		elements = strings.Split(strings.TrimPrefix(r.URL.Path, "/userinfo-page/"), "/")
+		session  Session
+		page     *os.File
+		n, count int
+		links    []byte
+		writer   io.Writer
+		closeW   func() error
+		err      error
+	)
+
+	if r.Method != "GET" {
+		writeError(w, l, fmt.Errorf("Bad HTTP Method: %v", r.Method))
+		return
+	}
+	if len(elements) != 2 {
+		writeError(w, l, fmt.Errorf("Bad User Info Page Path: %v", r.URL.Path))
+		return
+	}
+	n, err = strconv.Atoi(elements[1])
+	if err != nil {
+		writeError(w, l, fmt.Errorf("Bad User Info Page Number: %v", elements[1]))
+		return
+	}
+	session, err = getSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	page, count, err = openPage(elements[0], n, session.ID)
+	if err != nil {
+		writeError(w, l, err)
+		return
+	}
+	defer page.Close()
+
+	links, _ = json.Marshal(pageLinks(r, elements[0], n, count))
+	w.Header().Set("Content-Type", "application/json")
+	writer, closeW = newResultWriter(w, r)
+	fmt.Fprintf(writer, `{"page": %d, "pages": %d, "links": %s, "claims": `, n, count, links)
+	_, err = io.Copy(writer, page)
+	if err != nil {
+		logger.Printf("Streaming User Info Page %v failed with Error: %v\n", r.URL.Path, err)
+	}
+	io.WriteString(writer, "}")
+	closeW()
+}
+
+//pageLinks returns the prev and next links of a page
+func pageLinks(r *http.Request, key string, n, count int) map[string]string {
+	var links = make(map[string]string, 2)
+
+	if n > 0 {
+		links["prev"] = pageURL(r, key, n-1)
+	}
+	if n < count-1 {
+		links["next"] = pageURL(r, key, n+1)
+	}
+	return links
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/develrns/resilient/aead"
+)
+
+//getPage requests a userinfo page with a session cookie, if it is not nil
+func getPage(path string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	var (
+		w = httptest.NewRecorder()
+		r = httptest.NewRequest("GET", path, nil)
+	)
+
+	if cookie != nil {
+		r.AddCookie(cookie)
+	}
+	handleUserInfoPage(w, r)
+	return w
+}
+
+func TestUserInfoPage(test *testing.T) {
+	var (
+		_        = newOP(test)
+		replaced = aeadCipher
+		files    []string
+		cookie   *http.Cookie
+		other    *http.Cookie
+		id       string
+		page     struct {
+			Page, Pages int
+			Links       map[string]string
+			Claims      map[string]interface{}
+		}
+		err error
+	)
+	defer func() { aeadCipher = replaced }()
+
+	aeadCipher, err = aead.NewAEADCipher(nil)
+	if err == nil {
+		files, err = paginateUserInfo(strings.NewReader(`{"a": 1, "b": "2", "c": [3]}`), 1)
+	}
+	if err == nil {
+		cookie, id, err = newSessionCookie(map[string]interface{}{"sub": "s"}, nil, nil)
+	}
+	if err == nil {
+		other, _, err = newSessionCookie(map[string]interface{}{"sub": "s"}, nil, nil)
+	}
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer removePages(files)
+	key := addPages(files, id)
+
+	//The pages of a session are served only to it
+	if w := getPage("/userinfo-page/"+key+"/1", nil); w.Code != http.StatusUnauthorized {
+		test.Errorf("Page without a session: %v", w.Code)
+	}
+	if w := getPage("/userinfo-page/"+key+"/1", other); w.Code != http.StatusBadRequest {
+		test.Errorf("Page of another session: %v", w.Code)
+	}
+
+	w := getPage("/userinfo-page/"+key+"/1", cookie)
+	if err = json.Unmarshal(w.Body.Bytes(), &page); err != nil || w.Header().Get("Content-Type") != "application/json" {
+		test.Fatalf("Page: %v %v", w.Body.String(), err)
+	}
+	if page.Page != 1 || page.Pages != 3 || page.Claims["b"] != "2" || page.Links["prev"] != "https://rp.ex.org/userinfo-page/"+key+"/0" || page.Links["next"] != "https://rp.ex.org/userinfo-page/"+key+"/2" {
+		test.Errorf("Page: %+v", page)
+	}
+	for _, path := range []string{"/userinfo-page/" + key + "/3", "/userinfo-page/" + key + "/x", "/userinfo-page/" + key} {
+		if w := getPage(path, cookie); w.Code != http.StatusBadRequest {
+			test.Errorf("%v: %v", path, w.Code)
+		}
+	}
+
+	//A page that was opened can still be read to the end once it is removed
+	opened, _, err := openPage(key, 0, id)
+	if err != nil {
+		test.Fatal(err)
+	}
+	defer opened.Close()
+	removePages(files)
+	if claims, err := ioutil.ReadAll(opened); err != nil || string(claims) != `{"a":1}` {
+		test.Errorf("Removed page: %s %v", claims, err)
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/develrns/resilient/jld"
+
+	"github.com/pborman/uuid"
+)
+
+/*
+The transcript of each completed login is kept with its session as conformance evidence. It records the login's
+exchanges with the OP and user agent (the Authn Request and Response, the Token Request and Response and the User
+Info Request and Response) and the results of the login's validation checks (e.g. that the state and nonce match).
+
+Secrets are redacted before they are recorded: the Authorization Code, client secret and assertion, the tokens, JARM
+responses and the Authorization, Cookie and Set-Cookie headers. The ID Token is recorded as its decoded header and
+claims, and the User Info response as its first page (see stream.go).
+
+A /transcript GET request responds with the transcript of the session's login as a JSON LD document of the
+transcriptVocab vocabulary, compacted with its context, so that it can be stored in a graph store and queried later:
+
+	Login		- clientID, subject, tokenAuth, alg, responseMode, started, completed, idToken, exchange and check
+	Exchange	- step, method, url, requestHeader, requestBody, status, responseHeader, responseBody and time
+	Check		- name, passed and detail
+
+The exchange and check values are lists in the order they occurred. The response is 401 Unauthorized if there is no
+session and 404 Not Found if it has no transcript.
+*/
+
+//transcriptBase is the base of the transcript vocabulary
+const transcriptBase = "https://github.com/develrns/resilient/oidc/transcript#"
+
+//redacted replaces the value of a secret in a transcript
+const redacted = "REDACTED"
+
+var (
+	//transcriptVocab is the vocabulary of login transcripts
+	transcriptVocab = jld.NewVocabulary(jld.NewTypeBase(transcriptBase), jld.NewPropBase(transcriptBase))
+
+	//The transcript types
+	loginT    = transcriptVocab.DefineT("Login")
+	exchangeT = transcriptVocab.DefineT("Exchange")
+	checkT    = transcriptVocab.DefineT("Check")
+
+	//The Login properties
+	clientIDP     = transcriptVocab.DefineP("clientID")
+	subjectP      = transcriptVocab.DefineP("subject")
+	tokenAuthP    = transcriptVocab.DefineP("tokenAuth")
+	algP          = transcriptVocab.DefineP("alg")
+	responseModeP = transcriptVocab.DefineP("responseMode")
+	startedP      = transcriptVocab.DefineP("started")
+	completedP    = transcriptVocab.DefineP("completed")
+	idTokenP      = transcriptVocab.DefineP("idToken")
+	exchangeP     = transcriptVocab.DefineP("exchange")
+	checkP        = transcriptVocab.DefineP("check")
+
+	//The Exchange properties
+	stepP           = transcriptVocab.DefineP("step")
+	methodP         = transcriptVocab.DefineP("method")
+	urlP            = transcriptVocab.DefineP("url")
+	requestHeaderP  = transcriptVocab.DefineP("requestHeader")
+	requestBodyP    = transcriptVocab.DefineP("requestBody")
+	statusP         = transcriptVocab.DefineP("status")
+	responseHeaderP = transcriptVocab.DefineP("responseHeader")
+	responseBodyP   = transcriptVocab.DefineP("responseBody")
+	timeP           = transcriptVocab.DefineP("time")
+
+	//The Check properties
+	nameP   = transcriptVocab.DefineP("name")
+	passedP = transcriptVocab.DefineP("passed")
+	detailP = transcriptVocab.DefineP("detail")
+
+	//secretParams are the form, query and JSON parameters whose values are redacted
+	secretParams = map[string]bool{
+		"code": true, "client_secret": true, "client_assertion": true, "access_token": true, "refresh_token": true,
+		"id_token": true, "response": true, "logout_token": true,
+	}
+
+	//secretHeaders are the headers whose values are redacted
+	secretHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+)
+
+//A transcriptExchange is a request and response of a login
+type transcriptExchange struct {
+	Step           string
+	Method         string
+	URL            string
+	RequestHeader  string
+	RequestBody    string
+	Status         int
+	ResponseHeader string
+	ResponseBody   string
+	Time           time.Time
+}
+
+//A transcriptCheck is the result of a validation check of a login
+type transcriptCheck struct {
+	Name   string
+	Passed bool
+	Detail string
+}
+
+//A transcript records a login
+type transcript struct {
+	ID           string
+	ClientID     string
+	Subject      string
+	TokenAuth    string
+	Alg          string
+	ResponseMode string
+	Started      time.Time
+	Completed    time.Time
+	IDToken      string
+	Exchanges    []transcriptExchange
+	Checks       []transcriptCheck
+}
+
+/*
+newTranscript creates the transcript of the login of an Authn Request, which it records.
+*/
+func newTranscript(state AuthnReqState) *transcript {
+	var (
+		tr = &transcript{
+			ID:           uuid.NewRandom().String(),
+			ClientID:     clientID,
+			TokenAuth:    state.TokenAuth,
+			Alg:          state.Alg,
+			ResponseMode: responseMode,
+			Started:      clk.Now().UTC(),
+		}
+		redirectURI = state.RedirectURI
+		authnReq    *http.Request
+		err         error
+	)
+
+	//An authn cookie set before flows could select their options has no redirect URI
+	if redirectURI == "" {
+		redirectURI = "https://" + exthost + "/authn-token"
+	}
+	authnReq, err = http.NewRequest("GET", authnRequestURL(state.State, state.Nonce, redirectURI, state.UILocales), nil)
+	if err == nil {
+		tr.exchange("authn-request", authnReq, "", nil, "")
+	}
+	return tr
+}
+
+//check records the result of a validation check
+func (tr *transcript) check(name string, passed bool, detail string) {
+	tr.Checks = append(tr.Checks, transcriptCheck{Name: name, Passed: passed, Detail: detail})
+}
+
+/*
+exchange records a request and its response, which may be nil if the request was not issued by the RP (e.g. the
+Authn Request, which is a redirect of the user agent). The bodies must already be redacted.
+*/
+func (tr *transcript) exchange(step string, req *http.Request, reqBody string, rsp *http.Response, rspBody string) {
+	var x = transcriptExchange{Step: step, Method: req.Method, URL: redactURL(req.URL), RequestHeader: redactHeader(req.Header), RequestBody: reqBody, Time: clk.Now().UTC()}
+
+	if rsp != nil {
+		x.Status = rsp.StatusCode
+		x.ResponseHeader = redactHeader(rsp.Header)
+		x.ResponseBody = rspBody
+	}
+	tr.Exchanges = append(tr.Exchanges, x)
+}
+
+//redactValues returns the encoding of parameters with the values of the secretParams redacted
+func redactValues(values url.Values) string {
+	var redactedValues = make(url.Values, len(values))
+
+	for k, vs := range values {
+		if secretParams[k] {
+			vs = []string{redacted}
+		}
+		redactedValues[k] = vs
+	}
+	return redactedValues.Encode()
+}
+
+//redactURL returns a URL with the values of its secretParams redacted
+func redactURL(u *url.URL) string {
+	var ru = *u
+
+	if ru.RawQuery != "" {
+		ru.RawQuery = redactValues(ru.Query())
+	}
+	return ru.String()
+}
+
+//redactHeader returns the lines of a header, in sorted order, with the values of the secretHeaders redacted
+func redactHeader(header http.Header) string {
+	var (
+		h     = header.Clone()
+		names []string
+		lines []string
+	)
+
+	for _, name := range secretHeaders {
+		if _, ok := h[name]; ok {
+			h[name] = []string{redacted}
+		}
+	}
+	for name := range h {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		for _, value := range h[name] {
+			lines = append(lines, name+": "+value)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+//redactJSON returns a JSON object with the values of its secretParams redacted; a body that is not an object is not recorded
+func redactJSON(body []byte) string {
+	var (
+		obj map[string]interface{}
+		b   []byte
+	)
+
+	if json.Unmarshal(body, &obj) != nil {
+		return ""
+	}
+	for k := range obj {
+		if secretParams[k] {
+			obj[k] = redacted
+		}
+	}
+	b, _ = json.Marshal(obj)
+	return string(b)
+}
+
+//requestForm returns the redacted form body of a request, which must have been created with a GetBody
+func requestForm(req *http.Request) string {
+	var (
+		body   io.ReadCloser
+		b      []byte
+		values url.Values
+		err    error
+	)
+
+	if req.GetBody == nil {
+		return ""
+	}
+	body, err = req.GetBody()
+	if err != nil {
+		return ""
+	}
+	defer body.Close()
+	b, err = ioutil.ReadAll(body)
+	if err != nil {
+		return ""
+	}
+	values, err = url.ParseQuery(string(b))
+	if err != nil {
+		return ""
+	}
+	return redactValues(values)
+}
+
+/*
+document returns the transcript as a JSON LD document compacted with the transcriptVocab context.
+*/
+func (tr *transcript) document() (map[string]interface{}, error) {
+	var (
+		login     = jld.NewN("urn:uuid:"+tr.ID, loginT)
+		exchanges = make([]interface{}, 0, len(tr.Exchanges))
+		checks    = make([]interface{}, 0, len(tr.Checks))
+	)
+
+	login[clientIDP.URI()] = tr.ClientID
+	login[subjectP.URI()] = tr.Subject
+	login[tokenAuthP.URI()] = tr.TokenAuth
+	login[algP.URI()] = tr.Alg
+	if tr.ResponseMode != "" {
+		login[responseModeP.URI()] = tr.ResponseMode
+	}
+	login[startedP.URI()] = jld.NewTimeV(tr.Started)
+	login[completedP.URI()] = jld.NewTimeV(tr.Completed)
+	login[idTokenP.URI()] = tr.IDToken
+
+	for _, x := range tr.Exchanges {
+		exchange := jld.NewN("", exchangeT)
+		exchange[stepP.URI()] = x.Step
+		exchange[methodP.URI()] = x.Method
+		exchange[urlP.URI()] = x.URL
+		exchange[requestHeaderP.URI()] = x.RequestHeader
+		exchange[requestBodyP.URI()] = x.RequestBody
+		exchange[timeP.URI()] = jld.NewTimeV(x.Time)
+		if x.Status != 0 {
+			exchange[statusP.URI()] = x.Status
+			exchange[responseHeaderP.URI()] = x.ResponseHeader
+			exchange[responseBodyP.URI()] = x.ResponseBody
+		}
+		exchanges = append(exchanges, exchange)
+	}
+	login[exchangeP.URI()] = jld.NewL(exchanges)
+
+	for _, c := range tr.Checks {
+		check := jld.NewN("", checkT)
+		check[nameP.URI()] = c.Name
+		check[passedP.URI()] = c.Passed
+		check[detailP.URI()] = c.Detail
+		checks = append(checks, check)
+	}
+	login[checkP.URI()] = jld.NewL(checks)
+
+	return jld.Compact(login, map[string]interface{}{"@context": transcriptVocab.Context()})
+}
+
+/*
+handleTranscript responds with the JSON LD transcript of the login of the session.
+*/
+func handleTranscript(w http.ResponseWriter, r *http.Request) {
+	var (
+		session  Session
+		tr       *transcript
+		document map[string]interface{}
+		body     []byte
+		err      error
+	)
+
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	session, err = getSession(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnauthorized)
+		return
+	}
+	tr = sessionTranscript(session.ID)
+	if tr == nil {
+		http.Error(w, "Missing Transcript", http.StatusNotFound)
+		return
+	}
+	document, err = tr.document()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	body, _ = json.Marshal(document)
+	w.Header().Set("Content-Type", "application/ld+json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Write(body)
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+/*
+The OP client enforces a strict transport policy:
+
+	TLS version	- TLS 1.2 or later
+	cipher suites	- for TLS 1.2, only ECDHE key exchange with AES-GCM or ChaCha20-Poly1305 (i.e. forward secret AEAD
+			  suites); the TLS 1.3 suites are all AEAD and are not configurable
+
+Optionally, the OP's public key is pinned by -oppins: a connection to ophost must present a verified certificate chain
+that includes a certificate whose SubjectPublicKeyInfo SHA-256 hash is one of the pins. A pin is in the HPKP form
+sha256/<base64 hash>; the hash of a certificate can be computed with:
+
+	openssl x509 -in op.crt -pubkey -noout | openssl pkey -pubin -outform der | openssl dgst -sha256 -binary | base64
+
+A pin of an intermediate or root CA key pins the OP to the CA, and listing a backup key's pin allows the OP to rotate
+its key. Pinning is in addition to the usual chain verification. Connections to other hosts, such as the RP's own
+host in load mode, are not pinned.
+
+A pinning failure is returned as a *pinningError so that it can be distinguished from other transport errors; load
+mode reports the number of pinning failures of each stage.
+*/
+
+//opCipherSuites are the TLS 1.2 cipher suites of the OP client
+var opCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+}
+
+//pinningFailure starts the message of a pinningError; it identifies a pinning failure in an RP error page
+const pinningFailure = "TLS Pinning Failure"
+
+//A pinningError is returned when a pinned host's certificate chain does not include a pinned key
+type pinningError struct {
+	host string
+}
+
+//Error implements error
+func (e *pinningError) Error() string {
+	return fmt.Sprintf("%v: the certificate chain of %v does not include a pinned public key", pinningFailure, e.host)
+}
+
+//isPinningError is true if err is or wraps a pinningError
+func isPinningError(err error) bool {
+	var pinErr *pinningError
+
+	return errors.As(err, &pinErr)
+}
+
+//parsePins parses the comma separated sha256/<base64> pins of -oppins into a set of SPKI hashes
+func parsePins(value string) (map[string]bool, error) {
+	var (
+		pins = make(map[string]bool)
+		hash []byte
+		err  error
+	)
+
+	for _, pin := range strings.Split(value, ",") {
+		pin = strings.TrimSpace(pin)
+		if pin == "" {
+			continue
+		}
+		if !strings.HasPrefix(pin, "sha256/") {
+			return nil, fmt.Errorf("Bad Pin: %v must be of the form sha256/<base64 hash>", pin)
+		}
+		hash, err = base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("Bad Pin: %v is not a base64 SHA-256 hash", pin)
+		}
+		pins[string(hash)] = true
+	}
+	return pins, nil
+}
+
+//spkiHash returns the SHA-256 hash of a certificate's SubjectPublicKeyInfo
+func spkiHash(cert *x509.Certificate) string {
+	var hash = sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+
+	return string(hash[:])
+}
+
+/*
+newOPTLSConfig returns the TLS configuration of the OP client: the strict transport policy with the roots, and the
+pins of pinnedHost, if any.
+*/
+func newOPTLSConfig(roots *x509.CertPool, pinnedHost string, pins map[string]bool) *tls.Config {
+	var config = &tls.Config{
+		RootCAs:      roots,
+		MinVersion:   tls.VersionTLS12,
+		CipherSuites: opCipherSuites,
+	}
+
+	if host, _, err := net.SplitHostPort(pinnedHost); err == nil {
+		pinnedHost = host
+	}
+	if len(pins) == 0 {
+		return config
+	}
+
+	//VerifyConnection is called after the chain has been verified. A connection is to the pinned host if it is its
+	//server name or, since no server name is sent for an IP address, if its certificate is valid for the pinned host.
+	config.VerifyConnection = func(cs tls.ConnectionState) error {
+		if !strings.EqualFold(cs.ServerName, pinnedHost) &&
+			(len(cs.PeerCertificates) == 0 || cs.PeerCertificates[0].VerifyHostname(pinnedHost) != nil) {
+			return nil
+		}
+		for _, chain := range cs.VerifiedChains {
+			for _, cert := range chain {
+				if pins[spkiHash(cert)] {
+					return nil
+				}
+			}
+		}
+		return &pinningError{host: pinnedHost}
+	}
+	return config
+}
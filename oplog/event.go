@@ -0,0 +1,173 @@
+package oplog
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+/*
+Operational events are logged as structured JSON entries by Event. The fields of each event type are classified in
+the event schema registry (see RegisterEvent) so that personal data can be scrubbed according to a deployment policy:
+
+	Public	- logged as is
+	PII	- personal data: kept, hashed or dropped according to the PIIAction set by SetPIIAction
+	Secret	- never logged to the shared log (e.g. tokens and keys)
+
+Fields that are not declared in the registry are treated as PII. The scrubbed entry is written to the shared log (and
+so to any Forwarder), so that operational analytics can run on scrubbed data. The unscrubbed entry is also written to
+the restricted writer set by SetRestrictedWriter, if any, for a sink that is permitted to keep full fidelity.
+*/
+
+//A Class is the data protection classification of an event field
+type Class int
+
+//The field Classes
+const (
+	Public Class = iota
+	PII
+	Secret
+)
+
+//A PIIAction determines how PII fields are scrubbed
+type PIIAction int
+
+//The PIIActions
+const (
+	//HashPII replaces a PII value by its keyed hash, so that analytics can still correlate values (the default)
+	HashPII PIIAction = iota
+
+	//DropPII removes PII fields
+	DropPII
+
+	//KeepPII logs PII fields as is; it is only appropriate where the shared log is itself restricted
+	KeepPII
+)
+
+//registry holds the event schema registry and scrubbing policy
+var registry = struct {
+	m          sync.RWMutex
+	events     map[string]map[string]Class
+	action     PIIAction
+	hashKey    []byte
+	restricted io.Writer
+}{events: make(map[string]map[string]Class)}
+
+/*
+RegisterEvent declares the classification of the fields of an event type, replacing any earlier declaration.
+*/
+func RegisterEvent(name string, fields map[string]Class) {
+	var declared = make(map[string]Class, len(fields))
+
+	for field, class := range fields {
+		declared[field] = class
+	}
+	registry.m.Lock()
+	defer registry.m.Unlock()
+	registry.events[name] = declared
+}
+
+/*
+SetPIIAction sets how PII fields are scrubbed. Hashing uses HMAC-SHA256 with the key so that the hashes of low
+entropy values such as e-mail addresses cannot be reversed by a dictionary; the key should be kept with the restricted
+sink. A nil key hashes with an empty key.
+*/
+func SetPIIAction(action PIIAction, hashKey []byte) {
+	registry.m.Lock()
+	defer registry.m.Unlock()
+	registry.action = action
+	registry.hashKey = hashKey
+}
+
+/*
+SetRestrictedWriter sets the writer (e.g. a Forwarder to a restricted Sink) that receives the unscrubbed event entries.
+A nil writer disables it.
+*/
+func SetRestrictedWriter(w io.Writer) {
+	registry.m.Lock()
+	defer registry.m.Unlock()
+	registry.restricted = w
+}
+
+//eventEntry is the JSON form of an event entry
+type eventEntry struct {
+	Event  string                 `json:"event"`
+	Time   time.Time              `json:"time"`
+	Fields map[string]interface{} `json:"fields"`
+}
+
+/*
+Event logs an operational event with its fields. The fields are scrubbed according to the event's declared
+classification before the entry is written to the shared log; the unscrubbed entry is written to the restricted
+writer, if any.
+*/
+func (l *LoggerT) Event(name string, fields map[string]interface{}) {
+	var (
+		entry      = eventEntry{Event: name, Time: time.Now().UTC(), Fields: fields}
+		restricted io.Writer
+		full       []byte
+		scrubbed   []byte
+		err        error
+	)
+
+	registry.m.RLock()
+	restricted = registry.restricted
+	entry.Fields = scrub(registry.events[name], fields, registry.action, registry.hashKey)
+	registry.m.RUnlock()
+
+	scrubbed, err = json.Marshal(entry)
+	if err != nil {
+		l.Printf("Event %v could not be logged: %v\n", name, err)
+		return
+	}
+	l.Println(string(scrubbed))
+
+	if restricted != nil {
+		entry.Fields = fields
+		full, err = json.Marshal(entry)
+		if err == nil {
+			restricted.Write(append(full, '\n'))
+		}
+	}
+}
+
+//scrub returns a copy of the fields scrubbed according to their classes
+func scrub(classes map[string]Class, fields map[string]interface{}, action PIIAction, hashKey []byte) map[string]interface{} {
+	var (
+		scrubbed = make(map[string]interface{}, len(fields))
+		class    Class
+		ok       bool
+	)
+
+	for field, value := range fields {
+		class, ok = classes[field]
+		if !ok {
+			class = PII
+		}
+		switch {
+		case class == Public:
+			scrubbed[field] = value
+		case class == PII && action == KeepPII:
+			scrubbed[field] = value
+		case class == PII && action == HashPII:
+			scrubbed[field] = hashValue(value, hashKey)
+		}
+	}
+	return scrubbed
+}
+
+//hashValue returns the hex HMAC-SHA256 of a value's JSON encoding
+func hashValue(value interface{}, hashKey []byte) string {
+	var (
+		mac     = hmac.New(sha256.New, hashKey)
+		encoded []byte
+	)
+
+	encoded, _ = json.Marshal(value)
+	mac.Write(encoded)
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,103 @@
+package oplog
+
+import (
+	"bytes"
+	"encoding/json"
+	golog "log"
+	"strings"
+	"testing"
+)
+
+//capture redirects the shared logger to a buffer until the test ends
+func capture(test *testing.T) *bytes.Buffer {
+	var (
+		buf      bytes.Buffer
+		replaced = logger.logger
+	)
+
+	logger.logger = golog.New(&buf, "", 0)
+	test.Cleanup(func() { logger.logger = replaced })
+	return &buf
+}
+
+//decode decodes the event entries of log output
+func decode(test *testing.T, output string) []eventEntry {
+	var entries []eventEntry
+
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		var entry eventEntry
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			test.Fatalf("Bad event entry %q: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestScrub(test *testing.T) {
+	var (
+		key     = []byte("k")
+		classes = map[string]Class{"public": Public, "email": PII, "token": Secret}
+		fields  = map[string]interface{}{"public": "p", "email": "a@ex.org", "token": "t", "undeclared": 7}
+		cases   = []struct {
+			action   PIIAction
+			expected map[string]interface{}
+		}{
+			{HashPII, map[string]interface{}{"public": "p", "email": hashValue("a@ex.org", key), "undeclared": hashValue(7, key)}},
+			{DropPII, map[string]interface{}{"public": "p"}},
+			{KeepPII, map[string]interface{}{"public": "p", "email": "a@ex.org", "undeclared": 7}},
+		}
+	)
+
+	for _, c := range cases {
+		scrubbed := scrub(classes, fields, c.action, key)
+		if len(scrubbed) != len(c.expected) {
+			test.Errorf("scrub %v: %v", c.action, scrubbed)
+		}
+		for field, value := range c.expected {
+			if scrubbed[field] != value {
+				test.Errorf("scrub %v: %v = %v", c.action, field, scrubbed[field])
+			}
+		}
+	}
+	if len(fields) != 4 {
+		test.Errorf("scrub modified the fields: %v", fields)
+	}
+
+	//The hash is keyed, so it cannot be reversed by a dictionary without the key
+	if hashValue("a@ex.org", key) == hashValue("a@ex.org", nil) || !strings.HasPrefix(hashValue("a@ex.org", nil), "hmac-sha256:") {
+		test.Errorf("hashValue: %v %v", hashValue("a@ex.org", key), hashValue("a@ex.org", nil))
+	}
+}
+
+func TestEvent(test *testing.T) {
+	var (
+		output     = capture(test)
+		restricted bytes.Buffer
+		entries    []eventEntry
+	)
+	defer SetPIIAction(HashPII, nil)
+	defer SetRestrictedWriter(nil)
+
+	RegisterEvent("test.login", map[string]Class{"user": PII, "password": Secret, "outcome": Public})
+	SetPIIAction(DropPII, nil)
+	SetRestrictedWriter(&restricted)
+	Logger().Event("test.login", map[string]interface{}{"user": "ann", "password": "pw", "outcome": "ok"})
+
+	//The shared log receives the scrubbed entry and the restricted writer the full one
+	entries = decode(test, output.String())
+	if len(entries) != 1 || entries[0].Event != "test.login" || entries[0].Time.IsZero() || len(entries[0].Fields) != 1 || entries[0].Fields["outcome"] != "ok" {
+		test.Errorf("Scrubbed entry: %v", output)
+	}
+	entries = decode(test, restricted.String())
+	if len(entries) != 1 || entries[0].Fields["user"] != "ann" || entries[0].Fields["password"] != "pw" {
+		test.Errorf("Restricted entry: %v", restricted.String())
+	}
+
+	//An event that cannot be encoded is reported instead
+	output.Reset()
+	Logger().Event("test.login", map[string]interface{}{"outcome": make(chan int)})
+	if !strings.HasPrefix(output.String(), "Event test.login could not be logged") {
+		test.Errorf("Event that cannot be encoded: %v", output)
+	}
+}
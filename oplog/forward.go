@@ -0,0 +1,317 @@
+package oplog
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+/*
+A Sink is a remote collector of operational events. Send is passed one log entry; it returns an error if the
+entry was not accepted, in which case it will be retried later.
+*/
+type Sink interface {
+	Send(entry []byte) error
+}
+
+/*
+A Forwarder is an io.Writer that forwards log entries to a Sink without blocking the writer.
+
+Entries are passed to a gofunction via a bounded queue. If the queue is full, the queued entries and then the entry
+are spilled to a local spool file; if the Sink fails, the failed entry is spooled ahead of those spilled while it was
+being sent. While the spool holds entries, all new entries are appended to it, so entries reach the Sink in the order
+they were written. The spool is replayed to the Sink every retry interval until the Sink recovers; the Sink is never
+called while the Forwarder is locked, so a slow or failed Sink does not block Write.
+
+An entry is only lost if it can be neither queued nor written to the spool, or if the spool is corrupt (e.g. a record
+torn by a crash); such losses are counted by Dropped and reported on stderr.
+*/
+type Forwarder struct {
+	sink      Sink
+	capacity  int
+	spoolName string
+	retry     time.Duration
+	ready     chan struct{}
+	done      chan struct{}
+	stopped   chan struct{}
+	dropped   uint64
+
+	//m guards the queue and spool; the queue is empty while the spool holds entries
+	m        sync.Mutex
+	queue    [][]byte
+	spool    *os.File
+	spooling bool
+}
+
+/*
+NewForwarder creates a Forwarder with a queue of the given capacity and a spool file of the given name and starts its
+forwarding gofunction. Entries left in the spool by a previous execution are replayed; a corrupt remainder of the spool
+is discarded and counted by Dropped.
+*/
+func NewForwarder(sink Sink, capacity int, spoolName string, retry time.Duration) (*Forwarder, error) {
+	var (
+		f   Forwarder
+		err error
+	)
+
+	f.sink = sink
+	f.capacity = capacity
+	f.spoolName = spoolName
+	f.retry = retry
+	f.ready = make(chan struct{}, 1)
+	f.done = make(chan struct{})
+	f.stopped = make(chan struct{})
+	f.spool, err = os.OpenFile(spoolName, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	//A record torn by a crash is removed so that the entries appended after it can be replayed
+	f.rewriteSpool(f.parseSpool(f.spooled(0)), nil)
+	go f.forward()
+	return &f, nil
+}
+
+/*
+Write queues a copy of an entry for forwarding; if the queue is full or the spool holds entries, the entry is spilled to
+the spool. It never blocks on the Sink.
+*/
+func (f *Forwarder) Write(p []byte) (int, error) {
+	var entry = make([]byte, len(p))
+
+	copy(entry, p)
+	f.m.Lock()
+	switch {
+	case f.spooling:
+		f.appendSpooled(entry)
+	case len(f.queue) >= f.capacity:
+		//The queued entries were written first, so they are spooled ahead of the entry
+		for _, queued := range f.queue {
+			f.appendSpooled(queued)
+		}
+		f.queue = nil
+		f.appendSpooled(entry)
+	default:
+		f.queue = append(f.queue, entry)
+	}
+	f.m.Unlock()
+
+	select {
+	case f.ready <- struct{}{}:
+	default:
+	}
+	return len(p), nil
+}
+
+/*
+Dropped returns the number of entries that were lost because they could be neither queued nor spooled, or because the
+spool was corrupt.
+*/
+func (f *Forwarder) Dropped() uint64 {
+	return atomic.LoadUint64(&f.dropped)
+}
+
+/*
+Close stops the forwarding gofunction after the queued entries have been forwarded or spooled, and closes the spool.
+Spooled entries are replayed by the next Forwarder created with the same spool name.
+*/
+func (f *Forwarder) Close() error {
+	close(f.done)
+	<-f.stopped
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.spool.Close()
+}
+
+//forward sends queued entries to the sink and periodically replays the spool. Since it is the only gofunction that
+//sends entries or rewrites the spool, entries are sent in order.
+func (f *Forwarder) forward() {
+	var ticker = time.NewTicker(f.retry)
+
+	defer close(f.stopped)
+	defer ticker.Stop()
+	f.replay()
+	for {
+		f.sendQueued()
+		select {
+		case <-f.ready:
+		case <-ticker.C:
+			f.replay()
+		case <-f.done:
+			f.sendQueued()
+			return
+		}
+	}
+}
+
+//sendQueued sends the queued entries to the sink until the queue is empty or the sink fails
+func (f *Forwarder) sendQueued() {
+	var entry []byte
+
+	for {
+		f.m.Lock()
+		if len(f.queue) == 0 {
+			f.m.Unlock()
+			return
+		}
+		entry = f.queue[0]
+		f.queue = f.queue[1:]
+		f.m.Unlock()
+
+		if f.sink.Send(entry) != nil {
+			//The entries spilled while the entry was sent, and then those still queued, were written after it
+			f.m.Lock()
+			f.rewriteSpool([][]byte{entry}, f.spooled(0))
+			for _, queued := range f.queue {
+				f.appendSpooled(queued)
+			}
+			f.queue = nil
+			f.m.Unlock()
+			return
+		}
+	}
+}
+
+/*
+replay sends the spooled entries to the sink in order. The spool is read while the Forwarder is locked, but the
+entries are sent while it is unlocked so that Write can spill to the spool meanwhile. If the sink fails, the unsent
+entries and those spilled meanwhile are rewritten to the spool and replay is retried at the next tick.
+*/
+func (f *Forwarder) replay() {
+	var (
+		data    []byte
+		entries [][]byte
+		sent    int
+	)
+
+	f.m.Lock()
+	if !f.spooling {
+		f.m.Unlock()
+		return
+	}
+	data = f.spooled(0)
+	entries = f.parseSpool(data)
+	f.m.Unlock()
+
+	for sent < len(entries) && f.sink.Send(entries[sent]) == nil {
+		sent++
+	}
+
+	f.m.Lock()
+	defer f.m.Unlock()
+	f.rewriteSpool(entries[sent:], f.spooled(int64(len(data))))
+}
+
+//spooled returns the content of the spool from an offset; f.m must be held
+func (f *Forwarder) spooled(offset int64) []byte {
+	var (
+		fi   os.FileInfo
+		data []byte
+		err  error
+	)
+
+	fi, err = f.spool.Stat()
+	if err == nil && fi.Size() > offset {
+		data = make([]byte, fi.Size()-offset)
+		_, err = f.spool.ReadAt(data, offset)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "oplog: reading spool %v failed with Error: %v\n", f.spoolName, err)
+		return nil
+	}
+	return data
+}
+
+//parseSpool parses the length prefixed entries of spool content. A corrupt remainder is discarded and counted as a
+//dropped entry, since it cannot be parsed into entries; f.m must be held.
+func (f *Forwarder) parseSpool(data []byte) [][]byte {
+	var (
+		entries [][]byte
+		i       int
+		length  int
+		err     error
+	)
+
+	for len(data) > 0 {
+		i = bytes.IndexByte(data, '\n')
+		if i < 0 {
+			break
+		}
+		length, err = strconv.Atoi(string(data[:i]))
+		if err != nil || length < 0 || length > len(data)-i-1 {
+			break
+		}
+		entries = append(entries, data[i+1:i+1+length])
+		data = data[i+1+length:]
+	}
+	if len(data) > 0 {
+		atomic.AddUint64(&f.dropped, 1)
+		fmt.Fprintf(os.Stderr, "oplog: discarded %v bytes of corrupt spool %v\n", len(data), f.spoolName)
+	}
+	return entries
+}
+
+//rewriteSpool replaces the spool content with the entries followed by the tail, which is already spooled content;
+//f.m must be held
+func (f *Forwarder) rewriteSpool(entries [][]byte, tail []byte) {
+	var err error
+
+	err = f.spool.Truncate(0)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "oplog: rewriting spool %v failed with Error: %v\n", f.spoolName, err)
+		return
+	}
+	f.spooling = false
+	for _, entry := range entries {
+		f.appendSpooled(entry)
+	}
+	if len(tail) == 0 {
+		return
+	}
+	_, err = f.spool.Write(tail)
+	if err != nil {
+		atomic.AddUint64(&f.dropped, uint64(len(f.parseSpool(tail))))
+		fmt.Fprintf(os.Stderr, "oplog: dropped entries because spooling to %v failed with Error: %v\n", f.spoolName, err)
+		return
+	}
+	f.spooling = true
+}
+
+//appendSpooled appends a length prefixed entry to the spool; f.m must be held
+func (f *Forwarder) appendSpooled(entry []byte) {
+	var err error
+
+	_, err = fmt.Fprintf(f.spool, "%d\n", len(entry))
+	if err == nil {
+		_, err = f.spool.Write(entry)
+	}
+	if err != nil {
+		atomic.AddUint64(&f.dropped, 1)
+		fmt.Fprintf(os.Stderr, "oplog: dropped entry because spooling to %v failed with Error: %v\n", f.spoolName, err)
+		return
+	}
+	f.spooling = true
+}
+
+/*
+Forward configures the shared logger to also forward its entries to a Sink via a Forwarder. It should be called after
+Config. The returned Forwarder should be closed when the executable shuts down.
+*/
+func Forward(sink Sink, capacity int, spoolName string, retry time.Duration) (*Forwarder, error) {
+	var (
+		forwarder *Forwarder
+		err       error
+	)
+
+	forwarder, err = NewForwarder(sink, capacity, spoolName, retry)
+	if err != nil {
+		return nil, err
+	}
+	logger.Logger().SetOutput(io.MultiWriter(logger.Logger().Writer(), forwarder))
+	return forwarder, nil
+}
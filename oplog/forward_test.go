@@ -0,0 +1,174 @@
+package oplog
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+//sink is a Sink that records the entries it accepts. It fails while fail is set, and blocks each Send until its gate
+//is closed.
+type sink struct {
+	m       sync.Mutex
+	fail    bool
+	gate    chan struct{}
+	sending int
+	entries []string
+}
+
+func (s *sink) Send(entry []byte) error {
+	s.m.Lock()
+	s.sending++
+	gate := s.gate
+	s.m.Unlock()
+	if gate != nil {
+		<-gate
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.fail {
+		return errors.New("sink down")
+	}
+	s.entries = append(s.entries, string(entry))
+	return nil
+}
+
+func (s *sink) set(fail bool, gate chan struct{}) {
+	s.m.Lock()
+	s.fail, s.gate, s.sending = fail, gate, 0
+	s.m.Unlock()
+}
+
+func (s *sink) state() (int, string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	return s.sending, strings.Join(s.entries, " ")
+}
+
+//waitFor polls until cond is true
+func waitFor(test *testing.T, what string, cond func() bool) {
+	var deadline = time.Now().Add(5 * time.Second)
+
+	for !cond() {
+		if time.Now().After(deadline) {
+			test.Fatalf("Timed out waiting for %v", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+//write writes entries to a Forwarder, failing the test if Write blocks
+func write(test *testing.T, f *Forwarder, entries ...string) {
+	var written = make(chan struct{})
+
+	go func() {
+		for _, entry := range entries {
+			f.Write([]byte(entry))
+		}
+		close(written)
+	}()
+	select {
+	case <-written:
+	case <-time.After(5 * time.Second):
+		test.Fatalf("Write blocked")
+	}
+}
+
+func newForwarder(test *testing.T, s *sink, spoolName string) *Forwarder {
+	var f, err = NewForwarder(s, 2, spoolName, time.Millisecond)
+
+	if err != nil {
+		test.Fatalf("NewForwarder: %v", err)
+	}
+	return f
+}
+
+func TestForwarderSpill(test *testing.T) {
+	var (
+		s    = &sink{}
+		gate = make(chan struct{})
+		f    = newForwarder(test, s, filepath.Join(test.TempDir(), "spool"))
+	)
+	defer f.Close()
+
+	//While the Sink blocks, a full queue is spilled to the spool ahead of the entry that overflowed it
+	s.set(false, gate)
+	write(test, f, "e0")
+	waitFor(test, "Send", func() bool { sending, _ := s.state(); return sending == 1 })
+	write(test, f, "e1", "e2", "e3", "e4")
+	close(gate)
+	waitFor(test, "forwarding", func() bool { _, entries := s.state(); return strings.Count(entries, "e") == 5 })
+	if _, entries := s.state(); entries != "e0 e1 e2 e3 e4" || f.Dropped() != 0 {
+		test.Errorf("Forwarded: %v %v", entries, f.Dropped())
+	}
+}
+
+func TestForwarderReplay(test *testing.T) {
+	var (
+		s    = &sink{}
+		gate = make(chan struct{})
+		f    = newForwarder(test, s, filepath.Join(test.TempDir(), "spool"))
+	)
+	defer f.Close()
+
+	//A failed entry is spooled ahead of the later entries
+	s.set(true, nil)
+	write(test, f, "e0", "e1", "e2", "e3", "e4")
+	waitFor(test, "replay", func() bool { sending, _ := s.state(); return sending > 5 })
+
+	//The Sink is not called while the Forwarder is locked, so Write does not block while a replay does
+	s.set(false, gate)
+	waitFor(test, "replay", func() bool { sending, _ := s.state(); return sending == 1 })
+	write(test, f, "e5")
+	close(gate)
+	waitFor(test, "replay", func() bool { _, entries := s.state(); return strings.Count(entries, "e") == 6 })
+	if _, entries := s.state(); entries != "e0 e1 e2 e3 e4 e5" || f.Dropped() != 0 {
+		test.Errorf("Forwarded: %v %v", entries, f.Dropped())
+	}
+
+	//Once the spool is replayed, entries are queued again
+	write(test, f, "e6")
+	waitFor(test, "forwarding", func() bool { _, entries := s.state(); return strings.HasSuffix(entries, "e6") })
+}
+
+func TestForwarderRecovery(test *testing.T) {
+	var (
+		s         = &sink{}
+		spoolName = filepath.Join(test.TempDir(), "spool")
+		f         = newForwarder(test, s, spoolName)
+		spool     *os.File
+		content   []byte
+	)
+
+	//Entries spooled when the Forwarder is closed are replayed by the next one
+	s.set(true, nil)
+	write(test, f, "e0", "e1", "e2")
+	waitFor(test, "spooling", func() bool { sending, _ := s.state(); return sending > 0 })
+	if err := f.Close(); err != nil {
+		test.Fatalf("Close: %v", err)
+	}
+
+	//A record torn by a crash cannot be replayed, so it is counted as dropped
+	spool, _ = os.OpenFile(spoolName, os.O_WRONLY|os.O_APPEND, 0600)
+	fmt.Fprint(spool, "9\ne3")
+	spool.Close()
+
+	s.set(false, nil)
+	f = newForwarder(test, s, spoolName)
+	defer f.Close()
+	write(test, f, "e4")
+	waitFor(test, "replay", func() bool { _, entries := s.state(); return strings.Count(entries, "e") == 4 })
+	if _, entries := s.state(); entries != "e0 e1 e2 e4" || f.Dropped() != 1 {
+		test.Errorf("Forwarded: %v %v", entries, f.Dropped())
+	}
+	if content, _ = ioutil.ReadFile(spoolName); len(content) != 0 {
+		test.Errorf("Spool after replay: %q", content)
+	}
+}
@@ -0,0 +1,173 @@
+package oplog
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"time"
+)
+
+/*
+Services log standardized lifecycle events so that deployment tooling can track rollouts from the operational log
+alone:
+
+	service.starting	- logged by ServiceStarting when the service has parsed its configuration
+	service.ready		- logged by ServiceReady when the service accepts requests
+	service.stopping	- logged by ServiceStopping when the service begins to shut down
+
+Each carries the ServiceInfo passed to ServiceStarting: the service's name, its build info and the hash of its
+configuration. All their fields are Public.
+*/
+
+//The lifecycle event names
+const (
+	EventServiceStarting = "service.starting"
+	EventServiceReady    = "service.ready"
+	EventServiceStopping = "service.stopping"
+)
+
+//A ServiceInfo identifies a running service's build and configuration
+type ServiceInfo struct {
+	Name       string
+	Version    string
+	Revision   string
+	GoVersion  string
+	ConfigHash string
+}
+
+//lifecycle holds the ServiceInfo and start time of the service
+var lifecycle struct {
+	m       sync.Mutex
+	info    ServiceInfo
+	started time.Time
+}
+
+//secretFlag matches the names of flags whose values are secrets
+var secretFlag = regexp.MustCompile(`(?i)secret|password|passwd|token|key|credential|otp`)
+
+func init() {
+	RegisterEvent(EventServiceStarting, map[string]Class{
+		"service": Public, "version": Public, "revision": Public, "goVersion": Public, "configHash": Public,
+	})
+	RegisterEvent(EventServiceReady, map[string]Class{
+		"service": Public, "version": Public, "revision": Public, "goVersion": Public, "configHash": Public,
+		"startupSeconds": Public,
+	})
+	RegisterEvent(EventServiceStopping, map[string]Class{
+		"service": Public, "version": Public, "revision": Public, "goVersion": Public, "configHash": Public,
+		"reason": Public, "uptimeSeconds": Public,
+	})
+}
+
+/*
+NewServiceInfo returns the ServiceInfo of the named service from its build info (the main module's version and VCS
+revision, if it was built with them) and the ConfigHash of its parsed command line flags.
+*/
+func NewServiceInfo(name string) ServiceInfo {
+	var (
+		info   = ServiceInfo{Name: name, GoVersion: runtime.Version(), ConfigHash: ConfigHash()}
+		build  *debug.BuildInfo
+		hasVCS bool
+	)
+
+	build, hasVCS = debug.ReadBuildInfo()
+	if !hasVCS {
+		return info
+	}
+	info.Version = build.Main.Version
+	for _, setting := range build.Settings {
+		if setting.Key == "vcs.revision" {
+			info.Revision = setting.Value
+		}
+	}
+	return info
+}
+
+/*
+ConfigHash returns a short hash of the names and values of the command line flags, so that a configuration change can
+be detected without logging the configuration. The values of flags whose names indicate secrets are excluded.
+*/
+func ConfigHash() string {
+	var (
+		config []string
+		hash   = sha256.New()
+		sum    []byte
+	)
+
+	flag.VisitAll(func(f *flag.Flag) {
+		if IsSecretFlag(f.Name) {
+			config = append(config, f.Name)
+			return
+		}
+		config = append(config, f.Name+"="+f.Value.String())
+	})
+	sort.Strings(config)
+	for _, setting := range config {
+		hash.Write([]byte(setting))
+		hash.Write([]byte{0})
+	}
+	sum = hash.Sum(nil)
+	return hex.EncodeToString(sum[:8])
+}
+
+/*
+IsSecretFlag is true if a flag's name indicates that its value is a secret (e.g. -clientsecret or -otpkey). Such values
+are excluded from the ConfigHash and should be redacted wherever the configuration is shown.
+*/
+func IsSecretFlag(name string) bool {
+	return secretFlag.MatchString(name)
+}
+
+//fields returns the event fields of a ServiceInfo
+func (info ServiceInfo) fields() map[string]interface{} {
+	return map[string]interface{}{
+		"service": info.Name, "version": info.Version, "revision": info.Revision, "goVersion": info.GoVersion,
+		"configHash": info.ConfigHash,
+	}
+}
+
+/*
+ServiceStarting logs the service.starting event and records the ServiceInfo for the service's later lifecycle events.
+*/
+func ServiceStarting(info ServiceInfo) {
+	lifecycle.m.Lock()
+	lifecycle.info = info
+	lifecycle.started = time.Now()
+	lifecycle.m.Unlock()
+
+	logger.Event(EventServiceStarting, info.fields())
+}
+
+/*
+ServiceReady logs the service.ready event with the time since ServiceStarting.
+*/
+func ServiceReady() {
+	var fields map[string]interface{}
+
+	lifecycle.m.Lock()
+	fields = lifecycle.info.fields()
+	fields["startupSeconds"] = time.Since(lifecycle.started).Seconds()
+	lifecycle.m.Unlock()
+
+	logger.Event(EventServiceReady, fields)
+}
+
+/*
+ServiceStopping logs the service.stopping event with the reason for stopping (e.g. "SIGTERM") and the uptime.
+*/
+func ServiceStopping(reason string) {
+	var fields map[string]interface{}
+
+	lifecycle.m.Lock()
+	fields = lifecycle.info.fields()
+	fields["reason"] = reason
+	fields["uptimeSeconds"] = time.Since(lifecycle.started).Seconds()
+	lifecycle.m.Unlock()
+
+	logger.Event(EventServiceStopping, fields)
+}
@@ -0,0 +1,77 @@
+package oplog
+
+import (
+	"flag"
+	"runtime"
+	"testing"
+)
+
+func TestConfigHash(test *testing.T) {
+	var (
+		replaced = flag.CommandLine
+		hash     string
+	)
+	defer func() { flag.CommandLine = replaced }()
+
+	flag.CommandLine = flag.NewFlagSet("test", flag.ContinueOnError)
+	hash = ConfigHash()
+	flag.String("lifecycle-test-otp", "", "")
+	flag.String("lifecycle-test-secret", "", "")
+	flag.String("lifecycle-test-addr", "", "")
+	if !IsSecretFlag("lifecycle-test-otp") || !IsSecretFlag("ClientSecret") || IsSecretFlag("lifecycle-test-addr") {
+		test.Errorf("IsSecretFlag")
+	}
+
+	//Defining a flag changes the hash
+	if ConfigHash() == hash || len(hash) != 16 {
+		test.Errorf("ConfigHash of new flags: %v", hash)
+	}
+	hash = ConfigHash()
+	if ConfigHash() != hash {
+		test.Errorf("ConfigHash is not stable")
+	}
+
+	//The values of secret flags are excluded, but not those of other flags
+	flag.Set("lifecycle-test-otp", "123456")
+	flag.Set("lifecycle-test-secret", "s")
+	if ConfigHash() != hash {
+		test.Errorf("ConfigHash includes a secret value")
+	}
+	flag.Set("lifecycle-test-addr", ":8080")
+	if ConfigHash() == hash {
+		test.Errorf("ConfigHash excludes a value")
+	}
+}
+
+func TestLifecycle(test *testing.T) {
+	var (
+		output  = capture(test)
+		info    = NewServiceInfo("svc")
+		entries []eventEntry
+	)
+
+	if info.Name != "svc" || info.GoVersion != runtime.Version() || info.ConfigHash != ConfigHash() {
+		test.Errorf("NewServiceInfo: %+v", info)
+	}
+	ServiceStarting(info)
+	ServiceReady()
+	ServiceStopping("SIGTERM")
+
+	//The lifecycle events carry the ServiceInfo, and none of their fields are scrubbed
+	entries = decode(test, output.String())
+	if len(entries) != 3 || entries[0].Event != EventServiceStarting || entries[1].Event != EventServiceReady || entries[2].Event != EventServiceStopping {
+		test.Fatalf("Lifecycle events: %v", output)
+	}
+	for _, entry := range entries {
+		if entry.Fields["service"] != "svc" || entry.Fields["goVersion"] != info.GoVersion || entry.Fields["configHash"] != info.ConfigHash {
+			test.Errorf("%v fields: %v", entry.Event, entry.Fields)
+		}
+	}
+	startup, ok := entries[1].Fields["startupSeconds"].(float64)
+	if !ok || startup < 0 {
+		test.Errorf("startupSeconds: %v", entries[1].Fields)
+	}
+	if uptime, ok := entries[2].Fields["uptimeSeconds"].(float64); entries[2].Fields["reason"] != "SIGTERM" || !ok || uptime < startup {
+		test.Errorf("service.stopping fields: %v", entries[2].Fields)
+	}
+}
@@ -9,6 +9,14 @@ The executable's init will parse these command line flags and then configure thi
 
 If Config is not called, the default is to log to stderr with no prefix and no flag.
 
+Structured operational events are logged with Event, which scrubs their personal data according to the field
+classifications declared with RegisterEvent.
+
+ServiceStarting, ServiceReady and ServiceStopping log standardized service lifecycle events.
+
+A Reader (see NewReader and OpenFiles) replays the events of operational log files, including rotated and gzip
+compressed ones, as Records filtered by time range and event name.
+
 See the golang log package for a definition of the oplogflg bits that are ore'ed to form a flag value.
 
 Due to initialization order issues, this logger cannot be used in init() functions.
@@ -0,0 +1,212 @@
+package oplog
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+/*
+A Reader replays the events of operational log output (e.g. the files written by Config, including rotated and
+gzip compressed ones) as typed Records, so that tooling and tests can consume operational logs programmatically.
+
+Each event entry is a line whose JSON object follows the log prefix and flag header, if any; lines that are not event
+entries (e.g. those logged with Printf) are skipped. Compressed input is detected by its gzip header rather than by its
+name.
+*/
+type Reader struct {
+	filter Filter
+	names  []string
+	file   *os.File
+	reader *bufio.Reader
+	zr     *gzip.Reader
+}
+
+//A Record is an operational event read by a Reader
+type Record struct {
+	Event  string
+	Time   time.Time
+	Fields map[string]interface{}
+}
+
+/*
+A Filter selects the Records of a Reader. The zero Filter selects all of them.
+
+From and To bound the event times: From is inclusive, To is exclusive and a zero time is unbounded. If Events is not
+empty, only the events it names are selected.
+*/
+type Filter struct {
+	From   time.Time
+	To     time.Time
+	Events []string
+}
+
+//match is true if the Filter selects a Record
+func (f Filter) match(rec Record) bool {
+	if !f.From.IsZero() && rec.Time.Before(f.From) {
+		return false
+	}
+	if !f.To.IsZero() && !rec.Time.Before(f.To) {
+		return false
+	}
+	if len(f.Events) == 0 {
+		return true
+	}
+	for _, event := range f.Events {
+		if event == rec.Event {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+NewReader creates a Reader of the operational log output read from r. If the output is gzip compressed, it is
+decompressed.
+*/
+func NewReader(r io.Reader, filter Filter) (*Reader, error) {
+	var reader = &Reader{filter: filter}
+
+	err := reader.setInput(r)
+	if err != nil {
+		return nil, err
+	}
+	return reader, nil
+}
+
+/*
+OpenFiles creates a Reader of operational log files, which are read in the order named; so rotated files should be
+named oldest first. Each file is opened when the previous one has been read. The Reader should be closed.
+*/
+func OpenFiles(filter Filter, names ...string) (*Reader, error) {
+	var reader = &Reader{filter: filter, names: names}
+
+	err := reader.nextFile()
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return reader, nil
+}
+
+//setInput reads r next, decompressing it if it starts with a gzip header
+func (r *Reader) setInput(input io.Reader) error {
+	var (
+		buffered = bufio.NewReader(input)
+		magic    []byte
+		err      error
+	)
+
+	magic, _ = buffered.Peek(2)
+	if !bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		r.reader = buffered
+		return nil
+	}
+	r.zr, err = gzip.NewReader(buffered)
+	if err != nil {
+		return err
+	}
+	r.reader = bufio.NewReader(r.zr)
+	return nil
+}
+
+//nextFile opens the next named file; it returns io.EOF if there are no more files
+func (r *Reader) nextFile() error {
+	var err error
+
+	r.closeInput()
+	if len(r.names) == 0 {
+		r.reader = bufio.NewReader(bytes.NewReader(nil))
+		return io.EOF
+	}
+	r.file, err = os.Open(r.names[0])
+	if err != nil {
+		return err
+	}
+	err = r.setInput(r.file)
+	if err != nil {
+		return fmt.Errorf("Bad Operational Log File: %v: %v", r.names[0], err)
+	}
+	r.names = r.names[1:]
+	return nil
+}
+
+/*
+Next returns the next Record selected by the Reader's Filter. It returns io.EOF when the input is exhausted.
+*/
+func (r *Reader) Next() (Record, error) {
+	var (
+		line []byte
+		rec  Record
+		ok   bool
+		err  error
+	)
+
+	for {
+		line, err = r.reader.ReadBytes('\n')
+		if len(line) > 0 {
+			rec, ok = parseRecord(line)
+			if ok && r.filter.match(rec) {
+				return rec, nil
+			}
+		}
+		if err == io.EOF && r.file != nil {
+			err = r.nextFile()
+		}
+		if err != nil {
+			return Record{}, err
+		}
+	}
+}
+
+/*
+Close closes the Reader's open file, if any.
+*/
+func (r *Reader) Close() error {
+	r.names = nil
+	return r.closeInput()
+}
+
+//closeInput closes the current decompressor and file, if any
+func (r *Reader) closeInput() error {
+	var err error
+
+	if r.zr != nil {
+		r.zr.Close()
+		r.zr = nil
+	}
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	return err
+}
+
+//parseRecord parses an event entry line; it is false if the line is not one.
+//Since the prefix and flag header precede the entry, each '{' is tried as its start.
+func parseRecord(line []byte) (Record, bool) {
+	var (
+		entry eventEntry
+		start int
+		err   error
+	)
+
+	line = bytes.TrimSpace(line)
+	for {
+		i := bytes.IndexByte(line[start:], '{')
+		if i < 0 {
+			return Record{}, false
+		}
+		start += i
+		entry = eventEntry{}
+		err = json.Unmarshal(line[start:], &entry)
+		if err == nil && entry.Event != "" {
+			return Record{Event: entry.Event, Time: entry.Time, Fields: entry.Fields}, true
+		}
+		start++
+	}
+}
@@ -0,0 +1,109 @@
+package oplog
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var readerStart = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+//entryLine returns an event entry line with a log prefix and flag header, as written by the shared logger
+func entryLine(event string, minutes int) string {
+	var encoded, _ = json.Marshal(eventEntry{Event: event, Time: readerStart.Add(time.Duration(minutes) * time.Minute), Fields: map[string]interface{}{"n": minutes}})
+
+	return "svc: 2020/01/02 03:04:05 " + string(encoded) + "\n"
+}
+
+//readAll returns the event names and minutes of the Records of a Reader
+func readAll(test *testing.T, r *Reader) string {
+	var read []string
+
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return strings.Join(read, " ")
+		}
+		if err != nil {
+			test.Fatalf("Next: %v", err)
+		}
+		read = append(read, rec.Event+"@"+rec.Time.Sub(readerStart).String())
+	}
+}
+
+func TestReader(test *testing.T) {
+	var (
+		log = entryLine("a", 0) + "svc: 2020/01/02 03:04:05 not an event {\"x\": 1}\n" + entryLine("b", 1) +
+			"\n" + entryLine("a", 2) + strings.TrimSuffix(entryLine("b", 3), "\n")
+		cases = []struct {
+			filter   Filter
+			expected string
+		}{
+			{Filter{}, "a@0s b@1m0s a@2m0s b@3m0s"},
+			{Filter{Events: []string{"b", "c"}}, "b@1m0s b@3m0s"},
+			{Filter{From: readerStart.Add(time.Minute), To: readerStart.Add(3 * time.Minute)}, "b@1m0s a@2m0s"},
+			{Filter{From: readerStart.Add(time.Minute), Events: []string{"a"}}, "a@2m0s"},
+		}
+	)
+
+	//Lines that are not event entries are skipped, and the last line need not be terminated
+	for _, c := range cases {
+		r, err := NewReader(strings.NewReader(log), c.filter)
+		if err != nil {
+			test.Fatalf("NewReader: %v", err)
+		}
+		if read := readAll(test, r); read != c.expected {
+			test.Errorf("Read with %+v: %v", c.filter, read)
+		}
+	}
+	if r, _ := NewReader(strings.NewReader(""), Filter{}); readAll(test, r) != "" {
+		test.Errorf("Read of empty input")
+	}
+}
+
+func TestOpenFiles(test *testing.T) {
+	var (
+		dir        = test.TempDir()
+		rotated    = filepath.Join(dir, "oplog.1.gz")
+		current    = filepath.Join(dir, "oplog")
+		compressed bytes.Buffer
+		zw         = gzip.NewWriter(&compressed)
+		r          *Reader
+		err        error
+	)
+
+	zw.Write([]byte(entryLine("a", 0) + entryLine("b", 1)))
+	zw.Close()
+	ioutil.WriteFile(rotated, compressed.Bytes(), 0644)
+	ioutil.WriteFile(filepath.Join(dir, "empty.gz"), nil, 0644)
+	ioutil.WriteFile(current, []byte(entryLine("a", 2)), 0644)
+
+	//Compressed files are detected by their content and the files are read in the order named
+	r, err = OpenFiles(Filter{}, rotated, filepath.Join(dir, "empty.gz"), current)
+	if err != nil {
+		test.Fatalf("OpenFiles: %v", err)
+	}
+	if read := readAll(test, r); read != "a@0s b@1m0s a@2m0s" {
+		test.Errorf("Read of files: %v", read)
+	}
+	if err = r.Close(); err != nil {
+		test.Errorf("Close: %v", err)
+	}
+
+	if _, err = OpenFiles(Filter{}, filepath.Join(dir, "missing")); err == nil {
+		test.Errorf("OpenFiles of a missing file should fail")
+	}
+	ioutil.WriteFile(rotated, []byte{0x1f, 0x8b, 0}, 0644)
+	if _, err = OpenFiles(Filter{}, rotated); err == nil || !strings.HasPrefix(err.Error(), "Bad Operational Log File") {
+		test.Errorf("OpenFiles of a corrupt gzip file: %v", err)
+	}
+	if r, err = OpenFiles(Filter{}); err != nil || readAll(test, r) != "" {
+		test.Errorf("OpenFiles of no files: %v", err)
+	}
+}
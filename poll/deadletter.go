@@ -0,0 +1,119 @@
+package poll
+
+import (
+	"sync"
+	"time"
+
+	"github.com/develrns/resilient/storekv"
+)
+
+/*
+A result that cannot be delivered is dead-lettered rather than silently lost: it is passed, with the reason it was not
+delivered, to the dead-letter sink set by SetDeadLetterSink. A result is dead-lettered when:
+
+	ReasonNotConsumed	- its State is purged as abandoned while the result is waiting for a consumer
+	ReasonPushFailed	- every push of a push State's result failed and its State was then purged unconsumed
+	ReasonSentAfterExpiry	- the result is sent after its State has been purged
+	ReasonSentTwice		- the result is sent after another result of its State
+
+The default sink logs the key and reason of each dead letter. StoreDeadLetters returns a sink that keeps them in a
+storekv Bucket for later inspection or redelivery.
+*/
+
+//The reasons a result is dead-lettered
+const (
+	ReasonNotConsumed     = "not consumed"
+	ReasonPushFailed      = "push failed"
+	ReasonSentAfterExpiry = "sent after expiry"
+	ReasonSentTwice       = "sent twice"
+)
+
+//EventDeadLettered is the State lifecycle event of a result being dead-lettered
+const EventDeadLettered = "dead-lettered"
+
+//A DeadLetter is an undeliverable result and its State's details
+type DeadLetter struct {
+	Key      string      `json:"key"`
+	Reason   string      `json:"reason"`
+	Result   interface{} `json:"result"`
+	Created  time.Time   `json:"created"`
+	Time     time.Time   `json:"time"`
+	Callback string      `json:"callback,omitempty"`
+	Events   []Event     `json:"events"`
+}
+
+//deadLetterSink is the dead-letter sink; it is mutexed since it may be set while States expire
+var deadLetterSink = struct {
+	m    sync.Mutex
+	sink func(DeadLetter)
+}{sink: logDeadLetter}
+
+/*
+SetDeadLetterSink sets the function that is passed each dead letter; nil restores the default, which logs them. The
+sink is called by the gofunction that purges States or sends the result, so a slow sink should hand off its work.
+*/
+func SetDeadLetterSink(sink func(DeadLetter)) {
+	if sink == nil {
+		sink = logDeadLetter
+	}
+	deadLetterSink.m.Lock()
+	defer deadLetterSink.m.Unlock()
+	deadLetterSink.sink = sink
+}
+
+//logDeadLetter is the default dead-letter sink; the result itself is not logged since it may be sensitive
+func logDeadLetter(dl DeadLetter) {
+	logger.Printf("Result of State %v dead-lettered: %v\n", dl.Key, dl.Reason)
+}
+
+/*
+StoreDeadLetters returns a dead-letter sink that puts each dead letter, as JSON, in a storekv Bucket under its State's
+key for the ttl (0 never expires). The result must be JSON encodable. A dead letter that cannot be stored is logged.
+*/
+func StoreDeadLetters(bucket *storekv.Bucket, ttl time.Duration) func(DeadLetter) {
+	return func(dl DeadLetter) {
+		if err := bucket.PutJSON(dl.Key, &dl, ttl); err != nil {
+			logger.Printf("Dead letter of State %v (%v) could not be stored: %v\n", dl.Key, dl.Reason, err)
+		}
+	}
+}
+
+//deadLetter passes an undeliverable result of the State to the dead-letter sink
+func (s *State) deadLetter(result interface{}, reason string) {
+	var sink func(DeadLetter)
+
+	s.addEvent(EventDeadLettered)
+	deadLetterSink.m.Lock()
+	sink = deadLetterSink.sink
+	deadLetterSink.m.Unlock()
+	sink(DeadLetter{
+		Key:      s.Key,
+		Reason:   reason,
+		Result:   result,
+		Created:  s.created,
+		Time:     getClock().Now(),
+		Callback: s.callback,
+		Events:   s.Events(),
+	})
+}
+
+//enqueue puts a result in the State's channel for a consumer or, if the State has expired, dead-letters it.
+//Send dead-letters any result after the first so the channel, which holds one result, always has room for it.
+func (s *State) enqueue(result interface{}, reason string) {
+	s.m.Lock()
+	if s.expired {
+		s.m.Unlock()
+		s.deadLetter(result, reason)
+		return
+	}
+
+	//The result is put while the State is locked so that it cannot expire without the result being dead-lettered
+	select {
+	case s.C <- result:
+		s.m.Unlock()
+		return
+	default:
+	}
+	s.m.Unlock()
+	s.deadLetter(result, ReasonSentTwice)
+}
@@ -0,0 +1,128 @@
+package poll
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/develrns/resilient/storekv"
+)
+
+//collectDeadLetters sets a sink that collects the dead letters until the test ends
+func collectDeadLetters(test *testing.T) <-chan DeadLetter {
+	var c = make(chan DeadLetter, 100)
+
+	SetDeadLetterSink(func(dl DeadLetter) {
+		select {
+		case c <- dl:
+		default:
+		}
+	})
+	test.Cleanup(func() { SetDeadLetterSink(nil) })
+	return c
+}
+
+//nextDeadLetter returns the next collected dead letter of the State, skipping those of States of other tests
+func nextDeadLetter(test *testing.T, c <-chan DeadLetter, key string) DeadLetter {
+	var timeout = time.After(5 * time.Second)
+
+	for {
+		select {
+		case dl := <-c:
+			if dl.Key == key {
+				return dl
+			}
+		case <-timeout:
+			test.Fatalf("Timed out waiting for the dead letter of %v", key)
+		}
+	}
+}
+
+func TestDeadLetter(test *testing.T) {
+	var (
+		fake        = useFakeClock(test)
+		deadLetters = collectDeadLetters(test)
+		s           = NewState()
+		consumed    = NewState()
+	)
+
+	consumed.Send("delivered")
+	if result, ok := consumed.Receive(time.Second); !ok || result != "delivered" {
+		test.Fatalf("Receive: %v %v", result, ok)
+	}
+	s.Send("abandoned")
+	purge(fake)
+
+	dl := nextDeadLetter(test, deadLetters, s.Key)
+	if dl.Reason != ReasonNotConsumed || dl.Result != "abandoned" || !dl.Created.Equal(s.created) || !dl.Time.Equal(fake.Now()) {
+		test.Errorf("Dead letter of an unconsumed result: %+v", dl)
+	}
+	if len(dl.Events) == 0 || dl.Events[len(dl.Events)-1].Name != EventDeadLettered {
+		test.Errorf("Dead letter events: %v", dl.Events)
+	}
+
+	s.Send("late")
+	if dl = nextDeadLetter(test, deadLetters, s.Key); dl.Reason != ReasonSentAfterExpiry || dl.Result != "late" {
+		test.Errorf("Dead letter of a result sent after expiry: %+v", dl)
+	}
+
+	//A delivered result is not dead-lettered
+	select {
+	case dl = <-deadLetters:
+		if dl.Key == consumed.Key {
+			test.Errorf("Dead letter of a delivered result: %+v", dl)
+		}
+	default:
+	}
+}
+
+func TestSendTwice(test *testing.T) {
+	var (
+		deadLetters = collectDeadLetters(test)
+		s           = NewState()
+		sent        = make(chan struct{})
+	)
+	defer s.Done()
+
+	//The second result is dead-lettered rather than blocking its producer
+	go func() {
+		s.Send("first")
+		s.Send("second")
+		close(sent)
+	}()
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		test.Fatalf("Second Send blocked")
+	}
+	if dl := nextDeadLetter(test, deadLetters, s.Key); dl.Reason != ReasonSentTwice || dl.Result != "second" {
+		test.Errorf("Dead letter of a second result: %+v", dl)
+	}
+	if result := <-s.C; result != "first" {
+		test.Errorf("Result: %v", result)
+	}
+}
+
+func TestStoreDeadLetters(test *testing.T) {
+	var (
+		store  *storekv.Store
+		bucket *storekv.Bucket
+		stored DeadLetter
+		err    error
+	)
+
+	store, err = storekv.Open(filepath.Join(test.TempDir(), "deadletters.db"))
+	if err != nil {
+		test.Fatalf("Open: %v", err)
+	}
+	defer store.Close()
+	bucket, err = store.Bucket("deadletters")
+	if err != nil {
+		test.Fatalf("Bucket: %v", err)
+	}
+
+	StoreDeadLetters(bucket, 0)(DeadLetter{Key: "k", Reason: ReasonPushFailed, Result: "r"})
+	if ok, err := bucket.GetJSON("k", &stored); !ok || err != nil || stored.Reason != ReasonPushFailed || stored.Result != "r" {
+		test.Errorf("Stored dead letter: %+v %v %v", stored, ok, err)
+	}
+}
@@ -0,0 +1,144 @@
+package poll
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/develrns/resilient/eventbus"
+)
+
+/*
+The wait-to-delivery latency of a State is the time from its creation to the delivery of its result: when a consumer
+receives it (Receive, or Done after the result was sent) or when it is pushed to its callback. It is how long a user
+actually waited on the async flow. A states table records the latencies of its States in a histogram whose summary is
+the Latency of its Stats; and each delivery is published on the eventbus TopicResultDelivered topic as a Delivery so
+that a metrics component can export it.
+
+The bucket counts, count and max cover every delivery; the percentiles are of the most recent latencySamples deliveries
+so that the histogram's memory is bounded.
+*/
+
+//latencyBounds are the upper bounds of the latency histogram buckets; the last bucket is unbounded.
+//States are purged after an hour so there is no need to distinguish longer latencies.
+var latencyBounds = []time.Duration{
+	10 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond, 250 * time.Millisecond,
+	500 * time.Millisecond, time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+	30 * time.Second, time.Minute, 5 * time.Minute, 15 * time.Minute, time.Hour,
+}
+
+//latencySamples is the number of recent latencies that the percentiles are computed from
+const latencySamples = 1024
+
+//A latencyHistogram records the wait-to-delivery latencies of a states table. It is mutexed since States are
+//delivered concurrently.
+type latencyHistogram struct {
+	m       sync.Mutex
+	counts  []int64
+	count   int64
+	max     time.Duration
+	samples []time.Duration
+	next    int
+}
+
+//newLatencyHistogram allocates a latencyHistogram
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]int64, len(latencyBounds)+1), samples: make([]time.Duration, 0, latencySamples)}
+}
+
+//record adds a latency to the histogram
+func (h *latencyHistogram) record(d time.Duration) {
+	var i = sort.Search(len(latencyBounds), func(i int) bool { return d <= latencyBounds[i] })
+
+	h.m.Lock()
+	defer h.m.Unlock()
+	h.counts[i]++
+	h.count++
+	if d > h.max {
+		h.max = d
+	}
+	if len(h.samples) < latencySamples {
+		h.samples = append(h.samples, d)
+		return
+	}
+	h.samples[h.next] = d
+	h.next = (h.next + 1) % latencySamples
+	return
+}
+
+//LatencyStats is a snapshot of the wait-to-delivery latency histogram of a states table
+type LatencyStats struct {
+	Count   int64
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+	Buckets []LatencyBucket
+}
+
+//A LatencyBucket counts the latencies up to and including its upper bound, Le, and above the previous bucket's.
+//The Le of the last bucket is 0 since it is unbounded.
+type LatencyBucket struct {
+	Le    time.Duration
+	Count int64
+}
+
+//stats returns a snapshot of the histogram
+func (h *latencyHistogram) stats() LatencyStats {
+	var (
+		stats  LatencyStats
+		sorted []time.Duration
+	)
+
+	h.m.Lock()
+	defer h.m.Unlock()
+	stats.Count = h.count
+	stats.Max = h.max
+	stats.Buckets = make([]LatencyBucket, len(h.counts))
+	for i, count := range h.counts {
+		stats.Buckets[i].Count = count
+		if i < len(latencyBounds) {
+			stats.Buckets[i].Le = latencyBounds[i]
+		}
+	}
+	sorted = make([]time.Duration, len(h.samples))
+	copy(sorted, h.samples)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	stats.P50 = percentile(sorted, 50)
+	stats.P90 = percentile(sorted, 90)
+	stats.P99 = percentile(sorted, 99)
+	return stats
+}
+
+//percentile returns the latency at a percentile (0-100) of sorted latencies
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(p/100*float64(len(sorted)-1))]
+}
+
+//A Delivery is the data of a TopicResultDelivered event
+type Delivery struct {
+	Key     string
+	Latency time.Duration
+}
+
+//recordDelivery records the wait-to-delivery latency of the State once its result has been sent and delivered.
+//It is called by each delivery path so it only records the first delivery.
+func (s *State) recordDelivery() {
+	var latency time.Duration
+
+	s.m.Lock()
+	if !s.resultSent || s.resultDelivered {
+		s.m.Unlock()
+		return
+	}
+	s.resultDelivered = true
+	latency = getClock().Now().Sub(s.created)
+	s.m.Unlock()
+
+	States.latency.record(latency)
+	eventbus.Publish(eventbus.TopicResultDelivered, Delivery{Key: s.Key, Latency: latency})
+	return
+}
@@ -0,0 +1,80 @@
+package poll
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyHistogram(test *testing.T) {
+	var (
+		h     = newLatencyHistogram()
+		stats LatencyStats
+	)
+
+	for i := 1; i <= 100; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+	h.record(2 * time.Hour)
+	stats = h.stats()
+
+	if stats.Count != 101 || stats.Max != 2*time.Hour {
+		test.Errorf("Count and Max: %v %v", stats.Count, stats.Max)
+	}
+	if stats.P50 != 51*time.Millisecond || stats.P90 != 91*time.Millisecond || stats.P99 != 100*time.Millisecond {
+		test.Errorf("Percentiles: %v %v %v", stats.P50, stats.P90, stats.P99)
+	}
+	if len(stats.Buckets) != len(latencyBounds)+1 {
+		test.Fatalf("Buckets: %v", stats.Buckets)
+	}
+	expected := map[time.Duration]int64{10 * time.Millisecond: 10, 50 * time.Millisecond: 40, 100 * time.Millisecond: 50, 0: 1}
+	for _, bucket := range stats.Buckets {
+		if bucket.Count != expected[bucket.Le] {
+			test.Errorf("Bucket %v: %v", bucket.Le, bucket.Count)
+		}
+	}
+
+	//The percentiles are of the most recent samples
+	for i := 0; i < latencySamples; i++ {
+		h.record(time.Second)
+	}
+	if stats = h.stats(); stats.P50 != time.Second || stats.P99 != time.Second || stats.Count != 101+latencySamples {
+		test.Errorf("Recent percentiles: %+v", stats)
+	}
+}
+
+func TestDeliveryLatency(test *testing.T) {
+	var (
+		fake   = useFakeClock(test)
+		s      = NewState()
+		before = States.Stats().Latency
+		after  LatencyStats
+	)
+	defer s.Done()
+
+	fake.Advance(300 * time.Millisecond)
+	s.Send("r")
+	fake.Advance(time.Second)
+	if result, ok := s.Receive(time.Second); !ok || result != "r" {
+		test.Fatalf("Receive: %v %v", result, ok)
+	}
+
+	//The latency is recorded once, at the first delivery
+	s.Done()
+	after = States.Stats().Latency
+	if after.Count != before.Count+1 {
+		test.Errorf("Latency count: %v %v", before.Count, after.Count)
+	}
+	for i, bucket := range after.Buckets {
+		increase := bucket.Count - before.Buckets[i].Count
+		if (bucket.Le == 2500*time.Millisecond && increase != 1) || (bucket.Le != 2500*time.Millisecond && increase != 0) {
+			test.Errorf("Bucket %v increased by %v", bucket.Le, increase)
+		}
+	}
+
+	//A State whose result was never sent records no latency
+	unsent := NewState()
+	unsent.Done()
+	if count := States.Stats().Latency.Count; count != after.Count {
+		test.Errorf("Latency count of an unsent result: %v", count)
+	}
+}
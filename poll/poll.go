@@ -21,14 +21,38 @@ If a producing request is used, its path is formed in the same way as the long-p
 in the same way to retrieve its channel and send its results to the long-poll request.
 
 States that are over 1 hour old are deleted from the states map.
+
+Each State records a trail of lifecycle Events (created, producer attached, result sent, consumed and expired)
+with their times. The trail is available from a State's Events method and, for all active States, from States.Stats.
+This is used to debug where a result went without adding temporary prints to both the producer and consumer.
+To record the producer events, a producer should call Attach when it retrieves a State and Send to send its result.
+The key of each purged State is also published on the eventbus TopicStateExpired topic.
+
+The time from a State's creation to the delivery of its result is recorded in a latency histogram whose count,
+percentiles and buckets are the Latency of States.Stats; see latency.go.
+
+A producer that holds resources for a State (e.g. temp files or upstream subscriptions) should register their
+release with OnExpire, so that they are released when the State is purged as abandoned rather than leaking until the
+producer notices that the consumer has vanished.
+
+A result that was sent but could not be delivered before its State was purged is passed to a dead-letter sink; see
+deadletter.go.
+
+The State times, the purge period and the push backoff come from a clock.Clock that tests may replace with SetClock.
+
+A State created with NewPushState has a callback URL to which its result is POSTed if no consumer is waiting for it;
+see push.go.
 */
 package poll
 
 import (
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/develrns/resilient/clock"
+	"github.com/develrns/resilient/eventbus"
 	"github.com/develrns/resilient/log"
 
 	"github.com/pborman/uuid"
@@ -36,16 +60,37 @@ import (
 
 var logger = log.Logger()
 
+var (
+	//clk is the package's time source; it is mutexed since the purge gofunction reads it
+	clkM sync.Mutex
+	clk  = clock.Real
+)
+
+/*
+SetClock replaces the Clock of the package, e.g. with a clock.Fake in a test. It returns the replaced Clock.
+*/
+func SetClock(c clock.Clock) clock.Clock {
+	clkM.Lock()
+	defer clkM.Unlock()
+	c, clk = clk, c
+	return c
+}
+
+//getClock returns the package's Clock
+func getClock() clock.Clock {
+	clkM.Lock()
+	defer clkM.Unlock()
+	return clk
+}
+
 func init() {
 	go purgeTicker()
 }
 
 //purgeTicker purges abandoned States once per hour
 func purgeTicker() {
-	var ticker = time.NewTicker(time.Hour)
-
 	for {
-		_ = <-ticker.C
+		_ = <-getClock().After(time.Hour)
 		States.purgeAbandonedStates()
 	}
 
@@ -54,8 +99,9 @@ func purgeTicker() {
 //states holds active long-poll states. Since many HTTP requests and gofunctions will be concurrently
 //mutating a states table, it must be mutexed.
 type states struct {
-	m sync.Mutex
-	s map[string]*State
+	m       sync.Mutex
+	s       map[string]*State
+	latency *latencyHistogram
 }
 
 //The States Table that holds all the long-poll channels for a server.
@@ -65,6 +111,7 @@ var States = newStates(1000)
 func newStates(capacity int) *states {
 	var states states
 	states.s = make(map[string]*State, capacity)
+	states.latency = newLatencyHistogram()
 	return &states
 }
 
@@ -120,30 +167,95 @@ func (ss *states) delState(key string) {
 //it has been removed from the States table. A common case will be that a producer will produce the result
 //and exit. At that point, if the State for that results channel has been deleted from the States table the State and
 //its channel will be garbage collected.
+//The OnExpire functions of the purged States are run once the table is unlocked so that they cannot block it.
 func (ss *states) purgeAbandonedStates() {
+	var (
+		expired []*State
+		now     = getClock().Now()
+	)
+
 	ss.m.Lock()
-	defer ss.m.Unlock()
 	for key, state := range ss.s {
-		if time.Now().After(state.created.Add(time.Hour)) {
+		if now.After(state.created.Add(time.Hour)) {
+			state.addEvent(EventExpired)
 			delete(ss.s, key)
+			expired = append(expired, state)
 		}
 	}
+	ss.m.Unlock()
+
+	for _, state := range expired {
+		eventbus.Publish(eventbus.TopicStateExpired, state.Key)
+		state.expire()
+	}
 	return
 }
 
+//Stats is a snapshot of a states table and of the wait-to-delivery latency histogram of its States
+type Stats struct {
+	States  []StateStats
+	Latency LatencyStats
+}
+
+//StateStats is a snapshot of a State's key, creation time and lifecycle events
+type StateStats struct {
+	Key     string
+	Created time.Time
+	Events  []Event
+}
+
+//Stats returns a snapshot of the States in a states table.
+func (ss *states) Stats() Stats {
+	var stats Stats
+
+	ss.m.Lock()
+	defer ss.m.Unlock()
+	stats.States = make([]StateStats, 0, len(ss.s))
+	for key, state := range ss.s {
+		stats.States = append(stats.States, StateStats{Key: key, Created: state.created, Events: state.Events()})
+	}
+	stats.Latency = ss.latency.stats()
+	return stats
+}
+
+//The names of the State lifecycle events
+const (
+	EventCreated          = "created"
+	EventProducerAttached = "producer attached"
+	EventResultSent       = "result sent"
+	EventConsumed         = "consumed"
+	EventExpired          = "expired"
+)
+
+//An Event is a State lifecycle event and the time it occurred.
+type Event struct {
+	Name string
+	Time time.Time
+}
+
 /*
 A State holds the result channel for sending an async result to an HTTP long-poll result request.
 Done uses its key to remove it from the States table. purgeAbandonedStates uses its created time to
 determine if a State has been abandoned.
 
-State may be read concurrently. It must not be changed once it has been created.
+State may be read concurrently. Other than its event trail, which is mutexed, it must not be changed once it has been created.
 
 In this scenario a channel that holds a single value is sufficient because only one send to the channel will be done.
 */
 type State struct {
-	C       chan interface{}
-	Key     string
-	created time.Time
+	C        chan interface{}
+	Key      string
+	created  time.Time
+	m        sync.Mutex
+	events   []Event
+	onExpire []func()
+	expired  bool
+	callback string
+	waiting  int32
+
+	//resultSent and resultDelivered are mutexed by m; see recordDelivery
+	resultSent      bool
+	resultDelivered bool
 }
 
 /*
@@ -156,7 +268,8 @@ func NewState() *State {
 	)
 	state.C = make(chan interface{}, 1)
 	state.Key = key
-	state.created = time.Now()
+	state.created = getClock().Now()
+	state.events = []Event{{Name: EventCreated, Time: state.created}}
 	States.addState(&state, key)
 	return &state
 }
@@ -166,6 +279,133 @@ Done deletes the State from the States table. Once a long-poll request has retri
 it should call Done.
 */
 func (s *State) Done() {
+	s.addEvent(EventConsumed)
+	s.recordDelivery()
 	States.delState(s.Key)
 	return
 }
+
+/*
+Attach records that a producer has retrieved the State. A producing request or gofunction should call it before
+producing its result.
+*/
+func (s *State) Attach() {
+	s.addEvent(EventProducerAttached)
+	return
+}
+
+/*
+Send records that the result has been sent and sends it to the State's channel or, if it is a push State and no
+consumer is waiting in Receive, pushes it to the State's callback. Only one result is sent for a State, so a result
+sent after the first is dead-lettered rather than blocking the producer.
+*/
+func (s *State) Send(result interface{}) {
+	var reason = ReasonSentTwice
+
+	s.m.Lock()
+	if s.resultSent {
+		if s.expired {
+			reason = ReasonSentAfterExpiry
+		}
+		s.m.Unlock()
+		s.deadLetter(result, reason)
+		return
+	}
+	s.resultSent = true
+	s.m.Unlock()
+	s.addEvent(EventResultSent)
+	if s.callback != "" && atomic.LoadInt32(&s.waiting) == 0 {
+		go s.push(result)
+		return
+	}
+	s.enqueue(result, ReasonSentAfterExpiry)
+	return
+}
+
+/*
+OnExpire registers a function that is run if the State is purged as abandoned. It is not run if the State is
+consumed (see Done). If the State has already expired, the function is run immediately.
+*/
+func (s *State) OnExpire(f func()) {
+	s.m.Lock()
+	if !s.expired {
+		s.onExpire = append(s.onExpire, f)
+		s.m.Unlock()
+		return
+	}
+	s.m.Unlock()
+	runOnExpire(s.Key, f)
+	return
+}
+
+//expire marks the State expired, runs its OnExpire functions and dead-letters its result if it was not delivered
+func (s *State) expire() {
+	var (
+		onExpire    []func()
+		result      interface{}
+		undelivered bool
+	)
+
+	s.m.Lock()
+	s.expired = true
+	onExpire = s.onExpire
+	s.onExpire = nil
+	if s.resultSent && !s.resultDelivered {
+		select {
+		case result = <-s.C:
+			undelivered = true
+		default:
+		}
+	}
+	s.m.Unlock()
+
+	for _, f := range onExpire {
+		runOnExpire(s.Key, f)
+	}
+	if undelivered {
+		s.deadLetter(result, s.undeliveredReason())
+	}
+	return
+}
+
+//undeliveredReason is the reason that the State's sent result was not delivered
+func (s *State) undeliveredReason() string {
+	for _, event := range s.Events() {
+		if event.Name == EventPushFailed {
+			return ReasonPushFailed
+		}
+	}
+	return ReasonNotConsumed
+}
+
+//runOnExpire runs an OnExpire function, logging rather than propagating a panic so that the other functions still run
+func runOnExpire(key string, f func()) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Printf("OnExpire function of State %v panicked: %v\n", key, r)
+		}
+	}()
+	f()
+	return
+}
+
+/*
+Events returns a copy of the State's lifecycle event trail in the order the events occurred.
+*/
+func (s *State) Events() []Event {
+	var events []Event
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	events = make([]Event, len(s.events))
+	copy(events, s.events)
+	return events
+}
+
+//addEvent appends an event to the State's event trail
+func (s *State) addEvent(name string) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	s.events = append(s.events, Event{Name: name, Time: getClock().Now()})
+	return
+}
@@ -0,0 +1,107 @@
+package poll
+
+import (
+	"testing"
+	"time"
+
+	"github.com/develrns/resilient/clock"
+	"github.com/develrns/resilient/eventbus"
+)
+
+//useFakeClock replaces the package's Clock with a Fake until the test ends
+func useFakeClock(test *testing.T) *clock.Fake {
+	var (
+		fake     = clock.NewFake(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+		replaced = SetClock(fake)
+	)
+	test.Cleanup(func() { SetClock(replaced) })
+	return fake
+}
+
+//waitFor polls cond until it holds, failing the test if it does not within a few seconds
+func waitFor(test *testing.T, what string, cond func() bool) {
+	for deadline := time.Now().Add(5 * time.Second); !cond(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			test.Fatalf("Timed out waiting for %v", what)
+		}
+	}
+}
+
+//hasEvent returns true if the State's event trail has the event
+func hasEvent(s *State, name string) bool {
+	for _, event := range s.Events() {
+		if event.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+//purge advances the Fake past the abandoned State age and purges the States table
+func purge(fake *clock.Fake) {
+	fake.Advance(time.Hour + time.Second)
+	States.purgeAbandonedStates()
+}
+
+func TestGetState(test *testing.T) {
+	var s = NewState()
+
+	for _, keyOrPath := range []string{s.Key, "/poll/" + s.Key} {
+		if got, ok := States.GetState(keyOrPath); !ok || got != s {
+			test.Errorf("GetState %v: %v %v", keyOrPath, got, ok)
+		}
+	}
+	s.Done()
+	if _, ok := States.GetState(s.Key); ok {
+		test.Errorf("GetState of a Done State")
+	}
+	if !hasEvent(s, EventConsumed) {
+		test.Errorf("Done events: %v", s.Events())
+	}
+}
+
+func TestOnExpire(test *testing.T) {
+	var (
+		fake     = useFakeClock(test)
+		expired  = NewState()
+		consumed = NewState()
+		ran      []string
+		sub      = eventbus.Subscribe(eventbus.TopicStateExpired, 100, eventbus.DropNewest)
+	)
+	defer sub.Close()
+
+	expired.OnExpire(func() { ran = append(ran, "first") })
+	expired.OnExpire(func() { panic("released twice") })
+	expired.OnExpire(func() { ran = append(ran, "third") })
+	consumed.OnExpire(func() { ran = append(ran, "consumed") })
+	consumed.Done()
+
+	//A State is not purged until it is over an hour old
+	fake.Advance(time.Hour)
+	States.purgeAbandonedStates()
+	if len(ran) != 0 {
+		test.Fatalf("OnExpire ran before the State expired: %v", ran)
+	}
+
+	purge(fake)
+	if len(ran) != 2 || ran[0] != "first" || ran[1] != "third" {
+		test.Errorf("OnExpire ran: %v", ran)
+	}
+	if _, ok := States.GetState(expired.Key); ok || !hasEvent(expired, EventExpired) {
+		test.Errorf("Purged State: %v %v", ok, expired.Events())
+	}
+	waitFor(test, "the State expired event", func() bool {
+		select {
+		case event := <-sub.C:
+			return event.Data == expired.Key
+		default:
+			return false
+		}
+	})
+
+	//A function registered once the State has expired runs immediately
+	expired.OnExpire(func() { ran = append(ran, "late") })
+	if len(ran) != 3 || ran[2] != "late" {
+		test.Errorf("OnExpire after expiry ran: %v", ran)
+	}
+}
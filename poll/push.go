@@ -0,0 +1,143 @@
+package poll
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync/atomic"
+	"time"
+)
+
+/*
+A push State supports push-capable clients alongside long-polling ones. It is created with a callback URL by
+NewPushState. If its result is sent while no consumer is waiting for it in Receive, the result is POSTed to the
+callback as JSON rather than waiting for a poll:
+
+	{"key": "<State key>", "result": <result>}
+
+A push is retried with exponential backoff until the callback responds with a 2xx status; the State is then Done.
+If every attempt fails, the result is sent to the State's channel so that a later poll can still receive it, and it is
+dead-lettered if its State expires first.
+*/
+
+//The push State lifecycle events
+const (
+	EventPushed     = "pushed"
+	EventPushFailed = "push failed"
+)
+
+//The push retry policy
+const (
+	pushAttempts = 5
+	pushBackoff  = time.Second
+)
+
+//PushClient is the HTTP client used to POST results to callbacks
+var PushClient = &http.Client{Timeout: 30 * time.Second}
+
+//pushBody is the JSON body of a push
+type pushBody struct {
+	Key    string      `json:"key"`
+	Result interface{} `json:"result"`
+}
+
+/*
+NewPushState creates a new State with a callback URL, which must be an absolute http or https URL; puts it in the
+States table and returns it.
+*/
+func NewPushState(callback string) (*State, error) {
+	var (
+		u     *url.URL
+		state *State
+		err   error
+	)
+
+	u, err = url.Parse(callback)
+	if err != nil || !u.IsAbs() || (u.Scheme != "http" && u.Scheme != "https") {
+		return nil, fmt.Errorf("Bad Callback URL: %v", callback)
+	}
+	state = NewState()
+	state.callback = callback
+	return state, nil
+}
+
+/*
+Receive waits up to timeout for the State's result. While it waits, a push State's result is sent to it rather than
+pushed. It returns false if the timeout expires.
+*/
+func (s *State) Receive(timeout time.Duration) (interface{}, bool) {
+	var timeoutC = getClock().After(timeout)
+
+	atomic.AddInt32(&s.waiting, 1)
+	select {
+	case result := <-s.C:
+		atomic.AddInt32(&s.waiting, -1)
+		s.recordDelivery()
+		return result, true
+	case <-timeoutC:
+		atomic.AddInt32(&s.waiting, -1)
+	}
+
+	//A result sent as the wait expired is in the channel rather than pushed
+	select {
+	case result := <-s.C:
+		s.recordDelivery()
+		return result, true
+	default:
+		return nil, false
+	}
+}
+
+//push POSTs a result to the State's callback, retrying with exponential backoff
+func (s *State) push(result interface{}) {
+	var (
+		body    []byte
+		backoff = pushBackoff
+		err     error
+	)
+
+	body, err = json.Marshal(pushBody{Key: s.Key, Result: result})
+	if err != nil {
+		logger.Printf("Push of State %v result failed: %v\n", s.Key, err)
+		s.addEvent(EventPushFailed)
+		s.enqueue(result, ReasonPushFailed)
+		return
+	}
+
+	for attempt := 1; attempt <= pushAttempts; attempt++ {
+		err = postResult(s.callback, body)
+		if err == nil {
+			s.addEvent(EventPushed)
+			s.Done()
+			return
+		}
+		logger.Printf("Push %v of State %v result failed: %v\n", attempt, s.Key, err)
+		if attempt < pushAttempts {
+			<-getClock().After(backoff)
+			backoff *= 2
+		}
+	}
+	s.addEvent(EventPushFailed)
+	s.enqueue(result, ReasonPushFailed)
+	return
+}
+
+//postResult POSTs a push body to a callback
+func postResult(callback string, body []byte) error {
+	var (
+		rsp *http.Response
+		err error
+	)
+
+	rsp, err = PushClient.Post(callback, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	rsp.Body.Close()
+	if rsp.StatusCode < 200 || rsp.StatusCode > 299 {
+		return fmt.Errorf("Callback responded %v", rsp.Status)
+	}
+	return nil
+}
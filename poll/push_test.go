@@ -0,0 +1,170 @@
+package poll
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/develrns/resilient/clock"
+	"github.com/develrns/resilient/eventbus"
+)
+
+//callback is a push callback that fails its first failures POSTs and records the bodies of the rest
+type callback struct {
+	m        sync.Mutex
+	failures int
+	posts    int
+	bodies   []pushBody
+}
+
+func (cb *callback) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var body pushBody
+
+	cb.m.Lock()
+	defer cb.m.Unlock()
+	cb.posts++
+	if cb.posts <= cb.failures {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	json.NewDecoder(r.Body).Decode(&body)
+	cb.bodies = append(cb.bodies, body)
+}
+
+func TestNewPushState(test *testing.T) {
+	for _, callback := range []string{"/relative", "ftp://ex.org/callback", "://"} {
+		if _, err := NewPushState(callback); err == nil {
+			test.Errorf("NewPushState %v should fail", callback)
+		}
+	}
+}
+
+func TestPush(test *testing.T) {
+	var (
+		fake  = useFakeClock(test)
+		cb    = &callback{failures: 2}
+		srv   = httptest.NewServer(cb)
+		sub   = eventbus.Subscribe(eventbus.TopicResultDelivered, 100, eventbus.DropNewest)
+		s     *State
+		err   error
+		found bool
+	)
+	defer srv.Close()
+	defer sub.Close()
+
+	s, err = NewPushState(srv.URL)
+	if err != nil {
+		test.Fatalf("NewPushState: %v", err)
+	}
+	s.Send("r")
+
+	//The failed pushes are retried after 1s and then 2s
+	for _, backoff := range []time.Duration{time.Second, 2 * time.Second} {
+		waitFor(test, "the push backoff", func() bool { return fake.Waiters() == 1 })
+		fake.Advance(backoff)
+	}
+	waitFor(test, "the push", func() bool { return hasEvent(s, EventConsumed) })
+	cb.m.Lock()
+	defer cb.m.Unlock()
+	if !hasEvent(s, EventPushed) || len(cb.bodies) != 1 || cb.bodies[0].Key != s.Key || cb.bodies[0].Result != "r" {
+		test.Errorf("Push: %v %+v", s.Events(), cb.bodies)
+	}
+	if _, ok := States.GetState(s.Key); ok {
+		test.Errorf("A pushed State is Done")
+	}
+
+	//The push is delivered 3s after the State was created
+	for !found {
+		select {
+		case event := <-sub.C:
+			delivery := event.Data.(Delivery)
+			if delivery.Key == s.Key {
+				found = true
+				if delivery.Latency != 3*time.Second {
+					test.Errorf("Push latency: %v", delivery.Latency)
+				}
+			}
+		case <-time.After(5 * time.Second):
+			test.Fatalf("Timed out waiting for the push delivery")
+		}
+	}
+}
+
+//failPushes advances the Fake through the backoffs of a push State whose pushes all fail
+func failPushes(test *testing.T, fake *clock.Fake, s *State) {
+	for backoff := pushBackoff; backoff < pushBackoff<<(pushAttempts-1); backoff *= 2 {
+		waitFor(test, "the push backoff", func() bool { return fake.Waiters() == 1 })
+		fake.Advance(backoff)
+	}
+	waitFor(test, "the failed push", func() bool { return hasEvent(s, EventPushFailed) })
+}
+
+func TestPushFailed(test *testing.T) {
+	var (
+		fake        = useFakeClock(test)
+		deadLetters = collectDeadLetters(test)
+		srv         = httptest.NewServer(&callback{failures: 2 * pushAttempts})
+		polled      *State
+		abandoned   *State
+		err         error
+	)
+	defer srv.Close()
+
+	//A result that cannot be pushed waits for a poll, or is dead-lettered if its State expires first
+	polled, err = NewPushState(srv.URL)
+	if err != nil {
+		test.Fatalf("NewPushState: %v", err)
+	}
+	abandoned, _ = NewPushState(srv.URL)
+	polled.Send("polled")
+	failPushes(test, fake, polled)
+	abandoned.Send("abandoned")
+	failPushes(test, fake, abandoned)
+
+	if result, ok := polled.Receive(time.Second); !ok || result != "polled" {
+		test.Errorf("Receive of a failed push: %v %v", result, ok)
+	}
+	polled.Done()
+	purge(fake)
+	if dl := nextDeadLetter(test, deadLetters, abandoned.Key); dl.Reason != ReasonPushFailed || dl.Result != "abandoned" || dl.Callback != srv.URL {
+		test.Errorf("Dead letter of a failed push: %+v", dl)
+	}
+}
+
+func TestPushWhileReceiving(test *testing.T) {
+	var (
+		cb       = &callback{}
+		srv      = httptest.NewServer(cb)
+		s        *State
+		received = make(chan interface{})
+		err      error
+	)
+	defer srv.Close()
+	useFakeClock(test)
+
+	s, err = NewPushState(srv.URL)
+	if err != nil {
+		test.Fatalf("NewPushState: %v", err)
+	}
+	defer s.Done()
+
+	//A result sent while a consumer waits in Receive is sent to it rather than pushed
+	go func() {
+		result, _ := s.Receive(time.Minute)
+		received <- result
+	}()
+	waitFor(test, "Receive", func() bool { return atomic.LoadInt32(&s.waiting) == 1 })
+	s.Send("r")
+	if result := <-received; result != "r" {
+		test.Errorf("Receive: %v", result)
+	}
+	cb.m.Lock()
+	defer cb.m.Unlock()
+	if cb.posts != 0 {
+		test.Errorf("A received result was pushed")
+	}
+}
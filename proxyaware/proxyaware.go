@@ -0,0 +1,274 @@
+/*
+Package proxyaware resolves the client IP address, scheme and host of a request that may have passed through
+reverse proxies or load balancers (e.g. ones that terminate TLS).
+
+The forwarding headers (Forwarded, X-Forwarded-For, X-Forwarded-Proto, X-Forwarded-Host and X-Real-IP) are trusted
+only if the request was received from a proxy in the Resolver's trusted CIDR list; otherwise anyone could spoof them.
+The Forwarded header (RFC 7239) takes precedence over the X-Forwarded headers, which take precedence over X-Real-IP.
+
+The client is the nearest address in the forwarding chain that is not a trusted proxy; the scheme and host are those
+reported by the proxy that received the request from the client.
+
+A Resolver's Middleware rewrites a request's RemoteAddr and Host to the resolved client IP and host and saves the
+resolved Origin in the request context, where FromRequest gets it.
+*/
+package proxyaware
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+)
+
+//An Origin is the resolved client IP address, scheme and host of a request
+type Origin struct {
+	IP     net.IP
+	Scheme string
+	Host   string
+}
+
+/*
+BaseURL returns the absolute base URL (scheme://host) of the Origin, e.g. for building absolute redirect URIs.
+*/
+func (o Origin) BaseURL() string {
+	return o.Scheme + "://" + o.Host
+}
+
+/*
+A Resolver resolves the Origin of requests forwarded by a list of trusted proxies.
+*/
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+/*
+New creates a Resolver that trusts the proxies in a list of CIDRs (e.g. "10.0.0.0/8"). A single IP address is
+treated as a /32 (or /128) CIDR. A Resolver without trusted proxies ignores the forwarding headers.
+*/
+func New(cidrs ...string) (*Resolver, error) {
+	var (
+		resolver = &Resolver{}
+		ipNet    *net.IPNet
+		err      error
+	)
+
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("Bad Trusted Proxy: %v", cidr)
+			}
+			if ip.To4() != nil {
+				cidr = cidr + "/32"
+			} else {
+				cidr = cidr + "/128"
+			}
+		}
+		_, ipNet, err = net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("Bad Trusted Proxy: %v", cidr)
+		}
+		resolver.trusted = append(resolver.trusted, ipNet)
+	}
+	return resolver, nil
+}
+
+//isTrusted is true if an IP address is a trusted proxy
+func (res *Resolver) isTrusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, ipNet := range res.trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+//hop is one element of a forwarding chain
+type hop struct {
+	ip     net.IP
+	scheme string
+	host   string
+}
+
+/*
+Resolve resolves the Origin of a request.
+*/
+func (res *Resolver) Resolve(r *http.Request) Origin {
+	var (
+		origin = Origin{IP: remoteIP(r.RemoteAddr), Scheme: "http", Host: r.Host}
+		hops   []hop
+	)
+
+	if r.TLS != nil {
+		origin.Scheme = "https"
+	}
+	if !res.isTrusted(origin.IP) {
+		return origin
+	}
+
+	switch {
+	case r.Header.Get("Forwarded") != "":
+		hops = forwardedHops(r.Header["Forwarded"])
+	case r.Header.Get("X-Forwarded-For") != "":
+		hops = xForwardedHops(r.Header)
+	case r.Header.Get("X-Real-IP") != "":
+		hops = []hop{{ip: net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP")))}}
+	}
+
+	//The client is the nearest hop that is not a trusted proxy; if all are trusted it is the farthest
+	for i := len(hops) - 1; i >= 0; i-- {
+		if hops[i].ip == nil {
+			break
+		}
+		origin.IP = hops[i].ip
+		if hops[i].scheme != "" {
+			origin.Scheme = hops[i].scheme
+		}
+		if hops[i].host != "" {
+			origin.Host = hops[i].host
+		}
+		if !res.isTrusted(hops[i].ip) {
+			break
+		}
+	}
+	return origin
+}
+
+//remoteIP returns the IP address of a RemoteAddr
+func remoteIP(remoteAddr string) net.IP {
+	var host, _, err = net.SplitHostPort(remoteAddr)
+
+	if err != nil {
+		host = remoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+//nodeIP returns the IP address of a Forwarded for node or X-Forwarded-For entry; it is nil if it is obfuscated or unknown
+func nodeIP(node string) net.IP {
+	var ip net.IP
+
+	node = strings.Trim(strings.TrimSpace(node), `"`)
+	if strings.HasPrefix(node, "[") {
+		node = strings.TrimPrefix(node[:strings.Index(node+"]", "]")], "[")
+	}
+	ip = net.ParseIP(node)
+	if ip == nil {
+		ip = remoteIP(node)
+	}
+	return ip
+}
+
+//forwardedHops parses the elements of Forwarded headers
+func forwardedHops(headers []string) []hop {
+	var hops []hop
+
+	for _, header := range headers {
+		for _, element := range strings.Split(header, ",") {
+			var h hop
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) != 2 {
+					continue
+				}
+				value := strings.Trim(kv[1], `"`)
+				switch strings.ToLower(kv[0]) {
+				case "for":
+					h.ip = nodeIP(value)
+				case "proto":
+					h.scheme = strings.ToLower(value)
+				case "host":
+					h.host = value
+				}
+			}
+			hops = append(hops, h)
+		}
+	}
+	return hops
+}
+
+/*
+xForwardedHops parses the X-Forwarded-For list. X-Forwarded-Proto and X-Forwarded-Host are usually single values
+set by the proxy that received the request from the client; if they are lists, they are aligned with the end of the
+X-Forwarded-For list.
+*/
+func xForwardedHops(header http.Header) []hop {
+	var (
+		hops    []hop
+		schemes = splitList(header.Get("X-Forwarded-Proto"))
+		hosts   = splitList(header.Get("X-Forwarded-Host"))
+	)
+
+	for _, node := range splitList(strings.Join(header["X-Forwarded-For"], ",")) {
+		hops = append(hops, hop{ip: nodeIP(node)})
+	}
+	for i := range hops {
+		hops[i].scheme = strings.ToLower(aligned(schemes, i, len(hops)))
+		hops[i].host = aligned(hosts, i, len(hops))
+	}
+	return hops
+}
+
+//aligned returns the item of a list aligned with hop i of the end of a chain of n hops, or the list's only item
+func aligned(items []string, i, n int) string {
+	var j = len(items) - n + i
+
+	switch {
+	case len(items) == 1:
+		return items[0]
+	case j < 0 || j >= len(items):
+		return ""
+	default:
+		return items[j]
+	}
+}
+
+//splitList splits a comma separated header value, dropping empty items
+func splitList(value string) []string {
+	var items []string
+
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+//originKey is the request context key of an Origin
+type originKey struct{}
+
+/*
+Middleware wraps a handler so that the Origin of its requests is resolved. The request's RemoteAddr is set to the
+client IP (with port 0 if it was forwarded) and its Host to the resolved host.
+*/
+func (res *Resolver) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var origin = res.Resolve(r)
+
+		r = r.WithContext(context.WithValue(r.Context(), originKey{}, origin))
+		if origin.IP != nil && !origin.IP.Equal(remoteIP(r.RemoteAddr)) {
+			r.RemoteAddr = net.JoinHostPort(origin.IP.String(), "0")
+		}
+		r.Host = origin.Host
+		h.ServeHTTP(w, r)
+	})
+}
+
+/*
+FromRequest gets the Origin of a request saved by a Middleware.
+*/
+func FromRequest(r *http.Request) (Origin, bool) {
+	var origin, ok = r.Context().Value(originKey{}).(Origin)
+	return origin, ok
+}
@@ -0,0 +1,121 @@
+package proxyaware
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNew(test *testing.T) {
+	var (
+		res *Resolver
+		err error
+	)
+
+	for _, cidr := range []string{"10.0.0.0/33", "not-an-ip", "10.0.0.1/"} {
+		if _, err = New(cidr); err == nil {
+			test.Errorf("New %v should fail", cidr)
+		}
+	}
+	res, err = New(" 10.0.0.0/8", "", "192.168.1.1", "2001:db8::1")
+	if err != nil || len(res.trusted) != 3 {
+		test.Fatalf("New: %v %v", res, err)
+	}
+	if !res.isTrusted(remoteIP("192.168.1.1:80")) || res.isTrusted(remoteIP("192.168.1.2:80")) || !res.isTrusted(remoteIP("[2001:db8::1]:443")) {
+		test.Errorf("A single IP is not trusted as a /32 or /128")
+	}
+}
+
+func TestResolve(test *testing.T) {
+	var (
+		res, _ = New("10.0.0.0/8")
+		cases  = []struct {
+			name    string
+			remote  string
+			tls     bool
+			headers map[string]string
+			origin  Origin
+		}{
+			{"direct", "1.2.3.4:5000", false, nil, Origin{IP: net.ParseIP("1.2.3.4"), Scheme: "http", Host: "app.ex.org"}},
+			{"direct TLS", "1.2.3.4:5000", true, nil, Origin{IP: net.ParseIP("1.2.3.4"), Scheme: "https", Host: "app.ex.org"}},
+			{"untrusted X-Forwarded", "1.2.3.4:5000", false,
+				map[string]string{"X-Forwarded-For": "6.6.6.6", "X-Forwarded-Proto": "https", "X-Forwarded-Host": "evil.org"},
+				Origin{IP: net.ParseIP("1.2.3.4"), Scheme: "http", Host: "app.ex.org"}},
+			{"untrusted Forwarded", "1.2.3.4:5000", false,
+				map[string]string{"Forwarded": "for=6.6.6.6;proto=https;host=evil.org", "X-Real-IP": "6.6.6.6"},
+				Origin{IP: net.ParseIP("1.2.3.4"), Scheme: "http", Host: "app.ex.org"}},
+			{"trusted X-Forwarded", "10.0.0.1:5000", false,
+				map[string]string{"X-Forwarded-For": "1.2.3.4", "X-Forwarded-Proto": "HTTPS", "X-Forwarded-Host": "ex.org"},
+				Origin{IP: net.ParseIP("1.2.3.4"), Scheme: "https", Host: "ex.org"}},
+			{"spoofed X-Forwarded-For", "10.0.0.1:5000", false,
+				map[string]string{"X-Forwarded-For": "6.6.6.6, 1.2.3.4, 10.0.0.2", "X-Forwarded-Host": "evil.org, ex.org, internal"},
+				Origin{IP: net.ParseIP("1.2.3.4"), Scheme: "http", Host: "ex.org"}},
+			{"trusted Forwarded", "10.0.0.1:5000", false,
+				map[string]string{"Forwarded": `for="[2001:db8::7]:4711";proto=https;host=ex.org`},
+				Origin{IP: net.ParseIP("2001:db8::7"), Scheme: "https", Host: "ex.org"}},
+			{"spoofed Forwarded", "10.0.0.1:5000", false,
+				map[string]string{"Forwarded": "for=6.6.6.6;proto=http;host=evil.org, for=1.2.3.4;proto=https;host=ex.org"},
+				Origin{IP: net.ParseIP("1.2.3.4"), Scheme: "https", Host: "ex.org"}},
+			{"Forwarded precedence", "10.0.0.1:5000", false,
+				map[string]string{"Forwarded": "for=1.2.3.4", "X-Forwarded-For": "5.6.7.8", "X-Real-IP": "5.6.7.8"},
+				Origin{IP: net.ParseIP("1.2.3.4"), Scheme: "http", Host: "app.ex.org"}},
+			{"X-Real-IP", "10.0.0.1:5000", false, map[string]string{"X-Real-IP": " 1.2.3.4 "},
+				Origin{IP: net.ParseIP("1.2.3.4"), Scheme: "http", Host: "app.ex.org"}},
+			{"obfuscated", "10.0.0.1:5000", false, map[string]string{"Forwarded": "for=_hidden, for=10.0.0.2"},
+				Origin{IP: net.ParseIP("10.0.0.2"), Scheme: "http", Host: "app.ex.org"}},
+			{"all trusted", "10.0.0.1:5000", false, map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+				Origin{IP: net.ParseIP("10.0.0.3"), Scheme: "http", Host: "app.ex.org"}},
+		}
+	)
+
+	for _, c := range cases {
+		r := httptest.NewRequest("GET", "http://app.ex.org/", nil)
+		r.RemoteAddr = c.remote
+		if c.tls {
+			r.TLS = &tls.ConnectionState{}
+		}
+		for name, value := range c.headers {
+			r.Header.Set(name, value)
+		}
+		if origin := res.Resolve(r); !origin.IP.Equal(c.origin.IP) || origin.Scheme != c.origin.Scheme || origin.Host != c.origin.Host {
+			test.Errorf("Resolve %v: %+v", c.name, origin)
+		}
+	}
+}
+
+func TestMiddleware(test *testing.T) {
+	var (
+		res, _ = New("10.0.0.0/8")
+		origin Origin
+		remote string
+		host   string
+		ok     bool
+		r      = httptest.NewRequest("GET", "http://internal/", nil)
+	)
+
+	h := res.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin, ok = FromRequest(r)
+		remote, host = r.RemoteAddr, r.Host
+	}))
+	r.RemoteAddr = "10.0.0.1:5000"
+	r.Header.Set("X-Forwarded-For", "1.2.3.4")
+	r.Header.Set("X-Forwarded-Proto", "https")
+	r.Header.Set("X-Forwarded-Host", "ex.org")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if !ok || origin.BaseURL() != "https://ex.org" || remote != "1.2.3.4:0" || host != "ex.org" {
+		test.Errorf("Middleware: %+v %v %v %v", origin, ok, remote, host)
+	}
+
+	//A request that was not forwarded keeps its RemoteAddr
+	r = httptest.NewRequest("GET", "http://app.ex.org/", nil)
+	r.RemoteAddr = "1.2.3.4:5000"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if remote != "1.2.3.4:5000" || host != "app.ex.org" {
+		test.Errorf("Middleware of a direct request: %v %v", remote, host)
+	}
+	if _, ok = FromRequest(r); ok {
+		test.Errorf("FromRequest of a request that was not resolved")
+	}
+}
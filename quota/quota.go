@@ -0,0 +1,265 @@
+/*
+Package quota accounts for the usage of HTTP services by client (or tenant). It is used as the oidc RP and later
+services get multi-tenant configurations.
+
+An Accountant's Middleware counts the requests and the request and response body bytes of each client, identified
+by a ClientFunc, and adds them to a Store. The in memory MemoryStore is the default; a service that must keep usage
+across restarts or share it between instances provides its own Store. Usage is reported by an Accountant's Report
+and Usage methods and, as JSON, by its Handler.
+*/
+package quota
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+//Usage is the usage of a client
+type Usage struct {
+	Requests uint64 `json:"requests"`
+	BytesIn  uint64 `json:"bytesIn"`
+	BytesOut uint64 `json:"bytesOut"`
+}
+
+//add adds usage to a Usage
+func (u *Usage) add(usage Usage) {
+	u.Requests += usage.Requests
+	u.BytesIn += usage.BytesIn
+	u.BytesOut += usage.BytesOut
+}
+
+/*
+A Store holds the accumulated Usage of each client. It must be safe for concurrent use.
+*/
+type Store interface {
+	//Add adds usage to a client's accumulated Usage
+	Add(client string, usage Usage) error
+
+	//Get returns a client's accumulated Usage
+	Get(client string) (Usage, error)
+
+	//All returns the accumulated Usage of every client
+	All() (map[string]Usage, error)
+}
+
+/*
+A MemoryStore is a Store that holds usage in memory; it is lost when the executable exits.
+*/
+type MemoryStore struct {
+	m     sync.Mutex
+	usage map[string]*Usage
+}
+
+/*
+NewMemoryStore creates a MemoryStore.
+*/
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{usage: make(map[string]*Usage)}
+}
+
+/*
+Add adds usage to a client's accumulated Usage.
+*/
+func (s *MemoryStore) Add(client string, usage Usage) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.usage[client] == nil {
+		s.usage[client] = new(Usage)
+	}
+	s.usage[client].add(usage)
+	return nil
+}
+
+/*
+Get returns a client's accumulated Usage.
+*/
+func (s *MemoryStore) Get(client string) (Usage, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+	if s.usage[client] == nil {
+		return Usage{}, nil
+	}
+	return *s.usage[client], nil
+}
+
+/*
+All returns the accumulated Usage of every client.
+*/
+func (s *MemoryStore) All() (map[string]Usage, error) {
+	var all map[string]Usage
+
+	s.m.Lock()
+	defer s.m.Unlock()
+	all = make(map[string]Usage, len(s.usage))
+	for client, usage := range s.usage {
+		all[client] = *usage
+	}
+	return all, nil
+}
+
+/*
+A ClientFunc identifies the client of a request. An empty client is accounted as Anonymous.
+*/
+type ClientFunc func(r *http.Request) string
+
+//Anonymous is the client of requests whose ClientFunc returns ""
+const Anonymous = "anonymous"
+
+/*
+HeaderClient returns a ClientFunc that identifies the client by a request header, e.g. a tenant header set by a gateway.
+*/
+func HeaderClient(name string) ClientFunc {
+	return func(r *http.Request) string {
+		return r.Header.Get(name)
+	}
+}
+
+/*
+An Accountant accounts for the usage of the handlers it wraps.
+*/
+type Accountant struct {
+	store  Store
+	client ClientFunc
+	errors func(err error)
+}
+
+/*
+New creates an Accountant that adds usage to a Store (a MemoryStore if it is nil) by the clients identified by a
+ClientFunc. Errors returned by the Store are passed to onError, if it is not nil; they do not fail requests.
+*/
+func New(store Store, client ClientFunc, onError func(err error)) *Accountant {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Accountant{store: store, client: client, errors: onError}
+}
+
+//countingReader counts the bytes read from a request body
+type countingReader struct {
+	io.ReadCloser
+	n uint64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.ReadCloser.Read(p)
+	cr.n += uint64(n)
+	return n, err
+}
+
+//countingWriter counts the bytes written to a response body
+type countingWriter struct {
+	http.ResponseWriter
+	n uint64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.ResponseWriter.Write(p)
+	cw.n += uint64(n)
+	return n, err
+}
+
+//Flush supports streamed responses
+func (cw *countingWriter) Flush() {
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+/*
+Middleware wraps a handler so that the usage of its requests is accounted. The bytes in are the bytes of the request
+body read by the handler.
+*/
+func (a *Accountant) Middleware(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			client = a.client(r)
+			reader *countingReader
+			writer = &countingWriter{ResponseWriter: w}
+			err    error
+		)
+
+		if client == "" {
+			client = Anonymous
+		}
+		if r.Body != nil {
+			reader = &countingReader{ReadCloser: r.Body}
+			r.Body = reader
+		}
+		defer func() {
+			var usage = Usage{Requests: 1, BytesOut: writer.n}
+
+			if reader != nil {
+				usage.BytesIn = reader.n
+			}
+			err = a.store.Add(client, usage)
+			if err != nil && a.errors != nil {
+				a.errors(err)
+			}
+		}()
+		h.ServeHTTP(writer, r)
+	})
+}
+
+/*
+Usage returns a client's accumulated Usage.
+*/
+func (a *Accountant) Usage(client string) (Usage, error) {
+	return a.store.Get(client)
+}
+
+/*
+Report returns the accumulated Usage of every client.
+*/
+func (a *Accountant) Report() (map[string]Usage, error) {
+	return a.store.All()
+}
+
+//clientUsage is a client's Usage in a JSON report
+type clientUsage struct {
+	Client string `json:"client"`
+	Usage
+}
+
+/*
+Handler returns a handler that reports the accumulated Usage of every client as a JSON array sorted by client, or of
+a single client if there is a client query parameter. It should be mounted behind an authorization check since it
+exposes the clients.
+*/
+func (a *Accountant) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			all    map[string]Usage
+			report []clientUsage
+			body   []byte
+			err    error
+		)
+
+		if r.Method != "GET" {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if client := r.URL.Query().Get("client"); client != "" {
+			var usage Usage
+			usage, err = a.store.Get(client)
+			all = map[string]Usage{client: usage}
+		} else {
+			all, err = a.store.All()
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		report = make([]clientUsage, 0, len(all))
+		for client, usage := range all {
+			report = append(report, clientUsage{Client: client, Usage: usage})
+		}
+		sort.Slice(report, func(i, j int) bool { return report[i].Client < report[j].Client })
+		body, _ = json.MarshalIndent(report, "", "  ")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	})
+}
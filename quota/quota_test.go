@@ -0,0 +1,111 @@
+package quota
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+//echo is a handler that reads its request body and responds with it
+var echo = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	body, _ := ioutil.ReadAll(r.Body)
+	w.Write(body)
+})
+
+//serve sends a request with a tenant header and body to a handler
+func serve(h http.Handler, tenant, body string) *httptest.ResponseRecorder {
+	var (
+		r = httptest.NewRequest("POST", "/", strings.NewReader(body))
+		w = httptest.NewRecorder()
+	)
+
+	if tenant != "" {
+		r.Header.Set("X-Tenant", tenant)
+	}
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestMiddleware(test *testing.T) {
+	var (
+		a = New(nil, HeaderClient("X-Tenant"), nil)
+		h = a.Middleware(echo)
+	)
+
+	serve(h, "acme", "hello")
+	serve(h, "acme", "hi")
+	serve(h, "", "anon")
+	serve(a.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { io.WriteString(w, "unread") })), "acme", "ignored")
+
+	//Only the request body bytes read by the handler are counted
+	if usage, err := a.Usage("acme"); err != nil || usage != (Usage{Requests: 3, BytesIn: 7, BytesOut: 13}) {
+		test.Errorf("Usage acme: %+v %v", usage, err)
+	}
+	if usage, _ := a.Usage(Anonymous); usage != (Usage{Requests: 1, BytesIn: 4, BytesOut: 4}) {
+		test.Errorf("Usage anonymous: %+v", usage)
+	}
+	if usage, _ := a.Usage("nobody"); usage != (Usage{}) {
+		test.Errorf("Usage of an unknown client: %+v", usage)
+	}
+	if report, err := a.Report(); err != nil || len(report) != 2 {
+		test.Errorf("Report: %v %v", report, err)
+	}
+}
+
+//failingStore is a Store whose Adds fail
+type failingStore struct {
+	*MemoryStore
+}
+
+func (failingStore) Add(string, Usage) error {
+	return errors.New("store down")
+}
+
+func TestStoreError(test *testing.T) {
+	var (
+		errs []error
+		a    = New(failingStore{NewMemoryStore()}, HeaderClient("X-Tenant"), func(err error) { errs = append(errs, err) })
+	)
+
+	//A Store error is reported but does not fail the request
+	if w := serve(a.Middleware(echo), "acme", "hello"); w.Code != http.StatusOK || w.Body.String() != "hello" {
+		test.Errorf("Response: %v %v", w.Code, w.Body.String())
+	}
+	if len(errs) != 1 {
+		test.Errorf("Store errors: %v", errs)
+	}
+}
+
+func TestHandler(test *testing.T) {
+	var (
+		a      = New(nil, HeaderClient("X-Tenant"), nil)
+		report []clientUsage
+		w      *httptest.ResponseRecorder
+	)
+
+	serve(a.Middleware(echo), "zeta", "z")
+	serve(a.Middleware(echo), "acme", "a")
+
+	w = httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/usage", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil || len(report) != 2 || report[0].Client != "acme" || report[1].Client != "zeta" {
+		test.Errorf("Report: %v %v", w.Body.String(), err)
+	}
+
+	w = httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/usage?client=zeta", nil))
+	if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil || len(report) != 1 || report[0].Requests != 1 || report[0].BytesIn != 1 {
+		test.Errorf("Client report: %v %v", w.Body.String(), err)
+	}
+
+	w = httptest.NewRecorder()
+	a.Handler().ServeHTTP(w, httptest.NewRequest("POST", "/usage", nil))
+	if w.Code != http.StatusMethodNotAllowed {
+		test.Errorf("POST report: %v", w.Code)
+	}
+}
@@ -0,0 +1,294 @@
+/*
+Package storekv is a minimal embedded key/value store, backed by a bbolt database file, that is shared by the features
+that persist small records (e.g. sessions, archived tokens, idempotency keys and delivered results) so that each does
+not implement its own persistence.
+
+A Store holds named Buckets of string keys and byte values. A value may be put with a TTL after which it has expired:
+an expired value is not returned by Get or ForEach, and is deleted by Purge. PutJSON and GetJSON store values encoded
+as JSON, and PutIfAbsent puts a value only if its key has no unexpired value, as an idempotency check requires.
+
+Each operation is its own bbolt transaction, so it is durable once it returns. A database file can only be opened by
+one process at a time; Open waits up to a second for another process to close it.
+
+The TTLs use a clock.Clock, which tests may replace with SetClock.
+*/
+package storekv
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/develrns/resilient/clock"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+//A Store is an embedded key/value database
+type Store struct {
+	db *bolt.DB
+
+	//clkM guards clk, which may be replaced while the Store is used
+	clkM sync.Mutex
+	clk  clock.Clock
+}
+
+//A Bucket is a named collection of keys in a Store
+type Bucket struct {
+	s    *Store
+	name []byte
+}
+
+//expiryLength is the length of the expiry time that prefixes each stored value
+const expiryLength = 8
+
+/*
+Open opens the Store in the database file at path, creating it if it does not exist.
+*/
+func Open(path string) (*Store, error) {
+	var (
+		db  *bolt.DB
+		err error
+	)
+
+	db, err = bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("Opening Store %v Failed: %v", path, err)
+	}
+	return &Store{db: db, clk: clock.Real}, nil
+}
+
+/*
+Close closes the Store's database file.
+*/
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+/*
+SetClock replaces the Clock of the Store, e.g. with a clock.Fake in a test. It returns the replaced Clock.
+*/
+func (s *Store) SetClock(c clock.Clock) clock.Clock {
+	s.clkM.Lock()
+	defer s.clkM.Unlock()
+	c, s.clk = s.clk, c
+	return c
+}
+
+//now returns the time of the Store's Clock
+func (s *Store) now() time.Time {
+	s.clkM.Lock()
+	defer s.clkM.Unlock()
+	return s.clk.Now()
+}
+
+/*
+Bucket returns the Bucket with the name, creating it if it does not exist.
+*/
+func (s *Store) Bucket(name string) (*Bucket, error) {
+	var err error
+
+	if name == "" {
+		return nil, fmt.Errorf("Bad Bucket Name")
+	}
+	err = s.db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(name))
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &Bucket{s: s, name: []byte(name)}, nil
+}
+
+//encode prefixes a value with its expiry time in Unix nanoseconds, 0 if it has no TTL
+func (b *Bucket) encode(value []byte, ttl time.Duration) []byte {
+	var encoded = make([]byte, expiryLength+len(value))
+
+	if ttl > 0 {
+		binary.BigEndian.PutUint64(encoded, uint64(b.s.now().Add(ttl).UnixNano()))
+	}
+	copy(encoded[expiryLength:], value)
+	return encoded
+}
+
+//decode returns a copy of an encoded value; it is false if the value has expired at now or is not an encoded value
+func decode(encoded []byte, now time.Time) ([]byte, bool) {
+	var (
+		expiry int64
+		value  []byte
+	)
+
+	if len(encoded) < expiryLength {
+		return nil, false
+	}
+	expiry = int64(binary.BigEndian.Uint64(encoded))
+	if expiry != 0 && now.UnixNano() >= expiry {
+		return nil, false
+	}
+
+	//bbolt values are only valid during their transaction
+	value = make([]byte, len(encoded)-expiryLength)
+	copy(value, encoded[expiryLength:])
+	return value, true
+}
+
+/*
+Put puts the value of a key. A ttl of 0 never expires.
+*/
+func (b *Bucket) Put(key string, value []byte, ttl time.Duration) error {
+	var encoded = b.encode(value, ttl)
+
+	return b.s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.name).Put([]byte(key), encoded)
+	})
+}
+
+/*
+PutIfAbsent puts the value of a key if it has no value or its value has expired. It returns false if the key has an
+unexpired value, which is left unchanged.
+*/
+func (b *Bucket) PutIfAbsent(key string, value []byte, ttl time.Duration) (bool, error) {
+	var (
+		encoded = b.encode(value, ttl)
+		now     = b.s.now()
+		put     bool
+		err     error
+	)
+
+	err = b.s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.name)
+		if _, ok := decode(bucket.Get([]byte(key)), now); ok {
+			return nil
+		}
+		put = true
+		return bucket.Put([]byte(key), encoded)
+	})
+	if err != nil {
+		return false, err
+	}
+	return put, nil
+}
+
+/*
+Get gets the value of a key. It returns false if the key has no value or its value has expired.
+*/
+func (b *Bucket) Get(key string) ([]byte, bool, error) {
+	var (
+		now   = b.s.now()
+		value []byte
+		ok    bool
+		err   error
+	)
+
+	err = b.s.db.View(func(tx *bolt.Tx) error {
+		value, ok = decode(tx.Bucket(b.name).Get([]byte(key)), now)
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return value, ok, nil
+}
+
+/*
+Delete deletes the value of a key, if any.
+*/
+func (b *Bucket) Delete(key string) error {
+	return b.s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.name).Delete([]byte(key))
+	})
+}
+
+/*
+ForEach calls f with each unexpired key and value in key order. If f returns an error, ForEach stops and returns it.
+f must not use the Bucket's Store, since the Store is locked by ForEach's transaction.
+*/
+func (b *Bucket) ForEach(f func(key string, value []byte) error) error {
+	var now = b.s.now()
+
+	return b.s.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(b.name).ForEach(func(k, v []byte) error {
+			value, ok := decode(v, now)
+			if !ok {
+				return nil
+			}
+			return f(string(k), value)
+		})
+	})
+}
+
+/*
+Purge deletes the expired values of the Bucket and returns the number deleted.
+*/
+func (b *Bucket) Purge() (int, error) {
+	var (
+		now     = b.s.now()
+		expired [][]byte
+		err     error
+	)
+
+	err = b.s.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(b.name)
+		err := bucket.ForEach(func(k, v []byte) error {
+			if _, ok := decode(v, now); !ok {
+				expired = append(expired, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		//Keys cannot be deleted while the bucket is iterated
+		for _, k := range expired {
+			if err = bucket.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(expired), nil
+}
+
+/*
+PutJSON puts the JSON encoding of v as the value of a key. A ttl of 0 never expires.
+*/
+func (b *Bucket) PutJSON(key string, v interface{}, ttl time.Duration) error {
+	var (
+		value []byte
+		err   error
+	)
+
+	value, err = json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return b.Put(key, value, ttl)
+}
+
+/*
+GetJSON decodes the JSON value of a key into v. It returns false if the key has no value or its value has expired.
+*/
+func (b *Bucket) GetJSON(key string, v interface{}) (bool, error) {
+	var (
+		value []byte
+		ok    bool
+		err   error
+	)
+
+	value, ok, err = b.Get(key)
+	if err != nil || !ok {
+		return false, err
+	}
+	err = json.Unmarshal(value, v)
+	if err != nil {
+		return false, fmt.Errorf("Bad JSON Value of Key %v: %v", key, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,163 @@
+package storekv
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/develrns/resilient/clock"
+)
+
+//openBucket opens a Store in a temp dir with a Fake clock and returns a Bucket of it
+func openBucket(test *testing.T) (*Bucket, *clock.Fake) {
+	var (
+		fake   = clock.NewFake(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC))
+		store  *Store
+		bucket *Bucket
+		err    error
+	)
+
+	store, err = Open(filepath.Join(test.TempDir(), "store.db"))
+	if err != nil {
+		test.Fatalf("Open: %v", err)
+	}
+	test.Cleanup(func() { store.Close() })
+	store.SetClock(fake)
+	bucket, err = store.Bucket("b")
+	if err != nil {
+		test.Fatalf("Bucket: %v", err)
+	}
+	return bucket, fake
+}
+
+func TestTTL(test *testing.T) {
+	var (
+		b, fake = openBucket(test)
+		value   []byte
+		ok      bool
+		err     error
+	)
+
+	b.Put("forever", []byte("f"), 0)
+	b.Put("minute", []byte("m"), time.Minute)
+	b.Put("hour", []byte("h"), time.Hour)
+
+	fake.Advance(time.Minute - time.Nanosecond)
+	if value, ok, err = b.Get("minute"); !ok || err != nil || string(value) != "m" {
+		test.Errorf("Get before the TTL: %q %v %v", value, ok, err)
+	}
+
+	//A value expires once its TTL has passed
+	fake.Advance(time.Nanosecond)
+	if value, ok, err = b.Get("minute"); ok || err != nil || value != nil {
+		test.Errorf("Get of an expired value: %q %v %v", value, ok, err)
+	}
+	keys := ""
+	b.ForEach(func(key string, value []byte) error {
+		keys += key + "=" + string(value) + " "
+		return nil
+	})
+	if keys != "forever=f hour=h " {
+		test.Errorf("ForEach: %v", keys)
+	}
+
+	//Purge deletes only the expired values
+	fake.Advance(time.Hour)
+	if n, err := b.Purge(); n != 2 || err != nil {
+		test.Errorf("Purge: %v %v", n, err)
+	}
+	if value, ok, _ = b.Get("forever"); !ok || string(value) != "f" {
+		test.Errorf("Get of a value without a TTL: %q %v", value, ok)
+	}
+	if n, _ := b.Purge(); n != 0 {
+		test.Errorf("Second Purge: %v", n)
+	}
+}
+
+func TestPutIfAbsent(test *testing.T) {
+	var b, fake = openBucket(test)
+
+	if put, err := b.PutIfAbsent("k", []byte("first"), time.Minute); !put || err != nil {
+		test.Fatalf("PutIfAbsent of an absent key: %v %v", put, err)
+	}
+	if put, _ := b.PutIfAbsent("k", []byte("second"), time.Minute); put {
+		test.Errorf("PutIfAbsent of a present key")
+	}
+	if value, _, _ := b.Get("k"); string(value) != "first" {
+		test.Errorf("Value after PutIfAbsent of a present key: %q", value)
+	}
+
+	//An expired value is absent
+	fake.Advance(time.Minute)
+	if put, _ := b.PutIfAbsent("k", []byte("third"), 0); !put {
+		test.Errorf("PutIfAbsent of an expired key")
+	}
+	if value, ok, _ := b.Get("k"); !ok || string(value) != "third" {
+		test.Errorf("Value after PutIfAbsent of an expired key: %q %v", value, ok)
+	}
+}
+
+func TestBucket(test *testing.T) {
+	var (
+		b, _  = openBucket(test)
+		other *Bucket
+		err   error
+		stop  = errors.New("stop")
+		seen  int
+	)
+
+	if _, err = b.s.Bucket(""); err == nil {
+		test.Errorf("Bucket without a name should fail")
+	}
+	other, err = b.s.Bucket("other")
+	if err != nil {
+		test.Fatalf("Bucket: %v", err)
+	}
+
+	//Buckets have separate keys
+	b.Put("k", []byte("b"), 0)
+	other.Put("k", []byte("other"), 0)
+	if value, _, _ := b.Get("k"); string(value) != "b" {
+		test.Errorf("Get from a Bucket: %q", value)
+	}
+	b.Delete("k")
+	if _, ok, _ := b.Get("k"); ok {
+		test.Errorf("Get of a deleted key")
+	}
+	if _, ok, _ := other.Get("k"); !ok {
+		test.Errorf("Delete deleted the key of another Bucket")
+	}
+
+	b.Put("a", nil, 0)
+	b.Put("b", nil, 0)
+	if err = b.ForEach(func(string, []byte) error { seen++; return stop }); err != stop || seen != 1 {
+		test.Errorf("ForEach that fails: %v %v", err, seen)
+	}
+}
+
+func TestJSON(test *testing.T) {
+	var (
+		b, _ = openBucket(test)
+		v    struct{ Name string }
+		ok   bool
+		err  error
+	)
+
+	if err = b.PutJSON("k", struct{ Name string }{"Ann"}, 0); err != nil {
+		test.Fatalf("PutJSON: %v", err)
+	}
+	if ok, err = b.GetJSON("k", &v); !ok || err != nil || v.Name != "Ann" {
+		test.Errorf("GetJSON: %+v %v %v", v, ok, err)
+	}
+	if ok, err = b.GetJSON("absent", &v); ok || err != nil {
+		test.Errorf("GetJSON of an absent key: %v %v", ok, err)
+	}
+	if err = b.PutJSON("bad", make(chan int), 0); err == nil {
+		test.Errorf("PutJSON of a channel should fail")
+	}
+	b.Put("bad", []byte("{"), 0)
+	if ok, err = b.GetJSON("bad", &v); ok || err == nil {
+		test.Errorf("GetJSON of a value that is not JSON: %v %v", ok, err)
+	}
+}